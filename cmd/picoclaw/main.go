@@ -20,6 +20,7 @@ import (
 	"runtime"
 	"sort"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/chzyer/readline"
@@ -30,6 +31,7 @@ import (
 	"github.com/sipeed/picoclaw/pkg/config"
 	"github.com/sipeed/picoclaw/pkg/cron"
 	"github.com/sipeed/picoclaw/pkg/devices"
+	"github.com/sipeed/picoclaw/pkg/gateway"
 	"github.com/sipeed/picoclaw/pkg/heartbeat"
 	"github.com/sipeed/picoclaw/pkg/logger"
 	"github.com/sipeed/picoclaw/pkg/migrate"
@@ -374,11 +376,13 @@ func migrateHelp() {
 func agentCmd() {
 	message := ""
 	sessionKey := "cli:default"
+	debug := false
 
 	args := os.Args[2:]
 	for i := 0; i < len(args); i++ {
 		switch args[i] {
 		case "--debug", "-d":
+			debug = true
 			logger.SetLevel(logger.DEBUG)
 			fmt.Println("🔍 Debug mode enabled")
 		case "-m", "--message":
@@ -399,6 +403,7 @@ func agentCmd() {
 		fmt.Printf("Error loading config: %v\n", err)
 		os.Exit(1)
 	}
+	applyLoggingConfig(cfg, debug)
 
 	provider, err := providers.CreateProvider(cfg)
 	if err != nil {
@@ -408,6 +413,7 @@ func agentCmd() {
 
 	msgBus := bus.NewMessageBus()
 	agentLoop := agent.NewAgentLoop(cfg, msgBus, provider)
+	defer agentLoop.Stop()
 
 	// Print agent startup info (only for interactive mode)
 	startupInfo := agentLoop.GetStartupInfo()
@@ -423,6 +429,7 @@ func agentCmd() {
 		response, err := agentLoop.ProcessDirect(ctx, message, sessionKey)
 		if err != nil {
 			fmt.Printf("Error: %v\n", err)
+			agentLoop.Stop()
 			os.Exit(1)
 		}
 		fmt.Printf("\n%s %s\n", logo, response)
@@ -518,11 +525,35 @@ func simpleInteractiveMode(agentLoop *agent.AgentLoop, sessionKey string) {
 	}
 }
 
+// applyLoggingConfig sets the level from logging.level, which --debug wins
+// over, the per-component overrides from logging.component_levels, and
+// sampling of repeated entries.
+func applyLoggingConfig(cfg *config.Config, debug bool) {
+	if !debug && cfg.Logging.Level != "" {
+		if level, err := logger.ParseLevel(cfg.Logging.Level); err != nil {
+			fmt.Printf("Warning: %v; using %s\n", err, logger.GetLevel())
+		} else {
+			logger.SetLevel(level)
+		}
+	}
+	for component, name := range cfg.Logging.ComponentLevels {
+		level, err := logger.ParseLevel(name)
+		if err != nil {
+			fmt.Printf("Warning: logging.component_levels.%s: %v\n", component, err)
+			continue
+		}
+		logger.SetComponentLevel(component, level)
+	}
+	logger.SetSampling(time.Duration(cfg.Logging.SampleWindowSeconds)*time.Second, cfg.Logging.SampleThreshold)
+}
+
 func gatewayCmd() {
 	// Check for --debug flag
+	debug := false
 	args := os.Args[2:]
 	for _, arg := range args {
 		if arg == "--debug" || arg == "-d" {
+			debug = true
 			logger.SetLevel(logger.DEBUG)
 			fmt.Println("🔍 Debug mode enabled")
 			break
@@ -534,6 +565,7 @@ func gatewayCmd() {
 		fmt.Printf("Error loading config: %v\n", err)
 		os.Exit(1)
 	}
+	applyLoggingConfig(cfg, debug)
 
 	// Enable file logging if configured
 	if cfg.Logging.FileEnabled {
@@ -546,6 +578,16 @@ func gatewayCmd() {
 			fmt.Printf("Warning: Failed to enable file logging: %v\n", err)
 		}
 	}
+	if cfg.Logging.Remote.URL != "" {
+		if err := logger.EnableRemoteLogging(logger.RemoteOptions{
+			URL:           cfg.Logging.Remote.URL,
+			Headers:       cfg.Logging.Remote.Headers,
+			BufferSize:    cfg.Logging.Remote.BufferSize,
+			FlushInterval: time.Duration(cfg.Logging.Remote.FlushIntervalSeconds) * time.Second,
+		}); err != nil {
+			fmt.Printf("Warning: Failed to enable remote logging: %v\n", err)
+		}
+	}
 
 	provider, err := providers.CreateProvider(cfg)
 	if err != nil {
@@ -575,7 +617,7 @@ func gatewayCmd() {
 		})
 
 	// Setup cron tool and service
-	cronService := setupCronTool(agentLoop, msgBus, cfg.WorkspacePath())
+	cronService := setupCronTool(agentLoop, msgBus, cfg)
 
 	heartbeatService := heartbeat.NewHeartbeatService(
 		cfg.WorkspacePath(),
@@ -583,6 +625,14 @@ func gatewayCmd() {
 		cfg.Heartbeat.Enabled,
 	)
 	heartbeatService.SetBus(msgBus)
+	heartbeatService.SetStateManager(agentLoop.StateManager())
+	heartbeatService.SetPrompts(cfg.Heartbeat.Prompts, cfg.Heartbeat.PromptMode)
+	if cfg.Heartbeat.Weather.Enabled {
+		heartbeatService.SetWeather(cfg.Heartbeat.Weather.Location, heartbeat.NewWttrFetcher())
+	}
+	if err := heartbeatService.SetSchedules(heartbeatSchedulesFromConfig(cfg.Heartbeat)); err != nil {
+		fmt.Printf("Error configuring heartbeat schedules: %v (falling back to interval)\n", err)
+	}
 	heartbeatService.SetHandler(func(prompt, channel, chatID string) *tools.ToolResult {
 		// Use cli:direct as fallback if no valid channel
 		if channel == "" || chatID == "" {
@@ -606,14 +656,28 @@ func gatewayCmd() {
 		fmt.Printf("Error creating channel manager: %v\n", err)
 		os.Exit(1)
 	}
+	channelManager.SetConfigPath(getConfigPath())
+	agentLoop.SetAllowlistManager(channelManager)
+	agentLoop.SetChannelStatusProvider(channelManager)
 
 	var transcriber *voice.GroqTranscriber
 	if cfg.Providers.Groq.APIKey != "" {
 		transcriber = voice.NewGroqTranscriber(cfg.Providers.Groq.APIKey)
 		logger.InfoC("voice", "Groq voice transcription enabled")
+		if cfg.Voice.LanguageRouting {
+			routes := make(map[string]voice.LanguageRoute, len(cfg.Voice.Languages))
+			for lang, lc := range cfg.Voice.Languages {
+				routes[lang] = voice.LanguageRoute{Model: lc.Model, Prompt: lc.Prompt}
+			}
+			transcriber.SetLanguageRoutes(routes)
+			logger.InfoCF("voice", "Voice language routing enabled", map[string]interface{}{
+				"languages": len(routes),
+			})
+		}
 	}
 
 	if transcriber != nil {
+		agentLoop.SetTranscriber(transcriber)
 		if telegramChannel, ok := channelManager.GetChannel("telegram"); ok {
 			if tc, ok := telegramChannel.(*channels.TelegramChannel); ok {
 				tc.SetTranscriber(transcriber)
@@ -634,6 +698,22 @@ func gatewayCmd() {
 		}
 	}
 
+	if tts := cfg.Voice.TTS; tts.Enabled {
+		apiKey := tts.APIKey
+		if apiKey == "" {
+			apiKey = cfg.Providers.OpenAI.APIKey
+		}
+		if apiKey != "" {
+			agentLoop.SetSynthesizer(voice.NewSpeechSynthesizer(tts.APIBase, apiKey, tts.Model, tts.Voice, tts.Format))
+			logger.InfoCF("voice", "Spoken replies to voice messages enabled", map[string]interface{}{
+				"model": tts.Model,
+				"voice": tts.Voice,
+			})
+		} else {
+			logger.WarnC("voice", "voice.tts is enabled but no API key is configured; spoken replies disabled")
+		}
+	}
+
 	enabledChannels := channelManager.GetEnabledChannels()
 	if len(enabledChannels) > 0 {
 		fmt.Printf("✓ Channels enabled: %s\n", enabledChannels)
@@ -659,8 +739,11 @@ func gatewayCmd() {
 
 	stateManager := state.NewManager(cfg.WorkspacePath())
 	deviceService := devices.NewService(devices.Config{
-		Enabled:    cfg.Devices.Enabled,
-		MonitorUSB: cfg.Devices.MonitorUSB,
+		Enabled:         cfg.Devices.Enabled,
+		MonitorUSB:      cfg.Devices.MonitorUSB,
+		WatchSMS:        cfg.Devices.Telephony.SMSWatch,
+		SMSPollInterval: time.Duration(cfg.Devices.Telephony.SMSPollIntervalSeconds) * time.Second,
+		SMSDebounce:     time.Duration(cfg.Devices.Telephony.SMSDebounceSeconds) * time.Second,
 	}, stateManager)
 	deviceService.SetBus(msgBus)
 	if err := deviceService.Start(ctx); err != nil {
@@ -675,20 +758,58 @@ func gatewayCmd() {
 
 	go agentLoop.Run(ctx)
 
+	var apiServer *gateway.Server
+	if cfg.Gateway.APIEnabled {
+		apiServer = gateway.NewServer(cfg.Gateway, agentLoop)
+		apiServer.SetChannelStatusProvider(channelManager)
+		if err := apiServer.Start(ctx); err != nil {
+			fmt.Printf("Error starting OpenAI-compatible API: %v\n", err)
+			apiServer = nil
+		} else {
+			fmt.Printf("✓ OpenAI-compatible API at http://%s:%d/v1\n", cfg.Gateway.Host, cfg.Gateway.Port)
+		}
+	}
+
 	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, os.Interrupt)
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
 	<-sigChan
 
 	fmt.Println("\nShutting down...")
+	if apiServer != nil {
+		apiServer.Stop(context.Background())
+	}
 	cancel()
 	deviceService.Stop()
 	heartbeatService.Stop()
 	cronService.Stop()
-	agentLoop.Stop()
 	channelManager.StopAll(ctx)
+	// Last, so the other services' shutdown logs still reach the log file.
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 15*time.Second)
+	if err := agentLoop.Shutdown(shutdownCtx); err != nil {
+		fmt.Printf("Warning: %v\n", err)
+	}
+	shutdownCancel()
+	logger.DisableRemoteLogging(5 * time.Second)
 	fmt.Println("✓ Gateway stopped")
 }
 
+// heartbeatSchedulesFromConfig merges the single Schedule shorthand with the
+// named Schedules list.
+func heartbeatSchedulesFromConfig(hc config.HeartbeatConfig) []heartbeat.Schedule {
+	schedules := make([]heartbeat.Schedule, 0, len(hc.Schedules)+1)
+	if strings.TrimSpace(hc.Schedule) != "" {
+		schedules = append(schedules, heartbeat.Schedule{Name: "default", Expr: hc.Schedule})
+	}
+	for _, s := range hc.Schedules {
+		schedules = append(schedules, heartbeat.Schedule{
+			Name:   s.Name,
+			Expr:   s.Schedule,
+			Prompt: s.Prompt,
+		})
+	}
+	return schedules
+}
+
 func statusCmd() {
 	cfg, err := loadConfig()
 	if err != nil {
@@ -808,7 +929,13 @@ func usageCmd() {
 		}
 	}
 
-	store := usage.NewStore(cfg.WorkspacePath())
+	loc, err := cfg.Location()
+	if err != nil {
+		fmt.Printf("Warning: %v; using local time\n", err)
+	}
+	// Same location the agent loop records to.
+	store := usage.NewStore(filepath.Join(cfg.WorkspacePath(), "usage"), loc)
+	store.SetRetentionDays(cfg.Usage.RetentionDays)
 	if dayKey == "" && sessionKey == "" && provider == "" {
 		dayKey = store.TodayKey()
 	}
@@ -1147,15 +1274,20 @@ func getConfigPath() string {
 	return filepath.Join(home, ".picoclaw", "config.json")
 }
 
-func setupCronTool(agentLoop *agent.AgentLoop, msgBus *bus.MessageBus, workspace string) *cron.CronService {
+func setupCronTool(agentLoop *agent.AgentLoop, msgBus *bus.MessageBus, cfg *config.Config) *cron.CronService {
+	workspace := cfg.WorkspacePath()
 	cronStorePath := filepath.Join(workspace, "cron", "jobs.json")
 
 	// Create cron service
 	cronService := cron.NewCronService(cronStorePath, nil)
 
-	// Create and register CronTool
-	cronTool := tools.NewCronTool(cronService, agentLoop, msgBus, workspace)
+	// Create and register CronTool. Scheduled commands go through the
+	// agent's exec tool, so safe mode and the command lists apply to them.
+	cronTool := tools.NewCronTool(cronService, agentLoop, msgBus, agentLoop.ExecTool())
 	agentLoop.RegisterTool(cronTool)
+	agentLoop.RegisterTool(tools.NewListTasksTool(cronService))
+	agentLoop.RegisterTool(tools.NewCancelTaskTool(cronService))
+	agentLoop.SetCronService(cronService)
 
 	// Set the onJob handler
 	cronService.SetOnJob(func(job *cron.CronJob) (string, error) {
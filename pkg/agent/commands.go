@@ -0,0 +1,575 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/channels"
+	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/tools"
+)
+
+// Chat commands are routed before normal agent processing. Inbound messages
+// only reach the bus after BaseChannel.IsAllowed, so every command here is
+// already restricted to allowlisted senders.
+
+// commandSessionKey resolves the session key a command applies to. Run
+// registers in-flight requests in activeCancel under the same key.
+func commandSessionKey(msg bus.InboundMessage) string {
+	if msg.SessionKey != "" {
+		return msg.SessionKey
+	}
+	return fmt.Sprintf("%s:%s", msg.Channel, msg.ChatID)
+}
+
+// isCommand reports whether content is the given slash command, with or
+// without arguments.
+func isCommand(content, name string) bool {
+	return content == name || strings.HasPrefix(content, name+" ")
+}
+
+// handleSessionsCommand lists known sessions with message counts and last
+// activity. It shows every chat's sessions, so like the other admin
+// commands it is limited to the owner and allowlisted users.
+func (al *AgentLoop) handleSessionsCommand(msg bus.InboundMessage) string {
+	if al.allowlists != nil && !al.allowlists.CanManageAllowList(msg.Channel, msg.SenderID) {
+		return "Only the owner or an allowlisted user can list sessions."
+	}
+	infos := al.sessions.List()
+	if len(infos) == 0 {
+		return "No sessions yet."
+	}
+
+	current := commandSessionKey(msg)
+	lines := []string{fmt.Sprintf("**Sessions** (%d)", len(infos))}
+	for _, info := range infos {
+		marker := ""
+		if info.Key == current {
+			marker = " (current)"
+		}
+		summary := ""
+		if info.HasSummary {
+			summary = " +summary"
+		}
+		lines = append(lines, fmt.Sprintf("- `%s`%s · %d msgs%s · %s",
+			info.Key,
+			marker,
+			info.MessageCount,
+			summary,
+			info.Updated.Local().Format(time.DateTime),
+		))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// handleClearCommand wipes the current session's history and summary and
+// resets its exec working directory. It requires "/clear confirm" and
+// cancels any in-flight request first.
+func (al *AgentLoop) handleClearCommand(msg bus.InboundMessage, command string) string {
+	sessionKey := commandSessionKey(msg)
+	parts := strings.Fields(command)
+	if len(parts) < 2 || strings.ToLower(parts[1]) != "confirm" {
+		count := len(al.sessions.GetHistory(sessionKey))
+		return fmt.Sprintf("This will erase %d message(s) and the summary for `%s`. Send `/clear confirm` to proceed.", count, sessionKey)
+	}
+
+	if cancelFn, ok := al.activeCancel.LoadAndDelete(sessionKey); ok {
+		cancelFn.(context.CancelFunc)()
+		logger.InfoCF("agent", "Cancelled active request before clearing session",
+			map[string]interface{}{"session_key": sessionKey})
+	}
+
+	if execTool := al.ExecTool(); execTool != nil {
+		execTool.ResetSessionDir(msg.Channel, msg.ChatID)
+	}
+
+	if !al.sessions.Clear(sessionKey) {
+		return "Nothing to clear."
+	}
+	if err := al.sessions.Save(sessionKey); err != nil {
+		logger.WarnCF("agent", "Failed to persist cleared session",
+			map[string]interface{}{"session_key": sessionKey, "error": err.Error()})
+	}
+
+	logger.InfoCF("agent", "Session cleared",
+		map[string]interface{}{"session_key": sessionKey})
+	return "Session cleared."
+}
+
+// handleTasksCommand lists the chat's upcoming scheduled tasks, or cancels
+// one with "/tasks cancel <id>". Tasks of other chats are neither listed
+// nor cancellable.
+func (al *AgentLoop) handleTasksCommand(ctx context.Context, msg bus.InboundMessage, command string) string {
+	if al.cronService == nil {
+		return "Scheduled tasks are not available."
+	}
+	ctx = tools.WithToolContext(ctx, msg.Channel, msg.ChatID)
+
+	parts := strings.Fields(command)
+	if len(parts) == 1 {
+		return tools.NewListTasksTool(al.cronService).Execute(ctx, nil).ForLLM
+	}
+	if strings.ToLower(parts[1]) != "cancel" || len(parts) != 3 {
+		return "Usage: `/tasks` to list upcoming tasks, `/tasks cancel <id>` to cancel one."
+	}
+
+	result := tools.NewCancelTaskTool(al.cronService).Execute(ctx, map[string]interface{}{"task_id": parts[2]})
+	if !result.IsError {
+		logger.InfoCF("agent", "Scheduled task cancelled via /tasks",
+			map[string]interface{}{"task_id": parts[2]})
+	}
+	return result.ForLLM
+}
+
+// handleSkillsCommand manages skills: "/skills list" shows them,
+// "/skills reload" rescans the skills directories so new skills apply
+// without a restart, and "/skills enable|disable <name>" chooses which
+// skills are loaded into the system prompt. Reloading, enabling and
+// disabling change every chat's prompt, so they are limited to the owner
+// and allowlisted users.
+func (al *AgentLoop) handleSkillsCommand(msg bus.InboundMessage, command string) string {
+	parts := strings.Fields(command)
+	sub := "list"
+	if len(parts) > 1 {
+		sub = strings.ToLower(parts[1])
+	}
+	if sub == "reload" || sub == "enable" || sub == "disable" {
+		if al.allowlists != nil && !al.allowlists.CanManageAllowList(msg.Channel, msg.SenderID) {
+			return "Only the owner or an allowlisted user can reload, enable or disable skills."
+		}
+	}
+
+	switch sub {
+	case "list":
+		info := al.contextBuilder.GetSkillsInfo()
+		names, _ := info["names"].([]string)
+		disabled, _ := info["disabled"].([]string)
+		if len(names) == 0 && len(disabled) == 0 {
+			return "No skills loaded."
+		}
+		sort.Strings(names)
+		lines := []string{fmt.Sprintf("Skills (%d): %s", len(names), strings.Join(names, ", "))}
+		if len(names) == 0 {
+			lines[0] = "Skills: none enabled"
+		}
+		if len(disabled) > 0 {
+			sort.Strings(disabled)
+			lines = append(lines, fmt.Sprintf("Disabled (%d): %s", len(disabled), strings.Join(disabled, ", ")))
+		}
+		return strings.Join(lines, "\n")
+
+	case "reload":
+		before := make(map[string]bool)
+		for _, s := range al.contextBuilder.listSkills() {
+			before[s.Name] = true
+		}
+		var added []string
+		loaded := al.contextBuilder.ReloadSkills()
+		for _, s := range loaded {
+			if !before[s.Name] {
+				added = append(added, s.Name)
+			}
+			delete(before, s.Name)
+		}
+		removed := make([]string, 0, len(before))
+		for name := range before {
+			removed = append(removed, name)
+		}
+		sort.Strings(added)
+		sort.Strings(removed)
+		logger.InfoCF("agent", "Skills reloaded via /skills",
+			map[string]interface{}{
+				"count":   len(loaded),
+				"added":   added,
+				"removed": removed,
+			})
+
+		reply := fmt.Sprintf("Skills reloaded: %d loaded.", len(loaded))
+		if len(added) > 0 {
+			reply += " Added: " + strings.Join(added, ", ") + "."
+		}
+		if len(removed) > 0 {
+			reply += " Removed: " + strings.Join(removed, ", ") + "."
+		}
+		return reply
+
+	case "enable", "disable":
+		if len(parts) != 3 {
+			return fmt.Sprintf("Usage: `/skills %s <name>`", sub)
+		}
+		return al.toggleSkill(parts[2], sub == "enable")
+	}
+	return "Usage: `/skills list` to show skills, `/skills reload` to rescan the skills directories, `/skills enable|disable <name>` to choose which skills are loaded."
+}
+
+// toggleSkill enables or disables a skill and reports how the change
+// affects the size of every system prompt.
+func (al *AgentLoop) toggleSkill(name string, enable bool) string {
+	known := false
+	for _, s := range al.contextBuilder.listSkills() {
+		if s.Name == name {
+			known = true
+			break
+		}
+	}
+	if !known {
+		return fmt.Sprintf("Unknown skill `%s`. Use `/skills list` to see the available skills.", name)
+	}
+	if al.state.IsSkillDisabled(name) != enable {
+		if enable {
+			return fmt.Sprintf("Skill `%s` is already enabled.", name)
+		}
+		return fmt.Sprintf("Skill `%s` is already disabled.", name)
+	}
+
+	before := al.contextBuilder.skillsSummaryTokens()
+	if err := al.state.SetSkillEnabled(name, enable); err != nil {
+		return fmt.Sprintf("Failed to save the skill setting: %v", err)
+	}
+	after := al.contextBuilder.skillsSummaryTokens()
+	logger.InfoCF("agent", "Skill toggled via /skills",
+		map[string]interface{}{
+			"skill":         name,
+			"enabled":       enable,
+			"tokens_before": before,
+			"tokens_after":  after,
+		})
+
+	if enable {
+		return fmt.Sprintf("Enabled skill `%s`: adds ~%d tokens to each request (skills now ~%d tokens).", name, after-before, after)
+	}
+	return fmt.Sprintf("Disabled skill `%s`: saves ~%d tokens per request (skills now ~%d tokens).", name, before-after, after)
+}
+
+// handleCleanupCommand empties the media caches, keeping files that
+// in-flight turns still reference, and reports the space freed. Like the
+// other admin commands it is limited to the owner and allowlisted users.
+func (al *AgentLoop) handleCleanupCommand(ctx context.Context, msg bus.InboundMessage) string {
+	if al.allowlists != nil && !al.allowlists.CanManageAllowList(msg.Channel, msg.SenderID) {
+		return "Only the owner or an allowlisted user can clean up the media cache."
+	}
+	result := tools.NewCleanupCacheTool(al.mediaCacheDirs(), al.keepCachedMedia, nil).Execute(ctx, nil)
+	logger.InfoCF("agent", "Media cache cleaned via /cleanup",
+		map[string]interface{}{"result": result.ForLLM})
+	return result.ForLLM
+}
+
+// handleAllowCommand lists or changes the current channel's allowlist:
+// "/allow list", "/allow add <id>", "/allow remove <id>". Only the owner or
+// an explicitly allowlisted sender may use it.
+func (al *AgentLoop) handleAllowCommand(msg bus.InboundMessage, command string) string {
+	if al.allowlists == nil {
+		return "Allowlist management is not available."
+	}
+	if !al.allowlists.CanManageAllowList(msg.Channel, msg.SenderID) {
+		return "Only the owner or an allowlisted user can manage the allowlist."
+	}
+	current, err := al.allowlists.AllowList(msg.Channel)
+	if err != nil {
+		return fmt.Sprintf("Cannot read the allowlist: %v", err)
+	}
+
+	parts := strings.Fields(command)
+	action := "list"
+	if len(parts) > 1 {
+		action = strings.ToLower(parts[1])
+	}
+
+	switch {
+	case action == "list" && len(parts) <= 2:
+		if len(current) == 0 {
+			return fmt.Sprintf("The %s allowlist is empty, so everyone can talk to me.", msg.Channel)
+		}
+		lines := []string{fmt.Sprintf("**Allowlist** for %s (%d)", msg.Channel, len(current))}
+		for _, id := range current {
+			lines = append(lines, fmt.Sprintf("- `%s`", id))
+		}
+		return strings.Join(lines, "\n")
+
+	case action == "add" && len(parts) == 3:
+		id := parts[2]
+		if slices.Contains(current, id) {
+			return fmt.Sprintf("`%s` is already on the %s allowlist.", id, msg.Channel)
+		}
+		if err := al.allowlists.SetAllowList(msg.Channel, append(current, id)); err != nil {
+			return fmt.Sprintf("Failed to update the allowlist: %v", err)
+		}
+		logger.InfoCF("agent", "Allowlist entry added via /allow",
+			map[string]interface{}{"channel": msg.Channel, "id": id, "by": msg.SenderID})
+		reply := fmt.Sprintf("Added `%s` to the %s allowlist.", id, msg.Channel)
+		if len(current) == 0 {
+			reply += " The list was empty, so from now on only listed users can talk to me."
+		}
+		return reply
+
+	case action == "remove" && len(parts) == 3:
+		id := parts[2]
+		idx := slices.Index(current, id)
+		if idx < 0 {
+			return fmt.Sprintf("`%s` is not on the %s allowlist.", id, msg.Channel)
+		}
+		if len(current) == 1 {
+			return "Cannot remove the last entry: an empty allowlist lets everyone talk to me. Add another id first."
+		}
+		if err := al.allowlists.SetAllowList(msg.Channel, slices.Delete(current, idx, idx+1)); err != nil {
+			return fmt.Sprintf("Failed to update the allowlist: %v", err)
+		}
+		logger.InfoCF("agent", "Allowlist entry removed via /allow",
+			map[string]interface{}{"channel": msg.Channel, "id": id, "by": msg.SenderID})
+		return fmt.Sprintf("Removed `%s` from the %s allowlist.", id, msg.Channel)
+	}
+	return "Usage: `/allow list`, `/allow add <id>`, `/allow remove <id>`"
+}
+
+// handleLogLevelCommand reports the log levels, or changes them live until
+// the next restart: "/loglevel <level>" sets the global level and
+// "/loglevel <component> <level|default>" overrides (or stops overriding)
+// one component. When an allowlist manager is set, only those who may
+// manage the allowlist can change levels.
+func (al *AgentLoop) handleLogLevelCommand(msg bus.InboundMessage, command string) string {
+	const usage = "Usage: `/loglevel` to show levels, `/loglevel <debug|info|warn|error>` to set the global level, `/loglevel <component> <level|default>` to override one component."
+	parts := strings.Fields(command)
+	if len(parts) == 1 {
+		return formatLogLevels()
+	}
+	if len(parts) > 3 {
+		return usage
+	}
+	if al.allowlists != nil && !al.allowlists.CanManageAllowList(msg.Channel, msg.SenderID) {
+		return "Only the owner or an allowlisted user can change the log level."
+	}
+
+	if len(parts) == 3 {
+		component, name := parts[1], parts[2]
+		if strings.EqualFold(name, "default") {
+			logger.ClearComponentLevel(component)
+			logger.WarnCF("agent", "Component log level cleared via /loglevel",
+				map[string]interface{}{"log_component": component, "by": msg.SenderID})
+			return fmt.Sprintf("`%s` now follows the global level (%s).", component, logger.GetLevel())
+		}
+		level, err := logger.ParseLevel(name)
+		if err != nil {
+			return fmt.Sprintf("Unknown log level `%s`. Use debug, info, warn, error or default.", name)
+		}
+		logger.SetComponentLevel(component, level)
+		logger.WarnCF("agent", "Component log level changed via /loglevel",
+			map[string]interface{}{"log_component": component, "to": level.String(), "by": msg.SenderID})
+		return fmt.Sprintf("Log level for `%s`: %s (global %s). This lasts until restart; set logging.component_levels in the config to keep it.", component, level, logger.GetLevel())
+	}
+
+	level, err := logger.ParseLevel(parts[1])
+	if err != nil {
+		return fmt.Sprintf("Unknown log level `%s`. Use debug, info, warn or error.", parts[1])
+	}
+	previous := logger.GetLevel()
+	logger.SetLevel(level)
+	// Logged at WARN so the change is recorded at any level.
+	logger.WarnCF("agent", "Log level changed via /loglevel",
+		map[string]interface{}{"from": previous.String(), "to": level.String(), "by": msg.SenderID})
+	return fmt.Sprintf("Log level: %s (was %s). This lasts until restart; set logging.level in the config to keep it.", level, previous)
+}
+
+// formatLogLevels reports the global level and any component overrides.
+func formatLogLevels() string {
+	reply := fmt.Sprintf("Log level: %s", logger.GetLevel())
+	overrides := logger.ComponentLevels()
+	if len(overrides) == 0 {
+		return reply
+	}
+	components := make([]string, 0, len(overrides))
+	for component := range overrides {
+		components = append(components, component)
+	}
+	sort.Strings(components)
+	for i, component := range components {
+		components[i] = fmt.Sprintf("%s=%s", component, overrides[component])
+	}
+	return reply + "\nOverrides: " + strings.Join(components, ", ")
+}
+
+// handleFailoverCommand shows the failover state, or acts on it:
+// "/failover probe" probes the primary model now and "/failover reset"
+// returns to it immediately. When an allowlist manager is set, only those
+// who may manage the allowlist can probe or reset.
+func (al *AgentLoop) handleFailoverCommand(ctx context.Context, msg bus.InboundMessage, command string) string {
+	if al.failoverMgr == nil || !al.failoverMgr.Enabled() {
+		return "Failover is not enabled."
+	}
+	parts := strings.Fields(command)
+	if len(parts) == 1 || (len(parts) == 2 && strings.EqualFold(parts[1], "status")) {
+		return al.formatFailoverStatus(time.Now())
+	}
+	if len(parts) != 2 {
+		return "Usage: `/failover` to show the state, `/failover probe` to probe the primary model now, `/failover reset` to return to it."
+	}
+	if al.allowlists != nil && !al.allowlists.CanManageAllowList(msg.Channel, msg.SenderID) {
+		return "Only the owner or an allowlisted user can control failover."
+	}
+
+	switch strings.ToLower(parts[1]) {
+	case "probe":
+		release, ok := al.acquireProbe()
+		if !ok {
+			return "A probe is already running; try again shortly."
+		}
+		defer release()
+		probeCtx, cancel := context.WithTimeout(ctx, 20*time.Second)
+		defer cancel()
+		outcome := al.runFailoverProbe(probeCtx)
+		logger.InfoCF("agent", "Failover probe forced via /failover",
+			map[string]interface{}{"success": outcome.Success, "by": msg.SenderID})
+		result := "failed"
+		if outcome.Success {
+			result = "succeeded"
+		}
+		return fmt.Sprintf("Probe of `%s` %s.\n%s", al.failoverMgr.PrimaryModel(), result, al.formatFailoverStatus(time.Now()))
+	case "reset":
+		from, changed := al.failoverMgr.ResetToPrimary()
+		if !changed {
+			return fmt.Sprintf("Already on the primary model `%s`.", from)
+		}
+		logger.WarnCF("agent", "Failover reset to primary via /failover",
+			map[string]interface{}{"from": from, "by": msg.SenderID})
+		return fmt.Sprintf("Switched back to primary model `%s` from `%s`.", al.failoverMgr.PrimaryModel(), from)
+	}
+	return "Usage: `/failover` to show the state, `/failover probe` to probe the primary model now, `/failover reset` to return to it."
+}
+
+// formatFailoverStatus renders the failover state snapshot for /failover.
+func (al *AgentLoop) formatFailoverStatus(now time.Time) string {
+	fs := al.failoverMgr.Snapshot()
+	mode := fs.Mode
+	if mode == "" {
+		mode = "normal"
+	}
+	lines := []string{
+		fmt.Sprintf("**Failover** · %s", mode),
+		fmt.Sprintf("Active `%s` · primary `%s`", al.failoverMgr.ActiveModel(), al.failoverMgr.PrimaryModel()),
+	}
+	if !al.failoverMgr.IsUsingPrimary() {
+		next := "due"
+		if fs.NextProbeAt.After(now) {
+			next = fmt.Sprintf("in %s", fs.NextProbeAt.Sub(now).Round(time.Second))
+		}
+		threshold := max(al.config.Agents.Failover.ProbeSuccessThreshold, 1)
+		lines = append(lines,
+			fmt.Sprintf("Next probe: %s", next),
+			fmt.Sprintf("Probe successes: %d/%d", fs.ConsecutiveProbeSuccesses, threshold))
+	}
+	var open []string
+	for model, b := range fs.Breakers {
+		if b.OpenUntil.After(now) {
+			open = append(open, fmt.Sprintf("`%s` (%s left)", model, b.OpenUntil.Sub(now).Round(time.Second)))
+		}
+	}
+	if len(open) > 0 {
+		sort.Strings(open)
+		lines = append(lines, fmt.Sprintf("Skipped (circuit open): %s", strings.Join(open, ", ")))
+	}
+	if fs.LastSwitchReason != "" {
+		lines = append(lines, fmt.Sprintf("Last switch: %s", fs.LastSwitchReason))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// handleRetryCommand rolls back the session's last exchange and runs the
+// agent again on the last user message. Only its text is replayed;
+// attachments from the original message are not resent.
+func (al *AgentLoop) handleRetryCommand(ctx context.Context, msg bus.InboundMessage) (string, error) {
+	sessionKey := commandSessionKey(msg)
+	last, ok := al.sessions.RollbackLastUserMessage(sessionKey)
+	if !ok {
+		return "Nothing to retry yet.", nil
+	}
+
+	logger.InfoCF("agent", "Retrying last user message",
+		map[string]interface{}{
+			"session_key":    sessionKey,
+			"content_len":    len(last.Content),
+			"correlation_id": msg.CorrelationID,
+		})
+
+	msg.SessionKey = sessionKey
+	msg.Content = last.Content
+	msg.Media = nil
+	return al.runUserMessage(ctx, msg)
+}
+
+// handleStatusCommand reports each channel's health: whether it runs, and
+// when it last succeeded or failed. A channel whose latest outcome was an
+// error is shown as degraded.
+func (al *AgentLoop) handleStatusCommand() string {
+	if al.channelStatus == nil {
+		return "Channel status is not available."
+	}
+	statuses := al.channelStatus.ChannelStatuses()
+	if len(statuses) == 0 {
+		return "No channels are enabled."
+	}
+	lines := []string{"**Channels**"}
+	for _, st := range statuses {
+		lines = append(lines, "- "+formatChannelStatus(st, time.Now()))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// formatChannelStatus renders one /status line.
+func formatChannelStatus(st channels.Status, now time.Time) string {
+	state := "ok"
+	switch {
+	case !st.Running:
+		state = "stopped"
+	case st.Degraded():
+		state = "degraded"
+	}
+	line := fmt.Sprintf("%s: %s", st.Name, state)
+	if !st.LastSuccess.IsZero() {
+		line += fmt.Sprintf(" · last success %s ago", now.Sub(st.LastSuccess).Round(time.Second))
+	}
+	if !st.LastError.IsZero() {
+		line += fmt.Sprintf(" · last error %s ago: %s", now.Sub(st.LastError).Round(time.Second), st.LastErrorMsg)
+	}
+	return line
+}
+
+// handleWhoamiCommand reports what this deployment can do: model, failover
+// mode, workspace, and the loaded tools and skills.
+func (al *AgentLoop) handleWhoamiCommand() string {
+	lines := []string{fmt.Sprintf("**%s** · model `%s`", al.contextBuilder.assistantName, al.model)}
+
+	if al.failoverMgr != nil && al.failoverMgr.Enabled() {
+		fs := al.failoverMgr.Snapshot()
+		mode := fs.Mode
+		if mode == "" {
+			mode = "normal"
+		}
+		line := fmt.Sprintf("Failover: %s", mode)
+		if active := al.failoverMgr.ActiveModel(); active != al.model {
+			line += fmt.Sprintf(" · active `%s`", active)
+		}
+		lines = append(lines, line)
+	} else {
+		lines = append(lines, "Failover: off")
+	}
+	lines = append(lines, fmt.Sprintf("Workspace: `%s`", al.workspace))
+
+	toolNames := al.tools.List()
+	sort.Strings(toolNames)
+	lines = append(lines, fmt.Sprintf("Tools (%d): %s", len(toolNames), strings.Join(toolNames, ", ")))
+
+	skillsInfo := al.contextBuilder.GetSkillsInfo()
+	skillNames, _ := skillsInfo["names"].([]string)
+	if len(skillNames) == 0 {
+		lines = append(lines, "Skills: none")
+	} else {
+		sort.Strings(skillNames)
+		lines = append(lines, fmt.Sprintf("Skills (%d): %s", len(skillNames), strings.Join(skillNames, ", ")))
+	}
+
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,630 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/channels"
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/cron"
+	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/providers"
+	"github.com/sipeed/picoclaw/pkg/state"
+)
+
+func newCommandTestLoop(t *testing.T) *AgentLoop {
+	t.Helper()
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{
+				Workspace:         t.TempDir(),
+				Model:             "test-model",
+				MaxTokens:         4096,
+				MaxToolIterations: 10,
+			},
+		},
+	}
+	return NewAgentLoop(cfg, bus.NewMessageBus(), &mockProvider{})
+}
+
+func TestSessionsCommand_ListsSessions(t *testing.T) {
+	al := newCommandTestLoop(t)
+	al.sessions.AddMessage("telegram:1", "user", "hello")
+	al.sessions.AddMessage("telegram:1", "assistant", "hi")
+
+	msg := bus.InboundMessage{Channel: "telegram", ChatID: "1", SessionKey: "telegram:1", Content: "/sessions"}
+	resp, err := al.processMessage(context.Background(), msg)
+	if err != nil {
+		t.Fatalf("processMessage() error: %v", err)
+	}
+	if !strings.Contains(resp, "`telegram:1` (current) · 2 msgs") {
+		t.Fatalf("unexpected /sessions output: %s", resp)
+	}
+}
+
+func TestSessionsCommand_RefusesNonAdmin(t *testing.T) {
+	al := newCommandTestLoop(t)
+	al.SetAllowlistManager(&fakeAllowlists{lists: map[string][]string{"telegram": {"111"}}, owner: "999"})
+	al.sessions.AddMessage("discord:5", "user", "private")
+
+	msg := bus.InboundMessage{Channel: "telegram", ChatID: "1", SenderID: "222", SessionKey: "telegram:1", Content: "/sessions"}
+	resp, _ := al.processMessage(context.Background(), msg)
+	if !strings.Contains(resp, "Only the owner") || strings.Contains(resp, "discord:5") {
+		t.Fatalf("non-admin /sessions = %q, want refusal", resp)
+	}
+
+	msg.SenderID = "111"
+	if resp, _ := al.processMessage(context.Background(), msg); !strings.Contains(resp, "discord:5") {
+		t.Fatalf("allowlisted /sessions = %q, want the session list", resp)
+	}
+}
+
+func TestSessionTools_CheckSenderAtCallTime(t *testing.T) {
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{
+				Workspace:         t.TempDir(),
+				Model:             "test-model",
+				MaxTokens:         4096,
+				MaxToolIterations: 10,
+			},
+		},
+	}
+	cfg.Tools.Sessions.Enabled = true
+	al := NewAgentLoop(cfg, bus.NewMessageBus(), &mockProvider{})
+	al.SetAllowlistManager(&fakeAllowlists{lists: map[string][]string{"telegram": {"111"}}, owner: "999"})
+	al.sessions.AddMessage("discord:5", "user", "private")
+
+	tc := providers.ToolCall{ID: "call-1", Name: "session_export", Arguments: map[string]interface{}{"key": "discord:5"}}
+	result := al.executeToolCall(context.Background(), tc, processOptions{Channel: "telegram", ChatID: "1", SenderID: "222"})
+	if !result.IsError || !strings.Contains(result.ForLLM, "only the owner") {
+		t.Fatalf("non-admin session_export = %+v, want refusal", result)
+	}
+
+	result = al.executeToolCall(context.Background(), tc, processOptions{Channel: "telegram", ChatID: "1", SenderID: "111"})
+	if result.IsError {
+		t.Fatalf("allowlisted session_export failed: %s", result.ForLLM)
+	}
+}
+
+func TestClearCommand_RequiresConfirmation(t *testing.T) {
+	al := newCommandTestLoop(t)
+	al.sessions.AddMessage("telegram:1", "user", "hello")
+
+	msg := bus.InboundMessage{Channel: "telegram", ChatID: "1", SessionKey: "telegram:1", Content: "/clear"}
+	resp, _ := al.processMessage(context.Background(), msg)
+
+	if !strings.Contains(resp, "/clear confirm") {
+		t.Fatalf("expected confirmation prompt, got: %s", resp)
+	}
+	if got := len(al.sessions.GetHistory("telegram:1")); got != 1 {
+		t.Fatalf("history cleared without confirmation: %d messages left", got)
+	}
+}
+
+func TestClearCommand_ConfirmClearsAndCancels(t *testing.T) {
+	al := newCommandTestLoop(t)
+	al.sessions.AddMessage("telegram:1", "user", "hello")
+	al.sessions.SetSummary("telegram:1", "old summary")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	al.activeCancel.Store("telegram:1", cancel)
+
+	msg := bus.InboundMessage{Channel: "telegram", ChatID: "1", SessionKey: "telegram:1", Content: "/clear confirm"}
+	resp := al.handleClearCommand(msg, msg.Content)
+
+	if resp != "Session cleared." {
+		t.Fatalf("unexpected response: %s", resp)
+	}
+	if ctx.Err() == nil {
+		t.Error("in-flight request should be cancelled")
+	}
+	if got := len(al.sessions.GetHistory("telegram:1")); got != 0 {
+		t.Errorf("history has %d messages after clear, want 0", got)
+	}
+	if got := al.sessions.GetSummary("telegram:1"); got != "" {
+		t.Errorf("summary = %q after clear, want empty", got)
+	}
+}
+
+func TestTasksCommand_ListsAndCancels(t *testing.T) {
+	al := newCommandTestLoop(t)
+	cs := cron.NewCronService(filepath.Join(t.TempDir(), "jobs.json"), nil)
+	al.SetCronService(cs)
+
+	at := time.Now().Add(time.Hour).UnixMilli()
+	job, err := cs.AddJob("water plants", cron.CronSchedule{Kind: "at", AtMS: &at}, "water plants", true, "telegram", "1")
+	if err != nil {
+		t.Fatalf("AddJob() error: %v", err)
+	}
+
+	msg := bus.InboundMessage{Channel: "telegram", ChatID: "1", SessionKey: "telegram:1", Content: "/tasks"}
+	resp, _ := al.processMessage(context.Background(), msg)
+	if !strings.Contains(resp, "water plants (id: "+job.ID+")") {
+		t.Fatalf("unexpected /tasks output: %s", resp)
+	}
+
+	msg.Content = "/tasks cancel " + job.ID
+	resp, _ = al.processMessage(context.Background(), msg)
+	if !strings.Contains(resp, "Cancelled task 'water plants'") {
+		t.Fatalf("unexpected /tasks cancel output: %s", resp)
+	}
+	if len(cs.UpcomingJobs()) != 0 {
+		t.Fatal("job should be removed from the scheduler")
+	}
+}
+
+func TestTasksCommand_HidesOtherChats(t *testing.T) {
+	al := newCommandTestLoop(t)
+	cs := cron.NewCronService(filepath.Join(t.TempDir(), "jobs.json"), nil)
+	al.SetCronService(cs)
+
+	at := time.Now().Add(time.Hour).UnixMilli()
+	job, err := cs.AddJob("water plants", cron.CronSchedule{Kind: "at", AtMS: &at}, "water plants", true, "telegram", "1")
+	if err != nil {
+		t.Fatalf("AddJob() error: %v", err)
+	}
+
+	msg := bus.InboundMessage{Channel: "telegram", ChatID: "2", SessionKey: "telegram:2", Content: "/tasks"}
+	if resp, _ := al.processMessage(context.Background(), msg); resp != "No upcoming scheduled tasks." {
+		t.Fatalf("/tasks in another chat = %q", resp)
+	}
+
+	msg.Content = "/tasks cancel " + job.ID
+	al.processMessage(context.Background(), msg)
+	if len(cs.UpcomingJobs()) != 1 {
+		t.Fatal("/tasks cancel removed another chat's task")
+	}
+}
+
+func TestWhoamiCommand_ReportsCapabilities(t *testing.T) {
+	al := newCommandTestLoop(t)
+
+	msg := bus.InboundMessage{Channel: "telegram", ChatID: "1", SessionKey: "telegram:1", Content: "/whoami"}
+	resp, err := al.processMessage(context.Background(), msg)
+	if err != nil {
+		t.Fatalf("processMessage() error: %v", err)
+	}
+	for _, want := range []string{
+		"**picoclaw** · model `test-model`",
+		"Failover: off",
+		"Workspace: `" + al.workspace + "`",
+		fmt.Sprintf("Tools (%d): ", al.tools.Count()),
+		"read_file",
+	} {
+		if !strings.Contains(resp, want) {
+			t.Fatalf("/whoami output missing %q:\n%s", want, resp)
+		}
+	}
+	if len(al.sessions.GetHistory("telegram:1")) != 0 {
+		t.Fatal("/whoami should not be sent to the model or saved to history")
+	}
+}
+
+type fakeChannelStatus []channels.Status
+
+func (f fakeChannelStatus) ChannelStatuses() []channels.Status { return f }
+
+func TestStatusCommand_ReportsChannelHealth(t *testing.T) {
+	al := newCommandTestLoop(t)
+	now := time.Now()
+	al.SetChannelStatusProvider(fakeChannelStatus{
+		{Name: "discord", Running: false},
+		{Name: "telegram", Running: true, LastSuccess: now.Add(-time.Minute), LastError: now, LastErrorMsg: "updates channel closed"},
+	})
+
+	msg := bus.InboundMessage{Channel: "telegram", ChatID: "1", SessionKey: "telegram:1", Content: "/status"}
+	resp, err := al.processMessage(context.Background(), msg)
+	if err != nil {
+		t.Fatalf("processMessage() error: %v", err)
+	}
+	for _, want := range []string{
+		"- discord: stopped",
+		"- telegram: degraded · last success 1m0s ago · last error 0s ago: updates channel closed",
+	} {
+		if !strings.Contains(resp, want) {
+			t.Fatalf("/status output missing %q:\n%s", want, resp)
+		}
+	}
+}
+
+func TestFailoverCommand_StatusAndReset(t *testing.T) {
+	workspace := t.TempDir()
+	if err := state.NewManager(workspace).SetFailoverState(state.FailoverState{
+		Mode:             "degraded",
+		PrimaryModel:     "test-model",
+		ActiveModel:      "fallback-model",
+		NextProbeAt:      time.Now().Add(10 * time.Minute),
+		LastSwitchReason: "rate_limited",
+		SwitchEpoch:      1,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{
+				Workspace:         workspace,
+				Model:             "test-model",
+				FallbackModels:    []string{"fallback-model"},
+				MaxTokens:         4096,
+				MaxToolIterations: 10,
+			},
+			Failover: config.AgentFailover{Enabled: true, ProbeSuccessThreshold: 2},
+		},
+	}
+	al := NewAgentLoop(cfg, bus.NewMessageBus(), &mockProvider{})
+	msg := bus.InboundMessage{Channel: "telegram", ChatID: "1", SessionKey: "telegram:1", Content: "/failover"}
+
+	resp, _ := al.processMessage(context.Background(), msg)
+	for _, want := range []string{
+		"**Failover** · degraded",
+		"Active `fallback-model` · primary `test-model`",
+		"Probe successes: 0/2",
+		"Last switch: rate_limited",
+	} {
+		if !strings.Contains(resp, want) {
+			t.Fatalf("/failover output missing %q:\n%s", want, resp)
+		}
+	}
+
+	msg.Content = "/failover reset"
+	resp, _ = al.processMessage(context.Background(), msg)
+	if resp != "Switched back to primary model `test-model` from `fallback-model`." {
+		t.Fatalf("/failover reset = %q", resp)
+	}
+	if !al.failoverMgr.IsUsingPrimary() {
+		t.Fatal("/failover reset did not return to the primary model")
+	}
+	resp, _ = al.processMessage(context.Background(), msg)
+	if !strings.Contains(resp, "Already on the primary model") {
+		t.Fatalf("second /failover reset = %q", resp)
+	}
+}
+
+func TestSkillsCommand_ReloadPicksUpNewSkills(t *testing.T) {
+	al := newCommandTestLoop(t)
+	skillDir := filepath.Join(al.workspace, "skills", "tide-tables")
+	if err := os.MkdirAll(skillDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(skillDir, "SKILL.md"), []byte("---\nname: tide-tables\ndescription: Look up tides\n---\nCheck the tide."), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	run := func(content string) string {
+		t.Helper()
+		msg := bus.InboundMessage{Channel: "telegram", ChatID: "1", SenderID: "999", SessionKey: "telegram:1", Content: content}
+		resp, err := al.processMessage(context.Background(), msg)
+		if err != nil {
+			t.Fatalf("processMessage(%q) error: %v", content, err)
+		}
+		return resp
+	}
+
+	if resp := run("/skills list"); strings.Contains(resp, "tide-tables") {
+		t.Fatalf("skill listed before reload:\n%s", resp)
+	}
+	if strings.Contains(al.contextBuilder.BuildSystemPrompt(), "tide-tables") {
+		t.Fatal("skill in the system prompt before reload")
+	}
+
+	al.SetAllowlistManager(&fakeAllowlists{lists: map[string][]string{"telegram": {"111"}}, owner: "999"})
+	stranger := bus.InboundMessage{Channel: "telegram", ChatID: "1", SenderID: "222", SessionKey: "telegram:1", Content: "/skills reload"}
+	if resp, _ := al.processMessage(context.Background(), stranger); !strings.Contains(resp, "Only the owner") {
+		t.Fatalf("non-admin /skills reload = %q, want refusal", resp)
+	}
+	if strings.Contains(al.contextBuilder.BuildSystemPrompt(), "tide-tables") {
+		t.Fatal("refused /skills reload still loaded the skill")
+	}
+
+	if resp := run("/skills reload"); !strings.Contains(resp, "Added: tide-tables.") {
+		t.Fatalf("/skills reload = %q, want the new skill reported", resp)
+	}
+	if resp := run("/skills"); !strings.Contains(resp, "tide-tables") {
+		t.Fatalf("/skills = %q, want the reloaded skill listed", resp)
+	}
+	if !strings.Contains(al.contextBuilder.BuildSystemPrompt(), "tide-tables") {
+		t.Error("reloaded skill missing from the system prompt")
+	}
+
+	if err := os.RemoveAll(skillDir); err != nil {
+		t.Fatal(err)
+	}
+	if resp := run("/skills reload"); !strings.Contains(resp, "Removed: tide-tables.") {
+		t.Fatalf("/skills reload = %q, want the removed skill reported", resp)
+	}
+	if len(al.sessions.GetHistory("telegram:1")) != 0 {
+		t.Fatal("/skills should not be sent to the model or saved to history")
+	}
+}
+
+func TestSkillsCommand_DisableKeepsSkillOutOfPrompt(t *testing.T) {
+	al := newCommandTestLoop(t)
+	for _, name := range []string{"tide-tables", "moon-phases"} {
+		dir := filepath.Join(al.workspace, "skills", name)
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "SKILL.md"), []byte("---\nname: "+name+"\ndescription: Look things up\n---\nBody."), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	al.contextBuilder.ReloadSkills()
+
+	run := func(content string) string {
+		t.Helper()
+		msg := bus.InboundMessage{Channel: "telegram", ChatID: "1", SenderID: "111", SessionKey: "telegram:1", Content: content}
+		resp, err := al.processMessage(context.Background(), msg)
+		if err != nil {
+			t.Fatalf("processMessage(%q) error: %v", content, err)
+		}
+		return resp
+	}
+
+	al.SetAllowlistManager(&fakeAllowlists{lists: map[string][]string{"telegram": {"111"}}, owner: "999"})
+	stranger := bus.InboundMessage{Channel: "telegram", ChatID: "1", SenderID: "222", SessionKey: "telegram:1", Content: "/skills disable tide-tables"}
+	if resp, _ := al.processMessage(context.Background(), stranger); !strings.Contains(resp, "Only the owner") {
+		t.Fatalf("non-admin /skills disable = %q, want refusal", resp)
+	}
+	if prompt := al.contextBuilder.BuildSystemPrompt(); !strings.Contains(prompt, "tide-tables") {
+		t.Fatal("refused /skills disable still removed the skill")
+	}
+	stranger.Content = "/skills list"
+	if resp, _ := al.processMessage(context.Background(), stranger); !strings.Contains(resp, "tide-tables") {
+		t.Fatalf("/skills list should stay open, got %q", resp)
+	}
+
+	resp := run("/skills disable tide-tables")
+	if !strings.Contains(resp, "Disabled skill `tide-tables`: saves ~") {
+		t.Fatalf("/skills disable = %q", resp)
+	}
+	prompt := al.contextBuilder.BuildSystemPrompt()
+	if strings.Contains(prompt, "tide-tables") || !strings.Contains(prompt, "moon-phases") {
+		t.Fatalf("system prompt should hold only the enabled skill:\n%s", prompt)
+	}
+	if resp := run("/skills list"); !strings.Contains(resp, "Disabled (1): tide-tables") {
+		t.Fatalf("/skills list = %q, want the disabled skill listed", resp)
+	}
+	if resp := run("/skills disable tide-tables"); !strings.Contains(resp, "already disabled") {
+		t.Fatalf("second disable = %q", resp)
+	}
+	if resp := run("/skills disable no-such-skill"); !strings.Contains(resp, "Unknown skill") {
+		t.Fatalf("disable unknown = %q", resp)
+	}
+
+	// The setting survives a restart.
+	if !NewAgentLoop(al.config, bus.NewMessageBus(), &mockProvider{}).state.IsSkillDisabled("tide-tables") {
+		t.Fatal("disabled skill not persisted")
+	}
+
+	if resp := run("/skills enable tide-tables"); !strings.Contains(resp, "Enabled skill `tide-tables`: adds ~") {
+		t.Fatalf("/skills enable = %q", resp)
+	}
+	if !strings.Contains(al.contextBuilder.BuildSystemPrompt(), "tide-tables") {
+		t.Fatal("re-enabled skill missing from the system prompt")
+	}
+}
+
+func TestCleanupCommand_SkipsInFlightAndFreshMedia(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir()) // keep the legacy media dir out of the real temp dir
+	al := newCommandTestLoop(t)
+
+	cache := filepath.Join(al.workspace, "tmp", "media")
+	old := time.Now().Add(-time.Hour)
+	files := map[string]int{"stale.jpg": 2048, "inflight.jpg": 100, "fresh.jpg": 100}
+	for name, size := range files {
+		path := filepath.Join(cache, name)
+		if err := os.WriteFile(path, make([]byte, size), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if name != "fresh.jpg" {
+			os.Chtimes(path, old, old)
+		}
+	}
+	inflight := []string{filepath.Join(cache, "inflight.jpg")}
+	al.acquireTurnMedia(inflight)
+
+	resp, err := al.processMessage(context.Background(), bus.InboundMessage{Channel: "telegram", ChatID: "1", Content: "/cleanup"})
+	if err != nil {
+		t.Fatalf("processMessage() error: %v", err)
+	}
+	if want := "Freed 2.0 KiB (1 file removed), kept 2 in use."; resp != want {
+		t.Fatalf("response = %q, want %q", resp, want)
+	}
+	if _, err := os.Stat(inflight[0]); err != nil {
+		t.Fatalf("in-flight media was removed: %v", err)
+	}
+
+	al.SetAllowlistManager(&fakeAllowlists{lists: map[string][]string{"telegram": {"111"}}, owner: "999"})
+	stale := filepath.Join(cache, "stale2.jpg")
+	if err := os.WriteFile(stale, make([]byte, 10), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	os.Chtimes(stale, old, old)
+	resp, _ = al.processMessage(context.Background(), bus.InboundMessage{Channel: "telegram", ChatID: "1", SenderID: "222", Content: "/cleanup"})
+	if !strings.Contains(resp, "Only the owner") {
+		t.Fatalf("non-admin /cleanup = %q, want refusal", resp)
+	}
+	if _, err := os.Stat(stale); err != nil {
+		t.Fatalf("refused /cleanup still removed media: %v", err)
+	}
+
+	al.releaseTurnMedia(inflight)
+	if !al.keepCachedMedia(filepath.Join(cache, "fresh.jpg"), mustStat(t, filepath.Join(cache, "fresh.jpg"))) {
+		t.Fatal("freshly downloaded media should be kept")
+	}
+	if al.keepCachedMedia(inflight[0], mustStat(t, inflight[0])) {
+		t.Fatal("released media should no longer be kept")
+	}
+}
+
+func mustStat(t *testing.T, path string) os.FileInfo {
+	t.Helper()
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return info
+}
+
+// countingProvider numbers its replies and records the last user message.
+type countingProvider struct {
+	calls    int
+	lastUser string
+}
+
+func (p *countingProvider) Chat(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, model string, opts map[string]interface{}) (*providers.LLMResponse, error) {
+	p.calls++
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role == "user" {
+			p.lastUser = messages[i].Content
+			break
+		}
+	}
+	return &providers.LLMResponse{Content: fmt.Sprintf("answer %d", p.calls), FinishReason: "stop"}, nil
+}
+
+func (p *countingProvider) GetDefaultModel() string {
+	return "counting-model"
+}
+
+func TestRetryCommand_ReprocessesLastUserMessage(t *testing.T) {
+	provider := &countingProvider{}
+	al := newRefusalTestLoop(t, provider, false)
+	msg := bus.InboundMessage{Channel: "telegram", ChatID: "1", SenderID: "u", SessionKey: "telegram:1"}
+
+	msg.Content = "/retry"
+	if resp, _ := al.processMessage(context.Background(), msg); resp != "Nothing to retry yet." {
+		t.Fatalf("/retry on empty session = %q", resp)
+	}
+
+	msg.Content = "what is the weather"
+	if resp, _ := al.processMessage(context.Background(), msg); resp != "answer 1" {
+		t.Fatalf("first reply = %q, want answer 1", resp)
+	}
+
+	msg.Content = "/retry"
+	resp, err := al.processMessage(context.Background(), msg)
+	if err != nil {
+		t.Fatalf("/retry error: %v", err)
+	}
+	if resp != "answer 2" || provider.lastUser != "what is the weather" {
+		t.Fatalf("/retry = %q with user message %q, want answer 2 for the stored message", resp, provider.lastUser)
+	}
+
+	history := al.sessions.GetHistory("telegram:1")
+	if len(history) != 2 || history[0].Content != "what is the weather" || history[1].Content != "answer 2" {
+		t.Errorf("history after /retry = %+v, want only the retried exchange", history)
+	}
+}
+
+// fakeAllowlists is an in-memory AllowlistManager for /allow tests.
+type fakeAllowlists struct {
+	lists map[string][]string
+	owner string
+}
+
+func (f *fakeAllowlists) AllowList(channel string) ([]string, error) {
+	return append([]string(nil), f.lists[channel]...), nil
+}
+
+func (f *fakeAllowlists) SetAllowList(channel string, allowList []string) error {
+	f.lists[channel] = allowList
+	return nil
+}
+
+func (f *fakeAllowlists) CanManageAllowList(channel, senderID string) bool {
+	return senderID == f.owner || slices.Contains(f.lists[channel], senderID)
+}
+
+func TestAllowCommand(t *testing.T) {
+	al := newCommandTestLoop(t)
+	allow := &fakeAllowlists{lists: map[string][]string{"telegram": {"111"}}, owner: "999"}
+	run := func(sender, content string) string {
+		t.Helper()
+		msg := bus.InboundMessage{Channel: "telegram", ChatID: "1", SenderID: sender, SessionKey: "telegram:1", Content: content}
+		resp, err := al.processMessage(context.Background(), msg)
+		if err != nil {
+			t.Fatalf("%s error: %v", content, err)
+		}
+		return resp
+	}
+
+	if resp := run("111", "/allow"); resp != "Allowlist management is not available." {
+		t.Fatalf("/allow without manager = %q", resp)
+	}
+	al.SetAllowlistManager(allow)
+
+	if resp := run("222", "/allow add 222"); !strings.Contains(resp, "Only the owner") {
+		t.Errorf("unlisted sender /allow add = %q, want refusal", resp)
+	}
+	if resp := run("111", "/allow add 222"); !strings.Contains(resp, "Added `222`") {
+		t.Errorf("/allow add = %q", resp)
+	}
+	if resp := run("999", "/allow list"); !strings.Contains(resp, "(2)") || !strings.Contains(resp, "`222`") {
+		t.Errorf("/allow list = %q", resp)
+	}
+	if resp := run("111", "/allow remove 111"); !strings.Contains(resp, "Removed `111`") {
+		t.Errorf("/allow remove = %q", resp)
+	}
+	if resp := run("222", "/allow remove 222"); !strings.Contains(resp, "Cannot remove the last entry") {
+		t.Errorf("removing the last entry = %q, want refusal", resp)
+	}
+	if got := allow.lists["telegram"]; len(got) != 1 || got[0] != "222" {
+		t.Errorf("allowlist = %v, want [222]", got)
+	}
+}
+
+func TestLogLevelCommand(t *testing.T) {
+	initial := logger.GetLevel()
+	defer logger.SetLevel(initial)
+	logger.SetLevel(logger.INFO)
+
+	al := newCommandTestLoop(t)
+	run := func(sender, content string) string {
+		t.Helper()
+		msg := bus.InboundMessage{Channel: "telegram", ChatID: "1", SenderID: sender, SessionKey: "telegram:1", Content: content}
+		resp, err := al.processMessage(context.Background(), msg)
+		if err != nil {
+			t.Fatalf("%s error: %v", content, err)
+		}
+		return resp
+	}
+
+	if resp := run("111", "/loglevel"); resp != "Log level: INFO" {
+		t.Errorf("/loglevel = %q", resp)
+	}
+	if resp := run("111", "/loglevel loud"); !strings.Contains(resp, "Unknown log level") || logger.GetLevel() != logger.INFO {
+		t.Errorf("/loglevel loud = %q, level %v; want refusal", resp, logger.GetLevel())
+	}
+	if resp := run("111", "/loglevel debug"); !strings.HasPrefix(resp, "Log level: DEBUG (was INFO)") || logger.GetLevel() != logger.DEBUG {
+		t.Errorf("/loglevel debug = %q, level %v", resp, logger.GetLevel())
+	}
+
+	defer logger.ClearComponentLevel("telegram")
+	if resp := run("111", "/loglevel telegram warn"); !strings.HasPrefix(resp, "Log level for `telegram`: WARN") {
+		t.Errorf("/loglevel telegram warn = %q", resp)
+	}
+	if resp := run("111", "/loglevel"); resp != "Log level: DEBUG\nOverrides: telegram=WARN" {
+		t.Errorf("/loglevel with override = %q", resp)
+	}
+	if resp := run("111", "/loglevel telegram default"); !strings.Contains(resp, "follows the global level") {
+		t.Errorf("/loglevel telegram default = %q", resp)
+	}
+	if got := logger.ComponentLevels(); len(got) != 0 {
+		t.Errorf("overrides after default = %v, want none", got)
+	}
+
+	al.SetAllowlistManager(&fakeAllowlists{lists: map[string][]string{"telegram": {"111"}}, owner: "999"})
+	if resp := run("222", "/loglevel error"); !strings.Contains(resp, "Only the owner") || logger.GetLevel() != logger.DEBUG {
+		t.Errorf("unlisted sender /loglevel error = %q, level %v; want refusal", resp, logger.GetLevel())
+	}
+}
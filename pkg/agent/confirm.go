@@ -0,0 +1,152 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/providers"
+	"github.com/sipeed/picoclaw/pkg/tools"
+	"github.com/sipeed/picoclaw/pkg/utils"
+)
+
+// Tools listed in tools.confirm need the user's approval. The agent loop
+// handles one message at a time, so it cannot block a turn waiting for the
+// reply: the turn ends with an approval prompt instead, and the user's
+// "approve"/"deny" reply runs (or declines) the held call and continues the
+// turn with its result. Only the sender whose message led to the call can
+// decide it, so in group chats other members cannot approve it.
+
+// confirmationTTL bounds how long an approval prompt stays answerable.
+const confirmationTTL = 15 * time.Minute
+
+// pendingConfirmation is a tool call waiting for the user's decision.
+type pendingConfirmation struct {
+	ID       string // Correlation ID of the turn that requested the call
+	ToolCall providers.ToolCall
+	Created  time.Time
+}
+
+// requiresConfirmation reports whether the named tool needs approval.
+func (al *AgentLoop) requiresConfirmation(name string) bool {
+	return al.confirmTools[name]
+}
+
+// holdForConfirmation records calls[0] as pending for the chat and sender
+// and returns the approval prompt plus tool messages answering every held
+// call, so the session history stays valid while the turn is paused.
+func (al *AgentLoop) holdForConfirmation(calls []providers.ToolCall, opts processOptions) (string, []providers.Message) {
+	tc := calls[0]
+	id := opts.CorrelationID
+	if id == "" {
+		id = fmt.Sprintf("%d", time.Now().UnixMilli())
+	}
+
+	al.confirmMu.Lock()
+	al.confirmations[confirmationKey(opts.Channel, opts.ChatID, opts.SenderID)] = &pendingConfirmation{
+		ID:       id,
+		ToolCall: tc,
+		Created:  time.Now(),
+	}
+	al.confirmMu.Unlock()
+
+	logger.InfoCF("agent", "Tool call awaiting user approval",
+		map[string]interface{}{
+			"tool":           tc.Name,
+			"confirmation":   id,
+			"session_key":    opts.SessionKey,
+			"correlation_id": opts.CorrelationID,
+		})
+
+	held := make([]providers.Message, 0, len(calls))
+	held = append(held, providers.Message{
+		Role:       "tool",
+		Content:    fmt.Sprintf("Not run yet: waiting for the user to approve this %s call. Their decision and the result will arrive in their next message.", tc.Name),
+		ToolCallID: tc.ID,
+	})
+	for _, skipped := range calls[1:] {
+		held = append(held, providers.Message{
+			Role:       "tool",
+			Content:    fmt.Sprintf("Not run: skipped while waiting for the user to approve %s. Call it again if it is still needed.", tc.Name),
+			ToolCallID: skipped.ID,
+		})
+	}
+
+	argsJSON, _ := json.Marshal(tc.Arguments)
+	prompt := fmt.Sprintf("⚠️ Approval needed: %s\n`%s` %s\n\nReply **approve** to run it or **deny** to skip it.",
+		summarizeToolCallForPlan(tc), tc.Name, utils.Truncate(string(argsJSON), 300))
+	return prompt, held
+}
+
+// resolveConfirmation handles an "approve"/"deny" reply (optionally with
+// the confirmation ID, with or without a leading slash). When a pending
+// call is resolved it returns the message content to continue the turn
+// with; otherwise reply is sent back directly. handled is false for
+// messages that are not a decision.
+func (al *AgentLoop) resolveConfirmation(ctx context.Context, msg bus.InboundMessage, trimmed string) (content, reply string, handled bool) {
+	fields := strings.Fields(strings.ToLower(trimmed))
+	if len(fields) == 0 || len(fields) > 2 {
+		return "", "", false
+	}
+	decision := strings.TrimPrefix(fields[0], "/")
+	if decision != "approve" && decision != "deny" {
+		return "", "", false
+	}
+	explicit := strings.HasPrefix(fields[0], "/")
+
+	key := confirmationKey(msg.Channel, msg.ChatID, msg.SenderID)
+	al.confirmMu.Lock()
+	pending := al.confirmations[key]
+	if pending == nil {
+		al.confirmMu.Unlock()
+		if explicit {
+			return "", "Nothing is waiting for approval.", true
+		}
+		return "", "", false
+	}
+	if len(fields) == 2 && fields[1] != strings.ToLower(pending.ID) {
+		al.confirmMu.Unlock()
+		return "", fmt.Sprintf("No pending approval with id %s.", fields[1]), true
+	}
+	delete(al.confirmations, key)
+	al.confirmMu.Unlock()
+
+	tc := pending.ToolCall
+	if time.Since(pending.Created) > confirmationTTL {
+		return "", fmt.Sprintf("The approval request for `%s` expired. Ask again if you still want it.", tc.Name), true
+	}
+
+	var result *tools.ToolResult
+	if decision == "approve" {
+		result = al.executeToolCall(ctx, tc, processOptions{Channel: msg.Channel, ChatID: msg.ChatID, SenderID: msg.SenderID})
+	} else {
+		result = tools.ErrorResult(fmt.Sprintf("The user declined to run %s. Do not retry it unless they ask again.", tc.Name))
+	}
+	logger.InfoCF("agent", "Tool call approval resolved",
+		map[string]interface{}{
+			"tool":         tc.Name,
+			"decision":     decision,
+			"confirmation": pending.ID,
+			"is_error":     result.IsError,
+		})
+
+	resultContent := result.ForLLM
+	if resultContent == "" && result.Err != nil {
+		resultContent = result.Err.Error()
+	}
+	verb := "approved"
+	if decision == "deny" {
+		verb = "declined"
+	}
+	return fmt.Sprintf("%s\n\n[The user %s the pending %s call. Tool result:]\n%s", decision, verb, tc.Name, resultContent), "", true
+}
+
+// confirmationKey identifies a pending confirmation by chat and by the
+// sender allowed to decide it.
+func confirmationKey(channel, chatID, senderID string) string {
+	return fmt.Sprintf("%s:%s:%s", channel, chatID, senderID)
+}
@@ -0,0 +1,142 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/providers"
+)
+
+func newConfirmTestLoop(t *testing.T) (*AgentLoop, *toolCallingProvider, *recordingTool) {
+	t.Helper()
+	provider := &toolCallingProvider{toolCalls: []providers.ToolCall{
+		{ID: "c1", Name: "mutate", Arguments: map[string]interface{}{"id": 1}},
+		{ID: "c2", Name: "mutate", Arguments: map[string]interface{}{"id": 2}},
+	}}
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{
+				Workspace:         t.TempDir(),
+				Model:             "test-model",
+				MaxTokens:         4096,
+				MaxToolIterations: 10,
+			},
+		},
+		Tools: config.ToolsConfig{Confirm: []string{"mutate"}},
+	}
+	al := NewAgentLoop(cfg, bus.NewMessageBus(), provider)
+	mutate := &recordingTool{}
+	al.RegisterTool(mutate)
+	return al, provider, mutate
+}
+
+func confirmMsg(content string) bus.InboundMessage {
+	return bus.InboundMessage{Channel: "telegram", ChatID: "1", SenderID: "u", SessionKey: "telegram:1", Content: content, CorrelationID: "corr-1"}
+}
+
+func TestConfirmation_ApproveRunsHeldCall(t *testing.T) {
+	al, provider, mutate := newConfirmTestLoop(t)
+
+	resp, err := al.processMessage(context.Background(), confirmMsg("change things"))
+	if err != nil {
+		t.Fatalf("processMessage() error = %v", err)
+	}
+	if !strings.Contains(resp, "Approval needed") || !strings.Contains(resp, "`mutate`") {
+		t.Fatalf("response = %q, want approval prompt", resp)
+	}
+	if len(mutate.ran) != 0 {
+		t.Fatalf("tool ran before approval: %v", mutate.ran)
+	}
+	// Both calls are answered so the history stays valid for the provider.
+	var toolMsgs int
+	for _, m := range al.sessions.GetHistory("telegram:1") {
+		if m.Role == "tool" {
+			toolMsgs++
+		}
+	}
+	if toolMsgs != 2 {
+		t.Fatalf("history has %d tool messages, want 2", toolMsgs)
+	}
+
+	resp, err = al.processMessage(context.Background(), confirmMsg("approve"))
+	if err != nil {
+		t.Fatalf("processMessage() error = %v", err)
+	}
+	if resp != "done" {
+		t.Fatalf("response = %q, want turn to continue", resp)
+	}
+	if strings.Join(mutate.ran, ",") != "1" {
+		t.Fatalf("tool ran as %v, want only the approved call", mutate.ran)
+	}
+	last := provider.lastMsgs[len(provider.lastMsgs)-1]
+	if !strings.Contains(last.Content, "approved the pending mutate call") || !strings.Contains(last.Content, "mutate:1") {
+		t.Fatalf("continuation = %q, want approval and tool result", last.Content)
+	}
+
+	resp, _ = al.processMessage(context.Background(), confirmMsg("/approve"))
+	if resp != "Nothing is waiting for approval." {
+		t.Fatalf("response = %q, want nothing pending", resp)
+	}
+}
+
+func TestConfirmation_DenyReturnsDeclinedResult(t *testing.T) {
+	al, provider, mutate := newConfirmTestLoop(t)
+	al.processMessage(context.Background(), confirmMsg("change things"))
+
+	resp, _ := al.processMessage(context.Background(), confirmMsg("deny wrong-id"))
+	if resp != "No pending approval with id wrong-id." {
+		t.Fatalf("response = %q, want id mismatch", resp)
+	}
+
+	resp, err := al.processMessage(context.Background(), confirmMsg("Deny corr-1"))
+	if err != nil {
+		t.Fatalf("processMessage() error = %v", err)
+	}
+	if resp != "done" || len(mutate.ran) != 0 {
+		t.Fatalf("response = %q, ran = %v; want declined call not run", resp, mutate.ran)
+	}
+	last := provider.lastMsgs[len(provider.lastMsgs)-1]
+	if !strings.Contains(last.Content, "The user declined to run mutate") {
+		t.Fatalf("continuation = %q, want declined tool result", last.Content)
+	}
+}
+
+func TestConfirmation_ExpiredAndUnrelatedReplies(t *testing.T) {
+	al, _, mutate := newConfirmTestLoop(t)
+	al.processMessage(context.Background(), confirmMsg("change things"))
+
+	// A sentence merely starting with "approve" is a normal message.
+	if _, _, handled := al.resolveConfirmation(context.Background(), confirmMsg(""), "approve of the plan please"); handled {
+		t.Fatal("free-form text should not resolve the confirmation")
+	}
+
+	al.confirmations[confirmationKey("telegram", "1", "u")].Created = time.Now().Add(-2 * confirmationTTL)
+	resp, _ := al.processMessage(context.Background(), confirmMsg("approve"))
+	if !strings.Contains(resp, "expired") || len(mutate.ran) != 0 {
+		t.Fatalf("response = %q, ran = %v; want expired approval not run", resp, mutate.ran)
+	}
+}
+
+func TestConfirmation_OnlyRequesterCanDecide(t *testing.T) {
+	al, _, mutate := newConfirmTestLoop(t)
+	al.processMessage(context.Background(), confirmMsg("change things"))
+
+	other := confirmMsg("/approve")
+	other.SenderID = "someone-else"
+	resp, _ := al.processMessage(context.Background(), other)
+	if resp != "Nothing is waiting for approval." || len(mutate.ran) != 0 {
+		t.Fatalf("response = %q, ran = %v; another member must not approve", resp, mutate.ran)
+	}
+
+	resp, err := al.processMessage(context.Background(), confirmMsg("approve"))
+	if err != nil {
+		t.Fatalf("processMessage() error = %v", err)
+	}
+	if len(mutate.ran) != 1 {
+		t.Fatalf("response = %q, ran = %v; requester's approval should run the call", resp, mutate.ran)
+	}
+}
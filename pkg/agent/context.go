@@ -1,13 +1,16 @@
 package agent
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
 	"runtime"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/sipeed/picoclaw/pkg/agent/memory"
 	"github.com/sipeed/picoclaw/pkg/logger"
 	"github.com/sipeed/picoclaw/pkg/providers"
 	"github.com/sipeed/picoclaw/pkg/skills"
@@ -16,12 +19,45 @@ import (
 )
 
 type ContextBuilder struct {
-	workspace    string
-	skillsLoader *skills.SkillsLoader
-	memory       *MemoryStore
-	tools        *tools.ToolRegistry // Direct reference to tool registry
+	workspace     string
+	skillsLoader  *skills.SkillsLoader
+	memory        *MemoryStore
+	tools         *tools.ToolRegistry // Direct reference to tool registry
+	promptBudget  int                 // System prompt token budget (0 = unlimited)
+	assistantName string              // Name the assistant refers to itself by
+	ocr           utils.OCRFunc       // Extracts text from inbound images (nil = off)
+	ocrMaxChars   int                 // Cap on extracted text per image (0 = unlimited)
+	toolMaxTurns  int                 // Elide tool results older than this many turns (0 = keep all)
+	promptFile    string              // Replaces the built-in identity when set
+	extraFiles    []string            // Bootstrap files loaded after the defaults
+	isTermux      func() bool         // Platform detection, replaceable in tests
+	recall        *memory.Store       // Semantic memory; nil = inject the memory files whole
+	recallTopK    int                 // Memories injected per message
+	recallMin     float64             // Minimum similarity for a memory to be injected
+
+	skillsMu     sync.RWMutex
+	loadedSkills []skills.SkillInfo     // Skills found by the last scan; see ReloadSkills
+	skillEnabled func(name string) bool // Skills left out of the prompt return false (nil = all enabled)
 }
 
+// defaultBootstrapFiles are loaded from the workspace when present.
+var defaultBootstrapFiles = []string{
+	"AGENTS.md",
+	"SOUL.md",
+	"USER.md",
+	"IDENTITY.md",
+}
+
+// recallTimeout bounds semantic recall while building a prompt; on timeout
+// the memory files are injected whole instead.
+const recallTimeout = 10 * time.Second
+
+// elidedToolResult replaces the content of tool results past toolMaxTurns.
+const elidedToolResult = "[earlier tool output elided]"
+
+// defaultAssistantName is used when no assistant name is configured.
+const defaultAssistantName = "picoclaw"
+
 func getGlobalConfigDir() string {
 	home, err := os.UserHomeDir()
 	if err != nil {
@@ -37,11 +73,60 @@ func NewContextBuilder(workspace string) *ContextBuilder {
 	builtinSkillsDir := filepath.Join(wd, "skills")
 	globalSkillsDir := filepath.Join(getGlobalConfigDir(), "skills")
 
-	return &ContextBuilder{
-		workspace:    workspace,
-		skillsLoader: skills.NewSkillsLoader(workspace, globalSkillsDir, builtinSkillsDir),
-		memory:       NewMemoryStore(workspace),
+	cb := &ContextBuilder{
+		workspace:     workspace,
+		skillsLoader:  skills.NewSkillsLoader(workspace, globalSkillsDir, builtinSkillsDir),
+		memory:        NewMemoryStore(workspace),
+		assistantName: defaultAssistantName,
+		isTermux:      utils.IsTermux,
 	}
+	cb.ReloadSkills()
+	return cb
+}
+
+// ReloadSkills rescans the workspace, global and builtin skills directories
+// so skills added or removed since the last scan show up in the next system
+// prompt without a restart. It returns the skills found.
+func (cb *ContextBuilder) ReloadSkills() []skills.SkillInfo {
+	found := cb.skillsLoader.ListSkills()
+	cb.skillsMu.Lock()
+	cb.loadedSkills = found
+	cb.skillsMu.Unlock()
+	return append([]skills.SkillInfo(nil), found...)
+}
+
+// listSkills returns the skills found by the last scan.
+func (cb *ContextBuilder) listSkills() []skills.SkillInfo {
+	cb.skillsMu.RLock()
+	defer cb.skillsMu.RUnlock()
+	return append([]skills.SkillInfo(nil), cb.loadedSkills...)
+}
+
+// SetSkillFilter limits the skills loaded into the system prompt to those
+// for which enabled returns true. Disabled skills stay on disk and listed.
+func (cb *ContextBuilder) SetSkillFilter(enabled func(name string) bool) {
+	cb.skillEnabled = enabled
+}
+
+// enabledSkills returns the skills from the last scan that are enabled.
+func (cb *ContextBuilder) enabledSkills() []skills.SkillInfo {
+	all := cb.listSkills()
+	if cb.skillEnabled == nil {
+		return all
+	}
+	enabled := all[:0]
+	for _, s := range all {
+		if cb.skillEnabled(s.Name) {
+			enabled = append(enabled, s)
+		}
+	}
+	return enabled
+}
+
+// skillsSummaryTokens estimates the tokens the enabled skills add to every
+// system prompt.
+func (cb *ContextBuilder) skillsSummaryTokens() int {
+	return estimatePromptTokens(skills.BuildSkillsSummaryFor(cb.enabledSkills()))
 }
 
 // SetToolsRegistry sets the tools registry for dynamic tool summary generation.
@@ -49,7 +134,91 @@ func (cb *ContextBuilder) SetToolsRegistry(registry *tools.ToolRegistry) {
 	cb.tools = registry
 }
 
+// SetSystemPromptBudget sets the token budget for the system prompt.
+// When exceeded, memory, skills and bootstrap files are trimmed in that order.
+func (cb *ContextBuilder) SetSystemPromptBudget(tokens int) {
+	cb.promptBudget = tokens
+}
+
+// SetAssistantName sets the name used for self-reference in the identity
+// section. An empty name restores the default.
+func (cb *ContextBuilder) SetAssistantName(name string) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		name = defaultAssistantName
+	}
+	cb.assistantName = name
+}
+
+// SetToolResultMaxTurns elides the content of tool results older than turns
+// user turns in BuildMessages. The messages themselves stay so every tool
+// call keeps its result. 0 keeps all tool output.
+func (cb *ContextBuilder) SetToolResultMaxTurns(turns int) {
+	cb.toolMaxTurns = turns
+}
+
+// SetOCR enables text extraction from images attached to the current
+// message. The text is added to the message as an "[ocr: ...]" marker, cut
+// to maxChars. A nil ocr disables it.
+func (cb *ContextBuilder) SetOCR(ocr utils.OCRFunc, maxChars int) {
+	cb.ocr = ocr
+	cb.ocrMaxChars = maxChars
+}
+
+// SetSystemPromptFile replaces the built-in identity section with the
+// contents of path (relative paths are under the workspace). The tools
+// section is still appended. An empty path restores the built-in identity.
+func (cb *ContextBuilder) SetSystemPromptFile(path string) {
+	cb.promptFile = strings.TrimSpace(path)
+}
+
+// SetBootstrapFiles adds persona files loaded after AGENTS.md, SOUL.md,
+// USER.md and IDENTITY.md. Relative paths are under the workspace.
+func (cb *ContextBuilder) SetBootstrapFiles(files []string) {
+	cb.extraFiles = files
+}
+
+// resolvePromptPath expands a leading "~/" and resolves relative paths
+// against the workspace.
+// SetSemanticMemory injects the topK memories most relevant to the current
+// message (with a similarity of at least minScore) instead of the whole
+// memory files. A nil store restores whole-file injection.
+func (cb *ContextBuilder) SetSemanticMemory(store *memory.Store, topK int, minScore float64) {
+	cb.recall = store
+	cb.recallTopK = topK
+	cb.recallMin = minScore
+}
+
+func (cb *ContextBuilder) resolvePromptPath(path string) string {
+	if strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			path = filepath.Join(home, path[2:])
+		}
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(cb.workspace, path)
+	}
+	return path
+}
+
 func (cb *ContextBuilder) getIdentity() string {
+	if cb.promptFile != "" {
+		path := cb.resolvePromptPath(cb.promptFile)
+		data, err := os.ReadFile(path)
+		if err == nil {
+			identity := strings.TrimSpace(string(data))
+			if toolsSection := cb.buildToolsSection(); toolsSection != "" {
+				identity += "\n\n" + toolsSection
+			}
+			return identity
+		}
+		logger.WarnCF("agent", "Failed to read system prompt file, using built-in identity",
+			map[string]interface{}{
+				"path":  path,
+				"error": err.Error(),
+			})
+	}
+
 	now := time.Now().Format("2006-01-02 15:04 (Monday)")
 	workspacePath, _ := filepath.Abs(filepath.Join(cb.workspace))
 	runtime := fmt.Sprintf("%s %s, Go %s", runtime.GOOS, runtime.GOARCH, runtime.Version())
@@ -57,9 +226,9 @@ func (cb *ContextBuilder) getIdentity() string {
 	// Build tools section dynamically
 	toolsSection := cb.buildToolsSection()
 
-	return fmt.Sprintf(`# picoclaw 🦞
+	return fmt.Sprintf(`# %s 🦞
 
-You are picoclaw, a helpful AI assistant.
+You are %s, a helpful AI assistant.
 
 ## Current Time
 %s
@@ -67,6 +236,8 @@ You are picoclaw, a helpful AI assistant.
 ## Runtime
 %s
 
+%s
+
 ## Workspace
 Your workspace is at: %s
 - Memory: %s/memory/MEMORY.md
@@ -77,14 +248,81 @@ Your workspace is at: %s
 
 ## Important Rules
 
-1. **ALWAYS use tools** - When you need to perform an action (schedule reminders, send messages, execute commands, etc.), you MUST call the appropriate tool. Do NOT just say you'll do it or pretend to do it.
+1. **ALWAYS use tools** - When you need to perform an action listed under What You Can Do, you MUST call the appropriate tool. If it is not listed, you cannot do it; say so. Do NOT just say you'll do it or pretend to do it.
 
 2. **Be helpful and accurate** - When using tools, briefly explain what you're doing.
 
-3. **Memory** - When remembering something, write to %s/memory/MEMORY.md
+3. **Memory** - Long-term memory lives in %s/memory/MEMORY.md; update it with the memory tool when that tool is available.
 
 4. **Vision** - You can see images. When users send photos, the images are included in the message as base64-encoded data. Describe, analyze, or answer questions about them directly — do NOT say you cannot see images.`,
-		now, runtime, workspacePath, workspacePath, workspacePath, workspacePath, toolsSection, workspacePath)
+		cb.assistantName, cb.assistantName, now, runtime, cb.platformSection(), workspacePath, workspacePath, workspacePath, workspacePath, toolsSection, workspacePath)
+}
+
+// platformSection describes the host so the model only claims abilities it
+// has: phone tools and SMS forwarding exist only under Termux on Android.
+func (cb *ContextBuilder) platformSection() string {
+	if cb.isTermux() {
+		return `## Platform
+You are running on an Android phone inside Termux. Phone features work through Termux:API tools, and new SMS may be forwarded to you.`
+	}
+
+	host := runtime.GOOS + " host"
+	switch runtime.GOOS {
+	case "linux":
+		host = "a Linux host"
+	case "darwin":
+		host = "a macOS host"
+	case "windows":
+		host = "a Windows host"
+	}
+	return fmt.Sprintf(`## Platform
+You are running on %s, not a phone. You cannot place calls, send or read SMS, or control a device screen; say so instead of claiming to.`, host)
+}
+
+// capabilityGroups maps what the assistant can do to the tools that do it.
+// A capability is advertised only when one of its tools is registered, so
+// the prompt never promises actions the registry cannot perform.
+var capabilityGroups = []struct {
+	label string
+	tools []string
+}{
+	{"Read, write and edit files in the workspace", []string{"read_file", "write_file", "edit_file", "append_file", "undo_file", "list_dir"}},
+	{"Extract text from PDF and DOCX documents", []string{"read_document"}},
+	{"Remember facts in long-term memory", []string{"memory"}},
+	{"Run shell commands", []string{"exec"}},
+	{"Search the web", []string{"web_search"}},
+	{"Fetch web pages", []string{"web_fetch"}},
+	{"Send messages and files to chats", []string{"message", "send_file"}},
+	{"Schedule reminders and recurring tasks", []string{"cron"}},
+	{"Delegate work to subagents", []string{"spawn", "subagent"}},
+	{"Transcribe audio attachments", []string{"transcribe_attachment"}},
+	{"Talk to I2C and SPI hardware", []string{"i2c", "spi"}},
+	{"Read the phone's battery, sensors and location", []string{"battery_status", "device_sensors", "location"}},
+	{"Use the phone clipboard", []string{"clipboard_get", "clipboard_set"}},
+	{"Show phone notifications", []string{"notify"}},
+	{"Take photos with the phone camera", []string{"camera_photo"}},
+}
+
+// buildCapabilities lists the capabilities backed by registered tools.
+func (cb *ContextBuilder) buildCapabilities() string {
+	registered := make(map[string]bool)
+	for _, name := range cb.tools.List() {
+		registered[name] = true
+	}
+
+	var lines []string
+	for _, group := range capabilityGroups {
+		for _, name := range group.tools {
+			if registered[name] {
+				lines = append(lines, "- "+group.label)
+				break
+			}
+		}
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+	return "## What You Can Do\n\n" + strings.Join(lines, "\n") + "\n\n"
 }
 
 func (cb *ContextBuilder) buildToolsSection() string {
@@ -98,6 +336,7 @@ func (cb *ContextBuilder) buildToolsSection() string {
 	}
 
 	var sb strings.Builder
+	sb.WriteString(cb.buildCapabilities())
 	sb.WriteString("## Available Tools\n\n")
 	sb.WriteString("**CRITICAL**: You MUST use tools to perform actions. Do NOT pretend to execute commands or schedule tasks.\n\n")
 	sb.WriteString("You have access to the following tools:\n\n")
@@ -110,50 +349,84 @@ func (cb *ContextBuilder) buildToolsSection() string {
 }
 
 func (cb *ContextBuilder) BuildSystemPrompt() string {
-	parts := []string{}
-
-	// Core identity section
-	parts = append(parts, cb.getIdentity())
+	return cb.buildSystemPrompt(cb.memory.GetMemoryContext())
+}
 
-	// Bootstrap files
-	bootstrapContent := cb.LoadBootstrapFiles()
-	if bootstrapContent != "" {
-		parts = append(parts, bootstrapContent)
+func (cb *ContextBuilder) buildSystemPrompt(memoryContext string) string {
+	sections := systemPromptSections{
+		// Core identity section
+		Identity: cb.getIdentity(),
+		// Bootstrap files
+		Bootstrap: cb.LoadBootstrapFiles(),
+		Skills:    cb.enabledSkills(),
+		// Memory context
+		Memory: memoryContext,
 	}
 
-	// Skills - show summary, AI can read full content with read_file tool
-	skillsSummary := cb.skillsLoader.BuildSkillsSummary()
-	if skillsSummary != "" {
-		parts = append(parts, fmt.Sprintf(`# Skills
-
-The following skills extend your capabilities. To use a skill, read its SKILL.md file using the read_file tool.
-
-%s`, skillsSummary))
+	prompt := renderSystemPrompt(sections, skills.BuildSkillsSummaryFor)
+	if cb.promptBudget <= 0 {
+		return prompt
 	}
-
-	// Memory context
-	memoryContext := cb.memory.GetMemoryContext()
-	if memoryContext != "" {
-		parts = append(parts, "# Memory\n\n"+memoryContext)
+	before := estimatePromptTokens(prompt)
+	if before <= cb.promptBudget {
+		return prompt
 	}
 
-	// Join with "---" separator
-	return strings.Join(parts, "\n\n---\n\n")
+	trimmed := fitSystemPromptBudget(sections, cb.promptBudget, skills.BuildSkillsSummaryFor)
+	prompt = renderSystemPrompt(trimmed, skills.BuildSkillsSummaryFor)
+	logger.InfoCF("agent", "System prompt trimmed to fit token budget",
+		map[string]interface{}{
+			"budget_tokens": cb.promptBudget,
+			"before_tokens": before,
+			"after_tokens":  estimatePromptTokens(prompt),
+			"trimmed":       strings.Join(trimmed.Notes, "; "),
+		})
+	return prompt
 }
 
-func (cb *ContextBuilder) LoadBootstrapFiles() string {
-	bootstrapFiles := []string{
-		"AGENTS.md",
-		"SOUL.md",
-		"USER.md",
-		"IDENTITY.md",
+// memoryContext returns the memory section for a prompt answering query:
+// the relevant recalled memories when semantic memory is configured, the
+// whole memory files otherwise or when recall fails.
+func (cb *ContextBuilder) memoryContext(query string) string {
+	if cb.recall == nil || strings.TrimSpace(query) == "" {
+		return cb.memory.GetMemoryContext()
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), recallTimeout)
+	defer cancel()
+	results, err := cb.recall.Recall(ctx, query, cb.recallTopK, cb.recallMin)
+	if err != nil {
+		logger.WarnCF("agent", "Semantic memory recall failed, injecting memory files",
+			map[string]interface{}{
+				"error": err.Error(),
+			})
+		return cb.memory.GetMemoryContext()
 	}
+	if len(results) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString("## Relevant Memories\n\n")
+	for _, r := range results {
+		source := r.Source
+		if source == memory.SourceTurn {
+			source = "earlier conversation, " + r.Created.Format("2006-01-02")
+		}
+		fmt.Fprintf(&sb, "- %s (%s)\n", strings.ReplaceAll(r.Text, "\n", " "), source)
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
 
+func (cb *ContextBuilder) LoadBootstrapFiles() string {
 	var result string
-	for _, filename := range bootstrapFiles {
-		filePath := filepath.Join(cb.workspace, filename)
+	seen := make(map[string]bool)
+	for _, filename := range append(append([]string{}, defaultBootstrapFiles...), cb.extraFiles...) {
+		filePath := cb.resolvePromptPath(filename)
+		if seen[filePath] {
+			continue
+		}
+		seen[filePath] = true
 		if data, err := os.ReadFile(filePath); err == nil {
-			result += fmt.Sprintf("## %s\n\n%s\n\n", filename, string(data))
+			result += fmt.Sprintf("## %s\n\n%s\n\n", filepath.Base(filename), string(data))
 		}
 	}
 
@@ -163,7 +436,7 @@ func (cb *ContextBuilder) LoadBootstrapFiles() string {
 func (cb *ContextBuilder) BuildMessages(history []providers.Message, summary string, currentMessage string, media []string, channel, chatID string) []providers.Message {
 	messages := []providers.Message{}
 
-	systemPrompt := cb.BuildSystemPrompt()
+	systemPrompt := cb.buildSystemPrompt(cb.memoryContext(currentMessage))
 
 	// Add Current Session info if provided
 	if channel != "" && chatID != "" {
@@ -208,10 +481,11 @@ func (cb *ContextBuilder) BuildMessages(history []providers.Message, summary str
 		Content: systemPrompt,
 	})
 
-	messages = append(messages, history...)
+	messages = append(messages, cb.elideOldToolResults(history)...)
 
 	currentMsg := providers.Message{Role: "user", Content: currentMessage}
 	if len(media) > 0 {
+		currentMsg.Content += cb.ocrMarkers(media)
 		images := utils.ProcessMediaImages(media)
 		if len(images) > 0 {
 			currentMsg.Media = make([]providers.MediaImage, len(images))
@@ -230,6 +504,66 @@ func (cb *ContextBuilder) BuildMessages(history []providers.Message, summary str
 	return messages
 }
 
+// elideOldToolResults returns history with the content of tool results
+// older than toolMaxTurns replaced by a placeholder. A tool result's age is
+// the number of user messages after it, counting the current one. history
+// itself is not modified.
+func (cb *ContextBuilder) elideOldToolResults(history []providers.Message) []providers.Message {
+	if cb.toolMaxTurns <= 0 {
+		return history
+	}
+
+	out := make([]providers.Message, len(history))
+	copy(out, history)
+	turns := 1 // The current message starts a new turn
+	elided := 0
+	for i := len(out) - 1; i >= 0; i-- {
+		switch out[i].Role {
+		case "user":
+			turns++
+		case "tool":
+			if turns > cb.toolMaxTurns && len(out[i].Content) > len(elidedToolResult) {
+				out[i].Content = elidedToolResult
+				elided++
+			}
+		}
+	}
+	if elided > 0 {
+		logger.DebugCF("agent", "Elided old tool results",
+			map[string]interface{}{"count": elided, "max_turns": cb.toolMaxTurns})
+	}
+	return out
+}
+
+// ocrMarkers returns an "[ocr: ...]" line for each image in media that has
+// text, or "" when OCR is off.
+func (cb *ContextBuilder) ocrMarkers(media []string) string {
+	if cb.ocr == nil {
+		return ""
+	}
+	var sb strings.Builder
+	for _, p := range media {
+		if !utils.IsImageFile(p) {
+			continue
+		}
+		text, err := cb.ocr(p)
+		if err != nil {
+			logger.WarnCF("agent", "OCR failed, sending image only",
+				map[string]interface{}{"path": p, "error": err.Error()})
+			continue
+		}
+		text = strings.TrimSpace(text)
+		if text == "" {
+			continue
+		}
+		if cb.ocrMaxChars > 0 {
+			text = utils.Truncate(text, cb.ocrMaxChars)
+		}
+		sb.WriteString("\n[ocr: " + text + "]")
+	}
+	return sb.String()
+}
+
 func (cb *ContextBuilder) AddToolResult(messages []providers.Message, toolCallID, toolName, result string) []providers.Message {
 	messages = append(messages, providers.Message{
 		Role:       "tool",
@@ -250,7 +584,7 @@ func (cb *ContextBuilder) AddAssistantMessage(messages []providers.Message, cont
 }
 
 func (cb *ContextBuilder) loadSkills() string {
-	allSkills := cb.skillsLoader.ListSkills()
+	allSkills := cb.enabledSkills()
 	if len(allSkills) == 0 {
 		return ""
 	}
@@ -268,16 +602,35 @@ func (cb *ContextBuilder) loadSkills() string {
 	return "# Skill Definitions\n\n" + content
 }
 
-// GetSkillsInfo returns information about loaded skills.
+// GetSkillsInfo returns information about loaded skills: "names" are the
+// enabled ones, "disabled" those turned off.
 func (cb *ContextBuilder) GetSkillsInfo() map[string]interface{} {
-	allSkills := cb.skillsLoader.ListSkills()
-	skillNames := make([]string, 0, len(allSkills))
-	for _, s := range allSkills {
-		skillNames = append(skillNames, s.Name)
+	skillNames := cb.skillNames()
+	enabled := make(map[string]bool, len(skillNames))
+	for _, name := range skillNames {
+		enabled[name] = true
+	}
+	all := cb.listSkills()
+	disabled := make([]string, 0, len(all)-len(skillNames))
+	for _, s := range all {
+		if !enabled[s.Name] {
+			disabled = append(disabled, s.Name)
+		}
 	}
 	return map[string]interface{}{
-		"total":     len(allSkills),
-		"available": len(allSkills),
+		"total":     len(all),
+		"available": len(skillNames),
 		"names":     skillNames,
+		"disabled":  disabled,
+	}
+}
+
+// skillNames returns the names of all enabled skills.
+func (cb *ContextBuilder) skillNames() []string {
+	allSkills := cb.enabledSkills()
+	names := make([]string, 0, len(allSkills))
+	for _, s := range allSkills {
+		names = append(names, s.Name)
 	}
+	return names
 }
@@ -0,0 +1,259 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/agent/memory"
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/providers"
+	"github.com/sipeed/picoclaw/pkg/tools"
+)
+
+func TestBuildSystemPrompt_UsesConfiguredAssistantName(t *testing.T) {
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{
+				Workspace:     t.TempDir(),
+				Model:         "test-model",
+				AssistantName: "Jarvis",
+			},
+		},
+	}
+	al := NewAgentLoop(cfg, bus.NewMessageBus(), &mockProvider{})
+
+	prompt := al.contextBuilder.BuildSystemPrompt()
+
+	if !strings.Contains(prompt, "# Jarvis") || !strings.Contains(prompt, "You are Jarvis, a helpful AI assistant.") {
+		t.Fatalf("configured assistant name missing from identity:\n%s", prompt)
+	}
+	if strings.Contains(prompt, "You are picoclaw") {
+		t.Fatal("default name should be replaced")
+	}
+	if !strings.Contains(prompt, "## Available Tools") || !strings.Contains(prompt, "`read_file`") {
+		t.Fatal("tools section should be unaffected by the assistant name")
+	}
+}
+
+func TestBuildSystemPrompt_SystemPromptFileAndBootstrapFiles(t *testing.T) {
+	workspace := t.TempDir()
+	os.WriteFile(filepath.Join(workspace, "prompt.md"), []byte("You are Ada, a terse ops assistant.\n"), 0644)
+	os.WriteFile(filepath.Join(workspace, "SOUL.md"), []byte("Be kind."), 0644)
+	os.MkdirAll(filepath.Join(workspace, "persona"), 0755)
+	os.WriteFile(filepath.Join(workspace, "persona", "STYLE.md"), []byte("Answer in haiku."), 0644)
+
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{
+				Workspace:        workspace,
+				Model:            "test-model",
+				SystemPromptFile: "prompt.md",
+				BootstrapFiles:   []string{"persona/STYLE.md", "SOUL.md"},
+			},
+		},
+	}
+	al := NewAgentLoop(cfg, bus.NewMessageBus(), &mockProvider{})
+
+	prompt := al.contextBuilder.BuildSystemPrompt()
+	if !strings.HasPrefix(prompt, "You are Ada, a terse ops assistant.") {
+		t.Fatalf("system prompt file should replace the identity:\n%s", prompt)
+	}
+	if strings.Contains(prompt, "a helpful AI assistant") {
+		t.Error("built-in identity still present")
+	}
+	if !strings.Contains(prompt, "## Available Tools") {
+		t.Error("tools section should still be appended")
+	}
+	if !strings.Contains(prompt, "## STYLE.md\n\nAnswer in haiku.") {
+		t.Error("extra bootstrap file missing")
+	}
+	if strings.Count(prompt, "Be kind.") != 1 {
+		t.Error("a default bootstrap file listed again should load once")
+	}
+
+	// A missing file falls back to the built-in identity.
+	al.contextBuilder.SetSystemPromptFile("missing.md")
+	if prompt := al.contextBuilder.BuildSystemPrompt(); !strings.Contains(prompt, "You are picoclaw, a helpful AI assistant.") {
+		t.Fatalf("missing prompt file should fall back to the built-in identity:\n%s", prompt)
+	}
+}
+
+func TestBuildSystemPrompt_PlatformAware(t *testing.T) {
+	cb := NewContextBuilder(t.TempDir())
+
+	cb.isTermux = func() bool { return false }
+	prompt := cb.BuildSystemPrompt()
+	if strings.Contains(prompt, "Android") || !strings.Contains(prompt, "not a phone") {
+		t.Fatalf("non-Termux identity should not claim phone abilities:\n%s", prompt)
+	}
+
+	cb.isTermux = func() bool { return true }
+	prompt = cb.BuildSystemPrompt()
+	if !strings.Contains(prompt, "Android phone inside Termux") || strings.Contains(prompt, "not a phone") {
+		t.Fatalf("Termux identity should describe the phone:\n%s", prompt)
+	}
+}
+
+func TestBuildSystemPrompt_CapabilitiesFollowRegistry(t *testing.T) {
+	workspace := t.TempDir()
+	registry := tools.NewToolRegistry()
+	registry.Register(tools.NewReadFileTool(workspace, true))
+	registry.Register(tools.NewExecTool(workspace, true))
+	cb := NewContextBuilder(workspace)
+	cb.SetToolsRegistry(registry)
+
+	prompt := cb.BuildSystemPrompt()
+	for _, want := range []string{"## What You Can Do", "- Read, write and edit files in the workspace", "- Run shell commands"} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("prompt missing %q", want)
+		}
+	}
+	for _, unwanted := range []string{"Search the web", "Schedule reminders", "phone camera"} {
+		if strings.Contains(prompt, unwanted) {
+			t.Errorf("prompt advertises unregistered capability %q", unwanted)
+		}
+	}
+}
+
+func TestSetAssistantName_EmptyKeepsDefault(t *testing.T) {
+	cb := NewContextBuilder(t.TempDir())
+	cb.SetAssistantName("   ")
+
+	if !strings.Contains(cb.BuildSystemPrompt(), "You are picoclaw, a helpful AI assistant.") {
+		t.Fatal("empty assistant name should fall back to the default")
+	}
+}
+
+func TestBuildMessages_AddsOCRText(t *testing.T) {
+	dir := t.TempDir()
+	receipt := filepath.Join(dir, "receipt.png")
+	blank := filepath.Join(dir, "blank.png")
+	broken := filepath.Join(dir, "broken.png")
+	for _, p := range []string{receipt, blank, broken} {
+		if err := os.WriteFile(p, []byte("\x89PNG\r\n\x1a\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cb := NewContextBuilder(t.TempDir())
+	cb.SetOCR(func(path string) (string, error) {
+		switch path {
+		case receipt:
+			return "  TOTAL 12.50 EUR\n", nil
+		case broken:
+			return "", errors.New("ocr crashed")
+		}
+		return "", nil
+	}, 12)
+
+	messages := cb.BuildMessages(nil, "", "what did I pay?", []string{receipt, blank, broken, filepath.Join(dir, "notes.txt")}, "", "")
+	got := messages[len(messages)-1].Content
+
+	if want := "what did I pay?\n[ocr: TOTAL 12....]"; got != want {
+		t.Fatalf("content = %q, want %q", got, want)
+	}
+}
+
+func TestBuildMessages_NoOCRByDefault(t *testing.T) {
+	img := filepath.Join(t.TempDir(), "photo.png")
+	if err := os.WriteFile(img, []byte("\x89PNG\r\n\x1a\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	messages := NewContextBuilder(t.TempDir()).BuildMessages(nil, "", "look", []string{img}, "", "")
+	if got := messages[len(messages)-1].Content; got != "look" {
+		t.Fatalf("content = %q, want it unchanged without OCR", got)
+	}
+}
+
+// topicEmbedder embeds texts by which of a few topic words they mention.
+type topicEmbedder struct{ err error }
+
+func (e topicEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	if e.err != nil {
+		return nil, e.err
+	}
+	topics := []string{"cat", "bakery", "lisbon"}
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vectors[i] = make([]float32, len(topics))
+		for j, topic := range topics {
+			if strings.Contains(strings.ToLower(text), topic) {
+				vectors[i][j] = 1
+			}
+		}
+	}
+	return vectors, nil
+}
+
+func TestBuildMessages_InjectsOnlyRelevantMemories(t *testing.T) {
+	workspace := t.TempDir()
+	memoryDir := filepath.Join(workspace, "memory")
+	if err := os.MkdirAll(memoryDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(memoryDir, "MEMORY.md"), []byte("## Pets\n\nThe cat is called Miso.\n\n## Work\n\nWorks at a bakery.\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cb := NewContextBuilder(workspace)
+	cb.SetSemanticMemory(memory.NewStore(memoryDir, topicEmbedder{}), 5, 0.5)
+	prompt := cb.BuildMessages(nil, "", "what's my cat called?", nil, "", "")[0].Content
+	if !strings.Contains(prompt, "## Relevant Memories") || !strings.Contains(prompt, "The cat is called Miso. (MEMORY.md)") {
+		t.Fatalf("prompt lacks the relevant memory:\n%s", prompt)
+	}
+	if strings.Contains(prompt, "bakery") {
+		t.Errorf("prompt includes an unrelated memory:\n%s", prompt)
+	}
+
+	// When embedding fails the memory files are injected whole.
+	cb.SetSemanticMemory(memory.NewStore(memoryDir, topicEmbedder{err: errors.New("no network")}), 5, 0.5)
+	prompt = cb.BuildMessages(nil, "", "what's my cat called?", nil, "", "")[0].Content
+	if !strings.Contains(prompt, "Long-term Memory") || !strings.Contains(prompt, "bakery") {
+		t.Errorf("prompt did not fall back to the memory files:\n%s", prompt)
+	}
+}
+
+func TestBuildMessages_ElidesOldToolResults(t *testing.T) {
+	longOutput := strings.Repeat("file contents ", 50)
+	turn := func(n string) []providers.Message {
+		return []providers.Message{
+			{Role: "user", Content: "question " + n},
+			{Role: "assistant", ToolCalls: []providers.ToolCall{{ID: "call-" + n, Name: "read_file"}}},
+			{Role: "tool", ToolCallID: "call-" + n, Content: longOutput},
+			{Role: "assistant", Content: "answer " + n},
+		}
+	}
+	var history []providers.Message
+	for _, n := range []string{"1", "2", "3"} {
+		history = append(history, turn(n)...)
+	}
+
+	cb := NewContextBuilder(t.TempDir())
+	cb.SetToolResultMaxTurns(2)
+	messages := cb.BuildMessages(history, "", "question 4", nil, "", "")
+
+	toolResults := map[string]string{}
+	for _, m := range messages {
+		if m.Role == "tool" {
+			toolResults[m.ToolCallID] = m.Content
+		}
+	}
+	if len(toolResults) != 3 {
+		t.Fatalf("tool messages = %d, want all 3 kept for pairing", len(toolResults))
+	}
+	if toolResults["call-1"] != elidedToolResult {
+		t.Errorf("turn 1 result (3 turns old) = %q, want elided", toolResults["call-1"])
+	}
+	if toolResults["call-2"] != longOutput || toolResults["call-3"] != longOutput {
+		t.Error("results from the last 2 turns should be kept")
+	}
+	if history[2].Content != longOutput {
+		t.Error("session history must not be modified")
+	}
+}
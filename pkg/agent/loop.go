@@ -13,6 +13,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strings"
 	"sync"
@@ -20,10 +21,13 @@ import (
 	"time"
 	"unicode/utf8"
 
+	"github.com/sipeed/picoclaw/pkg/agent/memory"
 	"github.com/sipeed/picoclaw/pkg/attachments"
 	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/channels"
 	"github.com/sipeed/picoclaw/pkg/config"
 	"github.com/sipeed/picoclaw/pkg/constants"
+	"github.com/sipeed/picoclaw/pkg/cron"
 	"github.com/sipeed/picoclaw/pkg/failover"
 	"github.com/sipeed/picoclaw/pkg/logger"
 	"github.com/sipeed/picoclaw/pkg/providers"
@@ -32,6 +36,8 @@ import (
 	"github.com/sipeed/picoclaw/pkg/tools"
 	"github.com/sipeed/picoclaw/pkg/usage"
 	"github.com/sipeed/picoclaw/pkg/utils"
+	"github.com/sipeed/picoclaw/pkg/voice"
+	"golang.org/x/sync/errgroup"
 )
 
 type AgentLoop struct {
@@ -41,26 +47,52 @@ type AgentLoop struct {
 	model          string
 	contextWindow  int // Maximum context window size in tokens
 	maxIterations  int
+	requestTimeout time.Duration // Overall per-request deadline (0 = none)
+	filterRetry    bool          // Retry once after a content-filter refusal
+	compactTools   bool          // Keep only replies, not tool calls/results, in sessions
 	sessions       *session.SessionManager
 	state          *state.Manager
 	failoverMgr    *failover.Manager
 	contextBuilder *ContextBuilder
 	tools          *tools.ToolRegistry
 	usageStore     *usage.Store
+	cronService    *cron.CronService     // Scheduled jobs for /tasks (optional)
+	allowlists     AllowlistManager      // Channel allowlists for /allow (optional)
+	channelStatus  ChannelStatusProvider // Channel health for /status (optional)
+	synthesizer    voice.Synthesizer     // Spoken replies to voice messages (optional)
+	semanticMemory *memory.Store         // Embedded memories for recall (nil = off)
 	config         *config.Config
 	running        atomic.Bool
-	summarizing    sync.Map // Tracks which sessions are currently being summarized
-	activeCancel   sync.Map // sessionKey -> context.CancelFunc for in-flight requests
+	summarizing    sync.Map       // Tracks which sessions are currently being summarized
+	summaries      sync.WaitGroup // In-flight summarizations, awaited by Shutdown
+	activeCancel   sync.Map       // sessionKey -> context.CancelFunc for in-flight requests
+	mediaMu        sync.Mutex
+	turnMedia      map[string]int  // media path -> number of in-flight turns using it
+	confirmTools   map[string]bool // Tools that need user approval
+	confirmMu      sync.Mutex
+	confirmations  map[string]*pendingConfirmation // channel:chatID -> call awaiting approval
 	probeRunning   atomic.Bool
+	probeLockStale time.Duration // Age after which another process's probe lock is ignored
 	noticeMu       sync.Mutex
-	lastNoticeByEP int64
+	switchNotices  map[switchNoticeKey]time.Time // Chats told about a switch epoch, and when
 }
 
+// switchNoticeKey identifies one chat's notice about one failover switch.
+type switchNoticeKey struct {
+	channel string
+	chatID  string
+	epoch   int64
+}
+
+// maxSwitchNotices bounds switchNotices; the oldest notices are evicted.
+const maxSwitchNotices = 256
+
 // processOptions configures how a message is processed
 type processOptions struct {
 	SessionKey           string        // Session identifier for history/context
 	Channel              string        // Target channel for tool execution
 	ChatID               string        // Target chat ID for tool execution
+	SenderID             string        // User who sent the message, if any
 	UserMessage          string        // User message content (may include prefix)
 	DefaultResponse      string        // Response when LLM returns empty
 	EnableSummary        bool          // Whether to trigger summarization
@@ -72,22 +104,49 @@ type processOptions struct {
 	Media                []string      // Media file paths (images, etc.)
 }
 
+// safeModeDeniedTools are left out of every registry in tools.safe_mode:
+// anything that runs commands, writes files, drives hardware or the phone,
+// or changes workspace state. Patterns cover tool families.
+var safeModeDeniedTools = []string{
+	"exec",
+	"write_file", "edit_file", "append_file", "undo_file", "memory",
+	"import_attachment", "read_document", "zip_files",
+	"i2c", "spi",
+	"notify", "clipboard_set", "camera_photo",
+	"screen_*", "app_*", "sms_*", "call_*",
+	"cleanup_cache", "session_export", "session_import", "usage_export",
+}
+
 // createToolRegistry creates a tool registry with common tools.
 // This is shared between main agent and subagents.
 func createToolRegistry(workspace string, restrict bool, cfg *config.Config, msgBus *bus.MessageBus) *tools.ToolRegistry {
 	registry := tools.NewToolRegistry()
+	if cfg.Tools.SafeMode {
+		registry.Deny(safeModeDeniedTools...)
+	}
 	attachmentStore := attachments.NewStore(workspace)
+	attachmentStore.SetDedup(cfg.Attachments.Dedup)
 
 	// File system tools
 	registry.Register(tools.NewReadFileTool(workspace, restrict))
 	registry.Register(tools.NewWriteFileTool(workspace, restrict))
 	registry.Register(tools.NewListDirTool(workspace, restrict))
-	registry.Register(tools.NewEditFileTool(workspace, restrict))
+	editTool := tools.NewEditFileTool(workspace, restrict)
+	editTool.SetDiffToUser(cfg.Tools.Files.ShowDiff)
+	registry.Register(editTool)
 	registry.Register(tools.NewAppendFileTool(workspace, restrict))
+	registry.Register(tools.NewUndoFileTool(workspace, restrict))
 	registry.Register(tools.NewImportAttachmentTool(workspace, restrict, attachmentStore))
+	registry.Register(tools.NewListAttachmentsTool(attachmentStore))
+	registry.Register(tools.NewFindAttachmentTool(attachmentStore))
+	registry.Register(tools.NewReadDocumentTool(workspace, restrict))
+	registry.Register(tools.NewMemoryTool(workspace))
 
 	// Shell execution
-	registry.Register(tools.NewExecTool(workspace, restrict))
+	execTool := tools.NewExecTool(workspace, restrict)
+	execTool.SetEnv(cfg.Tools.Exec.DefaultEnv, cfg.Tools.Exec.SecretEnv)
+	execTool.SetCommandLists(cfg.Tools.Exec.AllowedCommands, cfg.Tools.Exec.BlockedCommands)
+	registry.Register(execTool)
 
 	if searchTool := tools.NewWebSearchTool(tools.WebSearchToolOptions{
 		BraveAPIKey:          cfg.Tools.Web.Brave.APIKey,
@@ -104,6 +163,17 @@ func createToolRegistry(workspace string, restrict bool, cfg *config.Config, msg
 	registry.Register(tools.NewI2CTool())
 	registry.Register(tools.NewSPITool())
 
+	// Phone tools via Termux:API - only offered when running in Termux
+	if utils.IsTermux() {
+		registry.Register(tools.NewBatteryStatusTool())
+		registry.Register(tools.NewSensorTool())
+		registry.Register(tools.NewNotifyTool())
+		registry.Register(tools.NewClipboardGetTool())
+		registry.Register(tools.NewClipboardSetTool())
+		registry.Register(tools.NewLocationTool())
+		registry.Register(tools.NewCameraPhotoTool(workspace, restrict))
+	}
+
 	// Message tool - available to both agent and subagent
 	// Subagent uses it to communicate directly with user
 	messageTool := tools.NewMessageTool()
@@ -130,6 +200,14 @@ func createToolRegistry(workspace string, restrict bool, cfg *config.Config, msg
 	})
 	registry.Register(sendFileTool)
 
+	// Zip tool - bundles workspace files for send_file
+	registry.Register(tools.NewZipFilesTool(workspace))
+
+	// Tools held for approval are destructive by definition, so the model
+	// should expect that too.
+	registry.MarkDangerous(cfg.Tools.Dangerous...)
+	registry.MarkDangerous(cfg.Tools.Confirm...)
+
 	return registry
 }
 
@@ -145,22 +223,28 @@ func NewAgentLoop(cfg *config.Config, msgBus *bus.MessageBus, provider providers
 		logger.InfoCF("agent", "Configured media cache directory",
 			map[string]interface{}{"dir": mediaCacheDir})
 	}
+	utils.SetImageDownscale(cfg.Tools.Media.MaxImageDimension, cfg.Tools.Media.JPEGQuality)
 
 	restrict := cfg.Agents.Defaults.RestrictToWorkspace
 
 	// Create tool registry for main agent
 	toolsRegistry := createToolRegistry(workspace, restrict, cfg, msgBus)
 
-	// Register MCP-discovered tools (best effort; continue on per-server failures)
-	mcpTools, mcpErr := tools.LoadMCPTools(context.Background(), cfg.Tools.MCP, workspace)
-	if mcpErr != nil {
-		logger.WarnCF("agent", "Some MCP servers failed to load",
-			map[string]interface{}{
-				"error": mcpErr.Error(),
-			})
-	}
-	for _, tool := range mcpTools {
-		toolsRegistry.Register(tool)
+	// Register MCP-discovered tools (best effort; continue on per-server failures).
+	// Safe mode skips them: what they can do is unknown.
+	if cfg.Tools.SafeMode {
+		logger.InfoC("agent", "Safe mode: shell, file-writing, device and MCP tools disabled")
+	} else {
+		mcpTools, mcpErr := tools.LoadMCPTools(context.Background(), cfg.Tools.MCP, workspace)
+		if mcpErr != nil {
+			logger.WarnCF("agent", "Some MCP servers failed to load",
+				map[string]interface{}{
+					"error": mcpErr.Error(),
+				})
+		}
+		for _, tool := range mcpTools {
+			toolsRegistry.Register(tool)
+		}
 	}
 
 	// Create subagent manager with its own tool registry
@@ -178,6 +262,16 @@ func NewAgentLoop(cfg *config.Config, msgBus *bus.MessageBus, provider providers
 	toolsRegistry.Register(subagentTool)
 
 	sessionsManager := session.NewSessionManager(filepath.Join(workspace, "sessions"))
+	loc, err := cfg.Location()
+	if err != nil {
+		logger.WarnCF("agent", "Falling back to local timezone for usage",
+			map[string]interface{}{"error": err.Error()})
+	}
+	usageStore := usage.NewStore(filepath.Join(workspace, "usage"), loc)
+	usageStore.SetPrices(usagePricesFromConfig(cfg))
+	usageStore.SetRetentionDays(cfg.Usage.RetentionDays)
+	usageStore.SetFlushInterval(time.Duration(cfg.Usage.FlushIntervalSeconds) * time.Second)
+	toolsRegistry.Register(tools.NewUsageExportTool(workspace, usageStore))
 
 	// Create state manager for atomic state persistence
 	stateManager := state.NewManager(workspace)
@@ -188,23 +282,77 @@ func NewAgentLoop(cfg *config.Config, msgBus *bus.MessageBus, provider providers
 	// Create context builder and set tools registry
 	contextBuilder := NewContextBuilder(workspace)
 	contextBuilder.SetToolsRegistry(toolsRegistry)
+	contextBuilder.SetSystemPromptBudget(cfg.Agents.Defaults.SystemPromptBudget)
+	contextBuilder.SetToolResultMaxTurns(cfg.Agents.Defaults.ToolResultMaxTurns)
+	contextBuilder.SetAssistantName(cfg.Agents.Defaults.AssistantName)
+	contextBuilder.SetSystemPromptFile(cfg.Agents.Defaults.SystemPromptFile)
+	contextBuilder.SetBootstrapFiles(cfg.Agents.Defaults.BootstrapFiles)
+	contextBuilder.SetSkillFilter(func(name string) bool { return !stateManager.IsSkillDisabled(name) })
+	semanticMemory := newSemanticMemory(cfg, workspace)
+	if semanticMemory != nil {
+		contextBuilder.SetSemanticMemory(semanticMemory, cfg.Agents.Memory.TopK, cfg.Agents.Memory.MinScore)
+	}
+	if ocr := cfg.Tools.OCR; ocr.Enabled {
+		timeout := time.Duration(ocr.TimeoutSeconds) * time.Second
+		if timeout <= 0 {
+			timeout = 30 * time.Second
+		}
+		contextBuilder.SetOCR(utils.NewCommandOCR(ocr.Command, ocr.Args, timeout), ocr.MaxChars)
+	}
+	toolsRegistry.Register(tools.NewStartupInfoTool(toolsRegistry.List, contextBuilder.skillNames))
 
-	return &AgentLoop{
+	al := &AgentLoop{
 		bus:            msgBus,
 		provider:       provider,
 		workspace:      workspace,
 		model:          cfg.Agents.Defaults.Model,
 		contextWindow:  cfg.Agents.Defaults.MaxTokens, // Restore context window for summarization
 		maxIterations:  cfg.Agents.Defaults.MaxToolIterations,
+		requestTimeout: time.Duration(cfg.Agents.Defaults.RequestTimeoutSeconds) * time.Second,
+		filterRetry:    cfg.Agents.Defaults.ContentFilterRetry,
+		compactTools:   strings.EqualFold(cfg.Agents.Defaults.SessionToolHistory, "compact"),
+		probeLockStale: time.Duration(cfg.Agents.Failover.ProbeLockStaleSeconds) * time.Second,
 		sessions:       sessionsManager,
 		state:          stateManager,
 		failoverMgr:    failoverManager,
 		contextBuilder: contextBuilder,
+		semanticMemory: semanticMemory,
 		tools:          toolsRegistry,
-		usageStore:     usage.NewStore(filepath.Join(workspace, "usage")),
+		usageStore:     usageStore,
 		config:         cfg,
 		summarizing:    sync.Map{},
+		turnMedia:      make(map[string]int),
+		confirmTools:   make(map[string]bool),
+		confirmations:  make(map[string]*pendingConfirmation),
+		switchNotices:  make(map[switchNoticeKey]time.Time),
+	}
+	for _, name := range cfg.Tools.Confirm {
+		al.confirmTools[strings.TrimSpace(name)] = true
+	}
+
+	utils.SetMediaCacheLimit(int64(cfg.Tools.Media.MaxCacheMB)<<20, al.keepCachedMedia)
+
+	// Cache cleanup is main-agent only and, like /cleanup, limited to the
+	// owner and allowlisted users.
+	toolsRegistry.Register(tools.NewCleanupCacheTool(al.mediaCacheDirs(), al.keepCachedMedia, al.canManage))
+	// Session backup/migration tools are admin-only: main agent, opt-in,
+	// and refused at call time unless the sender can manage the allowlist.
+	if cfg.Tools.Sessions.Enabled {
+		toolsRegistry.Register(tools.NewSessionExportTool(workspace, restrict, sessionsManager, al.canManage))
+		toolsRegistry.Register(tools.NewSessionImportTool(workspace, restrict, sessionsManager, al.canManage))
+	}
+	toolsRegistry.Register(tools.NewDiagnoseTool(logger.FilePath, al.summarizeDiagnostics))
+	toolsRegistry.Register(tools.NewDebugLogsTool(logger.FilePath))
+	toolsRegistry.Register(tools.NewModelsInfoTool(failoverManager))
+	for _, cb := range cfg.Tools.Callbacks {
+		if cb.Name == "" {
+			continue
+		}
+		toolsRegistry.Register(tools.NewCallbackTool(cb.Name, cb.Description, msgBus, cb.Channel, cb.ChatID,
+			time.Duration(cb.TimeoutSeconds)*time.Second))
 	}
+
+	return al
 }
 
 func (al *AgentLoop) Run(ctx context.Context) error {
@@ -222,7 +370,7 @@ func (al *AgentLoop) Run(ctx context.Context) error {
 
 			// Handle /stop command: cancel the active request for this session
 			if strings.TrimSpace(msg.Content) == "/stop" {
-				sessionKey := fmt.Sprintf("%s:%s", msg.Channel, msg.ChatID)
+				sessionKey := commandSessionKey(msg)
 				if cancelFn, ok := al.activeCancel.LoadAndDelete(sessionKey); ok {
 					cancelFn.(context.CancelFunc)()
 					logger.InfoCF("agent", "Cancelled active request", map[string]interface{}{
@@ -243,9 +391,20 @@ func (al *AgentLoop) Run(ctx context.Context) error {
 				continue
 			}
 
+			// Handle /clear before registering a new cancel func so it can
+			// cancel the in-flight request for this session.
+			if trimmed := strings.TrimSpace(msg.Content); isCommand(trimmed, "/clear") {
+				al.bus.PublishOutbound(bus.OutboundMessage{
+					Channel: msg.Channel,
+					ChatID:  msg.ChatID,
+					Content: al.handleClearCommand(msg, trimmed),
+				})
+				continue
+			}
+
 			// Create a cancellable context for this request
 			msgCtx, msgCancel := context.WithCancel(ctx)
-			sessionKey := fmt.Sprintf("%s:%s", msg.Channel, msg.ChatID)
+			sessionKey := commandSessionKey(msg)
 			al.activeCancel.Store(sessionKey, msgCancel)
 
 			response, err := al.processMessage(msgCtx, msg)
@@ -257,6 +416,16 @@ func (al *AgentLoop) Run(ctx context.Context) error {
 					// Request was cancelled by /stop, don't send error
 					continue
 				}
+				errorClass := utils.ClassifyError(err, "")
+				logger.ErrorCF("agent", "Failed to process message",
+					map[string]interface{}{
+						"channel":        msg.Channel,
+						"chat_id":        msg.ChatID,
+						"error":          err.Error(),
+						"error_class":    errorClass,
+						"fingerprint":    utils.ErrorFingerprint(err.Error(), "agent", errorClass),
+						"correlation_id": msg.CorrelationID,
+					})
 				response = fmt.Sprintf("Error processing message: %v", err)
 			}
 
@@ -266,17 +435,21 @@ func (al *AgentLoop) Run(ctx context.Context) error {
 				alreadySent := false
 				if tool, ok := al.tools.Get("message"); ok {
 					if mt, ok := tool.(*tools.MessageTool); ok {
-						alreadySent = mt.HasSentInRound()
+						alreadySent = mt.HasSentInRound(msg.Channel, msg.ChatID)
 					}
 				}
 
 				if !alreadySent {
 					al.bus.PublishOutbound(bus.OutboundMessage{
-						Channel: msg.Channel,
-						ChatID:  msg.ChatID,
-						Content: response,
+						Channel:          msg.Channel,
+						ChatID:           msg.ChatID,
+						Content:          response,
+						ReplyToMessageID: msg.Metadata["message_id"],
 					})
 					al.maybeSendSwitchbackPrompt(msg.Channel, msg.ChatID)
+					if al.wantsVoiceReply(msg, response) {
+						go al.sendVoiceReply(ctx, msg, response)
+					}
 				}
 			}
 		}
@@ -287,12 +460,120 @@ func (al *AgentLoop) Run(ctx context.Context) error {
 
 func (al *AgentLoop) Stop() {
 	al.running.Store(false)
+	// Persist usage records still buffered for the next flush.
+	if al.usageStore != nil {
+		al.usageStore.Close()
+	}
+}
+
+// Shutdown stops the loop for process exit: it cancels in-flight requests,
+// waits until ctx is done for running summarizations, persists every
+// session and buffered usage record, and closes the log file. A failed
+// step does not stop the later ones; the failures are returned joined.
+func (al *AgentLoop) Shutdown(ctx context.Context) error {
+	al.running.Store(false)
+
+	cancelled := 0
+	al.activeCancel.Range(func(key, cancelFn interface{}) bool {
+		cancelFn.(context.CancelFunc)()
+		al.activeCancel.Delete(key)
+		cancelled++
+		return true
+	})
+
+	var errs []error
+	done := make(chan struct{})
+	go func() {
+		al.summaries.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		errs = append(errs, fmt.Errorf("summarizations still running: %w", ctx.Err()))
+	}
+
+	if err := al.sessions.SaveAll(); err != nil {
+		errs = append(errs, fmt.Errorf("saving sessions: %w", err))
+	}
+	if al.usageStore != nil {
+		al.usageStore.Close()
+	}
+
+	err := errors.Join(errs...)
+	fields := map[string]interface{}{"cancelled_requests": cancelled}
+	if err != nil {
+		fields["error"] = err.Error()
+		logger.WarnCF("agent", "Shutdown finished with errors", fields)
+	} else {
+		logger.InfoCF("agent", "Shutdown complete", fields)
+	}
+	logger.DisableFileLogging()
+	return err
 }
 
 func (al *AgentLoop) RegisterTool(tool tools.Tool) {
 	al.tools.Register(tool)
 }
 
+// ExecTool returns the main agent's exec tool, configured with the exec
+// environment and command lists, or nil when it is disabled (safe mode).
+// Scheduled commands reuse it so they get the same restrictions.
+func (al *AgentLoop) ExecTool() *tools.ExecTool {
+	tool, ok := al.tools.Get("exec")
+	if !ok {
+		return nil
+	}
+	execTool, _ := tool.(*tools.ExecTool)
+	return execTool
+}
+
+// SetCronService enables the /tasks command for the given scheduler.
+func (al *AgentLoop) SetCronService(cs *cron.CronService) {
+	al.cronService = cs
+}
+
+// AllowlistManager changes channel allowlists at runtime for /allow.
+// channels.Manager implements it.
+type AllowlistManager interface {
+	AllowList(channel string) ([]string, error)
+	SetAllowList(channel string, allowList []string) error
+	CanManageAllowList(channel, senderID string) bool
+}
+
+// SetAllowlistManager enables the /allow command.
+func (al *AgentLoop) SetAllowlistManager(m AllowlistManager) {
+	al.allowlists = m
+}
+
+// canManage reports whether senderID may use admin tools on channel: the
+// owner or an allowlisted user, like the admin commands. Without an
+// allowlist manager (e.g. the CLI) everyone may.
+func (al *AgentLoop) canManage(channel, senderID string) bool {
+	return al.allowlists == nil || al.allowlists.CanManageAllowList(channel, senderID)
+}
+
+// ChannelStatusProvider reports channel health for /status.
+// channels.Manager implements it.
+type ChannelStatusProvider interface {
+	ChannelStatuses() []channels.Status
+}
+
+// SetChannelStatusProvider enables the /status command.
+func (al *AgentLoop) SetChannelStatusProvider(p ChannelStatusProvider) {
+	al.channelStatus = p
+}
+
+// SetTranscriber enables the transcribe_attachment tool.
+func (al *AgentLoop) SetTranscriber(t voice.Transcriber) {
+	al.tools.Register(tools.NewTranscribeAttachmentTool(attachments.NewStore(al.workspace), t))
+}
+
+// StateManager returns the workspace state manager shared by the agent loop.
+func (al *AgentLoop) StateManager() *state.Manager {
+	return al.state
+}
+
 // RecordLastChannel records the last active channel for this workspace.
 // This uses the atomic state save mechanism to prevent data loss on crash.
 func (al *AgentLoop) RecordLastChannel(channel string) error {
@@ -360,9 +641,52 @@ func (al *AgentLoop) processMessage(ctx context.Context, msg bus.InboundMessage)
 	}
 
 	trimmed := strings.TrimSpace(msg.Content)
+	if content, reply, handled := al.resolveConfirmation(ctx, msg, trimmed); handled {
+		if reply != "" {
+			return reply, nil
+		}
+		msg.Content = content
+		trimmed = content
+	}
 	if strings.HasPrefix(trimmed, "/usage") {
 		return al.handleUsageCommand(msg, trimmed), nil
 	}
+	if isCommand(trimmed, "/sessions") {
+		return al.handleSessionsCommand(msg), nil
+	}
+	if isCommand(trimmed, "/clear") {
+		return al.handleClearCommand(msg, trimmed), nil
+	}
+	if isCommand(trimmed, "/status") {
+		return al.handleStatusCommand(), nil
+	}
+	if isCommand(trimmed, "/whoami") {
+		return al.handleWhoamiCommand(), nil
+	}
+	if isCommand(trimmed, "/tasks") {
+		return al.handleTasksCommand(ctx, msg, trimmed), nil
+	}
+	if isCommand(trimmed, "/skills") {
+		return al.handleSkillsCommand(msg, trimmed), nil
+	}
+	if isCommand(trimmed, "/cleanup") {
+		return al.handleCleanupCommand(ctx, msg), nil
+	}
+	if isCommand(trimmed, "/allow") {
+		return al.handleAllowCommand(msg, trimmed), nil
+	}
+	if isCommand(trimmed, "/voice") {
+		return al.handleVoiceCommand(msg, trimmed), nil
+	}
+	if isCommand(trimmed, "/loglevel") {
+		return al.handleLogLevelCommand(msg, trimmed), nil
+	}
+	if isCommand(trimmed, "/failover") {
+		return al.handleFailoverCommand(ctx, msg, trimmed), nil
+	}
+	if isCommand(trimmed, "/retry") {
+		return al.handleRetryCommand(ctx, msg)
+	}
 	if al.failoverMgr != nil && al.failoverMgr.Enabled() {
 		if decision := al.failoverMgr.HandleUserSwitchbackDecision(trimmed); decision.Handled {
 			if decision.Reply != "" {
@@ -373,6 +697,11 @@ func (al *AgentLoop) processMessage(ctx context.Context, msg bus.InboundMessage)
 		al.maybeRunFailoverProbe()
 	}
 
+	return al.runUserMessage(ctx, msg)
+}
+
+// runUserMessage runs the agent loop on a user message.
+func (al *AgentLoop) runUserMessage(ctx context.Context, msg bus.InboundMessage) (string, error) {
 	// Create ActionStream for visibility if enabled
 	var actionStream *ActionStream
 	if al.config.Visibility.Enabled {
@@ -389,10 +718,11 @@ func (al *AgentLoop) processMessage(ctx context.Context, msg bus.InboundMessage)
 	}
 
 	// Process as user message
-	return al.runAgentLoop(ctx, processOptions{
+	response, err := al.runAgentLoop(ctx, processOptions{
 		SessionKey:           msg.SessionKey,
 		Channel:              msg.Channel,
 		ChatID:               msg.ChatID,
+		SenderID:             msg.SenderID,
 		UserMessage:          msg.Content,
 		DefaultResponse:      "I've completed processing but have no response to give.",
 		EnableSummary:        true,
@@ -402,11 +732,15 @@ func (al *AgentLoop) processMessage(ctx context.Context, msg bus.InboundMessage)
 		ActionStream:         actionStream,
 		Media:                msg.Media,
 	})
+	if err == nil {
+		al.rememberTurn(msg.Content)
+	}
+	return response, err
 }
 
 func formatUsageAggregatePlain(label string, agg usage.Aggregate) string {
 	return fmt.Sprintf(
-		"%s: calls=%d known=%d unknown=%d in=%s (%s) out=%s (%s) total=%s (%s)",
+		"%s: calls=%d known=%d unknown=%d in=%s (%s) out=%s (%s) total=%s (%s) cost=%s unpriced=%d",
 		label,
 		agg.Calls,
 		agg.KnownCalls,
@@ -417,22 +751,29 @@ func formatUsageAggregatePlain(label string, agg usage.Aggregate) string {
 		usage.HumanTokens(agg.CompletionTokens),
 		usage.GroupedInt(agg.TotalTokens),
 		usage.HumanTokens(agg.TotalTokens),
+		usage.FormatUSD(agg.CostUSD),
+		agg.UnknownCostCalls,
 	)
 }
 
 func formatUsageAggregateTable(label string, agg usage.Aggregate) string {
-	return fmt.Sprintf("| %-14s | %5d | %7s | %6s | %7s |",
+	cost := usage.FormatUSD(agg.CostUSD)
+	if agg.UnknownCostCalls > 0 {
+		cost += "*"
+	}
+	return fmt.Sprintf("| %-14s | %5d | %7s | %6s | %7s | %9s |",
 		label,
 		agg.Calls,
 		usage.HumanTokens(agg.PromptTokens),
 		usage.HumanTokens(agg.CompletionTokens),
 		usage.HumanTokens(agg.TotalTokens),
+		cost,
 	)
 }
 
 func usageTableHeader() string {
-	return "| Scope          | Calls |   Input | Output |   Total |\n" +
-		"|----------------|-------|---------|--------|---------|"
+	return "| Scope          | Calls |   Input | Output |   Total |      Cost |\n" +
+		"|----------------|-------|---------|--------|---------|-----------|"
 }
 
 func (al *AgentLoop) handleUsageCommand(msg bus.InboundMessage, command string) string {
@@ -587,6 +928,9 @@ func (al *AgentLoop) handleUsageCommand(msg bus.InboundMessage, command string)
 				lines = append(lines, formatUsageAggregateTable("  └ "+p, byProvider[p]))
 			}
 		}
+		if sessionAgg.UnknownCostCalls > 0 || todayAgg.UnknownCostCalls > 0 {
+			lines = append(lines, fmt.Sprintf("_* cost excludes %d call(s) today with no configured price_", todayAgg.UnknownCostCalls))
+		}
 		lines = append(lines, "")
 		lines = append(lines, "_/usage last · session · today · provider_")
 		return strings.Join(lines, "\n")
@@ -649,6 +993,7 @@ func (al *AgentLoop) processSystemMessage(ctx context.Context, msg bus.InboundMe
 // runAgentLoop is the core message processing logic.
 // It handles context building, LLM calls, tool execution, and response handling.
 func (al *AgentLoop) runAgentLoop(ctx context.Context, opts processOptions) (string, error) {
+	al.acquireTurnMedia(opts.Media)
 	defer al.cleanupTurnMedia(opts.Media)
 
 	// 0. Record last channel for heartbeat notifications (skip internal channels)
@@ -657,13 +1002,14 @@ func (al *AgentLoop) runAgentLoop(ctx context.Context, opts processOptions) (str
 		if !constants.IsInternalChannel(opts.Channel) {
 			channelKey := fmt.Sprintf("%s:%s", opts.Channel, opts.ChatID)
 			if err := al.RecordLastChannel(channelKey); err != nil {
-				logger.WarnCF("agent", "Failed to record last channel: %v", map[string]interface{}{"error": err.Error()})
+				logger.WarnCF("agent", "Failed to record last channel", map[string]interface{}{"error": err.Error()})
 			}
 		}
 	}
 
-	// 1. Update tool contexts
-	al.updateToolContexts(opts.Channel, opts.ChatID)
+	// 1. Start this chat's message round. Tools get channel/chatID per call
+	// from the registry, so no shared tool context is set here.
+	al.startMessageRound(opts.Channel, opts.ChatID)
 
 	// 2. Build messages (skip history for heartbeat)
 	var history []providers.Message
@@ -684,10 +1030,28 @@ func (al *AgentLoop) runAgentLoop(ctx context.Context, opts processOptions) (str
 	// 3. Save user message to session
 	al.sessions.AddMessage(opts.SessionKey, "user", opts.UserMessage)
 
-	// 4. Run LLM iteration loop
-	finalContent, iteration, err := al.runLLMIteration(ctx, messages, opts)
+	// 4. Run LLM iteration loop under the overall request deadline. /stop
+	// cancels the parent context (context.Canceled) and is still reported
+	// as an error; only our own deadline becomes a timeout response.
+	iterCtx := ctx
+	if al.requestTimeout > 0 {
+		var cancel context.CancelFunc
+		iterCtx, cancel = context.WithTimeout(ctx, al.requestTimeout)
+		defer cancel()
+	}
+	finalContent, iteration, toolsUsed, err := al.runLLMIteration(iterCtx, messages, opts)
 	if err != nil {
-		return "", err
+		if ctx.Err() != nil || !errors.Is(iterCtx.Err(), context.DeadlineExceeded) {
+			return "", err
+		}
+		logger.WarnCF("agent", "Request timed out",
+			map[string]interface{}{
+				"session_key":    opts.SessionKey,
+				"iterations":     iteration,
+				"timeout":        al.requestTimeout.String(),
+				"correlation_id": opts.CorrelationID,
+			})
+		finalContent = fmt.Sprintf("⏱️ This request timed out after %s and was stopped. Any work completed so far has been kept; send a follow-up to continue.", al.requestTimeout)
 	}
 
 	// If last tool had ForUser content and we already sent it, we might not need to send final response
@@ -698,8 +1062,13 @@ func (al *AgentLoop) runAgentLoop(ctx context.Context, opts processOptions) (str
 		finalContent = opts.DefaultResponse
 	}
 
-	// 6. Save final assistant message to session
-	al.sessions.AddMessage(opts.SessionKey, "assistant", finalContent)
+	// 6. Save final assistant message to session. Compact history skipped
+	// the tool messages, so note which tools ran instead.
+	savedContent := finalContent
+	if al.compactTools && len(toolsUsed) > 0 {
+		savedContent += fmt.Sprintf("\n\n[Tools used: %s]", strings.Join(toolsUsed, ", "))
+	}
+	al.sessions.AddMessage(opts.SessionKey, "assistant", savedContent)
 	al.sessions.Save(opts.SessionKey)
 
 	// 7. Optional: summarization
@@ -733,13 +1102,14 @@ func (al *AgentLoop) cleanupTurnMedia(media []string) {
 	if len(media) == 0 {
 		return
 	}
+	al.releaseTurnMedia(media)
 
 	workspaceMediaDir := filepath.Clean(filepath.Join(al.workspace, "tmp", "media"))
-	legacyTempDir := filepath.Clean(filepath.Join(os.TempDir(), "picoclaw_media"))
+	legacyTempDir := filepath.Clean(utils.LegacyMediaCacheDir())
 
 	for _, p := range media {
 		cleanPath := filepath.Clean(p)
-		if !isPathWithin(cleanPath, workspaceMediaDir) && !isPathWithin(cleanPath, legacyTempDir) {
+		if !utils.IsPathWithin(cleanPath, workspaceMediaDir) && !utils.IsPathWithin(cleanPath, legacyTempDir) {
 			continue
 		}
 
@@ -755,24 +1125,80 @@ func (al *AgentLoop) cleanupTurnMedia(media []string) {
 		logger.DebugCF("agent", "Removed media file after turn",
 			map[string]interface{}{"path": cleanPath})
 	}
+
+	if _, err := utils.EnforceMediaCacheLimit(); err != nil {
+		logger.WarnCF("agent", "Failed to enforce media cache limit",
+			map[string]interface{}{"error": err.Error()})
+	}
 }
 
-func isPathWithin(path, dir string) bool {
-	rel, err := filepath.Rel(dir, path)
-	if err != nil {
-		return false
+// mediaCleanupMinAge protects freshly downloaded media whose message is
+// still queued and has not started a turn yet.
+const mediaCleanupMinAge = time.Minute
+
+// mediaCacheDirs returns the directories /cleanup and cleanup_cache may
+// empty: the workspace media cache and the legacy temp directory.
+func (al *AgentLoop) mediaCacheDirs() []string {
+	return []string{
+		filepath.Join(al.workspace, "tmp", "media"),
+		utils.LegacyMediaCacheDir(),
+	}
+}
+
+// acquireTurnMedia marks media as in use until releaseTurnMedia.
+func (al *AgentLoop) acquireTurnMedia(media []string) {
+	al.mediaMu.Lock()
+	defer al.mediaMu.Unlock()
+	for _, p := range media {
+		al.turnMedia[filepath.Clean(p)]++
 	}
-	return rel == "." || (rel != ".." && !strings.HasPrefix(rel, ".."+string(os.PathSeparator)))
 }
 
-// runLLMIteration executes the LLM call loop with tool handling.
-// Returns the final content, iteration count, and any error.
-func (al *AgentLoop) runLLMIteration(ctx context.Context, messages []providers.Message, opts processOptions) (string, int, error) {
+func (al *AgentLoop) releaseTurnMedia(media []string) {
+	al.mediaMu.Lock()
+	defer al.mediaMu.Unlock()
+	for _, p := range media {
+		p = filepath.Clean(p)
+		if al.turnMedia[p] <= 1 {
+			delete(al.turnMedia, p)
+		} else {
+			al.turnMedia[p]--
+		}
+	}
+}
+
+// keepCachedMedia reports whether a cached file must survive a cleanup:
+// it is referenced by an in-flight turn or was downloaded very recently.
+func (al *AgentLoop) keepCachedMedia(path string, info os.FileInfo) bool {
+	if time.Since(info.ModTime()) < mediaCleanupMinAge {
+		return true
+	}
+	al.mediaMu.Lock()
+	defer al.mediaMu.Unlock()
+	return al.turnMedia[filepath.Clean(path)] > 0
+}
+
+// contentFilterMessage is shown instead of an empty reply when the provider
+// withholds its response under its content policy.
+const contentFilterMessage = "🚫 The model provider declined to answer this request because it was flagged by its content filter. Nothing went wrong on my side — try rephrasing or giving more context about what you need."
+
+// contentFilterRetryPrompt is appended for the single retry after a refusal.
+const contentFilterRetryPrompt = "Your previous reply was blocked by the provider's content filter. Answer the request again, staying within content policy: rephrase or omit anything that could be flagged, and if you cannot help, say so briefly."
+
+// runLLMIteration runs the LLM/tool loop and returns the final content, the
+// number of iterations and the distinct tools that ran, in call order.
+func (al *AgentLoop) runLLMIteration(ctx context.Context, messages []providers.Message, opts processOptions) (string, int, []string, error) {
 	iteration := 0
 	var finalContent string
 	planState := newExecutionPlanState()
+	filterRetried := false
+	paused := false // Waiting for the user to approve a tool call
+	var toolsUsed []string
 
 	for iteration < al.maxIterations {
+		if err := ctx.Err(); err != nil {
+			return "", iteration, toolsUsed, err
+		}
 		iteration++
 
 		logger.DebugCF("agent", "LLM iteration",
@@ -789,11 +1215,14 @@ func (al *AgentLoop) runLLMIteration(ctx context.Context, messages []providers.M
 		if al.failoverMgr != nil && al.failoverMgr.Enabled() {
 			route, routeErr := al.failoverMgr.ResolveRoute()
 			if routeErr != nil {
-				return "", iteration, fmt.Errorf("resolve failover route: %w", routeErr)
+				return "", iteration, toolsUsed, fmt.Errorf("resolve failover route: %w", routeErr)
 			}
 			activeProvider = route.Provider
 			activeModel = route.Model
 			switchEpoch = route.SwitchEpoch
+			if !route.IsPrimary && !opts.NoHistory {
+				al.notifyFallbackUse(opts.Channel, opts.ChatID, route)
+			}
 		}
 
 		// Log LLM request details
@@ -812,7 +1241,7 @@ func (al *AgentLoop) runLLMIteration(ctx context.Context, messages []providers.M
 		logger.DebugCF("agent", "Full LLM request",
 			map[string]interface{}{
 				"iteration":     iteration,
-				"messages_json": formatMessagesForLog(messages),
+				"messages_json": formatMessagesForLog(messages, al.tools.RedactedArgs),
 				"tools_json":    formatToolsForLog(providerToolDefs),
 			})
 
@@ -842,7 +1271,7 @@ func (al *AgentLoop) runLLMIteration(ctx context.Context, messages []providers.M
 					al.notifyFailoverSwitch(opts.Channel, opts.ChatID, switchEvent)
 					retryRoute, routeErr := al.failoverMgr.ResolveRoute()
 					if routeErr != nil {
-						return "", iteration, fmt.Errorf("resolve failover retry route: %w", routeErr)
+						return "", iteration, toolsUsed, fmt.Errorf("resolve failover retry route: %w", routeErr)
 					}
 					activeProvider = retryRoute.Provider
 					activeModel = retryRoute.Model
@@ -855,51 +1284,55 @@ func (al *AgentLoop) runLLMIteration(ctx context.Context, messages []providers.M
 				}
 			}
 
+			// A prompt blocked by the content policy is a refusal, not a
+			// failure; handle it like a filtered response below.
+			var filterErr *providers.ContentFilterError
+			if errors.As(err, &filterErr) {
+				response = &providers.LLMResponse{FinishReason: providers.FinishReasonContentFilter}
+				err = nil
+			}
+
 			if err != nil {
+				errorClass := classifyLLMError(err)
 				logger.ErrorCF("agent", "LLM call failed",
 					map[string]interface{}{
 						"iteration":      iteration,
 						"error":          err.Error(),
+						"error_class":    errorClass,
+						"fingerprint":    utils.ErrorFingerprint(err.Error(), "llm", errorClass),
 						"model":          activeModel,
 						"switch_epoch":   switchEpoch,
 						"correlation_id": opts.CorrelationID,
 					})
-				return "", iteration, fmt.Errorf("LLM call failed: %w", err)
+				return "", iteration, toolsUsed, fmt.Errorf("LLM call failed: %w", err)
 			}
 		}
 		if al.failoverMgr != nil && al.failoverMgr.Enabled() {
 			al.failoverMgr.OnLLMSuccess(activeModel)
 		}
 
-		if al.usageStore != nil {
-			usageKnown := response.Usage != nil
-			promptTokens := 0
-			completionTokens := 0
-			totalTokens := 0
-			if usageKnown {
-				promptTokens = response.Usage.PromptTokens
-				completionTokens = response.Usage.CompletionTokens
-				totalTokens = response.Usage.TotalTokens
-			}
-			if totalTokens == 0 {
-				totalTokens = promptTokens + completionTokens
-			}
-			reason := strings.TrimSpace(response.FinishReason)
-			if reason == "" {
-				reason = "normal_call"
+		reason := strings.TrimSpace(response.FinishReason)
+		if reason == "" {
+			reason = "normal_call"
+		}
+		al.recordUsage(opts.SessionKey, activeModel, response, reason)
+
+		if providers.IsContentFilterFinishReason(response.FinishReason) {
+			logger.WarnCF("agent", "Provider refused on content-policy grounds",
+				map[string]interface{}{
+					"iteration":      iteration,
+					"model":          activeModel,
+					"finish_reason":  response.FinishReason,
+					"will_retry":     al.filterRetry && !filterRetried,
+					"correlation_id": opts.CorrelationID,
+				})
+			if al.filterRetry && !filterRetried {
+				filterRetried = true
+				messages = append(messages, providers.Message{Role: "user", Content: contentFilterRetryPrompt})
+				continue
 			}
-			al.usageStore.Add(usage.Record{
-				Timestamp:        time.Now().UTC(),
-				SessionKey:       opts.SessionKey,
-				DayKey:           time.Now().UTC().Format("2006-01-02"),
-				Provider:         providerFromModel(activeModel),
-				Model:            activeModel,
-				PromptTokens:     promptTokens,
-				CompletionTokens: completionTokens,
-				TotalTokens:      totalTokens,
-				UsageKnown:       usageKnown,
-				Reason:           reason,
-			})
+			finalContent = contentFilterMessage
+			break
 		}
 
 		// Check if no tool calls - we're done
@@ -928,17 +1361,20 @@ func (al *AgentLoop) runLLMIteration(ctx context.Context, messages []providers.M
 
 		// Plan+execute mode: first tool-call batch becomes explicit user-visible plan.
 		// Persist the plan as a workspace artifact and publish it to chat.
-		if !planState.Announced {
+		// Turns whose first batch is too small for a plan run without one.
+		if !planState.Decided && al.wantsPlan(len(response.ToolCalls)) {
 			planModel := activeModel
 			planState.Bullets, planModel = al.generateExecutionPlanBullets(ctx, opts, activeModel, activeProvider, response.ToolCalls)
 			planState.absorbToolCalls(response.ToolCalls)
 			planState.Announced = true
 
-			planPath, planErr := writeExecutionPlanFile(al.workspace, planState.Bullets, planFileMetadata{
+			planState.Meta = planFileMetadata{
 				SessionKey:    opts.SessionKey,
 				CorrelationID: opts.CorrelationID,
 				Model:         planModel,
-			}, time.Now())
+			}
+			planState.Created = time.Now()
+			planPath, planErr := writeExecutionPlanFile(al.workspace, planState.Bullets, planState.Meta, planState.Created)
 			if planErr != nil {
 				logger.WarnCF("agent", "Failed to persist execution plan file",
 					map[string]interface{}{
@@ -947,6 +1383,7 @@ func (al *AgentLoop) runLLMIteration(ctx context.Context, messages []providers.M
 						"correlation_id": opts.CorrelationID,
 					})
 			} else {
+				planState.Path = planPath
 				logger.InfoCF("agent", "Execution plan file created",
 					map[string]interface{}{
 						"path":           planPath,
@@ -957,15 +1394,9 @@ func (al *AgentLoop) runLLMIteration(ctx context.Context, messages []providers.M
 			}
 
 			planMsg := formatExecutionPlanProgressWithArtifact(planState.Bullets, planPath)
-			if shouldPublishProgress(opts) {
-				// Send the plan as a regular message so it remains persistent in chat.
-				// Telegram channel logic will finalize the current placeholder for this message.
-				al.bus.PublishOutbound(bus.OutboundMessage{
-					Channel:          opts.Channel,
-					ChatID:           opts.ChatID,
-					Content:          planMsg,
-					IsProgressUpdate: false,
-				})
+			// Send the plan as a regular message so it remains persistent in chat.
+			// Telegram channel logic will finalize the current placeholder for this message.
+			if al.publishPlan(opts, planMsg, false) {
 				// Immediately start a second message dedicated to streaming progress updates.
 				al.bus.PublishOutbound(bus.OutboundMessage{
 					Channel:          opts.Channel,
@@ -981,6 +1412,7 @@ func (al *AgentLoop) runLLMIteration(ctx context.Context, messages []providers.M
 				Content: formatPlanContextMessage(planState.Bullets),
 			})
 		}
+		planState.Decided = true
 
 		// Build assistant message with tool calls
 		assistantMsg := providers.Message{
@@ -1001,124 +1433,263 @@ func (al *AgentLoop) runLLMIteration(ctx context.Context, messages []providers.M
 		messages = append(messages, assistantMsg)
 
 		// Save assistant message with tool calls to session
-		al.sessions.AddFullMessage(opts.SessionKey, assistantMsg)
-
-		// Execute tool calls
+		al.saveToolMessage(opts.SessionKey, assistantMsg)
 		for _, tc := range response.ToolCalls {
-			// If model introduces out-of-plan tool families, announce and persist plan update first.
-			tcName := strings.TrimSpace(tc.Name)
-			if tcName == "" && tc.Function != nil {
-				tcName = strings.TrimSpace(tc.Function.Name)
+			if name := toolCallName(tc); !slices.Contains(toolsUsed, name) {
+				toolsUsed = append(toolsUsed, name)
 			}
-			if planState.Announced && tcName != "" && !planState.isAllowedTool(tcName) {
-				updateStep := summarizeToolCallForPlan(tc)
-				planState.Bullets = append(planState.Bullets, updateStep)
-				planState.Allowed[tcName] = struct{}{}
+		}
 
-				updateMsg := formatPlanUpdateProgress(updateStep)
-				if shouldPublishProgress(opts) {
-					al.bus.PublishOutbound(bus.OutboundMessage{
-						Channel:          opts.Channel,
-						ChatID:           opts.ChatID,
-						Content:          updateMsg,
-						IsProgressUpdate: true,
-					})
+		// Execute tool calls. Runs of consecutive parallel-safe calls execute
+		// concurrently; everything else, and all bookkeeping, stays in call order.
+		held := false
+		for first := 0; first < len(response.ToolCalls); {
+			// Pause the turn at the first call that needs approval; it and
+			// the calls after it wait for the user's reply.
+			if al.requiresConfirmation(toolCallName(response.ToolCalls[first])) {
+				var heldMessages []providers.Message
+				finalContent, heldMessages = al.holdForConfirmation(response.ToolCalls[first:], opts)
+				for _, heldMsg := range heldMessages {
+					messages = append(messages, heldMsg)
+					al.saveToolMessage(opts.SessionKey, heldMsg)
 				}
-
-				messages = append(messages, providers.Message{
-					Role:    "system",
-					Content: formatPlanContextMessage(planState.Bullets),
-				})
-
+				held = true
+				paused = true
+				break
 			}
 
-			// Log tool call with arguments preview
-			argsJSON, _ := json.Marshal(tc.Arguments)
-			argsPreview := utils.Truncate(string(argsJSON), 200)
-			logger.InfoCF("agent", fmt.Sprintf("Tool call: %s(%s)", tc.Name, argsPreview),
-				map[string]interface{}{
-					"tool":           tc.Name,
-					"iteration":      iteration,
-					"correlation_id": opts.CorrelationID,
-				})
+			batch := response.ToolCalls[first : first+al.parallelSafeRun(response.ToolCalls[first:])]
+			first += len(batch)
 
-			// Track action start if visibility enabled
-			var actionID string
-			if opts.ActionStream != nil {
-				actionID = opts.ActionStream.StartAction(tc.Name, tc.Arguments)
+			calls := make([]pendingToolCall, len(batch))
+			for i, tc := range batch {
+				calls[i] = al.startToolCall(tc, planState, iteration, opts)
 			}
+			al.executeToolCalls(ctx, calls, opts)
+
+			for _, call := range calls {
+				messages = append(messages, call.planMessages...)
+				toolResultMsg := al.finishToolCall(call, opts)
+				messages = append(messages, toolResultMsg)
 
-			// Create async callback for tools that implement AsyncTool
-			// NOTE: Following openclaw's design, async tools do NOT send results directly to users.
-			// Instead, they notify the agent via PublishInbound, and the agent decides
-			// whether to forward the result to the user (in processSystemMessage).
-			asyncCallback := func(callbackCtx context.Context, result *tools.ToolResult) {
-				// Log the async completion but don't send directly to user
-				// The agent will handle user notification via processSystemMessage
-				if !result.Silent && result.ForUser != "" {
-					logger.InfoCF("agent", "Async tool completed, agent will handle notification",
-						map[string]interface{}{
-							"tool":        tc.Name,
-							"content_len": len(result.ForUser),
-						})
+				// Save tool result message to session
+				al.saveToolMessage(opts.SessionKey, toolResultMsg)
+				if planState.Announced {
+					planState.recordToolResult(call.tc, call.result.IsError)
 				}
 			}
+			al.updatePlanFile(planState, "")
+		}
+		if held {
+			break
+		}
+	}
 
-			toolResult := al.tools.ExecuteWithContext(ctx, tc.Name, tc.Arguments, opts.Channel, opts.ChatID, asyncCallback)
+	// A paused turn resumes after approval; its plan is not finished yet.
+	if planState.Announced && !paused {
+		al.finishPlan(planState, opts)
+	}
 
-			// Track action completion if visibility enabled
-			if opts.ActionStream != nil && actionID != "" {
-				resultContent := toolResult.ForUser
-				if resultContent == "" {
-					resultContent = toolResult.ForLLM
-				}
-				opts.ActionStream.CompleteAction(actionID, resultContent, toolResult.Err)
-			}
+	// Force final update if visibility enabled
+	if opts.ActionStream != nil {
+		opts.ActionStream.ForceUpdate()
+	}
 
-			// Send ForUser content to user immediately if not Silent
-			if !toolResult.Silent && toolResult.ForUser != "" && opts.SendResponse {
-				al.bus.PublishOutbound(bus.OutboundMessage{
-					Channel: opts.Channel,
-					ChatID:  opts.ChatID,
-					Content: toolResult.ForUser,
-				})
-				logger.DebugCF("agent", "Sent tool result to user",
-					map[string]interface{}{
-						"tool":        tc.Name,
-						"content_len": len(toolResult.ForUser),
-					})
-			}
+	return finalContent, iteration, toolsUsed, nil
+}
 
-			// Determine content for LLM based on tool result
-			contentForLLM := toolResult.ForLLM
-			if contentForLLM == "" && toolResult.Err != nil {
-				contentForLLM = toolResult.Err.Error()
-			}
+// saveToolMessage persists an assistant tool-call or tool-result message,
+// unless session tool history is compact.
+func (al *AgentLoop) saveToolMessage(sessionKey string, msg providers.Message) {
+	if al.compactTools {
+		return
+	}
+	al.sessions.AddFullMessage(sessionKey, msg)
+}
 
-			toolResultMsg := providers.Message{
-				Role:       "tool",
-				Content:    contentForLLM,
-				ToolCallID: tc.ID,
-			}
-			messages = append(messages, toolResultMsg)
+// maxParallelTools bounds how many parallel-safe tool calls run at once.
+const maxParallelTools = 4
 
-			// Save tool result message to session
-			al.sessions.AddFullMessage(opts.SessionKey, toolResultMsg)
-		}
+// pendingToolCall tracks one tool call from start to recorded result.
+type pendingToolCall struct {
+	tc           providers.ToolCall
+	actionID     string
+	planMessages []providers.Message // Plan updates to record before the result
+	result       *tools.ToolResult
+}
+
+// parallelSafeRun returns how many leading calls can run as one batch: the
+// whole run of consecutive parallel-safe calls, or just the first call.
+func (al *AgentLoop) parallelSafeRun(calls []providers.ToolCall) int {
+	n := 0
+	for n < len(calls) && al.tools.IsParallelSafe(toolCallName(calls[n])) && !al.requiresConfirmation(toolCallName(calls[n])) {
+		n++
 	}
+	return max(n, 1)
+}
 
-	// Force final update if visibility enabled
+func toolCallName(tc providers.ToolCall) string {
+	name := strings.TrimSpace(tc.Name)
+	if name == "" && tc.Function != nil {
+		name = strings.TrimSpace(tc.Function.Name)
+	}
+	return name
+}
+
+// startToolCall announces out-of-plan tools, logs the call and starts its
+// visibility action.
+func (al *AgentLoop) startToolCall(tc providers.ToolCall, planState *executionPlanState, iteration int, opts processOptions) pendingToolCall {
+	call := pendingToolCall{tc: tc}
+
+	// If model introduces out-of-plan tool families, announce and persist plan update first.
+	tcName := toolCallName(tc)
+	if planState.Announced && tcName != "" && !planState.isAllowedTool(tcName) {
+		updateStep := summarizeToolCallForPlan(tc)
+		planState.Bullets = append(planState.Bullets, updateStep)
+		planState.Allowed[tcName] = struct{}{}
+
+		al.publishPlan(opts, formatPlanUpdateProgress(updateStep), true)
+
+		call.planMessages = append(call.planMessages, providers.Message{
+			Role:    "system",
+			Content: formatPlanContextMessage(planState.Bullets),
+		})
+	}
+
+	// Log tool call with arguments preview
+	argsJSON, _ := json.Marshal(al.tools.RedactedArgs(tc.Name, tc.Arguments))
+	argsPreview := utils.Truncate(string(argsJSON), 200)
+	logger.InfoCF("agent", fmt.Sprintf("Tool call: %s(%s)", tc.Name, argsPreview),
+		map[string]interface{}{
+			"tool":           tc.Name,
+			"iteration":      iteration,
+			"correlation_id": opts.CorrelationID,
+		})
+
+	// Track action start if visibility enabled
 	if opts.ActionStream != nil {
-		opts.ActionStream.ForceUpdate()
+		call.actionID = opts.ActionStream.StartAction(tc.Name, tc.Arguments)
+	}
+	return call
+}
+
+// executeToolCalls runs the calls, concurrently (bounded) when there is
+// more than one; callers only batch parallel-safe tools. Results are stored
+// in each call so ordering is preserved.
+func (al *AgentLoop) executeToolCalls(ctx context.Context, calls []pendingToolCall, opts processOptions) {
+	if len(calls) == 1 {
+		calls[0].result = al.executeToolCall(withToolProgress(ctx, calls[0], opts), calls[0].tc, opts)
+		return
+	}
+
+	var g errgroup.Group
+	g.SetLimit(maxParallelTools)
+	for i := range calls {
+		g.Go(func() error {
+			calls[i].result = al.executeToolCall(withToolProgress(ctx, calls[i], opts), calls[i].tc, opts)
+			return nil
+		})
+	}
+	g.Wait()
+}
+
+// withToolProgress lets the call stream live output (exec) into its
+// visibility action when visibility is on.
+func withToolProgress(ctx context.Context, call pendingToolCall, opts processOptions) context.Context {
+	if opts.ActionStream == nil || call.actionID == "" {
+		return ctx
+	}
+	return tools.WithProgress(ctx, func(output string) {
+		opts.ActionStream.AppendOutput(call.actionID, output)
+	})
+}
+
+func (al *AgentLoop) executeToolCall(ctx context.Context, tc providers.ToolCall, opts processOptions) *tools.ToolResult {
+	// Create async callback for tools that implement AsyncTool
+	// NOTE: Following openclaw's design, async tools do NOT send results directly to users.
+	// Instead, they notify the agent via PublishInbound, and the agent decides
+	// whether to forward the result to the user (in processSystemMessage).
+	asyncCallback := func(callbackCtx context.Context, result *tools.ToolResult) {
+		// Log the async completion but don't send directly to user
+		// The agent will handle user notification via processSystemMessage
+		if !result.Silent && result.ForUser != "" {
+			logger.InfoCF("agent", "Async tool completed, agent will handle notification",
+				map[string]interface{}{
+					"tool":        tc.Name,
+					"content_len": len(result.ForUser),
+				})
+		}
+	}
+
+	ctx = tools.WithCorrelationID(tools.WithToolCallID(ctx, tc.ID), opts.CorrelationID)
+	ctx = tools.WithSenderID(ctx, opts.SenderID)
+	return al.tools.ExecuteWithContext(ctx, tc.Name, tc.Arguments, opts.Channel, opts.ChatID, asyncCallback)
+}
+
+// finishToolCall completes the visibility action, forwards user-facing
+// output and returns the tool message for the LLM.
+func (al *AgentLoop) finishToolCall(call pendingToolCall, opts processOptions) providers.Message {
+	tc, toolResult := call.tc, call.result
+
+	// Track action completion if visibility enabled
+	if opts.ActionStream != nil && call.actionID != "" {
+		opts.ActionStream.CompleteActionWithResult(call.actionID, toolResult)
+	}
+
+	// Send ForUser content to user immediately if not Silent
+	if !toolResult.Silent && toolResult.ForUser != "" && opts.SendResponse {
+		al.bus.PublishOutbound(bus.OutboundMessage{
+			Channel:    opts.Channel,
+			ChatID:     opts.ChatID,
+			Content:    toolResult.ForUser,
+			ResultType: string(toolResult.EffectiveType()),
+			Metadata:   toolResult.Metadata,
+		})
+		logger.DebugCF("agent", "Sent tool result to user",
+			map[string]interface{}{
+				"tool":        tc.Name,
+				"content_len": len(toolResult.ForUser),
+			})
+	}
+
+	// Determine content for LLM based on tool result
+	contentForLLM := toolResult.ForLLM
+	if contentForLLM == "" && toolResult.Err != nil {
+		contentForLLM = toolResult.Err.Error()
 	}
 
-	return finalContent, iteration, nil
+	return providers.Message{
+		Role:       "tool",
+		Content:    contentForLLM,
+		ToolCallID: tc.ID,
+	}
+}
+
+// classifyLLMError returns the error_class for a failed LLM call. Failures
+// the classifier can't place are still the provider's.
+func classifyLLMError(err error) string {
+	var rateLimitErr *providers.RateLimitError
+	if errors.As(err, &rateLimitErr) {
+		return utils.ErrorClassProviderRateLimit
+	}
+	var filterErr *providers.ContentFilterError
+	if errors.As(err, &filterErr) {
+		return utils.ErrorClassContentFilter
+	}
+	if class := utils.ClassifyError(err, ""); class != utils.ErrorClassUnknown {
+		return class
+	}
+	return utils.ErrorClassProviderError
 }
 
 func shouldPublishProgress(opts processOptions) bool {
 	return opts.AllowProgressUpdates && opts.Channel != "" && opts.ChatID != ""
 }
 
+// defaultProbeLockStale applies when failover.probe_lock_stale_seconds is
+// unset; it comfortably exceeds the probe's own 20s timeout.
+const defaultProbeLockStale = 2 * time.Minute
+
 func (al *AgentLoop) maybeRunFailoverProbe() {
 	if al.failoverMgr == nil || !al.failoverMgr.Enabled() {
 		return
@@ -1126,24 +1697,53 @@ func (al *AgentLoop) maybeRunFailoverProbe() {
 	if !al.failoverMgr.ShouldProbe(time.Now()) {
 		return
 	}
-	if !al.probeRunning.CompareAndSwap(false, true) {
+	release, ok := al.acquireProbe()
+	if !ok {
 		return
 	}
 
 	go func() {
-		defer al.probeRunning.Store(false)
+		defer release()
 		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
 		defer cancel()
+		al.runFailoverProbe(ctx)
+	}()
+}
 
-		outcome := al.failoverMgr.RunProbe(ctx)
-		logger.InfoCF("agent", "Failover probe completed",
-			map[string]interface{}{
-				"success":        outcome.Success,
-				"became_healthy": outcome.BecameHealthy,
-				"next_probe_at":  outcome.NextProbeAt.UTC().Format(time.RFC3339),
-			})
+// acquireProbe claims the right to probe, in this process and across
+// instances sharing the workspace. The caller must call release when done.
+func (al *AgentLoop) acquireProbe() (release func(), ok bool) {
+	if !al.probeRunning.CompareAndSwap(false, true) {
+		return nil, false
+	}
+	// Other instances sharing this workspace may be due to probe too; the
+	// lock file makes sure only one of them hits the recovering provider.
+	stale := al.probeLockStale
+	if stale <= 0 {
+		stale = defaultProbeLockStale
+	}
+	releaseLock, locked := al.state.TryLock("failover_probe", stale)
+	if !locked {
+		al.probeRunning.Store(false)
+		logger.DebugC("agent", "Failover probe skipped: another instance holds the probe lock")
+		return nil, false
+	}
+	return func() {
+		releaseLock()
+		al.probeRunning.Store(false)
+	}, true
+}
 
-	}()
+// runFailoverProbe probes the primary model and logs the outcome.
+func (al *AgentLoop) runFailoverProbe(ctx context.Context) failover.ProbeOutcome {
+	outcome := al.failoverMgr.RunProbe(ctx)
+	logger.InfoCF("agent", "Failover probe completed",
+		map[string]interface{}{
+			"success":        outcome.Success,
+			"became_healthy": outcome.BecameHealthy,
+			"next_probe_at":  outcome.NextProbeAt.UTC().Format(time.RFC3339),
+		})
+	return outcome
 }
 
 func (al *AgentLoop) maybeSendSwitchbackPrompt(channel, chatID string) {
@@ -1168,6 +1768,9 @@ func (al *AgentLoop) maybeSendSwitchbackPrompt(channel, chatID string) {
 	})
 }
 
+// notifyFailoverSwitch tells the chat about a failover switch. Each chat is
+// told once per switch epoch, so every conversation hit by the same switch
+// learns about it.
 func (al *AgentLoop) notifyFailoverSwitch(channel, chatID string, event failover.SwitchEvent) {
 	if channel == "" || chatID == "" || !al.config.Agents.Failover.NotifyOnSwitch {
 		return
@@ -1177,16 +1780,9 @@ func (al *AgentLoop) notifyFailoverSwitch(channel, chatID string, event failover
 	if al.failoverMgr != nil {
 		epoch = al.failoverMgr.Snapshot().SwitchEpoch
 	}
-
-	al.noticeMu.Lock()
-	if epoch > 0 && epoch <= al.lastNoticeByEP {
-		al.noticeMu.Unlock()
+	if epoch > 0 && !al.markSwitchNotice(switchNoticeKey{channel: channel, chatID: chatID, epoch: epoch}) {
 		return
 	}
-	if epoch > 0 {
-		al.lastNoticeByEP = epoch
-	}
-	al.noticeMu.Unlock()
 
 	al.bus.PublishOutbound(bus.OutboundMessage{
 		Channel: channel,
@@ -1195,43 +1791,92 @@ func (al *AgentLoop) notifyFailoverSwitch(channel, chatID string, event failover
 	})
 }
 
-func providerFromModel(model string) string {
-	m := strings.ToLower(strings.TrimSpace(model))
-	switch {
-	case strings.Contains(m, "claude"), strings.Contains(m, "anthropic"):
-		return "anthropic"
-	case strings.Contains(m, "gpt"), strings.Contains(m, "openai"):
-		return "openai"
-	case strings.Contains(m, "gemini"), strings.Contains(m, "google"):
-		return "google"
-	case strings.Contains(m, "deepseek"):
-		return "deepseek"
-	default:
-		return "unknown"
+// notifyFallbackUse tells the chat that replies come from a fallback model,
+// for chats that did not see the switch itself. It shares the per-chat,
+// per-epoch record with notifyFailoverSwitch so no chat is told twice.
+func (al *AgentLoop) notifyFallbackUse(channel, chatID string, route failover.Route) {
+	if channel == "" || chatID == "" || !al.config.Agents.Failover.NotifyOnFallbackUse {
+		return
+	}
+	if constants.IsInternalChannel(channel) {
+		return
+	}
+	if !al.markSwitchNotice(switchNoticeKey{channel: channel, chatID: chatID, epoch: route.SwitchEpoch}) {
+		return
 	}
+
+	al.bus.PublishOutbound(bus.OutboundMessage{
+		Channel: channel,
+		ChatID:  chatID,
+		Content: fmt.Sprintf("Failover active: replies currently come from fallback model %s while %s is unavailable.", route.Model, al.failoverMgr.PrimaryModel()),
+	})
 }
 
-// updateToolContexts updates the context for tools that need channel/chatID info.
-func (al *AgentLoop) updateToolContexts(channel, chatID string) {
-	// Use ContextualTool interface instead of type assertions
-	if tool, ok := al.tools.Get("message"); ok {
-		if mt, ok := tool.(tools.ContextualTool); ok {
-			mt.SetContext(channel, chatID)
-		}
+// markSwitchNotice records a notice and reports whether it is new. Past
+// maxSwitchNotices the oldest notice is evicted.
+func (al *AgentLoop) markSwitchNotice(key switchNoticeKey) bool {
+	al.noticeMu.Lock()
+	defer al.noticeMu.Unlock()
+	if _, seen := al.switchNotices[key]; seen {
+		return false
 	}
-	if tool, ok := al.tools.Get("spawn"); ok {
-		if st, ok := tool.(tools.ContextualTool); ok {
-			st.SetContext(channel, chatID)
+	if len(al.switchNotices) >= maxSwitchNotices {
+		var oldestKey switchNoticeKey
+		var oldest time.Time
+		for k, at := range al.switchNotices {
+			if oldest.IsZero() || at.Before(oldest) {
+				oldestKey, oldest = k, at
+			}
 		}
+		delete(al.switchNotices, oldestKey)
 	}
-	if tool, ok := al.tools.Get("subagent"); ok {
-		if st, ok := tool.(tools.ContextualTool); ok {
-			st.SetContext(channel, chatID)
-		}
+	al.switchNotices[key] = time.Now()
+	return true
+}
+
+// usagePricesFromConfig builds the usage price table keyed by the
+// providers.* config names that usageProvider records.
+func usagePricesFromConfig(cfg *config.Config) map[string]usage.Price {
+	price := func(pc config.ProviderConfig) usage.Price {
+		return usage.Price{InputPerMTok: pc.InputPricePerMTok, OutputPerMTok: pc.OutputPricePerMTok}
+	}
+	p := cfg.Providers
+	return map[string]usage.Price{
+		"anthropic":      price(p.Anthropic),
+		"openai":         price(p.OpenAI),
+		"openrouter":     price(p.OpenRouter),
+		"groq":           price(p.Groq),
+		"zhipu":          price(p.Zhipu),
+		"vllm":           price(p.VLLM),
+		"gemini":         price(p.Gemini),
+		"nvidia":         price(p.Nvidia),
+		"moonshot":       price(p.Moonshot),
+		"shengsuanyun":   price(p.ShengSuanYun),
+		"deepseek":       price(p.DeepSeek),
+		"github_copilot": price(p.GitHubCopilot),
 	}
-	if tool, ok := al.tools.Get("send_file"); ok {
-		if sf, ok := tool.(tools.ContextualTool); ok {
-			sf.SetContext(channel, chatID)
+}
+
+// usageProvider names the provider that served model for usage records and
+// pricing: the configured provider for the default model, otherwise the one
+// detected from the model name, as CreateProviderForModel does.
+func (al *AgentLoop) usageProvider(model string) string {
+	selected := ""
+	if model == al.config.Agents.Defaults.Model {
+		selected = al.config.Agents.Defaults.Provider
+	}
+	if name := providers.ProviderName(al.config, model, selected); name != "" {
+		return name
+	}
+	return "unknown"
+}
+
+// startMessageRound resets the message tool's send tracking for a chat, so
+// Run can tell whether this turn already messaged the user.
+func (al *AgentLoop) startMessageRound(channel, chatID string) {
+	if tool, ok := al.tools.Get("message"); ok {
+		if mt, ok := tool.(*tools.MessageTool); ok {
+			mt.StartRound(channel, chatID)
 		}
 	}
 }
@@ -1244,7 +1889,9 @@ func (al *AgentLoop) maybeSummarize(sessionKey string) {
 
 	if len(newHistory) > 20 || tokenEstimate > threshold {
 		if _, loading := al.summarizing.LoadOrStore(sessionKey, true); !loading {
+			al.summaries.Add(1)
 			go func() {
+				defer al.summaries.Done()
 				defer al.summarizing.Delete(sessionKey)
 				al.summarizeSession(sessionKey)
 			}()
@@ -1269,8 +1916,10 @@ func (al *AgentLoop) GetStartupInfo() map[string]interface{} {
 	return info
 }
 
-// formatMessagesForLog formats messages for logging
-func formatMessagesForLog(messages []providers.Message) string {
+// formatMessagesForLog formats messages for logging. Tool call arguments
+// pass through redact (the registry's RedactedArgs) so secret values never
+// reach the log.
+func formatMessagesForLog(messages []providers.Message, redact func(name string, args map[string]interface{}) map[string]interface{}) string {
 	if len(messages) == 0 {
 		return "[]"
 	}
@@ -1283,8 +1932,8 @@ func formatMessagesForLog(messages []providers.Message) string {
 			result += "  ToolCalls:\n"
 			for _, tc := range msg.ToolCalls {
 				result += fmt.Sprintf("    - ID: %s, Type: %s, Name: %s\n", tc.ID, tc.Type, tc.Name)
-				if tc.Function != nil {
-					result += fmt.Sprintf("      Arguments: %s\n", utils.Truncate(tc.Function.Arguments, 200))
+				if args := toolCallArgsForLog(tc, redact); args != "" {
+					result += fmt.Sprintf("      Arguments: %s\n", utils.Truncate(args, 200))
 				}
 			}
 		}
@@ -1301,6 +1950,30 @@ func formatMessagesForLog(messages []providers.Message) string {
 	return result
 }
 
+// toolCallArgsForLog returns tc's arguments as redacted JSON. Arguments
+// that are not valid JSON are summarized rather than printed.
+func toolCallArgsForLog(tc providers.ToolCall, redact func(string, map[string]interface{}) map[string]interface{}) string {
+	name, args := tc.Name, tc.Arguments
+	if tc.Function != nil {
+		if name == "" {
+			name = tc.Function.Name
+		}
+		if args == nil && tc.Function.Arguments != "" {
+			if err := json.Unmarshal([]byte(tc.Function.Arguments), &args); err != nil {
+				return fmt.Sprintf("(unparsed, %d bytes)", len(tc.Function.Arguments))
+			}
+		}
+	}
+	if args == nil {
+		return ""
+	}
+	if redact != nil {
+		args = redact(name, args)
+	}
+	data, _ := json.Marshal(args)
+	return string(data)
+}
+
 // formatToolsForLog formats tool definitions for logging
 func formatToolsForLog(tools []providers.ToolDefinition) string {
 	if len(tools) == 0 {
@@ -1416,6 +2089,54 @@ func (al *AgentLoop) summarizeBatch(ctx context.Context, batch []providers.Messa
 	return response.Content, nil
 }
 
+// recordUsage stores the token usage of one LLM call.
+func (al *AgentLoop) recordUsage(sessionKey, model string, response *providers.LLMResponse, reason string) {
+	if al.usageStore == nil {
+		return
+	}
+	usageKnown := response.Usage != nil
+	promptTokens := 0
+	completionTokens := 0
+	totalTokens := 0
+	if usageKnown {
+		promptTokens = response.Usage.PromptTokens
+		completionTokens = response.Usage.CompletionTokens
+		totalTokens = response.Usage.TotalTokens
+	}
+	if totalTokens == 0 {
+		totalTokens = promptTokens + completionTokens
+	}
+	al.usageStore.Add(usage.Record{
+		Timestamp:        time.Now().UTC(),
+		SessionKey:       sessionKey,
+		Provider:         al.usageProvider(model),
+		Model:            model,
+		PromptTokens:     promptTokens,
+		CompletionTokens: completionTokens,
+		TotalTokens:      totalTokens,
+		UsageKnown:       usageKnown,
+		Reason:           reason,
+	})
+}
+
+// summarizeDiagnostics asks the model to explain a diagnose tool report and
+// records the call's usage against the chat the tool ran in.
+func (al *AgentLoop) summarizeDiagnostics(ctx context.Context, prompt string) (string, error) {
+	response, err := al.provider.Chat(ctx, []providers.Message{{Role: "user", Content: prompt}}, nil, al.model, map[string]interface{}{
+		"max_tokens":  1024,
+		"temperature": 0.3,
+	})
+	if err != nil {
+		return "", err
+	}
+	sessionKey := ""
+	if channel, chatID := tools.ToolContext(ctx, "", ""); channel != "" {
+		sessionKey = fmt.Sprintf("%s:%s", channel, chatID)
+	}
+	al.recordUsage(sessionKey, al.model, response, "diagnose")
+	return response.Content, nil
+}
+
 // estimateTokens estimates the number of tokens in a message list.
 // Uses rune count instead of byte length so that CJK and other multi-byte
 // characters are not over-counted (a Chinese character is 3 bytes but roughly
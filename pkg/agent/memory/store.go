@@ -0,0 +1,343 @@
+package memory
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// SourceTurn marks entries remembered from conversation turns; other
+	// entries come from the memory files and carry their relative path.
+	SourceTurn = "turn"
+	// indexFile holds the entries and vectors, next to the memory files.
+	indexFile = "embeddings.json"
+	// defaultMaxTurnEntries bounds remembered turns; the oldest go first.
+	defaultMaxTurnEntries = 2000
+	// embedBatch bounds the texts sent in one embedding request.
+	embedBatch = 64
+)
+
+// Entry is one remembered text and its embedding.
+type Entry struct {
+	Text    string    `json:"text"`
+	Source  string    `json:"source"`
+	Hash    string    `json:"hash"`
+	Vector  []float32 `json:"vector"`
+	Created time.Time `json:"created"`
+}
+
+// Result is an entry returned by Recall with its similarity to the query.
+type Result struct {
+	Entry
+	Score float64
+}
+
+// Store keeps embeddings for the memory files (MEMORY.md and the daily
+// notes, which stay the source of truth in their usual format) and for
+// remembered turns. Files are re-chunked on every Recall, but only new or
+// changed chunks are embedded.
+type Store struct {
+	dir            string
+	embedder       Embedder
+	maxTurnEntries int
+	now            func() time.Time
+
+	mu      sync.Mutex
+	entries []Entry
+	loaded  bool
+}
+
+// NewStore creates a store over the memory directory dir
+// (workspace/memory).
+func NewStore(dir string, embedder Embedder) *Store {
+	return &Store{
+		dir:            dir,
+		embedder:       embedder,
+		maxTurnEntries: defaultMaxTurnEntries,
+		now:            time.Now,
+	}
+}
+
+// Remember embeds text and stores it under source. Text already stored
+// under the same source is skipped.
+func (s *Store) Remember(ctx context.Context, source, text string) error {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil
+	}
+	hash := hashText(text)
+
+	s.mu.Lock()
+	if err := s.loadLocked(); err != nil {
+		s.mu.Unlock()
+		return err
+	}
+	for _, e := range s.entries {
+		if e.Source == source && e.Hash == hash {
+			s.mu.Unlock()
+			return nil
+		}
+	}
+	s.mu.Unlock()
+
+	vectors, err := s.embedder.Embed(ctx, []string{text})
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries = append(s.entries, Entry{Text: text, Source: source, Hash: hash, Vector: vectors[0], Created: s.now()})
+	s.pruneTurnsLocked()
+	return s.saveLocked()
+}
+
+// Recall returns up to k entries most similar to query with a score of at
+// least minScore, best first.
+func (s *Store) Recall(ctx context.Context, query string, k int, minScore float64) ([]Result, error) {
+	if err := s.syncFiles(ctx); err != nil {
+		return nil, err
+	}
+	vectors, err := s.embedder.Embed(ctx, []string{query})
+	if err != nil {
+		return nil, err
+	}
+	q := vectors[0]
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	results := make([]Result, 0, len(s.entries))
+	for _, e := range s.entries {
+		if score := cosine(q, e.Vector); score >= minScore {
+			results = append(results, Result{Entry: e, Score: score})
+		}
+	}
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Score > results[j].Score })
+	if k > 0 && len(results) > k {
+		results = results[:k]
+	}
+	return results, nil
+}
+
+// syncFiles brings the file entries in line with the memory files:
+// chunks that disappeared are dropped and new ones embedded.
+func (s *Store) syncFiles(ctx context.Context) error {
+	chunks, err := s.fileChunks()
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	if err := s.loadLocked(); err != nil {
+		s.mu.Unlock()
+		return err
+	}
+	have := make(map[string]bool)
+	kept := s.entries[:0]
+	changed := false
+	for _, e := range s.entries {
+		key := e.Source + "\x00" + e.Hash
+		if e.Source == SourceTurn || chunks[key] != "" {
+			kept = append(kept, e)
+			have[key] = true
+		} else {
+			changed = true
+		}
+	}
+	s.entries = kept
+	s.mu.Unlock()
+
+	var keys, texts []string
+	for key, text := range chunks {
+		if !have[key] {
+			keys = append(keys, key)
+			texts = append(texts, text)
+		}
+	}
+	sort.Sort(byKey{keys, texts}) // Deterministic order for batching and tests
+
+	var added []Entry
+	for start := 0; start < len(texts); start += embedBatch {
+		end := min(start+embedBatch, len(texts))
+		vectors, err := s.embedder.Embed(ctx, texts[start:end])
+		if err != nil {
+			return err
+		}
+		for i, v := range vectors {
+			source, hash, _ := strings.Cut(keys[start+i], "\x00")
+			added = append(added, Entry{Text: texts[start+i], Source: source, Hash: hash, Vector: v, Created: s.now()})
+		}
+	}
+
+	if !changed && len(added) == 0 {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries = append(s.entries, added...)
+	return s.saveLocked()
+}
+
+// fileChunks splits every Markdown file in the memory directory into
+// paragraphs, each prefixed with its section heading. Keys are
+// source + "\x00" + hash.
+func (s *Store) fileChunks() (map[string]string, error) {
+	chunks := make(map[string]string)
+	err := filepath.WalkDir(s.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() || filepath.Ext(path) != ".md" {
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(s.dir, path)
+		for _, chunk := range ChunkMarkdown(string(data)) {
+			chunks[rel+"\x00"+hashText(chunk)] = chunk
+		}
+		return nil
+	})
+	return chunks, err
+}
+
+// ChunkMarkdown splits Markdown into blank-line separated paragraphs. Each
+// paragraph is prefixed with the heading it sits under ("Heading: text").
+func ChunkMarkdown(content string) []string {
+	var chunks, para []string
+	heading := ""
+	flush := func() {
+		if len(para) == 0 {
+			return
+		}
+		text := strings.Join(para, "\n")
+		if heading != "" {
+			text = heading + ": " + text
+		}
+		chunks = append(chunks, text)
+		para = nil
+	}
+	for _, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "":
+			flush()
+		case strings.HasPrefix(trimmed, "#"):
+			flush()
+			heading = strings.TrimSpace(strings.TrimLeft(trimmed, "#"))
+		default:
+			para = append(para, trimmed)
+		}
+	}
+	flush()
+	return chunks
+}
+
+func (s *Store) pruneTurnsLocked() {
+	turns := 0
+	for _, e := range s.entries {
+		if e.Source == SourceTurn {
+			turns++
+		}
+	}
+	drop := turns - s.maxTurnEntries
+	if drop <= 0 {
+		return
+	}
+	kept := s.entries[:0]
+	for _, e := range s.entries {
+		if e.Source == SourceTurn && drop > 0 {
+			drop--
+			continue
+		}
+		kept = append(kept, e)
+	}
+	s.entries = kept
+}
+
+func (s *Store) loadLocked() error {
+	if s.loaded {
+		return nil
+	}
+	data, err := os.ReadFile(filepath.Join(s.dir, indexFile))
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read memory index: %w", err)
+	}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &s.entries); err != nil {
+			return fmt.Errorf("failed to parse memory index: %w", err)
+		}
+	}
+	s.loaded = true
+	return nil
+}
+
+// saveLocked writes the index atomically via a temp file and rename.
+func (s *Store) saveLocked() error {
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(s.entries)
+	if err != nil {
+		return fmt.Errorf("failed to marshal memory index: %w", err)
+	}
+	path := filepath.Join(s.dir, indexFile)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write memory index: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write memory index: %w", err)
+	}
+	return nil
+}
+
+func hashText(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:8])
+}
+
+func cosine(a, b []float32) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(na) * math.Sqrt(nb))
+}
+
+// byKey sorts keys and their texts together.
+type byKey struct {
+	keys, texts []string
+}
+
+func (b byKey) Len() int           { return len(b.keys) }
+func (b byKey) Less(i, j int) bool { return b.keys[i] < b.keys[j] }
+func (b byKey) Swap(i, j int) {
+	b.keys[i], b.keys[j] = b.keys[j], b.keys[i]
+	b.texts[i], b.texts[j] = b.texts[j], b.texts[i]
+}
@@ -0,0 +1,156 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// wordEmbedder embeds texts as hashed bag-of-words vectors, so texts that
+// share words are similar.
+type wordEmbedder struct {
+	calls  int
+	inputs int
+	err    error
+}
+
+func (e *wordEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	if e.err != nil {
+		return nil, e.err
+	}
+	e.calls++
+	e.inputs += len(texts)
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		v := make([]float32, 64)
+		for _, w := range strings.Fields(strings.ToLower(text)) {
+			h := fnv.New32a()
+			h.Write([]byte(strings.Trim(w, ".,:!?")))
+			v[h.Sum32()%64]++
+		}
+		vectors[i] = v
+	}
+	return vectors, nil
+}
+
+func writeMemoryFile(t *testing.T, dir, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, "MEMORY.md"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestChunkMarkdown(t *testing.T) {
+	chunks := ChunkMarkdown("# Memory\n\n## Pets\n\nThe cat is called Miso.\nShe is grey.\n\nThe dog is called Rex.\n\n## Work\nWorks at a bakery.\n")
+	want := []string{
+		"Pets: The cat is called Miso.\nShe is grey.",
+		"Pets: The dog is called Rex.",
+		"Work: Works at a bakery.",
+	}
+	if len(chunks) != len(want) {
+		t.Fatalf("chunks = %q, want %q", chunks, want)
+	}
+	for i := range want {
+		if chunks[i] != want[i] {
+			t.Errorf("chunk %d = %q, want %q", i, chunks[i], want[i])
+		}
+	}
+}
+
+func TestStore_RecallRanksRelevantMemories(t *testing.T) {
+	dir := t.TempDir()
+	writeMemoryFile(t, dir, "## Pets\n\nThe cat is called Miso.\n\n## Work\n\nThe user works night shifts at a bakery.\n")
+	emb := &wordEmbedder{}
+	s := NewStore(dir, emb)
+	ctx := context.Background()
+
+	if err := s.Remember(ctx, SourceTurn, "my sister lives in Lisbon and visits every summer"); err != nil {
+		t.Fatalf("Remember: %v", err)
+	}
+
+	results, err := s.Recall(ctx, "what is the cat called?", 1, 0.1)
+	if err != nil {
+		t.Fatalf("Recall: %v", err)
+	}
+	if len(results) != 1 || !strings.Contains(results[0].Text, "Miso") || results[0].Source != "MEMORY.md" {
+		t.Fatalf("results = %+v, want the cat memory from MEMORY.md", results)
+	}
+
+	results, err = s.Recall(ctx, "when does my sister visit from Lisbon?", 1, 0.1)
+	if err != nil {
+		t.Fatalf("Recall: %v", err)
+	}
+	if len(results) != 1 || results[0].Source != SourceTurn {
+		t.Fatalf("results = %+v, want the remembered turn", results)
+	}
+
+	results, err = s.Recall(ctx, "quantum chromodynamics", 5, 0.5)
+	if err != nil {
+		t.Fatalf("Recall: %v", err)
+	}
+	if len(results) != 0 {
+		t.Errorf("unrelated query recalled %+v", results)
+	}
+}
+
+func TestStore_EmbedsOnlyNewChunksAndPersists(t *testing.T) {
+	dir := t.TempDir()
+	writeMemoryFile(t, dir, "## Pets\n\nThe cat is called Miso.\n")
+	emb := &wordEmbedder{}
+	ctx := context.Background()
+
+	s := NewStore(dir, emb)
+	if _, err := s.Recall(ctx, "cat", 5, 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Recall(ctx, "cat", 5, 0); err != nil {
+		t.Fatal(err)
+	}
+	// One chunk plus two queries: the unchanged chunk is not re-embedded.
+	if emb.inputs != 3 {
+		t.Errorf("embedded %d texts, want 3", emb.inputs)
+	}
+
+	// A changed file replaces its old chunks; a fresh store reuses the index.
+	writeMemoryFile(t, dir, "## Pets\n\nThe cat is called Tofu.\n")
+	emb2 := &wordEmbedder{}
+	results, err := NewStore(dir, emb2).Recall(ctx, "cat", 5, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || !strings.Contains(results[0].Text, "Tofu") {
+		t.Errorf("results = %+v, want only the new chunk", results)
+	}
+	if emb2.inputs != 2 {
+		t.Errorf("embedded %d texts after the edit, want 2", emb2.inputs)
+	}
+}
+
+func TestStore_RememberDedupsAndCapsTurns(t *testing.T) {
+	dir := t.TempDir()
+	s := NewStore(dir, &wordEmbedder{})
+	s.maxTurnEntries = 2
+	ctx := context.Background()
+
+	for _, text := range []string{"first fact here", "first fact here", "second fact here", "third fact here"} {
+		if err := s.Remember(ctx, SourceTurn, text); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(s.entries) != 2 || s.entries[0].Text != "second fact here" || s.entries[1].Text != "third fact here" {
+		t.Errorf("entries = %+v, want the two newest distinct turns", s.entries)
+	}
+}
+
+func TestStore_RecallReportsEmbedderErrors(t *testing.T) {
+	dir := t.TempDir()
+	writeMemoryFile(t, dir, "The cat is called Miso.\n")
+	s := NewStore(dir, &wordEmbedder{err: errors.New("boom")})
+	if _, err := s.Recall(context.Background(), "cat", 5, 0); err == nil {
+		t.Fatal("expected an error")
+	}
+}
@@ -13,11 +13,24 @@ import (
 )
 
 type executionPlanState struct {
+	Decided   bool // The first tool batch was seen; plans are only made for it
 	Announced bool
 	Bullets   []string
 	Allowed   map[string]struct{}
+	Status    []planStepStatus // Per bullet; shorter than Bullets while new steps are pending
+	Path      string           // Plan file, "" when it could not be written
+	Meta      planFileMetadata
+	Created   time.Time
 }
 
+type planStepStatus int
+
+const (
+	planStepPending planStepStatus = iota
+	planStepDone
+	planStepFailed
+)
+
 func newExecutionPlanState() *executionPlanState {
 	return &executionPlanState{
 		Allowed: make(map[string]struct{}),
@@ -42,6 +55,89 @@ func (s *executionPlanState) isAllowedTool(name string) bool {
 	return ok
 }
 
+// recordToolResult marks the step a finished tool call belongs to as done or
+// failed and returns its index (-1 without steps). A call belongs to the
+// step worded like its summary (fallback plans and plan updates), else to
+// the first step still pending, since planner steps run in order. A failed
+// step that is retried and succeeds counts as done.
+func (s *executionPlanState) recordToolResult(tc providers.ToolCall, failed bool) int {
+	for len(s.Status) < len(s.Bullets) {
+		s.Status = append(s.Status, planStepPending)
+	}
+	if len(s.Bullets) == 0 {
+		return -1
+	}
+
+	step := -1
+	summary := summarizeToolCallForPlan(tc)
+	for i, b := range s.Bullets {
+		if strings.EqualFold(b, summary) {
+			step = i
+			break
+		}
+	}
+	if step == -1 {
+		for i, st := range s.Status {
+			if st == planStepPending {
+				step = i
+				break
+			}
+		}
+	}
+	if step == -1 {
+		// Every step already ran; attribute extra calls to the last one.
+		step = len(s.Bullets) - 1
+	}
+
+	switch {
+	case !failed:
+		s.Status[step] = planStepDone
+	case s.Status[step] != planStepDone:
+		s.Status[step] = planStepFailed
+	}
+	return step
+}
+
+func (s *executionPlanState) stepStatus(i int) planStepStatus {
+	if i < len(s.Status) {
+		return s.Status[i]
+	}
+	return planStepPending
+}
+
+// formatPlanCompletion summarizes how the plan went, e.g.
+// "Plan complete: 4/5 steps done, step 3 failed."
+func formatPlanCompletion(s *executionPlanState) string {
+	var done int
+	var failed, notRun []string
+	for i := range s.Bullets {
+		switch s.stepStatus(i) {
+		case planStepDone:
+			done++
+		case planStepFailed:
+			failed = append(failed, fmt.Sprintf("%d", i+1))
+		default:
+			notRun = append(notRun, fmt.Sprintf("%d", i+1))
+		}
+	}
+
+	msg := fmt.Sprintf("Plan complete: %d/%d steps done", done, len(s.Bullets))
+	if len(failed) > 0 {
+		msg += ", " + formatPlanStepList(failed) + " failed"
+	}
+	if len(notRun) > 0 {
+		msg += ", " + formatPlanStepList(notRun) + " not run"
+	}
+	return msg + "."
+}
+
+func formatPlanStepList(steps []string) string {
+	if len(steps) == 1 {
+		return "step " + steps[0]
+	}
+	return "steps " + strings.Join(steps, ", ")
+}
+
 func buildExecutionPlanBullets(toolCalls []providers.ToolCall) []string {
 	seen := make(map[string]struct{})
 	bullets := make([]string, 0, len(toolCalls))
@@ -153,33 +249,66 @@ func writeExecutionPlanFile(workspace string, bullets []string, meta planFileMet
 	filename := fmt.Sprintf("%s_%s.md", now.UTC().Format("2006-01-02_150405"), slug)
 	path := filepath.Join(planDir, filename)
 
+	state := &executionPlanState{Bullets: bullets, Meta: meta, Created: now}
+	if err := writePlanFileAtomic(path, renderExecutionPlanFile(state, "")); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// updateExecutionPlanFile rewrites the plan file with each step's checkbox
+// ticked or marked failed. A non-empty result replaces the adaptation note
+// once the turn is over.
+func updateExecutionPlanFile(s *executionPlanState, result string) error {
+	if s.Path == "" {
+		return nil
+	}
+	return writePlanFileAtomic(s.Path, renderExecutionPlanFile(s, result))
+}
+
+func renderExecutionPlanFile(s *executionPlanState, result string) string {
 	var lines []string
 	lines = append(lines, "---")
-	lines = append(lines, fmt.Sprintf("session_key: %q", meta.SessionKey))
-	lines = append(lines, fmt.Sprintf("correlation_id: %q", meta.CorrelationID))
-	lines = append(lines, fmt.Sprintf("model: %q", meta.Model))
-	lines = append(lines, fmt.Sprintf("created_at_utc: %q", now.UTC().Format(time.RFC3339)))
+	lines = append(lines, fmt.Sprintf("session_key: %q", s.Meta.SessionKey))
+	lines = append(lines, fmt.Sprintf("correlation_id: %q", s.Meta.CorrelationID))
+	lines = append(lines, fmt.Sprintf("model: %q", s.Meta.Model))
+	lines = append(lines, fmt.Sprintf("created_at_utc: %q", s.Created.UTC().Format(time.RFC3339)))
 	lines = append(lines, "plan_mode: true")
 	lines = append(lines, "---")
 	lines = append(lines, "")
 	lines = append(lines, "# Execution Plan")
 	lines = append(lines, "")
-	for i, b := range bullets {
-		lines = append(lines, fmt.Sprintf("%d. %s", i+1, b))
+	for i, b := range s.Bullets {
+		switch s.stepStatus(i) {
+		case planStepDone:
+			lines = append(lines, fmt.Sprintf("- [x] %d. %s", i+1, b))
+		case planStepFailed:
+			lines = append(lines, fmt.Sprintf("- [ ] %d. %s (failed)", i+1, b))
+		default:
+			lines = append(lines, fmt.Sprintf("- [ ] %d. %s", i+1, b))
+		}
 	}
 	lines = append(lines, "")
-	lines = append(lines, "_Note: plan may adapt if a step fails._")
-	content := strings.Join(lines, "\n") + "\n"
+	if result != "" {
+		lines = append(lines, "_"+result+"_")
+	} else {
+		lines = append(lines, "_Note: plan may adapt if a step fails._")
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
 
+// writePlanFileAtomic writes via a temp file and rename so readers never
+// see a partial plan.
+func writePlanFileAtomic(path, content string) error {
 	tmpPath := path + ".tmp"
 	if err := os.WriteFile(tmpPath, []byte(content), 0644); err != nil {
-		return "", err
+		return err
 	}
 	if err := os.Rename(tmpPath, path); err != nil {
 		_ = os.Remove(tmpPath)
-		return "", err
+		return err
 	}
-	return path, nil
+	return nil
 }
 
 func firstNonEmptyPlanStep(bullets []string) string {
@@ -1,12 +1,16 @@
 package agent
 
 import (
+	"context"
+	"errors"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/config"
 	"github.com/sipeed/picoclaw/pkg/providers"
 )
 
@@ -143,3 +147,307 @@ func TestParseExecutionPlanBullets_Numbered(t *testing.T) {
 		t.Fatalf("unexpected first bullet: %q", got[0])
 	}
 }
+
+// drainOutbound returns everything published to the bus so far.
+func drainOutbound(al *AgentLoop) []bus.OutboundMessage {
+	var out []bus.OutboundMessage
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		msg, ok := al.bus.SubscribeOutbound(ctx)
+		cancel()
+		if !ok {
+			return out
+		}
+		out = append(out, msg)
+	}
+}
+
+func TestPlanDelivery_RedirectsPlanAwayFromUserChat(t *testing.T) {
+	for _, delivery := range []string{"chat", "admin", "log"} {
+		t.Run(delivery, func(t *testing.T) {
+			cfg := &config.Config{
+				Agents: config.AgentsConfig{
+					Defaults: config.AgentDefaults{
+						Workspace:         t.TempDir(),
+						Model:             "test-model",
+						MaxTokens:         4096,
+						MaxToolIterations: 10,
+					},
+					Planner: config.AgentPlanner{
+						AnnouncePlan: true,
+						PlanDelivery: delivery,
+						AdminChannel: "telegram",
+						AdminChatID:  "admin",
+					},
+				},
+			}
+			provider := &toolCallingProvider{toolCalls: []providers.ToolCall{
+				{ID: "c1", Name: "mutate", Arguments: map[string]interface{}{"id": 1}},
+			}}
+			al := NewAgentLoop(cfg, bus.NewMessageBus(), provider)
+			al.RegisterTool(&recordingTool{})
+
+			msg := bus.InboundMessage{Channel: "telegram", ChatID: "1", SenderID: "u", SessionKey: "telegram:1", Content: "do it"}
+			if _, err := al.processMessage(context.Background(), msg); err != nil {
+				t.Fatalf("processMessage() error: %v", err)
+			}
+
+			var userPlans, adminPlans int
+			for _, out := range drainOutbound(al) {
+				if !strings.Contains(out.Content, "Execution plan:") {
+					continue
+				}
+				switch out.ChatID {
+				case "1":
+					userPlans++
+				case "admin":
+					adminPlans++
+					if !strings.HasPrefix(out.Content, "[telegram:1] ") {
+						t.Errorf("admin plan lacks source chat: %q", out.Content)
+					}
+				}
+			}
+
+			wantUser, wantAdmin := 0, 0
+			switch delivery {
+			case "chat":
+				wantUser = 1
+			case "admin":
+				wantAdmin = 1
+			}
+			if userPlans != wantUser || adminPlans != wantAdmin {
+				t.Fatalf("plans sent to user chat = %d, admin chat = %d; want %d, %d", userPlans, adminPlans, wantUser, wantAdmin)
+			}
+		})
+	}
+}
+
+func TestExecutionPlanState_RecordToolResult(t *testing.T) {
+	s := newExecutionPlanState()
+	s.Bullets = []string{"Inspect the repo", "Run go test", "Write patch"}
+	goTest := providers.ToolCall{Name: "exec", Arguments: map[string]interface{}{"command": "go test ./..."}}
+
+	// Planner wording: calls fill the steps in order.
+	if got := s.recordToolResult(providers.ToolCall{Name: "list_dir"}, false); got != 0 {
+		t.Fatalf("list_dir mapped to step %d, want 0", got)
+	}
+	// Matching wording wins over order.
+	if got := s.recordToolResult(providers.ToolCall{Name: "write_file", Arguments: map[string]interface{}{"path": "patch"}}, false); got != 2 {
+		t.Fatalf("write_file mapped to step %d, want 2", got)
+	}
+	if got := s.recordToolResult(goTest, true); got != 1 {
+		t.Fatalf("go test mapped to step %d, want 1", got)
+	}
+	if got, want := formatPlanCompletion(s), "Plan complete: 2/3 steps done, step 2 failed."; got != want {
+		t.Fatalf("formatPlanCompletion() = %q, want %q", got, want)
+	}
+
+	// A successful retry counts; a later failure does not undo it.
+	s.recordToolResult(goTest, false)
+	s.recordToolResult(goTest, true)
+	if got, want := formatPlanCompletion(s), "Plan complete: 3/3 steps done."; got != want {
+		t.Fatalf("formatPlanCompletion() = %q, want %q", got, want)
+	}
+
+	// Steps added by plan updates start pending.
+	s.Bullets = append(s.Bullets, "Run web_search", "Run message")
+	if got, want := formatPlanCompletion(s), "Plan complete: 3/5 steps done, steps 4, 5 not run."; got != want {
+		t.Fatalf("formatPlanCompletion() = %q, want %q", got, want)
+	}
+}
+
+func TestRunLLMIteration_RecordsPlanProgress(t *testing.T) {
+	workspace := t.TempDir()
+	notes := filepath.Join(workspace, "notes.txt")
+	if err := os.WriteFile(notes, []byte("hello"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{
+				Workspace:         workspace,
+				Model:             "test-model",
+				MaxTokens:         4096,
+				MaxToolIterations: 10,
+			},
+			Planner: config.AgentPlanner{AnnouncePlan: true},
+		},
+	}
+	provider := &toolCallingProvider{toolCalls: []providers.ToolCall{
+		{ID: "c1", Name: "read_file", Arguments: map[string]interface{}{"path": notes}},
+		{ID: "c2", Name: "mutate", Arguments: map[string]interface{}{"id": 1}},
+		{ID: "c3", Name: "read_file", Arguments: map[string]interface{}{"path": filepath.Join(workspace, "missing.txt")}},
+	}}
+	al := NewAgentLoop(cfg, bus.NewMessageBus(), provider)
+	al.RegisterTool(&recordingTool{})
+
+	msg := bus.InboundMessage{Channel: "telegram", ChatID: "1", SenderID: "u", SessionKey: "telegram:1", Content: "do it"}
+	if _, err := al.processMessage(context.Background(), msg); err != nil {
+		t.Fatalf("processMessage() error: %v", err)
+	}
+
+	const summary = "Plan complete: 2/3 steps done, step 3 failed."
+	sent := false
+	for _, out := range drainOutbound(al) {
+		if out.Content == summary && out.ChatID == "1" {
+			sent = true
+		}
+	}
+	if !sent {
+		t.Errorf("completion summary %q not sent", summary)
+	}
+
+	files, err := filepath.Glob(filepath.Join(workspace, "plans", "*.md"))
+	if err != nil || len(files) != 1 {
+		t.Fatalf("plan files = %v (%v), want one", files, err)
+	}
+	content, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"- [x] 1. Read notes.txt",
+		"- [x] 2. Run mutate",
+		"- [ ] 3. Read missing.txt (failed)",
+		"_" + summary + "_",
+	} {
+		if !strings.Contains(string(content), want) {
+			t.Errorf("plan file missing %q:\n%s", want, content)
+		}
+	}
+}
+
+func TestPlanMode_MinToolCallsAndAnnouncePlan(t *testing.T) {
+	twoCalls := []providers.ToolCall{
+		{ID: "c1", Name: "mutate", Arguments: map[string]interface{}{"id": 1}},
+		{ID: "c2", Name: "mutate", Arguments: map[string]interface{}{"id": 2}},
+	}
+	for _, tc := range []struct {
+		name     string
+		planner  config.AgentPlanner
+		wantPlan bool
+	}{
+		{"threshold met", config.AgentPlanner{AnnouncePlan: true, MinToolCalls: 2}, true},
+		{"default threshold", config.AgentPlanner{AnnouncePlan: true}, true},
+		{"below threshold", config.AgentPlanner{AnnouncePlan: true, MinToolCalls: 3}, false},
+		{"silenced", config.AgentPlanner{AnnouncePlan: false, MinToolCalls: 1}, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			workspace := t.TempDir()
+			cfg := &config.Config{
+				Agents: config.AgentsConfig{
+					Defaults: config.AgentDefaults{
+						Workspace:         workspace,
+						Model:             "test-model",
+						MaxTokens:         4096,
+						MaxToolIterations: 10,
+					},
+					Planner: tc.planner,
+				},
+			}
+			tool := &recordingTool{}
+			al := NewAgentLoop(cfg, bus.NewMessageBus(), &toolCallingProvider{toolCalls: twoCalls})
+			al.RegisterTool(tool)
+
+			msg := bus.InboundMessage{Channel: "telegram", ChatID: "1", SenderID: "u", SessionKey: "telegram:1", Content: "do it"}
+			if _, err := al.processMessage(context.Background(), msg); err != nil {
+				t.Fatalf("processMessage() error: %v", err)
+			}
+			if len(tool.ran) != 2 {
+				t.Fatalf("tool ran %d times, want 2", len(tool.ran))
+			}
+
+			var planMsgs, placeholders int
+			for _, out := range drainOutbound(al) {
+				switch {
+				case strings.Contains(out.Content, "Execution plan:"):
+					planMsgs++
+				case strings.HasPrefix(out.Content, "Working..."):
+					placeholders++
+				}
+			}
+			files, _ := filepath.Glob(filepath.Join(workspace, "plans", "*.md"))
+
+			want := 0
+			if tc.wantPlan {
+				want = 1
+			}
+			if planMsgs != want || placeholders != want || len(files) != want {
+				t.Fatalf("plan messages = %d, placeholders = %d, plan files = %d; want %d each", planMsgs, placeholders, len(files), want)
+			}
+		})
+	}
+}
+
+// plannerProvider answers planner calls with plan (or fails them when
+// plan is empty) and behaves like toolCallingProvider otherwise.
+type plannerProvider struct {
+	toolCallingProvider
+	plan         string
+	plannerCalls int
+}
+
+func (p *plannerProvider) Chat(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, model string, opts map[string]interface{}) (*providers.LLMResponse, error) {
+	if len(messages) > 0 && messages[0].Content == plannerSystemPrompt {
+		p.plannerCalls++
+		if p.plan == "" {
+			return nil, errors.New("planner unavailable")
+		}
+		return &providers.LLMResponse{Content: p.plan, FinishReason: "stop"}, nil
+	}
+	return p.toolCallingProvider.Chat(ctx, messages, tools, model, opts)
+}
+
+func TestPlanMode_UsesPlannerModelWithFallback(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		enabled  bool
+		plan     string
+		wantStep string
+		wantCall int
+	}{
+		{"planner plan", true, "1. Gather the inputs\n2. Apply the change\n", "1. Gather the inputs", 1},
+		{"planner error", true, "", "1. Run mutate", 1},
+		{"planner disabled", false, "1. Gather the inputs\n", "1. Run mutate", 0},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := &config.Config{
+				Agents: config.AgentsConfig{
+					Defaults: config.AgentDefaults{
+						Workspace:         t.TempDir(),
+						Model:             "test-model",
+						MaxTokens:         4096,
+						MaxToolIterations: 10,
+					},
+					Planner: config.AgentPlanner{Enabled: tc.enabled, Model: "test-model", AnnouncePlan: true},
+				},
+			}
+			provider := &plannerProvider{
+				toolCallingProvider: toolCallingProvider{toolCalls: []providers.ToolCall{
+					{ID: "c1", Name: "mutate", Arguments: map[string]interface{}{"id": 1}},
+				}},
+				plan: tc.plan,
+			}
+			al := NewAgentLoop(cfg, bus.NewMessageBus(), provider)
+			al.RegisterTool(&recordingTool{})
+
+			msg := bus.InboundMessage{Channel: "telegram", ChatID: "1", SenderID: "u", SessionKey: "telegram:1", Content: "do it"}
+			if _, err := al.processMessage(context.Background(), msg); err != nil {
+				t.Fatalf("processMessage() error: %v", err)
+			}
+			if provider.plannerCalls != tc.wantCall {
+				t.Fatalf("planner called %d times, want %d", provider.plannerCalls, tc.wantCall)
+			}
+
+			var plan string
+			for _, out := range drainOutbound(al) {
+				if strings.Contains(out.Content, "Execution plan:") {
+					plan = out.Content
+				}
+			}
+			if !strings.Contains(plan, tc.wantStep) {
+				t.Fatalf("plan = %q, want it to contain %q", plan, tc.wantStep)
+			}
+		})
+	}
+}
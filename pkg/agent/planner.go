@@ -6,6 +6,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/sipeed/picoclaw/pkg/bus"
 	"github.com/sipeed/picoclaw/pkg/logger"
 	"github.com/sipeed/picoclaw/pkg/providers"
 	"github.com/sipeed/picoclaw/pkg/usage"
@@ -110,8 +111,7 @@ func (al *AgentLoop) generateExecutionPlanBullets(ctx context.Context, opts proc
 		al.usageStore.Add(usage.Record{
 			Timestamp:        time.Now().UTC(),
 			SessionKey:       opts.SessionKey,
-			DayKey:           time.Now().UTC().Format("2006-01-02"),
-			Provider:         providerFromModel(plannerModel),
+			Provider:         al.usageProvider(plannerModel),
 			Model:            plannerModel,
 			PromptTokens:     promptTokens,
 			CompletionTokens: completionTokens,
@@ -132,3 +132,83 @@ func (al *AgentLoop) generateExecutionPlanBullets(ctx context.Context, opts proc
 	}
 	return parsed, plannerModel
 }
+
+// wantsPlan reports whether a turn whose first tool batch has toolCalls
+// calls runs in plan+execute mode (agents.planner.announce_plan and
+// min_tool_calls).
+func (al *AgentLoop) wantsPlan(toolCalls int) bool {
+	plannerCfg := al.config.Agents.Planner
+	return plannerCfg.AnnouncePlan && toolCalls >= max(plannerCfg.MinToolCalls, 1)
+}
+
+// publishPlan delivers an execution plan or plan update where
+// agents.planner.plan_delivery sends it and reports whether it went to the
+// triggering chat. Deployments that hide plans from users can route them
+// to an admin chat or to the log only.
+func (al *AgentLoop) publishPlan(opts processOptions, content string, isProgressUpdate bool) bool {
+	if !shouldPublishProgress(opts) {
+		return false
+	}
+
+	plannerCfg := al.config.Agents.Planner
+	switch strings.ToLower(strings.TrimSpace(plannerCfg.PlanDelivery)) {
+	case "log":
+		logger.InfoCF("agent", "Execution plan (not sent to chat)",
+			map[string]interface{}{
+				"plan":           content,
+				"session_key":    opts.SessionKey,
+				"correlation_id": opts.CorrelationID,
+			})
+		return false
+	case "admin":
+		if plannerCfg.AdminChannel == "" || plannerCfg.AdminChatID == "" {
+			logger.WarnCF("agent", "Plan delivery is admin but no admin chat is configured; plan only logged",
+				map[string]interface{}{
+					"plan":        content,
+					"session_key": opts.SessionKey,
+				})
+			return false
+		}
+		al.bus.PublishOutbound(bus.OutboundMessage{
+			Channel: plannerCfg.AdminChannel,
+			ChatID:  plannerCfg.AdminChatID,
+			Content: fmt.Sprintf("[%s:%s] %s", opts.Channel, opts.ChatID, content),
+		})
+		return false
+	}
+
+	al.bus.PublishOutbound(bus.OutboundMessage{
+		Channel:          opts.Channel,
+		ChatID:           opts.ChatID,
+		Content:          content,
+		IsProgressUpdate: isProgressUpdate,
+	})
+	return true
+}
+
+// updatePlanFile records step progress in the plan file; failures are only
+// logged since the plan file is informational.
+func (al *AgentLoop) updatePlanFile(planState *executionPlanState, result string) {
+	if err := updateExecutionPlanFile(planState, result); err != nil {
+		logger.WarnCF("agent", "Failed to update execution plan file",
+			map[string]interface{}{
+				"path":  planState.Path,
+				"error": err.Error(),
+			})
+	}
+}
+
+// finishPlan records the outcome of the turn's plan in the plan file and
+// reports it, e.g. "Plan complete: 4/5 steps done, step 3 failed."
+func (al *AgentLoop) finishPlan(planState *executionPlanState, opts processOptions) {
+	summary := formatPlanCompletion(planState)
+	al.updatePlanFile(planState, summary)
+	logger.InfoCF("agent", "Execution plan finished",
+		map[string]interface{}{
+			"summary":        summary,
+			"path":           planState.Path,
+			"session_key":    opts.SessionKey,
+			"correlation_id": opts.CorrelationID,
+		})
+	al.publishPlan(opts, summary, false)
+}
@@ -0,0 +1,121 @@
+package agent
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/sipeed/picoclaw/pkg/skills"
+)
+
+// systemPromptSections holds the pieces of the system prompt before rendering.
+// Trimming order when over budget: memory, then skills (from the end of the
+// list, i.e. builtin before global before workspace), then bootstrap files.
+// The identity section is never trimmed.
+type systemPromptSections struct {
+	Identity  string
+	Bootstrap string
+	Skills    []skills.SkillInfo
+	Memory    string
+	Notes     []string
+}
+
+// estimatePromptTokens mirrors AgentLoop.estimateTokens (runes / 3).
+func estimatePromptTokens(s string) int {
+	return utf8.RuneCountInString(s) / 3
+}
+
+// renderSystemPrompt joins the sections with the "---" separator.
+func renderSystemPrompt(s systemPromptSections, summarizeSkills func([]skills.SkillInfo) string) string {
+	parts := []string{s.Identity}
+
+	if s.Bootstrap != "" {
+		parts = append(parts, s.Bootstrap)
+	}
+
+	// Skills - show summary, AI can read full content with read_file tool
+	if len(s.Skills) > 0 {
+		if summary := summarizeSkills(s.Skills); summary != "" {
+			parts = append(parts, fmt.Sprintf(`# Skills
+
+The following skills extend your capabilities. To use a skill, read its SKILL.md file using the read_file tool.
+
+%s`, summary))
+		}
+	}
+
+	if s.Memory != "" {
+		parts = append(parts, "# Memory\n\n"+s.Memory)
+	}
+
+	if len(s.Notes) > 0 {
+		parts = append(parts, "[Note: system prompt trimmed to fit token budget: "+strings.Join(s.Notes, "; ")+"]")
+	}
+
+	return strings.Join(parts, "\n\n---\n\n")
+}
+
+// fitSystemPromptBudget trims lower-priority sections until the rendered
+// prompt fits within budget tokens (or nothing trimmable is left).
+func fitSystemPromptBudget(s systemPromptSections, budget int, summarizeSkills func([]skills.SkillInfo) string) systemPromptSections {
+	if budget <= 0 {
+		return s
+	}
+	over := func() int {
+		return estimatePromptTokens(renderSystemPrompt(s, summarizeSkills)) - budget
+	}
+
+	// 1. Memory: truncate, then drop entirely.
+	if over() > 0 && s.Memory != "" {
+		// Account for the trim note before measuring how much memory to keep.
+		s.Notes = append(s.Notes, "memory truncated")
+		keep := estimatePromptTokens(s.Memory) - over() - 2
+		if keep > 0 {
+			s.Memory = truncateToTokens(s.Memory, keep)
+		}
+		if keep <= 0 || over() > 0 {
+			s.Memory = ""
+			s.Notes[len(s.Notes)-1] = "memory omitted"
+		}
+	}
+
+	// 2. Skills: drop from the end until we fit.
+	if over() > 0 && len(s.Skills) > 0 {
+		noteIdx := len(s.Notes)
+		s.Notes = append(s.Notes, "")
+		dropped := 0
+		for over() > 0 && len(s.Skills) > 0 {
+			s.Skills = s.Skills[:len(s.Skills)-1]
+			dropped++
+			s.Notes[noteIdx] = fmt.Sprintf("%d skill(s) omitted", dropped)
+		}
+	}
+
+	// 3. Bootstrap files: truncate as a last resort.
+	if over() > 0 && s.Bootstrap != "" {
+		s.Notes = append(s.Notes, "bootstrap files truncated")
+		keep := estimatePromptTokens(s.Bootstrap) - over() - 2
+		if keep > 0 {
+			s.Bootstrap = truncateToTokens(s.Bootstrap, keep)
+		}
+		if keep <= 0 || over() > 0 {
+			s.Bootstrap = ""
+		}
+	}
+
+	return s
+}
+
+// truncateToTokens cuts s to roughly maxTokens tokens on a rune boundary.
+func truncateToTokens(s string, maxTokens int) string {
+	const marker = "\n...[truncated]"
+	maxRunes := maxTokens*3 - utf8.RuneCountInString(marker)
+	if maxRunes <= 0 {
+		return ""
+	}
+	runes := []rune(s)
+	if len(runes) <= maxRunes {
+		return s
+	}
+	return string(runes[:maxRunes]) + marker
+}
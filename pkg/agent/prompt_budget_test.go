@@ -0,0 +1,101 @@
+package agent
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/skills"
+)
+
+func testPromptSections() systemPromptSections {
+	return systemPromptSections{
+		Identity:  "# picoclaw\n\nYou are picoclaw.",
+		Bootstrap: "## SOUL.md\n\n" + strings.Repeat("soul ", 60),
+		Skills: []skills.SkillInfo{
+			{Name: "workspace-skill", Path: "/ws/skills/a/SKILL.md", Source: "workspace", Description: "first"},
+			{Name: "global-skill", Path: "/g/skills/b/SKILL.md", Source: "global", Description: "second"},
+			{Name: "builtin-skill", Path: "/b/skills/c/SKILL.md", Source: "builtin", Description: "third"},
+		},
+		Memory: "## Long-term Memory\n\n" + strings.Repeat("remember this ", 300),
+	}
+}
+
+func TestFitSystemPromptBudget_TruncatesMemoryFirst(t *testing.T) {
+	sections := testPromptSections()
+	full := estimatePromptTokens(renderSystemPrompt(sections, skills.BuildSkillsSummaryFor))
+	budget := full - 500
+
+	trimmed := fitSystemPromptBudget(sections, budget, skills.BuildSkillsSummaryFor)
+	rendered := renderSystemPrompt(trimmed, skills.BuildSkillsSummaryFor)
+
+	if got := estimatePromptTokens(rendered); got > budget {
+		t.Fatalf("rendered tokens = %d, want <= %d", got, budget)
+	}
+	if trimmed.Memory == "" || !strings.Contains(trimmed.Memory, "[truncated]") {
+		t.Fatalf("memory should be truncated, got %q", trimmed.Memory)
+	}
+	if len(trimmed.Skills) != 3 {
+		t.Fatalf("skills = %d, want all 3 kept when memory trimming suffices", len(trimmed.Skills))
+	}
+	if trimmed.Bootstrap != sections.Bootstrap {
+		t.Fatal("bootstrap should be untouched")
+	}
+	if !strings.Contains(rendered, "memory truncated") {
+		t.Fatal("rendered prompt should include a trim note")
+	}
+}
+
+func TestFitSystemPromptBudget_DropsSkillsFromEnd(t *testing.T) {
+	sections := testPromptSections()
+	noMemory := sections
+	noMemory.Memory = ""
+	noMemory.Skills = sections.Skills[:1]
+	noMemory.Notes = []string{"memory omitted", "2 skill(s) omitted"}
+	budget := estimatePromptTokens(renderSystemPrompt(noMemory, skills.BuildSkillsSummaryFor))
+
+	trimmed := fitSystemPromptBudget(sections, budget, skills.BuildSkillsSummaryFor)
+
+	if trimmed.Memory != "" {
+		t.Fatal("memory should be dropped before skills")
+	}
+	if len(trimmed.Skills) != 1 || trimmed.Skills[0].Name != "workspace-skill" {
+		t.Fatalf("skills = %+v, want only workspace-skill", trimmed.Skills)
+	}
+	if trimmed.Bootstrap != sections.Bootstrap {
+		t.Fatal("bootstrap should only be trimmed after memory and skills")
+	}
+	if got := estimatePromptTokens(renderSystemPrompt(trimmed, skills.BuildSkillsSummaryFor)); got > budget {
+		t.Fatalf("rendered tokens = %d, want <= %d", got, budget)
+	}
+}
+
+func TestFitSystemPromptBudget_NeverTrimsIdentity(t *testing.T) {
+	sections := testPromptSections()
+
+	trimmed := fitSystemPromptBudget(sections, 10, skills.BuildSkillsSummaryFor)
+
+	if trimmed.Identity != sections.Identity {
+		t.Fatal("identity must never be trimmed")
+	}
+	if trimmed.Memory != "" || len(trimmed.Skills) != 0 {
+		t.Fatal("all lower-priority sections should be removed under a tiny budget")
+	}
+}
+
+func TestBuildSystemPrompt_RespectsBudget(t *testing.T) {
+	workspace := t.TempDir()
+	cb := NewContextBuilder(workspace)
+	if err := os.WriteFile(filepath.Join(workspace, "memory", "MEMORY.md"), []byte(strings.Repeat("fact ", 5000)), 0644); err != nil {
+		t.Fatalf("write memory: %v", err)
+	}
+
+	unbounded := cb.BuildSystemPrompt()
+	budget := estimatePromptTokens(unbounded) / 2
+	cb.SetSystemPromptBudget(budget)
+
+	if got := estimatePromptTokens(cb.BuildSystemPrompt()); got > budget {
+		t.Fatalf("system prompt tokens = %d, want <= %d", got, budget)
+	}
+}
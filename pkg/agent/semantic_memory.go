@@ -0,0 +1,69 @@
+package agent
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/sipeed/picoclaw/pkg/agent/memory"
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// With agents.memory.embedding_model set, the memory files and salient user
+// messages are embedded, and prompts carry only the memories relevant to
+// the current message (ContextBuilder.memoryContext). Without it, or when
+// the embedding API fails, the memory files are injected whole as before.
+
+// rememberTimeout bounds embedding one remembered turn.
+const rememberTimeout = 30 * time.Second
+
+// minRememberChars skips short messages ("ok", "thanks") that carry no facts.
+const minRememberChars = 20
+
+// newSemanticMemory returns the store for cfg, or nil when semantic memory
+// is not configured.
+func newSemanticMemory(cfg *config.Config, workspace string) *memory.Store {
+	mc := cfg.Agents.Memory
+	if mc.EmbeddingModel == "" {
+		return nil
+	}
+	apiKey := mc.APIKey
+	if apiKey == "" {
+		apiKey = cfg.Providers.OpenAI.APIKey
+	}
+	if apiKey == "" && mc.APIBase == "" {
+		logger.WarnC("agent", "agents.memory.embedding_model is set but no API key is configured; semantic memory disabled")
+		return nil
+	}
+	logger.InfoCF("agent", "Semantic memory enabled",
+		map[string]interface{}{
+			"model": mc.EmbeddingModel,
+			"top_k": mc.TopK,
+		})
+	return memory.NewStore(filepath.Join(workspace, "memory"), memory.NewOpenAIEmbedder(mc.APIBase, apiKey, mc.EmbeddingModel))
+}
+
+// rememberTurn embeds a salient user message in the background so later
+// turns can recall it.
+func (al *AgentLoop) rememberTurn(content string) {
+	if al.semanticMemory == nil || !al.config.Agents.Memory.RememberTurns {
+		return
+	}
+	text := strings.TrimSpace(content)
+	if strings.HasPrefix(text, "/") || utf8.RuneCountInString(text) < minRememberChars {
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), rememberTimeout)
+		defer cancel()
+		if err := al.semanticMemory.Remember(ctx, memory.SourceTurn, text); err != nil {
+			logger.WarnCF("agent", "Failed to remember turn",
+				map[string]interface{}{
+					"error": err.Error(),
+				})
+		}
+	}()
+}
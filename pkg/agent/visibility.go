@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/tools"
 	"github.com/sipeed/picoclaw/pkg/utils"
 )
 
@@ -16,6 +17,7 @@ type ActionStatus string
 const (
 	ActionRunning   ActionStatus = "running"
 	ActionSuccess   ActionStatus = "success"
+	ActionPartial   ActionStatus = "partial"
 	ActionError     ActionStatus = "error"
 	ActionSkipped   ActionStatus = "skipped"
 )
@@ -45,6 +47,10 @@ type Action struct {
 	Result      string       // Truncated result
 	FullResult  string       // Full result (not sent to Telegram)
 	Error       string
+	Details     *tools.ResultDetails // Structured outcome (counts, warnings), if the tool reported one
+	ResultType  tools.ResultType       // Shape of the result for rich rendering
+	Metadata    map[string]interface{} // Structured result data, if the tool reported any
+	Output      []string               // Latest live output lines while running
 }
 
 // ActionStream tracks and formats action updates for visibility
@@ -97,8 +103,56 @@ func (as *ActionStream) StartAction(toolName string, args map[string]interface{}
 	return actionID
 }
 
+// actionOutputLines is how many live output lines the summary shows under
+// a running action.
+const actionOutputLines = 3
+
+// AppendOutput records a line of live output from a running action (a long
+// exec command); the summary shows the latest few under the action. Updates
+// stay throttled to the configured interval.
+func (as *ActionStream) AppendOutput(actionID string, line string) {
+	if actionID == "" {
+		return // Skipped action
+	}
+
+	as.mu.Lock()
+	defer as.mu.Unlock()
+
+	for i := range as.actions {
+		if as.actions[i].ID == actionID {
+			output := append(as.actions[i].Output, line)
+			if len(output) > actionOutputLines {
+				output = output[len(output)-actionOutputLines:]
+			}
+			as.actions[i].Output = output
+			as.maybeUpdate()
+			break
+		}
+	}
+}
+
 // CompleteAction marks an action as complete
 func (as *ActionStream) CompleteAction(actionID string, result string, err error) {
+	as.CompleteActionWithDetails(actionID, result, err, nil)
+}
+
+// CompleteActionWithDetails marks an action as complete, keeping the tool's
+// structured outcome so partial successes can be shown in verbose mode
+func (as *ActionStream) CompleteActionWithDetails(actionID string, result string, err error, details *tools.ResultDetails) {
+	as.completeAction(actionID, result, err, details, tools.ResultTypeText, nil)
+}
+
+// CompleteActionWithResult marks an action as complete from a tool result,
+// keeping its details, type and metadata for downstream rendering
+func (as *ActionStream) CompleteActionWithResult(actionID string, tr *tools.ToolResult) {
+	result := tr.ForUser
+	if result == "" {
+		result = tr.ForLLM
+	}
+	as.completeAction(actionID, result, tr.Err, tr.Details, tr.EffectiveType(), tr.Metadata)
+}
+
+func (as *ActionStream) completeAction(actionID string, result string, err error, details *tools.ResultDetails, resultType tools.ResultType, metadata map[string]interface{}) {
 	if actionID == "" {
 		return // Skipped action
 	}
@@ -110,12 +164,19 @@ func (as *ActionStream) CompleteAction(actionID string, result string, err error
 		if as.actions[i].ID == actionID {
 			as.actions[i].EndTime = time.Now()
 			as.actions[i].Duration = as.actions[i].EndTime.Sub(as.actions[i].StartTime)
+			as.actions[i].ResultType = resultType
+			as.actions[i].Metadata = metadata
 
 			if err != nil {
 				as.actions[i].Status = ActionError
 				as.actions[i].Error = err.Error()
+				as.actions[i].ResultType = tools.ResultTypeError
 			} else {
 				as.actions[i].Status = ActionSuccess
+				if details.Partial() {
+					as.actions[i].Status = ActionPartial
+				}
+				as.actions[i].Details = details
 				as.actions[i].FullResult = result
 				as.actions[i].Result = as.truncateResult(result, as.actions[i].Type)
 			}
@@ -178,6 +239,15 @@ func (as *ActionStream) formatSummary() string {
 		sb.WriteString(fmt.Sprintf("✓ %d step%s done\n", len(completed), pluralS(len(completed))))
 	}
 
+	// In verbose mode, itemize completed actions that reported details
+	if as.config.VerboseMode {
+		for _, a := range completed {
+			if a.Details != nil {
+				sb.WriteString(as.formatDetails(a))
+			}
+		}
+	}
+
 	// Show errors briefly
 	for _, a := range errored {
 		sb.WriteString(fmt.Sprintf("✗ %s: %s\n", as.formatActionName(a), utils.Truncate(a.Error, 60)))
@@ -186,6 +256,9 @@ func (as *ActionStream) formatSummary() string {
 	// Show currently running action(s) with description
 	for _, a := range running {
 		sb.WriteString(fmt.Sprintf("⏳ %s\n", as.formatActionName(a)))
+		for _, line := range a.Output {
+			sb.WriteString(fmt.Sprintf("   %s\n", utils.Truncate(line, 80)))
+		}
 	}
 
 	// If nothing is running, we're finishing up
@@ -211,6 +284,8 @@ func (as *ActionStream) getStatusIcon(status ActionStatus) string {
 		return "⏳"
 	case ActionSuccess:
 		return "✓"
+	case ActionPartial:
+		return "⚠"
 	case ActionError:
 		return "✗"
 	case ActionSkipped:
@@ -220,6 +295,36 @@ func (as *ActionStream) getStatusIcon(status ActionStatus) string {
 	}
 }
 
+// formatDetails renders an action's structured outcome, e.g.
+// "  ⚠ Writing files: 3/5 succeeded" followed by its warnings
+func (as *ActionStream) formatDetails(action Action) string {
+	d := action.Details
+	var sb strings.Builder
+
+	sb.WriteString(fmt.Sprintf("  %s %s", as.getStatusIcon(action.Status), as.formatActionName(action)))
+	switch {
+	case d.Failed > 0:
+		sb.WriteString(fmt.Sprintf(": %d/%d succeeded", d.Succeeded, d.Succeeded+d.Failed))
+	case d.Succeeded > 0:
+		sb.WriteString(fmt.Sprintf(": %d item%s", d.Succeeded, pluralS(d.Succeeded)))
+	}
+	if len(d.Warnings) > 0 {
+		sb.WriteString(fmt.Sprintf(" (%d warning%s)", len(d.Warnings), pluralS(len(d.Warnings))))
+	}
+	sb.WriteString("\n")
+
+	const maxWarnings = 3
+	for i, w := range d.Warnings {
+		if i == maxWarnings {
+			sb.WriteString(fmt.Sprintf("    … %d more\n", len(d.Warnings)-maxWarnings))
+			break
+		}
+		sb.WriteString(fmt.Sprintf("    - %s\n", utils.Truncate(w, 60)))
+	}
+
+	return sb.String()
+}
+
 // formatActionName creates a brief, descriptive name for an action (5-7 words)
 func (as *ActionStream) formatActionName(action Action) string {
 	switch action.ToolName {
@@ -0,0 +1,119 @@
+package agent
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/tools"
+)
+
+func TestActionStream_RendersPartialSuccessInVerboseMode(t *testing.T) {
+	as := NewActionStream(config.VisibilityConfig{VerboseMode: true}, nil)
+
+	id := as.StartAction("write_file", map[string]interface{}{"path": "notes/a.txt"})
+	as.CompleteActionWithDetails(id, "Wrote 3 of 5 files", nil, &tools.ResultDetails{
+		Succeeded: 3,
+		Failed:    2,
+		Warnings:  []string{"d.txt: permission denied", "e.txt: disk full"},
+	})
+
+	if got := as.actions[0].Status; got != ActionPartial {
+		t.Fatalf("status = %s, want %s", got, ActionPartial)
+	}
+
+	summary := as.formatSummary()
+	for _, want := range []string{
+		"✓ 1 step done",
+		"⚠ Writing a.txt: 3/5 succeeded (2 warnings)",
+		"- d.txt: permission denied",
+		"- e.txt: disk full",
+	} {
+		if !strings.Contains(summary, want) {
+			t.Fatalf("summary missing %q:\n%s", want, summary)
+		}
+	}
+}
+
+func TestActionStream_DetailsHiddenOutsideVerboseMode(t *testing.T) {
+	as := NewActionStream(config.VisibilityConfig{}, nil)
+
+	id := as.StartAction("write_file", map[string]interface{}{"path": "a.txt"})
+	as.CompleteActionWithDetails(id, "done", nil, &tools.ResultDetails{Succeeded: 3, Failed: 2})
+
+	summary := as.formatSummary()
+	if strings.Contains(summary, "3/5") {
+		t.Fatalf("details should only render in verbose mode:\n%s", summary)
+	}
+	if !strings.Contains(summary, "✓ 1 step done") {
+		t.Fatalf("partial action should still count as done:\n%s", summary)
+	}
+}
+
+func TestActionStream_PlainCompletionStillWorks(t *testing.T) {
+	as := NewActionStream(config.VisibilityConfig{VerboseMode: true}, nil)
+
+	ok := as.StartAction("exec", map[string]interface{}{"command": "ls"})
+	as.CompleteAction(ok, "a b c", nil)
+	failed := as.StartAction("exec", map[string]interface{}{"command": "git pull"})
+	as.CompleteAction(failed, "", errors.New("network down"))
+
+	if as.actions[0].Status != ActionSuccess || as.actions[0].Details != nil {
+		t.Fatalf("plain completion = %+v, want success without details", as.actions[0])
+	}
+	summary := as.formatSummary()
+	if !strings.Contains(summary, "✗ Git pull: network down") {
+		t.Fatalf("summary missing error line:\n%s", summary)
+	}
+}
+
+func TestActionStream_CompleteActionWithResultKeepsMetadata(t *testing.T) {
+	as := NewActionStream(config.VisibilityConfig{VerboseMode: true}, nil)
+
+	table := tools.TableMetadata([]string{"model", "tokens"}, [][]string{{"gpt-4o", "1200"}})
+	id := as.StartAction("exec", map[string]interface{}{"command": "usage"})
+	as.CompleteActionWithResult(id, tools.UserResult("gpt-4o: 1200 tokens").WithMetadata(tools.ResultTypeTable, table))
+
+	got := as.actions[0]
+	if got.ResultType != tools.ResultTypeTable || got.Metadata["columns"] == nil {
+		t.Fatalf("action = %+v, want table type with metadata", got)
+	}
+	if got.FullResult != "gpt-4o: 1200 tokens" {
+		t.Fatalf("FullResult = %q, want plain-text fallback", got.FullResult)
+	}
+
+	failed := as.StartAction("exec", map[string]interface{}{"command": "git pull"})
+	as.CompleteActionWithResult(failed, tools.ErrorResult("network down").WithError(errors.New("network down")))
+	if as.actions[1].Status != ActionError || as.actions[1].ResultType != tools.ResultTypeError {
+		t.Fatalf("failed action = %+v, want error type", as.actions[1])
+	}
+}
+
+func TestActionStream_ShowsLatestOutputOfRunningAction(t *testing.T) {
+	var updates []string
+	as := NewActionStream(config.VisibilityConfig{UpdateIntervalMS: 60000}, func(summary string) { updates = append(updates, summary) })
+
+	id := as.StartAction("exec", map[string]interface{}{"command": "go build ./..."})
+	for _, line := range []string{"step 1", "step 2", "step 3", "step 4"} {
+		as.AppendOutput(id, line)
+	}
+
+	summary := as.formatSummary()
+	if strings.Contains(summary, "step 1") {
+		t.Errorf("summary keeps more than the latest lines:\n%s", summary)
+	}
+	for _, want := range []string{"   step 2\n", "   step 3\n", "   step 4\n"} {
+		if !strings.Contains(summary, want) {
+			t.Errorf("summary missing %q:\n%s", want, summary)
+		}
+	}
+	if len(updates) != 0 {
+		t.Errorf("output sent %d updates inside the update interval", len(updates))
+	}
+
+	as.CompleteAction(id, "ok", nil)
+	if summary := as.formatSummary(); strings.Contains(summary, "step 4") {
+		t.Errorf("completed action still shows live output:\n%s", summary)
+	}
+}
@@ -0,0 +1,111 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/voice"
+)
+
+// Replies to voice messages are spoken as well as written when a
+// synthesizer is set (voice.tts). The text reply goes out first; the audio
+// follows as a separate media message once synthesis finishes. Users opt
+// out with /voice off.
+
+// voiceReplyTimeout bounds synthesis of one spoken reply.
+const voiceReplyTimeout = 60 * time.Second
+
+// SetSynthesizer enables spoken replies to voice messages.
+func (al *AgentLoop) SetSynthesizer(s voice.Synthesizer) {
+	al.synthesizer = s
+}
+
+func voiceReplyKey(msg bus.InboundMessage) string {
+	return fmt.Sprintf("%s:%s", msg.Channel, msg.SenderID)
+}
+
+// wantsVoiceReply reports whether response to msg should also be spoken:
+// the message was a voice message, the sender has not turned spoken replies
+// off, and the response is within voice.tts.max_chars.
+func (al *AgentLoop) wantsVoiceReply(msg bus.InboundMessage, response string) bool {
+	if al.synthesizer == nil || !al.synthesizer.IsAvailable() || msg.Metadata["is_voice"] != "true" {
+		return false
+	}
+	if max := al.config.Voice.TTS.MaxChars; max > 0 && utf8.RuneCountInString(response) > max {
+		return false
+	}
+	if enabled, set := al.state.GetVoiceReplies(voiceReplyKey(msg)); set {
+		return enabled
+	}
+	return true
+}
+
+// sendVoiceReply synthesizes response into the media cache and publishes
+// it to the chat. Failures are logged only; the text reply already went out.
+func (al *AgentLoop) sendVoiceReply(ctx context.Context, msg bus.InboundMessage, response string) {
+	ctx, cancel := context.WithTimeout(ctx, voiceReplyTimeout)
+	defer cancel()
+
+	path, err := al.synthesizer.Synthesize(ctx, speechText(response), filepath.Join(al.workspace, "tmp", "media"))
+	if err != nil {
+		logger.WarnCF("agent", "Voice reply synthesis failed",
+			map[string]interface{}{
+				"channel":        msg.Channel,
+				"error":          err.Error(),
+				"correlation_id": msg.CorrelationID,
+			})
+		return
+	}
+	al.bus.PublishOutbound(bus.OutboundMessage{
+		Channel: msg.Channel,
+		ChatID:  msg.ChatID,
+		Media:   []string{path},
+	})
+}
+
+// speechText drops Markdown markers that would otherwise be read aloud.
+func speechText(s string) string {
+	s = strings.NewReplacer("```", "", "**", "", "__", "", "`", "", "#", "").Replace(s)
+	return strings.TrimSpace(s)
+}
+
+// handleVoiceCommand turns spoken replies to the sender's voice messages
+// on or off, or reports the current setting.
+func (al *AgentLoop) handleVoiceCommand(msg bus.InboundMessage, command string) string {
+	if al.synthesizer == nil {
+		return "Voice replies are not configured."
+	}
+
+	key := voiceReplyKey(msg)
+	parts := strings.Fields(command)
+	if len(parts) < 2 {
+		enabled, set := al.state.GetVoiceReplies(key)
+		if !set || enabled {
+			return "Voice replies are on: voice messages get a spoken answer too. Send `/voice off` to stop."
+		}
+		return "Voice replies are off. Send `/voice on` to get spoken answers to voice messages."
+	}
+
+	var enabled bool
+	switch strings.ToLower(parts[1]) {
+	case "on":
+		enabled = true
+	case "off":
+		enabled = false
+	default:
+		return "Usage: `/voice on|off`"
+	}
+	if err := al.state.SetVoiceReplies(key, enabled); err != nil {
+		return fmt.Sprintf("Failed to save the setting: %v", err)
+	}
+	if enabled {
+		return "Voice replies on."
+	}
+	return "Voice replies off."
+}
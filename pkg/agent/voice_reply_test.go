@@ -0,0 +1,105 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+)
+
+type fakeSynthesizer struct {
+	text string
+}
+
+func (f *fakeSynthesizer) IsAvailable() bool { return true }
+
+func (f *fakeSynthesizer) Synthesize(ctx context.Context, text, dir string) (string, error) {
+	f.text = text
+	return dir + "/reply.voice.ogg", nil
+}
+
+func voiceMsg(content string) bus.InboundMessage {
+	return bus.InboundMessage{
+		Channel:    "telegram",
+		ChatID:     "1",
+		SenderID:   "42",
+		SessionKey: "telegram:1",
+		Content:    content,
+		Metadata:   map[string]string{"is_voice": "true"},
+	}
+}
+
+func TestVoiceCommand(t *testing.T) {
+	al := newCommandTestLoop(t)
+	run := func(content string) string {
+		t.Helper()
+		resp, err := al.processMessage(context.Background(), voiceMsg(content))
+		if err != nil {
+			t.Fatalf("%s error: %v", content, err)
+		}
+		return resp
+	}
+
+	if resp := run("/voice off"); resp != "Voice replies are not configured." {
+		t.Fatalf("/voice without synthesizer = %q", resp)
+	}
+	al.SetSynthesizer(&fakeSynthesizer{})
+
+	if resp := run("/voice"); !strings.Contains(resp, "on") {
+		t.Errorf("default /voice status = %q, want on", resp)
+	}
+	if !al.wantsVoiceReply(voiceMsg(""), "hi") {
+		t.Error("voice reply not wanted by default")
+	}
+	if resp := run("/voice off"); resp != "Voice replies off." {
+		t.Errorf("/voice off = %q", resp)
+	}
+	if al.wantsVoiceReply(voiceMsg(""), "hi") {
+		t.Error("voice reply still wanted after /voice off")
+	}
+	if resp := run("/voice maybe"); !strings.Contains(resp, "Usage") {
+		t.Errorf("/voice maybe = %q, want usage", resp)
+	}
+	run("/voice on")
+	if !al.wantsVoiceReply(voiceMsg(""), "hi") {
+		t.Error("voice reply not wanted after /voice on")
+	}
+}
+
+func TestWantsVoiceReply(t *testing.T) {
+	al := newCommandTestLoop(t)
+	al.SetSynthesizer(&fakeSynthesizer{})
+	al.config.Voice.TTS.MaxChars = 10
+
+	text := voiceMsg("")
+	text.Metadata = map[string]string{}
+	if al.wantsVoiceReply(text, "hi") {
+		t.Error("text message got a voice reply")
+	}
+	if al.wantsVoiceReply(voiceMsg(""), strings.Repeat("a", 11)) {
+		t.Error("reply over max_chars got a voice reply")
+	}
+}
+
+func TestSendVoiceReply_PublishesMedia(t *testing.T) {
+	al := newCommandTestLoop(t)
+	synth := &fakeSynthesizer{}
+	al.SetSynthesizer(synth)
+
+	al.sendVoiceReply(context.Background(), voiceMsg(""), "## Done\n**All** set with `ls`.")
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	out, ok := al.bus.SubscribeOutbound(ctx)
+	if !ok {
+		t.Fatal("no outbound voice message")
+	}
+	if out.ChatID != "1" || len(out.Media) != 1 || !strings.HasSuffix(out.Media[0], ".voice.ogg") {
+		t.Errorf("outbound = %+v", out)
+	}
+	if synth.text != "Done\nAll set with ls." {
+		t.Errorf("spoken text = %q", synth.text)
+	}
+}
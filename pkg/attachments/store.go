@@ -8,6 +8,7 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
 	"time"
@@ -42,6 +43,9 @@ type Store struct {
 	statePath string
 	rootPath  string
 	records   map[string]Record
+	// refs counts the records sharing each stored file.
+	refs  map[string]int
+	dedup bool
 }
 
 func NewStore(workspace string) *Store {
@@ -56,6 +60,7 @@ func NewStore(workspace string) *Store {
 		statePath: statePath,
 		rootPath:  root,
 		records:   map[string]Record{},
+		refs:      map[string]int{},
 	}
 	_ = s.load()
 	return s
@@ -65,6 +70,16 @@ func (s *Store) RootPath() string {
 	return s.rootPath
 }
 
+// SetDedup makes SaveFromLocalFile reuse the stored copy of a file that was
+// saved before, matched by SHA256, instead of copying it again. Each message
+// still gets its own record, and RefCount tracks how many records share the
+// copy. Matching spans all chats, so a file re-sent elsewhere is stored once.
+func (s *Store) SetDedup(enabled bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.dedup = enabled
+}
+
 func (s *Store) SaveFromLocalFile(channel, chatID, userID, messageID, originalName, mimeType, kind, localPath string) (Record, error) {
 	info, err := os.Stat(localPath)
 	if err != nil {
@@ -74,7 +89,40 @@ func (s *Store) SaveFromLocalFile(channel, chatID, userID, messageID, originalNa
 		return Record{}, fmt.Errorf("local path is not a regular file: %s", localPath)
 	}
 
+	s.mu.RLock()
+	dedup := s.dedup
+	s.mu.RUnlock()
+
 	now := time.Now().UTC()
+	baseName := utils.SanitizeFilename(originalName)
+	if baseName == "" {
+		baseName = filepath.Base(localPath)
+	}
+	rec := Record{
+		ID:        "att_" + uuid.NewString(),
+		Channel:   channel,
+		ChatID:    chatID,
+		UserID:    userID,
+		MessageID: messageID,
+		Name:      baseName,
+		MIMEType:  mimeType,
+		Kind:      kind,
+		CreatedAt: now,
+	}
+
+	if dedup {
+		sum, err := hashFile(localPath)
+		if err != nil {
+			return Record{}, err
+		}
+		if existing, ok := s.FindBySHA256(sum); ok {
+			rec.StoredPath = existing.StoredPath
+			rec.SizeBytes = existing.SizeBytes
+			rec.SHA256 = sum
+			return s.addRecord(rec)
+		}
+	}
+
 	dayPath := filepath.Join(
 		s.rootPath,
 		strings.ToLower(strings.TrimSpace(channel)),
@@ -87,10 +135,6 @@ func (s *Store) SaveFromLocalFile(channel, chatID, userID, messageID, originalNa
 		return Record{}, fmt.Errorf("mkdir attachment day path: %w", err)
 	}
 
-	baseName := utils.SanitizeFilename(originalName)
-	if baseName == "" {
-		baseName = filepath.Base(localPath)
-	}
 	destName := fmt.Sprintf("%s_%s_%s", now.Format("150405"), uuid.NewString()[:8], baseName)
 	destPath := filepath.Join(dayPath, destName)
 
@@ -98,38 +142,151 @@ func (s *Store) SaveFromLocalFile(channel, chatID, userID, messageID, originalNa
 	if err != nil {
 		return Record{}, err
 	}
+	rec.StoredPath = destPath
+	rec.SizeBytes = size
+	rec.SHA256 = sum
+	return s.addRecord(rec)
+}
 
-	rec := Record{
-		ID:         "att_" + uuid.NewString(),
-		Channel:    channel,
-		ChatID:     chatID,
-		UserID:     userID,
-		MessageID:  messageID,
-		Name:       baseName,
-		StoredPath: destPath,
-		MIMEType:   mimeType,
-		Kind:       kind,
-		SizeBytes:  size,
-		SHA256:     sum,
-		CreatedAt:  now,
-	}
-
+func (s *Store) addRecord(rec Record) (Record, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	s.records[rec.ID] = rec
+	s.refs[rec.StoredPath]++
 	if err := s.saveLocked(); err != nil {
 		return Record{}, err
 	}
 	return rec, nil
 }
 
-func (s *Store) GetByID(id string) (Record, bool) {
+// FindBySHA256 returns the oldest record whose stored file has the given
+// SHA256 and still exists. Like GetByID, a miss reloads the state file.
+func (s *Store) FindBySHA256(sum string) (Record, bool) {
+	if r, ok := s.findBySHA256(sum); ok {
+		return r, true
+	}
+	_ = s.load()
+	return s.findBySHA256(sum)
+}
+
+func (s *Store) findBySHA256(sum string) (Record, bool) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
+	var found Record
+	for _, r := range s.records {
+		if r.SHA256 != sum || (found.ID != "" && !r.CreatedAt.Before(found.CreatedAt)) {
+			continue
+		}
+		if info, err := os.Stat(r.StoredPath); err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		found = r
+	}
+	return found, found.ID != ""
+}
+
+// RefCount returns how many records share the file at storedPath.
+func (s *Store) RefCount(storedPath string) int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.refs[storedPath]
+}
+
+// GetByID returns the record with the given ID. Channels and tools keep
+// separate Stores over the same state file, so a miss reloads it to pick up
+// attachments saved by another Store.
+func (s *Store) GetByID(id string) (Record, bool) {
+	s.mu.RLock()
 	r, ok := s.records[id]
+	s.mu.RUnlock()
+	if ok {
+		return r, true
+	}
+
+	_ = s.load()
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	r, ok = s.records[id]
 	return r, ok
 }
 
+// Query filters attachment records. Empty fields match everything; text
+// matches are case-insensitive.
+type Query struct {
+	Channel      string
+	ChatID       string
+	Kind         string
+	NameContains string
+	// MIMEType matches exactly, or as a prefix when it ends in "/"
+	// (e.g. "image/").
+	MIMEType string
+	Since    time.Time // CreatedAt at or after
+	Until    time.Time // CreatedAt before
+	Limit    int       // 0 returns all matches
+}
+
+func (q Query) matches(r Record) bool {
+	switch {
+	case q.Channel != "" && !strings.EqualFold(r.Channel, q.Channel),
+		q.ChatID != "" && r.ChatID != q.ChatID,
+		q.Kind != "" && !strings.EqualFold(r.Kind, q.Kind),
+		q.NameContains != "" && !strings.Contains(strings.ToLower(r.Name), strings.ToLower(q.NameContains)),
+		!q.Since.IsZero() && r.CreatedAt.Before(q.Since),
+		!q.Until.IsZero() && !r.CreatedAt.Before(q.Until):
+		return false
+	}
+	if q.MIMEType != "" {
+		want, got := strings.ToLower(q.MIMEType), strings.ToLower(r.MIMEType)
+		if strings.HasSuffix(want, "/") {
+			return strings.HasPrefix(got, want)
+		}
+		return got == want
+	}
+	return true
+}
+
+// Find returns the records matching q, newest first. It reloads the state
+// file so attachments saved by other Stores are included.
+func (s *Store) Find(q Query) []Record {
+	_ = s.load()
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var out []Record
+	for _, r := range s.records {
+		if q.matches(r) {
+			out = append(out, r)
+		}
+	}
+	slices.SortFunc(out, func(a, b Record) int {
+		return b.CreatedAt.Compare(a.CreatedAt)
+	})
+	if q.Limit > 0 && len(out) > q.Limit {
+		out = out[:q.Limit]
+	}
+	return out
+}
+
+// Remove deletes the record with the given ID. Its stored file is deleted
+// with it unless other records still share it.
+func (s *Store) Remove(id string) error {
+	_ = s.load()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, ok := s.records[id]
+	if !ok {
+		return fmt.Errorf("attachment not found: %s", id)
+	}
+	delete(s.records, id)
+	s.refs[r.StoredPath]--
+	if s.refs[r.StoredPath] <= 0 {
+		delete(s.refs, r.StoredPath)
+		if err := os.Remove(r.StoredPath); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("remove stored file: %w", err)
+		}
+	}
+	return s.saveLocked()
+}
+
 func (s *Store) MarkImported(id, importedPath string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -177,6 +334,20 @@ func copyWithHash(srcPath, dstPath string) (int64, string, error) {
 	return n, hex.EncodeToString(hasher.Sum(nil)), nil
 }
 
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("open source file: %w", err)
+	}
+	defer f.Close()
+
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, f); err != nil {
+		return "", fmt.Errorf("hash file: %w", err)
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
 func (s *Store) load() error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -190,13 +361,17 @@ func (s *Store) load() error {
 	var st stateFile
 	if err := json.Unmarshal(data, &st); err != nil {
 		s.records = map[string]Record{}
+		s.refs = map[string]int{}
 		return nil
 	}
 	out := make(map[string]Record, len(st.Records))
+	refs := make(map[string]int, len(st.Records))
 	for _, r := range st.Records {
 		out[r.ID] = r
+		refs[r.StoredPath]++
 	}
 	s.records = out
+	s.refs = refs
 	return nil
 }
 
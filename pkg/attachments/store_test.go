@@ -3,6 +3,7 @@ package attachments
 import (
 	"os"
 	"path/filepath"
+	"slices"
 	"testing"
 )
 
@@ -56,3 +57,154 @@ func TestMarkImported(t *testing.T) {
 		t.Fatalf("unexpected imported path: %q", got.ImportedPath)
 	}
 }
+
+func TestGetByID_SeesRecordsSavedByAnotherStore(t *testing.T) {
+	tmp := t.TempDir()
+	in := filepath.Join(tmp, "in.txt")
+	if err := os.WriteFile(in, []byte("hello"), 0644); err != nil {
+		t.Fatalf("write input: %v", err)
+	}
+
+	reader := NewStore(tmp) // e.g. a tool's store, created first
+	writer := NewStore(tmp) // e.g. a channel's store
+	rec, err := writer.SaveFromLocalFile("telegram", "123", "u1", "m1", "demo.txt", "text/plain", "document", in)
+	if err != nil {
+		t.Fatalf("SaveFromLocalFile failed: %v", err)
+	}
+
+	if _, ok := reader.GetByID(rec.ID); !ok {
+		t.Fatal("record saved by another store should be found")
+	}
+}
+
+func TestSaveFromLocalFile_DedupSharesStoredFile(t *testing.T) {
+	tmp := t.TempDir()
+	first := filepath.Join(tmp, "first.jpg")
+	again := filepath.Join(tmp, "again.jpg")
+	other := filepath.Join(tmp, "other.jpg")
+	for path, content := range map[string]string{first: "photo", again: "photo", other: "different"} {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("write input: %v", err)
+		}
+	}
+
+	s := NewStore(tmp)
+	s.SetDedup(true)
+	a, err := s.SaveFromLocalFile("telegram", "123", "u1", "m1", "photo.jpg", "image/jpeg", "photo", first)
+	if err != nil {
+		t.Fatalf("SaveFromLocalFile failed: %v", err)
+	}
+	b, err := s.SaveFromLocalFile("telegram", "123", "u1", "m2", "photo.jpg", "image/jpeg", "photo", again)
+	if err != nil {
+		t.Fatalf("SaveFromLocalFile failed: %v", err)
+	}
+	c, err := s.SaveFromLocalFile("telegram", "123", "u1", "m3", "other.jpg", "image/jpeg", "photo", other)
+	if err != nil {
+		t.Fatalf("SaveFromLocalFile failed: %v", err)
+	}
+
+	if a.ID == b.ID || b.MessageID != "m2" {
+		t.Fatalf("each message should keep its own record: %+v / %+v", a, b)
+	}
+	if b.StoredPath != a.StoredPath || b.SHA256 != a.SHA256 || b.SizeBytes != 5 {
+		t.Fatalf("duplicate should share the stored file: %+v / %+v", a, b)
+	}
+	if c.StoredPath == a.StoredPath {
+		t.Fatal("different content must get its own stored file")
+	}
+	if got := s.RefCount(a.StoredPath); got != 2 {
+		t.Fatalf("RefCount = %d, want 2", got)
+	}
+	if got, ok := s.FindBySHA256(a.SHA256); !ok || got.StoredPath != a.StoredPath {
+		t.Fatalf("FindBySHA256 = %+v, %v; want the shared file", got, ok)
+	}
+}
+
+func TestRemove_KeepsSharedFileUntilLastReference(t *testing.T) {
+	tmp := t.TempDir()
+	in := filepath.Join(tmp, "photo.jpg")
+	if err := os.WriteFile(in, []byte("photo"), 0644); err != nil {
+		t.Fatalf("write input: %v", err)
+	}
+
+	s := NewStore(tmp)
+	s.SetDedup(true)
+	a, err := s.SaveFromLocalFile("telegram", "123", "u1", "m1", "photo.jpg", "image/jpeg", "photo", in)
+	if err != nil {
+		t.Fatalf("SaveFromLocalFile failed: %v", err)
+	}
+	b, err := s.SaveFromLocalFile("discord", "9", "u2", "m2", "photo.jpg", "image/jpeg", "photo", in)
+	if err != nil {
+		t.Fatalf("SaveFromLocalFile failed: %v", err)
+	}
+	if b.StoredPath != a.StoredPath {
+		t.Fatal("a file re-sent in another chat should share the stored copy")
+	}
+
+	if err := s.Remove(a.ID); err != nil {
+		t.Fatalf("Remove failed: %v", err)
+	}
+	if _, err := os.Stat(b.StoredPath); err != nil {
+		t.Fatalf("shared file removed while still referenced: %v", err)
+	}
+	if got := s.RefCount(b.StoredPath); got != 1 {
+		t.Fatalf("RefCount = %d after removing one record, want 1", got)
+	}
+
+	if err := s.Remove(b.ID); err != nil {
+		t.Fatalf("Remove failed: %v", err)
+	}
+	if _, err := os.Stat(b.StoredPath); !os.IsNotExist(err) {
+		t.Fatalf("stored file should be removed with its last record: %v", err)
+	}
+	if _, ok := NewStore(tmp).GetByID(b.ID); ok {
+		t.Fatal("removed record should not be persisted")
+	}
+}
+
+func TestFind_FiltersAndSortsNewestFirst(t *testing.T) {
+	tmp := t.TempDir()
+	s := NewStore(tmp)
+	save := func(chatID, name, mime, kind string) Record {
+		t.Helper()
+		in := filepath.Join(tmp, name)
+		if err := os.WriteFile(in, []byte(name), 0644); err != nil {
+			t.Fatalf("write input: %v", err)
+		}
+		rec, err := s.SaveFromLocalFile("telegram", chatID, "u1", "m1", name, mime, kind, in)
+		if err != nil {
+			t.Fatalf("SaveFromLocalFile failed: %v", err)
+		}
+		return rec
+	}
+	save("1", "Q3 Report.pdf", "application/pdf", "document")
+	photo := save("1", "photo.jpg", "image/jpeg", "photo")
+	save("2", "invoice.pdf", "application/pdf", "document")
+
+	names := func(recs []Record) []string {
+		var out []string
+		for _, r := range recs {
+			out = append(out, r.Name)
+		}
+		return out
+	}
+	tests := []struct {
+		name string
+		q    Query
+		want []string
+	}{
+		{"chat", Query{ChatID: "1"}, []string{"photo.jpg", "Q3 Report.pdf"}},
+		{"kind", Query{Channel: "Telegram", Kind: "photo"}, []string{"photo.jpg"}},
+		{"name", Query{NameContains: "report"}, []string{"Q3 Report.pdf"}},
+		{"mime", Query{MIMEType: "application/pdf", ChatID: "2"}, []string{"invoice.pdf"}},
+		{"mime prefix", Query{MIMEType: "image/"}, []string{"photo.jpg"}},
+		{"since", Query{ChatID: "1", Since: photo.CreatedAt}, []string{"photo.jpg"}},
+		{"until", Query{ChatID: "1", Until: photo.CreatedAt}, []string{"Q3 Report.pdf"}},
+		{"limit", Query{Limit: 1}, []string{"invoice.pdf"}},
+	}
+	for _, tt := range tests {
+		if got := names(s.Find(tt.q)); !slices.Equal(got, tt.want) {
+			t.Errorf("%s: Find = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
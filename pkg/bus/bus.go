@@ -6,21 +6,30 @@ import (
 )
 
 type MessageBus struct {
-	inbound  chan InboundMessage
-	outbound chan OutboundMessage
-	handlers map[string]MessageHandler
-	mu       sync.RWMutex
+	inbound     chan InboundMessage
+	outbound    chan OutboundMessage
+	handlers    map[string]MessageHandler
+	mu          sync.RWMutex
+	toolAnswers map[string]*toolAnswerWaiter // tool call ID -> waiting callback tool
+	answersMu   sync.Mutex
 }
 
 func NewMessageBus() *MessageBus {
 	return &MessageBus{
-		inbound:  make(chan InboundMessage, 100),
-		outbound: make(chan OutboundMessage, 100),
-		handlers: make(map[string]MessageHandler),
+		inbound:     make(chan InboundMessage, 100),
+		outbound:    make(chan OutboundMessage, 100),
+		handlers:    make(map[string]MessageHandler),
+		toolAnswers: make(map[string]*toolAnswerWaiter),
 	}
 }
 
+// PublishInbound queues msg for the agent. Tool answers (see
+// IsToolAnswer) bypass the queue and go to the waiting callback tool.
 func (mb *MessageBus) PublishInbound(msg InboundMessage) {
+	if IsToolAnswer(msg) {
+		mb.deliverToolAnswer(msg)
+		return
+	}
 	mb.inbound <- msg
 }
 
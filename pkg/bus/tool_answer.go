@@ -0,0 +1,85 @@
+package bus
+
+// Callback tools hand a request to an external system and block their turn
+// until it answers. The agent loop is busy running that turn, so answers
+// cannot wait in the inbound queue: PublishInbound hands them straight to
+// the tool waiting on the tool call ID.
+
+import "fmt"
+
+const (
+	// MetadataType marks inbound messages that are not user messages.
+	MetadataType = "type"
+	// MetadataToolCallID is the tool call a tool answer belongs to.
+	MetadataToolCallID = "tool_call_id"
+	// TypeToolAnswer is the MetadataType value of tool answers.
+	TypeToolAnswer = "tool_answer"
+)
+
+// NewToolAnswer returns the inbound message answering the callback tool
+// call toolCallID with content.
+func NewToolAnswer(channel, senderID, chatID, toolCallID, content string) InboundMessage {
+	return InboundMessage{
+		Channel:  channel,
+		SenderID: senderID,
+		ChatID:   chatID,
+		Content:  content,
+		Metadata: map[string]string{
+			MetadataType:       TypeToolAnswer,
+			MetadataToolCallID: toolCallID,
+		},
+	}
+}
+
+// IsToolAnswer reports whether msg answers a callback tool call.
+func IsToolAnswer(msg InboundMessage) bool {
+	return msg.Metadata[MetadataType] == TypeToolAnswer && msg.Metadata[MetadataToolCallID] != ""
+}
+
+// toolAnswerWaiter is a callback tool waiting for an answer from the chat
+// its request was sent to.
+type toolAnswerWaiter struct {
+	ch      chan InboundMessage
+	channel string
+	chatID  string
+}
+
+// ExpectToolAnswer registers a waiter for the answer to toolCallID, accepted
+// only from channel/chatID, where the request goes. Call it before
+// publishing the request so a fast answer is not missed, and call cancel
+// once done waiting. It fails if toolCallID already has a waiter.
+func (mb *MessageBus) ExpectToolAnswer(toolCallID, channel, chatID string) (answers <-chan InboundMessage, cancel func(), err error) {
+	w := &toolAnswerWaiter{ch: make(chan InboundMessage, 1), channel: channel, chatID: chatID}
+	mb.answersMu.Lock()
+	if _, exists := mb.toolAnswers[toolCallID]; exists {
+		mb.answersMu.Unlock()
+		return nil, nil, fmt.Errorf("tool call %s is already waiting for an answer", toolCallID)
+	}
+	mb.toolAnswers[toolCallID] = w
+	mb.answersMu.Unlock()
+
+	return w.ch, func() {
+		mb.answersMu.Lock()
+		defer mb.answersMu.Unlock()
+		if mb.toolAnswers[toolCallID] == w {
+			delete(mb.toolAnswers, toolCallID)
+		}
+	}, nil
+}
+
+// deliverToolAnswer passes msg to its waiter and reports whether there was
+// one. Only the first answer from the asked chat counts; answers from other
+// chats, late or unknown answers are dropped rather than reaching the agent
+// as user messages.
+func (mb *MessageBus) deliverToolAnswer(msg InboundMessage) bool {
+	id := msg.Metadata[MetadataToolCallID]
+	mb.answersMu.Lock()
+	defer mb.answersMu.Unlock()
+	w, ok := mb.toolAnswers[id]
+	if !ok || msg.Channel != w.channel || msg.ChatID != w.chatID {
+		return false
+	}
+	delete(mb.toolAnswers, id)
+	w.ch <- msg
+	return true
+}
@@ -17,6 +17,14 @@ type OutboundMessage struct {
 	Content          string   `json:"content"`
 	Media            []string `json:"media,omitempty"`         // local file paths to send
 	IsProgressUpdate bool     `json:"is_progress_update,omitempty"` // true for ActionStream updates
+	// ReplyToMessageID is the platform ID of the inbound message this answers,
+	// for channels that can quote it.
+	ReplyToMessageID string `json:"reply_to_message_id,omitempty"`
+	// ResultType and Metadata describe structured tool output (e.g. "table"
+	// with columns/rows) for channels that can render it. Content is always
+	// the plain-text fallback.
+	ResultType string                 `json:"result_type,omitempty"`
+	Metadata   map[string]interface{} `json:"metadata,omitempty"`
 }
 
 type MessageHandler func(InboundMessage) error
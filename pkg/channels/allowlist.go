@@ -0,0 +1,88 @@
+package channels
+
+import (
+	"fmt"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// AllowListChannel is a channel whose allowlist can change at runtime.
+// Every channel built on BaseChannel implements it.
+type AllowListChannel interface {
+	AllowList() []string
+	SetAllowList(allowList []string)
+}
+
+// SetConfigPath makes allowlist changes persist to the config file at path.
+func (m *Manager) SetConfigPath(path string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.configPath = path
+}
+
+func (m *Manager) allowListChannel(name string) (AllowListChannel, error) {
+	channel, ok := m.GetChannel(name)
+	if !ok {
+		return nil, fmt.Errorf("channel %s not found", name)
+	}
+	alc, ok := channel.(AllowListChannel)
+	if !ok {
+		return nil, fmt.Errorf("channel %s does not support allowlist changes", name)
+	}
+	return alc, nil
+}
+
+// AllowList returns the named channel's current allowlist.
+func (m *Manager) AllowList(channel string) ([]string, error) {
+	alc, err := m.allowListChannel(channel)
+	if err != nil {
+		return nil, err
+	}
+	return alc.AllowList(), nil
+}
+
+// SetAllowList replaces the named channel's allowlist on the running channel
+// and in the config, saving the config file when a path is set.
+func (m *Manager) SetAllowList(channel string, allowList []string) error {
+	alc, err := m.allowListChannel(channel)
+	if err != nil {
+		return err
+	}
+	if !m.config.SetChannelAllowFrom(channel, allowList) {
+		return fmt.Errorf("channel %s has no allow_from setting", channel)
+	}
+	alc.SetAllowList(allowList)
+	logger.InfoCF("channels", "Allowlist updated", map[string]interface{}{
+		"channel": channel,
+		"entries": len(allowList),
+	})
+
+	m.mu.RLock()
+	path := m.configPath
+	m.mu.RUnlock()
+	if path == "" {
+		return nil
+	}
+	// Only allow_from is written back: the loaded config also holds
+	// secrets from the environment that must not end up in the file.
+	if err := config.SaveChannelAllowFrom(path, channel, allowList); err != nil {
+		return fmt.Errorf("allowlist updated but not saved: %w", err)
+	}
+	return nil
+}
+
+// CanManageAllowList reports whether senderID may change the named
+// channel's allowlist: the configured owner always can, anyone else only
+// when explicitly listed. An empty allowlist lets everyone chat but not
+// manage it.
+func (m *Manager) CanManageAllowList(channel, senderID string) bool {
+	if owner := m.config.Channels.OwnerID; owner != "" && matchesAllowList([]string{owner}, senderID) {
+		return true
+	}
+	allowList, err := m.AllowList(channel)
+	if err != nil || len(allowList) == 0 {
+		return false
+	}
+	return matchesAllowList(allowList, senderID)
+}
@@ -0,0 +1,128 @@
+package channels
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+type stubChannel struct {
+	*BaseChannel
+}
+
+func (c *stubChannel) Start(ctx context.Context) error {
+	return nil
+}
+
+func (c *stubChannel) Stop(ctx context.Context) error {
+	return nil
+}
+
+func (c *stubChannel) Send(ctx context.Context, msg bus.OutboundMessage) error {
+	return nil
+}
+
+func TestBaseChannelSetAllowList(t *testing.T) {
+	initial := []string{"111"}
+	ch := NewBaseChannel("test", nil, nil, initial)
+	initial[0] = "changed"
+	if !ch.IsAllowed("111") {
+		t.Fatal("allowlist should not alias the caller's slice")
+	}
+
+	ch.SetAllowList([]string{"222"})
+	if ch.IsAllowed("111") || !ch.IsAllowed("222|bob") {
+		t.Errorf("IsAllowed after SetAllowList: 111=%v 222=%v", ch.IsAllowed("111"), ch.IsAllowed("222|bob"))
+	}
+	if got := ch.AllowList(); len(got) != 1 || got[0] != "222" {
+		t.Errorf("AllowList() = %v, want [222]", got)
+	}
+}
+
+func TestManagerSetAllowListPersists(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Channels.Telegram.AllowFrom = config.FlexibleStringSlice{"111"}
+	cfg.Channels.OwnerID = "999"
+	m, err := NewManager(cfg, bus.NewMessageBus())
+	if err != nil {
+		t.Fatalf("NewManager() error: %v", err)
+	}
+	m.RegisterChannel("telegram", &stubChannel{NewBaseChannel("telegram", nil, nil, cfg.Channels.Telegram.AllowFrom)})
+	path := filepath.Join(t.TempDir(), "config.json")
+	m.SetConfigPath(path)
+
+	if !m.CanManageAllowList("telegram", "111|alice") || !m.CanManageAllowList("telegram", "999") {
+		t.Error("listed sender and owner should be able to manage the allowlist")
+	}
+	if m.CanManageAllowList("telegram", "222") {
+		t.Error("unlisted sender should not manage the allowlist")
+	}
+
+	if err := m.SetAllowList("telegram", []string{"111", "222"}); err != nil {
+		t.Fatalf("SetAllowList() error: %v", err)
+	}
+	ch, _ := m.GetChannel("telegram")
+	if !ch.IsAllowed("222") {
+		t.Error("running channel should allow the added sender")
+	}
+	saved, err := config.LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig() error: %v", err)
+	}
+	if got := saved.Channels.Telegram.AllowFrom; len(got) != 2 || got[1] != "222" {
+		t.Errorf("saved allow_from = %v, want [111 222]", got)
+	}
+
+	if err := m.SetAllowList("discord", []string{"1"}); err == nil {
+		t.Error("SetAllowList() on an unregistered channel should fail")
+	}
+}
+
+func TestManagerSetAllowList_KeepsEnvSecretsOutOfFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	onDisk := `{
+  "providers": {"openai": {"api_key": "${MY_OPENAI_KEY}"}},
+  "channels": {"telegram": {"enabled": true, "allow_from": ["111"]}}
+}`
+	if err := os.WriteFile(path, []byte(onDisk), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("MY_OPENAI_KEY", "sk-referenced-secret")
+	t.Setenv("PICOCLAW_PROVIDERS_ANTHROPIC_API_KEY", "sk-env-secret")
+
+	cfg, err := config.LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig() error: %v", err)
+	}
+	if cfg.Providers.Anthropic.APIKey != "sk-env-secret" {
+		t.Fatalf("env key not applied: %q", cfg.Providers.Anthropic.APIKey)
+	}
+	m, err := NewManager(cfg, bus.NewMessageBus())
+	if err != nil {
+		t.Fatalf("NewManager() error: %v", err)
+	}
+	m.RegisterChannel("telegram", &stubChannel{NewBaseChannel("telegram", nil, nil, cfg.Channels.Telegram.AllowFrom)})
+	m.SetConfigPath(path)
+
+	if err := m.SetAllowList("telegram", []string{"111", "222"}); err != nil {
+		t.Fatalf("SetAllowList() error: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "sk-") {
+		t.Fatalf("secret written to config file:\n%s", data)
+	}
+	if !strings.Contains(string(data), "${MY_OPENAI_KEY}") || !strings.Contains(string(data), `"222"`) {
+		t.Fatalf("config file should keep the reference and gain the new entry:\n%s", data)
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0600 {
+		t.Errorf("file mode = %v, want 0600 kept", info.Mode().Perm())
+	}
+}
@@ -3,7 +3,9 @@ package channels
 import (
 	"context"
 	"fmt"
+	"slices"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/sipeed/picoclaw/pkg/bus"
@@ -24,6 +26,9 @@ type BaseChannel struct {
 	running   bool
 	name      string
 	allowList []string
+	allowMu   sync.RWMutex
+	statusMu  sync.Mutex
+	status    Status
 }
 
 func NewBaseChannel(name string, config interface{}, bus *bus.MessageBus, allowList []string) *BaseChannel {
@@ -31,7 +36,7 @@ func NewBaseChannel(name string, config interface{}, bus *bus.MessageBus, allowL
 		config:    config,
 		bus:       bus,
 		name:      name,
-		allowList: allowList,
+		allowList: slices.Clone(allowList),
 		running:   false,
 	}
 }
@@ -45,10 +50,31 @@ func (c *BaseChannel) IsRunning() bool {
 }
 
 func (c *BaseChannel) IsAllowed(senderID string) bool {
+	c.allowMu.RLock()
+	defer c.allowMu.RUnlock()
 	if len(c.allowList) == 0 {
 		return true
 	}
+	return matchesAllowList(c.allowList, senderID)
+}
+
+// AllowList returns a copy of the channel's current allowlist.
+func (c *BaseChannel) AllowList() []string {
+	c.allowMu.RLock()
+	defer c.allowMu.RUnlock()
+	return slices.Clone(c.allowList)
+}
 
+// SetAllowList replaces the allowlist of the running channel. An empty list
+// allows everyone.
+func (c *BaseChannel) SetAllowList(allowList []string) {
+	c.allowMu.Lock()
+	defer c.allowMu.Unlock()
+	c.allowList = slices.Clone(allowList)
+}
+
+// matchesAllowList reports whether senderID matches an entry of allowList.
+func matchesAllowList(allowList []string, senderID string) bool {
 	// Extract parts from compound senderID like "123456|username"
 	idPart := senderID
 	userPart := ""
@@ -57,7 +83,7 @@ func (c *BaseChannel) IsAllowed(senderID string) bool {
 		userPart = senderID[idx+1:]
 	}
 
-	for _, allowed := range c.allowList {
+	for _, allowed := range allowList {
 		// Strip leading "@" from allowed value for username matching
 		trimmed := strings.TrimPrefix(allowed, "@")
 		allowedID := trimmed
@@ -88,6 +114,12 @@ func (c *BaseChannel) HandleMessage(senderID, chatID, content string, media []st
 		return
 	}
 
+	// "/answer <id> <text>" answers a waiting callback tool.
+	if id, answer, ok := parseToolAnswer(content); ok {
+		c.bus.PublishInbound(bus.NewToolAnswer(c.name, senderID, chatID, id, answer))
+		return
+	}
+
 	// Build session key: channel:chatID
 	sessionKey := fmt.Sprintf("%s:%s", c.name, chatID)
 
@@ -108,6 +140,60 @@ func (c *BaseChannel) HandleMessage(senderID, chatID, content string, media []st
 	c.bus.PublishInbound(msg)
 }
 
+// parseToolAnswer splits "/answer <id> <text>" into the tool call ID and
+// the answer text.
+func parseToolAnswer(content string) (id, answer string, ok bool) {
+	rest, found := strings.CutPrefix(strings.TrimSpace(content), "/answer ")
+	if !found {
+		return "", "", false
+	}
+	id, answer, _ = strings.Cut(strings.TrimSpace(rest), " ")
+	answer = strings.TrimSpace(answer)
+	if id == "" || answer == "" {
+		return "", "", false
+	}
+	return id, answer, true
+}
+
 func (c *BaseChannel) setRunning(running bool) {
 	c.running = running
 }
+
+// Status is a channel's connection health as last observed.
+type Status struct {
+	Name         string    `json:"name"`
+	Running      bool      `json:"running"`
+	LastSuccess  time.Time `json:"last_success"`
+	LastError    time.Time `json:"last_error"`
+	LastErrorMsg string    `json:"last_error_message,omitempty"`
+}
+
+// Degraded reports whether the latest observed outcome was an error.
+func (s Status) Degraded() bool {
+	return !s.LastError.IsZero() && s.LastError.After(s.LastSuccess)
+}
+
+// RecordSuccess marks a successful poll, connection or send.
+func (c *BaseChannel) RecordSuccess() {
+	c.statusMu.Lock()
+	defer c.statusMu.Unlock()
+	c.status.LastSuccess = time.Now()
+}
+
+// RecordError marks a failed poll, connection or send.
+func (c *BaseChannel) RecordError(err error) {
+	c.statusMu.Lock()
+	defer c.statusMu.Unlock()
+	c.status.LastError = time.Now()
+	c.status.LastErrorMsg = err.Error()
+}
+
+// Status returns the channel's current health.
+func (c *BaseChannel) Status() Status {
+	c.statusMu.Lock()
+	status := c.status
+	c.statusMu.Unlock()
+	status.Name = c.name
+	status.Running = c.IsRunning()
+	return status
+}
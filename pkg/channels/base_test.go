@@ -1,6 +1,10 @@
 package channels
 
-import "testing"
+import (
+	"errors"
+	"testing"
+	"time"
+)
 
 func TestBaseChannelIsAllowed(t *testing.T) {
 	tests := []struct {
@@ -50,3 +54,46 @@ func TestBaseChannelIsAllowed(t *testing.T) {
 		})
 	}
 }
+
+func TestParseToolAnswer(t *testing.T) {
+	tests := []struct {
+		content    string
+		wantID     string
+		wantAnswer string
+		wantOK     bool
+	}{
+		{"/answer call_1 yes, go ahead", "call_1", "yes, go ahead", true},
+		{"  /answer call_1   42  ", "call_1", "42", true},
+		{"/answer call_1", "", "", false},
+		{"/answers call_1 yes", "", "", false},
+		{"please /answer call_1 yes", "", "", false},
+	}
+	for _, tt := range tests {
+		id, answer, ok := parseToolAnswer(tt.content)
+		if id != tt.wantID || answer != tt.wantAnswer || ok != tt.wantOK {
+			t.Errorf("parseToolAnswer(%q) = (%q, %q, %v), want (%q, %q, %v)",
+				tt.content, id, answer, ok, tt.wantID, tt.wantAnswer, tt.wantOK)
+		}
+	}
+}
+
+func TestBaseChannelStatus(t *testing.T) {
+	ch := NewBaseChannel("test", nil, nil, nil)
+	if ch.Status().Degraded() {
+		t.Fatal("a fresh channel should not be degraded")
+	}
+
+	ch.RecordSuccess()
+	time.Sleep(time.Millisecond)
+	ch.RecordError(errors.New("getUpdates failed"))
+	status := ch.Status()
+	if !status.Degraded() || status.LastErrorMsg != "getUpdates failed" {
+		t.Fatalf("after an error: %+v, want degraded with the error message", status)
+	}
+
+	time.Sleep(time.Millisecond)
+	ch.RecordSuccess()
+	if ch.Status().Degraded() {
+		t.Fatal("a success after the error should clear the degraded state")
+	}
+}
@@ -0,0 +1,85 @@
+package channels
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// dedupPruneInterval is how often a dedupCache sweeps expired entries.
+// Lookups honour the TTL on their own, so the sweep only bounds memory.
+const dedupPruneInterval = time.Minute
+
+// dedupCache remembers recently handled message IDs so redeliveries from
+// a platform can be dropped. IDs are retained for ttl; expired entries are
+// swept at most once per pruneInterval, and if the cache still exceeds
+// maxEntries the oldest entries are evicted first so that recently seen
+// IDs are never dropped in favour of stale ones.
+type dedupCache struct {
+	mu            sync.Mutex
+	seen          map[string]time.Time // key -> first seen
+	ttl           time.Duration
+	maxEntries    int
+	pruneInterval time.Duration
+	lastPrune     time.Time
+	now           func() time.Time
+}
+
+func newDedupCache(ttl time.Duration, maxEntries int) *dedupCache {
+	return &dedupCache{
+		seen:          make(map[string]time.Time),
+		ttl:           ttl,
+		maxEntries:    maxEntries,
+		pruneInterval: dedupPruneInterval,
+		now:           time.Now,
+	}
+}
+
+// Seen reports whether key was already recorded within the TTL, and
+// records it if not.
+func (d *dedupCache) Seen(key string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := d.now()
+	if seenAt, ok := d.seen[key]; ok && now.Sub(seenAt) < d.ttl {
+		return true
+	}
+	d.seen[key] = now
+
+	if now.Sub(d.lastPrune) >= d.pruneInterval {
+		d.pruneExpiredLocked(now)
+		d.lastPrune = now
+	}
+	if len(d.seen) > d.maxEntries {
+		d.evictOldestLocked(len(d.seen) - d.maxEntries)
+	}
+	return false
+}
+
+// pruneExpiredLocked drops entries older than the TTL. Caller must hold d.mu.
+func (d *dedupCache) pruneExpiredLocked(now time.Time) {
+	for key, seenAt := range d.seen {
+		if now.Sub(seenAt) >= d.ttl {
+			delete(d.seen, key)
+		}
+	}
+}
+
+// evictOldestLocked drops the n oldest entries. Caller must hold d.mu.
+func (d *dedupCache) evictOldestLocked(n int) {
+	type seenEntry struct {
+		key    string
+		seenAt time.Time
+	}
+	entries := make([]seenEntry, 0, len(d.seen))
+	for key, seenAt := range d.seen {
+		entries = append(entries, seenEntry{key: key, seenAt: seenAt})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].seenAt.Before(entries[j].seenAt)
+	})
+	for _, e := range entries[:n] {
+		delete(d.seen, e.key)
+	}
+}
@@ -0,0 +1,29 @@
+package channels
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDedupCache_SweepsExpiredOnInterval(t *testing.T) {
+	d := newDedupCache(time.Minute, 100)
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	d.now = func() time.Time { return now }
+	d.pruneInterval = 5 * time.Minute
+
+	d.Seen("old")
+	now = now.Add(2 * time.Minute)
+	d.Seen("new")
+	if _, ok := d.seen["old"]; !ok {
+		t.Fatal("expired entry should be kept until the next sweep")
+	}
+	if d.Seen("old") {
+		t.Fatal("expired entry should not count as a duplicate")
+	}
+
+	now = now.Add(5 * time.Minute)
+	d.Seen("latest")
+	if len(d.seen) != 1 {
+		t.Fatalf("sweep should leave only the latest entry, have %v", d.seen)
+	}
+}
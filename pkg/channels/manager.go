@@ -9,6 +9,7 @@ package channels
 import (
 	"context"
 	"fmt"
+	"sort"
 	"sync"
 
 	"github.com/sipeed/picoclaw/pkg/bus"
@@ -22,6 +23,8 @@ type Manager struct {
 	bus          *bus.MessageBus
 	config       *config.Config
 	dispatchTask *asyncTask
+	outbound     *outboundQueue // nil when outbound rate limiting is disabled
+	configPath   string         // Where allowlist changes are saved ("" = memory only)
 	mu           sync.RWMutex
 }
 
@@ -54,6 +57,8 @@ func (m *Manager) initChannels() error {
 				"error": err.Error(),
 			})
 		} else {
+			// Attachment dedup is a store setting shared by every channel.
+			telegram.attachmentStore.SetDedup(m.config.Attachments.Dedup)
 			m.channels["telegram"] = telegram
 			logger.InfoC("channels", "Telegram channel enabled successfully")
 		}
@@ -196,6 +201,9 @@ func (m *Manager) StartAll(ctx context.Context) error {
 
 	dispatchCtx, cancel := context.WithCancel(ctx)
 	m.dispatchTask = &asyncTask{cancel: cancel}
+	if rl := m.config.Channels.Outbound; rl.MessagesPerSecond > 0 {
+		m.outbound = newOutboundQueue(dispatchCtx, rl.MessagesPerSecond, rl.Burst, m.sendToChannel)
+	}
 
 	go m.dispatchOutbound(dispatchCtx)
 
@@ -208,6 +216,9 @@ func (m *Manager) StartAll(ctx context.Context) error {
 				"channel": name,
 				"error":   err.Error(),
 			})
+			recordOutcome(channel, err)
+		} else {
+			recordOutcome(channel, nil)
 		}
 	}
 
@@ -216,16 +227,24 @@ func (m *Manager) StartAll(ctx context.Context) error {
 }
 
 func (m *Manager) StopAll(ctx context.Context) error {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-
 	logger.InfoC("channels", "Stopping all channels")
 
-	if m.dispatchTask != nil {
-		m.dispatchTask.cancel()
-		m.dispatchTask = nil
+	// Stop dispatching and let queued final messages flush before the
+	// channels go away. Done outside m.mu since flushing sends take RLock.
+	m.mu.Lock()
+	task, outbound := m.dispatchTask, m.outbound
+	m.dispatchTask, m.outbound = nil, nil
+	m.mu.Unlock()
+	if task != nil {
+		task.cancel()
+	}
+	if outbound != nil {
+		outbound.Close()
 	}
 
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	for name, channel := range m.channels {
 		logger.InfoCF("channels", "Stopping channel", map[string]interface{}{
 			"channel": name,
@@ -262,26 +281,67 @@ func (m *Manager) dispatchOutbound(ctx context.Context) {
 			}
 
 			m.mu.RLock()
-			channel, exists := m.channels[msg.Channel]
+			outbound := m.outbound
 			m.mu.RUnlock()
 
-			if !exists {
-				logger.WarnCF("channels", "Unknown channel for outbound message", map[string]interface{}{
-					"channel": msg.Channel,
-				})
-				continue
-			}
-
-			if err := channel.Send(ctx, msg); err != nil {
-				logger.ErrorCF("channels", "Error sending message to channel", map[string]interface{}{
-					"channel": msg.Channel,
-					"error":   err.Error(),
-				})
+			if outbound != nil {
+				if err := outbound.Enqueue(msg); err != nil {
+					logger.WarnCF("channels", "Dropping outbound message after shutdown", map[string]interface{}{
+						"channel": msg.Channel,
+						"chat_id": msg.ChatID,
+					})
+				}
+			} else {
+				m.sendToChannel(ctx, msg)
 			}
 		}
 	}
 }
 
+// sendToChannel delivers msg to its channel, logging any failure.
+func (m *Manager) sendToChannel(ctx context.Context, msg bus.OutboundMessage) {
+	m.mu.RLock()
+	channel, exists := m.channels[msg.Channel]
+	m.mu.RUnlock()
+
+	if !exists {
+		logger.WarnCF("channels", "Unknown channel for outbound message", map[string]interface{}{
+			"channel": msg.Channel,
+		})
+		return
+	}
+
+	err := channel.Send(ctx, msg)
+	recordOutcome(channel, err)
+	if err != nil {
+		logger.ErrorCF("channels", "Error sending message to channel", map[string]interface{}{
+			"channel": msg.Channel,
+			"error":   err.Error(),
+		})
+	}
+}
+
+// statusRecorder is implemented by channels embedding BaseChannel.
+type statusRecorder interface {
+	RecordSuccess()
+	RecordError(err error)
+	Status() Status
+}
+
+// recordOutcome updates the channel's status with the result of a start or
+// send, if the channel tracks status.
+func recordOutcome(channel Channel, err error) {
+	recorder, ok := channel.(statusRecorder)
+	if !ok {
+		return
+	}
+	if err != nil {
+		recorder.RecordError(err)
+	} else {
+		recorder.RecordSuccess()
+	}
+}
+
 func (m *Manager) GetChannel(name string) (Channel, bool) {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
@@ -303,6 +363,25 @@ func (m *Manager) GetStatus() map[string]interface{} {
 	return status
 }
 
+// ChannelStatuses returns the health of every channel, sorted by name.
+func (m *Manager) ChannelStatuses() []Status {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	statuses := make([]Status, 0, len(m.channels))
+	for name, channel := range m.channels {
+		if recorder, ok := channel.(statusRecorder); ok {
+			status := recorder.Status()
+			status.Name = name
+			statuses = append(statuses, status)
+		} else {
+			statuses = append(statuses, Status{Name: name, Running: channel.IsRunning()})
+		}
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses
+}
+
 func (m *Manager) GetEnabledChannels() []string {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
@@ -341,5 +420,7 @@ func (m *Manager) SendToChannel(ctx context.Context, channelName, chatID, conten
 		Content: content,
 	}
 
-	return channel.Send(ctx, msg)
+	err := channel.Send(ctx, msg)
+	recordOutcome(channel, err)
+	return err
 }
@@ -81,14 +81,13 @@ type oneBotAPIRequest struct {
 	Echo   string      `json:"echo,omitempty"`
 }
 
-type oneBotSendPrivateMsgParams struct {
-	UserID  int64  `json:"user_id"`
-	Message string `json:"message"`
-}
-
-type oneBotSendGroupMsgParams struct {
-	GroupID int64  `json:"group_id"`
-	Message string `json:"message"`
+// oneBotSendMsgParams are the params of the send_msg action, which sends
+// to a user or a group depending on MessageType.
+type oneBotSendMsgParams struct {
+	MessageType string `json:"message_type"`
+	UserID      int64  `json:"user_id,omitempty"`
+	GroupID     int64  `json:"group_id,omitempty"`
+	Message     string `json:"message"`
 }
 
 func NewOneBotChannel(cfg config.OneBotConfig, messageBus *bus.MessageBus) (*OneBotChannel, error) {
@@ -156,6 +155,7 @@ func (c *OneBotChannel) connect() error {
 	c.conn = conn
 	c.mu.Unlock()
 
+	c.RecordSuccess()
 	logger.InfoC("onebot", "WebSocket connected")
 	return nil
 }
@@ -178,6 +178,7 @@ func (c *OneBotChannel) reconnectLoop() {
 			if conn == nil {
 				logger.InfoC("onebot", "Attempting to reconnect...")
 				if err := c.connect(); err != nil {
+					c.RecordError(err)
 					logger.ErrorCF("onebot", "Reconnect failed", map[string]interface{}{
 						"error": err.Error(),
 					})
@@ -258,36 +259,30 @@ func (c *OneBotChannel) Send(ctx context.Context, msg bus.OutboundMessage) error
 func (c *OneBotChannel) buildSendRequest(msg bus.OutboundMessage) (string, interface{}, error) {
 	chatID := msg.ChatID
 
-	if len(chatID) > 6 && chatID[:6] == "group:" {
-		groupID, err := strconv.ParseInt(chatID[6:], 10, 64)
+	if rest, ok := strings.CutPrefix(chatID, "group:"); ok && rest != "" {
+		groupID, err := strconv.ParseInt(rest, 10, 64)
 		if err != nil {
 			return "", nil, fmt.Errorf("invalid group ID in chatID: %s", chatID)
 		}
-		return "send_group_msg", oneBotSendGroupMsgParams{
-			GroupID: groupID,
-			Message: msg.Content,
+		return "send_msg", oneBotSendMsgParams{
+			MessageType: "group",
+			GroupID:     groupID,
+			Message:     msg.Content,
 		}, nil
 	}
 
-	if len(chatID) > 8 && chatID[:8] == "private:" {
-		userID, err := strconv.ParseInt(chatID[8:], 10, 64)
-		if err != nil {
-			return "", nil, fmt.Errorf("invalid user ID in chatID: %s", chatID)
-		}
-		return "send_private_msg", oneBotSendPrivateMsgParams{
-			UserID:  userID,
-			Message: msg.Content,
-		}, nil
+	if rest, ok := strings.CutPrefix(chatID, "private:"); ok && rest != "" {
+		chatID = rest
 	}
-
 	userID, err := strconv.ParseInt(chatID, 10, 64)
 	if err != nil {
-		return "", nil, fmt.Errorf("invalid chatID for OneBot: %s", chatID)
+		return "", nil, fmt.Errorf("invalid chatID for OneBot: %s", msg.ChatID)
 	}
 
-	return "send_private_msg", oneBotSendPrivateMsgParams{
-		UserID:  userID,
-		Message: msg.Content,
+	return "send_msg", oneBotSendMsgParams{
+		MessageType: "private",
+		UserID:      userID,
+		Message:     msg.Content,
 	}, nil
 }
 
@@ -308,6 +303,9 @@ func (c *OneBotChannel) listen() {
 
 			_, message, err := conn.ReadMessage()
 			if err != nil {
+				if c.ctx.Err() == nil {
+					c.RecordError(err)
+				}
 				logger.ErrorCF("onebot", "WebSocket read error", map[string]interface{}{
 					"error": err.Error(),
 				})
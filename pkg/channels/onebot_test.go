@@ -0,0 +1,124 @@
+package channels
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func TestOneBotBuildSendRequest_UsesSendMsg(t *testing.T) {
+	ch, err := NewOneBotChannel(config.OneBotConfig{}, bus.NewMessageBus())
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		chatID string
+		want   oneBotSendMsgParams
+	}{
+		{"group:42", oneBotSendMsgParams{MessageType: "group", GroupID: 42, Message: "hi"}},
+		{"private:7", oneBotSendMsgParams{MessageType: "private", UserID: 7, Message: "hi"}},
+		{"7", oneBotSendMsgParams{MessageType: "private", UserID: 7, Message: "hi"}},
+	}
+	for _, tt := range tests {
+		action, params, err := ch.buildSendRequest(bus.OutboundMessage{ChatID: tt.chatID, Content: "hi"})
+		if err != nil || action != "send_msg" || params != tt.want {
+			t.Errorf("buildSendRequest(%q) = %q, %+v, %v; want send_msg %+v", tt.chatID, action, params, err, tt.want)
+		}
+	}
+	for _, bad := range []string{"group:", "group:abc", "someone"} {
+		if _, _, err := ch.buildSendRequest(bus.OutboundMessage{ChatID: bad}); err == nil {
+			t.Errorf("buildSendRequest(%q) should fail", bad)
+		}
+	}
+}
+
+func TestOneBotChannel_EndToEnd(t *testing.T) {
+	upgrader := websocket.Upgrader{}
+	sent := make(chan oneBotAPIRequest, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		event := func(messageID, userID int, text string) string {
+			b, _ := json.Marshal(map[string]interface{}{
+				"post_type": "message", "message_type": "group", "message_id": messageID,
+				"user_id": userID, "group_id": 42, "self_id": 1, "message": text,
+				"sender": map[string]interface{}{"user_id": userID, "nickname": "n"},
+			})
+			return string(b)
+		}
+		for _, msg := range []string{
+			event(1, 100, "no trigger"),
+			event(2, 200, "!bot from a stranger"),
+			event(3, 100, "!bot hello"),
+			event(3, 100, "!bot hello"), // redelivered
+		} {
+			conn.WriteMessage(websocket.TextMessage, []byte(msg))
+		}
+
+		var req oneBotAPIRequest
+		if err := conn.ReadJSON(&req); err == nil {
+			sent <- req
+		}
+	}))
+	defer srv.Close()
+
+	msgBus := bus.NewMessageBus()
+	ch, err := NewOneBotChannel(config.OneBotConfig{
+		WSUrl:              "ws" + strings.TrimPrefix(srv.URL, "http"),
+		AccessToken:        "secret",
+		GroupTriggerPrefix: []string{"!bot"},
+		AllowFrom:          config.FlexibleStringSlice{"100"},
+	}, msgBus)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := ch.Start(ctx); err != nil {
+		t.Fatalf("Start() error: %v", err)
+	}
+	defer ch.Stop(context.Background())
+
+	in, ok := msgBus.ConsumeInbound(ctx)
+	if !ok || in.Content != "hello" || in.ChatID != "group:42" || in.SenderID != "100" {
+		t.Fatalf("inbound = %+v, want the triggered message from the allowed user", in)
+	}
+	dupCtx, dupCancel := context.WithTimeout(ctx, 200*time.Millisecond)
+	defer dupCancel()
+	if extra, ok := msgBus.ConsumeInbound(dupCtx); ok {
+		t.Fatalf("unexpected extra inbound message: %+v", extra)
+	}
+	if !ch.Status().LastSuccess.After(time.Time{}) {
+		t.Error("a successful connection should be recorded in the channel status")
+	}
+
+	if err := ch.Send(ctx, bus.OutboundMessage{ChatID: in.ChatID, Content: "hi"}); err != nil {
+		t.Fatalf("Send() error: %v", err)
+	}
+	select {
+	case req := <-sent:
+		params, _ := json.Marshal(req.Params)
+		if req.Action != "send_msg" || !strings.Contains(string(params), `"group_id":42`) {
+			t.Fatalf("request = %s %s, want send_msg to group 42", req.Action, params)
+		}
+	case <-ctx.Done():
+		t.Fatal("no send_msg request received")
+	}
+}
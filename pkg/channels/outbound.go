@@ -0,0 +1,188 @@
+package channels
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+)
+
+// outboundFlushTimeout bounds how long pending final messages may take to
+// flush after the dispatcher is stopped.
+const outboundFlushTimeout = 5 * time.Second
+
+// outboundQueue serializes and rate-limits outbound messages per chat using a
+// token bucket. Consecutive pending progress updates for a chat are coalesced
+// so only the latest one is sent; final (non-progress) messages are never
+// dropped.
+type outboundQueue struct {
+	send  func(ctx context.Context, msg bus.OutboundMessage)
+	rate  float64 // tokens per second
+	burst float64
+
+	ctx    context.Context
+	mu     sync.Mutex
+	chats  map[string]*chatOutbox // only chats with a running worker
+	closed bool
+	wg     sync.WaitGroup
+}
+
+// errOutboundClosed is returned by Enqueue after Close.
+var errOutboundClosed = errors.New("outbound queue closed")
+
+type chatOutbox struct {
+	key     string
+	pending []bus.OutboundMessage
+	tokens  float64
+	last    time.Time
+	running bool
+}
+
+func newOutboundQueue(ctx context.Context, messagesPerSecond float64, burst int, send func(ctx context.Context, msg bus.OutboundMessage)) *outboundQueue {
+	if burst < 1 {
+		burst = 1
+	}
+	return &outboundQueue{
+		send:  send,
+		rate:  messagesPerSecond,
+		burst: float64(burst),
+		ctx:   ctx,
+		chats: make(map[string]*chatOutbox),
+	}
+}
+
+// Enqueue adds msg to its chat's queue, starting a worker for the chat if
+// none is running. It fails once the queue is closed.
+func (q *outboundQueue) Enqueue(msg bus.OutboundMessage) error {
+	key := msg.Channel + ":" + msg.ChatID
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return errOutboundClosed
+	}
+	box, ok := q.chats[key]
+	if !ok {
+		box = &chatOutbox{key: key, tokens: q.burst, last: time.Now()}
+		q.chats[key] = box
+	}
+
+	if n := len(box.pending); msg.IsProgressUpdate && n > 0 && box.pending[n-1].IsProgressUpdate {
+		box.pending[n-1] = msg
+	} else {
+		box.pending = append(box.pending, msg)
+	}
+
+	if !box.running {
+		box.running = true
+		q.wg.Add(1)
+		go q.run(box)
+	}
+	return nil
+}
+
+// Close stops accepting messages and blocks until all chat workers have
+// exited. Workers still drain their queues; cancel the queue's context to
+// flush them without rate limiting.
+func (q *outboundQueue) Close() {
+	q.mu.Lock()
+	q.closed = true
+	q.mu.Unlock()
+	q.wg.Wait()
+}
+
+func (q *outboundQueue) run(box *chatOutbox) {
+	defer q.wg.Done()
+
+	for {
+		if q.ctx.Err() != nil {
+			q.flush(box)
+			return
+		}
+
+		q.mu.Lock()
+		if len(box.pending) == 0 {
+			// Keep the chat's bucket until it has refilled, so a burst
+			// right after draining is still limited; then drop it.
+			wait := q.refillLocked(box)
+			if wait == 0 {
+				box.running = false
+				delete(q.chats, box.key)
+				q.mu.Unlock()
+				return
+			}
+			q.mu.Unlock()
+			q.sleep(wait)
+			continue
+		}
+		wait := q.reserveLocked(box)
+		var msg bus.OutboundMessage
+		if wait == 0 {
+			msg = box.pending[0]
+			box.pending = box.pending[1:]
+		}
+		q.mu.Unlock()
+
+		if wait > 0 {
+			q.sleep(wait)
+			continue
+		}
+
+		q.send(q.ctx, msg)
+	}
+}
+
+// sleep waits for d or until the queue's context is cancelled.
+func (q *outboundQueue) sleep(d time.Duration) {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-q.ctx.Done():
+	}
+}
+
+// refillLocked adds the tokens earned since the last refill and returns how
+// long until the bucket is full.
+func (q *outboundQueue) refillLocked(box *chatOutbox) time.Duration {
+	now := time.Now()
+	box.tokens += now.Sub(box.last).Seconds() * q.rate
+	if box.tokens > q.burst {
+		box.tokens = q.burst
+	}
+	box.last = now
+	return time.Duration((q.burst - box.tokens) / q.rate * float64(time.Second))
+}
+
+// reserveLocked refills the bucket and takes a token if one is available.
+// It returns how long to wait otherwise.
+func (q *outboundQueue) reserveLocked(box *chatOutbox) time.Duration {
+	q.refillLocked(box)
+	if box.tokens >= 1 {
+		box.tokens--
+		return 0
+	}
+	return time.Duration((1 - box.tokens) / q.rate * float64(time.Second))
+}
+
+// flush sends any remaining final messages after shutdown, bypassing the
+// rate limit. Pending progress updates are dropped.
+func (q *outboundQueue) flush(box *chatOutbox) {
+	q.mu.Lock()
+	pending := box.pending
+	box.pending = nil
+	box.running = false
+	delete(q.chats, box.key)
+	q.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), outboundFlushTimeout)
+	defer cancel()
+	for _, msg := range pending {
+		if !msg.IsProgressUpdate {
+			q.send(ctx, msg)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package channels
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+)
+
+type recordingSender struct {
+	mu      sync.Mutex
+	sent    []bus.OutboundMessage
+	times   []time.Time
+	block   chan struct{} // if set, the first send waits on it
+	started chan struct{} // closed when the blocked first send begins
+}
+
+func (r *recordingSender) send(ctx context.Context, msg bus.OutboundMessage) {
+	if r.block != nil {
+		close(r.started)
+		<-r.block
+		r.block = nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sent = append(r.sent, msg)
+	r.times = append(r.times, time.Now())
+}
+
+func (r *recordingSender) contents() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := make([]string, len(r.sent))
+	for i, m := range r.sent {
+		out[i] = m.Content
+	}
+	return out
+}
+
+func TestOutboundQueue_CoalescesProgressUpdates(t *testing.T) {
+	rec := &recordingSender{block: make(chan struct{}), started: make(chan struct{})}
+	q := newOutboundQueue(context.Background(), 1000, 10, rec.send)
+
+	q.Enqueue(bus.OutboundMessage{Channel: "telegram", ChatID: "1", Content: "plan", IsProgressUpdate: true})
+	<-rec.started
+	// The worker is now blocked sending "plan"; these pile up behind it.
+	q.Enqueue(bus.OutboundMessage{Channel: "telegram", ChatID: "1", Content: "step 1", IsProgressUpdate: true})
+	q.Enqueue(bus.OutboundMessage{Channel: "telegram", ChatID: "1", Content: "step 2", IsProgressUpdate: true})
+	q.Enqueue(bus.OutboundMessage{Channel: "telegram", ChatID: "1", Content: "final"})
+	q.Enqueue(bus.OutboundMessage{Channel: "telegram", ChatID: "1", Content: "step 3", IsProgressUpdate: true})
+	close(rec.block)
+	q.Close()
+
+	got := rec.contents()
+	want := []string{"plan", "step 2", "final", "step 3"}
+	if len(got) != len(want) {
+		t.Fatalf("sent = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("sent = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestOutboundQueue_RateLimitsPerChat(t *testing.T) {
+	rec := &recordingSender{}
+	q := newOutboundQueue(context.Background(), 20, 1, rec.send) // one message per 50ms
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		q.Enqueue(bus.OutboundMessage{Channel: "telegram", ChatID: "1", Content: "msg"})
+	}
+	q.Enqueue(bus.OutboundMessage{Channel: "telegram", ChatID: "2", Content: "other chat"})
+	q.Close()
+
+	if got := len(rec.contents()); got != 4 {
+		t.Fatalf("sent %d messages, want 4", got)
+	}
+	// Chat 1 needs two refills after the initial token: at least ~100ms.
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+		t.Fatalf("3 messages to one chat took %v, expected rate limiting", elapsed)
+	}
+	// Chat 2 has its own bucket and must not wait behind chat 1.
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	for i, m := range rec.sent {
+		if m.ChatID == "2" && rec.times[i].Sub(start) > 40*time.Millisecond {
+			t.Fatalf("other chat delayed by %v", rec.times[i].Sub(start))
+		}
+	}
+}
+
+func TestOutboundQueue_FlushesFinalMessagesOnShutdown(t *testing.T) {
+	rec := &recordingSender{}
+	ctx, cancel := context.WithCancel(context.Background())
+	q := newOutboundQueue(ctx, 0.01, 1, rec.send) // effectively one message, then a long wait
+
+	q.Enqueue(bus.OutboundMessage{Channel: "telegram", ChatID: "1", Content: "first"})
+	q.Enqueue(bus.OutboundMessage{Channel: "telegram", ChatID: "1", Content: "progress", IsProgressUpdate: true})
+	q.Enqueue(bus.OutboundMessage{Channel: "telegram", ChatID: "1", Content: "final"})
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+	q.Close()
+
+	got := rec.contents()
+	if len(got) != 2 || got[0] != "first" || got[1] != "final" {
+		t.Fatalf("sent = %v, want [first final] (progress dropped, final flushed)", got)
+	}
+}
+
+func TestOutboundQueue_DropsIdleChatsAndRefusesAfterClose(t *testing.T) {
+	rec := &recordingSender{}
+	q := newOutboundQueue(context.Background(), 1000, 2, rec.send)
+
+	for i := 0; i < 50; i++ {
+		if err := q.Enqueue(bus.OutboundMessage{Channel: "telegram", ChatID: string(rune('a' + i%26)), Content: "hi"}); err != nil {
+			t.Fatalf("Enqueue() error: %v", err)
+		}
+	}
+	q.Close()
+
+	q.mu.Lock()
+	left := len(q.chats)
+	q.mu.Unlock()
+	if left != 0 {
+		t.Errorf("%d chat outboxes left after draining, want 0", left)
+	}
+	if got := len(rec.contents()); got != 50 {
+		t.Errorf("sent %d messages, want 50", got)
+	}
+	if err := q.Enqueue(bus.OutboundMessage{Channel: "telegram", ChatID: "a"}); err != errOutboundClosed {
+		t.Errorf("Enqueue() after Close = %v, want errOutboundClosed", err)
+	}
+}
@@ -3,7 +3,6 @@ package channels
 import (
 	"context"
 	"fmt"
-	"sync"
 	"time"
 
 	"github.com/tencent-connect/botgo"
@@ -26,17 +25,30 @@ type QQChannel struct {
 	ctx            context.Context
 	cancel         context.CancelFunc
 	sessionManager botgo.SessionManager
-	processedIDs   map[string]bool
-	mu             sync.RWMutex
+	dedup          *dedupCache
 }
 
+const (
+	defaultQQDedupTTL        = 10 * time.Minute
+	defaultQQDedupMaxEntries = 10000
+)
+
 func NewQQChannel(cfg config.QQConfig, messageBus *bus.MessageBus) (*QQChannel, error) {
 	base := NewBaseChannel("qq", cfg, messageBus, cfg.AllowFrom)
 
+	dedupTTL := time.Duration(cfg.DedupTTLSeconds) * time.Second
+	if dedupTTL <= 0 {
+		dedupTTL = defaultQQDedupTTL
+	}
+	dedupMax := cfg.DedupMaxEntries
+	if dedupMax <= 0 {
+		dedupMax = defaultQQDedupMaxEntries
+	}
+
 	return &QQChannel{
-		BaseChannel:  base,
-		config:       cfg,
-		processedIDs: make(map[string]bool),
+		BaseChannel: base,
+		config:      cfg,
+		dedup:       newDedupCache(dedupTTL, dedupMax),
 	}, nil
 }
 
@@ -215,29 +227,7 @@ func (c *QQChannel) handleGroupATMessage() event.GroupATMessageEventHandler {
 	}
 }
 
-// isDuplicate checks whether message is duplicate
+// isDuplicate checks whether message is duplicate.
 func (c *QQChannel) isDuplicate(messageID string) bool {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
-	if c.processedIDs[messageID] {
-		return true
-	}
-
-	c.processedIDs[messageID] = true
-
-	// Simple cleanup: limit map size
-	if len(c.processedIDs) > 10000 {
-		// Clear half
-		count := 0
-		for id := range c.processedIDs {
-			if count >= 5000 {
-				break
-			}
-			delete(c.processedIDs, id)
-			count++
-		}
-	}
-
-	return false
+	return c.dedup.Seen(messageID)
 }
@@ -0,0 +1,83 @@
+package channels
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func newTestQQChannel(t *testing.T, cfg config.QQConfig) (*QQChannel, *time.Time) {
+	t.Helper()
+
+	ch, err := NewQQChannel(cfg, bus.NewMessageBus())
+	if err != nil {
+		t.Fatalf("NewQQChannel() error: %v", err)
+	}
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	ch.dedup.now = func() time.Time { return now }
+	return ch, &now
+}
+
+func TestQQIsDuplicate_WithinTTL(t *testing.T) {
+	ch, now := newTestQQChannel(t, config.QQConfig{DedupTTLSeconds: 60})
+
+	if ch.isDuplicate("msg-1") {
+		t.Fatal("first delivery should not be a duplicate")
+	}
+
+	*now = now.Add(30 * time.Second)
+	if !ch.isDuplicate("msg-1") {
+		t.Fatal("redelivery within TTL should be a duplicate")
+	}
+}
+
+func TestQQIsDuplicate_PrunesExpired(t *testing.T) {
+	ch, now := newTestQQChannel(t, config.QQConfig{DedupTTLSeconds: 60})
+
+	ch.isDuplicate("old")
+	*now = now.Add(2 * time.Minute)
+	ch.isDuplicate("new")
+
+	ch.dedup.mu.Lock()
+	_, hasOld := ch.dedup.seen["old"]
+	_, hasNew := ch.dedup.seen["new"]
+	ch.dedup.mu.Unlock()
+
+	if hasOld {
+		t.Error("expired ID should have been pruned")
+	}
+	if !hasNew {
+		t.Error("recent ID should be retained")
+	}
+	if ch.isDuplicate("old") {
+		t.Error("expired ID should be admitted again")
+	}
+}
+
+func TestQQIsDuplicate_SizeCapEvictsOldestFirst(t *testing.T) {
+	ch, now := newTestQQChannel(t, config.QQConfig{DedupTTLSeconds: 3600, DedupMaxEntries: 3})
+
+	for _, id := range []string{"a", "b", "c", "d"} {
+		ch.isDuplicate(id)
+		*now = now.Add(time.Second)
+	}
+
+	ch.dedup.mu.Lock()
+	size := len(ch.dedup.seen)
+	_, hasA := ch.dedup.seen["a"]
+	ch.dedup.mu.Unlock()
+
+	if size != 3 {
+		t.Fatalf("dedup size = %d, want 3", size)
+	}
+	if hasA {
+		t.Error("oldest ID should be evicted when over the size cap")
+	}
+	for _, id := range []string{"b", "c", "d"} {
+		if !ch.isDuplicate(id) {
+			t.Errorf("recent ID %q should still be deduped", id)
+		}
+	}
+}
@@ -2,15 +2,19 @@ package channels
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
 	"os"
 	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
+	"unicode/utf16"
+	"unicode/utf8"
 
 	"github.com/mymmrac/telego"
 	tu "github.com/mymmrac/telego/telegoutil"
@@ -28,10 +32,15 @@ type TelegramChannel struct {
 	bot             *telego.Bot
 	config          config.TelegramConfig
 	chatIDs         map[string]int64
-	transcriber     *voice.GroqTranscriber
+	transcriber     voice.Transcriber
 	attachmentStore *attachments.Store
-	placeholders    sync.Map // chatID -> messageID
-	stopThinking    sync.Map // chatID -> thinkingCancel
+	placeholders    sync.Map    // chatID -> messageID
+	stopThinking    sync.Map    // chatID -> thinkingCancel
+	dedup           *dedupCache // keyed by "chatID:messageID"
+	stopPolling     context.CancelFunc
+
+	reconnectMinBackoff time.Duration
+	reconnectMaxBackoff time.Duration
 }
 
 type thinkingCancel struct {
@@ -46,6 +55,16 @@ func (c *thinkingCancel) Cancel() {
 
 const telegramAttachmentMaxBytes int64 = 100 * 1024 * 1024 // 100 MB
 
+const (
+	telegramReconnectMinBackoff = time.Second
+	telegramReconnectMaxBackoff = 2 * time.Minute
+)
+
+const (
+	defaultTelegramDedupTTL        = 10 * time.Minute
+	defaultTelegramDedupMaxEntries = 10000
+)
+
 func NewTelegramChannel(cfg config.TelegramConfig, bus *bus.MessageBus, workspace string) (*TelegramChannel, error) {
 	var opts []telego.BotOption
 
@@ -68,59 +87,132 @@ func NewTelegramChannel(cfg config.TelegramConfig, bus *bus.MessageBus, workspac
 
 	base := NewBaseChannel("telegram", cfg, bus, cfg.AllowFrom)
 
+	dedupTTL := time.Duration(cfg.DedupTTLSeconds) * time.Second
+	if dedupTTL <= 0 {
+		dedupTTL = defaultTelegramDedupTTL
+	}
+	dedupMax := cfg.DedupMaxEntries
+	if dedupMax <= 0 {
+		dedupMax = defaultTelegramDedupMaxEntries
+	}
+
+	attachmentStore := attachments.NewStore(workspace)
+
 	return &TelegramChannel{
 		BaseChannel:     base,
 		bot:             bot,
 		config:          cfg,
 		chatIDs:         make(map[string]int64),
 		transcriber:     nil,
-		attachmentStore: attachments.NewStore(workspace),
+		attachmentStore: attachmentStore,
 		placeholders:    sync.Map{},
 		stopThinking:    sync.Map{},
+		dedup:           newDedupCache(dedupTTL, dedupMax),
+
+		reconnectMinBackoff: telegramReconnectMinBackoff,
+		reconnectMaxBackoff: telegramReconnectMaxBackoff,
 	}, nil
 }
 
-func (c *TelegramChannel) SetTranscriber(transcriber *voice.GroqTranscriber) {
+func (c *TelegramChannel) SetTranscriber(transcriber voice.Transcriber) {
 	c.transcriber = transcriber
 }
 
 func (c *TelegramChannel) Start(ctx context.Context) error {
 	logger.InfoC("telegram", "Starting Telegram bot (polling mode)...")
 
-	updates, err := c.bot.UpdatesViaLongPolling(ctx, &telego.GetUpdatesParams{
-		Timeout: 30,
-	})
+	pollCtx, cancel := context.WithCancel(ctx)
+	updates, err := c.startLongPolling(pollCtx, 0)
 	if err != nil {
+		cancel()
 		return fmt.Errorf("failed to start long polling: %w", err)
 	}
 
+	c.stopPolling = cancel
 	c.setRunning(true)
 	logger.InfoCF("telegram", "Telegram bot connected", map[string]interface{}{
 		"username": c.bot.Username(),
 	})
 
-	go func() {
-		for {
+	go c.pollUpdates(pollCtx, updates)
+
+	return nil
+}
+
+// startLongPolling starts telego long polling from the given update offset.
+// telego's own fixed-interval retry is disabled so a failed getUpdates closes
+// the updates channel and pollUpdates can reconnect with backoff.
+func (c *TelegramChannel) startLongPolling(ctx context.Context, offset int) (<-chan telego.Update, error) {
+	return c.bot.UpdatesViaLongPolling(ctx, &telego.GetUpdatesParams{
+		Offset:  offset,
+		Timeout: 30,
+	}, telego.WithLongPollingRetryTimeout(0))
+}
+
+// pollUpdates dispatches updates until ctx is done. Whenever the updates
+// channel closes, long polling is restarted from the last seen update with
+// exponential backoff; the channel stays marked as running throughout so
+// outbound sends keep working.
+func (c *TelegramChannel) pollUpdates(ctx context.Context, updates <-chan telego.Update) {
+	var offset int
+	backoff := c.reconnectMinBackoff
+
+	for {
+		connectedAt := time.Now()
+		for update := range updates {
+			c.RecordSuccess()
+			offset = update.UpdateID + 1
+			if update.Message != nil {
+				c.handleMessage(ctx, update)
+			}
+		}
+		if ctx.Err() != nil {
+			return
+		}
+
+		// A connection that stayed up for a while was healthy; start the
+		// backoff over instead of carrying it from an earlier outage.
+		if time.Since(connectedAt) > c.reconnectMaxBackoff {
+			backoff = c.reconnectMinBackoff
+		}
+
+		logger.WarnC("telegram", "Updates channel closed")
+		c.RecordError(errors.New("updates channel closed"))
+		for attempt := 1; ; attempt++ {
+			logger.WarnCF("telegram", "Reconnecting long polling", map[string]interface{}{
+				"attempt": attempt,
+				"backoff": backoff.String(),
+			})
 			select {
 			case <-ctx.Done():
 				return
-			case update, ok := <-updates:
-				if !ok {
-					logger.InfoC("telegram", "Updates channel closed, reconnecting...")
-					return
-				}
-				if update.Message != nil {
-					c.handleMessage(ctx, update)
-				}
+			case <-time.After(backoff):
+			}
+			backoff = min(backoff*2, c.reconnectMaxBackoff)
+
+			var err error
+			updates, err = c.startLongPolling(ctx, offset)
+			if err == nil {
+				c.RecordSuccess()
+				logger.InfoCF("telegram", "Telegram long polling reconnected", map[string]interface{}{
+					"attempt": attempt,
+				})
+				break
 			}
+			c.RecordError(err)
+			logger.ErrorCF("telegram", "Failed to restart long polling", map[string]interface{}{
+				"attempt": attempt,
+				"error":   err.Error(),
+			})
 		}
-	}()
-
-	return nil
+	}
 }
 
 func (c *TelegramChannel) Stop(ctx context.Context) error {
 	logger.InfoC("telegram", "Stopping Telegram bot...")
+	if c.stopPolling != nil {
+		c.stopPolling()
+	}
 	c.setRunning(false)
 	return nil
 }
@@ -157,10 +249,15 @@ func (c *TelegramChannel) Send(ctx context.Context, msg bus.OutboundMessage) err
 		return c.sendMediaFiles(ctx, chatID, msg.Content, msg.Media)
 	}
 
-	htmlContent := markdownToTelegramHTML(msg.Content)
-
 	// Split message if it exceeds Telegram's limit
 	const telegramMaxLen = 4096
+
+	htmlContent := markdownToTelegramHTML(msg.Content)
+	// Render structured tables as a monospaced block when they fit in one
+	// message; otherwise fall back to the plain-text content.
+	if table, ok := telegramTableHTML(msg); ok && len(table) <= telegramMaxLen {
+		htmlContent = table
+	}
 	chunks := splitLargeMessage(htmlContent, telegramMaxLen)
 
 	// Try to edit placeholder (only for first chunk)
@@ -210,6 +307,9 @@ func (c *TelegramChannel) Send(ctx context.Context, msg bus.OutboundMessage) err
 
 		tgMsg := tu.Message(tu.ID(chatID), chunkContent)
 		tgMsg.ParseMode = telego.ModeHTML
+		if i == 0 {
+			tgMsg.ReplyParameters = c.replyParameters(msg.ReplyToMessageID)
+		}
 
 		sent, err := c.bot.SendMessage(ctx, tgMsg)
 		if err != nil {
@@ -242,15 +342,46 @@ func (c *TelegramChannel) Send(ctx context.Context, msg bus.OutboundMessage) err
 	return nil
 }
 
+// replyParameters quotes messageID when ReplyToMessages is enabled, or
+// returns nil. The reply is still sent if the original message was deleted.
+func (c *TelegramChannel) replyParameters(messageID string) *telego.ReplyParameters {
+	if !c.config.ReplyToMessages || messageID == "" {
+		return nil
+	}
+	id, err := strconv.Atoi(messageID)
+	if err != nil || id <= 0 {
+		return nil
+	}
+	return &telego.ReplyParameters{MessageID: id, AllowSendingWithoutReply: true}
+}
+
 // sendMediaFiles sends local files via Telegram, choosing the appropriate method by extension.
 func (c *TelegramChannel) sendMediaFiles(ctx context.Context, chatID int64, caption string, files []string) error {
 	for i, filePath := range files {
-		f, err := os.Open(filePath)
+		sendPath := filePath
+		ext := strings.ToLower(filepath.Ext(filePath))
+		// Telegram can't show HEIC or BMP as photos: send a JPEG copy, or
+		// the original as a document when it can't be converted.
+		if ext == ".heic" || ext == ".heif" || ext == ".bmp" {
+			if jpegPath, err := utils.TranscodeToJPEG(filePath); err == nil {
+				sendPath, ext = jpegPath, ".jpg"
+			} else {
+				logger.WarnCF("telegram", "Sending image as document: transcoding to JPEG failed", map[string]interface{}{
+					"path":  filePath,
+					"error": err.Error(),
+				})
+			}
+		}
+
+		f, err := os.Open(sendPath)
 		if err != nil {
 			logger.ErrorCF("telegram", "Failed to open file for sending", map[string]interface{}{
 				"path":  filePath,
 				"error": err.Error(),
 			})
+			if sendPath != filePath {
+				os.Remove(sendPath)
+			}
 			continue
 		}
 
@@ -260,8 +391,6 @@ func (c *TelegramChannel) sendMediaFiles(ctx context.Context, chatID int64, capt
 			fileCaption = caption
 		}
 
-		ext := strings.ToLower(filepath.Ext(filePath))
-
 		switch {
 		case ext == ".jpg" || ext == ".jpeg" || ext == ".png" || ext == ".gif" || ext == ".webp":
 			params := tu.Photo(tu.ID(chatID), tu.File(f))
@@ -296,6 +425,9 @@ func (c *TelegramChannel) sendMediaFiles(ctx context.Context, chatID int64, capt
 		}
 
 		f.Close()
+		if sendPath != filePath {
+			os.Remove(sendPath)
+		}
 
 		if err != nil {
 			logger.ErrorCF("telegram", "Failed to send file", map[string]interface{}{
@@ -319,6 +451,16 @@ func (c *TelegramChannel) handleMessage(ctx context.Context, update telego.Updat
 		return
 	}
 
+	// getUpdates occasionally redelivers a message; drop repeats before any
+	// downloads or placeholder messages happen.
+	if c.isDuplicate(message.Chat.ID, message.MessageID) {
+		logger.DebugCF("telegram", "Dropping duplicate message", map[string]interface{}{
+			"chat_id":    message.Chat.ID,
+			"message_id": message.MessageID,
+		})
+		return
+	}
+
 	user := message.From
 	if user == nil {
 		return
@@ -339,6 +481,20 @@ func (c *TelegramChannel) handleMessage(ctx context.Context, update telego.Updat
 		return
 	}
 
+	// In groups, only respond when addressed if triggers are configured.
+	triggerStrip := ""
+	if message.Chat.Type != "private" && c.groupTriggersEnabled() {
+		triggered, strip := c.checkGroupTrigger(message)
+		if !triggered {
+			logger.DebugCF("telegram", "Group message ignored (no trigger)", map[string]interface{}{
+				"chat_id": message.Chat.ID,
+				"user_id": userID,
+			})
+			return
+		}
+		triggerStrip = strip
+	}
+
 	chatID := message.Chat.ID
 	c.chatIDs[senderID] = chatID
 
@@ -369,6 +525,9 @@ func (c *TelegramChannel) handleMessage(ctx context.Context, update telego.Updat
 		}
 		content += message.Caption
 	}
+	if triggerStrip != "" {
+		content = strings.TrimSpace(strings.TrimPrefix(content, triggerStrip))
+	}
 
 	saveAttachment := func(localPath, originalName, mimeType, kind string, persist bool) {
 		info, err := os.Stat(localPath)
@@ -443,6 +602,21 @@ func (c *TelegramChannel) handleMessage(ctx context.Context, update telego.Updat
 		))
 	}
 
+	// addPreview attaches a thumbnail as an image so vision models can see
+	// stickers and GIFs.
+	addPreview := func(thumb *telego.PhotoSize, label string) {
+		if !c.config.AnimationPreviews || thumb == nil {
+			return
+		}
+		previewPath := c.downloadPhoto(ctx, thumb.FileID)
+		if previewPath == "" {
+			return
+		}
+		// Like photos, agent cleanup removes the file after encoding.
+		mediaPaths = append(mediaPaths, previewPath)
+		content += fmt.Sprintf("\n[image: %s]", label)
+	}
+
 	if message.Photo != nil && len(message.Photo) > 0 {
 		photo := message.Photo[len(message.Photo)-1]
 		photoPath := c.downloadPhoto(ctx, photo.FileID)
@@ -476,7 +650,7 @@ func (c *TelegramChannel) handleMessage(ctx context.Context, update telego.Updat
 					})
 					transcribedText = fmt.Sprintf("[voice (transcription failed)]")
 				} else {
-					transcribedText = fmt.Sprintf("[voice transcription: %s]", result.Text)
+					transcribedText = voice.FormatTranscription("voice", result)
 					logger.InfoCF("telegram", "Voice transcribed successfully", map[string]interface{}{
 						"text": result.Text,
 					})
@@ -501,17 +675,45 @@ func (c *TelegramChannel) handleMessage(ctx context.Context, update telego.Updat
 				audioName = fmt.Sprintf("audio_%s.mp3", message.Audio.FileID)
 			}
 			saveAttachment(audioPath, audioName, message.Audio.MimeType, "audio", true)
+			audioMarker := voice.TranscribeAudio(ctx, c.transcriber, "audio", audioPath, audioName, message.Audio.MimeType)
+			if audioMarker == "" {
+				audioMarker = "[audio]"
+			}
 			if content != "" {
 				content += "\n"
 			}
-			content += fmt.Sprintf("[audio]")
+			content += audioMarker
+		}
+	}
+
+	if message.Sticker != nil {
+		if content != "" {
+			content += "\n"
 		}
+		content += stickerMarker(message.Sticker)
+		addPreview(message.Sticker.Thumbnail, "sticker preview")
 	}
 
-	if message.Document != nil {
+	if message.Animation != nil {
+		if content != "" {
+			content += "\n"
+		}
+		content += animationMarker(message.Animation)
+		addPreview(message.Animation.Thumbnail, "gif preview")
+	}
+
+	// Telegram duplicates a GIF as Document for older clients; it is
+	// already described above.
+	if message.Document != nil && message.Animation == nil {
 		docPath := c.downloadFile(ctx, message.Document.FileID, "")
 		if docPath != "" {
-			localFiles = append(localFiles, docPath)
+			// Images sent as files (e.g. HEIC from iPhones) go to vision
+			// like photos; agent cleanup removes them after encoding.
+			if utils.IsImageFile(docPath) {
+				mediaPaths = append(mediaPaths, docPath)
+			} else {
+				localFiles = append(localFiles, docPath)
+			}
 			docName := message.Document.FileName
 			if docName == "" {
 				docName = fmt.Sprintf("document_%s", message.Document.FileID)
@@ -520,7 +722,11 @@ func (c *TelegramChannel) handleMessage(ctx context.Context, update telego.Updat
 			if content != "" {
 				content += "\n"
 			}
-			content += fmt.Sprintf("[file]")
+			content += "[file]"
+			// Audio sent as a file (e.g. a recording shared from another app).
+			if transcribed := voice.TranscribeAudio(ctx, c.transcriber, "audio", docPath, docName, message.Document.MimeType); transcribed != "" {
+				content += "\n" + transcribed
+			}
 		}
 	}
 
@@ -571,7 +777,11 @@ func (c *TelegramChannel) handleMessage(ctx context.Context, update telego.Updat
 	_, thinkCancel := context.WithTimeout(ctx, 5*time.Minute)
 	c.stopThinking.Store(chatIDStr, &thinkingCancel{fn: thinkCancel})
 
-	pMsg, err := c.bot.SendMessage(ctx, tu.Message(tu.ID(chatID), "Thinking... 💭"))
+	// The answer is edited into the placeholder, so the placeholder carries
+	// the reply threading.
+	placeholder := tu.Message(tu.ID(chatID), "Thinking... 💭")
+	placeholder.ReplyParameters = c.replyParameters(fmt.Sprintf("%d", message.MessageID))
+	pMsg, err := c.bot.SendMessage(ctx, placeholder)
 	if err == nil {
 		pID := pMsg.MessageID
 		c.placeholders.Store(chatIDStr, pID)
@@ -584,6 +794,10 @@ func (c *TelegramChannel) handleMessage(ctx context.Context, update telego.Updat
 		"first_name": user.FirstName,
 		"is_group":   fmt.Sprintf("%t", message.Chat.Type != "private"),
 	}
+	if message.Voice != nil {
+		// Lets the agent answer voice with voice (voice.tts).
+		metadata["is_voice"] = "true"
+	}
 	if len(attachmentIDs) > 0 {
 		metadata["attachment_ids"] = strings.Join(attachmentIDs, ",")
 	}
@@ -591,6 +805,124 @@ func (c *TelegramChannel) handleMessage(ctx context.Context, update telego.Updat
 	c.HandleMessage(senderID, fmt.Sprintf("%d", chatID), content, mediaPaths, metadata)
 }
 
+// isDuplicate reports whether the message was already handled. Message IDs
+// are only unique per chat, so entries are keyed by chat and message ID.
+func (c *TelegramChannel) isDuplicate(chatID int64, messageID int) bool {
+	return c.dedup.Seen(fmt.Sprintf("%d:%d", chatID, messageID))
+}
+
+// stickerMarker describes a sticker for the model, which otherwise only
+// sees an empty message.
+func stickerMarker(sticker *telego.Sticker) string {
+	kind := "static"
+	switch {
+	case sticker.IsAnimated:
+		kind = "animated"
+	case sticker.IsVideo:
+		kind = "video"
+	}
+	parts := []string{"[sticker"}
+	if sticker.Emoji != "" {
+		parts = append(parts, "emoji="+sticker.Emoji)
+	}
+	if sticker.SetName != "" {
+		parts = append(parts, "set="+sticker.SetName)
+	}
+	parts = append(parts, "kind="+kind)
+	return strings.Join(parts, " ") + "]"
+}
+
+// animationMarker describes a GIF (sent by Telegram as a silent video).
+func animationMarker(anim *telego.Animation) string {
+	parts := []string{"[gif"}
+	if anim.FileName != "" {
+		parts = append(parts, "name="+utils.SanitizeFilename(anim.FileName))
+	}
+	if anim.Duration > 0 {
+		parts = append(parts, fmt.Sprintf("duration=%ds", anim.Duration))
+	}
+	if anim.Width > 0 && anim.Height > 0 {
+		parts = append(parts, fmt.Sprintf("size=%dx%d", anim.Width, anim.Height))
+	}
+	return strings.Join(parts, " ") + "]"
+}
+
+// groupTriggersEnabled reports whether group messages must address the bot
+// (by mention or a configured prefix) to be handled.
+func (c *TelegramChannel) groupTriggersEnabled() bool {
+	if c.config.RequireMention {
+		return true
+	}
+	for _, prefix := range c.config.GroupTriggerPrefix {
+		if prefix != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// checkGroupTrigger reports whether a group message addresses the bot: it
+// mentions the bot, replies to one of its messages, or starts with a
+// configured prefix. strip is the leading text to remove from the content.
+func (c *TelegramChannel) checkGroupTrigger(message *telego.Message) (triggered bool, strip string) {
+	text, entities := message.Text, message.Entities
+	if text == "" {
+		text, entities = message.Caption, message.CaptionEntities
+	}
+
+	if c.mentionsBot(text, entities) {
+		mention := "@" + c.bot.Username()
+		if len(text) >= len(mention) && strings.EqualFold(text[:len(mention)], mention) {
+			return true, text[:len(mention)]
+		}
+		return true, ""
+	}
+	if reply := message.ReplyToMessage; reply != nil && reply.From != nil && reply.From.IsBot && reply.From.ID == c.bot.ID() {
+		return true, ""
+	}
+
+	for _, prefix := range c.config.GroupTriggerPrefix {
+		if prefix == "" {
+			continue
+		}
+		if strings.HasPrefix(text, prefix) {
+			return true, prefix
+		}
+	}
+	return false, ""
+}
+
+// mentionsBot reports whether entities hold an @mention of the bot's
+// username or a text mention of the bot's user.
+func (c *TelegramChannel) mentionsBot(text string, entities []telego.MessageEntity) bool {
+	username := c.bot.Username()
+	var units []uint16
+	for _, e := range entities {
+		switch e.Type {
+		case "mention":
+			if username == "" {
+				continue
+			}
+			if units == nil {
+				units = utf16.Encode([]rune(text))
+			}
+			// Entity offsets count UTF-16 code units.
+			if e.Offset < 0 || e.Length <= 0 || e.Offset+e.Length > len(units) {
+				continue
+			}
+			mention := string(utf16.Decode(units[e.Offset : e.Offset+e.Length]))
+			if strings.EqualFold(mention, "@"+username) {
+				return true
+			}
+		case "text_mention":
+			if e.User != nil && e.User.ID == c.bot.ID() {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 func (c *TelegramChannel) downloadPhoto(ctx context.Context, fileID string) string {
 	file, err := c.bot.GetFile(ctx, &telego.GetFileParams{FileID: fileID})
 	if err != nil {
@@ -782,6 +1114,52 @@ func extractInlineCodes(text string) inlineCodeMatch {
 	return inlineCodeMatch{text: text, codes: codes}
 }
 
+// telegramTableHTML renders a "table" result as an aligned <pre> block.
+// It reports false when the message carries no usable table metadata.
+func telegramTableHTML(msg bus.OutboundMessage) (string, bool) {
+	if msg.ResultType != "table" {
+		return "", false
+	}
+	columns, _ := msg.Metadata["columns"].([]string)
+	rows, _ := msg.Metadata["rows"].([][]string)
+	if len(columns) == 0 {
+		return "", false
+	}
+
+	widths := make([]int, len(columns))
+	for i, col := range columns {
+		widths[i] = utf8.RuneCountInString(col)
+	}
+	for _, row := range rows {
+		for i := 0; i < len(row) && i < len(columns); i++ {
+			widths[i] = max(widths[i], utf8.RuneCountInString(row[i]))
+		}
+	}
+
+	formatRow := func(cells []string) string {
+		parts := make([]string, len(columns))
+		for i := range columns {
+			cell := ""
+			if i < len(cells) {
+				cell = cells[i]
+			}
+			parts[i] = cell + strings.Repeat(" ", widths[i]-utf8.RuneCountInString(cell))
+		}
+		return strings.TrimRight(strings.Join(parts, "  "), " ")
+	}
+
+	separator := make([]string, len(columns))
+	for i, w := range widths {
+		separator[i] = strings.Repeat("-", w)
+	}
+
+	lines := []string{formatRow(columns), strings.Join(separator, "  ")}
+	for _, row := range rows {
+		lines = append(lines, formatRow(row))
+	}
+	return "<pre>" + escapeHTML(strings.Join(lines, "\n")) + "</pre>", true
+}
+
 func escapeHTML(text string) string {
 	text = strings.ReplaceAll(text, "&", "&amp;")
 	text = strings.ReplaceAll(text, "<", "&lt;")
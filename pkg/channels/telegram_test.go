@@ -0,0 +1,481 @@
+package channels
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mymmrac/telego"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+// stubTelegramAPI answers every Bot API method with a minimal sent message.
+func stubTelegramAPI(w http.ResponseWriter, r *http.Request) {
+	io.Copy(io.Discard, r.Body)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte(`{"ok":true,"result":{"message_id":99,"date":0,"chat":{"id":42,"type":"private"}}}`))
+}
+
+// newTestTelegramChannel returns a channel whose bot talks to a stub Bot API
+// server, so handleMessage can run without network access.
+func newTestTelegramChannel(t *testing.T, cfg config.TelegramConfig) (*TelegramChannel, *bus.MessageBus) {
+	t.Helper()
+	return newTestTelegramChannelWithAPI(t, cfg, stubTelegramAPI)
+}
+
+func newTestTelegramChannelWithAPI(t *testing.T, cfg config.TelegramConfig, handler http.HandlerFunc) (*TelegramChannel, *bus.MessageBus) {
+	t.Helper()
+
+	api := httptest.NewServer(handler)
+	t.Cleanup(api.Close)
+
+	cfg.Token = "123456:ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghi"
+	msgBus := bus.NewMessageBus()
+	ch, err := NewTelegramChannel(cfg, msgBus, t.TempDir())
+	if err != nil {
+		t.Fatalf("NewTelegramChannel() error: %v", err)
+	}
+	ch.bot, err = telego.NewBot(cfg.Token, telego.WithAPIServer(api.URL), telego.WithDiscardLogger())
+	if err != nil {
+		t.Fatalf("telego.NewBot() error: %v", err)
+	}
+	return ch, msgBus
+}
+
+func TestTelegramHandleMessage_DropsRepeatedUpdate(t *testing.T) {
+	ch, msgBus := newTestTelegramChannel(t, config.TelegramConfig{})
+
+	update := telego.Update{
+		UpdateID: 1,
+		Message: &telego.Message{
+			MessageID: 7,
+			From:      &telego.User{ID: 1001, Username: "alice"},
+			Chat:      telego.Chat{ID: 42, Type: "private"},
+			Text:      "hello",
+		},
+	}
+
+	ctx := context.Background()
+	ch.handleMessage(ctx, update)
+	ch.handleMessage(ctx, update) // getUpdates redelivery
+
+	consumeCtx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	if msg, ok := msgBus.ConsumeInbound(consumeCtx); !ok || msg.Content != "hello" {
+		t.Fatalf("first delivery not published: ok=%t msg=%+v", ok, msg)
+	}
+
+	emptyCtx, cancelEmpty := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer cancelEmpty()
+	if msg, ok := msgBus.ConsumeInbound(emptyCtx); ok {
+		t.Fatalf("duplicate delivery was published: %+v", msg)
+	}
+}
+
+func TestTelegramIsDuplicate_KeyedPerChat(t *testing.T) {
+	ch, _ := newTestTelegramChannel(t, config.TelegramConfig{})
+
+	if ch.isDuplicate(1, 7) {
+		t.Fatal("first delivery should not be a duplicate")
+	}
+	if ch.isDuplicate(2, 7) {
+		t.Fatal("same message ID in another chat should not be a duplicate")
+	}
+	if !ch.isDuplicate(1, 7) {
+		t.Fatal("redelivery in the same chat should be a duplicate")
+	}
+}
+
+func TestTelegramIsDuplicate_ExpiresAndEvictsOldestFirst(t *testing.T) {
+	ch, _ := newTestTelegramChannel(t, config.TelegramConfig{DedupTTLSeconds: 60, DedupMaxEntries: 3})
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	ch.dedup.now = func() time.Time { return now }
+
+	for id := 1; id <= 4; id++ {
+		ch.isDuplicate(42, id)
+		now = now.Add(time.Second)
+	}
+
+	ch.dedup.mu.Lock()
+	size := len(ch.dedup.seen)
+	_, hasFirst := ch.dedup.seen["42:1"]
+	ch.dedup.mu.Unlock()
+	if size != 3 || hasFirst {
+		t.Fatalf("size = %d, oldest retained = %t; want 3 entries without the oldest", size, hasFirst)
+	}
+
+	now = now.Add(2 * time.Minute)
+	if ch.isDuplicate(42, 4) {
+		t.Fatal("expired ID should be admitted again")
+	}
+}
+
+func TestTelegramPollUpdates_ReconnectsAfterChannelCloses(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		calls   int
+		offsets []int
+	)
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/getUpdates") {
+			stubTelegramAPI(w, r)
+			return
+		}
+		var params struct {
+			Offset int `json:"offset"`
+		}
+		json.NewDecoder(r.Body).Decode(&params)
+
+		mu.Lock()
+		calls++
+		call := calls
+		offsets = append(offsets, params.Offset)
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		switch call {
+		case 1, 3:
+			// Deliver one message per healthy connection.
+			w.Write([]byte(fmt.Sprintf(`{"ok":true,"result":[{"update_id":%d,"message":{"message_id":%d,"date":0,"from":{"id":1001,"is_bot":false,"first_name":"A"},"chat":{"id":42,"type":"private"},"text":"msg %d"}}]}`, call, call, call)))
+		case 2:
+			// A failed getUpdates closes the updates channel.
+			w.WriteHeader(http.StatusBadGateway)
+			w.Write([]byte(`{"ok":false,"error_code":502,"description":"Bad Gateway"}`))
+		default:
+			time.Sleep(20 * time.Millisecond)
+			w.Write([]byte(`{"ok":true,"result":[]}`))
+		}
+	}
+
+	ch, msgBus := newTestTelegramChannelWithAPI(t, config.TelegramConfig{}, handler)
+	ch.reconnectMinBackoff = 10 * time.Millisecond
+	ch.reconnectMaxBackoff = 40 * time.Millisecond
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := ch.Start(ctx); err != nil {
+		t.Fatalf("Start() error: %v", err)
+	}
+	defer ch.Stop(context.Background())
+
+	for _, want := range []string{"msg 1", "msg 3"} {
+		msg, ok := msgBus.ConsumeInbound(ctx)
+		if !ok || msg.Content != want {
+			t.Fatalf("inbound = %q (ok=%t), want %q", msg.Content, ok, want)
+		}
+	}
+	if !ch.IsRunning() {
+		t.Fatal("channel should stay running across reconnects")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	// The reconnect resumes after the last delivered update.
+	if len(offsets) < 3 || offsets[2] != 2 {
+		t.Fatalf("getUpdates offsets = %v, want reconnect to resume at 2", offsets)
+	}
+}
+
+func TestTelegramTableHTML(t *testing.T) {
+	msg := bus.OutboundMessage{
+		Content:    "gpt-4o: 1200, claude: 80",
+		ResultType: "table",
+		Metadata: map[string]interface{}{
+			"columns": []string{"model", "tokens"},
+			"rows":    [][]string{{"gpt-4o", "1200"}, {"<claude>", "80"}},
+		},
+	}
+
+	got, ok := telegramTableHTML(msg)
+	if !ok {
+		t.Fatal("expected table metadata to render")
+	}
+	want := "<pre>model     tokens\n--------  ------\ngpt-4o    1200\n&lt;claude&gt;  80</pre>"
+	if got != want {
+		t.Fatalf("table =\n%s\nwant\n%s", got, want)
+	}
+
+	for _, fallback := range []bus.OutboundMessage{
+		{Content: "plain"},
+		{Content: "plain", ResultType: "table"},
+		{Content: "plain", ResultType: "image", Metadata: msg.Metadata},
+	} {
+		if _, ok := telegramTableHTML(fallback); ok {
+			t.Fatalf("%+v should fall back to plain text", fallback)
+		}
+	}
+}
+
+// stubTelegramBotAPI is stubTelegramAPI with getMe answering as @pico_bot.
+func stubTelegramBotAPI(w http.ResponseWriter, r *http.Request) {
+	if strings.HasSuffix(r.URL.Path, "/getMe") {
+		io.Copy(io.Discard, r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"ok":true,"result":{"id":555,"is_bot":true,"first_name":"Pico","username":"pico_bot"}}`))
+		return
+	}
+	stubTelegramAPI(w, r)
+}
+
+func TestTelegramHandleMessage_GroupTriggers(t *testing.T) {
+	groupMsg := func(id int, text string, entities ...telego.MessageEntity) telego.Update {
+		return telego.Update{Message: &telego.Message{
+			MessageID: id,
+			From:      &telego.User{ID: 1001, Username: "alice"},
+			Chat:      telego.Chat{ID: -100, Type: "supergroup"},
+			Text:      text,
+			Entities:  entities,
+		}}
+	}
+
+	tests := []struct {
+		name   string
+		cfg    config.TelegramConfig
+		update telego.Update
+		want   string // "" means the message is ignored
+	}{
+		{
+			name:   "no triggers configured",
+			cfg:    config.TelegramConfig{},
+			update: groupMsg(1, "hello everyone"),
+			want:   "hello everyone",
+		},
+		{
+			name:   "mention required but missing",
+			cfg:    config.TelegramConfig{RequireMention: true},
+			update: groupMsg(2, "hello everyone"),
+		},
+		{
+			name:   "mention strips leading username",
+			cfg:    config.TelegramConfig{RequireMention: true},
+			update: groupMsg(3, "@Pico_Bot what time is it", telego.MessageEntity{Type: "mention", Offset: 0, Length: 9}),
+			want:   "what time is it",
+		},
+		{
+			name:   "mention after emoji uses UTF-16 offsets",
+			cfg:    config.TelegramConfig{RequireMention: true},
+			update: groupMsg(4, "👋 @pico_bot hi", telego.MessageEntity{Type: "mention", Offset: 3, Length: 9}),
+			want:   "👋 @pico_bot hi",
+		},
+		{
+			name:   "mention of another user",
+			cfg:    config.TelegramConfig{RequireMention: true},
+			update: groupMsg(5, "@bob hi", telego.MessageEntity{Type: "mention", Offset: 0, Length: 4}),
+		},
+		{
+			name:   "prefix strips",
+			cfg:    config.TelegramConfig{GroupTriggerPrefix: []string{"/ask"}},
+			update: groupMsg(6, "/ask what time is it"),
+			want:   "what time is it",
+		},
+		{
+			name:   "prefix missing",
+			cfg:    config.TelegramConfig{GroupTriggerPrefix: []string{"/ask"}},
+			update: groupMsg(7, "what time is it"),
+		},
+		{
+			name: "reply to the bot",
+			cfg:  config.TelegramConfig{RequireMention: true},
+			update: func() telego.Update {
+				u := groupMsg(8, "and tomorrow?")
+				u.Message.ReplyToMessage = &telego.Message{
+					MessageID: 5,
+					From:      &telego.User{ID: 555, IsBot: true, Username: "pico_bot"},
+					Chat:      telego.Chat{ID: -100, Type: "supergroup"},
+					Text:      "It is noon.",
+				}
+				return u
+			}(),
+			want: "and tomorrow?",
+		},
+		{
+			name: "private chat is not gated",
+			cfg:  config.TelegramConfig{RequireMention: true, GroupTriggerPrefix: []string{"/ask"}},
+			update: telego.Update{Message: &telego.Message{
+				MessageID: 9,
+				From:      &telego.User{ID: 1001, Username: "alice"},
+				Chat:      telego.Chat{ID: 42, Type: "private"},
+				Text:      "hello",
+			}},
+			want: "hello",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ch, msgBus := newTestTelegramChannelWithAPI(t, tt.cfg, stubTelegramBotAPI)
+			ctx := context.Background()
+			ch.handleMessage(ctx, tt.update)
+
+			wait := time.Second
+			if tt.want == "" {
+				wait = 100 * time.Millisecond
+			}
+			consumeCtx, cancel := context.WithTimeout(ctx, wait)
+			defer cancel()
+			msg, ok := msgBus.ConsumeInbound(consumeCtx)
+			if tt.want == "" {
+				if ok {
+					t.Fatalf("message should be ignored, got %+v", msg)
+				}
+				return
+			}
+			if !ok {
+				t.Fatal("message was not published")
+			}
+			if !strings.HasPrefix(msg.Content, tt.want) {
+				t.Errorf("Content = %q, want prefix %q", msg.Content, tt.want)
+			}
+		})
+	}
+}
+
+func TestTelegramReplyToMessages(t *testing.T) {
+	type replyParams struct {
+		MessageID                int  `json:"message_id"`
+		AllowSendingWithoutReply bool `json:"allow_sending_without_reply"`
+	}
+	type sendParams struct {
+		Text            string       `json:"text"`
+		ReplyParameters *replyParams `json:"reply_parameters"`
+	}
+
+	newChannel := func(t *testing.T, enabled bool) (*TelegramChannel, func() []sendParams) {
+		var (
+			mu   sync.Mutex
+			sent []sendParams
+		)
+		handler := func(w http.ResponseWriter, r *http.Request) {
+			if strings.HasSuffix(r.URL.Path, "/sendMessage") {
+				var p sendParams
+				json.NewDecoder(r.Body).Decode(&p)
+				mu.Lock()
+				sent = append(sent, p)
+				mu.Unlock()
+			}
+			stubTelegramAPI(w, r)
+		}
+		ch, _ := newTestTelegramChannelWithAPI(t, config.TelegramConfig{ReplyToMessages: enabled}, handler)
+		return ch, func() []sendParams {
+			mu.Lock()
+			defer mu.Unlock()
+			return append([]sendParams(nil), sent...)
+		}
+	}
+
+	t.Run("placeholder quotes the inbound message", func(t *testing.T) {
+		ch, sent := newChannel(t, true)
+		ch.handleMessage(context.Background(), telego.Update{Message: &telego.Message{
+			MessageID: 7,
+			From:      &telego.User{ID: 1001, Username: "alice"},
+			Chat:      telego.Chat{ID: 42, Type: "group"},
+			Text:      "hello",
+		}})
+
+		got := sent()
+		if len(got) != 1 || got[0].ReplyParameters == nil {
+			t.Fatalf("placeholder sendMessage = %+v, want reply parameters", got)
+		}
+		if rp := got[0].ReplyParameters; rp.MessageID != 7 || !rp.AllowSendingWithoutReply {
+			t.Errorf("reply parameters = %+v, want message 7 allowing send without reply", *rp)
+		}
+	})
+
+	t.Run("answer quotes the inbound message", func(t *testing.T) {
+		ch, sent := newChannel(t, true)
+		ch.setRunning(true)
+		err := ch.Send(context.Background(), bus.OutboundMessage{Channel: "telegram", ChatID: "42", Content: "hi", ReplyToMessageID: "7"})
+		if err != nil {
+			t.Fatalf("Send() error: %v", err)
+		}
+
+		got := sent()
+		if len(got) != 1 || got[0].ReplyParameters == nil || got[0].ReplyParameters.MessageID != 7 {
+			t.Fatalf("sendMessage = %+v, want reply to message 7", got)
+		}
+	})
+
+	t.Run("disabled by default", func(t *testing.T) {
+		ch, sent := newChannel(t, false)
+		ch.setRunning(true)
+		err := ch.Send(context.Background(), bus.OutboundMessage{Channel: "telegram", ChatID: "42", Content: "hi", ReplyToMessageID: "7"})
+		if err != nil {
+			t.Fatalf("Send() error: %v", err)
+		}
+
+		if got := sent(); len(got) != 1 || got[0].ReplyParameters != nil {
+			t.Fatalf("sendMessage = %+v, want no reply parameters", got)
+		}
+	})
+}
+
+func TestTelegramStickerAndAnimationMarkers(t *testing.T) {
+	tests := []struct {
+		name string
+		got  string
+		want string
+	}{
+		{
+			name: "static sticker",
+			got:  stickerMarker(&telego.Sticker{Emoji: "😂", SetName: "HappyCats"}),
+			want: "[sticker emoji=😂 set=HappyCats kind=static]",
+		},
+		{
+			name: "animated sticker without set",
+			got:  stickerMarker(&telego.Sticker{Emoji: "👍", IsAnimated: true}),
+			want: "[sticker emoji=👍 kind=animated]",
+		},
+		{
+			name: "video sticker",
+			got:  stickerMarker(&telego.Sticker{SetName: "Dance", IsVideo: true}),
+			want: "[sticker set=Dance kind=video]",
+		},
+		{
+			name: "gif",
+			got:  animationMarker(&telego.Animation{FileName: "cat.mp4", Duration: 3, Width: 320, Height: 240}),
+			want: "[gif name=cat.mp4 duration=3s size=320x240]",
+		},
+		{
+			name: "gif without metadata",
+			got:  animationMarker(&telego.Animation{}),
+			want: "[gif]",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.got != tt.want {
+				t.Errorf("marker = %q, want %q", tt.got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTelegramHandleMessage_Sticker(t *testing.T) {
+	ch, msgBus := newTestTelegramChannel(t, config.TelegramConfig{})
+
+	ch.handleMessage(context.Background(), telego.Update{Message: &telego.Message{
+		MessageID: 11,
+		From:      &telego.User{ID: 1001, Username: "alice"},
+		Chat:      telego.Chat{ID: 42, Type: "private"},
+		Sticker:   &telego.Sticker{Emoji: "🔥", SetName: "Fire"},
+	}})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	msg, ok := msgBus.ConsumeInbound(ctx)
+	if !ok {
+		t.Fatal("sticker message was not published")
+	}
+	if msg.Content != "[sticker emoji=🔥 set=Fire kind=static]" {
+		t.Errorf("Content = %q", msg.Content)
+	}
+}
@@ -5,8 +5,10 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/caarlos0/env/v11"
 )
@@ -45,34 +47,78 @@ func (f *FlexibleStringSlice) UnmarshalJSON(data []byte) error {
 }
 
 type Config struct {
-	Agents     AgentsConfig     `json:"agents"`
-	Channels   ChannelsConfig   `json:"channels"`
-	Providers  ProvidersConfig  `json:"providers"`
-	Gateway    GatewayConfig    `json:"gateway"`
-	Tools      ToolsConfig      `json:"tools"`
-	Heartbeat  HeartbeatConfig  `json:"heartbeat"`
-	Devices    DevicesConfig    `json:"devices"`
-	Logging    LoggingConfig    `json:"logging"`
-	Visibility VisibilityConfig `json:"visibility"`
-	mu         sync.RWMutex
+	Agents      AgentsConfig      `json:"agents"`
+	Channels    ChannelsConfig    `json:"channels"`
+	Providers   ProvidersConfig   `json:"providers"`
+	Gateway     GatewayConfig     `json:"gateway"`
+	Tools       ToolsConfig       `json:"tools"`
+	Heartbeat   HeartbeatConfig   `json:"heartbeat"`
+	Devices     DevicesConfig     `json:"devices"`
+	Logging     LoggingConfig     `json:"logging"`
+	Visibility  VisibilityConfig  `json:"visibility"`
+	Usage       UsageConfig       `json:"usage"`
+	Voice       VoiceConfig       `json:"voice"`
+	Attachments AttachmentsConfig `json:"attachments"`
+	Timezone    string            `json:"timezone,omitempty" env:"PICOCLAW_TIMEZONE"` // IANA name; "" = system local
+	mu          sync.RWMutex
+}
+
+// VoiceConfig controls voice message transcription.
+type VoiceConfig struct {
+	// LanguageRouting transcribes in two passes: the first detects the
+	// language, the second uses the Languages entry for it, if any.
+	LanguageRouting bool `json:"language_routing" env:"PICOCLAW_VOICE_LANGUAGE_ROUTING"`
+	// Languages is keyed by ISO 639-1 code ("de") or Whisper language
+	// name ("german").
+	Languages map[string]VoiceLanguageConfig `json:"languages"`
+	// TTS answers voice messages with a spoken reply as well as text.
+	TTS VoiceTTSConfig `json:"tts"`
+}
+
+// VoiceTTSConfig configures speech synthesis through an OpenAI-compatible
+// /audio/speech endpoint. Users can turn spoken replies off with /voice off.
+type VoiceTTSConfig struct {
+	Enabled bool   `json:"enabled" env:"PICOCLAW_VOICE_TTS_ENABLED"`
+	APIBase string `json:"api_base" env:"PICOCLAW_VOICE_TTS_API_BASE"`
+	APIKey  string `json:"api_key" env:"PICOCLAW_VOICE_TTS_API_KEY"` // "" = providers.openai.api_key
+	Model   string `json:"model" env:"PICOCLAW_VOICE_TTS_MODEL"`
+	Voice   string `json:"voice" env:"PICOCLAW_VOICE_TTS_VOICE"`
+	Format  string `json:"format" env:"PICOCLAW_VOICE_TTS_FORMAT"` // "opus" (voice note) or "mp3"
+	// MaxChars skips speech for longer replies, which stay text-only.
+	MaxChars int `json:"max_chars" env:"PICOCLAW_VOICE_TTS_MAX_CHARS"`
+}
+
+// VoiceLanguageConfig holds the transcription settings for one language.
+type VoiceLanguageConfig struct {
+	Model  string `json:"model,omitempty"`
+	Prompt string `json:"prompt,omitempty"`
 }
 
 type AgentsConfig struct {
 	Defaults AgentDefaults `json:"defaults"`
 	Failover AgentFailover `json:"failover"`
 	Planner  AgentPlanner  `json:"planner"`
+	Memory   AgentMemory   `json:"memory"`
 }
 
 type AgentDefaults struct {
-	Workspace           string   `json:"workspace" env:"PICOCLAW_AGENTS_DEFAULTS_WORKSPACE"`
-	RestrictToWorkspace bool     `json:"restrict_to_workspace" env:"PICOCLAW_AGENTS_DEFAULTS_RESTRICT_TO_WORKSPACE"`
-	Provider            string   `json:"provider" env:"PICOCLAW_AGENTS_DEFAULTS_PROVIDER"`
-	Model               string   `json:"model" env:"PICOCLAW_AGENTS_DEFAULTS_MODEL"`
-	MaxTokens           int      `json:"max_tokens" env:"PICOCLAW_AGENTS_DEFAULTS_MAX_TOKENS"`
-	Temperature         float64  `json:"temperature" env:"PICOCLAW_AGENTS_DEFAULTS_TEMPERATURE"`
-	MaxToolIterations   int      `json:"max_tool_iterations" env:"PICOCLAW_AGENTS_DEFAULTS_MAX_TOOL_ITERATIONS"`
-	FallbackModel       string   `json:"fallback_model" env:"PICOCLAW_AGENTS_DEFAULTS_FALLBACK_MODEL"`
-	FallbackModels      []string `json:"fallback_models" env:"PICOCLAW_AGENTS_DEFAULTS_FALLBACK_MODELS"`
+	Workspace             string   `json:"workspace" env:"PICOCLAW_AGENTS_DEFAULTS_WORKSPACE"`
+	RestrictToWorkspace   bool     `json:"restrict_to_workspace" env:"PICOCLAW_AGENTS_DEFAULTS_RESTRICT_TO_WORKSPACE"`
+	Provider              string   `json:"provider" env:"PICOCLAW_AGENTS_DEFAULTS_PROVIDER"`
+	Model                 string   `json:"model" env:"PICOCLAW_AGENTS_DEFAULTS_MODEL"`
+	MaxTokens             int      `json:"max_tokens" env:"PICOCLAW_AGENTS_DEFAULTS_MAX_TOKENS"`
+	Temperature           float64  `json:"temperature" env:"PICOCLAW_AGENTS_DEFAULTS_TEMPERATURE"`
+	MaxToolIterations     int      `json:"max_tool_iterations" env:"PICOCLAW_AGENTS_DEFAULTS_MAX_TOOL_ITERATIONS"`
+	FallbackModel         string   `json:"fallback_model" env:"PICOCLAW_AGENTS_DEFAULTS_FALLBACK_MODEL"`
+	FallbackModels        []string `json:"fallback_models" env:"PICOCLAW_AGENTS_DEFAULTS_FALLBACK_MODELS"`                       // an entry "a,b" tries b when a is rate limited before moving on
+	SystemPromptBudget    int      `json:"system_prompt_token_budget" env:"PICOCLAW_AGENTS_DEFAULTS_SYSTEM_PROMPT_TOKEN_BUDGET"` // 0 = unlimited
+	AssistantName         string   `json:"assistant_name,omitempty" env:"PICOCLAW_AGENTS_DEFAULTS_ASSISTANT_NAME"`               // "" = picoclaw
+	RequestTimeoutSeconds int      `json:"request_timeout_seconds" env:"PICOCLAW_AGENTS_DEFAULTS_REQUEST_TIMEOUT_SECONDS"`       // 0 = no limit
+	ContentFilterRetry    bool     `json:"content_filter_retry" env:"PICOCLAW_AGENTS_DEFAULTS_CONTENT_FILTER_RETRY"`             // retry once with a rephrase hint after a refusal
+	SessionToolHistory    string   `json:"session_tool_history" env:"PICOCLAW_AGENTS_DEFAULTS_SESSION_TOOL_HISTORY"`             // "full" keeps tool calls/results in sessions, "compact" keeps only replies
+	ToolResultMaxTurns    int      `json:"tool_result_max_turns" env:"PICOCLAW_AGENTS_DEFAULTS_TOOL_RESULT_MAX_TURNS"`           // elide tool results older than this many turns; 0 = keep all
+	SystemPromptFile      string   `json:"system_prompt_file,omitempty" env:"PICOCLAW_AGENTS_DEFAULTS_SYSTEM_PROMPT_FILE"`       // replaces the built-in identity; relative to the workspace
+	BootstrapFiles        []string `json:"bootstrap_files,omitempty" env:"PICOCLAW_AGENTS_DEFAULTS_BOOTSTRAP_FILES"`             // persona files loaded after AGENTS.md, SOUL.md, USER.md, IDENTITY.md
 }
 
 type AgentFailover struct {
@@ -86,11 +132,48 @@ type AgentFailover struct {
 	SwitchbackRequiresApproval   bool `json:"switchback_requires_approval" env:"PICOCLAW_AGENTS_FAILOVER_SWITCHBACK_REQUIRES_APPROVAL"`
 	SwitchbackPromptCooldownMins int  `json:"switchback_prompt_cooldown_minutes" env:"PICOCLAW_AGENTS_FAILOVER_SWITCHBACK_PROMPT_COOLDOWN_MINUTES"`
 	SwitchbackPromptTimeoutMins  int  `json:"switchback_prompt_timeout_minutes" env:"PICOCLAW_AGENTS_FAILOVER_SWITCHBACK_PROMPT_TIMEOUT_MINUTES"`
+	ProbeLockStaleSeconds        int  `json:"probe_lock_stale_seconds" env:"PICOCLAW_AGENTS_FAILOVER_PROBE_LOCK_STALE_SECONDS"` // Cross-process probe lock is taken over after this long
+	// ProbeMode is "chat" (default) to send a tiny chat request, or "http" to
+	// only list the provider's models, which spends no tokens. Providers
+	// without a models endpoint fall back to the chat probe.
+	ProbeMode      string `json:"probe_mode,omitempty" env:"PICOCLAW_AGENTS_FAILOVER_PROBE_MODE"`
+	ProbeModel     string `json:"probe_model,omitempty" env:"PICOCLAW_AGENTS_FAILOVER_PROBE_MODEL"`           // Cheap model probed instead of the primary ("" = primary)
+	ProbePrompt    string `json:"probe_prompt,omitempty" env:"PICOCLAW_AGENTS_FAILOVER_PROBE_PROMPT"`         // "" = "health_check: reply with OK"
+	ProbeMaxTokens int    `json:"probe_max_tokens,omitempty" env:"PICOCLAW_AGENTS_FAILOVER_PROBE_MAX_TOKENS"` // 0 = 8
+	// A model rate limited BreakerThreshold times in a row within
+	// BreakerWindowSeconds is skipped for BreakerCooldownMinutes.
+	BreakerThreshold       int `json:"breaker_threshold" env:"PICOCLAW_AGENTS_FAILOVER_BREAKER_THRESHOLD"` // 0 = no circuit breaker
+	BreakerWindowSeconds   int `json:"breaker_window_seconds" env:"PICOCLAW_AGENTS_FAILOVER_BREAKER_WINDOW_SECONDS"`
+	BreakerCooldownMinutes int `json:"breaker_cooldown_minutes" env:"PICOCLAW_AGENTS_FAILOVER_BREAKER_COOLDOWN_MINUTES"`
 }
 
 type AgentPlanner struct {
 	Enabled bool   `json:"enabled" env:"PICOCLAW_AGENTS_PLANNER_ENABLED"`
 	Model   string `json:"model" env:"PICOCLAW_AGENTS_PLANNER_MODEL"`
+	// PlanDelivery decides where execution plans and plan updates go:
+	// "chat" (the triggering chat, default), "admin" (AdminChannel and
+	// AdminChatID) or "log" (logs only).
+	PlanDelivery string `json:"plan_delivery" env:"PICOCLAW_AGENTS_PLANNER_PLAN_DELIVERY"`
+	AdminChannel string `json:"admin_channel" env:"PICOCLAW_AGENTS_PLANNER_ADMIN_CHANNEL"`
+	AdminChatID  string `json:"admin_chat_id" env:"PICOCLAW_AGENTS_PLANNER_ADMIN_CHAT_ID"`
+	// AnnouncePlan turns plan+execute mode on: the first tool-call batch of
+	// a turn becomes a published plan with a plan file and a progress
+	// message. MinToolCalls is the smallest first batch that gets a plan
+	// (0 or 1 = every turn that uses tools).
+	AnnouncePlan bool `json:"announce_plan" env:"PICOCLAW_AGENTS_PLANNER_ANNOUNCE_PLAN"`
+	MinToolCalls int  `json:"min_tool_calls" env:"PICOCLAW_AGENTS_PLANNER_MIN_TOOL_CALLS"`
+}
+
+// AgentMemory configures semantic memory: memories are embedded and only
+// those relevant to the current message are injected into the prompt.
+// Without an embedding model the memory files are injected whole.
+type AgentMemory struct {
+	EmbeddingModel string  `json:"embedding_model" env:"PICOCLAW_AGENTS_MEMORY_EMBEDDING_MODEL"` // "" = semantic memory off
+	APIBase        string  `json:"api_base" env:"PICOCLAW_AGENTS_MEMORY_API_BASE"`               // OpenAI-compatible; "" = OpenAI
+	APIKey         string  `json:"api_key" env:"PICOCLAW_AGENTS_MEMORY_API_KEY"`                 // "" = providers.openai.api_key
+	TopK           int     `json:"top_k" env:"PICOCLAW_AGENTS_MEMORY_TOP_K"`
+	MinScore       float64 `json:"min_score" env:"PICOCLAW_AGENTS_MEMORY_MIN_SCORE"`
+	RememberTurns  bool    `json:"remember_turns" env:"PICOCLAW_AGENTS_MEMORY_REMEMBER_TURNS"` // also remember what users say, not only the memory files
 }
 
 type ChannelsConfig struct {
@@ -104,6 +187,14 @@ type ChannelsConfig struct {
 	Slack    SlackConfig    `json:"slack"`
 	LINE     LINEConfig     `json:"line"`
 	OneBot   OneBotConfig   `json:"onebot"`
+	Outbound OutboundConfig `json:"outbound"`
+	OwnerID  string         `json:"owner_id" env:"PICOCLAW_CHANNELS_OWNER_ID"` // Sender ID that may always manage allowlists via /allow
+}
+
+// OutboundConfig rate-limits outbound messages per chat across all channels.
+type OutboundConfig struct {
+	MessagesPerSecond float64 `json:"messages_per_second" env:"PICOCLAW_CHANNELS_OUTBOUND_MESSAGES_PER_SECOND"` // 0 = unlimited
+	Burst             int     `json:"burst" env:"PICOCLAW_CHANNELS_OUTBOUND_BURST"`
 }
 
 type WhatsAppConfig struct {
@@ -113,10 +204,21 @@ type WhatsAppConfig struct {
 }
 
 type TelegramConfig struct {
-	Enabled   bool                `json:"enabled" env:"PICOCLAW_CHANNELS_TELEGRAM_ENABLED"`
-	Token     string              `json:"token" env:"PICOCLAW_CHANNELS_TELEGRAM_TOKEN"`
-	Proxy     string              `json:"proxy" env:"PICOCLAW_CHANNELS_TELEGRAM_PROXY"`
-	AllowFrom FlexibleStringSlice `json:"allow_from" env:"PICOCLAW_CHANNELS_TELEGRAM_ALLOW_FROM"`
+	Enabled         bool                `json:"enabled" env:"PICOCLAW_CHANNELS_TELEGRAM_ENABLED"`
+	Token           string              `json:"token" env:"PICOCLAW_CHANNELS_TELEGRAM_TOKEN"`
+	Proxy           string              `json:"proxy" env:"PICOCLAW_CHANNELS_TELEGRAM_PROXY"`
+	AllowFrom       FlexibleStringSlice `json:"allow_from" env:"PICOCLAW_CHANNELS_TELEGRAM_ALLOW_FROM"`
+	DedupTTLSeconds int                 `json:"dedup_ttl_seconds" env:"PICOCLAW_CHANNELS_TELEGRAM_DEDUP_TTL_SECONDS"`
+	DedupMaxEntries int                 `json:"dedup_max_entries" env:"PICOCLAW_CHANNELS_TELEGRAM_DEDUP_MAX_ENTRIES"`
+	// In group chats, only messages that mention the bot, reply to it, or
+	// start with one of GroupTriggerPrefix are handled when either is set.
+	GroupTriggerPrefix []string `json:"group_trigger_prefix" env:"PICOCLAW_CHANNELS_TELEGRAM_GROUP_TRIGGER_PREFIX"`
+	RequireMention     bool     `json:"require_mention" env:"PICOCLAW_CHANNELS_TELEGRAM_REQUIRE_MENTION"`
+	// ReplyToMessages quotes the triggering message in the bot's answer.
+	ReplyToMessages bool `json:"reply_to_messages" env:"PICOCLAW_CHANNELS_TELEGRAM_REPLY_TO_MESSAGES"`
+	// AnimationPreviews attaches the thumbnail of stickers and GIFs as an
+	// image for vision models.
+	AnimationPreviews bool `json:"animation_previews" env:"PICOCLAW_CHANNELS_TELEGRAM_ANIMATION_PREVIEWS"`
 }
 
 type FeishuConfig struct {
@@ -142,10 +244,12 @@ type MaixCamConfig struct {
 }
 
 type QQConfig struct {
-	Enabled   bool                `json:"enabled" env:"PICOCLAW_CHANNELS_QQ_ENABLED"`
-	AppID     string              `json:"app_id" env:"PICOCLAW_CHANNELS_QQ_APP_ID"`
-	AppSecret string              `json:"app_secret" env:"PICOCLAW_CHANNELS_QQ_APP_SECRET"`
-	AllowFrom FlexibleStringSlice `json:"allow_from" env:"PICOCLAW_CHANNELS_QQ_ALLOW_FROM"`
+	Enabled         bool                `json:"enabled" env:"PICOCLAW_CHANNELS_QQ_ENABLED"`
+	AppID           string              `json:"app_id" env:"PICOCLAW_CHANNELS_QQ_APP_ID"`
+	AppSecret       string              `json:"app_secret" env:"PICOCLAW_CHANNELS_QQ_APP_SECRET"`
+	AllowFrom       FlexibleStringSlice `json:"allow_from" env:"PICOCLAW_CHANNELS_QQ_ALLOW_FROM"`
+	DedupTTLSeconds int                 `json:"dedup_ttl_seconds" env:"PICOCLAW_CHANNELS_QQ_DEDUP_TTL_SECONDS"`
+	DedupMaxEntries int                 `json:"dedup_max_entries" env:"PICOCLAW_CHANNELS_QQ_DEDUP_MAX_ENTRIES"`
 }
 
 type DingTalkConfig struct {
@@ -182,21 +286,80 @@ type OneBotConfig struct {
 }
 
 type HeartbeatConfig struct {
-	Enabled  bool `json:"enabled" env:"PICOCLAW_HEARTBEAT_ENABLED"`
-	Interval int  `json:"interval" env:"PICOCLAW_HEARTBEAT_INTERVAL"` // minutes, min 5
+	Enabled    bool                `json:"enabled" env:"PICOCLAW_HEARTBEAT_ENABLED"`
+	Interval   int                 `json:"interval" env:"PICOCLAW_HEARTBEAT_INTERVAL"` // minutes, min 5
+	Schedule   string              `json:"schedule,omitempty" env:"PICOCLAW_HEARTBEAT_SCHEDULE"`
+	Schedules  []HeartbeatSchedule `json:"schedules,omitempty"`
+	Prompts    []string            `json:"prompts,omitempty" env:"PICOCLAW_HEARTBEAT_PROMPTS"`
+	PromptMode string              `json:"prompt_mode,omitempty" env:"PICOCLAW_HEARTBEAT_PROMPT_MODE"` // rotate|all
+	Weather    HeartbeatWeather    `json:"weather"`
+}
+
+// HeartbeatWeather adds current weather to heartbeat prompts. An empty
+// location lets the weather service geolocate the device.
+type HeartbeatWeather struct {
+	Enabled  bool   `json:"enabled" env:"PICOCLAW_HEARTBEAT_WEATHER_ENABLED"`
+	Location string `json:"location,omitempty" env:"PICOCLAW_HEARTBEAT_WEATHER_LOCATION"`
+}
+
+// HeartbeatSchedule is a named cron expression (e.g. "0 9 * * *") with an
+// optional prompt. An empty prompt falls back to HEARTBEAT.md.
+type HeartbeatSchedule struct {
+	Name     string `json:"name"`
+	Schedule string `json:"schedule"`
+	Prompt   string `json:"prompt,omitempty"`
 }
 
 type DevicesConfig struct {
-	Enabled    bool `json:"enabled" env:"PICOCLAW_DEVICES_ENABLED"`
-	MonitorUSB bool `json:"monitor_usb" env:"PICOCLAW_DEVICES_MONITOR_USB"`
+	Enabled    bool            `json:"enabled" env:"PICOCLAW_DEVICES_ENABLED"`
+	MonitorUSB bool            `json:"monitor_usb" env:"PICOCLAW_DEVICES_MONITOR_USB"`
+	Telephony  TelephonyConfig `json:"telephony"`
+}
+
+// TelephonyConfig controls phone integrations available under Termux.
+type TelephonyConfig struct {
+	SMSWatch               bool `json:"sms_watch" env:"PICOCLAW_DEVICES_TELEPHONY_SMS_WATCH"`
+	SMSPollIntervalSeconds int  `json:"sms_poll_interval_seconds" env:"PICOCLAW_DEVICES_TELEPHONY_SMS_POLL_INTERVAL_SECONDS"`
+	SMSDebounceSeconds     int  `json:"sms_debounce_seconds" env:"PICOCLAW_DEVICES_TELEPHONY_SMS_DEBOUNCE_SECONDS"`
 }
 
 type LoggingConfig struct {
-	FileEnabled     bool   `json:"file_enabled" env:"PICOCLAW_LOGGING_FILE_ENABLED"`
-	FilePath        string `json:"file_path" env:"PICOCLAW_LOGGING_FILE_PATH"`
-	RotationEnabled bool   `json:"rotation_enabled" env:"PICOCLAW_LOGGING_ROTATION_ENABLED"`
-	MaxAgeDays      int    `json:"max_age_days" env:"PICOCLAW_LOGGING_MAX_AGE_DAYS"`
-	MaxSizeMB       int    `json:"max_size_mb" env:"PICOCLAW_LOGGING_MAX_SIZE_MB"`
+	Level           string            `json:"level" env:"PICOCLAW_LOGGING_LEVEL"` // debug, info, warn or error
+	ComponentLevels map[string]string `json:"component_levels"`                   // Per-component overrides, e.g. {"telegram": "debug"}
+	FileEnabled     bool              `json:"file_enabled" env:"PICOCLAW_LOGGING_FILE_ENABLED"`
+	FilePath        string            `json:"file_path" env:"PICOCLAW_LOGGING_FILE_PATH"`
+	RotationEnabled bool              `json:"rotation_enabled" env:"PICOCLAW_LOGGING_ROTATION_ENABLED"`
+	MaxAgeDays      int               `json:"max_age_days" env:"PICOCLAW_LOGGING_MAX_AGE_DAYS"`
+	MaxSizeMB       int               `json:"max_size_mb" env:"PICOCLAW_LOGGING_MAX_SIZE_MB"`
+	// Repeated identical entries beyond SampleThreshold within
+	// SampleWindowSeconds are collapsed into one line with a count.
+	SampleWindowSeconds int `json:"sample_window_seconds" env:"PICOCLAW_LOGGING_SAMPLE_WINDOW_SECONDS"` // 0 = off
+	SampleThreshold     int `json:"sample_threshold" env:"PICOCLAW_LOGGING_SAMPLE_THRESHOLD"`
+	// Remote additionally ships entries to an HTTP endpoint or syslog server.
+	Remote RemoteLogConfig `json:"remote"`
+}
+
+// RemoteLogConfig configures the remote log sink. URL is an http(s)
+// endpoint receiving JSON arrays of entries, or udp://, tcp:// or
+// syslog:// host:port for a syslog server; empty disables it.
+type RemoteLogConfig struct {
+	URL                  string            `json:"url" env:"PICOCLAW_LOGGING_REMOTE_URL"`
+	Headers              map[string]string `json:"headers,omitempty"`
+	BufferSize           int               `json:"buffer_size" env:"PICOCLAW_LOGGING_REMOTE_BUFFER_SIZE"`
+	FlushIntervalSeconds int               `json:"flush_interval_seconds" env:"PICOCLAW_LOGGING_REMOTE_FLUSH_INTERVAL_SECONDS"`
+}
+
+// AttachmentsConfig controls the attachment store shared by all channels.
+type AttachmentsConfig struct {
+	// Dedup stores a re-sent file once, shared by the records of every
+	// message (in any chat) that carried it.
+	Dedup bool `json:"dedup" env:"PICOCLAW_ATTACHMENTS_DEDUP"`
+}
+
+// UsageConfig controls the token usage log.
+type UsageConfig struct {
+	RetentionDays        int `json:"retention_days" env:"PICOCLAW_USAGE_RETENTION_DAYS"`                 // 0 = keep forever
+	FlushIntervalSeconds int `json:"flush_interval_seconds" env:"PICOCLAW_USAGE_FLUSH_INTERVAL_SECONDS"` // 0 = write every record immediately
 }
 
 type VisibilityConfig struct {
@@ -227,11 +390,18 @@ type ProviderConfig struct {
 	Proxy       string `json:"proxy,omitempty" env:"PICOCLAW_PROVIDERS_{{.Name}}_PROXY"`
 	AuthMethod  string `json:"auth_method,omitempty" env:"PICOCLAW_PROVIDERS_{{.Name}}_AUTH_METHOD"`
 	ConnectMode string `json:"connect_mode,omitempty" env:"PICOCLAW_PROVIDERS_{{.Name}}_CONNECT_MODE"` //only for Github Copilot, `stdio` or `grpc`
+	// Optional pricing in USD per million tokens, used for usage cost estimates.
+	InputPricePerMTok  float64 `json:"input_price_per_mtok,omitempty" env:"PICOCLAW_PROVIDERS_{{.Name}}_INPUT_PRICE_PER_MTOK"`
+	OutputPricePerMTok float64 `json:"output_price_per_mtok,omitempty" env:"PICOCLAW_PROVIDERS_{{.Name}}_OUTPUT_PRICE_PER_MTOK"`
 }
 
 type GatewayConfig struct {
 	Host string `json:"host" env:"PICOCLAW_GATEWAY_HOST"`
 	Port int    `json:"port" env:"PICOCLAW_GATEWAY_PORT"`
+	// APIEnabled serves an OpenAI-compatible /v1/chat/completions endpoint
+	// on Host:Port. APIKey is required unless Host is a loopback address.
+	APIEnabled bool   `json:"api_enabled" env:"PICOCLAW_GATEWAY_API_ENABLED"`
+	APIKey     string `json:"api_key" env:"PICOCLAW_GATEWAY_API_KEY"`
 }
 
 type BraveConfig struct {
@@ -271,22 +441,107 @@ type MCPToolsConfig struct {
 	Servers []MCPServerConfig `json:"servers"`
 }
 
+// SessionToolsConfig controls the session_export/session_import admin tools.
+type SessionToolsConfig struct {
+	Enabled bool `json:"enabled" env:"PICOCLAW_TOOLS_SESSIONS_ENABLED"`
+}
+
+// OCRToolsConfig controls text extraction from inbound images. The text is
+// added to the user message next to the image.
+type OCRToolsConfig struct {
+	Enabled bool `json:"enabled" env:"PICOCLAW_TOOLS_OCR_ENABLED"`
+	// Command and Args run the OCR program; "{path}" in Args is replaced by
+	// the image path. The program must print the text to stdout.
+	Command        string   `json:"command" env:"PICOCLAW_TOOLS_OCR_COMMAND"`
+	Args           []string `json:"args" env:"PICOCLAW_TOOLS_OCR_ARGS"`
+	TimeoutSeconds int      `json:"timeout_seconds" env:"PICOCLAW_TOOLS_OCR_TIMEOUT_SECONDS"`
+	MaxChars       int      `json:"max_chars" env:"PICOCLAW_TOOLS_OCR_MAX_CHARS"`
+}
+
+// MediaToolsConfig controls how inbound images are prepared for the model.
+type MediaToolsConfig struct {
+	// MaxImageDimension is the longest side, in pixels, images are scaled
+	// down to before being sent; -1 sends them at full resolution.
+	MaxImageDimension int `json:"max_image_dimension" env:"PICOCLAW_TOOLS_MEDIA_MAX_IMAGE_DIMENSION"`
+	// JPEGQuality is used when re-encoding scaled images (1-100).
+	JPEGQuality int `json:"jpeg_quality" env:"PICOCLAW_TOOLS_MEDIA_JPEG_QUALITY"`
+	// MaxCacheMB caps the downloaded media cache; the oldest files are
+	// deleted once it is exceeded. 0 disables the cap.
+	MaxCacheMB int `json:"max_cache_mb" env:"PICOCLAW_TOOLS_MEDIA_MAX_CACHE_MB"`
+}
+
+// ExecToolsConfig controls the exec tool's environment and the commands it
+// may run.
+type ExecToolsConfig struct {
+	// DefaultEnv is set for every command, e.g. a PATH that includes the
+	// Termux binaries. $VAR references expand against the process
+	// environment; the call's env argument overrides these.
+	DefaultEnv map[string]string `json:"default_env"`
+	// SecretEnv names variables whose values are redacted when the call's
+	// arguments are logged.
+	SecretEnv []string `json:"secret_env" env:"PICOCLAW_TOOLS_EXEC_SECRET_ENV"`
+	// AllowedCommands and BlockedCommands match the base name of every
+	// command in a command line (each part of a pipe or chain, and the
+	// command behind sudo, env or xargs). A non-empty allowlist refuses
+	// all other commands.
+	AllowedCommands []string `json:"allowed_commands" env:"PICOCLAW_TOOLS_EXEC_ALLOWED_COMMANDS"`
+	BlockedCommands []string `json:"blocked_commands" env:"PICOCLAW_TOOLS_EXEC_BLOCKED_COMMANDS"`
+}
+
+// FileToolsConfig controls the file editing tools.
+type FileToolsConfig struct {
+	// ShowDiff sends edit_file's diff to the user as well as the model.
+	ShowDiff bool `json:"show_diff" env:"PICOCLAW_TOOLS_FILES_SHOW_DIFF"`
+}
+
 type ToolsConfig struct {
-	Web WebToolsConfig `json:"web"`
-	MCP MCPToolsConfig `json:"mcp"`
+	Web      WebToolsConfig     `json:"web"`
+	MCP      MCPToolsConfig     `json:"mcp"`
+	Sessions SessionToolsConfig `json:"sessions"`
+	OCR      OCRToolsConfig     `json:"ocr"`
+	Media    MediaToolsConfig   `json:"media"`
+	Exec     ExecToolsConfig    `json:"exec"`
+	Files    FileToolsConfig    `json:"files"`
+	// Confirm lists tool names (e.g. "exec") that only run after the user
+	// replies "approve" in chat.
+	Confirm []string `json:"confirm" env:"PICOCLAW_TOOLS_CONFIRM"`
+	// Dangerous lists tool names the model is told are destructive, so it
+	// checks with the user before calling them. Confirm tools are always
+	// marked as well.
+	Dangerous []string `json:"dangerous" env:"PICOCLAW_TOOLS_DANGEROUS"`
+	// Callbacks are tools answered by a person or external system: each
+	// call sends a request to a chat and waits for "/answer <id> <text>".
+	Callbacks []CallbackToolConfig `json:"callbacks"`
+	// SafeMode runs picoclaw as a pure chat assistant: shell, file-writing,
+	// hardware, device-control and MCP tools are left out, keeping
+	// read-only and web tools.
+	SafeMode bool `json:"safe_mode" env:"PICOCLAW_TOOLS_SAFE_MODE"`
+}
+
+// CallbackToolConfig defines one callback tool. Requests go to Channel and
+// ChatID, or to the chat the call came from when they are empty.
+type CallbackToolConfig struct {
+	Name           string `json:"name"`
+	Description    string `json:"description"`
+	Channel        string `json:"channel,omitempty"`
+	ChatID         string `json:"chat_id,omitempty"`
+	TimeoutSeconds int    `json:"timeout_seconds"` // 0 = 60; the agent's turn waits this long
 }
 
 func DefaultConfig() *Config {
 	return &Config{
 		Agents: AgentsConfig{
 			Defaults: AgentDefaults{
-				Workspace:           "~/.picoclaw/workspace",
-				RestrictToWorkspace: true,
-				Provider:            "",
-				Model:               "glm-4.7",
-				MaxTokens:           8192,
-				Temperature:         0.7,
-				MaxToolIterations:   20,
+				Workspace:             "~/.picoclaw/workspace",
+				RestrictToWorkspace:   true,
+				Provider:              "",
+				Model:                 "glm-4.7",
+				MaxTokens:             8192,
+				Temperature:           0.7,
+				MaxToolIterations:     20,
+				RequestTimeoutSeconds: 600,
+				SessionToolHistory:    "full",
+				ToolResultMaxTurns:    0,
 			},
 			Failover: AgentFailover{
 				Enabled:                      true,
@@ -299,10 +554,22 @@ func DefaultConfig() *Config {
 				SwitchbackRequiresApproval:   true,
 				SwitchbackPromptCooldownMins: 60,
 				SwitchbackPromptTimeoutMins:  0,
+				ProbeLockStaleSeconds:        120,
+				BreakerThreshold:             3,
+				BreakerWindowSeconds:         300,
+				BreakerCooldownMinutes:       15,
 			},
 			Planner: AgentPlanner{
-				Enabled: true,
-				Model:   "gpt-5.1-mini",
+				Enabled:      true,
+				Model:        "gpt-5.1-mini",
+				PlanDelivery: "chat",
+				AnnouncePlan: true,
+				MinToolCalls: 1,
+			},
+			Memory: AgentMemory{
+				TopK:          8,
+				MinScore:      0.3,
+				RememberTurns: true,
 			},
 		},
 		Channels: ChannelsConfig{
@@ -312,9 +579,15 @@ func DefaultConfig() *Config {
 				AllowFrom: FlexibleStringSlice{},
 			},
 			Telegram: TelegramConfig{
-				Enabled:   false,
-				Token:     "",
-				AllowFrom: FlexibleStringSlice{},
+				Enabled:            false,
+				Token:              "",
+				AllowFrom:          FlexibleStringSlice{},
+				DedupTTLSeconds:    600,
+				DedupMaxEntries:    10000,
+				GroupTriggerPrefix: []string{},
+				RequireMention:     false,
+				ReplyToMessages:    false,
+				AnimationPreviews:  false,
 			},
 			Feishu: FeishuConfig{
 				Enabled:           false,
@@ -336,10 +609,12 @@ func DefaultConfig() *Config {
 				AllowFrom: FlexibleStringSlice{},
 			},
 			QQ: QQConfig{
-				Enabled:   false,
-				AppID:     "",
-				AppSecret: "",
-				AllowFrom: FlexibleStringSlice{},
+				Enabled:         false,
+				AppID:           "",
+				AppSecret:       "",
+				AllowFrom:       FlexibleStringSlice{},
+				DedupTTLSeconds: 600,
+				DedupMaxEntries: 10000,
 			},
 			DingTalk: DingTalkConfig{
 				Enabled:      false,
@@ -370,6 +645,10 @@ func DefaultConfig() *Config {
 				GroupTriggerPrefix: []string{},
 				AllowFrom:          FlexibleStringSlice{},
 			},
+			Outbound: OutboundConfig{
+				MessagesPerSecond: 0, // opt-in
+				Burst:             3,
+			},
 		},
 		Providers: ProvidersConfig{
 			Anthropic:    ProviderConfig{},
@@ -403,6 +682,23 @@ func DefaultConfig() *Config {
 				Enabled: false,
 				Servers: []MCPServerConfig{},
 			},
+			Sessions: SessionToolsConfig{
+				Enabled: false,
+			},
+			OCR: OCRToolsConfig{
+				Enabled:        false,
+				Command:        "tesseract",
+				Args:           []string{"{path}", "stdout"},
+				TimeoutSeconds: 30,
+				MaxChars:       4000,
+			},
+			Media: MediaToolsConfig{
+				MaxImageDimension: 1568,
+				JPEGQuality:       85,
+				MaxCacheMB:        200,
+			},
+			Confirm:   []string{},
+			Dangerous: []string{},
 		},
 		Heartbeat: HeartbeatConfig{
 			Enabled:  true,
@@ -411,13 +707,24 @@ func DefaultConfig() *Config {
 		Devices: DevicesConfig{
 			Enabled:    false,
 			MonitorUSB: true,
+			Telephony: TelephonyConfig{
+				SMSWatch:               false,
+				SMSPollIntervalSeconds: 60,
+				SMSDebounceSeconds:     5,
+			},
 		},
 		Logging: LoggingConfig{
+			Level:           "info",
 			FileEnabled:     true,
 			FilePath:        "~/.picoclaw/workspace/picoclaw.log",
 			RotationEnabled: true,
 			MaxAgeDays:      7,
 			MaxSizeMB:       50,
+			SampleThreshold: 5,
+			Remote: RemoteLogConfig{
+				BufferSize:           1000,
+				FlushIntervalSeconds: 2,
+			},
 		},
 		Visibility: VisibilityConfig{
 			Enabled:          true,
@@ -425,6 +732,22 @@ func DefaultConfig() *Config {
 			UpdateIntervalMS: 1000,
 			ShowDuration:     true,
 		},
+		Usage: UsageConfig{
+			RetentionDays:        30,
+			FlushIntervalSeconds: 10,
+		},
+		Voice: VoiceConfig{
+			LanguageRouting: false,
+			Languages:       map[string]VoiceLanguageConfig{},
+			TTS: VoiceTTSConfig{
+				Enabled:  false,
+				APIBase:  "https://api.openai.com/v1",
+				Model:    "gpt-4o-mini-tts",
+				Voice:    "alloy",
+				Format:   "opus",
+				MaxChars: 1500,
+			},
+		},
 	}
 }
 
@@ -551,12 +874,124 @@ func SaveConfig(path string, cfg *Config) error {
 	return os.WriteFile(path, data, 0644)
 }
 
+// SaveChannelAllowFrom rewrites only channels.<channel>.allow_from in the
+// config file at path, leaving everything else as written on disk. Unlike
+// SaveConfig with a loaded Config, it never writes values that came from
+// the environment or from ${VAR} references.
+func SaveChannelAllowFrom(path, channel string, allowFrom []string) error {
+	root := map[string]json.RawMessage{}
+	perm := os.FileMode(0644)
+	data, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		if err := json.Unmarshal(data, &root); err != nil {
+			return fmt.Errorf("parse %s: %w", path, err)
+		}
+		if info, err := os.Stat(path); err == nil {
+			perm = info.Mode().Perm()
+		}
+	case !os.IsNotExist(err):
+		return err
+	}
+
+	channels := map[string]json.RawMessage{}
+	if raw, ok := root["channels"]; ok {
+		if err := json.Unmarshal(raw, &channels); err != nil {
+			return fmt.Errorf("parse channels in %s: %w", path, err)
+		}
+	}
+	section := map[string]json.RawMessage{}
+	if raw, ok := channels[channel]; ok {
+		if err := json.Unmarshal(raw, &section); err != nil {
+			return fmt.Errorf("parse channels.%s in %s: %w", channel, path, err)
+		}
+	}
+
+	if allowFrom == nil {
+		allowFrom = []string{}
+	}
+	var marshalErr error
+	set := func(m map[string]json.RawMessage, key string, v interface{}) {
+		raw, err := json.Marshal(v)
+		if err != nil && marshalErr == nil {
+			marshalErr = err
+		}
+		m[key] = raw
+	}
+	set(section, "allow_from", allowFrom)
+	set(channels, channel, section)
+	set(root, "channels", channels)
+	if marshalErr != nil {
+		return marshalErr
+	}
+
+	out, err := json.MarshalIndent(root, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, out, perm)
+}
+
+// SetChannelAllowFrom replaces the allow_from list of the named channel. It
+// returns false for an unknown channel.
+func (c *Config) SetChannelAllowFrom(channel string, allowFrom []string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var target *FlexibleStringSlice
+	switch channel {
+	case "whatsapp":
+		target = &c.Channels.WhatsApp.AllowFrom
+	case "telegram":
+		target = &c.Channels.Telegram.AllowFrom
+	case "feishu":
+		target = &c.Channels.Feishu.AllowFrom
+	case "discord":
+		target = &c.Channels.Discord.AllowFrom
+	case "maixcam":
+		target = &c.Channels.MaixCam.AllowFrom
+	case "qq":
+		target = &c.Channels.QQ.AllowFrom
+	case "dingtalk":
+		target = &c.Channels.DingTalk.AllowFrom
+	case "slack":
+		target = &c.Channels.Slack.AllowFrom
+	case "line":
+		target = &c.Channels.LINE.AllowFrom
+	case "onebot":
+		target = &c.Channels.OneBot.AllowFrom
+	default:
+		return false
+	}
+	*target = FlexibleStringSlice(slices.Clone(allowFrom))
+	return true
+}
+
 func (c *Config) WorkspacePath() string {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 	return expandHome(c.Agents.Defaults.Workspace)
 }
 
+// Location returns the configured timezone, or the system local zone when
+// unset. An unknown IANA name returns time.Local along with the error.
+func (c *Config) Location() (*time.Location, error) {
+	c.mu.RLock()
+	name := strings.TrimSpace(c.Timezone)
+	c.mu.RUnlock()
+	if name == "" {
+		return time.Local, nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return time.Local, fmt.Errorf("invalid timezone %q: %w", name, err)
+	}
+	return loc, nil
+}
+
 func (c *Config) GetAPIKey() string {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
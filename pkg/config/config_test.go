@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"testing"
+	"time"
 )
 
 // TestDefaultConfig_HeartbeatEnabled verifies heartbeat is enabled by default
@@ -189,6 +190,9 @@ func TestConfig_Complete(t *testing.T) {
 	if cfg.Agents.Planner.Model == "" {
 		t.Error("Planner model should have default value")
 	}
+	if !cfg.Agents.Planner.AnnouncePlan || cfg.Agents.Planner.MinToolCalls != 1 {
+		t.Error("Plans should be announced for every tool-using turn by default")
+	}
 }
 
 func TestApplyProviderEnvOverrides(t *testing.T) {
@@ -225,3 +229,20 @@ func TestResolveEnvRefKeepsOriginalWhenUnset(t *testing.T) {
 		t.Fatalf("expected unresolved ref to stay unchanged, got %q", got)
 	}
 }
+
+func TestConfigLocation(t *testing.T) {
+	cfg := DefaultConfig()
+	if loc, err := cfg.Location(); err != nil || loc != time.Local {
+		t.Fatalf("Location() = %v, %v; want time.Local", loc, err)
+	}
+
+	cfg.Timezone = "UTC"
+	if loc, err := cfg.Location(); err != nil || loc.String() != "UTC" {
+		t.Fatalf("Location() = %v, %v; want UTC", loc, err)
+	}
+
+	cfg.Timezone = "Not/AZone"
+	if loc, err := cfg.Location(); err == nil || loc != time.Local {
+		t.Fatalf("Location() = %v, %v; want time.Local with error", loc, err)
+	}
+}
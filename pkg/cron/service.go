@@ -4,10 +4,12 @@ import (
 	"crypto/rand"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"os"
 	"path/filepath"
+	"sort"
 	"sync"
 	"time"
 
@@ -57,6 +59,14 @@ type CronStore struct {
 
 type JobHandler func(job *CronJob) (string, error)
 
+var (
+	// ErrJobNotFound is returned when no job has the given ID.
+	ErrJobNotFound = errors.New("job not found")
+	// ErrJobAlreadyFired is returned when cancelling a one-time job that has
+	// already run (or is running now), so cancelling prevented nothing.
+	ErrJobAlreadyFired = errors.New("job already fired")
+)
+
 type CronService struct {
 	storePath string
 	store     *CronStore
@@ -421,6 +431,55 @@ func (cs *CronService) removeJobUnsafe(jobID string) bool {
 	return removed
 }
 
+// CancelJob removes a job so it never runs again and returns it. A one-time
+// job that already fired is still removed, but ErrJobAlreadyFired is
+// returned alongside it.
+func (cs *CronService) CancelJob(jobID string) (*CronJob, error) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	var job *CronJob
+	for i := range cs.store.Jobs {
+		if cs.store.Jobs[i].ID == jobID {
+			jobCopy := cs.store.Jobs[i]
+			job = &jobCopy
+			break
+		}
+	}
+	if job == nil {
+		return nil, ErrJobNotFound
+	}
+
+	cs.removeJobUnsafe(jobID)
+
+	if job.Schedule.Kind == "at" {
+		ran := job.State.LastRunAtMS != nil
+		// checkJobs clears NextRunAtMS just before executing a due job.
+		running := job.Enabled && job.State.NextRunAtMS == nil
+		if ran || running {
+			return job, ErrJobAlreadyFired
+		}
+	}
+	return job, nil
+}
+
+// UpcomingJobs returns enabled jobs that have a pending run, soonest first.
+func (cs *CronService) UpcomingJobs() []CronJob {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+
+	var upcoming []CronJob
+	for _, job := range cs.store.Jobs {
+		if job.Enabled && job.State.NextRunAtMS != nil {
+			upcoming = append(upcoming, job)
+		}
+	}
+	sort.Slice(upcoming, func(i, j int) bool {
+		return *upcoming[i].State.NextRunAtMS < *upcoming[j].State.NextRunAtMS
+	})
+	return upcoming
+}
+
 func (cs *CronService) EnableJob(jobID string, enabled bool) *CronJob {
 	cs.mu.Lock()
 	defer cs.mu.Unlock()
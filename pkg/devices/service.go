@@ -4,6 +4,7 @@ import (
 	"context"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/sipeed/picoclaw/pkg/bus"
 	"github.com/sipeed/picoclaw/pkg/constants"
@@ -17,6 +18,8 @@ type Service struct {
 	bus     *bus.MessageBus
 	state   *state.Manager
 	sources []events.EventSource
+	sms     *smsConfig
+	watcher *SMSWatcher
 	enabled bool
 	ctx     context.Context
 	cancel  context.CancelFunc
@@ -27,6 +30,15 @@ type Config struct {
 	Enabled    bool
 	MonitorUSB bool // When true, monitor USB hotplug (Linux only)
 	// Future: MonitorBluetooth, MonitorPCI, etc.
+
+	WatchSMS        bool          // When true, forward new SMS to the agent (Termux only)
+	SMSPollInterval time.Duration // How often to check the SMS inbox
+	SMSDebounce     time.Duration // Quiet period before forwarding a burst of SMS
+}
+
+type smsConfig struct {
+	interval time.Duration
+	debounce time.Duration
 }
 
 func NewService(cfg Config, stateMgr *state.Manager) *Service {
@@ -39,6 +51,9 @@ func NewService(cfg Config, stateMgr *state.Manager) *Service {
 	if cfg.Enabled && cfg.MonitorUSB {
 		s.sources = append(s.sources, sources.NewUSBMonitor())
 	}
+	if cfg.Enabled && cfg.WatchSMS {
+		s.sms = &smsConfig{interval: cfg.SMSPollInterval, debounce: cfg.SMSDebounce}
+	}
 
 	return s
 }
@@ -53,13 +68,23 @@ func (s *Service) Start(ctx context.Context) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	if !s.enabled || len(s.sources) == 0 {
+	if !s.enabled || (len(s.sources) == 0 && s.sms == nil) {
 		logger.InfoC("devices", "Device event service disabled or no sources")
 		return nil
 	}
 
 	s.ctx, s.cancel = context.WithCancel(ctx)
 
+	if s.sms != nil && s.bus != nil {
+		s.watcher = NewSMSWatcher(s.bus, s.state, s.sms.interval, s.sms.debounce)
+		if err := s.watcher.Start(s.ctx); err != nil {
+			logger.ErrorCF("devices", "Failed to start SMS watcher", map[string]interface{}{
+				"error": err.Error(),
+			})
+			s.watcher = nil
+		}
+	}
+
 	for _, src := range s.sources {
 		eventCh, err := src.Start(s.ctx)
 		if err != nil {
@@ -91,6 +116,10 @@ func (s *Service) Stop() {
 	for _, src := range s.sources {
 		src.Stop()
 	}
+	if s.watcher != nil {
+		s.watcher.Stop()
+		s.watcher = nil
+	}
 
 	logger.InfoC("devices", "Device event service stopped")
 }
@@ -0,0 +1,221 @@
+package devices
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/constants"
+	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/state"
+	"github.com/sipeed/picoclaw/pkg/utils"
+)
+
+// SMSMessage is one entry of `termux-sms-list` output.
+type SMSMessage struct {
+	ID       int64  `json:"_id"`
+	Number   string `json:"number"`
+	Sender   string `json:"sender"`
+	Received string `json:"received"`
+	Body     string `json:"body"`
+}
+
+// SMSWatcher polls the Termux SMS inbox and forwards new messages to the
+// agent as inbound messages on the last active channel, so it can tell the
+// user about them. Outside Termux it does nothing.
+type SMSWatcher struct {
+	bus      *bus.MessageBus
+	state    *state.Manager
+	interval time.Duration
+	debounce time.Duration
+	list     func(ctx context.Context) ([]SMSMessage, error)
+
+	seen    map[int64]bool // IDs in the latest inbox listing
+	seeded  bool           // First poll only records what is already there
+	pending []SMSMessage   // New messages waiting for the debounce to pass
+
+	cancel context.CancelFunc
+	mu     sync.Mutex
+}
+
+// NewSMSWatcher creates a watcher polling every interval. New messages are
+// forwarded once no further ones arrived for debounce.
+func NewSMSWatcher(msgBus *bus.MessageBus, stateMgr *state.Manager, interval, debounce time.Duration) *SMSWatcher {
+	return &SMSWatcher{
+		bus:      msgBus,
+		state:    stateMgr,
+		interval: interval,
+		debounce: debounce,
+		list:     termuxSMSList,
+		seen:     make(map[int64]bool),
+	}
+}
+
+// Start begins polling in the background. It is a no-op outside Termux.
+func (w *SMSWatcher) Start(ctx context.Context) error {
+	if !utils.IsTermux() || !hasCommand("termux-sms-list") {
+		logger.InfoC("devices", "SMS watcher disabled: not running under Termux with termux-sms-list")
+		return nil
+	}
+	return w.start(ctx)
+}
+
+func (w *SMSWatcher) start(ctx context.Context) error {
+	if w.interval <= 0 {
+		return fmt.Errorf("sms poll interval must be positive")
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	ctx, w.cancel = context.WithCancel(ctx)
+	go w.run(ctx)
+
+	logger.InfoCF("devices", "SMS watcher started", map[string]interface{}{
+		"interval": w.interval.String(),
+		"debounce": w.debounce.String(),
+	})
+	return nil
+}
+
+// Stop ends polling. Messages still inside the debounce window are dropped
+// from forwarding but remain in the inbox.
+func (w *SMSWatcher) Stop() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.cancel != nil {
+		w.cancel()
+		w.cancel = nil
+	}
+}
+
+func (w *SMSWatcher) run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	// flush is armed when new messages arrive and re-armed by each later
+	// arrival, so a burst (e.g. a multipart SMS) is forwarded once.
+	var flush <-chan time.Time
+	w.poll(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if w.poll(ctx) > 0 {
+				flush = time.After(w.debounce)
+			}
+		case <-flush:
+			flush = nil
+			w.forwardPending()
+		}
+	}
+}
+
+// poll lists the inbox and queues messages not seen before, returning how
+// many were queued.
+func (w *SMSWatcher) poll(ctx context.Context) int {
+	msgs, err := w.list(ctx)
+	if err != nil {
+		logger.WarnCF("devices", "Failed to list SMS inbox", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return 0
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	seen := make(map[int64]bool, len(msgs))
+	added := 0
+	for _, m := range msgs {
+		seen[m.ID] = true
+		if w.seeded && !w.seen[m.ID] {
+			w.pending = append(w.pending, m)
+			added++
+		}
+	}
+	// The listing only holds the newest messages, so older IDs can be
+	// forgotten without risking a repeat.
+	w.seen = seen
+	w.seeded = true
+	return added
+}
+
+// forwardPending injects queued messages as one inbound message on the last
+// active channel.
+func (w *SMSWatcher) forwardPending() {
+	w.mu.Lock()
+	pending := w.pending
+	w.pending = nil
+	w.mu.Unlock()
+
+	if len(pending) == 0 {
+		return
+	}
+
+	platform, chatID := parseLastChannel(w.state.GetLastChannel())
+	if platform == "" || chatID == "" || constants.IsInternalChannel(platform) {
+		logger.DebugCF("devices", "No last channel, skipping SMS forward", map[string]interface{}{
+			"count": len(pending),
+		})
+		return
+	}
+
+	w.bus.PublishInbound(bus.InboundMessage{
+		Channel:    platform,
+		SenderID:   "sms",
+		ChatID:     chatID,
+		Content:    formatSMSNotice(pending),
+		SessionKey: fmt.Sprintf("%s:%s", platform, chatID),
+		Metadata:   map[string]string{"source": "sms_watcher"},
+	})
+	logger.InfoCF("devices", "Forwarded new SMS to agent", map[string]interface{}{
+		"count": len(pending),
+		"to":    platform,
+	})
+}
+
+func formatSMSNotice(msgs []SMSMessage) string {
+	var sb strings.Builder
+	if len(msgs) == 1 {
+		sb.WriteString("[New SMS received. Tell the user about it briefly.]\n")
+	} else {
+		sb.WriteString(fmt.Sprintf("[%d new SMS received. Tell the user about them briefly.]\n", len(msgs)))
+	}
+	for _, m := range msgs {
+		from := m.Sender
+		if from == "" {
+			from = m.Number
+		} else if m.Number != "" && m.Number != from {
+			from = fmt.Sprintf("%s (%s)", from, m.Number)
+		}
+		sb.WriteString(fmt.Sprintf("\nFrom %s at %s:\n%s\n", from, m.Received, m.Body))
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+func hasCommand(name string) bool {
+	_, err := exec.LookPath(name)
+	return err == nil
+}
+
+// termuxSMSList reads the newest inbox messages via Termux:API.
+func termuxSMSList(ctx context.Context) ([]SMSMessage, error) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	out, err := exec.CommandContext(ctx, "termux-sms-list", "-t", "inbox", "-l", "50").Output()
+	if err != nil {
+		return nil, fmt.Errorf("termux-sms-list: %w", err)
+	}
+	var msgs []SMSMessage
+	if err := json.Unmarshal(out, &msgs); err != nil {
+		return nil, fmt.Errorf("parse termux-sms-list output: %w", err)
+	}
+	return msgs, nil
+}
@@ -0,0 +1,119 @@
+package devices
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/state"
+)
+
+// fakeInbox serves a mutable SMS listing, newest first like Termux.
+type fakeInbox struct {
+	mu   sync.Mutex
+	msgs []SMSMessage
+}
+
+func (f *fakeInbox) deliver(m SMSMessage) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.msgs = append([]SMSMessage{m}, f.msgs...)
+}
+
+func (f *fakeInbox) list(ctx context.Context) ([]SMSMessage, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]SMSMessage(nil), f.msgs...), nil
+}
+
+func newTestSMSWatcher(t *testing.T, inbox *fakeInbox) (*SMSWatcher, *bus.MessageBus) {
+	t.Helper()
+	stateMgr := state.NewManager(t.TempDir())
+	if err := stateMgr.SetLastChannel("telegram:42"); err != nil {
+		t.Fatal(err)
+	}
+	msgBus := bus.NewMessageBus()
+	w := NewSMSWatcher(msgBus, stateMgr, time.Hour, time.Hour)
+	w.list = inbox.list
+	return w, msgBus
+}
+
+func TestSMSWatcher_DedupesByIDAndSkipsExistingInbox(t *testing.T) {
+	inbox := &fakeInbox{msgs: []SMSMessage{{ID: 1, Number: "+100", Body: "old"}}}
+	w, _ := newTestSMSWatcher(t, inbox)
+
+	if n := w.poll(context.Background()); n != 0 {
+		t.Fatalf("first poll queued %d, want existing inbox to be skipped", n)
+	}
+	inbox.deliver(SMSMessage{ID: 2, Number: "+200", Body: "hello"})
+	if n := w.poll(context.Background()); n != 1 {
+		t.Fatalf("poll queued %d, want 1 new message", n)
+	}
+	if n := w.poll(context.Background()); n != 0 {
+		t.Fatalf("repeat poll queued %d, want dedupe by id", n)
+	}
+	if len(w.pending) != 1 || w.pending[0].ID != 2 {
+		t.Fatalf("pending = %+v, want only id 2", w.pending)
+	}
+}
+
+func TestSMSWatcher_ForwardsToLastChannel(t *testing.T) {
+	inbox := &fakeInbox{}
+	w, msgBus := newTestSMSWatcher(t, inbox)
+	w.poll(context.Background())
+	inbox.deliver(SMSMessage{ID: 7, Number: "+200", Sender: "Alice", Received: "2026-01-02 10:00:00", Body: "Running late"})
+	inbox.deliver(SMSMessage{ID: 8, Number: "+200", Sender: "Alice", Received: "2026-01-02 10:00:05", Body: "10 min"})
+	w.poll(context.Background())
+
+	w.forwardPending()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	msg, ok := msgBus.ConsumeInbound(ctx)
+	if !ok {
+		t.Fatal("expected an inbound message")
+	}
+	if msg.Channel != "telegram" || msg.ChatID != "42" || msg.SessionKey != "telegram:42" {
+		t.Fatalf("routed to %s/%s (%s), want telegram/42", msg.Channel, msg.ChatID, msg.SessionKey)
+	}
+	for _, want := range []string{"2 new SMS", "From Alice (+200)", "Running late", "10 min"} {
+		if !strings.Contains(msg.Content, want) {
+			t.Fatalf("content missing %q:\n%s", want, msg.Content)
+		}
+	}
+	if len(w.pending) != 0 {
+		t.Fatal("pending messages should be cleared after forwarding")
+	}
+}
+
+func TestSMSWatcher_DebouncesBurst(t *testing.T) {
+	inbox := &fakeInbox{}
+	w, msgBus := newTestSMSWatcher(t, inbox)
+	w.interval = 10 * time.Millisecond
+	w.debounce = 200 * time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := w.start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer w.Stop()
+
+	time.Sleep(20 * time.Millisecond) // let the first poll seed the inbox
+	inbox.deliver(SMSMessage{ID: 1, Number: "+1", Body: "part 1"})
+	time.Sleep(20 * time.Millisecond)
+	inbox.deliver(SMSMessage{ID: 2, Number: "+1", Body: "part 2"})
+
+	recvCtx, recvCancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer recvCancel()
+	msg, ok := msgBus.ConsumeInbound(recvCtx)
+	if !ok {
+		t.Fatal("expected a forwarded SMS")
+	}
+	if !strings.Contains(msg.Content, "part 1") || !strings.Contains(msg.Content, "part 2") {
+		t.Fatalf("burst should be forwarded once:\n%s", msg.Content)
+	}
+}
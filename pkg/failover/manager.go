@@ -3,6 +3,8 @@ package failover
 import (
 	"context"
 	"fmt"
+	"math/rand/v2"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
@@ -56,12 +58,28 @@ type Manager struct {
 	primary   string
 	fallbacks []string
 	providers map[string]providers.LLMProvider
+	jitter    func(max time.Duration) time.Duration // Random delay in [0, max); fixed in tests
+}
+
+// probeJitterFraction spreads probes by up to this share of their delay so
+// instances and retries don't hit a recovering provider in lockstep.
+const probeJitterFraction = 0.1
+
+// maxProbeBackoffFactor caps how far consecutive probe failures stretch the
+// probe failure backoff.
+const maxProbeBackoffFactor = 8
+
+func randomJitter(max time.Duration) time.Duration {
+	if max <= 0 {
+		return 0
+	}
+	return rand.N(max)
 }
 
 func NewManager(cfg *config.Config, stateMgr *state.Manager) *Manager {
 	primary := cfg.Agents.Defaults.Model
 	fallbacks := normalizeFallbackChain(primary, cfg.Agents.Defaults.FallbackModels, cfg.Agents.Defaults.FallbackModel)
-	fs := stateMgr.GetFailoverState()
+	fs := stateMgr.GetFailoverState().Clone()
 
 	if fs.Mode == "" {
 		fs.Mode = modeNormal
@@ -83,24 +101,34 @@ func NewManager(cfg *config.Config, stateMgr *state.Manager) *Manager {
 		primary:   primary,
 		fallbacks: fallbacks,
 		providers: make(map[string]providers.LLMProvider),
+		jitter:    randomJitter,
 	}
 	_ = stateMgr.SetFailoverState(fs)
 	return m
 }
 
+// normalizeFallbackChain drops blank entries, the primary and duplicates.
+// An entry listing several comma-separated models is one slot whose models
+// are tried in order before the chain advances.
 func normalizeFallbackChain(primary string, chain []string, single string) []string {
 	if len(chain) == 0 && strings.TrimSpace(single) != "" {
 		chain = []string{single}
 	}
 	seen := map[string]bool{}
 	result := make([]string, 0, len(chain))
-	for _, model := range chain {
-		model = strings.TrimSpace(model)
-		if model == "" || model == primary || seen[model] {
+	for _, entry := range chain {
+		var models []string
+		for _, model := range slotModels(entry) {
+			if model != primary && !slices.Contains(models, model) {
+				models = append(models, model)
+			}
+		}
+		slot := strings.Join(models, slotSeparator)
+		if slot == "" || seen[slot] {
 			continue
 		}
-		seen[model] = true
-		result = append(result, model)
+		seen[slot] = true
+		result = append(result, slot)
 	}
 	return result
 }
@@ -109,6 +137,9 @@ func (m *Manager) Enabled() bool {
 	return m.cfg.Agents.Failover.Enabled
 }
 
+// ResolveRoute returns the model and provider to use. When the active
+// model's breaker is open, it first switches to the next fallback whose
+// breaker is closed.
 func (m *Manager) ResolveRoute() (Route, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -117,6 +148,16 @@ func (m *Manager) ResolveRoute() (Route, error) {
 	if model == "" {
 		model = m.primary
 	}
+	if now := time.Now(); m.Enabled() && m.breakerOpenLocked(model, now) {
+		start := 0
+		if model != m.primary {
+			start = max(m.fs.FallbackIndex+1, 0)
+		}
+		if idx := m.nextHealthyFallbackLocked(start, now); idx >= 0 {
+			hold := time.Duration(maxInt(m.cfg.Agents.Failover.HoldMinutes, 1)) * time.Minute
+			model = m.switchToFallbackLocked(now, idx, now.Add(hold), "circuit_open")
+		}
+	}
 	provider, err := m.providerForModelLocked(model)
 	if err != nil {
 		return Route{}, err
@@ -135,9 +176,22 @@ func (m *Manager) providerForModelLocked(model string) (providers.LLMProvider, e
 	if p, ok := m.providers[model]; ok {
 		return p, nil
 	}
-	p, err := providers.CreateProviderForModel(m.cfg, model)
-	if err != nil {
-		return nil, err
+	var p providers.LLMProvider
+	if models := slotModels(model); len(models) > 1 {
+		slot := &slotProvider{models: models}
+		for _, slotModel := range models {
+			member, err := m.providerForModelLocked(slotModel)
+			if err != nil {
+				return nil, err
+			}
+			slot.providers = append(slot.providers, member)
+		}
+		p = slot
+	} else {
+		var err error
+		if p, err = providers.CreateProviderForModel(m.cfg, model); err != nil {
+			return nil, err
+		}
 	}
 	m.providers[model] = p
 	return p, nil
@@ -188,37 +242,93 @@ func (m *Manager) OnLLMRateLimited(model string, err error) SwitchEvent {
 		}
 	}
 
-	var to string
-	if from == m.primary {
-		m.fs.FallbackIndex = 0
-		to = m.fallbacks[0]
-	} else {
-		next := m.fs.FallbackIndex + 1
-		if next < 0 {
-			next = 0
-		}
-		if next >= len(m.fallbacks) {
-			m.fs.LastSwitchReason = "rate_limited_fallback_exhausted"
-			m.persistLocked()
-			return SwitchEvent{FromModel: from, ToModel: from, Reason: "fallback_exhausted", Switched: false}
-		}
-		m.fs.FallbackIndex = next
-		to = m.fallbacks[next]
+	m.recordFailureLocked(from, now)
+
+	next := 0
+	if from != m.primary {
+		next = max(m.fs.FallbackIndex+1, 0)
+	}
+	if next >= len(m.fallbacks) {
+		m.fs.LastSwitchReason = "rate_limited_fallback_exhausted"
+		m.persistLocked()
+		return SwitchEvent{FromModel: from, ToModel: from, Reason: "fallback_exhausted", Switched: false}
+	}
+	// Skip models whose breaker is open; if all remaining ones are open,
+	// the next one is still better than staying.
+	if healthy := m.nextHealthyFallbackLocked(next, now); healthy >= 0 {
+		next = healthy
 	}
 
+	to := m.switchToFallbackLocked(now, next, holdUntil, "rate_limited")
+	return SwitchEvent{FromModel: from, ToModel: to, Reason: "rate_limited", Switched: true}
+}
+
+// switchToFallbackLocked makes fallback idx the active model and persists
+// the state. It returns the new active model.
+func (m *Manager) switchToFallbackLocked(now time.Time, idx int, holdUntil time.Time, reason string) string {
+	to := m.fallbacks[idx]
+	m.fs.FallbackIndex = idx
 	m.fs.Mode = modeDegraded
 	m.fs.ActiveModel = to
 	m.fs.PrimaryModel = m.primary
 	m.fs.DegradedAt = now
 	m.fs.HoldUntil = holdUntil
-	m.fs.NextProbeAt = holdUntil
+	m.fs.NextProbeAt = m.withJitter(now, holdUntil)
 	m.fs.ConsecutiveProbeSuccesses = 0
-	m.fs.LastSwitchReason = "rate_limited"
+	m.fs.LastSwitchReason = reason
 	m.fs.SwitchbackPromptSent = false
 	m.fs.SwitchEpoch++
 	m.persistLocked()
+	return to
+}
 
-	return SwitchEvent{FromModel: from, ToModel: to, Reason: "rate_limited", Switched: true}
+// nextHealthyFallbackLocked returns the index of the first fallback from
+// start on whose breaker is closed, or -1.
+func (m *Manager) nextHealthyFallbackLocked(start int, now time.Time) int {
+	for i := start; i < len(m.fallbacks); i++ {
+		if !m.breakerOpenLocked(m.fallbacks[i], now) {
+			return i
+		}
+	}
+	return -1
+}
+
+func (m *Manager) breakerOpenLocked(model string, now time.Time) bool {
+	return now.Before(m.fs.Breakers[model].OpenUntil)
+}
+
+// recordFailureLocked counts a failure of model and opens its breaker once
+// breaker_threshold failures fall within breaker_window_seconds.
+func (m *Manager) recordFailureLocked(model string, now time.Time) {
+	fc := m.cfg.Agents.Failover
+	if fc.BreakerThreshold <= 0 {
+		return
+	}
+	window := time.Duration(maxInt(fc.BreakerWindowSeconds, 1)) * time.Second
+	cooldown := time.Duration(maxInt(fc.BreakerCooldownMinutes, 1)) * time.Minute
+
+	if m.fs.Breakers == nil {
+		m.fs.Breakers = make(map[string]state.BreakerState)
+	}
+	b := m.fs.Breakers[model]
+	if b.Failures == 0 || now.Sub(b.FirstFailureAt) > window {
+		b = state.BreakerState{FirstFailureAt: now}
+	}
+	b.Failures++
+	if b.Failures >= fc.BreakerThreshold {
+		b = state.BreakerState{OpenUntil: now.Add(cooldown)}
+	}
+	m.fs.Breakers[model] = b
+}
+
+// closeBreakerLocked forgets model's failures and reports whether there
+// were any.
+func (m *Manager) closeBreakerLocked(model string) bool {
+	if _, ok := m.fs.Breakers[model]; !ok {
+		return false
+	}
+	delete(m.fs.Breakers, model)
+	return true
 }
 
 func (m *Manager) OnLLMSuccess(model string) {
@@ -227,8 +337,12 @@ func (m *Manager) OnLLMSuccess(model string) {
 	}
 	m.mu.Lock()
 	defer m.mu.Unlock()
+	changed := m.closeBreakerLocked(model)
 	if m.fs.ActiveModel == "" {
 		m.fs.ActiveModel = model
+		changed = true
+	}
+	if changed {
 		m.persistLocked()
 	}
 }
@@ -249,29 +363,60 @@ func (m *Manager) ShouldProbe(now time.Time) bool {
 	return m.fs.NextProbeAt.IsZero() || !now.Before(m.fs.NextProbeAt)
 }
 
+// defaultProbePrompt and defaultProbeMaxTokens apply when the failover
+// config leaves probe_prompt and probe_max_tokens unset.
+const (
+	defaultProbePrompt    = "health_check: reply with OK"
+	defaultProbeMaxTokens = 8
+)
+
+// RunProbe checks whether the primary model's provider has recovered. It
+// sends a tiny chat request to the primary (or the configured probe model),
+// or with probe_mode "http" only lists the provider's models.
 func (m *Manager) RunProbe(ctx context.Context) ProbeOutcome {
+	fc := m.cfg.Agents.Failover
+	model := strings.TrimSpace(fc.ProbeModel)
+	if model == "" {
+		model = m.primary
+	}
+
 	m.mu.Lock()
-	primary := m.primary
+	provider, err := m.providerForModelLocked(model)
 	m.mu.Unlock()
-
-	provider, err := providers.CreateProviderForModel(m.cfg, primary)
 	if err != nil {
-		return m.recordProbeResult(false, err)
+		return m.recordProbeResult(model, false, err)
+	}
+
+	if checker, ok := provider.(providers.ReachabilityChecker); ok && strings.EqualFold(fc.ProbeMode, "http") {
+		err := checker.CheckReachable(ctx)
+		return m.recordProbeResult(model, err == nil, err)
 	}
 
+	prompt := fc.ProbePrompt
+	if strings.TrimSpace(prompt) == "" {
+		prompt = defaultProbePrompt
+	}
+	maxTokens := fc.ProbeMaxTokens
+	if maxTokens <= 0 {
+		maxTokens = defaultProbeMaxTokens
+	}
 	_, err = provider.Chat(ctx,
-		[]providers.Message{{Role: "user", Content: "health_check: reply with OK"}},
+		[]providers.Message{{Role: "user", Content: prompt}},
 		nil,
-		primary,
-		map[string]interface{}{"max_tokens": 8, "temperature": 0.0},
+		model,
+		map[string]interface{}{"max_tokens": maxTokens, "temperature": 0.0},
 	)
 	if err != nil {
-		return m.recordProbeResult(false, err)
+		return m.recordProbeResult(model, false, err)
 	}
-	return m.recordProbeResult(true, nil)
+	return m.recordProbeResult(model, true, nil)
 }
 
-func (m *Manager) recordProbeResult(success bool, err error) ProbeOutcome {
+// recordProbeResult updates the failover state after probing model and
+// schedules the next probe. Rate limit reset hints from the provider take
+// precedence over the configured hold; otherwise a failed probe backs off
+// exponentially per probed model. Every delay gets some jitter.
+func (m *Manager) recordProbeResult(model string, success bool, err error) ProbeOutcome {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
@@ -284,9 +429,11 @@ func (m *Manager) recordProbeResult(success bool, err error) ProbeOutcome {
 	m.fs.LastProbeAt = now
 
 	if success {
+		delete(m.fs.ProbeFailures, model)
+		m.closeBreakerLocked(model)
 		m.fs.ConsecutiveProbeSuccesses++
 		m.fs.LastSwitchbackProbe = fmt.Sprintf("%d/%d successful probes as of %s", m.fs.ConsecutiveProbeSuccesses, threshold, now.Format(time.RFC3339))
-		m.fs.NextProbeAt = now.Add(interval)
+		m.fs.NextProbeAt = m.withJitter(now, now.Add(interval))
 		if m.fs.ConsecutiveProbeSuccesses >= threshold {
 			if m.cfg.Agents.Failover.SwitchbackRequiresApproval {
 				m.fs.Mode = modeAwaitingUserSwitchbk
@@ -295,6 +442,7 @@ func (m *Manager) recordProbeResult(success bool, err error) ProbeOutcome {
 				m.fs.ActiveModel = m.primary
 				m.fs.FallbackIndex = -1
 				m.fs.LastSwitchReason = "auto_switchback_probe_healthy"
+				m.closeBreakerLocked(m.primary)
 				m.fs.LastSwitchbackPromptAt = time.Time{}
 				m.fs.LastSwitchbackProbe = ""
 				m.fs.SwitchbackPromptSent = false
@@ -305,23 +453,39 @@ func (m *Manager) recordProbeResult(success bool, err error) ProbeOutcome {
 		return ProbeOutcome{Success: true, BecameHealthy: m.fs.ConsecutiveProbeSuccesses >= threshold, NextProbeAt: m.fs.NextProbeAt}
 	}
 
+	if m.fs.ProbeFailures == nil {
+		m.fs.ProbeFailures = make(map[string]int)
+	}
+	m.fs.ProbeFailures[model]++
+	failures := m.fs.ProbeFailures[model]
+
 	m.fs.ConsecutiveProbeSuccesses = 0
 	m.fs.Mode = modeDegraded
 	m.fs.LastSwitchbackProbe = ""
 	m.fs.SwitchbackPromptSent = false
-	m.fs.NextProbeAt = now.Add(backoff)
+	factor := min(1<<min(failures-1, 10), maxProbeBackoffFactor)
+	next := now.Add(backoff * time.Duration(factor))
 	if rl, ok := err.(*providers.RateLimitError); ok {
-		next := now.Add(hold)
-		if hinted := nextProbeFromRateLimitHints(now, rl); hinted.After(next) {
+		next = now.Add(hold)
+		if hinted := nextProbeFromRateLimitHints(now, rl); hinted.After(now) {
 			next = hinted
 		}
 		m.fs.HoldUntil = next
-		m.fs.NextProbeAt = next
 	}
+	m.fs.NextProbeAt = m.withJitter(now, next)
 	m.persistLocked()
 	return ProbeOutcome{Success: false, NextProbeAt: m.fs.NextProbeAt}
 }
 
+// withJitter delays next by up to probeJitterFraction of its distance from
+// now. It never moves a probe earlier.
+func (m *Manager) withJitter(now, next time.Time) time.Time {
+	if m.jitter == nil || !next.After(now) {
+		return next
+	}
+	return next.Add(m.jitter(time.Duration(float64(next.Sub(now)) * probeJitterFraction)))
+}
+
 func nextProbeFromRateLimitHints(now time.Time, rl *providers.RateLimitError) time.Time {
 	candidates := []time.Time{}
 	if rl == nil {
@@ -414,6 +578,7 @@ func (m *Manager) HandleUserSwitchbackDecision(text string) DecisionOutcome {
 		m.fs.FallbackIndex = -1
 		m.fs.ConsecutiveProbeSuccesses = 0
 		m.fs.LastSwitchReason = "manual_switchback_approved"
+		m.closeBreakerLocked(m.primary)
 		m.fs.LastSwitchbackPromptAt = time.Time{}
 		m.fs.LastSwitchbackProbe = ""
 		m.fs.SwitchbackPromptSent = false
@@ -429,6 +594,38 @@ func (m *Manager) HandleUserSwitchbackDecision(text string) DecisionOutcome {
 	return DecisionOutcome{Handled: true, Changed: false, Reply: fmt.Sprintf("Staying on fallback model %s. Ask for failover status any time if you want to switch back later.", m.fs.ActiveModel)}
 }
 
+// ResetToPrimary returns to the primary model immediately, skipping the
+// probes and any switchback approval. It returns the model that was active
+// and whether anything changed.
+func (m *Manager) ResetToPrimary() (string, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	from := m.fs.ActiveModel
+	if from == "" {
+		from = m.primary
+	}
+	if from == m.primary && m.fs.Mode == modeNormal {
+		return from, false
+	}
+
+	m.fs.Mode = modeNormal
+	m.fs.ActiveModel = m.primary
+	m.fs.FallbackIndex = -1
+	m.fs.ConsecutiveProbeSuccesses = 0
+	m.fs.HoldUntil = time.Time{}
+	m.fs.NextProbeAt = time.Time{}
+	m.fs.ProbeFailures = nil
+	m.closeBreakerLocked(m.primary)
+	m.fs.LastSwitchReason = "manual_reset"
+	m.fs.LastSwitchbackPromptAt = time.Time{}
+	m.fs.LastSwitchbackProbe = ""
+	m.fs.SwitchbackPromptSent = false
+	m.fs.SwitchEpoch++
+	m.persistLocked()
+	return from, true
+}
+
 func (m *Manager) IsUsingPrimary() bool {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -441,17 +638,23 @@ func (m *Manager) IsUsingPrimary() bool {
 func (m *Manager) Snapshot() state.FailoverState {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	return m.fs
+	return m.fs.Clone()
 }
 
 func (m *Manager) persistLocked() {
-	_ = m.stateMgr.SetFailoverState(m.fs)
+	_ = m.stateMgr.SetFailoverState(m.fs.Clone())
 }
 
 func (m *Manager) PrimaryModel() string {
 	return m.primary
 }
 
+// FallbackModels returns the normalized fallback chain in failover order,
+// without the primary model or duplicates.
+func (m *Manager) FallbackModels() []string {
+	return append([]string(nil), m.fallbacks...)
+}
+
 func (m *Manager) ActiveModel() string {
 	m.mu.Lock()
 	defer m.mu.Unlock()
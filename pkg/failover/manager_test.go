@@ -1,11 +1,16 @@
 package failover
 
 import (
+	"context"
+	"errors"
 	"os"
+	"slices"
+	"strconv"
 	"testing"
 	"time"
 
 	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/providers"
 	"github.com/sipeed/picoclaw/pkg/state"
 )
 
@@ -27,7 +32,9 @@ func newTestManager(t *testing.T) *Manager {
 	cfg.Agents.Failover.ProbeFailureBackoffMinutes = 10
 
 	sm := state.NewManager(tmp)
-	return NewManager(cfg, sm)
+	m := NewManager(cfg, sm)
+	m.jitter = func(time.Duration) time.Duration { return 0 }
+	return m
 }
 
 func TestOnLLMRateLimitedSwitchesToFirstFallback(t *testing.T) {
@@ -92,8 +99,8 @@ func TestProbeAutoSwitchbackWithoutApproval(t *testing.T) {
 	m.cfg.Agents.Failover.SwitchbackRequiresApproval = false
 	_ = m.OnLLMRateLimited(m.PrimaryModel(), nil)
 
-	_ = m.recordProbeResult(true, nil)
-	outcome := m.recordProbeResult(true, nil)
+	_ = m.recordProbeResult(m.PrimaryModel(), true, nil)
+	outcome := m.recordProbeResult(m.PrimaryModel(), true, nil)
 	if !outcome.BecameHealthy {
 		t.Fatalf("expected probe threshold to mark healthy")
 	}
@@ -121,3 +128,271 @@ func TestNewFailoverCycleResetsPromptSent(t *testing.T) {
 		t.Fatalf("expected switchback prompt sent flag reset in new failover cycle")
 	}
 }
+
+func TestFallbackModels_ReturnsCopyOfChain(t *testing.T) {
+	m := newTestManager(t)
+
+	got := m.FallbackModels()
+	if len(got) != 2 || got[0] != "gpt-5-mini" || got[1] != "gemini-2.5-flash" {
+		t.Fatalf("FallbackModels() = %v", got)
+	}
+	got[0] = "mutated"
+	if m.FallbackModels()[0] != "gpt-5-mini" {
+		t.Fatal("callers must not be able to change the chain")
+	}
+}
+
+func TestResetToPrimary(t *testing.T) {
+	m := newTestManager(t)
+	if _, changed := m.ResetToPrimary(); changed {
+		t.Fatal("ResetToPrimary() on the primary should change nothing")
+	}
+
+	_ = m.OnLLMRateLimited(m.PrimaryModel(), nil)
+	epoch := m.Snapshot().SwitchEpoch
+	from, changed := m.ResetToPrimary()
+	if !changed || from != "gpt-5-mini" {
+		t.Fatalf("ResetToPrimary() = %q, %v; want gpt-5-mini, true", from, changed)
+	}
+	fs := m.Snapshot()
+	if fs.Mode != modeNormal || fs.ActiveModel != m.PrimaryModel() || fs.SwitchEpoch != epoch+1 {
+		t.Fatalf("after reset: %+v", fs)
+	}
+	if m.ShouldProbe(time.Now()) {
+		t.Fatal("no probe should be due on the primary")
+	}
+}
+
+type probeProvider struct {
+	model     string
+	prompt    string
+	maxTokens interface{}
+	reachable bool
+}
+
+func (p *probeProvider) Chat(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, model string, options map[string]interface{}) (*providers.LLMResponse, error) {
+	p.model, p.prompt, p.maxTokens = model, messages[0].Content, options["max_tokens"]
+	return &providers.LLMResponse{Content: "OK"}, nil
+}
+
+func (p *probeProvider) GetDefaultModel() string { return "" }
+
+func (p *probeProvider) CheckReachable(ctx context.Context) error {
+	p.reachable = true
+	return nil
+}
+
+func TestRunProbe_DefaultsToTinyChatOnPrimary(t *testing.T) {
+	m := newTestManager(t)
+	fake := &probeProvider{}
+	m.SetProviderForModel(m.PrimaryModel(), fake)
+
+	if outcome := m.RunProbe(context.Background()); !outcome.Success {
+		t.Fatal("RunProbe() failed")
+	}
+	if fake.model != m.PrimaryModel() || fake.prompt != defaultProbePrompt || fake.maxTokens != defaultProbeMaxTokens || fake.reachable {
+		t.Fatalf("probe = %+v, want the default chat probe of the primary", fake)
+	}
+}
+
+func TestRunProbe_UsesConfiguredProbe(t *testing.T) {
+	m := newTestManager(t)
+	m.cfg.Agents.Failover.ProbeModel = "claude-haiku-4-5"
+	m.cfg.Agents.Failover.ProbePrompt = "ping"
+	m.cfg.Agents.Failover.ProbeMaxTokens = 16
+	fake := &probeProvider{}
+	m.SetProviderForModel("claude-haiku-4-5", fake)
+
+	m.RunProbe(context.Background())
+	if fake.model != "claude-haiku-4-5" || fake.prompt != "ping" || fake.maxTokens != 16 {
+		t.Fatalf("probe = %+v, want the configured chat probe", fake)
+	}
+
+	m.cfg.Agents.Failover.ProbeMode = "http"
+	fake.prompt = ""
+	if outcome := m.RunProbe(context.Background()); !outcome.Success || !fake.reachable || fake.prompt != "" {
+		t.Fatalf("http probe = %+v, want a reachability check without chat", fake)
+	}
+}
+
+func TestRecordProbeResult_PrefersRateLimitResetHints(t *testing.T) {
+	m := newTestManager(t)
+	_ = m.OnLLMRateLimited(m.PrimaryModel(), nil)
+
+	reset := time.Now().Add(20 * time.Minute).Truncate(time.Second)
+	outcome := m.recordProbeResult(m.PrimaryModel(), false, &providers.RateLimitError{
+		StatusCode:             429,
+		RateLimitRequestsReset: strconv.FormatInt(reset.Unix(), 10),
+	})
+	// The hint is earlier than the 300 minute hold and wins over it.
+	if !outcome.NextProbeAt.Equal(reset) {
+		t.Fatalf("NextProbeAt = %v, want the reset hint %v", outcome.NextProbeAt, reset)
+	}
+
+	outcome = m.recordProbeResult(m.PrimaryModel(), false, &providers.RateLimitError{StatusCode: 429})
+	if d := time.Until(outcome.NextProbeAt); d < 299*time.Minute {
+		t.Fatalf("without hints the next probe is in %v, want the hold", d)
+	}
+}
+
+func TestRecordProbeResult_BacksOffPerModel(t *testing.T) {
+	m := newTestManager(t)
+	_ = m.OnLLMRateLimited(m.PrimaryModel(), nil)
+
+	var delays []time.Duration
+	for i := 0; i < 5; i++ {
+		outcome := m.recordProbeResult("gpt-5-mini", false, errors.New("boom"))
+		delays = append(delays, time.Until(outcome.NextProbeAt).Round(time.Minute))
+	}
+	want := []time.Duration{10 * time.Minute, 20 * time.Minute, 40 * time.Minute, 80 * time.Minute, 80 * time.Minute}
+	if !slices.Equal(delays, want) {
+		t.Fatalf("backoff = %v, want %v", delays, want)
+	}
+
+	// Another model's failures don't stretch this one's backoff.
+	outcome := m.recordProbeResult(m.PrimaryModel(), false, errors.New("boom"))
+	if d := time.Until(outcome.NextProbeAt).Round(time.Minute); d != 10*time.Minute {
+		t.Fatalf("primary backoff = %v, want 10m", d)
+	}
+
+	m.recordProbeResult("gpt-5-mini", true, nil)
+	if n := m.Snapshot().ProbeFailures["gpt-5-mini"]; n != 0 {
+		t.Fatalf("a successful probe should reset the failure count, got %d", n)
+	}
+}
+
+func TestWithJitter_NeverEarlier(t *testing.T) {
+	m := newTestManager(t)
+	var gotMax time.Duration
+	m.jitter = func(max time.Duration) time.Duration { gotMax = max; return max / 2 }
+
+	now := time.Now()
+	next := m.withJitter(now, now.Add(time.Hour))
+	if gotMax != 6*time.Minute || !next.Equal(now.Add(time.Hour+3*time.Minute)) {
+		t.Fatalf("jitter max = %v, next = %v", gotMax, next.Sub(now))
+	}
+}
+
+type scriptedProvider struct {
+	calls []string
+	errs  map[string]error
+}
+
+func (p *scriptedProvider) Chat(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, model string, options map[string]interface{}) (*providers.LLMResponse, error) {
+	p.calls = append(p.calls, model)
+	if err := p.errs[model]; err != nil {
+		return nil, err
+	}
+	return &providers.LLMResponse{Content: model}, nil
+}
+
+func (p *scriptedProvider) GetDefaultModel() string { return "" }
+
+func TestNormalizeFallbackChain_GroupsSlots(t *testing.T) {
+	got := normalizeFallbackChain("primary", []string{
+		"gpt-5-mini",
+		" openrouter/a , openrouter/b,openrouter/a ",
+		"primary,openrouter/c",
+		"openrouter/a,openrouter/b",
+		" , ",
+	}, "")
+	want := []string{"gpt-5-mini", "openrouter/a,openrouter/b", "openrouter/c"}
+	if !slices.Equal(got, want) {
+		t.Fatalf("normalizeFallbackChain() = %v, want %v", got, want)
+	}
+}
+
+func TestSlotTriesAlternativesBeforeAdvancing(t *testing.T) {
+	m := newTestManager(t)
+	m.fallbacks = []string{"openrouter/a,openrouter/b", "gpt-5-mini"}
+	upstream := &scriptedProvider{errs: map[string]error{
+		"openrouter/a": &providers.RateLimitError{StatusCode: 429},
+	}}
+	m.SetProviderForModel("openrouter/a", upstream)
+	m.SetProviderForModel("openrouter/b", upstream)
+
+	if evt := m.OnLLMRateLimited(m.PrimaryModel(), nil); evt.ToModel != "openrouter/a,openrouter/b" {
+		t.Fatalf("switched to %q, want the grouped slot", evt.ToModel)
+	}
+	route, err := m.ResolveRoute()
+	if err != nil {
+		t.Fatalf("ResolveRoute() error: %v", err)
+	}
+	resp, err := route.Provider.Chat(context.Background(), nil, nil, route.Model, nil)
+	if err != nil || resp.Content != "openrouter/b" {
+		t.Fatalf("Chat() = %v, %v; want openrouter/b after a is rate limited", resp, err)
+	}
+
+	// With the whole slot rate limited, the rate limit surfaces so the
+	// manager moves on to the next slot.
+	upstream.errs["openrouter/b"] = &providers.RateLimitError{StatusCode: 429}
+	_, err = route.Provider.Chat(context.Background(), nil, nil, route.Model, nil)
+	var rl *providers.RateLimitError
+	if !errors.As(err, &rl) {
+		t.Fatalf("Chat() error = %v, want RateLimitError", err)
+	}
+	if evt := m.OnLLMRateLimited(route.Model, err); evt.ToModel != "gpt-5-mini" {
+		t.Fatalf("switched to %q, want gpt-5-mini", evt.ToModel)
+	}
+}
+
+func TestCircuitBreaker_SkipsOpenModel(t *testing.T) {
+	m := newTestManager(t)
+	m.cfg.Agents.Failover.BreakerThreshold = 2
+
+	// gpt-5-mini is rate limited twice within the window and its breaker opens.
+	_ = m.OnLLMRateLimited(m.PrimaryModel(), nil)
+	_ = m.OnLLMRateLimited("gpt-5-mini", nil)
+	m.ResetToPrimary()
+	_ = m.OnLLMRateLimited(m.PrimaryModel(), nil)
+	_ = m.OnLLMRateLimited("gpt-5-mini", nil)
+	if b := m.Snapshot().Breakers["gpt-5-mini"]; !b.OpenUntil.After(time.Now()) {
+		t.Fatalf("breaker = %+v, want open", b)
+	}
+
+	// Back on the primary, the next rate limit skips the open gpt-5-mini.
+	m.ResetToPrimary()
+	if evt := m.OnLLMRateLimited(m.PrimaryModel(), nil); evt.ToModel != "gemini-2.5-flash" {
+		t.Fatalf("switched to %q, want gemini-2.5-flash past the open breaker", evt.ToModel)
+	}
+}
+
+func TestCircuitBreaker_ResolveRouteLeavesOpenModel(t *testing.T) {
+	m := newTestManager(t)
+	_ = m.OnLLMRateLimited(m.PrimaryModel(), nil)
+	epoch := m.Snapshot().SwitchEpoch
+
+	m.mu.Lock()
+	m.fs.Breakers = map[string]state.BreakerState{"gpt-5-mini": {OpenUntil: time.Now().Add(time.Minute)}}
+	m.mu.Unlock()
+	m.SetProviderForModel("gemini-2.5-flash", &probeProvider{})
+
+	route, err := m.ResolveRoute()
+	if err != nil {
+		t.Fatalf("ResolveRoute() error: %v", err)
+	}
+	if route.Model != "gemini-2.5-flash" || route.SwitchEpoch != epoch+1 || m.Snapshot().LastSwitchReason != "circuit_open" {
+		t.Fatalf("route = %s epoch %d, want gemini-2.5-flash in a new epoch", route.Model, route.SwitchEpoch)
+	}
+}
+
+func TestCircuitBreaker_WindowAndSuccessReset(t *testing.T) {
+	m := newTestManager(t)
+	m.cfg.Agents.Failover.BreakerThreshold = 2
+	m.cfg.Agents.Failover.BreakerWindowSeconds = 60
+	now := time.Now()
+
+	m.mu.Lock()
+	m.recordFailureLocked("gpt-5-mini", now.Add(-2*time.Minute))
+	m.recordFailureLocked("gpt-5-mini", now)
+	open := m.breakerOpenLocked("gpt-5-mini", now)
+	m.mu.Unlock()
+	if open {
+		t.Fatal("failures outside the window should not open the breaker")
+	}
+
+	m.OnLLMSuccess("gpt-5-mini")
+	if _, ok := m.Snapshot().Breakers["gpt-5-mini"]; ok {
+		t.Fatal("a success should clear the model's failures")
+	}
+}
@@ -0,0 +1,49 @@
+package failover
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/sipeed/picoclaw/pkg/providers"
+)
+
+// slotSeparator joins the models of one fallback slot, e.g.
+// "openrouter/a,openrouter/b".
+const slotSeparator = ","
+
+// slotModels splits a fallback slot into its models.
+func slotModels(slot string) []string {
+	var models []string
+	for _, model := range strings.Split(slot, slotSeparator) {
+		if model = strings.TrimSpace(model); model != "" {
+			models = append(models, model)
+		}
+	}
+	return models
+}
+
+// slotProvider serves a fallback slot listing several models. Each model
+// is tried in order while the previous one is rate limited, so the manager
+// only advances the chain once the whole slot is exhausted.
+type slotProvider struct {
+	models    []string
+	providers []providers.LLMProvider
+}
+
+func (p *slotProvider) Chat(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, model string, options map[string]interface{}) (*providers.LLMResponse, error) {
+	var err error
+	for i, slotModel := range p.models {
+		var resp *providers.LLMResponse
+		resp, err = p.providers[i].Chat(ctx, messages, tools, slotModel, options)
+		var rateLimitErr *providers.RateLimitError
+		if err == nil || !errors.As(err, &rateLimitErr) {
+			return resp, err
+		}
+	}
+	return nil, err
+}
+
+func (p *slotProvider) GetDefaultModel() string {
+	return p.models[0]
+}
@@ -0,0 +1,290 @@
+package gateway
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+const (
+	// apiChannel is the channel name requests are processed under.
+	apiChannel = "api"
+	// defaultModelID is reported when the client doesn't name a model.
+	defaultModelID = "picoclaw"
+	// maxRequestBytes bounds the request body size.
+	maxRequestBytes = 4 << 20
+)
+
+type chatMessage struct {
+	Role    string          `json:"role"`
+	Content json.RawMessage `json:"content"`
+}
+
+type chatCompletionRequest struct {
+	Model    string        `json:"model"`
+	Messages []chatMessage `json:"messages"`
+	Stream   bool          `json:"stream"`
+	// User keys the agent session, so each caller keeps its own history.
+	User string `json:"user"`
+}
+
+type chatCompletionChoice struct {
+	Index        int               `json:"index"`
+	Message      *assistantMessage `json:"message,omitempty"`
+	Delta        *assistantMessage `json:"delta,omitempty"`
+	FinishReason *string           `json:"finish_reason"`
+}
+
+type assistantMessage struct {
+	Role    string `json:"role,omitempty"`
+	Content string `json:"content,omitempty"`
+}
+
+type chatCompletionResponse struct {
+	ID      string                 `json:"id"`
+	Object  string                 `json:"object"`
+	Created int64                  `json:"created"`
+	Model   string                 `json:"model"`
+	Choices []chatCompletionChoice `json:"choices"`
+	Usage   *completionUsage       `json:"usage,omitempty"`
+}
+
+// completionUsage is reported as zeros: one agent turn may span many LLM
+// calls, which are tracked by the usage store instead.
+type completionUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+// handleChatCompletions maps a chat completions request onto one agent
+// turn. The agent keeps its own session history, so only the latest user
+// message is forwarded; earlier messages in the request are ignored.
+func (s *Server) handleChatCompletions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "Use POST")
+		return
+	}
+
+	var req chatCompletionRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBytes)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request_error", fmt.Sprintf("Invalid JSON body: %v", err))
+		return
+	}
+
+	content, ok := lastUserMessage(req.Messages)
+	if !ok {
+		writeError(w, http.StatusBadRequest, "invalid_request_error", "messages must include a user message with text content")
+		return
+	}
+
+	model := req.Model
+	if model == "" {
+		model = defaultModelID
+	}
+	chatID := sessionName(req.User)
+	sessionKey := apiChannel + ":" + chatID
+	id := "chatcmpl-" + randomID()
+
+	logger.InfoCF("gateway", "Chat completion request", map[string]interface{}{
+		"session_key": sessionKey,
+		"stream":      req.Stream,
+		"content_len": len(content),
+	})
+
+	if req.Stream {
+		s.streamCompletion(w, r, id, model, content, sessionKey, chatID)
+		return
+	}
+
+	reply, err := s.agent.ProcessDirectWithChannel(r.Context(), content, sessionKey, apiChannel, chatID)
+	if err != nil {
+		logger.ErrorCF("gateway", "Agent processing failed", map[string]interface{}{
+			"session_key": sessionKey,
+			"error":       err.Error(),
+		})
+		writeError(w, http.StatusInternalServerError, "agent_error", err.Error())
+		return
+	}
+
+	stop := "stop"
+	writeJSON(w, http.StatusOK, chatCompletionResponse{
+		ID:      id,
+		Object:  "chat.completion",
+		Created: time.Now().Unix(),
+		Model:   model,
+		Choices: []chatCompletionChoice{{
+			Message:      &assistantMessage{Role: "assistant", Content: reply},
+			FinishReason: &stop,
+		}},
+		Usage: &completionUsage{},
+	})
+}
+
+// streamCompletion answers with server-sent events. The agent doesn't
+// stream tokens, so the reply arrives as one content chunk; SSE comments
+// keep the connection alive while the agent works.
+func (s *Server) streamCompletion(w http.ResponseWriter, r *http.Request, id, model, content, sessionKey, chatID string) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "streaming_unsupported", "Streaming is not supported by this server")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	created := time.Now().Unix()
+	chunk := func(delta *assistantMessage, finish *string) chatCompletionResponse {
+		return chatCompletionResponse{
+			ID:      id,
+			Object:  "chat.completion.chunk",
+			Created: created,
+			Model:   model,
+			Choices: []chatCompletionChoice{{Delta: delta, FinishReason: finish}},
+		}
+	}
+
+	writeEvent(w, chunk(&assistantMessage{Role: "assistant"}, nil))
+	flusher.Flush()
+
+	type result struct {
+		reply string
+		err   error
+	}
+	done := make(chan result, 1)
+	go func() {
+		reply, err := s.agent.ProcessDirectWithChannel(r.Context(), content, sessionKey, apiChannel, chatID)
+		done <- result{reply, err}
+	}()
+
+	ticker := time.NewTicker(s.keepAlive)
+	defer ticker.Stop()
+
+	var res result
+wait:
+	for {
+		select {
+		case res = <-done:
+			break wait
+		case <-ticker.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	if res.err != nil {
+		logger.ErrorCF("gateway", "Agent processing failed", map[string]interface{}{
+			"session_key": sessionKey,
+			"error":       res.err.Error(),
+		})
+		writeEvent(w, map[string]interface{}{"error": apiError{Message: res.err.Error(), Type: "agent_error"}})
+	} else {
+		stop := "stop"
+		writeEvent(w, chunk(&assistantMessage{Content: res.reply}, nil))
+		writeEvent(w, chunk(&assistantMessage{}, &stop))
+	}
+	fmt.Fprint(w, "data: [DONE]\n\n")
+	flusher.Flush()
+}
+
+// handleModels lists the single model the gateway exposes, for clients that
+// probe /v1/models before chatting.
+func (s *Server) handleModels(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"object": "list",
+		"data": []map[string]interface{}{{
+			"id":       defaultModelID,
+			"object":   "model",
+			"owned_by": "picoclaw",
+		}},
+	})
+}
+
+// lastUserMessage returns the text of the most recent user message. Content
+// may be a string or an array of parts; only text parts are used.
+func lastUserMessage(messages []chatMessage) (string, bool) {
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role != "user" {
+			continue
+		}
+		text := strings.TrimSpace(messageText(messages[i].Content))
+		return text, text != ""
+	}
+	return "", false
+}
+
+func messageText(raw json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s
+	}
+	var parts []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	}
+	if err := json.Unmarshal(raw, &parts); err != nil {
+		return ""
+	}
+	var texts []string
+	for _, p := range parts {
+		if p.Type == "text" && p.Text != "" {
+			texts = append(texts, p.Text)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
+
+// sessionName derives the chat ID from the request's user field, keeping
+// only characters that are safe in session file names.
+func sessionName(user string) string {
+	var b strings.Builder
+	for _, r := range strings.TrimSpace(user) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+			b.WriteRune(r)
+		default:
+			b.WriteRune('_')
+		}
+	}
+	if b.Len() == 0 {
+		return "default"
+	}
+	return b.String()
+}
+
+func randomID() string {
+	b := make([]byte, 12)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+type apiError struct {
+	Message string `json:"message"`
+	Type    string `json:"type"`
+}
+
+func writeError(w http.ResponseWriter, status int, errType, message string) {
+	writeJSON(w, status, map[string]interface{}{"error": apiError{Message: message, Type: errType}})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeEvent(w http.ResponseWriter, v interface{}) {
+	data, _ := json.Marshal(v)
+	fmt.Fprintf(w, "data: %s\n\n", data)
+}
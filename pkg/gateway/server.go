@@ -0,0 +1,172 @@
+// Package gateway exposes the agent over an OpenAI-compatible HTTP API so
+// external tools can talk to picoclaw like any chat completions endpoint.
+package gateway
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/channels"
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// Processor runs a user message through the agent and returns its reply.
+// *agent.AgentLoop satisfies it.
+type Processor interface {
+	ProcessDirectWithChannel(ctx context.Context, content, sessionKey, channel, chatID string) (string, error)
+}
+
+// ChannelStatusProvider reports channel health for /health.
+// *channels.Manager satisfies it.
+type ChannelStatusProvider interface {
+	ChannelStatuses() []channels.Status
+}
+
+// Server serves the OpenAI-compatible API.
+type Server struct {
+	cfg        config.GatewayConfig
+	agent      Processor
+	channels   ChannelStatusProvider // nil = /health reports only liveness
+	httpServer *http.Server
+	keepAlive  time.Duration // SSE comment interval while the agent works
+}
+
+// NewServer creates a gateway server for the given agent.
+func NewServer(cfg config.GatewayConfig, agent Processor) *Server {
+	return &Server{
+		cfg:       cfg,
+		agent:     agent,
+		keepAlive: 15 * time.Second,
+	}
+}
+
+// Handler returns the HTTP handler with all API routes.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/chat/completions", s.requireAPIKey(s.handleChatCompletions))
+	mux.HandleFunc("/v1/models", s.requireAPIKey(s.handleModels))
+	mux.HandleFunc("/health", s.handleHealth)
+	return mux
+}
+
+// SetChannelStatusProvider makes /health report channel health.
+func (s *Server) SetChannelStatusProvider(p ChannelStatusProvider) {
+	s.channels = p
+}
+
+type channelHealth struct {
+	Name        string    `json:"name"`
+	Running     bool      `json:"running"`
+	Degraded    bool      `json:"degraded"`
+	LastSuccess time.Time `json:"last_success"`
+	LastError   time.Time `json:"last_error"`
+}
+
+// handleHealth answers "ok" while the process is up. With a channel status
+// provider it returns JSON whose status is "degraded" when any channel is
+// stopped or last failed. Error messages are left out since /health needs
+// no API key.
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	if s.channels == nil {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("ok"))
+		return
+	}
+
+	status := "ok"
+	statuses := s.channels.ChannelStatuses()
+	report := make([]channelHealth, 0, len(statuses))
+	for _, st := range statuses {
+		if !st.Running || st.Degraded() {
+			status = "degraded"
+		}
+		report = append(report, channelHealth{
+			Name:        st.Name,
+			Running:     st.Running,
+			Degraded:    st.Degraded(),
+			LastSuccess: st.LastSuccess,
+			LastError:   st.LastError,
+		})
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"status":   status,
+		"channels": report,
+	})
+}
+
+// Start binds the listener and serves in the background. Without an API key
+// the gateway only listens on loopback addresses, since the agent can run
+// shell commands.
+func (s *Server) Start(ctx context.Context) error {
+	if s.cfg.APIKey == "" && !isLoopbackHost(s.cfg.Host) {
+		return fmt.Errorf("gateway api_key is required when listening on %q", s.cfg.Host)
+	}
+
+	addr := net.JoinHostPort(s.cfg.Host, fmt.Sprintf("%d", s.cfg.Port))
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+
+	s.httpServer = &http.Server{
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+		BaseContext:       func(net.Listener) context.Context { return ctx },
+	}
+
+	go func() {
+		logger.InfoCF("gateway", "OpenAI-compatible API listening", map[string]interface{}{
+			"addr": addr,
+		})
+		if err := s.httpServer.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.ErrorCF("gateway", "API server error", map[string]interface{}{
+				"error": err.Error(),
+			})
+		}
+	}()
+
+	return nil
+}
+
+// Stop shuts the server down, waiting briefly for in-flight requests.
+func (s *Server) Stop(ctx context.Context) error {
+	if s.httpServer == nil {
+		return nil
+	}
+	shutdownCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	return s.httpServer.Shutdown(shutdownCtx)
+}
+
+// requireAPIKey checks "Authorization: Bearer <key>" (what OpenAI clients
+// send) or "X-API-Key" when an API key is configured.
+func (s *Server) requireAPIKey(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.cfg.APIKey != "" {
+			key := r.Header.Get("X-API-Key")
+			if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+				key = strings.TrimPrefix(auth, "Bearer ")
+			}
+			if subtle.ConstantTimeCompare([]byte(key), []byte(s.cfg.APIKey)) != 1 {
+				writeError(w, http.StatusUnauthorized, "invalid_api_key", "Invalid or missing API key")
+				return
+			}
+		}
+		next(w, r)
+	}
+}
+
+func isLoopbackHost(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
@@ -0,0 +1,219 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/channels"
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+type fakeAgent struct {
+	mu         sync.Mutex
+	content    string
+	sessionKey string
+	channel    string
+	reply      string
+	err        error
+	delay      time.Duration
+}
+
+func (f *fakeAgent) ProcessDirectWithChannel(ctx context.Context, content, sessionKey, channel, chatID string) (string, error) {
+	f.mu.Lock()
+	f.content, f.sessionKey, f.channel = content, sessionKey, channel
+	f.mu.Unlock()
+	time.Sleep(f.delay)
+	return f.reply, f.err
+}
+
+func newTestServer(t *testing.T, apiKey string, agent *fakeAgent) *httptest.Server {
+	t.Helper()
+	s := NewServer(config.GatewayConfig{Host: "127.0.0.1", APIKey: apiKey}, agent)
+	s.keepAlive = 10 * time.Millisecond
+	ts := httptest.NewServer(s.Handler())
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func postCompletion(t *testing.T, url, apiKey, body string) *http.Response {
+	t.Helper()
+	req, _ := http.NewRequest(http.MethodPost, url+"/v1/chat/completions", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func TestChatCompletions_RequiresAPIKey(t *testing.T) {
+	ts := newTestServer(t, "secret", &fakeAgent{reply: "hi"})
+
+	body := `{"messages":[{"role":"user","content":"hello"}]}`
+	if resp := postCompletion(t, ts.URL, "", body); resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("missing key: status = %d, want 401", resp.StatusCode)
+	}
+	if resp := postCompletion(t, ts.URL, "wrong", body); resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("wrong key: status = %d, want 401", resp.StatusCode)
+	}
+	if resp := postCompletion(t, ts.URL, "secret", body); resp.StatusCode != http.StatusOK {
+		t.Fatalf("valid key: status = %d, want 200", resp.StatusCode)
+	}
+}
+
+func TestChatCompletions_NonStreaming(t *testing.T) {
+	agent := &fakeAgent{reply: "Hello from picoclaw"}
+	ts := newTestServer(t, "", agent)
+
+	resp := postCompletion(t, ts.URL, "", `{
+		"model": "gpt-4o",
+		"user": "alice@example.com",
+		"messages": [
+			{"role": "system", "content": "be nice"},
+			{"role": "user", "content": "earlier"},
+			{"role": "assistant", "content": "ok"},
+			{"role": "user", "content": [{"type": "text", "text": "what's up?"}]}
+		]
+	}`)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want 200", resp.StatusCode)
+	}
+
+	var out chatCompletionResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if out.Object != "chat.completion" || out.Model != "gpt-4o" || !strings.HasPrefix(out.ID, "chatcmpl-") {
+		t.Fatalf("unexpected envelope: %+v", out)
+	}
+	if len(out.Choices) != 1 || out.Choices[0].Message.Content != "Hello from picoclaw" || *out.Choices[0].FinishReason != "stop" {
+		t.Fatalf("unexpected choices: %+v", out.Choices)
+	}
+	if agent.content != "what's up?" {
+		t.Fatalf("agent got %q, want only the latest user message", agent.content)
+	}
+	if agent.sessionKey != "api:alice_example.com" || agent.channel != "api" {
+		t.Fatalf("session key = %q, channel = %q", agent.sessionKey, agent.channel)
+	}
+}
+
+func TestChatCompletions_Streaming(t *testing.T) {
+	agent := &fakeAgent{reply: "streamed reply", delay: 30 * time.Millisecond}
+	ts := newTestServer(t, "", agent)
+
+	resp := postCompletion(t, ts.URL, "", `{"stream": true, "messages": [{"role": "user", "content": "hi"}]}`)
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Content-Type = %q, want text/event-stream", ct)
+	}
+
+	var body strings.Builder
+	buf := make([]byte, 4096)
+	for {
+		n, err := resp.Body.Read(buf)
+		body.Write(buf[:n])
+		if err != nil {
+			break
+		}
+	}
+
+	var events []chatCompletionResponse
+	sawKeepAlive := false
+	for _, line := range strings.Split(body.String(), "\n") {
+		switch {
+		case strings.HasPrefix(line, ": keep-alive"):
+			sawKeepAlive = true
+		case line == "data: [DONE]":
+		case strings.HasPrefix(line, "data: "):
+			var ev chatCompletionResponse
+			if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &ev); err != nil {
+				t.Fatalf("bad event %q: %v", line, err)
+			}
+			events = append(events, ev)
+		}
+	}
+
+	if !strings.HasSuffix(body.String(), "data: [DONE]\n\n") {
+		t.Fatalf("stream should end with [DONE]:\n%s", body.String())
+	}
+	if !sawKeepAlive {
+		t.Fatal("expected keep-alive comments while the agent works")
+	}
+	if len(events) != 3 {
+		t.Fatalf("got %d events, want role, content and finish chunks:\n%s", len(events), body.String())
+	}
+	if events[0].Choices[0].Delta.Role != "assistant" || events[1].Choices[0].Delta.Content != "streamed reply" {
+		t.Fatalf("unexpected chunks: %+v", events)
+	}
+	if events[2].Choices[0].FinishReason == nil || *events[2].Choices[0].FinishReason != "stop" || events[2].Object != "chat.completion.chunk" {
+		t.Fatalf("unexpected final chunk: %+v", events[2])
+	}
+}
+
+func TestChatCompletions_Errors(t *testing.T) {
+	ts := newTestServer(t, "", &fakeAgent{err: errors.New("provider down")})
+
+	if resp := postCompletion(t, ts.URL, "", `{"messages":[{"role":"system","content":"x"}]}`); resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("no user message: status = %d, want 400", resp.StatusCode)
+	}
+	resp := postCompletion(t, ts.URL, "", `{"messages":[{"role":"user","content":"hi"}]}`)
+	if resp.StatusCode != http.StatusInternalServerError {
+		t.Fatalf("agent error: status = %d, want 500", resp.StatusCode)
+	}
+	var out struct {
+		Error apiError `json:"error"`
+	}
+	json.NewDecoder(resp.Body).Decode(&out)
+	if out.Error.Message != "provider down" {
+		t.Fatalf("error body = %+v", out)
+	}
+}
+
+func TestStart_RefusesPublicHostWithoutKey(t *testing.T) {
+	s := NewServer(config.GatewayConfig{Host: "0.0.0.0", Port: 0}, &fakeAgent{})
+	if err := s.Start(context.Background()); err == nil {
+		s.Stop(context.Background())
+		t.Fatal("Start() should refuse a public host without an API key")
+	}
+}
+
+type fakeChannels []channels.Status
+
+func (f fakeChannels) ChannelStatuses() []channels.Status { return f }
+
+func TestHealth_ReportsDegradedChannels(t *testing.T) {
+	s := NewServer(config.GatewayConfig{Host: "127.0.0.1"}, &fakeAgent{})
+	now := time.Now()
+	s.SetChannelStatusProvider(fakeChannels{
+		{Name: "discord", Running: true, LastSuccess: now},
+		{Name: "telegram", Running: true, LastSuccess: now.Add(-time.Minute), LastError: now, LastErrorMsg: "secret-token"},
+	})
+	ts := httptest.NewServer(s.Handler())
+	t.Cleanup(ts.Close)
+
+	resp, err := http.Get(ts.URL + "/health")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	var out struct {
+		Status   string          `json:"status"`
+		Channels []channelHealth `json:"channels"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if out.Status != "degraded" || len(out.Channels) != 2 || !out.Channels[1].Degraded || out.Channels[0].Degraded {
+		t.Fatalf("health = %+v, want telegram degraded", out)
+	}
+}
@@ -0,0 +1,60 @@
+package heartbeat
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/adhocore/gronx"
+)
+
+// Schedule is a named cron-style heartbeat trigger.
+// When Prompt is empty the HEARTBEAT.md task list is used.
+type Schedule struct {
+	Name   string
+	Expr   string
+	Prompt string
+}
+
+// validateSchedules checks every cron expression and fills in missing names.
+func validateSchedules(schedules []Schedule) ([]Schedule, error) {
+	valid := make([]Schedule, 0, len(schedules))
+	for i, s := range schedules {
+		s.Expr = strings.TrimSpace(s.Expr)
+		if s.Expr == "" {
+			continue
+		}
+		if !gronx.IsValid(s.Expr) {
+			return nil, fmt.Errorf("invalid heartbeat schedule %q: %q", s.Name, s.Expr)
+		}
+		if strings.TrimSpace(s.Name) == "" {
+			s.Name = fmt.Sprintf("schedule-%d", i+1)
+		}
+		valid = append(valid, s)
+	}
+	return valid, nil
+}
+
+// nextScheduledRun returns the earliest fire time strictly after now and the
+// schedules that are due at that time. Schedules that cannot be resolved are
+// skipped; an empty result means nothing will ever fire.
+func nextScheduledRun(schedules []Schedule, now time.Time) (time.Time, []Schedule) {
+	var next time.Time
+	var due []Schedule
+
+	for _, s := range schedules {
+		at, err := gronx.NextTickAfter(s.Expr, now, false)
+		if err != nil || !at.After(now) {
+			continue
+		}
+		switch {
+		case next.IsZero() || at.Before(next):
+			next = at
+			due = []Schedule{s}
+		case at.Equal(next):
+			due = append(due, s)
+		}
+	}
+
+	return next, due
+}
@@ -0,0 +1,85 @@
+package heartbeat
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/tools"
+)
+
+func TestNextScheduledRun_PicksEarliest(t *testing.T) {
+	now := time.Date(2026, 3, 10, 8, 30, 0, 0, time.Local)
+	schedules := []Schedule{
+		{Name: "morning", Expr: "0 9 * * *"},
+		{Name: "evening", Expr: "0 18 * * *"},
+	}
+
+	next, due := nextScheduledRun(schedules, now)
+
+	want := time.Date(2026, 3, 10, 9, 0, 0, 0, time.Local)
+	if !next.Equal(want) {
+		t.Fatalf("next = %v, want %v", next, want)
+	}
+	if len(due) != 1 || due[0].Name != "morning" {
+		t.Fatalf("due = %+v, want [morning]", due)
+	}
+}
+
+func TestNextScheduledRun_AfterFireMovesToNextDay(t *testing.T) {
+	fired := time.Date(2026, 3, 10, 9, 0, 0, 1000, time.Local)
+
+	next, due := nextScheduledRun([]Schedule{{Name: "morning", Expr: "0 9 * * *"}}, fired)
+
+	want := time.Date(2026, 3, 11, 9, 0, 0, 0, time.Local)
+	if !next.Equal(want) {
+		t.Fatalf("next = %v, want %v", next, want)
+	}
+	if len(due) != 1 {
+		t.Fatalf("due count = %d, want 1", len(due))
+	}
+}
+
+func TestNextScheduledRun_GroupsSimultaneous(t *testing.T) {
+	now := time.Date(2026, 3, 10, 8, 30, 0, 0, time.Local)
+	schedules := []Schedule{
+		{Name: "a", Expr: "0 9 * * *"},
+		{Name: "b", Expr: "0 */3 * * *"},
+	}
+
+	_, due := nextScheduledRun(schedules, now)
+	if len(due) != 2 {
+		t.Fatalf("due = %+v, want both schedules", due)
+	}
+}
+
+func TestValidateSchedules(t *testing.T) {
+	valid, err := validateSchedules([]Schedule{{Expr: "*/15 * * * *"}, {Expr: "  "}})
+	if err != nil {
+		t.Fatalf("validateSchedules() error: %v", err)
+	}
+	if len(valid) != 1 || valid[0].Name != "schedule-1" {
+		t.Fatalf("valid = %+v, want one schedule named schedule-1", valid)
+	}
+
+	if _, err := validateSchedules([]Schedule{{Name: "bad", Expr: "not a cron"}}); err == nil {
+		t.Fatal("expected error for invalid expression")
+	}
+}
+
+func TestExecuteSchedule_UsesSchedulePrompt(t *testing.T) {
+	hs := NewHeartbeatService(t.TempDir(), 30, true)
+	hs.stopChan = make(chan struct{}) // Enable for testing
+
+	var gotPrompt string
+	hs.SetHandler(func(prompt, channel, chatID string) *tools.ToolResult {
+		gotPrompt = prompt
+		return tools.SilentResult("ok")
+	})
+
+	hs.executeSchedule(Schedule{Name: "standup", Expr: "0 9 * * 1-5", Prompt: "Summarize today's calendar"})
+
+	if !strings.Contains(gotPrompt, "Summarize today's calendar") {
+		t.Fatalf("prompt = %q, want schedule prompt included", gotPrompt)
+	}
+}
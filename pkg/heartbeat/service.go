@@ -26,6 +26,12 @@ const (
 	defaultIntervalMinutes = 30
 )
 
+// Prompt modes for configured heartbeat prompts.
+const (
+	PromptModeRotate = "rotate" // one prompt per heartbeat, cycling through the list
+	PromptModeAll    = "all"    // every prompt on each heartbeat
+)
+
 // HeartbeatHandler is the function type for handling heartbeat.
 // It returns a ToolResult that can indicate async operations.
 // channel and chatID are derived from the last active user channel.
@@ -38,9 +44,18 @@ type HeartbeatService struct {
 	state     *state.Manager
 	handler   HeartbeatHandler
 	interval  time.Duration
+	schedules []Schedule
+	prompts   []string
+	mode      string
+	promptIdx int
 	enabled   bool
 	mu        sync.RWMutex
 	stopChan  chan struct{}
+
+	weatherFetch    WeatherFetcher
+	weatherLocation string
+	weatherCache    string
+	weatherAt       time.Time
 }
 
 // NewHeartbeatService creates a new heartbeat service
@@ -76,6 +91,51 @@ func (hs *HeartbeatService) SetHandler(handler HeartbeatHandler) {
 	hs.handler = handler
 }
 
+// SetStateManager shares the agent's state manager so the last active
+// channel recorded by the agent is visible to the heartbeat.
+func (hs *HeartbeatService) SetStateManager(sm *state.Manager) {
+	if sm == nil {
+		return
+	}
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+	hs.state = sm
+}
+
+// SetPrompts configures user-defined heartbeat prompts that replace the
+// HEARTBEAT.md task list. mode is PromptModeRotate (default) or PromptModeAll.
+func (hs *HeartbeatService) SetPrompts(prompts []string, mode string) {
+	cleaned := make([]string, 0, len(prompts))
+	for _, p := range prompts {
+		if p = strings.TrimSpace(p); p != "" {
+			cleaned = append(cleaned, p)
+		}
+	}
+	if mode != PromptModeAll {
+		mode = PromptModeRotate
+	}
+
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+	hs.prompts = cleaned
+	hs.mode = mode
+	hs.promptIdx = 0
+}
+
+// SetSchedules configures cron-style schedules. When at least one schedule is
+// set it replaces the fixed interval ticker.
+func (hs *HeartbeatService) SetSchedules(schedules []Schedule) error {
+	valid, err := validateSchedules(schedules)
+	if err != nil {
+		return err
+	}
+
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+	hs.schedules = valid
+	return nil
+}
+
 // Start begins the heartbeat service
 func (hs *HeartbeatService) Start() error {
 	hs.mu.Lock()
@@ -92,6 +152,21 @@ func (hs *HeartbeatService) Start() error {
 	}
 
 	hs.stopChan = make(chan struct{})
+
+	if len(hs.schedules) > 0 {
+		schedules := append([]Schedule(nil), hs.schedules...)
+		go hs.runScheduleLoop(hs.stopChan, schedules)
+
+		names := make([]string, 0, len(schedules))
+		for _, s := range schedules {
+			names = append(names, s.Name)
+		}
+		logger.InfoCF("heartbeat", "Heartbeat service started", map[string]any{
+			"schedules": names,
+		})
+		return nil
+	}
+
 	go hs.runLoop(hs.stopChan)
 
 	logger.InfoCF("heartbeat", "Heartbeat service started", map[string]any{
@@ -142,8 +217,109 @@ func (hs *HeartbeatService) runLoop(stopChan chan struct{}) {
 	}
 }
 
+// runScheduleLoop fires heartbeats at the next due time of each cron schedule
+func (hs *HeartbeatService) runScheduleLoop(stopChan chan struct{}, schedules []Schedule) {
+	for {
+		now := time.Now()
+		next, due := nextScheduledRun(schedules, now)
+		if len(due) == 0 {
+			logger.WarnC("heartbeat", "No heartbeat schedule has a future fire time; scheduler idle")
+			return
+		}
+
+		timer := time.NewTimer(next.Sub(now))
+		select {
+		case <-stopChan:
+			timer.Stop()
+			return
+		case <-timer.C:
+			for _, s := range due {
+				hs.executeSchedule(s)
+			}
+		}
+	}
+}
+
+// executeSchedule performs a heartbeat for a named schedule, using its own
+// prompt when set and HEARTBEAT.md otherwise.
+func (hs *HeartbeatService) executeSchedule(s Schedule) {
+	logger.DebugCF("heartbeat", "Executing scheduled heartbeat", map[string]any{
+		"schedule": s.Name,
+		"expr":     s.Expr,
+	})
+
+	if strings.TrimSpace(s.Prompt) == "" {
+		hs.executeHeartbeat()
+		return
+	}
+	hs.runHeartbeat(hs.formatPrompt(s.Prompt))
+}
+
 // executeHeartbeat performs a single heartbeat check
 func (hs *HeartbeatService) executeHeartbeat() {
+	if prompts := hs.nextPrompts(); len(prompts) > 0 {
+		hs.executeConfiguredPrompts(prompts)
+		return
+	}
+	hs.runHeartbeat(hs.buildPrompt())
+}
+
+// nextPrompts returns the configured prompts to run for this heartbeat,
+// advancing the rotation when in rotate mode.
+func (hs *HeartbeatService) nextPrompts() []string {
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+
+	if len(hs.prompts) == 0 {
+		return nil
+	}
+	if hs.mode == PromptModeAll {
+		return append([]string(nil), hs.prompts...)
+	}
+	p := hs.prompts[hs.promptIdx%len(hs.prompts)]
+	hs.promptIdx = (hs.promptIdx + 1) % len(hs.prompts)
+	return []string{p}
+}
+
+// executeConfiguredPrompts runs each configured prompt against the last
+// recorded channel. Nothing runs until a user channel has been recorded.
+func (hs *HeartbeatService) executeConfiguredPrompts(prompts []string) {
+	channel, chatID := hs.resolveLastTarget()
+	if channel == "" || chatID == "" {
+		logger.InfoC("heartbeat", "No last channel recorded yet, skipping configured heartbeat prompts")
+		hs.logInfo("No last channel recorded, skipped %d configured prompt(s)", len(prompts))
+		return
+	}
+
+	for _, p := range prompts {
+		hs.dispatch(hs.formatPrompt(p), channel, chatID)
+	}
+}
+
+// resolveLastTarget returns the platform and chat ID of the last active user
+// channel, falling back to the separately recorded last chat ID.
+func (hs *HeartbeatService) resolveLastTarget() (string, string) {
+	hs.mu.RLock()
+	sm := hs.state
+	hs.mu.RUnlock()
+
+	lastChannel := sm.GetLastChannel()
+	if lastChannel != "" && !strings.Contains(lastChannel, ":") {
+		if lastChatID := sm.GetLastChatID(); lastChatID != "" {
+			lastChannel = lastChannel + ":" + lastChatID
+		}
+	}
+	return hs.parseLastChannel(lastChannel)
+}
+
+// runHeartbeat dispatches a prepared heartbeat prompt to the last channel
+func (hs *HeartbeatService) runHeartbeat(prompt string) {
+	channel, chatID := hs.resolveLastTarget()
+	hs.dispatch(prompt, channel, chatID)
+}
+
+// dispatch sends a prepared heartbeat prompt to the handler for a target
+func (hs *HeartbeatService) dispatch(prompt, channel, chatID string) {
 	hs.mu.RLock()
 	enabled := hs.enabled
 	handler := hs.handler
@@ -159,7 +335,6 @@ func (hs *HeartbeatService) executeHeartbeat() {
 
 	logger.DebugC("heartbeat", "Executing heartbeat")
 
-	prompt := hs.buildPrompt()
 	if prompt == "" {
 		logger.InfoC("heartbeat", "No heartbeat prompt (HEARTBEAT.md empty or missing)")
 		return
@@ -170,12 +345,8 @@ func (hs *HeartbeatService) executeHeartbeat() {
 		return
 	}
 
-	// Get last channel info for context
-	lastChannel := hs.state.GetLastChannel()
-	channel, chatID := hs.parseLastChannel(lastChannel)
-
 	// Debug log for channel resolution
-	hs.logInfo("Resolved channel: %s, chatID: %s (from lastChannel: %s)", channel, chatID, lastChannel)
+	hs.logInfo("Resolved channel: %s, chatID: %s", channel, chatID)
 
 	result := handler(prompt, channel, chatID)
 
@@ -207,9 +378,9 @@ func (hs *HeartbeatService) executeHeartbeat() {
 
 	// Send result to user
 	if result.ForUser != "" {
-		hs.sendResponse(result.ForUser)
+		hs.sendResponseTo(channel, chatID, result.ForUser)
 	} else if result.ForLLM != "" {
-		hs.sendResponse(result.ForLLM)
+		hs.sendResponseTo(channel, chatID, result.ForLLM)
 	}
 
 	hs.logInfo("Heartbeat completed: %s", result.ForLLM)
@@ -234,17 +405,25 @@ func (hs *HeartbeatService) buildPrompt() string {
 		return ""
 	}
 
-	now := time.Now().Format("2006-01-02 15:04:05")
+	return hs.formatPrompt(content)
+}
+
+// formatPrompt wraps task content in the standard heartbeat instructions
+func (hs *HeartbeatService) formatPrompt(content string) string {
+	header := "Current time: " + time.Now().Format("2006-01-02 15:04:05")
+	if weather := hs.weatherContext(); weather != "" {
+		header += "\n" + weather
+	}
 	return fmt.Sprintf(`# Heartbeat Check
 
-Current time: %s
+%s
 
 You are a proactive AI assistant. This is a scheduled heartbeat check.
 Review the following tasks and execute any necessary actions using available skills.
 If there is nothing that requires attention, respond ONLY with: HEARTBEAT_OK
 
 %s
-`, now, content)
+`, header, content)
 }
 
 // createDefaultHeartbeatTemplate creates the default HEARTBEAT.md file
@@ -282,8 +461,8 @@ Add your heartbeat tasks below this line:
 	}
 }
 
-// sendResponse sends the heartbeat response to the last channel
-func (hs *HeartbeatService) sendResponse(response string) {
+// sendResponseTo sends the heartbeat response to the given channel
+func (hs *HeartbeatService) sendResponseTo(platform, userID, response string) {
 	hs.mu.RLock()
 	msgBus := hs.bus
 	hs.mu.RUnlock()
@@ -293,17 +472,9 @@ func (hs *HeartbeatService) sendResponse(response string) {
 		return
 	}
 
-	// Get last channel from state
-	lastChannel := hs.state.GetLastChannel()
-	if lastChannel == "" {
-		hs.logInfo("No last channel recorded, heartbeat result not sent")
-		return
-	}
-
-	platform, userID := hs.parseLastChannel(lastChannel)
-
 	// Skip internal channels that can't receive messages
 	if platform == "" || userID == "" {
+		hs.logInfo("No last channel recorded, heartbeat result not sent")
 		return
 	}
 
@@ -3,6 +3,7 @@ package heartbeat
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -219,3 +220,77 @@ func TestHeartbeatFilePath(t *testing.T) {
 		t.Errorf("Expected HEARTBEAT.md at %s, but it doesn't exist", expectedPath)
 	}
 }
+
+func TestExecuteHeartbeat_ConfiguredPromptsRotate(t *testing.T) {
+	tmpDir := t.TempDir()
+	hs := NewHeartbeatService(tmpDir, 30, true)
+	hs.stopChan = make(chan struct{}) // Enable for testing
+	hs.state.SetLastChannel("telegram:42")
+	hs.SetPrompts([]string{"check battery", "check calendar"}, PromptModeRotate)
+
+	var prompts []string
+	hs.SetHandler(func(prompt, channel, chatID string) *tools.ToolResult {
+		if channel != "telegram" || chatID != "42" {
+			t.Errorf("routed to %s:%s, want telegram:42", channel, chatID)
+		}
+		prompts = append(prompts, prompt)
+		return tools.SilentResult("ok")
+	})
+
+	hs.executeHeartbeat()
+	hs.executeHeartbeat()
+	hs.executeHeartbeat()
+
+	if len(prompts) != 3 {
+		t.Fatalf("handler calls = %d, want 3", len(prompts))
+	}
+	want := []string{"check battery", "check calendar", "check battery"}
+	for i, w := range want {
+		if !strings.Contains(prompts[i], w) {
+			t.Errorf("call %d prompt missing %q", i, w)
+		}
+	}
+}
+
+func TestExecuteHeartbeat_ConfiguredPromptsAll(t *testing.T) {
+	tmpDir := t.TempDir()
+	hs := NewHeartbeatService(tmpDir, 30, true)
+	hs.stopChan = make(chan struct{}) // Enable for testing
+	hs.state.SetLastChannel("slack:C1")
+	hs.SetPrompts([]string{"one", "two", " "}, PromptModeAll)
+
+	calls := 0
+	hs.SetHandler(func(prompt, channel, chatID string) *tools.ToolResult {
+		calls++
+		return tools.SilentResult("ok")
+	})
+
+	hs.executeHeartbeat()
+
+	if calls != 2 {
+		t.Fatalf("handler calls = %d, want 2", calls)
+	}
+}
+
+func TestExecuteHeartbeat_ConfiguredPromptsSkipWithoutChannel(t *testing.T) {
+	tmpDir := t.TempDir()
+	hs := NewHeartbeatService(tmpDir, 30, true)
+	hs.stopChan = make(chan struct{}) // Enable for testing
+	hs.SetPrompts([]string{"check battery"}, PromptModeRotate)
+
+	called := false
+	hs.SetHandler(func(prompt, channel, chatID string) *tools.ToolResult {
+		called = true
+		return tools.SilentResult("ok")
+	})
+
+	hs.executeHeartbeat()
+
+	if called {
+		t.Fatal("handler should not run before any channel is recorded")
+	}
+	data, err := os.ReadFile(filepath.Join(tmpDir, "heartbeat.log"))
+	if err != nil || !strings.Contains(string(data), "No last channel recorded") {
+		t.Fatalf("expected skip to be logged, got %q (err=%v)", string(data), err)
+	}
+}
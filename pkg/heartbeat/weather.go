@@ -0,0 +1,100 @@
+package heartbeat
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+const (
+	weatherFetchTimeout = 10 * time.Second
+	weatherCacheTTL     = 15 * time.Minute
+)
+
+// WeatherFetcher returns a short, human-readable weather summary for a
+// location. An empty location means "wherever this device is".
+type WeatherFetcher func(ctx context.Context, location string) (string, error)
+
+// NewWttrFetcher returns a WeatherFetcher backed by wttr.in, which needs no
+// API key and falls back to IP geolocation when no location is given.
+func NewWttrFetcher() WeatherFetcher {
+	client := &http.Client{Timeout: weatherFetchTimeout}
+	return func(ctx context.Context, location string) (string, error) {
+		endpoint := "https://wttr.in/" + url.PathEscape(location) + "?format=%l:+%C+%t+(feels+like+%f),+humidity+%h,+wind+%w"
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+		if err != nil {
+			return "", err
+		}
+		req.Header.Set("User-Agent", "picoclaw")
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+
+		body, err := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		if err != nil {
+			return "", err
+		}
+		if resp.StatusCode != http.StatusOK {
+			return "", fmt.Errorf("weather service returned %d", resp.StatusCode)
+		}
+		return strings.TrimSpace(string(body)), nil
+	}
+}
+
+// SetWeather enables weather enrichment of heartbeat prompts. A nil fetcher
+// disables it.
+func (hs *HeartbeatService) SetWeather(location string, fetch WeatherFetcher) {
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+	hs.weatherLocation = strings.TrimSpace(location)
+	hs.weatherFetch = fetch
+	hs.weatherCache = ""
+	hs.weatherAt = time.Time{}
+}
+
+// weatherContext returns the weather section for the heartbeat prompt, or ""
+// when disabled or the fetch fails. Results are cached briefly so "all"
+// prompt mode doesn't fetch once per prompt.
+func (hs *HeartbeatService) weatherContext() string {
+	hs.mu.RLock()
+	fetch := hs.weatherFetch
+	location := hs.weatherLocation
+	cached, cachedAt := hs.weatherCache, hs.weatherAt
+	hs.mu.RUnlock()
+
+	if fetch == nil {
+		return ""
+	}
+	if cached != "" && time.Since(cachedAt) < weatherCacheTTL {
+		return cached
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), weatherFetchTimeout)
+	defer cancel()
+	summary, err := fetch(ctx, location)
+	if err != nil || strings.TrimSpace(summary) == "" {
+		reason := "empty response"
+		if err != nil {
+			reason = err.Error()
+		}
+		logger.WarnCF("heartbeat", "Weather fetch failed, continuing without it",
+			map[string]interface{}{"location": location, "error": reason})
+		hs.logError("Weather fetch failed: %s", reason)
+		return ""
+	}
+
+	section := "Current weather: " + strings.TrimSpace(summary)
+	hs.mu.Lock()
+	hs.weatherCache, hs.weatherAt = section, time.Now()
+	hs.mu.Unlock()
+	return section
+}
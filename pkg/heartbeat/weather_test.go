@@ -0,0 +1,58 @@
+package heartbeat
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestFormatPrompt_IncludesWeather(t *testing.T) {
+	hs := NewHeartbeatService(t.TempDir(), 30, true)
+
+	var gotLocation string
+	calls := 0
+	hs.SetWeather("Berlin", func(ctx context.Context, location string) (string, error) {
+		gotLocation = location
+		calls++
+		return "Berlin: Sunny +18°C", nil
+	})
+
+	prompt := hs.formatPrompt("- Check calendar")
+	if !strings.Contains(prompt, "Current weather: Berlin: Sunny +18°C") {
+		t.Fatalf("prompt missing weather context:\n%s", prompt)
+	}
+	if !strings.Contains(prompt, "- Check calendar") {
+		t.Fatal("prompt should still include the task content")
+	}
+	if gotLocation != "Berlin" {
+		t.Fatalf("fetcher location = %q, want Berlin", gotLocation)
+	}
+
+	hs.formatPrompt("- Another prompt")
+	if calls != 1 {
+		t.Fatalf("fetcher called %d times, want cached result reused", calls)
+	}
+}
+
+func TestFormatPrompt_WeatherFailureIsSkipped(t *testing.T) {
+	hs := NewHeartbeatService(t.TempDir(), 30, true)
+	hs.SetWeather("", func(ctx context.Context, location string) (string, error) {
+		return "", errors.New("network unreachable")
+	})
+
+	prompt := hs.formatPrompt("- Check calendar")
+	if strings.Contains(prompt, "weather") {
+		t.Fatalf("failed fetch should not add weather context:\n%s", prompt)
+	}
+	if !strings.Contains(prompt, "Current time:") || !strings.Contains(prompt, "- Check calendar") {
+		t.Fatalf("prompt should be unchanged apart from weather:\n%s", prompt)
+	}
+}
+
+func TestFormatPrompt_NoWeatherByDefault(t *testing.T) {
+	hs := NewHeartbeatService(t.TempDir(), 30, true)
+	if strings.Contains(hs.formatPrompt("- task"), "weather") {
+		t.Fatal("weather should be off unless configured")
+	}
+}
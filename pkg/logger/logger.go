@@ -7,6 +7,7 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -31,10 +32,11 @@ var (
 		FATAL: "FATAL",
 	}
 
-	currentLevel = INFO
-	logger       *Logger
-	once         sync.Once
-	mu           sync.RWMutex
+	currentLevel    = INFO
+	componentLevels = map[string]LogLevel{}
+	logger          *Logger
+	once            sync.Once
+	mu              sync.RWMutex
 )
 
 type Logger struct {
@@ -75,6 +77,67 @@ func GetLevel() LogLevel {
 	return currentLevel
 }
 
+// SetComponentLevel overrides the global level for one component (e.g.
+// "telegram"), in either direction.
+func SetComponentLevel(component string, level LogLevel) {
+	mu.Lock()
+	defer mu.Unlock()
+	componentLevels[component] = level
+}
+
+// ClearComponentLevel removes a component's override so it follows the
+// global level again.
+func ClearComponentLevel(component string) {
+	mu.Lock()
+	defer mu.Unlock()
+	delete(componentLevels, component)
+}
+
+// ComponentLevels returns a copy of the per-component overrides.
+func ComponentLevels() map[string]LogLevel {
+	mu.RLock()
+	defer mu.RUnlock()
+	out := make(map[string]LogLevel, len(componentLevels))
+	for component, level := range componentLevels {
+		out[component] = level
+	}
+	return out
+}
+
+// effectiveLevel returns the component's override, or the global level.
+func effectiveLevel(component string) LogLevel {
+	mu.RLock()
+	defer mu.RUnlock()
+	if level, ok := componentLevels[component]; ok {
+		return level
+	}
+	return currentLevel
+}
+
+// String returns the level's name, e.g. "INFO".
+func (l LogLevel) String() string {
+	if name, ok := logLevelNames[l]; ok {
+		return name
+	}
+	return fmt.Sprintf("LogLevel(%d)", int(l))
+}
+
+// ParseLevel parses a level name (debug, info, warn or error; any case).
+// "warning" is accepted for warn.
+func ParseLevel(s string) (LogLevel, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "debug":
+		return DEBUG, nil
+	case "info":
+		return INFO, nil
+	case "warn", "warning":
+		return WARN, nil
+	case "error":
+		return ERROR, nil
+	}
+	return INFO, fmt.Errorf("unknown log level %q (want debug, info, warn or error)", s)
+}
+
 func EnableFileLogging(filePath string) error {
 	return EnableFileLoggingWithRotation(filePath, false, 0, 0)
 }
@@ -128,6 +191,17 @@ func EnableFileLoggingWithRotation(filePath string, rotationEnabled bool, maxSiz
 	return nil
 }
 
+// FilePath returns the path of the active log file, or "" when file
+// logging is disabled.
+func FilePath() string {
+	mu.RLock()
+	defer mu.RUnlock()
+	if logger.file == nil {
+		return ""
+	}
+	return logger.filePath
+}
+
 func DisableFileLogging() {
 	mu.Lock()
 	defer mu.Unlock()
@@ -237,10 +311,11 @@ func (l *Logger) cleanOldRotatedFiles() {
 }
 
 func logMessage(level LogLevel, component string, message string, fields map[string]interface{}) {
-	if level < currentLevel {
+	if level < effectiveLevel(component) {
 		return
 	}
 
+	fields = compactFields(fields)
 	entry := LogEntry{
 		Level:     logLevelNames[level],
 		Timestamp: time.Now().UTC().Format(time.RFC3339),
@@ -256,6 +331,19 @@ func logMessage(level LogLevel, component string, message string, fields map[str
 		}
 	}
 
+	if level != FATAL && !sampler.allow(level, entry) {
+		return
+	}
+	writeEntry(level, entry)
+
+	if level == FATAL {
+		DisableRemoteLogging(2 * time.Second)
+		os.Exit(1)
+	}
+}
+
+// writeEntry writes entry to the log file, when enabled, and to stderr.
+func writeEntry(level LogLevel, entry LogEntry) {
 	if logger.file != nil {
 		// Check if rotation is needed
 		if logger.shouldRotate() {
@@ -275,22 +363,22 @@ func logMessage(level LogLevel, component string, message string, fields map[str
 	}
 
 	var fieldStr string
-	if len(fields) > 0 {
-		fieldStr = " " + formatFields(fields)
+	if len(entry.Fields) > 0 {
+		fieldStr = " " + formatFields(entry.Fields)
 	}
 
 	logLine := fmt.Sprintf("[%s] [%s]%s %s%s",
 		entry.Timestamp,
 		logLevelNames[level],
-		formatComponent(component),
-		message,
+		formatComponent(entry.Component),
+		entry.Message,
 		fieldStr,
 	)
 
 	log.Println(logLine)
 
-	if level == FATAL {
-		os.Exit(1)
+	if sink := remote.Load(); sink != nil {
+		sink.enqueue(entry)
 	}
 }
 
@@ -301,10 +389,31 @@ func formatComponent(component string) string {
 	return fmt.Sprintf(" %s:", component)
 }
 
-func formatFields(fields map[string]interface{}) string {
-	var parts []string
+// compactFields drops nil and empty-string values, returning nil when
+// nothing is left, so entries never carry an empty "{}".
+func compactFields(fields map[string]interface{}) map[string]interface{} {
+	var out map[string]interface{}
 	for k, v := range fields {
-		parts = append(parts, fmt.Sprintf("%s=%v", k, v))
+		if v == nil || v == "" {
+			continue
+		}
+		if out == nil {
+			out = make(map[string]interface{}, len(fields))
+		}
+		out[k] = v
+	}
+	return out
+}
+
+func formatFields(fields map[string]interface{}) string {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys) // Stable order for reading and grepping
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		parts = append(parts, fmt.Sprintf("%s=%v", k, fields[k]))
 	}
 	return fmt.Sprintf("{%s}", strings.Join(parts, ", "))
 }
@@ -137,3 +137,64 @@ func TestLoggerHelperFunctions(t *testing.T) {
 	DebugC("test", "Debug with component")
 	WarnF("Warning with fields", map[string]interface{}{"key": "value"})
 }
+
+func TestCompactFields(t *testing.T) {
+	if got := compactFields(map[string]interface{}{"a": nil, "b": ""}); got != nil {
+		t.Errorf("compactFields(empty values) = %v, want nil", got)
+	}
+	if got := compactFields(nil); got != nil {
+		t.Errorf("compactFields(nil) = %v, want nil", got)
+	}
+	got := compactFields(map[string]interface{}{"tool": "exec", "error": "", "duration": 0})
+	if len(got) != 2 || got["tool"] != "exec" || got["duration"] != 0 {
+		t.Errorf("compactFields kept %v, want tool and duration", got)
+	}
+}
+
+func TestFormatFieldsSorted(t *testing.T) {
+	got := formatFields(map[string]interface{}{"b": 2, "a": 1, "c": "x"})
+	if got != "{a=1, b=2, c=x}" {
+		t.Errorf("formatFields = %q, want {a=1, b=2, c=x}", got)
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	for input, want := range map[string]LogLevel{"debug": DEBUG, "INFO": INFO, " warn ": WARN, "warning": WARN, "Error": ERROR} {
+		got, err := ParseLevel(input)
+		if err != nil || got != want {
+			t.Errorf("ParseLevel(%q) = %v, %v; want %v", input, got, err, want)
+		}
+	}
+	if _, err := ParseLevel("verbose"); err == nil {
+		t.Error("ParseLevel(verbose) succeeded, want error")
+	}
+}
+
+func TestComponentLevels(t *testing.T) {
+	initial := GetLevel()
+	defer SetLevel(initial)
+	defer ClearComponentLevel("telegram")
+	defer ClearComponentLevel("failover")
+
+	SetLevel(INFO)
+	SetComponentLevel("telegram", DEBUG)
+	SetComponentLevel("failover", ERROR)
+
+	if got := effectiveLevel("telegram"); got != DEBUG {
+		t.Errorf("effectiveLevel(telegram) = %v, want DEBUG", got)
+	}
+	if got := effectiveLevel("failover"); got != ERROR {
+		t.Errorf("effectiveLevel(failover) = %v, want ERROR", got)
+	}
+	if got := effectiveLevel("agent"); got != INFO {
+		t.Errorf("effectiveLevel(agent) = %v, want the global INFO", got)
+	}
+
+	ClearComponentLevel("telegram")
+	if got := effectiveLevel("telegram"); got != INFO {
+		t.Errorf("effectiveLevel(telegram) after clear = %v, want INFO", got)
+	}
+	if got := ComponentLevels(); len(got) != 1 || got["failover"] != ERROR {
+		t.Errorf("ComponentLevels() = %v, want only failover", got)
+	}
+}
@@ -0,0 +1,285 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultRemoteBufferSize    = 1000
+	defaultRemoteFlushInterval = 2 * time.Second
+	remoteBatchSize            = 100
+	remoteRetryBase            = time.Second
+	remoteRetryMax             = 30 * time.Second
+	remoteSendTimeout          = 10 * time.Second
+)
+
+// RemoteOptions configures the remote log sink.
+type RemoteOptions struct {
+	// URL is an http(s) endpoint that receives POSTed JSON arrays of
+	// entries, or a syslog server as udp://host:port, tcp://host:port or
+	// syslog://host:port (UDP).
+	URL string
+	// Headers are added to HTTP requests, e.g. an Authorization token.
+	Headers map[string]string
+	// BufferSize bounds the entries queued while the sink is slow or
+	// unreachable; beyond it new entries are dropped and counted.
+	BufferSize int
+	// FlushInterval is how often queued entries are sent.
+	FlushInterval time.Duration
+}
+
+// remote is the active sink, or nil.
+var remote atomic.Pointer[remoteSink]
+
+// EnableRemoteLogging ships every written entry to opts.URL as well as the
+// console and log file. Entries are queued and sent in batches by a
+// background goroutine, retrying with backoff, so logging never waits on
+// the network.
+func EnableRemoteLogging(opts RemoteOptions) error {
+	send, err := remoteSender(opts)
+	if err != nil {
+		return err
+	}
+	sink := newRemoteSink(send, opts)
+	if previous := remote.Swap(sink); previous != nil {
+		previous.close(remoteSendTimeout)
+	}
+	go sink.run()
+	return nil
+}
+
+// DisableRemoteLogging stops the remote sink, sending what is queued for
+// up to timeout.
+func DisableRemoteLogging(timeout time.Duration) {
+	if sink := remote.Swap(nil); sink != nil {
+		sink.close(timeout)
+	}
+}
+
+// remoteSender builds the send function for the URL's scheme.
+func remoteSender(opts RemoteOptions) (func([]LogEntry) error, error) {
+	u, err := url.Parse(opts.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid remote log URL: %w", err)
+	}
+	switch u.Scheme {
+	case "http", "https":
+		client := &http.Client{Timeout: remoteSendTimeout}
+		return func(entries []LogEntry) error {
+			return postEntries(client, opts.URL, opts.Headers, entries)
+		}, nil
+	case "udp", "tcp", "syslog":
+		if u.Host == "" {
+			return nil, fmt.Errorf("remote log URL %q has no host", opts.URL)
+		}
+		network := u.Scheme
+		if network == "syslog" {
+			network = "udp"
+		}
+		hostname, _ := os.Hostname()
+		return func(entries []LogEntry) error {
+			return sendSyslog(network, u.Host, hostname, entries)
+		}, nil
+	}
+	return nil, fmt.Errorf("unsupported remote log URL scheme %q (want http, https, udp, tcp or syslog)", u.Scheme)
+}
+
+func postEntries(client *http.Client, endpoint string, headers map[string]string, entries []LogEntry) error {
+	body, err := json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("remote log endpoint returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// syslogSeverity maps levels to RFC 5424 severities.
+var syslogSeverity = map[string]int{"DEBUG": 7, "INFO": 6, "WARN": 4, "ERROR": 3, "FATAL": 2}
+
+// formatSyslog renders entry as an RFC 5424 message from the "user"
+// facility, with the JSON entry as the message.
+func formatSyslog(hostname string, entry LogEntry) string {
+	severity, ok := syslogSeverity[entry.Level]
+	if !ok {
+		severity = 6
+	}
+	if hostname == "" {
+		hostname = "-"
+	}
+	payload, _ := json.Marshal(entry)
+	return fmt.Sprintf("<%d>1 %s %s picoclaw - - - %s", 8+severity, entry.Timestamp, hostname, payload)
+}
+
+func sendSyslog(network, address, hostname string, entries []LogEntry) error {
+	conn, err := net.DialTimeout(network, address, remoteSendTimeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(remoteSendTimeout))
+	for _, entry := range entries {
+		msg := formatSyslog(hostname, entry)
+		if network == "tcp" {
+			msg += "\n"
+		}
+		if _, err := conn.Write([]byte(msg)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// remoteSink queues entries and sends them in batches from run.
+type remoteSink struct {
+	send          func([]LogEntry) error
+	entries       chan LogEntry
+	bufferSize    int
+	flushInterval time.Duration
+	retryBase     time.Duration
+	dropped       atomic.Int64
+	done          chan struct{}
+	stopped       chan struct{}
+}
+
+func newRemoteSink(send func([]LogEntry) error, opts RemoteOptions) *remoteSink {
+	bufferSize := opts.BufferSize
+	if bufferSize <= 0 {
+		bufferSize = defaultRemoteBufferSize
+	}
+	flushInterval := opts.FlushInterval
+	if flushInterval <= 0 {
+		flushInterval = defaultRemoteFlushInterval
+	}
+	return &remoteSink{
+		send:          send,
+		entries:       make(chan LogEntry, bufferSize),
+		bufferSize:    bufferSize,
+		flushInterval: flushInterval,
+		retryBase:     remoteRetryBase,
+		done:          make(chan struct{}),
+		stopped:       make(chan struct{}),
+	}
+}
+
+// enqueue never blocks: when the queue is full the entry is dropped and
+// counted, and the count is reported once sending recovers.
+func (s *remoteSink) enqueue(entry LogEntry) {
+	select {
+	case s.entries <- entry:
+	default:
+		s.dropped.Add(1)
+	}
+}
+
+func (s *remoteSink) run() {
+	defer close(s.stopped)
+	ticker := time.NewTicker(s.flushInterval)
+	defer ticker.Stop()
+
+	var batch []LogEntry
+	var retryAt time.Time
+	backoff := time.Duration(0)
+	failing := false
+
+	flush := func(now time.Time) {
+		if len(batch) == 0 || now.Before(retryAt) {
+			return
+		}
+		if n := s.dropped.Swap(0); n > 0 {
+			batch = append(batch, LogEntry{
+				Level:     logLevelNames[WARN],
+				Timestamp: now.UTC().Format(time.RFC3339),
+				Component: "logger",
+				Message:   "Remote log sink dropped entries",
+				Fields:    map[string]interface{}{"dropped": n},
+			})
+		}
+		for len(batch) > 0 {
+			n := min(len(batch), remoteBatchSize)
+			if err := s.send(batch[:n]); err != nil {
+				// Not logged through the logger, which would feed the sink.
+				if !failing {
+					log.Printf("Remote log sink failed, retrying: %v", err)
+				}
+				failing = true
+				backoff = min(max(backoff*2, s.retryBase), remoteRetryMax)
+				retryAt = now.Add(backoff)
+				// Hold at most a buffer's worth while the sink is down.
+				if over := len(batch) - s.bufferSize; over > 0 {
+					s.dropped.Add(int64(over))
+					batch = batch[over:]
+				}
+				return
+			}
+			batch = batch[n:]
+		}
+		if failing {
+			log.Printf("Remote log sink recovered")
+		}
+		batch, failing, backoff, retryAt = nil, false, 0, time.Time{}
+	}
+
+	for {
+		select {
+		case entry := <-s.entries:
+			batch = append(batch, entry)
+			if len(batch) > s.bufferSize {
+				s.dropped.Add(1)
+				batch = batch[1:]
+			}
+			if len(batch) >= remoteBatchSize {
+				flush(time.Now())
+			}
+		case now := <-ticker.C:
+			flush(now)
+		case <-s.done:
+		drain:
+			for {
+				select {
+				case entry := <-s.entries:
+					batch = append(batch, entry)
+				default:
+					break drain
+				}
+			}
+			retryAt = time.Time{}
+			flush(time.Now())
+			return
+		}
+	}
+}
+
+// close stops run after one last attempt to send what is queued, waiting
+// at most timeout.
+func (s *remoteSink) close(timeout time.Duration) {
+	close(s.done)
+	select {
+	case <-s.stopped:
+	case <-time.After(timeout):
+		log.Printf("Remote log sink did not flush within %s", timeout)
+	}
+}
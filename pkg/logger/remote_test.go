@@ -0,0 +1,120 @@
+package logger
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRemoteLogging_HTTP(t *testing.T) {
+	var mu sync.Mutex
+	var received []LogEntry
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var entries []LogEntry
+		if err := json.NewDecoder(r.Body).Decode(&entries); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		mu.Lock()
+		received = append(received, entries...)
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	if err := EnableRemoteLogging(RemoteOptions{URL: server.URL, Headers: map[string]string{"Authorization": "Bearer token"}}); err != nil {
+		t.Fatalf("EnableRemoteLogging: %v", err)
+	}
+	WarnCF("remote-test", "first", map[string]interface{}{"n": 1})
+	ErrorCF("remote-test", "second", nil)
+	DisableRemoteLogging(5 * time.Second)
+
+	mu.Lock()
+	defer mu.Unlock()
+	var messages []string
+	for _, e := range received {
+		if e.Component == "remote-test" {
+			messages = append(messages, e.Level+" "+e.Message)
+		}
+	}
+	if strings.Join(messages, ",") != "WARN first,ERROR second" {
+		t.Errorf("received %v, want the two entries in order", messages)
+	}
+}
+
+func TestRemoteLogging_InvalidURL(t *testing.T) {
+	for _, u := range []string{"ftp://example.com", "udp://", "::"} {
+		if err := EnableRemoteLogging(RemoteOptions{URL: u}); err == nil {
+			DisableRemoteLogging(time.Second)
+			t.Errorf("EnableRemoteLogging(%q) succeeded, want error", u)
+		}
+	}
+}
+
+func TestRemoteSink_RetriesFailedBatches(t *testing.T) {
+	var mu sync.Mutex
+	var sent []string
+	failures := 2
+	sink := newRemoteSink(func(entries []LogEntry) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if failures > 0 {
+			failures--
+			return errors.New("connection refused")
+		}
+		for _, e := range entries {
+			sent = append(sent, e.Message)
+		}
+		return nil
+	}, RemoteOptions{FlushInterval: 5 * time.Millisecond})
+	sink.retryBase = 5 * time.Millisecond
+	go sink.run()
+
+	for _, msg := range []string{"a", "b", "c"} {
+		sink.enqueue(LogEntry{Level: "INFO", Message: msg})
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		mu.Lock()
+		done := len(sent) == 3
+		mu.Unlock()
+		if done {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	sink.close(time.Second)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if strings.Join(sent, "") != "abc" {
+		t.Errorf("sent %v after retries, want [a b c]", sent)
+	}
+}
+
+func TestRemoteSink_DropsWhenFull(t *testing.T) {
+	sink := newRemoteSink(func([]LogEntry) error { return nil }, RemoteOptions{BufferSize: 2})
+	for i := 0; i < 5; i++ {
+		sink.enqueue(LogEntry{Message: "x"})
+	}
+	if got := sink.dropped.Load(); got != 3 {
+		t.Errorf("dropped = %d, want 3", got)
+	}
+}
+
+func TestFormatSyslog(t *testing.T) {
+	entry := LogEntry{Level: "ERROR", Timestamp: "2026-01-02T15:04:05Z", Component: "tool", Message: "failed"}
+	got := formatSyslog("vm1", entry)
+	want := `<11>1 2026-01-02T15:04:05Z vm1 picoclaw - - - {"level":"ERROR"`
+	if !strings.HasPrefix(got, want) {
+		t.Errorf("formatSyslog = %q, want prefix %q", got, want)
+	}
+}
@@ -0,0 +1,152 @@
+package logger
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// maxSampleKeys bounds the tracked messages; idle ones are swept past it.
+const maxSampleKeys = 1000
+
+// sampler collapses bursts of identical entries; it is off until
+// SetSampling enables it.
+var sampler = newLogSampler(writeEntry)
+
+// SetSampling collapses repeated entries: within window, the first
+// threshold entries with the same component, message and fingerprint field
+// are written, the rest are counted, and when the window closes the last
+// of them is written with "occurrences" and "suppressed" counts. A window
+// of zero or less turns sampling off, writing any pending summaries. FATAL
+// entries are never sampled.
+func SetSampling(window time.Duration, threshold int) {
+	sampler.configure(window, threshold)
+}
+
+// logSampler tracks identical entries per window.
+type logSampler struct {
+	mu        sync.Mutex
+	window    time.Duration
+	threshold int
+	buckets   map[string]*sampleBucket
+	emit      func(LogLevel, LogEntry)
+}
+
+// sampleBucket counts one kind of entry within the current window.
+type sampleBucket struct {
+	start      time.Time
+	count      int
+	suppressed int
+	last       LogEntry
+	lastLevel  LogLevel
+	timer      *time.Timer
+}
+
+func newLogSampler(emit func(LogLevel, LogEntry)) *logSampler {
+	return &logSampler{buckets: make(map[string]*sampleBucket), emit: emit}
+}
+
+func (s *logSampler) configure(window time.Duration, threshold int) {
+	s.mu.Lock()
+	s.window = window
+	s.threshold = max(threshold, 1)
+	var pending []*sampleBucket
+	if window <= 0 {
+		for key, b := range s.buckets {
+			pending = append(pending, b)
+			delete(s.buckets, key)
+		}
+	}
+	s.mu.Unlock()
+
+	for _, b := range pending {
+		if b.timer != nil {
+			b.timer.Stop()
+		}
+		s.emitSummary(b)
+	}
+}
+
+// allow reports whether entry should be written now.
+func (s *logSampler) allow(level LogLevel, entry LogEntry) bool {
+	return s.observe(sampleKey(entry), level, entry, time.Now())
+}
+
+func (s *logSampler) observe(key string, level LogLevel, entry LogEntry, now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.window <= 0 {
+		return true
+	}
+
+	b, ok := s.buckets[key]
+	if !ok || now.Sub(b.start) >= s.window {
+		if len(s.buckets) >= maxSampleKeys {
+			s.sweepLocked(now)
+		}
+		// A previous window with suppressed entries is flushed by its timer.
+		if !ok || b.suppressed == 0 {
+			s.buckets[key] = &sampleBucket{start: now, count: 1}
+			return true
+		}
+	}
+
+	b.count++
+	if b.count <= s.threshold {
+		return true
+	}
+	b.suppressed++
+	b.last = entry
+	b.lastLevel = level
+	if b.timer == nil {
+		b.timer = time.AfterFunc(b.start.Add(s.window).Sub(now), func() { s.flush(key, b) })
+	}
+	return false
+}
+
+// flush writes the summary for b once its window has closed, unless b has
+// already been replaced or flushed.
+func (s *logSampler) flush(key string, b *sampleBucket) {
+	s.mu.Lock()
+	if s.buckets[key] != b {
+		s.mu.Unlock()
+		return
+	}
+	delete(s.buckets, key)
+	s.mu.Unlock()
+	s.emitSummary(b)
+}
+
+func (s *logSampler) emitSummary(b *sampleBucket) {
+	if b.suppressed == 0 {
+		return
+	}
+	entry := b.last
+	fields := make(map[string]interface{}, len(entry.Fields)+2)
+	for k, v := range entry.Fields {
+		fields[k] = v
+	}
+	fields["occurrences"] = b.count
+	fields["suppressed"] = b.suppressed
+	entry.Fields = fields
+	s.emit(b.lastLevel, entry)
+}
+
+// sweepLocked drops buckets whose window has closed with nothing pending.
+func (s *logSampler) sweepLocked(now time.Time) {
+	for key, b := range s.buckets {
+		if b.suppressed == 0 && now.Sub(b.start) >= s.window {
+			delete(s.buckets, key)
+		}
+	}
+}
+
+// sampleKey identifies identical entries: component, message and, when
+// present, the error fingerprint.
+func sampleKey(entry LogEntry) string {
+	key := entry.Component + "\x00" + entry.Message
+	if fp, ok := entry.Fields["fingerprint"]; ok {
+		key += "\x00" + fmt.Sprint(fp)
+	}
+	return key
+}
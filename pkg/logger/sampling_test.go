@@ -0,0 +1,96 @@
+package logger
+
+import (
+	"testing"
+	"time"
+)
+
+type emitted struct {
+	level LogLevel
+	entry LogEntry
+}
+
+func newTestSampler(window time.Duration, threshold int) (*logSampler, *[]emitted) {
+	var out []emitted
+	s := newLogSampler(func(level LogLevel, entry LogEntry) {
+		out = append(out, emitted{level, entry})
+	})
+	s.configure(window, threshold)
+	return s, &out
+}
+
+func TestSampler_CollapsesRepeats(t *testing.T) {
+	s, out := newTestSampler(time.Hour, 2)
+	start := time.Now()
+	entry := LogEntry{Component: "tool", Message: "Tool execution failed", Fields: map[string]interface{}{"fingerprint": "abc"}}
+
+	allowed := 0
+	for i := 0; i < 10; i++ {
+		e := entry
+		e.Timestamp = start.Add(time.Duration(i) * time.Second).Format(time.RFC3339)
+		if s.observe(sampleKey(e), ERROR, e, start.Add(time.Duration(i)*time.Second)) {
+			allowed++
+		}
+	}
+	if allowed != 2 {
+		t.Fatalf("allowed %d entries, want the threshold of 2", allowed)
+	}
+	if len(*out) != 0 {
+		t.Fatalf("summary written before the window closed: %+v", *out)
+	}
+
+	key := sampleKey(entry)
+	s.flush(key, s.buckets[key])
+	if len(*out) != 1 {
+		t.Fatalf("flush wrote %d entries, want 1 summary", len(*out))
+	}
+	summary := (*out)[0]
+	if summary.level != ERROR || summary.entry.Fields["occurrences"] != 10 || summary.entry.Fields["suppressed"] != 8 {
+		t.Errorf("summary = %+v, want ERROR with 10 occurrences and 8 suppressed", summary)
+	}
+	if want := start.Add(9 * time.Second).Format(time.RFC3339); summary.entry.Timestamp != want {
+		t.Errorf("summary timestamp = %s, want the last occurrence's %s", summary.entry.Timestamp, want)
+	}
+	if _, ok := entry.Fields["occurrences"]; ok {
+		t.Error("summary fields leaked into the original entry")
+	}
+}
+
+func TestSampler_KeysAndWindows(t *testing.T) {
+	s, _ := newTestSampler(time.Minute, 1)
+	now := time.Now()
+	a := LogEntry{Component: "tool", Message: "failed", Fields: map[string]interface{}{"fingerprint": "a"}}
+	b := LogEntry{Component: "tool", Message: "failed", Fields: map[string]interface{}{"fingerprint": "b"}}
+
+	if !s.observe(sampleKey(a), WARN, a, now) || !s.observe(sampleKey(b), WARN, b, now) {
+		t.Fatal("first entries of distinct fingerprints were suppressed")
+	}
+	if s.observe(sampleKey(a), WARN, a, now.Add(time.Second)) {
+		t.Error("repeat within the window was allowed")
+	}
+
+	other := LogEntry{Component: "agent", Message: "tick"}
+	s.observe(sampleKey(other), INFO, other, now)
+	if !s.observe(sampleKey(other), INFO, other, now.Add(2*time.Minute)) {
+		t.Error("entry after the window closed was suppressed")
+	}
+}
+
+func TestSampler_DisabledAndFlushOnDisable(t *testing.T) {
+	s, out := newTestSampler(0, 1)
+	e := LogEntry{Component: "agent", Message: "same"}
+	for i := 0; i < 3; i++ {
+		if !s.observe(sampleKey(e), INFO, e, time.Now()) {
+			t.Fatal("disabled sampler suppressed an entry")
+		}
+	}
+
+	s.configure(time.Hour, 1)
+	now := time.Now()
+	s.observe(sampleKey(e), INFO, e, now)
+	s.observe(sampleKey(e), INFO, e, now)
+	s.configure(0, 1)
+	if len(*out) != 1 || (*out)[0].entry.Fields["suppressed"] != 1 {
+		t.Errorf("disabling wrote %+v, want one pending summary", *out)
+	}
+}
@@ -266,6 +266,8 @@ func parseClaudeResponse(resp *anthropic.Message) *LLMResponse {
 		finishReason = "length"
 	case anthropic.StopReasonEndTurn:
 		finishReason = "stop"
+	case anthropic.StopReasonRefusal:
+		finishReason = FinishReasonContentFilter
 	}
 
 	return &LLMResponse{
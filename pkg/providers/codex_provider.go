@@ -207,6 +207,9 @@ func parseCodexResponse(resp *responses.Response) *LLMResponse {
 	}
 	if resp.Status == "incomplete" {
 		finishReason = "length"
+		if resp.IncompleteDetails.Reason == FinishReasonContentFilter {
+			finishReason = FinishReasonContentFilter
+		}
 	}
 
 	var usage *UsageInfo
@@ -35,6 +35,23 @@ func (e *RateLimitError) Error() string {
 	return fmt.Sprintf("rate limited (status %d): %s", e.StatusCode, e.Body)
 }
 
+// ContentFilterError is returned when the provider rejects a request outright
+// because the prompt violates its content policy.
+type ContentFilterError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *ContentFilterError) Error() string {
+	return fmt.Sprintf("content filtered (status %d): %s", e.StatusCode, e.Body)
+}
+
+// isContentFilterBody matches the error codes OpenAI and Azure OpenAI use
+// when a prompt is blocked by the content policy.
+func isContentFilterBody(body string) bool {
+	return strings.Contains(body, "content_filter") || strings.Contains(body, "content_policy_violation")
+}
+
 type HTTPProvider struct {
 	apiKey     string
 	apiBase    string
@@ -147,12 +164,48 @@ func (p *HTTPProvider) Chat(ctx context.Context, messages []Message, tools []Too
 				Headers:                headers,
 			}
 		}
+		if resp.StatusCode == http.StatusBadRequest && isContentFilterBody(string(body)) {
+			return nil, &ContentFilterError{StatusCode: resp.StatusCode, Body: string(body)}
+		}
 		return nil, fmt.Errorf("API request failed:\n  Status: %d\n  Body:   %s", resp.StatusCode, string(body))
 	}
 
 	return p.parseResponse(body)
 }
 
+// CheckReachable lists the provider's models, which costs no tokens. A 429
+// is returned as a *RateLimitError like from Chat.
+func (p *HTTPProvider) CheckReachable(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", p.apiBase+"/models", nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	if p.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+p.apiKey)
+	}
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return &RateLimitError{
+			StatusCode:             resp.StatusCode,
+			Body:                   string(body),
+			RetryAfter:             resp.Header.Get("Retry-After"),
+			RateLimitRequestsReset: resp.Header.Get("X-RateLimit-Requests-Reset"),
+			RateLimitTokensReset:   resp.Header.Get("X-RateLimit-Tokens-Reset"),
+		}
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		return fmt.Errorf("models request failed:\n  Status: %d\n  Body:   %s", resp.StatusCode, string(body))
+	}
+	return nil
+}
+
 func (p *HTTPProvider) parseResponse(body []byte) (*LLMResponse, error) {
 	var apiResponse struct {
 		Choices []struct {
@@ -512,3 +565,76 @@ func createProviderWithSelection(cfg *config.Config, model string, provider stri
 func CreateProviderForModel(cfg *config.Config, model string) (LLMProvider, error) {
 	return createProviderWithSelection(cfg, model, "")
 }
+
+// ProviderName returns the providers.* config name (e.g. "openrouter") that
+// serves model, following the same selection as the provider factory:
+// the explicitly configured provider first, then detection from the model
+// name. It returns "" when no configured provider matches.
+func ProviderName(cfg *config.Config, model, provider string) string {
+	p := cfg.Providers
+	switch strings.ToLower(provider) {
+	case "groq":
+		if p.Groq.APIKey != "" {
+			return "groq"
+		}
+	case "openai", "gpt":
+		if p.OpenAI.APIKey != "" || p.OpenAI.AuthMethod != "" {
+			return "openai"
+		}
+	case "anthropic", "claude":
+		if p.Anthropic.APIKey != "" || p.Anthropic.AuthMethod != "" {
+			return "anthropic"
+		}
+	case "openrouter":
+		if p.OpenRouter.APIKey != "" {
+			return "openrouter"
+		}
+	case "zhipu", "glm":
+		if p.Zhipu.APIKey != "" {
+			return "zhipu"
+		}
+	case "gemini", "google":
+		if p.Gemini.APIKey != "" {
+			return "gemini"
+		}
+	case "vllm":
+		if p.VLLM.APIBase != "" {
+			return "vllm"
+		}
+	case "shengsuanyun":
+		if p.ShengSuanYun.APIKey != "" {
+			return "shengsuanyun"
+		}
+	case "deepseek":
+		if p.DeepSeek.APIKey != "" {
+			return "deepseek"
+		}
+	case "github_copilot", "copilot":
+		return "github_copilot"
+	}
+
+	lowerModel := strings.ToLower(model)
+	switch {
+	case (strings.Contains(lowerModel, "kimi") || strings.Contains(lowerModel, "moonshot")) && p.Moonshot.APIKey != "":
+		return "moonshot"
+	case strings.HasPrefix(model, "openrouter/") || strings.HasPrefix(model, "anthropic/") || strings.HasPrefix(model, "openai/") || strings.HasPrefix(model, "meta-llama/") || strings.HasPrefix(model, "deepseek/") || strings.HasPrefix(model, "google/"):
+		return "openrouter"
+	case strings.Contains(lowerModel, "claude") && (p.Anthropic.APIKey != "" || p.Anthropic.AuthMethod != ""):
+		return "anthropic"
+	case strings.Contains(lowerModel, "gpt") && (p.OpenAI.APIKey != "" || p.OpenAI.AuthMethod != ""):
+		return "openai"
+	case strings.Contains(lowerModel, "gemini") && p.Gemini.APIKey != "":
+		return "gemini"
+	case (strings.Contains(lowerModel, "glm") || strings.Contains(lowerModel, "zhipu") || strings.Contains(lowerModel, "zai")) && p.Zhipu.APIKey != "":
+		return "zhipu"
+	case strings.Contains(lowerModel, "groq") && p.Groq.APIKey != "":
+		return "groq"
+	case strings.Contains(lowerModel, "nvidia") && p.Nvidia.APIKey != "":
+		return "nvidia"
+	case p.VLLM.APIBase != "":
+		return "vllm"
+	case p.OpenRouter.APIKey != "":
+		return "openrouter"
+	}
+	return ""
+}
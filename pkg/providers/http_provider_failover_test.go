@@ -38,3 +38,57 @@ func TestHTTPProvider429IncludesHeaders(t *testing.T) {
 		t.Fatalf("expected headers map to contain Retry-After")
 	}
 }
+
+func TestHTTPProviderContentFilterRejection(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"error":{"code":"content_filter","message":"The response was filtered"}}`))
+	}))
+	defer ts.Close()
+
+	p := NewHTTPProvider("k", ts.URL, "")
+	_, err := p.Chat(context.Background(), []Message{{Role: "user", Content: "ping"}}, nil, "gpt-5-mini", map[string]interface{}{})
+
+	var cf *ContentFilterError
+	if !errors.As(err, &cf) {
+		t.Fatalf("expected ContentFilterError, got %T (%v)", err, err)
+	}
+	if cf.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected status 400, got %d", cf.StatusCode)
+	}
+}
+
+func TestIsContentFilterFinishReason(t *testing.T) {
+	for _, reason := range []string{"content_filter", "SAFETY", "refusal"} {
+		if !IsContentFilterFinishReason(reason) {
+			t.Errorf("%q should be a content-filter finish reason", reason)
+		}
+	}
+	for _, reason := range []string{"", "stop", "length", "tool_calls"} {
+		if IsContentFilterFinishReason(reason) {
+			t.Errorf("%q should not be a content-filter finish reason", reason)
+		}
+	}
+}
+
+func TestHTTPProviderCheckReachable(t *testing.T) {
+	status := http.StatusOK
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || r.URL.Path != "/models" || r.Header.Get("Authorization") != "Bearer k" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		w.WriteHeader(status)
+	}))
+	defer ts.Close()
+
+	p := NewHTTPProvider("k", ts.URL, "")
+	if err := p.CheckReachable(context.Background()); err != nil {
+		t.Fatalf("CheckReachable() error: %v", err)
+	}
+
+	status = http.StatusTooManyRequests
+	var rl *RateLimitError
+	if err := p.CheckReachable(context.Background()); !errors.As(err, &rl) {
+		t.Fatalf("CheckReachable() on 429 = %v, want RateLimitError", err)
+	}
+}
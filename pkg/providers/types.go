@@ -1,6 +1,9 @@
 package providers
 
-import "context"
+import (
+	"context"
+	"strings"
+)
 
 type ToolCall struct {
 	ID        string                 `json:"id"`
@@ -22,6 +25,22 @@ type LLMResponse struct {
 	Usage        *UsageInfo `json:"usage,omitempty"`
 }
 
+// FinishReasonContentFilter is the normalized finish reason for responses
+// withheld by a provider's content policy.
+const FinishReasonContentFilter = "content_filter"
+
+// IsContentFilterFinishReason reports whether a finish reason means the
+// provider refused on content-policy grounds. Besides the normalized value it
+// accepts the raw reasons used by OpenAI-compatible backends (e.g. Gemini's
+// "SAFETY").
+func IsContentFilterFinishReason(reason string) bool {
+	switch strings.ToLower(strings.TrimSpace(reason)) {
+	case FinishReasonContentFilter, "refusal", "safety", "prohibited_content":
+		return true
+	}
+	return false
+}
+
 type UsageInfo struct {
 	PromptTokens     int `json:"prompt_tokens"`
 	CompletionTokens int `json:"completion_tokens"`
@@ -46,6 +65,12 @@ type LLMProvider interface {
 	GetDefaultModel() string
 }
 
+// ReachabilityChecker is implemented by providers that can check their API
+// is up without a chat request, e.g. by listing models.
+type ReachabilityChecker interface {
+	CheckReachable(ctx context.Context) error
+}
+
 type ToolDefinition struct {
 	Type     string                 `json:"type"`
 	Function ToolFunctionDefinition `json:"function"`
@@ -0,0 +1,120 @@
+package session
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/providers"
+)
+
+// ExportVersion is the current session export format version.
+const ExportVersion = 1
+
+// SessionExport is the portable JSON representation of a session used for
+// backup and migration between machines.
+type SessionExport struct {
+	Version    int       `json:"version"`
+	ExportedAt time.Time `json:"exported_at"`
+	Session    Session   `json:"session"`
+}
+
+var validRoles = map[string]bool{
+	"system":    true,
+	"user":      true,
+	"assistant": true,
+	"tool":      true,
+}
+
+// Export returns a JSON blob containing the session's history, summary and
+// timestamps. It returns an error if the session does not exist.
+func (sm *SessionManager) Export(key string) ([]byte, error) {
+	sm.mu.RLock()
+	stored, ok := sm.sessions[key]
+	if !ok {
+		sm.mu.RUnlock()
+		return nil, fmt.Errorf("session %q not found", key)
+	}
+	export := SessionExport{
+		Version:    ExportVersion,
+		ExportedAt: time.Now(),
+		Session: Session{
+			Key:      stored.Key,
+			Messages: make([]providers.Message, len(stored.Messages)),
+			Summary:  stored.Summary,
+			Created:  stored.Created,
+			Updated:  stored.Updated,
+		},
+	}
+	copy(export.Session.Messages, stored.Messages)
+	sm.mu.RUnlock()
+
+	return json.MarshalIndent(export, "", "  ")
+}
+
+// Import restores a session from a blob produced by Export. If key is
+// non-empty it overrides the key stored in the blob. An existing session is
+// only replaced when overwrite is true. The restored session is not saved;
+// callers should call Save afterwards.
+func (sm *SessionManager) Import(data []byte, key string, overwrite bool) (SessionInfo, error) {
+	var export SessionExport
+	if err := json.Unmarshal(data, &export); err != nil {
+		return SessionInfo{}, fmt.Errorf("invalid session export: %w", err)
+	}
+	if key != "" {
+		export.Session.Key = key
+	}
+	if err := ValidateExport(&export); err != nil {
+		return SessionInfo{}, err
+	}
+
+	restored := export.Session
+	if restored.Messages == nil {
+		restored.Messages = []providers.Message{}
+	}
+	if restored.Created.IsZero() {
+		restored.Created = time.Now()
+	}
+	if restored.Updated.IsZero() {
+		restored.Updated = restored.Created
+	}
+
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	if _, exists := sm.sessions[restored.Key]; exists && !overwrite {
+		return SessionInfo{}, fmt.Errorf("session %q already exists; set overwrite to replace it", restored.Key)
+	}
+	sm.sessions[restored.Key] = &restored
+
+	return SessionInfo{
+		Key:          restored.Key,
+		MessageCount: len(restored.Messages),
+		HasSummary:   restored.Summary != "",
+		Updated:      restored.Updated,
+	}, nil
+}
+
+// ValidateExport checks that an export has a supported version, a usable
+// session key and well-formed messages.
+func ValidateExport(export *SessionExport) error {
+	if export.Version != ExportVersion {
+		return fmt.Errorf("unsupported session export version %d (want %d)", export.Version, ExportVersion)
+	}
+	if export.Session.Key == "" {
+		return errors.New("session export is missing a key")
+	}
+	if name := sanitizeFilename(export.Session.Key); !isSafeFilename(name) {
+		return fmt.Errorf("session key %q is not a valid session name", export.Session.Key)
+	}
+	for i, msg := range export.Session.Messages {
+		if !validRoles[msg.Role] {
+			return fmt.Errorf("message %d has invalid role %q", i, msg.Role)
+		}
+		if msg.Role == "tool" && msg.ToolCallID == "" {
+			return fmt.Errorf("message %d is a tool result without tool_call_id", i)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package session
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/providers"
+)
+
+func TestExportImport_RoundTrip(t *testing.T) {
+	src := NewSessionManager(t.TempDir())
+	key := "telegram:123456"
+	src.AddMessage(key, "user", "hello")
+	src.AddFullMessage(key, providers.Message{
+		Role:      "assistant",
+		ToolCalls: []providers.ToolCall{{ID: "call_1", Name: "read_file"}},
+	})
+	src.AddFullMessage(key, providers.Message{Role: "tool", Content: "file body", ToolCallID: "call_1"})
+	src.SetSummary(key, "user said hello")
+
+	data, err := src.Export(key)
+	if err != nil {
+		t.Fatalf("Export() error: %v", err)
+	}
+
+	dst := NewSessionManager(t.TempDir())
+	info, err := dst.Import(data, "", false)
+	if err != nil {
+		t.Fatalf("Import() error: %v", err)
+	}
+	if info.Key != key || info.MessageCount != 3 || !info.HasSummary {
+		t.Fatalf("unexpected import info: %+v", info)
+	}
+
+	history := dst.GetHistory(key)
+	if len(history) != 3 {
+		t.Fatalf("history length = %d, want 3", len(history))
+	}
+	if history[0].Content != "hello" || history[2].ToolCallID != "call_1" {
+		t.Fatalf("history not preserved: %+v", history)
+	}
+	if len(history[1].ToolCalls) != 1 || history[1].ToolCalls[0].ID != "call_1" {
+		t.Fatalf("tool calls not preserved: %+v", history[1])
+	}
+	if got := dst.GetSummary(key); got != "user said hello" {
+		t.Fatalf("summary = %q, want %q", got, "user said hello")
+	}
+}
+
+func TestImport_RespectsOverwriteAndKeyOverride(t *testing.T) {
+	sm := NewSessionManager(t.TempDir())
+	sm.AddMessage("telegram:1", "user", "original")
+	data, err := sm.Export("telegram:1")
+	if err != nil {
+		t.Fatalf("Export() error: %v", err)
+	}
+
+	if _, err := sm.Import(data, "", false); err == nil {
+		t.Fatal("expected error importing over an existing session without overwrite")
+	}
+	if _, err := sm.Import(data, "", true); err != nil {
+		t.Fatalf("Import(overwrite) error: %v", err)
+	}
+	if _, err := sm.Import(data, "discord:2", false); err != nil {
+		t.Fatalf("Import(key override) error: %v", err)
+	}
+	if got := sm.GetHistory("discord:2"); len(got) != 1 || got[0].Content != "original" {
+		t.Fatalf("imported history under new key = %+v", got)
+	}
+}
+
+func TestImport_ValidatesStructure(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{"not json", `{`, "invalid session export"},
+		{"wrong version", `{"version": 99, "session": {"key": "a"}}`, "unsupported"},
+		{"missing key", `{"version": 1, "session": {"messages": []}}`, "missing a key"},
+		{"path key", `{"version": 1, "session": {"key": "../etc"}}`, "not a valid session name"},
+		{"bad role", `{"version": 1, "session": {"key": "a", "messages": [{"role": "robot"}]}}`, "invalid role"},
+		{"orphan tool", `{"version": 1, "session": {"key": "a", "messages": [{"role": "tool", "content": "x"}]}}`, "tool_call_id"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sm := NewSessionManager("")
+			_, err := sm.Import([]byte(tt.data), "", false)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("Import() error = %v, want containing %q", err, tt.want)
+			}
+			if len(sm.List()) != 0 {
+				t.Fatal("invalid import must not create a session")
+			}
+		})
+	}
+}
@@ -2,8 +2,11 @@ package session
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -19,6 +22,14 @@ type Session struct {
 	Updated  time.Time           `json:"updated"`
 }
 
+// SessionInfo is a lightweight view of a session used for listings.
+type SessionInfo struct {
+	Key          string    `json:"key"`
+	MessageCount int       `json:"message_count"`
+	HasSummary   bool      `json:"has_summary"`
+	Updated      time.Time `json:"updated"`
+}
+
 type SessionManager struct {
 	sessions map[string]*Session
 	mu       sync.RWMutex
@@ -145,6 +156,67 @@ func (sm *SessionManager) TruncateHistory(key string, keepLast int) {
 	session.Updated = time.Now()
 }
 
+// List returns all known sessions, most recently updated first.
+func (sm *SessionManager) List() []SessionInfo {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+
+	infos := make([]SessionInfo, 0, len(sm.sessions))
+	for key, session := range sm.sessions {
+		infos = append(infos, SessionInfo{
+			Key:          key,
+			MessageCount: len(session.Messages),
+			HasSummary:   session.Summary != "",
+			Updated:      session.Updated,
+		})
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		if infos[i].Updated.Equal(infos[j].Updated) {
+			return infos[i].Key < infos[j].Key
+		}
+		return infos[i].Updated.After(infos[j].Updated)
+	})
+	return infos
+}
+
+// Clear wipes the history and summary of a session, keeping the session
+// itself. It returns false if the session does not exist.
+func (sm *SessionManager) Clear(key string) bool {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	session, ok := sm.sessions[key]
+	if !ok {
+		return false
+	}
+	session.Messages = []providers.Message{}
+	session.Summary = ""
+	session.Updated = time.Now()
+	return true
+}
+
+// RollbackLastUserMessage removes the session's last user message and
+// everything after it, returning that message. ok is false when the session
+// has no user message.
+func (sm *SessionManager) RollbackLastUserMessage(key string) (msg providers.Message, ok bool) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	session, exists := sm.sessions[key]
+	if !exists {
+		return providers.Message{}, false
+	}
+	for i := len(session.Messages) - 1; i >= 0; i-- {
+		if session.Messages[i].Role == "user" {
+			msg = session.Messages[i]
+			session.Messages = session.Messages[:i]
+			session.Updated = time.Now()
+			return msg, true
+		}
+	}
+	return providers.Message{}, false
+}
+
 // sanitizeFilename converts a session key into a cross-platform safe filename.
 // Session keys use "channel:chatID" (e.g. "telegram:123456") but ':' is the
 // volume separator on Windows, so filepath.Base would misinterpret the key.
@@ -154,18 +226,22 @@ func sanitizeFilename(key string) string {
 	return strings.ReplaceAll(key, ":", "_")
 }
 
+// isSafeFilename reports whether a sanitized session filename can be written
+// directly inside the storage directory.
+// filepath.IsLocal rejects empty names, "..", absolute paths, and
+// OS-reserved device names (NUL, COM1 … on Windows).
+// The extra checks reject "." and any directory separators.
+func isSafeFilename(filename string) bool {
+	return filename != "." && filepath.IsLocal(filename) && !strings.ContainsAny(filename, `/\`)
+}
+
 func (sm *SessionManager) Save(key string) error {
 	if sm.storage == "" {
 		return nil
 	}
 
 	filename := sanitizeFilename(key)
-
-	// filepath.IsLocal rejects empty names, "..", absolute paths, and
-	// OS-reserved device names (NUL, COM1 … on Windows).
-	// The extra checks reject "." and any directory separators so that
-	// the session file is always written directly inside sm.storage.
-	if filename == "." || !filepath.IsLocal(filename) || strings.ContainsAny(filename, `/\`) {
+	if !isSafeFilename(filename) {
 		return os.ErrInvalid
 	}
 
@@ -233,6 +309,25 @@ func (sm *SessionManager) Save(key string) error {
 	return nil
 }
 
+// SaveAll persists every session, e.g. at shutdown, and returns the
+// failures joined.
+func (sm *SessionManager) SaveAll() error {
+	sm.mu.RLock()
+	keys := make([]string, 0, len(sm.sessions))
+	for key := range sm.sessions {
+		keys = append(keys, key)
+	}
+	sm.mu.RUnlock()
+
+	var errs []error
+	for _, key := range keys {
+		if err := sm.Save(key); err != nil {
+			errs = append(errs, fmt.Errorf("session %s: %w", key, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
 func (sm *SessionManager) loadSessions() error {
 	files, err := os.ReadDir(sm.storage)
 	if err != nil {
@@ -72,3 +72,79 @@ func TestSave_RejectsPathTraversal(t *testing.T) {
 		}
 	}
 }
+
+func TestListAndClear(t *testing.T) {
+	sm := NewSessionManager(t.TempDir())
+
+	sm.AddMessage("telegram:1", "user", "hello")
+	sm.AddMessage("telegram:1", "assistant", "hi")
+	sm.SetSummary("telegram:1", "greeting")
+	sm.AddMessage("slack:C1", "user", "ping")
+
+	infos := sm.List()
+	if len(infos) != 2 {
+		t.Fatalf("List() returned %d sessions, want 2", len(infos))
+	}
+	if infos[0].Key != "slack:C1" {
+		t.Errorf("most recent session = %q, want slack:C1", infos[0].Key)
+	}
+	if infos[1].MessageCount != 2 || !infos[1].HasSummary {
+		t.Errorf("telegram:1 info = %+v, want 2 messages with summary", infos[1])
+	}
+
+	if !sm.Clear("telegram:1") {
+		t.Fatal("Clear() returned false for existing session")
+	}
+	if got := sm.GetHistory("telegram:1"); len(got) != 0 {
+		t.Errorf("history after Clear = %d messages, want 0", len(got))
+	}
+	if got := sm.GetSummary("telegram:1"); got != "" {
+		t.Errorf("summary after Clear = %q, want empty", got)
+	}
+	if sm.Clear("missing") {
+		t.Error("Clear() should return false for unknown session")
+	}
+}
+
+func TestRollbackLastUserMessage(t *testing.T) {
+	sm := NewSessionManager("")
+	if _, ok := sm.RollbackLastUserMessage("telegram:1"); ok {
+		t.Error("RollbackLastUserMessage() on unknown session should return false")
+	}
+
+	sm.AddMessage("telegram:1", "user", "first")
+	sm.AddMessage("telegram:1", "assistant", "one")
+	sm.AddMessage("telegram:1", "user", "second")
+	sm.AddMessage("telegram:1", "assistant", "two")
+
+	msg, ok := sm.RollbackLastUserMessage("telegram:1")
+	if !ok || msg.Content != "second" {
+		t.Fatalf("RollbackLastUserMessage() = %q, %v; want \"second\", true", msg.Content, ok)
+	}
+	history := sm.GetHistory("telegram:1")
+	if len(history) != 2 || history[1].Content != "one" {
+		t.Errorf("history after rollback = %+v, want first exchange only", history)
+	}
+
+	sm.Clear("telegram:1")
+	sm.AddMessage("telegram:1", "assistant", "hello")
+	if _, ok := sm.RollbackLastUserMessage("telegram:1"); ok {
+		t.Error("RollbackLastUserMessage() without a user message should return false")
+	}
+}
+
+func TestSaveAll(t *testing.T) {
+	tmpDir := t.TempDir()
+	sm := NewSessionManager(tmpDir)
+	sm.AddMessage("telegram:1", "user", "one")
+	sm.AddMessage("discord:2", "user", "two")
+
+	if err := sm.SaveAll(); err != nil {
+		t.Fatalf("SaveAll() error: %v", err)
+	}
+	for _, name := range []string{"telegram_1.json", "discord_2.json"} {
+		if _, err := os.Stat(filepath.Join(tmpDir, name)); err != nil {
+			t.Errorf("expected %s to be saved: %v", name, err)
+		}
+	}
+}
@@ -179,7 +179,11 @@ func (sl *SkillsLoader) LoadSkillsForContext(skillNames []string) string {
 }
 
 func (sl *SkillsLoader) BuildSkillsSummary() string {
-	allSkills := sl.ListSkills()
+	return BuildSkillsSummaryFor(sl.ListSkills())
+}
+
+// BuildSkillsSummaryFor renders the XML skills summary for the given skills.
+func BuildSkillsSummaryFor(allSkills []SkillInfo) string {
 	if len(allSkills) == 0 {
 		return ""
 	}
@@ -0,0 +1,52 @@
+package state
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// TryLock takes the named lock file in the state directory, so only one of
+// the processes sharing this workspace does a job at a time. A lock older
+// than staleAfter is assumed abandoned (its holder crashed) and taken over.
+// ok is false while another holder has the lock; release frees it.
+func (sm *Manager) TryLock(name string, staleAfter time.Duration) (release func(), ok bool) {
+	path := filepath.Join(filepath.Dir(sm.stateFile), name+".lock")
+	token := fmt.Sprintf("%d-%d", os.Getpid(), time.Now().UnixNano())
+
+	for attempt := 0; attempt < 2; attempt++ {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			_, werr := f.WriteString(token)
+			f.Close()
+			if werr != nil {
+				os.Remove(path)
+				return nil, false
+			}
+			return func() { releaseLock(path, token) }, true
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, false
+		}
+
+		info, err := os.Stat(path)
+		if err != nil || time.Since(info.ModTime()) < staleAfter {
+			return nil, false
+		}
+		// Stale: remove it and try once more.
+		os.Remove(path)
+	}
+	return nil, false
+}
+
+// releaseLock removes the lock file if it still holds token, so a holder
+// whose lock was taken over as stale does not free the new holder's lock.
+func releaseLock(path, token string) {
+	data, err := os.ReadFile(path)
+	if err != nil || string(data) != token {
+		return
+	}
+	os.Remove(path)
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"maps"
 	"os"
 	"path/filepath"
 	"sync"
@@ -24,6 +25,14 @@ type State struct {
 
 	// Failover tracks gateway-wide model failover state.
 	Failover FailoverState `json:"failover,omitempty"`
+
+	// VoiceReplies holds users' /voice on|off choices, keyed by
+	// "channel:sender_id". Users without an entry get the default.
+	VoiceReplies map[string]bool `json:"voice_replies,omitempty"`
+
+	// DisabledSkills holds skills turned off with /skills disable. They stay
+	// on disk but are left out of the system prompt.
+	DisabledSkills map[string]bool `json:"disabled_skills,omitempty"`
 }
 
 // FailoverState contains persisted circuit-breaker state for model routing.
@@ -43,6 +52,26 @@ type FailoverState struct {
 	LastSwitchbackProbe       string    `json:"last_switchback_probe,omitempty"`
 	SwitchbackPromptSent      bool      `json:"switchback_prompt_sent,omitempty"`
 	SwitchEpoch               int64     `json:"switch_epoch"`
+	// ProbeFailures counts consecutive failed probes per probed model; each
+	// failure doubles that model's probe backoff.
+	ProbeFailures map[string]int `json:"probe_failures,omitempty"`
+	// Breakers tracks per-model circuit breakers; an open breaker keeps the
+	// model out of the failover chain until OpenUntil.
+	Breakers map[string]BreakerState `json:"breakers,omitempty"`
+}
+
+// BreakerState is one model's circuit breaker.
+type BreakerState struct {
+	Failures       int       `json:"failures"`                   // Consecutive failures within the window
+	FirstFailureAt time.Time `json:"first_failure_at,omitempty"` // Start of the current window
+	OpenUntil      time.Time `json:"open_until,omitempty"`       // Skipped until then
+}
+
+// Clone returns a copy of fs that shares no maps with it.
+func (fs FailoverState) Clone() FailoverState {
+	fs.ProbeFailures = maps.Clone(fs.ProbeFailures)
+	fs.Breakers = maps.Clone(fs.Breakers)
+	return fs
 }
 
 // Manager manages persistent state with atomic saves.
@@ -163,6 +192,59 @@ func (sm *Manager) SetFailoverState(fs FailoverState) error {
 	return nil
 }
 
+// GetVoiceReplies returns the user's spoken-reply preference and whether
+// they set one.
+func (sm *Manager) GetVoiceReplies(user string) (enabled, set bool) {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+	enabled, set = sm.state.VoiceReplies[user]
+	return enabled, set
+}
+
+// SetVoiceReplies atomically records the user's spoken-reply preference.
+func (sm *Manager) SetVoiceReplies(user string, enabled bool) error {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	if sm.state.VoiceReplies == nil {
+		sm.state.VoiceReplies = make(map[string]bool)
+	}
+	sm.state.VoiceReplies[user] = enabled
+	sm.state.Timestamp = time.Now()
+	if err := sm.saveAtomic(); err != nil {
+		return fmt.Errorf("failed to save state atomically: %w", err)
+	}
+	return nil
+}
+
+// IsSkillDisabled reports whether the skill was turned off.
+func (sm *Manager) IsSkillDisabled(name string) bool {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+	return sm.state.DisabledSkills[name]
+}
+
+// SetSkillEnabled atomically records whether the skill is loaded into the
+// system prompt. Skills are enabled unless disabled here.
+func (sm *Manager) SetSkillEnabled(name string, enabled bool) error {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	if enabled {
+		delete(sm.state.DisabledSkills, name)
+	} else {
+		if sm.state.DisabledSkills == nil {
+			sm.state.DisabledSkills = make(map[string]bool)
+		}
+		sm.state.DisabledSkills[name] = true
+	}
+	sm.state.Timestamp = time.Now()
+	if err := sm.saveAtomic(); err != nil {
+		return fmt.Errorf("failed to save state atomically: %w", err)
+	}
+	return nil
+}
+
 // saveAtomic performs an atomic save using temp file + rename.
 // This ensures that the state file is never corrupted:
 // 1. Write to a temp file
@@ -6,6 +6,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 )
 
 func TestAtomicSave(t *testing.T) {
@@ -241,3 +242,94 @@ func TestSetFailoverStatePersistence(t *testing.T) {
 		t.Fatalf("unexpected failover state after reload: %+v", got)
 	}
 }
+
+func TestTryLock(t *testing.T) {
+	sm := NewManager(t.TempDir())
+
+	release, ok := sm.TryLock("probe", time.Minute)
+	if !ok {
+		t.Fatal("TryLock() on a free lock should succeed")
+	}
+	other := NewManager(sm.workspace) // Second process sharing the workspace
+	if _, ok := other.TryLock("probe", time.Minute); ok {
+		t.Fatal("TryLock() should fail while the lock is held")
+	}
+
+	release()
+	release2, ok := other.TryLock("probe", time.Minute)
+	if !ok {
+		t.Fatal("TryLock() should succeed after release")
+	}
+	defer release2()
+}
+
+func TestTryLock_TakesOverStaleLock(t *testing.T) {
+	sm := NewManager(t.TempDir())
+	staleRelease, ok := sm.TryLock("probe", time.Minute)
+	if !ok {
+		t.Fatal("TryLock() on a free lock should succeed")
+	}
+	lockPath := filepath.Join(sm.workspace, "state", "probe.lock")
+	old := time.Now().Add(-2 * time.Minute)
+	if err := os.Chtimes(lockPath, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	release, ok := sm.TryLock("probe", time.Minute)
+	if !ok {
+		t.Fatal("TryLock() should take over a stale lock")
+	}
+	// The crashed holder's late release must not free the new lock.
+	staleRelease()
+	if _, err := os.Stat(lockPath); err != nil {
+		t.Fatalf("lock file removed by stale holder: %v", err)
+	}
+	release()
+	if _, err := os.Stat(lockPath); !os.IsNotExist(err) {
+		t.Fatalf("lock file still present after release: %v", err)
+	}
+}
+
+func TestVoiceRepliesPersistence(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	sm := NewManager(tmpDir)
+	if _, set := sm.GetVoiceReplies("telegram:1"); set {
+		t.Fatal("expected no preference before one is set")
+	}
+	if err := sm.SetVoiceReplies("telegram:1", false); err != nil {
+		t.Fatalf("SetVoiceReplies failed: %v", err)
+	}
+
+	sm2 := NewManager(tmpDir)
+	enabled, set := sm2.GetVoiceReplies("telegram:1")
+	if !set || enabled {
+		t.Fatalf("GetVoiceReplies after reload = (%v, %v), want (false, true)", enabled, set)
+	}
+	if _, set := sm2.GetVoiceReplies("telegram:2"); set {
+		t.Fatal("preference leaked to another user")
+	}
+}
+
+func TestDisabledSkillsPersistence(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	sm := NewManager(tmpDir)
+	if sm.IsSkillDisabled("weather") {
+		t.Fatal("skills should be enabled by default")
+	}
+	if err := sm.SetSkillEnabled("weather", false); err != nil {
+		t.Fatalf("SetSkillEnabled failed: %v", err)
+	}
+
+	sm2 := NewManager(tmpDir)
+	if !sm2.IsSkillDisabled("weather") {
+		t.Fatal("disabled skill not persisted")
+	}
+	if err := sm2.SetSkillEnabled("weather", true); err != nil {
+		t.Fatalf("SetSkillEnabled failed: %v", err)
+	}
+	if NewManager(tmpDir).IsSkillDisabled("weather") {
+		t.Fatal("re-enabled skill still disabled after reload")
+	}
+}
@@ -109,7 +109,11 @@ func (t *ImportAttachmentTool) Execute(ctx context.Context, args map[string]inte
 		_ = t.store.MarkImported(attachmentID, resolvedTarget)
 	}
 
-	return NewToolResult(fmt.Sprintf("Attachment imported: %s (%d bytes)", resolvedTarget, bytesCopied))
+	msg := fmt.Sprintf("Attachment imported: %s (%d bytes)", resolvedTarget, bytesCopied)
+	if documentKind(resolvedTarget) != "" {
+		msg += ". Use read_document to extract its text."
+	}
+	return NewToolResult(msg)
 }
 
 func copyFile(src, dst string) (int64, error) {
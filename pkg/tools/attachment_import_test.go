@@ -55,3 +55,45 @@ func TestImportAttachmentToolRejectsOutsideRoot(t *testing.T) {
 		t.Fatalf("unexpected error: %s", res.ForLLM)
 	}
 }
+
+func TestListAndFindAttachmentTools(t *testing.T) {
+	workspace := t.TempDir()
+	store := attachments.NewStore(workspace)
+	for _, f := range []struct{ chat, name, mime, kind string }{
+		{"1", "report.pdf", "application/pdf", "document"},
+		{"1", "photo.jpg", "image/jpeg", "photo"},
+		{"2", "other.pdf", "application/pdf", "document"},
+	} {
+		src := filepath.Join(workspace, f.name)
+		if err := os.WriteFile(src, []byte(f.name), 0644); err != nil {
+			t.Fatalf("write src: %v", err)
+		}
+		if _, err := store.SaveFromLocalFile("telegram", f.chat, "u1", "m1", f.name, f.mime, f.kind, src); err != nil {
+			t.Fatalf("save attachment: %v", err)
+		}
+	}
+	ctx := WithToolContext(context.Background(), "telegram", "1")
+
+	res := NewListAttachmentsTool(store).Execute(ctx, map[string]interface{}{"kind": "document"})
+	if res.IsError || !strings.Contains(res.ForLLM, "report.pdf") || strings.Contains(res.ForLLM, "other.pdf") ||
+		strings.Contains(res.ForLLM, "photo.jpg") {
+		t.Fatalf("list_attachments should show only this chat's documents: %s", res.ForLLM)
+	}
+	res = NewListAttachmentsTool(store).Execute(ctx, map[string]interface{}{"since": "yesterday"})
+	if !res.IsError {
+		t.Fatalf("expected an error for an unparseable date: %s", res.ForLLM)
+	}
+
+	find := NewFindAttachmentTool(store)
+	res = find.Execute(ctx, map[string]interface{}{"mime_type": "image/"})
+	if res.IsError || !strings.Contains(res.ForLLM, "photo.jpg") || strings.Contains(res.ForLLM, ".pdf") {
+		t.Fatalf("find_attachment by MIME prefix: %s", res.ForLLM)
+	}
+	res = find.Execute(ctx, map[string]interface{}{"name": "OTHER", "chat_id": "2"})
+	if res.IsError || !strings.Contains(res.ForLLM, "other.pdf") {
+		t.Fatalf("find_attachment in another chat: %s", res.ForLLM)
+	}
+	if res := find.Execute(ctx, map[string]interface{}{}); !res.IsError {
+		t.Fatalf("find_attachment without criteria should fail: %s", res.ForLLM)
+	}
+}
@@ -0,0 +1,199 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/attachments"
+)
+
+// defaultAttachmentResults caps list_attachments and find_attachment output.
+const defaultAttachmentResults = 20
+
+// ListAttachmentsTool lists saved attachments, by default those of the
+// current chat, so the model can find an ID without having seen its marker.
+type ListAttachmentsTool struct {
+	store          *attachments.Store
+	defaultChannel string
+	defaultChatID  string
+}
+
+func NewListAttachmentsTool(store *attachments.Store) *ListAttachmentsTool {
+	return &ListAttachmentsTool{store: store}
+}
+
+func (t *ListAttachmentsTool) Name() string {
+	return "list_attachments"
+}
+
+func (t *ListAttachmentsTool) Description() string {
+	return "List files the user sent earlier (newest first) with their attachment IDs, for use with import_attachment. Defaults to the current chat; filter by kind or date."
+}
+
+func (t *ListAttachmentsTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"channel": map[string]interface{}{
+				"type":        "string",
+				"description": "Optional: channel the attachment was sent on (default: current)",
+			},
+			"chat_id": map[string]interface{}{
+				"type":        "string",
+				"description": "Optional: chat the attachment was sent in (default: current)",
+			},
+			"kind": map[string]interface{}{
+				"type":        "string",
+				"description": "Optional: photo, document, audio or voice",
+			},
+			"since": map[string]interface{}{
+				"type":        "string",
+				"description": "Optional: only attachments sent on or after this date (YYYY-MM-DD or RFC 3339)",
+			},
+			"until": map[string]interface{}{
+				"type":        "string",
+				"description": "Optional: only attachments sent before this date (YYYY-MM-DD or RFC 3339)",
+			},
+			"limit": map[string]interface{}{
+				"type":        "integer",
+				"description": fmt.Sprintf("Maximum results (default %d)", defaultAttachmentResults),
+			},
+		},
+	}
+}
+
+func (t *ListAttachmentsTool) SetContext(channel, chatID string) {
+	t.defaultChannel = channel
+	t.defaultChatID = chatID
+}
+
+func (t *ListAttachmentsTool) Execute(ctx context.Context, args map[string]interface{}) *ToolResult {
+	q := attachmentScope(ctx, args, t.defaultChannel, t.defaultChatID)
+	q.Kind, _ = args["kind"].(string)
+	var err error
+	if q.Since, err = parseAttachmentDate(args, "since"); err != nil {
+		return ErrorResult(err.Error())
+	}
+	if q.Until, err = parseAttachmentDate(args, "until"); err != nil {
+		return ErrorResult(err.Error())
+	}
+	q.Limit = attachmentLimit(args)
+	return formatAttachments(t.store.Find(q))
+}
+
+// FindAttachmentTool searches saved attachments by name or MIME type.
+type FindAttachmentTool struct {
+	store          *attachments.Store
+	defaultChannel string
+	defaultChatID  string
+}
+
+func NewFindAttachmentTool(store *attachments.Store) *FindAttachmentTool {
+	return &FindAttachmentTool{store: store}
+}
+
+func (t *FindAttachmentTool) Name() string {
+	return "find_attachment"
+}
+
+func (t *FindAttachmentTool) Description() string {
+	return "Search files the user sent earlier by part of the file name or by MIME type (e.g. application/pdf, or image/ for any image). Defaults to the current chat."
+}
+
+func (t *FindAttachmentTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"name": map[string]interface{}{
+				"type":        "string",
+				"description": "Part of the file name, case-insensitive",
+			},
+			"mime_type": map[string]interface{}{
+				"type":        "string",
+				"description": "Exact MIME type, or a prefix ending in / such as image/",
+			},
+			"channel": map[string]interface{}{
+				"type":        "string",
+				"description": "Optional: channel the attachment was sent on (default: current)",
+			},
+			"chat_id": map[string]interface{}{
+				"type":        "string",
+				"description": "Optional: chat the attachment was sent in (default: current)",
+			},
+			"limit": map[string]interface{}{
+				"type":        "integer",
+				"description": fmt.Sprintf("Maximum results (default %d)", defaultAttachmentResults),
+			},
+		},
+	}
+}
+
+func (t *FindAttachmentTool) SetContext(channel, chatID string) {
+	t.defaultChannel = channel
+	t.defaultChatID = chatID
+}
+
+func (t *FindAttachmentTool) Execute(ctx context.Context, args map[string]interface{}) *ToolResult {
+	q := attachmentScope(ctx, args, t.defaultChannel, t.defaultChatID)
+	q.NameContains, _ = args["name"].(string)
+	q.MIMEType, _ = args["mime_type"].(string)
+	if strings.TrimSpace(q.NameContains) == "" && strings.TrimSpace(q.MIMEType) == "" {
+		return ErrorResult("name or mime_type is required")
+	}
+	q.Limit = attachmentLimit(args)
+	return formatAttachments(t.store.Find(q))
+}
+
+// attachmentScope returns a query for the channel and chat in args, falling
+// back to the conversation the call belongs to when neither is given.
+func attachmentScope(ctx context.Context, args map[string]interface{}, fallbackChannel, fallbackChatID string) attachments.Query {
+	channel, _ := args["channel"].(string)
+	chatID, _ := args["chat_id"].(string)
+	if channel == "" && chatID == "" {
+		channel, chatID = ToolContext(ctx, fallbackChannel, fallbackChatID)
+	}
+	return attachments.Query{Channel: channel, ChatID: chatID}
+}
+
+func attachmentLimit(args map[string]interface{}) int {
+	if v, ok := args["limit"].(float64); ok && v > 0 {
+		return int(v)
+	}
+	return defaultAttachmentResults
+}
+
+func parseAttachmentDate(args map[string]interface{}, key string) (time.Time, error) {
+	v, _ := args[key].(string)
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return time.Time{}, nil
+	}
+	if ts, err := time.Parse(time.RFC3339, v); err == nil {
+		return ts, nil
+	}
+	ts, err := time.ParseInLocation("2006-01-02", v, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s must be YYYY-MM-DD or RFC 3339, got %q", key, v)
+	}
+	return ts, nil
+}
+
+func formatAttachments(recs []attachments.Record) *ToolResult {
+	if len(recs) == 0 {
+		return NewToolResult("No matching attachments.")
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%d attachment(s), newest first:\n", len(recs))
+	for _, r := range recs {
+		fmt.Fprintf(&sb, "- %s: %s (%s, %s, %d bytes) sent %s in %s:%s",
+			r.ID, r.Name, r.Kind, r.MIMEType, r.SizeBytes,
+			r.CreatedAt.Local().Format("2006-01-02 15:04"), r.Channel, r.ChatID)
+		if r.ImportedPath != "" {
+			fmt.Fprintf(&sb, ", imported to %s", r.ImportedPath)
+		}
+		sb.WriteString("\n")
+	}
+	return NewToolResult(strings.TrimRight(sb.String(), "\n"))
+}
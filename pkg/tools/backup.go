@@ -0,0 +1,289 @@
+package tools
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+const (
+	// backupsDir holds file snapshots, relative to the workspace.
+	backupsDir = ".picoclaw/backups"
+	// defaultMaxBackupsPerFile bounds the snapshots kept for one file.
+	defaultMaxBackupsPerFile = 10
+	// defaultMaxBackupsBytes bounds all snapshots together; the oldest go
+	// first.
+	defaultMaxBackupsBytes = 50 << 20
+	// backupAbsentSuffix marks a snapshot of a file that did not exist, so
+	// undoing its creation removes it.
+	backupAbsentSuffix = ".absent"
+	// backupTimeFormat names snapshots so they sort chronologically.
+	backupTimeFormat = "20060102T150405.000000000"
+)
+
+// backupsMu serializes snapshot, restore and prune across all tools.
+var backupsMu sync.Mutex
+
+// FileBackups snapshots files before write_file, edit_file and append_file
+// change them, so undo_file can restore the previous content. Each file
+// gets a directory named by the hash of its path, holding a "path" file
+// and one snapshot per change named by its timestamp.
+type FileBackups struct {
+	dir        string
+	maxPerFile int
+	maxBytes   int64
+	now        func() time.Time
+}
+
+// NewFileBackups keeps snapshots under the workspace. It returns nil for an
+// empty workspace; a nil *FileBackups does nothing.
+func NewFileBackups(workspace string) *FileBackups {
+	if workspace == "" {
+		return nil
+	}
+	return &FileBackups{
+		dir:        filepath.Join(workspace, backupsDir),
+		maxPerFile: defaultMaxBackupsPerFile,
+		maxBytes:   defaultMaxBackupsBytes,
+		now:        time.Now,
+	}
+}
+
+// Snapshot saves the current content of path (an absolute path). Failures
+// are logged rather than returned: a missing backup should not block the
+// edit.
+func (b *FileBackups) Snapshot(path string) {
+	if b == nil {
+		return
+	}
+	if err := b.snapshot(path); err != nil {
+		logger.WarnCF("tool", "Failed to back up file before editing",
+			map[string]interface{}{
+				"path":  path,
+				"error": err.Error(),
+			})
+	}
+}
+
+func (b *FileBackups) snapshot(path string) error {
+	backupsMu.Lock()
+	defer backupsMu.Unlock()
+
+	data, err := os.ReadFile(path)
+	absent := os.IsNotExist(err)
+	if err != nil && !absent {
+		return err
+	}
+	if int64(len(data)) > b.maxBytes {
+		return fmt.Errorf("file is larger than the backup limit (%d bytes)", b.maxBytes)
+	}
+
+	fileDir := b.fileDir(path)
+	if err := os.MkdirAll(fileDir, 0700); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(fileDir, "path"), []byte(path), 0600); err != nil {
+		return err
+	}
+	// Coarse clocks can repeat a timestamp; nudge it so names stay unique
+	// and ordered.
+	taken := b.now().UTC()
+	for snapshotExists(fileDir, taken) {
+		taken = taken.Add(time.Nanosecond)
+	}
+	name := taken.Format(backupTimeFormat)
+	if absent {
+		name += backupAbsentSuffix
+	}
+	if err := os.WriteFile(filepath.Join(fileDir, name), data, 0600); err != nil {
+		return err
+	}
+	return b.pruneLocked(fileDir)
+}
+
+// Restore puts back the newest snapshot of path and drops it, so repeated
+// calls step further back. It returns when that snapshot was taken.
+func (b *FileBackups) Restore(path string) (time.Time, error) {
+	if b == nil {
+		return time.Time{}, fmt.Errorf("file backups are not available")
+	}
+	backupsMu.Lock()
+	defer backupsMu.Unlock()
+
+	fileDir := b.fileDir(path)
+	snapshots, err := listSnapshots(fileDir)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if len(snapshots) == 0 {
+		return time.Time{}, fmt.Errorf("no backup of %s to restore", path)
+	}
+	latest := snapshots[len(snapshots)-1]
+	snapshotPath := filepath.Join(fileDir, latest)
+
+	if strings.HasSuffix(latest, backupAbsentSuffix) {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return time.Time{}, err
+		}
+	} else {
+		data, err := os.ReadFile(snapshotPath)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if err := writeFileAtomic(path, data); err != nil {
+			return time.Time{}, err
+		}
+	}
+	if err := os.Remove(snapshotPath); err != nil {
+		return time.Time{}, err
+	}
+	if len(snapshots) == 1 {
+		os.RemoveAll(fileDir)
+	}
+	taken, _ := time.Parse(backupTimeFormat, strings.TrimSuffix(latest, backupAbsentSuffix))
+	return taken, nil
+}
+
+func (b *FileBackups) fileDir(path string) string {
+	sum := sha256.Sum256([]byte(filepath.Clean(path)))
+	return filepath.Join(b.dir, hex.EncodeToString(sum[:8]))
+}
+
+// pruneLocked keeps the newest maxPerFile snapshots in fileDir, then drops
+// the oldest snapshots of any file until all fit in maxBytes.
+func (b *FileBackups) pruneLocked(fileDir string) error {
+	snapshots, err := listSnapshots(fileDir)
+	if err != nil {
+		return err
+	}
+	for len(snapshots) > b.maxPerFile {
+		os.Remove(filepath.Join(fileDir, snapshots[0]))
+		snapshots = snapshots[1:]
+	}
+
+	type snapshotFile struct {
+		path string
+		name string
+		size int64
+	}
+	var all []snapshotFile
+	var total int64
+	dirs, err := os.ReadDir(b.dir)
+	if err != nil {
+		return err
+	}
+	for _, d := range dirs {
+		if !d.IsDir() {
+			continue
+		}
+		names, err := listSnapshots(filepath.Join(b.dir, d.Name()))
+		if err != nil {
+			continue
+		}
+		for _, name := range names {
+			p := filepath.Join(b.dir, d.Name(), name)
+			info, err := os.Stat(p)
+			if err != nil {
+				continue
+			}
+			all = append(all, snapshotFile{path: p, name: name, size: info.Size()})
+			total += info.Size()
+		}
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].name < all[j].name })
+	for len(all) > 0 && total > b.maxBytes {
+		os.Remove(all[0].path)
+		total -= all[0].size
+		all = all[1:]
+	}
+	return nil
+}
+
+func snapshotExists(fileDir string, taken time.Time) bool {
+	name := filepath.Join(fileDir, taken.Format(backupTimeFormat))
+	for _, p := range []string{name, name + backupAbsentSuffix} {
+		if _, err := os.Stat(p); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// listSnapshots returns the snapshot names in fileDir, oldest first.
+func listSnapshots(fileDir string) ([]string, error) {
+	entries, err := os.ReadDir(fileDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var names []string
+	for _, e := range entries {
+		if !e.IsDir() && e.Name() != "path" {
+			names = append(names, e.Name())
+		}
+	}
+	sort.Strings(names) // Timestamp names sort chronologically
+	return names, nil
+}
+
+// UndoFileTool restores the content a file had before the last
+// write_file, edit_file or append_file call changed it.
+type UndoFileTool struct {
+	workspace string
+	restrict  bool
+	backups   *FileBackups
+}
+
+func NewUndoFileTool(workspace string, restrict bool) *UndoFileTool {
+	return &UndoFileTool{workspace: workspace, restrict: restrict, backups: NewFileBackups(workspace)}
+}
+
+func (t *UndoFileTool) Name() string {
+	return "undo_file"
+}
+
+func (t *UndoFileTool) Description() string {
+	return "Undo the last write_file, edit_file or append_file change to a file, restoring its previous content. Call again to step further back. A file created by write_file is removed."
+}
+
+func (t *UndoFileTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"path": map[string]interface{}{
+				"type":        "string",
+				"description": "Path to the file to restore",
+			},
+		},
+		"required": []string{"path"},
+	}
+}
+
+func (t *UndoFileTool) Execute(ctx context.Context, args map[string]interface{}) *ToolResult {
+	path, ok := args["path"].(string)
+	if !ok {
+		return ErrorResult("path is required")
+	}
+
+	resolvedPath, err := validatePath(path, t.workspace, t.restrict)
+	if err != nil {
+		return ErrorResult(err.Error())
+	}
+
+	taken, err := t.backups.Restore(resolvedPath)
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("failed to undo: %v", err))
+	}
+	return SilentResult(fmt.Sprintf("Restored %s to its content from %s", path, taken.Local().Format("2006-01-02 15:04:05")))
+}
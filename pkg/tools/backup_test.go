@@ -0,0 +1,110 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestUndoFile_RestoresEditsInReverse(t *testing.T) {
+	workspace := t.TempDir()
+	ctx := context.Background()
+	path := filepath.Join(workspace, "notes.txt")
+	undo := NewUndoFileTool(workspace, true)
+
+	steps := []struct {
+		tool Tool
+		args map[string]interface{}
+	}{
+		{NewWriteFileTool(workspace, true), map[string]interface{}{"path": "notes.txt", "content": "one\n"}},
+		{NewEditFileTool(workspace, true), map[string]interface{}{"path": "notes.txt", "old_text": "one", "new_text": "two"}},
+		{NewAppendFileTool(workspace, true), map[string]interface{}{"path": "notes.txt", "content": "three\n"}},
+	}
+	for _, s := range steps {
+		if result := s.tool.Execute(ctx, s.args); result.IsError {
+			t.Fatalf("%s failed: %s", s.tool.Name(), result.ForLLM)
+		}
+	}
+
+	for _, want := range []string{"two\n", "one\n"} {
+		if result := undo.Execute(ctx, map[string]interface{}{"path": "notes.txt"}); result.IsError {
+			t.Fatalf("undo failed: %s", result.ForLLM)
+		}
+		data, _ := os.ReadFile(path)
+		if string(data) != want {
+			t.Errorf("after undo content = %q, want %q", data, want)
+		}
+	}
+
+	// Undoing the write that created the file removes it
+	if result := undo.Execute(ctx, map[string]interface{}{"path": "notes.txt"}); result.IsError {
+		t.Fatalf("undo failed: %s", result.ForLLM)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("file still exists after undoing its creation: %v", err)
+	}
+
+	result := undo.Execute(ctx, map[string]interface{}{"path": "notes.txt"})
+	if !result.IsError || !strings.Contains(result.ForLLM, "no backup") {
+		t.Errorf("undo with no backups = %q, want an error", result.ForLLM)
+	}
+}
+
+func TestFileBackups_Caps(t *testing.T) {
+	workspace := t.TempDir()
+	b := NewFileBackups(workspace)
+	b.maxPerFile = 3
+	b.maxBytes = 25
+
+	a := filepath.Join(workspace, "a.txt")
+	for _, content := range []string{"a1", "a2", "a3", "a4", "a5"} {
+		os.WriteFile(a, []byte(content), 0644)
+		b.Snapshot(a)
+	}
+	snapshots, _ := listSnapshots(b.fileDir(a))
+	if len(snapshots) != 3 {
+		t.Fatalf("kept %d snapshots of a.txt, want 3", len(snapshots))
+	}
+
+	// A 20-byte snapshot pushes the total over 25 bytes; a.txt's oldest go.
+	big := filepath.Join(workspace, "big.txt")
+	os.WriteFile(big, []byte(strings.Repeat("x", 20)), 0644)
+	b.Snapshot(big)
+	snapshots, _ = listSnapshots(b.fileDir(a))
+	if len(snapshots) != 2 {
+		t.Errorf("kept %d snapshots of a.txt after the size cap, want 2", len(snapshots))
+	}
+	if _, err := b.Restore(a); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(a); string(data) != "a5" {
+		t.Errorf("restored %q, want the newest snapshot a5", data)
+	}
+}
+
+func TestFileBackups_SnapshotsArePrivate(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file modes are not enforced on Windows")
+	}
+	workspace := t.TempDir()
+	path := filepath.Join(workspace, "secrets.env")
+	os.WriteFile(path, []byte("TOKEN=abc\n"), 0600)
+
+	b := NewFileBackups(workspace)
+	if err := b.snapshot(path); err != nil {
+		t.Fatalf("snapshot() error: %v", err)
+	}
+	entries, err := os.ReadDir(b.fileDir(path))
+	if err != nil || len(entries) == 0 {
+		t.Fatalf("no snapshot written: %v", err)
+	}
+	for _, e := range entries {
+		info, _ := e.Info()
+		if perm := info.Mode().Perm(); perm&0o077 != 0 {
+			t.Errorf("%s has mode %o, want it readable by the owner only", e.Name(), perm)
+		}
+	}
+}
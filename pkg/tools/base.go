@@ -11,12 +11,133 @@ type Tool interface {
 }
 
 // ContextualTool is an optional interface that tools can implement
-// to receive the current message context (channel, chatID)
+// to receive the current message context (channel, chatID).
+//
+// The registry passes the context of each call through ctx (see
+// WithToolContext), so concurrent conversations never share it.
+// SetContext only sets the fallback used when ctx carries none, e.g. when
+// the tool is executed directly.
 type ContextualTool interface {
 	Tool
 	SetContext(channel, chatID string)
 }
 
+type toolContextKey struct{}
+
+type toolContext struct {
+	channel string
+	chatID  string
+}
+
+// WithToolContext returns a copy of ctx carrying the channel and chat ID of
+// the conversation a tool call belongs to.
+func WithToolContext(ctx context.Context, channel, chatID string) context.Context {
+	return context.WithValue(ctx, toolContextKey{}, toolContext{channel: channel, chatID: chatID})
+}
+
+// ToolContext returns the channel and chat ID attached by WithToolContext,
+// or fallbackChannel/fallbackChatID when ctx carries none.
+func ToolContext(ctx context.Context, fallbackChannel, fallbackChatID string) (channel, chatID string) {
+	if tc, ok := ctx.Value(toolContextKey{}).(toolContext); ok {
+		return tc.channel, tc.chatID
+	}
+	return fallbackChannel, fallbackChatID
+}
+
+type senderIDKey struct{}
+
+// WithSenderID returns a copy of ctx carrying the ID of the user whose
+// message led to the tool call, for tools limited to admins.
+func WithSenderID(ctx context.Context, senderID string) context.Context {
+	return context.WithValue(ctx, senderIDKey{}, senderID)
+}
+
+// SenderID returns the ID attached by WithSenderID, or "".
+func SenderID(ctx context.Context) string {
+	id, _ := ctx.Value(senderIDKey{}).(string)
+	return id
+}
+
+// AdminCheck reports whether senderID may use admin tools on channel.
+type AdminCheck func(channel, senderID string) bool
+
+// allows reports whether the sender of the call ctx belongs to passes the
+// check. A nil AdminCheck allows everyone.
+func (c AdminCheck) allows(ctx context.Context) bool {
+	if c == nil {
+		return true
+	}
+	channel, _ := ToolContext(ctx, "", "")
+	return c(channel, SenderID(ctx))
+}
+
+type toolCallIDKey struct{}
+
+// WithToolCallID returns a copy of ctx carrying the ID the LLM gave the
+// tool call, for tools that correlate work with it (CallbackTool).
+func WithToolCallID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, toolCallIDKey{}, id)
+}
+
+// ToolCallID returns the ID attached by WithToolCallID, or "".
+func ToolCallID(ctx context.Context) string {
+	id, _ := ctx.Value(toolCallIDKey{}).(string)
+	return id
+}
+
+type correlationIDKey struct{}
+
+// WithCorrelationID returns a copy of ctx carrying the turn's correlation
+// ID, so the registry's logs can be tied to the turn.
+func WithCorrelationID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, correlationIDKey{}, id)
+}
+
+// CorrelationID returns the ID attached by WithCorrelationID, or "".
+func CorrelationID(ctx context.Context) string {
+	id, _ := ctx.Value(correlationIDKey{}).(string)
+	return id
+}
+
+type progressKey struct{}
+
+// WithProgress returns a copy of ctx carrying a sink for live output, for
+// long-running tools (ExecTool) to report progress before they return.
+func WithProgress(ctx context.Context, sink func(output string)) context.Context {
+	return context.WithValue(ctx, progressKey{}, sink)
+}
+
+// Progress returns the sink attached by WithProgress, or nil.
+func Progress(ctx context.Context) func(output string) {
+	sink, _ := ctx.Value(progressKey{}).(func(output string))
+	return sink
+}
+
+// ParallelSafeTool is an optional interface for tools that only read state,
+// so several calls from one LLM response can run concurrently. Tools that
+// write files, run commands, or keep per-call state (ContextualTool,
+// AsyncTool) must not report true.
+type ParallelSafeTool interface {
+	Tool
+	ParallelSafe() bool
+}
+
+// SchemaHintTool is an optional interface for tools whose arguments models
+// often get wrong. When SchemaHint reports true, errors caused by the
+// arguments carry a compact summary of the parameter schema, so the retry
+// has the right shape at hand.
+type SchemaHintTool interface {
+	Tool
+	SchemaHint() bool
+}
+
+// RedactingTool is an optional interface for tools whose arguments may
+// carry secrets; RedactArgs returns a copy safe to log, leaving args as is.
+type RedactingTool interface {
+	Tool
+	RedactArgs(args map[string]interface{}) map[string]interface{}
+}
+
 // AsyncCallback is a function type that async tools use to notify completion.
 // When an async tool finishes its work, it calls this callback with the result.
 //
@@ -0,0 +1,122 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// defaultCallbackTimeout applies when a callback tool has no timeout set.
+// The agent's turn is blocked while it waits, so keep it short.
+const defaultCallbackTimeout = time.Minute
+
+// CallbackTool hands a request to an external system, a person or a
+// service reachable through a channel, and blocks until the answer is
+// injected back as a tool answer (bus.NewToolAnswer) for the same tool
+// call ID, or the timeout passes. Users answer in the chat the request
+// was sent to with "/answer <id> <text>".
+type CallbackTool struct {
+	name        string
+	description string
+	bus         *bus.MessageBus
+	channel     string // "" = the chat the tool call came from
+	chatID      string
+	timeout     time.Duration
+}
+
+// NewCallbackTool creates a callback tool that sends its requests to
+// channel/chatID, or to the current chat when they are empty.
+func NewCallbackTool(name, description string, msgBus *bus.MessageBus, channel, chatID string, timeout time.Duration) *CallbackTool {
+	if timeout <= 0 {
+		timeout = defaultCallbackTimeout
+	}
+	return &CallbackTool{
+		name:        name,
+		description: description,
+		bus:         msgBus,
+		channel:     channel,
+		chatID:      chatID,
+		timeout:     timeout,
+	}
+}
+
+func (t *CallbackTool) Name() string {
+	return t.name
+}
+
+func (t *CallbackTool) Description() string {
+	return t.description
+}
+
+func (t *CallbackTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"request": map[string]interface{}{
+				"type":        "string",
+				"description": "What to ask or look up, written so the responder can answer it without other context",
+			},
+		},
+		"required": []string{"request"},
+	}
+}
+
+func (t *CallbackTool) Execute(ctx context.Context, args map[string]interface{}) *ToolResult {
+	request, ok := args["request"].(string)
+	if !ok || request == "" {
+		return ErrorResult("request is required")
+	}
+
+	channel, chatID := t.channel, t.chatID
+	if channel == "" || chatID == "" {
+		channel, chatID = ToolContext(ctx, "", "")
+	}
+	if channel == "" || chatID == "" {
+		return ErrorResult(fmt.Sprintf("%s has no chat to send the request to", t.name))
+	}
+
+	id := ToolCallID(ctx)
+	if id == "" {
+		id = fmt.Sprintf("%s-%d", t.name, time.Now().UnixNano())
+	}
+
+	// Register before publishing so a fast answer is not missed.
+	answers, cancel, err := t.bus.ExpectToolAnswer(id, channel, chatID)
+	if err != nil {
+		return ErrorResult(err.Error())
+	}
+	defer cancel()
+
+	t.bus.PublishOutbound(bus.OutboundMessage{
+		Channel: channel,
+		ChatID:  chatID,
+		Content: fmt.Sprintf("❓ %s request `%s`:\n%s\n\nReply with `/answer %s <text>`.", t.name, id, request, id),
+		Metadata: map[string]interface{}{
+			bus.MetadataType:       "tool_request",
+			bus.MetadataToolCallID: id,
+			"tool":                 t.name,
+			"request":              request,
+		},
+	})
+	logger.InfoCF("tool", "Callback tool waiting for answer",
+		map[string]interface{}{
+			"tool":         t.name,
+			"tool_call_id": id,
+			"channel":      channel,
+			"timeout":      t.timeout.String(),
+		})
+
+	timer := time.NewTimer(t.timeout)
+	defer timer.Stop()
+	select {
+	case answer := <-answers:
+		return SilentResult(fmt.Sprintf("Answer from %s: %s", answer.SenderID, answer.Content))
+	case <-timer.C:
+		return ErrorResult(fmt.Sprintf("No answer to the %s request within %s. Continue without it or tell the user.", t.name, t.timeout))
+	case <-ctx.Done():
+		return ErrorResult(fmt.Sprintf("%s request cancelled: %v", t.name, ctx.Err()))
+	}
+}
@@ -0,0 +1,104 @@
+package tools
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+)
+
+func TestCallbackTool_RoundTrip(t *testing.T) {
+	msgBus := bus.NewMessageBus()
+	tool := NewCallbackTool("ask_operator", "Ask the operator", msgBus, "telegram", "ops", time.Second)
+
+	// Simulated external responder: answers the request it receives.
+	go func() {
+		req, ok := msgBus.SubscribeOutbound(context.Background())
+		if !ok {
+			return
+		}
+		id, _ := req.Metadata[bus.MetadataToolCallID].(string)
+		if req.ChatID != "ops" || !strings.Contains(req.Content, "Deploy to prod?") {
+			t.Errorf("unexpected request %+v", req)
+		}
+		msgBus.PublishInbound(bus.NewToolAnswer("telegram", "alice", "ops", id, "yes, go ahead"))
+	}()
+
+	ctx := WithToolCallID(context.Background(), "call_1")
+	result := tool.Execute(ctx, map[string]interface{}{"request": "Deploy to prod?"})
+	if result.IsError {
+		t.Fatalf("Execute() error: %s", result.ForLLM)
+	}
+	if result.ForLLM != "Answer from alice: yes, go ahead" {
+		t.Fatalf("ForLLM = %q", result.ForLLM)
+	}
+
+	// The answer bypassed the inbound queue, so the agent never sees it.
+	qctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if msg, ok := msgBus.ConsumeInbound(qctx); ok {
+		t.Fatalf("tool answer reached the inbound queue: %+v", msg)
+	}
+}
+
+func TestCallbackTool_TimeoutAndLateAnswer(t *testing.T) {
+	msgBus := bus.NewMessageBus()
+	tool := NewCallbackTool("ask_operator", "Ask the operator", msgBus, "", "", 20*time.Millisecond)
+
+	ctx := WithToolContext(WithToolCallID(context.Background(), "call_2"), "telegram", "42")
+	result := tool.Execute(ctx, map[string]interface{}{"request": "Anyone there?"})
+	if !result.IsError || !strings.Contains(result.ForLLM, "No answer") {
+		t.Fatalf("result = %+v, want timeout error", result)
+	}
+	req, _ := msgBus.SubscribeOutbound(context.Background())
+	if req.ChatID != "42" || !strings.Contains(req.Content, "/answer call_2") {
+		t.Errorf("request went to %q with %q, want the current chat", req.ChatID, req.Content)
+	}
+
+	// A late answer is dropped instead of becoming a user message.
+	msgBus.PublishInbound(bus.NewToolAnswer("telegram", "alice", "42", "call_2", "sorry, late"))
+	qctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if msg, ok := msgBus.ConsumeInbound(qctx); ok {
+		t.Fatalf("late tool answer reached the inbound queue: %+v", msg)
+	}
+}
+
+func TestCallbackTool_IgnoresAnswersFromOtherChats(t *testing.T) {
+	msgBus := bus.NewMessageBus()
+	tool := NewCallbackTool("ask_operator", "Ask the operator", msgBus, "telegram", "ops", 200*time.Millisecond)
+
+	go func() {
+		req, ok := msgBus.SubscribeOutbound(context.Background())
+		if !ok {
+			return
+		}
+		id, _ := req.Metadata[bus.MetadataToolCallID].(string)
+		msgBus.PublishInbound(bus.NewToolAnswer("telegram", "mallory", "other", id, "yes"))
+		msgBus.PublishInbound(bus.NewToolAnswer("discord", "mallory", "ops", id, "yes"))
+		msgBus.PublishInbound(bus.NewToolAnswer("telegram", "alice", "ops", id, "no"))
+	}()
+
+	ctx := WithToolCallID(context.Background(), "call_3")
+	result := tool.Execute(ctx, map[string]interface{}{"request": "Deploy to prod?"})
+	if result.IsError || result.ForLLM != "Answer from alice: no" {
+		t.Fatalf("result = %+v, want only the answer from the asked chat", result)
+	}
+}
+
+func TestCallbackTool_RefusesDuplicateCallID(t *testing.T) {
+	msgBus := bus.NewMessageBus()
+	_, cancel, err := msgBus.ExpectToolAnswer("call_4", "telegram", "ops")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cancel()
+
+	tool := NewCallbackTool("ask_operator", "Ask the operator", msgBus, "telegram", "ops", time.Second)
+	result := tool.Execute(WithToolCallID(context.Background(), "call_4"), map[string]interface{}{"request": "?"})
+	if !result.IsError || !strings.Contains(result.ForLLM, "already waiting") {
+		t.Fatalf("result = %+v, want a duplicate ID error", result)
+	}
+}
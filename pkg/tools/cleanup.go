@@ -0,0 +1,87 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/sipeed/picoclaw/pkg/utils"
+)
+
+// CleanupCacheTool deletes cached media (downloaded attachments, voice
+// notes) to reclaim disk space.
+type CleanupCacheTool struct {
+	dirs    []string
+	keep    func(path string, info os.FileInfo) bool
+	isAdmin AdminCheck
+}
+
+// NewCleanupCacheTool creates a tool that cleans the given cache
+// directories. keep reports files that must survive, e.g. media still
+// referenced by an in-flight turn; it may be nil. Only senders passing
+// isAdmin may run it; a nil isAdmin allows everyone.
+func NewCleanupCacheTool(dirs []string, keep func(path string, info os.FileInfo) bool, isAdmin AdminCheck) *CleanupCacheTool {
+	return &CleanupCacheTool{dirs: dirs, keep: keep, isAdmin: isAdmin}
+}
+
+func (t *CleanupCacheTool) Name() string {
+	return "cleanup_cache"
+}
+
+func (t *CleanupCacheTool) Description() string {
+	return "Delete cached media files (downloaded images, voice notes, attachments) to free disk space. Files still in use are kept. Reports how much space was freed."
+}
+
+func (t *CleanupCacheTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type":       "object",
+		"properties": map[string]interface{}{},
+	}
+}
+
+func (t *CleanupCacheTool) Execute(ctx context.Context, args map[string]interface{}) *ToolResult {
+	if !t.isAdmin.allows(ctx) {
+		return ErrorResult("only the owner or an allowlisted user can clean up the media cache")
+	}
+	res, err := utils.CleanMediaCache(t.dirs, t.keep)
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("cache cleanup failed after freeing %s: %v", FormatBytes(res.BytesFreed), err)).WithError(err)
+	}
+	return SilentResult(FormatCleanupResult(res)).WithDetails(&ResultDetails{
+		Succeeded: res.Removed,
+		Failed:    res.Failed,
+	})
+}
+
+// FormatCleanupResult summarizes a cache cleanup for the user.
+func FormatCleanupResult(res utils.MediaCleanupResult) string {
+	if res.Removed == 0 && res.Skipped == 0 && res.Failed == 0 {
+		return "Media cache is already empty."
+	}
+	files := "files"
+	if res.Removed == 1 {
+		files = "file"
+	}
+	msg := fmt.Sprintf("Freed %s (%d %s removed)", FormatBytes(res.BytesFreed), res.Removed, files)
+	if res.Skipped > 0 {
+		msg += fmt.Sprintf(", kept %d in use", res.Skipped)
+	}
+	if res.Failed > 0 {
+		msg += fmt.Sprintf(", %d could not be removed", res.Failed)
+	}
+	return msg + "."
+}
+
+// FormatBytes renders a byte count with a binary unit, e.g. "1.5 MiB".
+func FormatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,80 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/utils"
+)
+
+func TestCleanupCacheTool_ReportsBytesFreed(t *testing.T) {
+	cache := t.TempDir()
+	os.WriteFile(filepath.Join(cache, "a.jpg"), make([]byte, 3*1024*1024/2), 0o644)
+	os.WriteFile(filepath.Join(cache, "b.jpg"), []byte("in use"), 0o644)
+
+	tool := NewCleanupCacheTool([]string{cache}, func(path string, info os.FileInfo) bool {
+		return filepath.Base(path) == "b.jpg"
+	}, nil)
+	result := tool.Execute(context.Background(), nil)
+
+	if result.IsError {
+		t.Fatalf("unexpected error: %s", result.ForLLM)
+	}
+	if want := "Freed 1.5 MiB (1 file removed), kept 1 in use."; result.ForLLM != want {
+		t.Fatalf("ForLLM = %q, want %q", result.ForLLM, want)
+	}
+
+	again := tool.Execute(context.Background(), nil)
+	if !strings.Contains(again.ForLLM, "Freed 0 B") {
+		t.Fatalf("second run = %q, want nothing freed", again.ForLLM)
+	}
+}
+
+func TestCleanupCacheTool_RefusesNonAdminSender(t *testing.T) {
+	cache := t.TempDir()
+	media := filepath.Join(cache, "a.jpg")
+	os.WriteFile(media, []byte("photo"), 0o644)
+
+	isAdmin := AdminCheck(func(channel, senderID string) bool { return senderID == "owner" })
+	tool := NewCleanupCacheTool([]string{cache}, nil, isAdmin)
+	ctx := WithSenderID(WithToolContext(context.Background(), "telegram", "1"), "someone")
+
+	result := tool.Execute(ctx, nil)
+	if !result.IsError || !strings.Contains(result.ForLLM, "only the owner") {
+		t.Fatalf("non-admin cleanup = %+v, want refusal", result)
+	}
+	if _, err := os.Stat(media); err != nil {
+		t.Fatalf("refused cleanup still removed media: %v", err)
+	}
+
+	if result := tool.Execute(WithSenderID(ctx, "owner"), nil); result.IsError {
+		t.Fatalf("admin cleanup failed: %s", result.ForLLM)
+	}
+	if _, err := os.Stat(media); !os.IsNotExist(err) {
+		t.Fatalf("admin cleanup kept media: %v", err)
+	}
+}
+
+func TestFormatCleanupResult_Empty(t *testing.T) {
+	if got := FormatCleanupResult(utils.MediaCleanupResult{}); got != "Media cache is already empty." {
+		t.Fatalf("got %q", got)
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	tests := map[int64]string{
+		0:               "0 B",
+		1023:            "1023 B",
+		1024:            "1.0 KiB",
+		5 * 1024 * 1024: "5.0 MiB",
+		3 << 40:         "3.0 TiB",
+	}
+	for n, want := range tests {
+		if got := FormatBytes(n); got != want {
+			t.Errorf("FormatBytes(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
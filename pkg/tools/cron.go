@@ -27,16 +27,21 @@ type CronTool struct {
 	mu          sync.RWMutex
 }
 
-// NewCronTool creates a new CronTool
-func NewCronTool(cronService *cron.CronService, executor JobExecutor, msgBus *bus.MessageBus, workspace string) *CronTool {
+// NewCronTool creates a new CronTool. Scheduled shell commands run through
+// execTool; with a nil execTool (e.g. in safe mode) command jobs are refused.
+func NewCronTool(cronService *cron.CronService, executor JobExecutor, msgBus *bus.MessageBus, execTool *ExecTool) *CronTool {
 	return &CronTool{
 		cronService: cronService,
 		executor:    executor,
 		msgBus:      msgBus,
-		execTool:    NewExecTool(workspace, false),
+		execTool:    execTool,
 	}
 }
 
+// errCronCommandsDisabled is returned for command jobs when no exec tool
+// is available.
+const errCronCommandsDisabled = "scheduled shell commands are disabled"
+
 // Name returns the tool name
 func (t *CronTool) Name() string {
 	return "cron"
@@ -107,7 +112,7 @@ func (t *CronTool) Execute(ctx context.Context, args map[string]interface{}) *To
 
 	switch action {
 	case "add":
-		return t.addJob(args)
+		return t.addJob(ctx, args)
 	case "list":
 		return t.listJobs()
 	case "remove":
@@ -121,10 +126,9 @@ func (t *CronTool) Execute(ctx context.Context, args map[string]interface{}) *To
 	}
 }
 
-func (t *CronTool) addJob(args map[string]interface{}) *ToolResult {
+func (t *CronTool) addJob(ctx context.Context, args map[string]interface{}) *ToolResult {
 	t.mu.RLock()
-	channel := t.channel
-	chatID := t.chatID
+	channel, chatID := ToolContext(ctx, t.channel, t.chatID)
 	t.mu.RUnlock()
 
 	if channel == "" || chatID == "" {
@@ -172,6 +176,9 @@ func (t *CronTool) addJob(args map[string]interface{}) *ToolResult {
 	}
 
 	command, _ := args["command"].(string)
+	if command != "" && t.execTool == nil {
+		return ErrorResult(errCronCommandsDisabled + "; schedule a reminder message instead")
+	}
 	if command != "" {
 		// Commands must be processed by agent/exec tool, so deliver must be false (or handled specifically)
 		// Actually, let's keep deliver=false to let the system know it's not a simple chat message
@@ -279,7 +286,12 @@ func (t *CronTool) ExecuteJob(ctx context.Context, job *cron.CronJob) string {
 			"command": job.Payload.Command,
 		}
 
-		result := t.execTool.Execute(ctx, args)
+		var result *ToolResult
+		if t.execTool == nil {
+			result = ErrorResult(errCronCommandsDisabled)
+		} else {
+			result = t.execTool.Execute(ctx, args)
+		}
 		var output string
 		if result.IsError {
 			output = fmt.Sprintf("Error executing scheduled command: %s", result.ForLLM)
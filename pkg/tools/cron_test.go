@@ -0,0 +1,39 @@
+package tools
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+)
+
+func TestCronTool_WithoutExecToolRefusesCommands(t *testing.T) {
+	cs := newTestCronService(t)
+	msgBus := bus.NewMessageBus()
+	tool := NewCronTool(cs, nil, msgBus, nil) // safe mode: no shell
+	ctx := WithToolContext(context.Background(), "telegram", "1")
+
+	res := tool.Execute(ctx, map[string]interface{}{
+		"action":     "add",
+		"message":    "wipe",
+		"command":    "rm -rf ~",
+		"at_seconds": float64(60),
+	})
+	if !res.IsError || !strings.Contains(res.ForLLM, errCronCommandsDisabled) {
+		t.Fatalf("scheduling a command should be refused: %+v", res)
+	}
+	if jobs := cs.ListJobs(true); len(jobs) != 0 {
+		t.Fatalf("no job should be stored, got %d", len(jobs))
+	}
+
+	// A command job stored before safe mode was turned on must not run.
+	job := addAtJob(t, cs, "old", time.Minute)
+	job.Payload.Command = "echo ran"
+	tool.ExecuteJob(context.Background(), job)
+	out, ok := msgBus.SubscribeOutbound(ctx)
+	if !ok || !strings.Contains(out.Content, errCronCommandsDisabled) {
+		t.Fatalf("outbound = %+v, want the command refused", out)
+	}
+}
@@ -0,0 +1,266 @@
+package tools
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/utils"
+)
+
+const (
+	// debugLogsMaxChars caps the output; whole entries are dropped to fit.
+	debugLogsMaxChars = 8000
+	// debugLogsDefaultLimit and debugLogsMaxLimit bound the entries returned.
+	debugLogsDefaultLimit = 50
+	debugLogsMaxLimit     = 500
+)
+
+// logLevelRank orders levels for the minimum-level filter.
+var logLevelRank = map[string]int{"DEBUG": 0, "INFO": 1, "WARN": 2, "ERROR": 3, "FATAL": 4}
+
+// DebugLogsTool returns raw log entries filtered by level, component,
+// correlation ID, text and time window, from either end of the window.
+// diagnose summarizes problems; this is for following one incident.
+type DebugLogsTool struct {
+	logPath func() string
+}
+
+// NewDebugLogsTool creates a DebugLogsTool reading the file returned by
+// logPath on each call.
+func NewDebugLogsTool(logPath func() string) *DebugLogsTool {
+	return &DebugLogsTool{logPath: logPath}
+}
+
+func (t *DebugLogsTool) Name() string {
+	return "debug_logs"
+}
+
+// ParallelSafe reports true: debug_logs only reads the log file.
+func (t *DebugLogsTool) ParallelSafe() bool {
+	return true
+}
+
+func (t *DebugLogsTool) Description() string {
+	return "Read raw log entries for debugging. Filter by minimum level, component, correlation ID, text and a time window; mode tail returns the latest matching entries, head the earliest, so a window can be read from its start."
+}
+
+func (t *DebugLogsTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"mode": map[string]interface{}{
+				"type":        "string",
+				"enum":        []string{"tail", "head"},
+				"description": "tail (default) returns the latest matching entries, head the earliest",
+			},
+			"limit": map[string]interface{}{
+				"type":        "integer",
+				"description": fmt.Sprintf("Maximum entries to return (default %d, max %d)", debugLogsDefaultLimit, debugLogsMaxLimit),
+			},
+			"level": map[string]interface{}{
+				"type":        "string",
+				"enum":        []string{"DEBUG", "INFO", "WARN", "ERROR"},
+				"description": "Minimum level to include",
+			},
+			"component": map[string]interface{}{
+				"type":        "string",
+				"description": "Only entries from this component (e.g. agent, tool, telegram)",
+			},
+			"correlation_id": map[string]interface{}{
+				"type":        "string",
+				"description": "Only entries for this correlation ID",
+			},
+			"contains": map[string]interface{}{
+				"type":        "string",
+				"description": "Only entries whose message or fields contain this text (case-insensitive)",
+			},
+			"after_timestamp": map[string]interface{}{
+				"type":        "string",
+				"description": "Only entries at or after this RFC 3339 time, e.g. 2026-01-02T15:04:05Z",
+			},
+			"before_timestamp": map[string]interface{}{
+				"type":        "string",
+				"description": "Only entries at or before this RFC 3339 time",
+			},
+		},
+	}
+}
+
+// logFilter holds the parsed debug_logs filters.
+type logFilter struct {
+	minLevel      int
+	component     string
+	correlationID string
+	contains      string
+	after, before time.Time
+}
+
+func (t *DebugLogsTool) Execute(ctx context.Context, args map[string]interface{}) *ToolResult {
+	path := t.logPath()
+	if path == "" {
+		return ErrorResult("File logging is disabled, so there are no logs to read. Enable logging.file_enabled in the config.")
+	}
+
+	mode, _ := args["mode"].(string)
+	if mode == "" {
+		mode = "tail"
+	}
+	if mode != "tail" && mode != "head" {
+		return ErrorResult("mode must be tail or head")
+	}
+	limit := debugLogsDefaultLimit
+	if n, ok := numberValue(args["limit"]); ok && n > 0 {
+		limit = min(int(n), debugLogsMaxLimit)
+	}
+	filter, err := parseLogFilter(args)
+	if err != nil {
+		return ErrorResult(err.Error())
+	}
+
+	entries, err := scanLogEntries(path, filter, mode, limit)
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("failed to read log file: %v", err)).WithError(err)
+	}
+	if len(entries) == 0 {
+		return SilentResult("No log entries match.")
+	}
+	return SilentResult(formatLogEntries(entries, mode))
+}
+
+func parseLogFilter(args map[string]interface{}) (logFilter, error) {
+	var f logFilter
+	if level, _ := args["level"].(string); level != "" {
+		rank, ok := logLevelRank[strings.ToUpper(level)]
+		if !ok {
+			return f, fmt.Errorf("unknown level %q", level)
+		}
+		f.minLevel = rank
+	}
+	f.component, _ = args["component"].(string)
+	f.correlationID, _ = args["correlation_id"].(string)
+	contains, _ := args["contains"].(string)
+	f.contains = strings.ToLower(contains)
+	for _, bound := range []struct {
+		arg string
+		dst *time.Time
+	}{{"after_timestamp", &f.after}, {"before_timestamp", &f.before}} {
+		s, _ := args[bound.arg].(string)
+		if s == "" {
+			continue
+		}
+		ts, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			return f, fmt.Errorf("%s must be an RFC 3339 time like 2026-01-02T15:04:05Z", bound.arg)
+		}
+		*bound.dst = ts
+	}
+	return f, nil
+}
+
+func (f logFilter) match(e logger.LogEntry) bool {
+	if logLevelRank[e.Level] < f.minLevel {
+		return false
+	}
+	if f.component != "" && !strings.EqualFold(e.Component, f.component) {
+		return false
+	}
+	if f.correlationID != "" && fmt.Sprint(e.Fields["correlation_id"]) != f.correlationID {
+		return false
+	}
+	if !f.after.IsZero() || !f.before.IsZero() {
+		ts, err := time.Parse(time.RFC3339, e.Timestamp)
+		if err != nil || (!f.after.IsZero() && ts.Before(f.after)) || (!f.before.IsZero() && ts.After(f.before)) {
+			return false
+		}
+	}
+	if f.contains != "" {
+		fields, _ := json.Marshal(e.Fields)
+		if !strings.Contains(strings.ToLower(e.Message+" "+string(fields)), f.contains) {
+			return false
+		}
+	}
+	return true
+}
+
+// scanLogEntries streams the log file and returns up to limit matching
+// entries: the first ones in head mode, the last ones in tail mode.
+func scanLogEntries(path string, filter logFilter, mode string, limit int) ([]logger.LogEntry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var entries []logger.LogEntry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var e logger.LogEntry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil || !filter.match(e) {
+			continue
+		}
+		entries = append(entries, e)
+		if len(entries) == limit && mode == "head" {
+			break
+		}
+		if len(entries) > limit {
+			entries = entries[1:]
+		}
+	}
+	return entries, scanner.Err()
+}
+
+// formatLogEntries renders one line per entry, dropping whole entries to
+// stay under debugLogsMaxChars: the oldest in tail mode, the newest in
+// head mode.
+func formatLogEntries(entries []logger.LogEntry, mode string) string {
+	lines := make([]string, len(entries))
+	for i, e := range entries {
+		line := fmt.Sprintf("%s %s [%s] %s", e.Timestamp, e.Level, e.Component, e.Message)
+		if len(e.Fields) > 0 {
+			fields, _ := json.Marshal(e.Fields)
+			line += " " + string(fields)
+		}
+		lines[i] = utils.Truncate(line, debugLogsMaxChars-100)
+	}
+
+	total := 0
+	kept := 0
+	for kept < len(lines) {
+		i := kept
+		if mode == "tail" {
+			i = len(lines) - 1 - kept
+		}
+		if total+len(lines[i])+1 > debugLogsMaxChars-100 && kept > 0 {
+			break
+		}
+		total += len(lines[i]) + 1
+		kept++
+	}
+	omitted := len(lines) - kept
+	if mode == "tail" {
+		lines = lines[omitted:]
+	} else {
+		lines = lines[:kept]
+	}
+
+	out := strings.Join(lines, "\n")
+	if omitted > 0 {
+		which := "earlier"
+		if mode == "head" {
+			which = "later"
+		}
+		note := fmt.Sprintf("(%d %s entries omitted to fit the output limit)", omitted, which)
+		if mode == "tail" {
+			return note + "\n" + out
+		}
+		return out + "\n" + note
+	}
+	return out
+}
@@ -0,0 +1,86 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestDebugLogsTool_FiltersAndModes(t *testing.T) {
+	path := writeTestLog(t,
+		`{"level":"INFO","timestamp":"2026-01-01T10:00:00Z","component":"agent","message":"Processing message","fields":{"correlation_id":"c1"}}`,
+		`{"level":"INFO","timestamp":"2026-01-01T10:01:00Z","component":"tool","message":"Tool execution started","fields":{"correlation_id":"c1","tool":"exec"}}`,
+		`not json`,
+		`{"level":"ERROR","timestamp":"2026-01-01T10:02:00Z","component":"tool","message":"Tool execution failed","fields":{"correlation_id":"c1"}}`,
+		`{"level":"WARN","timestamp":"2026-01-01T10:03:00Z","component":"telegram","message":"Rate limited","fields":{"correlation_id":"c2"}}`,
+		`{"level":"INFO","timestamp":"2026-01-01T10:04:00Z","component":"agent","message":"Response sent","fields":{"correlation_id":"c1"}}`,
+	)
+	tool := NewDebugLogsTool(func() string { return path })
+
+	messages := func(args map[string]interface{}) string {
+		t.Helper()
+		result := tool.Execute(context.Background(), args)
+		if result.IsError {
+			t.Fatalf("args %v: %s", args, result.ForLLM)
+		}
+		var got []string
+		for _, line := range strings.Split(result.ForLLM, "\n") {
+			if _, rest, ok := strings.Cut(line, "] "); ok {
+				msg, _, _ := strings.Cut(rest, " {")
+				got = append(got, msg)
+			}
+		}
+		return strings.Join(got, ",")
+	}
+
+	tests := []struct {
+		args map[string]interface{}
+		want string
+	}{
+		{map[string]interface{}{"limit": float64(2)}, "Rate limited,Response sent"},
+		{map[string]interface{}{"mode": "head", "limit": float64(2)}, "Processing message,Tool execution started"},
+		{map[string]interface{}{"component": "tool"}, "Tool execution started,Tool execution failed"},
+		{map[string]interface{}{"level": "WARN"}, "Tool execution failed,Rate limited"},
+		{map[string]interface{}{"correlation_id": "c1", "mode": "head", "limit": float64(3)}, "Processing message,Tool execution started,Tool execution failed"},
+		{map[string]interface{}{"contains": "RATE"}, "Rate limited"},
+		{map[string]interface{}{"after_timestamp": "2026-01-01T10:01:00Z", "before_timestamp": "2026-01-01T10:03:00Z"}, "Tool execution started,Tool execution failed,Rate limited"},
+	}
+	for _, tt := range tests {
+		if got := messages(tt.args); got != tt.want {
+			t.Errorf("args %v: got %q, want %q", tt.args, got, tt.want)
+		}
+	}
+
+	result := tool.Execute(context.Background(), map[string]interface{}{"after_timestamp": "yesterday"})
+	if !result.IsError {
+		t.Error("expected an invalid timestamp to be rejected")
+	}
+}
+
+func TestDebugLogsTool_CapsOutputAtWholeEntries(t *testing.T) {
+	var lines []string
+	for i := 0; i < 200; i++ {
+		lines = append(lines, fmt.Sprintf(`{"level":"INFO","timestamp":"2026-01-01T10:00:00Z","component":"agent","message":"entry %03d %s"}`, i, strings.Repeat("x", 80)))
+	}
+	tool := NewDebugLogsTool(func() string { return writeTestLog(t, lines...) })
+
+	for _, mode := range []string{"tail", "head"} {
+		result := tool.Execute(context.Background(), map[string]interface{}{"mode": mode, "limit": float64(200)})
+		if len(result.ForLLM) > debugLogsMaxChars {
+			t.Errorf("%s: output is %d chars, want at most %d", mode, len(result.ForLLM), debugLogsMaxChars)
+		}
+		out := strings.Split(result.ForLLM, "\n")
+		for _, line := range out {
+			if !strings.HasPrefix(line, "2026-01-01T10:00:00Z INFO [agent] entry ") && !strings.Contains(line, "entries omitted") {
+				t.Fatalf("%s: partial or unexpected line %q", mode, line)
+			}
+		}
+		if mode == "tail" && (!strings.Contains(out[0], "earlier entries omitted") || !strings.Contains(out[len(out)-1], "entry 199")) {
+			t.Errorf("tail should keep the newest entries and note the omitted ones")
+		}
+		if mode == "head" && (!strings.Contains(out[0], "entry 000") || !strings.Contains(out[len(out)-1], "later entries omitted")) {
+			t.Errorf("head should keep the earliest entries and note the omitted ones")
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package tools
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/utils"
+)
+
+const (
+	// diagnoseTailBytes bounds how much of the log file is read.
+	diagnoseTailBytes = 256 * 1024
+	// diagnoseMaxGroups bounds how many distinct problems reach the model.
+	diagnoseMaxGroups = 20
+)
+
+// DiagnoseSummarizer turns the grouped log report into a plain-language
+// explanation.
+type DiagnoseSummarizer func(ctx context.Context, prompt string) (string, error)
+
+// DiagnoseTool explains recent warnings and errors from the log file in
+// plain language, so the user does not have to read raw log entries.
+type DiagnoseTool struct {
+	logPath   func() string
+	summarize DiagnoseSummarizer
+}
+
+// NewDiagnoseTool creates a DiagnoseTool reading the file returned by
+// logPath on each call.
+func NewDiagnoseTool(logPath func() string, summarize DiagnoseSummarizer) *DiagnoseTool {
+	return &DiagnoseTool{logPath: logPath, summarize: summarize}
+}
+
+func (t *DiagnoseTool) Name() string {
+	return "diagnose"
+}
+
+func (t *DiagnoseTool) Description() string {
+	return "Explain recent problems in plain language: reads the latest WARN/ERROR log entries, groups them, and summarizes what went wrong and likely causes. Use when the user asks what is wrong or why something failed."
+}
+
+func (t *DiagnoseTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type":       "object",
+		"properties": map[string]interface{}{},
+	}
+}
+
+func (t *DiagnoseTool) Execute(ctx context.Context, args map[string]interface{}) *ToolResult {
+	path := t.logPath()
+	if path == "" {
+		return ErrorResult("File logging is disabled, so there are no logs to diagnose. Enable logging.file_enabled in the config.")
+	}
+
+	data, err := readTail(path, diagnoseTailBytes)
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("failed to read log file: %v", err)).WithError(err)
+	}
+	groups := groupLogProblems(parseLogEntries(data))
+	if len(groups) == 0 {
+		return SilentResult("No warnings or errors in the recent logs.")
+	}
+
+	summary, err := t.summarize(ctx, formatDiagnosePrompt(groups))
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("failed to summarize logs: %v", err)).WithError(err)
+	}
+	return SilentResult(summary)
+}
+
+// readTail returns up to maxBytes from the end of the file, starting at a
+// line boundary.
+func readTail(path string, maxBytes int64) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	offset := max(info.Size()-maxBytes, 0)
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+	if offset > 0 {
+		// Drop the partial first line.
+		if i := bytes.IndexByte(data, '\n'); i >= 0 {
+			data = data[i+1:]
+		}
+	}
+	return data, nil
+}
+
+// parseLogEntries decodes JSON log lines, keeping WARN and worse.
+func parseLogEntries(data []byte) []logger.LogEntry {
+	var entries []logger.LogEntry
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var e logger.LogEntry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			continue
+		}
+		switch e.Level {
+		case "WARN", "ERROR", "FATAL":
+			entries = append(entries, e)
+		}
+	}
+	return entries
+}
+
+// logProblem is one distinct warning or error and how often it occurred.
+type logProblem struct {
+	Level     string
+	Component string
+	Message   string
+	Count     int
+	First     string
+	Last      string
+	Example   string // Fields of the latest occurrence
+}
+
+// groupLogProblems groups entries by level, component and message, most
+// frequent first.
+func groupLogProblems(entries []logger.LogEntry) []*logProblem {
+	byKey := make(map[string]*logProblem)
+	var groups []*logProblem
+	for _, e := range entries {
+		key := e.Level + "|" + e.Component + "|" + e.Message
+		g, ok := byKey[key]
+		if !ok {
+			g = &logProblem{Level: e.Level, Component: e.Component, Message: e.Message, First: e.Timestamp}
+			byKey[key] = g
+			groups = append(groups, g)
+		}
+		g.Count++
+		g.Last = e.Timestamp
+		if len(e.Fields) > 0 {
+			fields, _ := json.Marshal(e.Fields)
+			g.Example = utils.Truncate(string(fields), 300)
+		}
+	}
+	sort.SliceStable(groups, func(i, j int) bool {
+		return groups[i].Count > groups[j].Count
+	})
+	return groups
+}
+
+// formatDiagnosePrompt renders the grouped problems as the summarizer's
+// prompt.
+func formatDiagnosePrompt(groups []*logProblem) string {
+	var sb strings.Builder
+	sb.WriteString("These are the recent warnings and errors from a personal AI assistant's logs, grouped and counted. ")
+	sb.WriteString("Explain to a non-technical user, in a few short sentences or bullets, what went wrong, the likely causes, and what they could do about it. ")
+	sb.WriteString("Mention the most important problems first and skip noise.\n\nLOG PROBLEMS:\n")
+	shown := groups
+	if len(shown) > diagnoseMaxGroups {
+		shown = shown[:diagnoseMaxGroups]
+	}
+	for _, g := range shown {
+		sb.WriteString(fmt.Sprintf("- [%s] %s: %s (x%d, %s to %s)", g.Level, g.Component, g.Message, g.Count, g.First, g.Last))
+		if g.Example != "" {
+			sb.WriteString(" " + g.Example)
+		}
+		sb.WriteString("\n")
+	}
+	if extra := len(groups) - len(shown); extra > 0 {
+		sb.WriteString(fmt.Sprintf("(%d less frequent problems omitted)\n", extra))
+	}
+	return sb.String()
+}
@@ -0,0 +1,95 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeTestLog(t *testing.T, lines ...string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "picoclaw.log")
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestDiagnoseTool_GroupsProblemsForSummarizer(t *testing.T) {
+	path := writeTestLog(t,
+		`{"level":"INFO","timestamp":"2026-01-01T10:00:00Z","component":"agent","message":"Processing message"}`,
+		`{"level":"ERROR","timestamp":"2026-01-01T10:01:00Z","component":"telegram","message":"Failed to send","fields":{"error":"timeout"}}`,
+		`not json`,
+		`{"level":"WARN","timestamp":"2026-01-01T10:02:00Z","component":"agent","message":"Rate limited"}`,
+		`{"level":"ERROR","timestamp":"2026-01-01T10:03:00Z","component":"telegram","message":"Failed to send","fields":{"error":"bad gateway"}}`,
+	)
+
+	var prompt string
+	tool := NewDiagnoseTool(func() string { return path }, func(ctx context.Context, p string) (string, error) {
+		prompt = p
+		return "Telegram could not deliver two messages.", nil
+	})
+	result := tool.Execute(context.Background(), nil)
+
+	if result.IsError || result.ForLLM != "Telegram could not deliver two messages." {
+		t.Fatalf("Execute() = %+v, want the summarizer's explanation", result)
+	}
+	if strings.Contains(prompt, "Processing message") {
+		t.Error("INFO entries should not reach the summarizer")
+	}
+	errLine := `- [ERROR] telegram: Failed to send (x2, 2026-01-01T10:01:00Z to 2026-01-01T10:03:00Z) {"error":"bad gateway"}`
+	if !strings.Contains(prompt, errLine) {
+		t.Errorf("prompt missing grouped error line %q:\n%s", errLine, prompt)
+	}
+	if !strings.Contains(prompt, "- [WARN] agent: Rate limited (x1") {
+		t.Errorf("prompt missing warning:\n%s", prompt)
+	}
+	if strings.Index(prompt, "Failed to send") > strings.Index(prompt, "Rate limited") {
+		t.Error("most frequent problem should come first")
+	}
+}
+
+func TestDiagnoseTool_NoProblems(t *testing.T) {
+	path := writeTestLog(t, `{"level":"INFO","timestamp":"2026-01-01T10:00:00Z","message":"ok"}`)
+	tool := NewDiagnoseTool(func() string { return path }, func(ctx context.Context, p string) (string, error) {
+		t.Fatal("summarizer should not be called without problems")
+		return "", nil
+	})
+	if result := tool.Execute(context.Background(), nil); !strings.Contains(result.ForLLM, "No warnings or errors") {
+		t.Errorf("Execute() = %q", result.ForLLM)
+	}
+
+	failing := NewDiagnoseTool(func() string { return "" }, nil)
+	if result := failing.Execute(context.Background(), nil); !result.IsError {
+		t.Error("expected an error when file logging is disabled")
+	}
+
+	path = writeTestLog(t, `{"level":"ERROR","timestamp":"t","message":"boom"}`)
+	broken := NewDiagnoseTool(func() string { return path }, func(ctx context.Context, p string) (string, error) {
+		return "", errors.New("provider down")
+	})
+	if result := broken.Execute(context.Background(), nil); !result.IsError {
+		t.Error("expected an error when the summarizer fails")
+	}
+}
+
+func TestReadTail_StartsAtLineBoundary(t *testing.T) {
+	var lines []string
+	for i := range 100 {
+		lines = append(lines, fmt.Sprintf("line %03d", i))
+	}
+	path := writeTestLog(t, lines...)
+
+	data, err := readTail(path, 40)
+	if err != nil {
+		t.Fatalf("readTail() error: %v", err)
+	}
+	got := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if got[0] != "line 096" || got[len(got)-1] != "line 099" {
+		t.Errorf("readTail() = %q, want whole lines ending at line 099", got)
+	}
+}
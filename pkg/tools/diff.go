@@ -0,0 +1,86 @@
+package tools
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// diffContextLines surround the changed lines in a diff hunk.
+	diffContextLines = 3
+	// maxDiffLines caps the hunk body edit_file reports.
+	maxDiffLines = 120
+)
+
+// unifiedDiff renders the change from oldContent to newContent as a
+// unified diff with one hunk: the lines between the common prefix and
+// suffix, plus context. That is exact for edit_file's single replacement.
+// Hunks longer than maxDiffLines are cut short. It returns "" when the
+// contents are equal.
+func unifiedDiff(path, oldContent, newContent string) string {
+	if oldContent == newContent {
+		return ""
+	}
+	oldLines := splitDiffLines(oldContent)
+	newLines := splitDiffLines(newContent)
+
+	prefix := 0
+	for prefix < len(oldLines) && prefix < len(newLines) && oldLines[prefix] == newLines[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(oldLines)-prefix && suffix < len(newLines)-prefix &&
+		oldLines[len(oldLines)-1-suffix] == newLines[len(newLines)-1-suffix] {
+		suffix++
+	}
+
+	if prefix == len(oldLines)-suffix && prefix == len(newLines)-suffix {
+		return fmt.Sprintf("--- a/%s\n+++ b/%s\n(only the newline at the end of the file changed)", path, path)
+	}
+
+	start := max(prefix-diffContextLines, 0)
+	oldEnd := min(len(oldLines)-suffix+diffContextLines, len(oldLines))
+	newEnd := min(len(newLines)-suffix+diffContextLines, len(newLines))
+
+	var body []string
+	for _, l := range oldLines[start:prefix] {
+		body = append(body, " "+l)
+	}
+	for _, l := range oldLines[prefix : len(oldLines)-suffix] {
+		body = append(body, "-"+l)
+	}
+	for _, l := range newLines[prefix : len(newLines)-suffix] {
+		body = append(body, "+"+l)
+	}
+	for _, l := range oldLines[len(oldLines)-suffix : oldEnd] {
+		body = append(body, " "+l)
+	}
+	if len(body) > maxDiffLines {
+		omitted := len(body) - maxDiffLines
+		body = append(body[:maxDiffLines], fmt.Sprintf("... (diff truncated, %d more lines)", omitted))
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- a/%s\n+++ b/%s\n", path, path)
+	fmt.Fprintf(&sb, "@@ -%s +%s @@\n", hunkRange(start, oldEnd-start), hunkRange(start, newEnd-start))
+	sb.WriteString(strings.Join(body, "\n"))
+	return sb.String()
+}
+
+// hunkRange formats a hunk's start line and length the way diff -u does.
+func hunkRange(start, length int) string {
+	if length == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	if length == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, length)
+}
+
+func splitDiffLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
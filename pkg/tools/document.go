@@ -0,0 +1,250 @@
+package tools
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/ledongthuc/pdf"
+)
+
+const (
+	// documentMaxInputBytes bounds the size of documents read_document opens.
+	documentMaxInputBytes = 50 * 1024 * 1024
+	// documentMaxTextBytes caps the extracted text written to disk.
+	documentMaxTextBytes = 1024 * 1024
+	// documentPreviewChars is how much of the text is returned inline.
+	documentPreviewChars = 2000
+)
+
+// ReadDocumentTool extracts the plain text of PDF and DOCX files in the
+// workspace (typically imported attachments) into a .txt next to them, so
+// read_file and the other file tools can work with it. Extraction is pure Go
+// and needs no external binaries.
+type ReadDocumentTool struct {
+	workspace string
+	restrict  bool
+}
+
+func NewReadDocumentTool(workspace string, restrict bool) *ReadDocumentTool {
+	return &ReadDocumentTool{workspace: workspace, restrict: restrict}
+}
+
+func (t *ReadDocumentTool) Name() string {
+	return "read_document"
+}
+
+func (t *ReadDocumentTool) Description() string {
+	return "Extract the text of a PDF or DOCX file in the workspace (e.g. one brought in with import_attachment). Writes the text to a .txt file next to it and returns a preview and the text file path."
+}
+
+func (t *ReadDocumentTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"path": map[string]interface{}{
+				"type":        "string",
+				"description": "Path of the PDF or DOCX file in the workspace",
+			},
+		},
+		"required": []string{"path"},
+	}
+}
+
+func (t *ReadDocumentTool) Execute(ctx context.Context, args map[string]interface{}) *ToolResult {
+	path, ok := args["path"].(string)
+	if !ok || path == "" {
+		return ErrorResult("path is required")
+	}
+	resolved, err := validatePath(path, t.workspace, t.restrict)
+	if err != nil {
+		return ErrorResult(err.Error())
+	}
+
+	info, err := os.Stat(resolved)
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("failed to read document: %v", err))
+	}
+	if info.Size() > documentMaxInputBytes {
+		return ErrorResult(fmt.Sprintf("document is too large (%d bytes, limit %d)", info.Size(), documentMaxInputBytes))
+	}
+
+	var text string
+	switch kind := documentKind(resolved); kind {
+	case "pdf":
+		text, err = extractPDFText(resolved, info.Size())
+	case "docx":
+		text, err = extractDOCXText(resolved)
+	default:
+		return ErrorResult("unsupported document type: only PDF and DOCX files can be read")
+	}
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("failed to extract text: %v", err)).WithError(err)
+	}
+
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return ErrorResult("no text found in the document; it may be a scanned image")
+	}
+	truncated := false
+	if len(text) > documentMaxTextBytes {
+		text = truncateUTF8(text, documentMaxTextBytes)
+		truncated = true
+	}
+
+	textPath := strings.TrimSuffix(resolved, filepath.Ext(resolved)) + ".txt"
+	if err := os.WriteFile(textPath, []byte(text), 0644); err != nil {
+		return ErrorResult(fmt.Sprintf("failed to write text file: %v", err))
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Extracted %d bytes of text to %s", len(text), textPath)
+	if truncated {
+		fmt.Fprintf(&sb, " (truncated at %d bytes)", documentMaxTextBytes)
+	}
+	sb.WriteString(". Use read_file on it for the full text.\n\n")
+	preview := []rune(text)
+	if len(preview) > documentPreviewChars {
+		sb.WriteString(string(preview[:documentPreviewChars]) + "\n...")
+	} else {
+		sb.WriteString(text)
+	}
+	return NewToolResult(sb.String())
+}
+
+// documentKind reports "pdf" or "docx" from the file extension, falling
+// back to content sniffing for PDFs saved without one.
+func documentKind(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".pdf":
+		return "pdf"
+	case ".docx":
+		return "docx"
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	head := make([]byte, 512)
+	n, _ := io.ReadFull(f, head)
+	if http.DetectContentType(head[:n]) == "application/pdf" {
+		return "pdf"
+	}
+	return ""
+}
+
+// extractPDFText returns the text of every page. The PDF library panics on
+// some malformed files, so panics are turned into errors.
+func extractPDFText(path string, size int64) (text string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("malformed PDF: %v", r)
+		}
+	}()
+
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	r, err := pdf.NewReader(f, size)
+	if err != nil {
+		if errors.Is(err, pdf.ErrInvalidPassword) || strings.Contains(err.Error(), "encryption") {
+			return "", fmt.Errorf("the PDF is encrypted or password-protected; ask the user for an unprotected copy")
+		}
+		return "", err
+	}
+	plain, err := r.GetPlainText()
+	if err != nil {
+		return "", err
+	}
+	b, err := io.ReadAll(io.LimitReader(plain, documentMaxTextBytes+1))
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// extractDOCXText reads word/document.xml, keeping paragraph and line
+// breaks and tabs.
+func extractDOCXText(path string) (string, error) {
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		return "", fmt.Errorf("not a valid DOCX file: %w", err)
+	}
+	defer zr.Close()
+
+	for _, f := range zr.File {
+		if f.Name != "word/document.xml" {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return "", err
+		}
+		defer rc.Close()
+		return docxBodyText(io.LimitReader(rc, documentMaxInputBytes))
+	}
+	return "", fmt.Errorf("not a valid DOCX file: word/document.xml missing")
+}
+
+func docxBodyText(r io.Reader) (string, error) {
+	var sb bytes.Buffer
+	dec := xml.NewDecoder(r)
+	inText := false
+	for sb.Len() <= documentMaxTextBytes {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("parse DOCX: %w", err)
+		}
+		switch el := tok.(type) {
+		case xml.StartElement:
+			switch el.Name.Local {
+			case "t":
+				inText = true
+			case "tab":
+				sb.WriteByte('\t')
+			case "br", "cr":
+				sb.WriteByte('\n')
+			}
+		case xml.EndElement:
+			switch el.Name.Local {
+			case "t":
+				inText = false
+			case "p":
+				sb.WriteByte('\n')
+			}
+		case xml.CharData:
+			if inText {
+				sb.Write(el)
+			}
+		}
+	}
+	return sb.String(), nil
+}
+
+// truncateUTF8 cuts s to at most n bytes without splitting a rune.
+func truncateUTF8(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
@@ -0,0 +1,150 @@
+package tools
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// minimalPDF builds a one-page PDF showing text in Helvetica. trailer adds
+// entries to the trailer dictionary.
+func minimalPDF(text, trailer string) []byte {
+	stream := fmt.Sprintf("BT /F1 12 Tf 72 720 Td (%s) Tj ET", text)
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Resources << /Font << /F1 4 0 R >> >> /Contents 5 0 R >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+		fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(stream), stream),
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R %s>>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, trailer, xref)
+	return buf.Bytes()
+}
+
+func minimalDOCX(t *testing.T, body string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, err := zw.Create("word/document.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Fprintf(w, `<?xml version="1.0" encoding="UTF-8"?><w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>%s</w:body></w:document>`, body)
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestReadDocumentTool(t *testing.T) {
+	tests := []struct {
+		name     string
+		file     string
+		content  func(t *testing.T) []byte
+		wantText string
+		wantTxt  string
+	}{
+		{
+			name:     "pdf",
+			file:     "invoice.pdf",
+			content:  func(t *testing.T) []byte { return minimalPDF("Invoice 42 total 99 EUR", "") },
+			wantText: "Invoice 42 total 99 EUR",
+			wantTxt:  "invoice.txt",
+		},
+		{
+			name:     "pdf without extension",
+			file:     "scan",
+			content:  func(t *testing.T) []byte { return minimalPDF("Hello", "") },
+			wantText: "Hello",
+			wantTxt:  "scan.txt",
+		},
+		{
+			name: "docx",
+			file: "letter.docx",
+			content: func(t *testing.T) []byte {
+				return minimalDOCX(t, `<w:p><w:r><w:t>Dear Bob,</w:t></w:r></w:p><w:p><w:r><w:t>Total:</w:t><w:tab/><w:t>99 EUR</w:t></w:r></w:p>`)
+			},
+			wantText: "Dear Bob,\nTotal:\t99 EUR",
+			wantTxt:  "letter.txt",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			workspace := t.TempDir()
+			if err := os.WriteFile(filepath.Join(workspace, tt.file), tt.content(t), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			res := NewReadDocumentTool(workspace, true).Execute(context.Background(), map[string]interface{}{"path": tt.file})
+			if res.IsError {
+				t.Fatalf("read_document failed: %s", res.ForLLM)
+			}
+			txtPath := filepath.Join(workspace, tt.wantTxt)
+			if !strings.Contains(res.ForLLM, txtPath) || !strings.Contains(res.ForLLM, tt.wantText) {
+				t.Errorf("result should name %s and preview the text, got:\n%s", txtPath, res.ForLLM)
+			}
+			got, err := os.ReadFile(txtPath)
+			if err != nil {
+				t.Fatalf("text file not written: %v", err)
+			}
+			if string(got) != tt.wantText {
+				t.Errorf("text = %q, want %q", got, tt.wantText)
+			}
+		})
+	}
+}
+
+func TestReadDocumentTool_Errors(t *testing.T) {
+	workspace := t.TempDir()
+	files := map[string][]byte{
+		"notes.txt":  []byte("plain"),
+		"broken.pdf": []byte("%PDF-1.4\nnot really a pdf"),
+		"fake.docx":  []byte("not a zip"),
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(workspace, name), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tool := NewReadDocumentTool(workspace, true)
+	for _, path := range []string{"notes.txt", "broken.pdf", "fake.docx", "missing.pdf", "../outside.pdf"} {
+		t.Run(path, func(t *testing.T) {
+			res := tool.Execute(context.Background(), map[string]interface{}{"path": path})
+			if !res.IsError {
+				t.Fatalf("expected an error, got %q", res.ForLLM)
+			}
+		})
+	}
+}
+
+func TestReadDocumentTool_EncryptedPDF(t *testing.T) {
+	workspace := t.TempDir()
+	data := minimalPDF("secret", "/Encrypt << /Filter /Custom /V 5 >> ")
+	if err := os.WriteFile(filepath.Join(workspace, "locked.pdf"), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	res := NewReadDocumentTool(workspace, true).Execute(context.Background(), map[string]interface{}{"path": "locked.pdf"})
+	if !res.IsError || !strings.Contains(res.ForLLM, "password-protected") {
+		t.Fatalf("expected a clear encryption error, got %q", res.ForLLM)
+	}
+}
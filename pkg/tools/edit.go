@@ -12,6 +12,10 @@ import (
 type EditFileTool struct {
 	allowedDir string
 	restrict   bool
+	backups    *FileBackups
+	// diffToUser also shows the diff to the user instead of keeping the
+	// result silent.
+	diffToUser bool
 }
 
 // NewEditFileTool creates a new EditFileTool with optional directory restriction.
@@ -19,6 +23,7 @@ func NewEditFileTool(allowedDir string, restrict bool) *EditFileTool {
 	return &EditFileTool{
 		allowedDir: allowedDir,
 		restrict:   restrict,
+		backups:    NewFileBackups(allowedDir),
 	}
 }
 
@@ -94,20 +99,34 @@ func (t *EditFileTool) Execute(ctx context.Context, args map[string]interface{})
 
 	newContent := strings.Replace(contentStr, oldText, newText, 1)
 
+	t.backups.Snapshot(resolvedPath)
 	if err := os.WriteFile(resolvedPath, []byte(newContent), 0644); err != nil {
 		return ErrorResult(fmt.Sprintf("failed to write file: %v", err))
 	}
 
-	return SilentResult(fmt.Sprintf("File edited: %s", path))
+	msg := fmt.Sprintf("File edited: %s", path)
+	if diff := unifiedDiff(path, contentStr, newContent); diff != "" {
+		msg += "\n" + diff
+	}
+	if t.diffToUser {
+		return &ToolResult{ForLLM: msg, ForUser: msg}
+	}
+	return SilentResult(msg)
+}
+
+// SetDiffToUser controls whether the edit's diff is also shown to the user.
+func (t *EditFileTool) SetDiffToUser(show bool) {
+	t.diffToUser = show
 }
 
 type AppendFileTool struct {
 	workspace string
 	restrict  bool
+	backups   *FileBackups
 }
 
 func NewAppendFileTool(workspace string, restrict bool) *AppendFileTool {
-	return &AppendFileTool{workspace: workspace, restrict: restrict}
+	return &AppendFileTool{workspace: workspace, restrict: restrict, backups: NewFileBackups(workspace)}
 }
 
 func (t *AppendFileTool) Name() string {
@@ -151,6 +170,7 @@ func (t *AppendFileTool) Execute(ctx context.Context, args map[string]interface{
 		return ErrorResult(err.Error())
 	}
 
+	t.backups.Snapshot(resolvedPath)
 	f, err := os.OpenFile(resolvedPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
 	if err != nil {
 		return ErrorResult(fmt.Sprintf("failed to open file: %v", err))
@@ -2,6 +2,7 @@ package tools
 
 import (
 	"context"
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
@@ -287,3 +288,59 @@ func TestEditTool_AppendFile_MissingContent(t *testing.T) {
 		t.Errorf("Expected error when content is missing")
 	}
 }
+
+// TestEditTool_EditFile_ReportsDiff verifies the result carries a unified
+// diff of the change with context, and only the changed hunk.
+func TestEditTool_EditFile_ReportsDiff(t *testing.T) {
+	tmpDir := t.TempDir()
+	var lines []string
+	for i := 1; i <= 20; i++ {
+		lines = append(lines, fmt.Sprintf("line %d", i))
+	}
+	os.WriteFile(filepath.Join(tmpDir, "f.txt"), []byte(strings.Join(lines, "\n")+"\n"), 0644)
+
+	tool := NewEditFileTool(tmpDir, true)
+	result := tool.Execute(context.Background(), map[string]interface{}{
+		"path":     "f.txt",
+		"old_text": "line 10\n",
+		"new_text": "line ten\nline ten and a half\n",
+	})
+	if result.IsError {
+		t.Fatalf("edit failed: %s", result.ForLLM)
+	}
+	want := `File edited: f.txt
+--- a/f.txt
++++ b/f.txt
+@@ -7,7 +7,8 @@
+ line 7
+ line 8
+ line 9
+-line 10
++line ten
++line ten and a half
+ line 11
+ line 12
+ line 13`
+	if result.ForLLM != want {
+		t.Errorf("ForLLM =\n%s\nwant\n%s", result.ForLLM, want)
+	}
+	if !result.Silent || result.ForUser != "" {
+		t.Error("diff should only reach the user when enabled")
+	}
+
+	tool.SetDiffToUser(true)
+	result = tool.Execute(context.Background(), map[string]interface{}{"path": "f.txt", "old_text": "line 1\n", "new_text": "line one\n"})
+	if result.Silent || !strings.Contains(result.ForUser, "-line 1\n+line one") {
+		t.Errorf("ForUser = %q, want the diff", result.ForUser)
+	}
+}
+
+func TestUnifiedDiff_TruncatesLongHunks(t *testing.T) {
+	diff := unifiedDiff("big.txt", "start\nend\n", "start\n"+strings.Repeat("new\n", 200)+"end\n")
+	if !strings.HasSuffix(diff, "... (diff truncated, 82 more lines)") {
+		t.Errorf("diff does not end with a truncation note:\n%s", diff)
+	}
+	if got := strings.Count(diff, "\n+new"); got != maxDiffLines-1 {
+		t.Errorf("diff shows %d added lines, want %d", got, maxDiffLines-1)
+	}
+}
@@ -57,6 +57,12 @@ func (t *SendFileTool) Parameters() map[string]interface{} {
 	}
 }
 
+// SchemaHint reports true: models often pass a single path instead of an
+// array.
+func (t *SendFileTool) SchemaHint() bool {
+	return true
+}
+
 func (t *SendFileTool) SetContext(channel, chatID string) {
 	t.defaultChannel = channel
 	t.defaultChatID = chatID
@@ -86,11 +92,12 @@ func (t *SendFileTool) Execute(ctx context.Context, args map[string]interface{})
 	channel, _ := args["channel"].(string)
 	chatID, _ := args["chat_id"].(string)
 
+	ctxChannel, ctxChatID := ToolContext(ctx, t.defaultChannel, t.defaultChatID)
 	if channel == "" {
-		channel = t.defaultChannel
+		channel = ctxChannel
 	}
 	if chatID == "" {
-		chatID = t.defaultChatID
+		chatID = ctxChatID
 	}
 
 	if channel == "" || chatID == "" {
@@ -49,8 +49,13 @@ func (t *ReadFileTool) Name() string {
 	return "read_file"
 }
 
+// ParallelSafe reports true: read_file only reads.
+func (t *ReadFileTool) ParallelSafe() bool {
+	return true
+}
+
 func (t *ReadFileTool) Description() string {
-	return "Read the contents of a file"
+	return "Read the contents of a file. For large files, pass start_line and end_line to read a range of lines; the result then says how many lines the file has."
 }
 
 func (t *ReadFileTool) Parameters() map[string]interface{} {
@@ -61,6 +66,14 @@ func (t *ReadFileTool) Parameters() map[string]interface{} {
 				"type":        "string",
 				"description": "Path to the file to read",
 			},
+			"start_line": map[string]interface{}{
+				"type":        "integer",
+				"description": "Optional first line to read, counting from 1",
+			},
+			"end_line": map[string]interface{}{
+				"type":        "integer",
+				"description": "Optional last line to read (inclusive); defaults to the end of the file",
+			},
 		},
 		"required": []string{"path"},
 	}
@@ -82,16 +95,56 @@ func (t *ReadFileTool) Execute(ctx context.Context, args map[string]interface{})
 		return ErrorResult(fmt.Sprintf("failed to read file: %v", err))
 	}
 
-	return NewToolResult(string(content))
+	startLine, hasStart := numberValue(args["start_line"])
+	endLine, hasEnd := numberValue(args["end_line"])
+	if !hasStart && !hasEnd {
+		return NewToolResult(string(content))
+	}
+	return readLineRange(path, string(content), int(startLine), int(endLine), hasEnd)
+}
+
+// readLineRange returns lines start..end (1-based, inclusive) of content.
+// A range short of the whole file gets a header with the file's line count,
+// and a pointer to the next line when more follow.
+func readLineRange(path, content string, start, end int, hasEnd bool) *ToolResult {
+	lines := strings.SplitAfter(content, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	total := len(lines)
+
+	if start < 1 {
+		start = 1
+	}
+	if !hasEnd || end > total {
+		end = total
+	}
+	if start > total {
+		return ErrorResult(fmt.Sprintf("start_line %d is past the end of %s (%d lines)", start, path, total))
+	}
+	if end < start {
+		return ErrorResult(fmt.Sprintf("end_line %d is before start_line %d", end, start))
+	}
+
+	body := strings.Join(lines[start-1:end], "")
+	if start == 1 && end == total {
+		return NewToolResult(body)
+	}
+	header := fmt.Sprintf("[%s: lines %d-%d of %d", path, start, end, total)
+	if end < total {
+		header += fmt.Sprintf("; continue with start_line=%d", end+1)
+	}
+	return NewToolResult(header + "]\n" + body)
 }
 
 type WriteFileTool struct {
 	workspace string
 	restrict  bool
+	backups   *FileBackups
 }
 
 func NewWriteFileTool(workspace string, restrict bool) *WriteFileTool {
-	return &WriteFileTool{workspace: workspace, restrict: restrict}
+	return &WriteFileTool{workspace: workspace, restrict: restrict, backups: NewFileBackups(workspace)}
 }
 
 func (t *WriteFileTool) Name() string {
@@ -140,6 +193,7 @@ func (t *WriteFileTool) Execute(ctx context.Context, args map[string]interface{}
 		return ErrorResult(fmt.Sprintf("failed to create directory: %v", err))
 	}
 
+	t.backups.Snapshot(resolvedPath)
 	if err := os.WriteFile(resolvedPath, []byte(content), 0644); err != nil {
 		return ErrorResult(fmt.Sprintf("failed to write file: %v", err))
 	}
@@ -160,6 +214,11 @@ func (t *ListDirTool) Name() string {
 	return "list_dir"
 }
 
+// ParallelSafe reports true: list_dir only reads.
+func (t *ListDirTool) ParallelSafe() bool {
+	return true
+}
+
 func (t *ListDirTool) Description() string {
 	return "List files and directories in a path"
 }
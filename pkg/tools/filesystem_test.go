@@ -39,6 +39,45 @@ func TestFilesystemTool_ReadFile_Success(t *testing.T) {
 	}
 }
 
+// TestFilesystemTool_ReadFile_LineRange verifies paging through a file by
+// line range, with a header giving the line count.
+func TestFilesystemTool_ReadFile_LineRange(t *testing.T) {
+	tmpDir := t.TempDir()
+	os.WriteFile(filepath.Join(tmpDir, "big.txt"), []byte("a\nb\nc\nd\ne\n"), 0644)
+	tool := NewReadFileTool(tmpDir, true)
+	ctx := context.Background()
+
+	tests := []struct {
+		args map[string]interface{}
+		want string
+	}{
+		{map[string]interface{}{"start_line": float64(2), "end_line": float64(3)}, "[big.txt: lines 2-3 of 5; continue with start_line=4]\nb\nc\n"},
+		{map[string]interface{}{"start_line": float64(4)}, "[big.txt: lines 4-5 of 5]\nd\ne\n"},
+		{map[string]interface{}{"end_line": float64(99)}, "a\nb\nc\nd\ne\n"},
+		{map[string]interface{}{}, "a\nb\nc\nd\ne\n"},
+	}
+	for _, tt := range tests {
+		tt.args["path"] = "big.txt"
+		result := tool.Execute(ctx, tt.args)
+		if result.IsError || result.ForLLM != tt.want {
+			t.Errorf("args %v: got %q, want %q", tt.args, result.ForLLM, tt.want)
+		}
+	}
+
+	result := tool.Execute(ctx, map[string]interface{}{"path": "big.txt", "start_line": float64(9)})
+	if !result.IsError || !strings.Contains(result.ForLLM, "past the end") {
+		t.Errorf("start past the end: got %q, want an error", result.ForLLM)
+	}
+
+	// Ranges don't bypass the workspace restriction
+	outside := filepath.Join(t.TempDir(), "secret.txt")
+	os.WriteFile(outside, []byte("secret\n"), 0644)
+	result = tool.Execute(ctx, map[string]interface{}{"path": outside, "start_line": float64(1)})
+	if !result.IsError || strings.Contains(result.ForLLM, "secret\n") {
+		t.Errorf("read outside the workspace: got %q, want it denied", result.ForLLM)
+	}
+}
+
 // TestFilesystemTool_ReadFile_NotFound verifies error handling for missing file
 func TestFilesystemTool_ReadFile_NotFound(t *testing.T) {
 	tool := &ReadFileTool{}
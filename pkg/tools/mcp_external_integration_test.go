@@ -49,18 +49,20 @@ func TestMCPExternalPopularFilesystemCommand(t *testing.T) {
 	listAllowedDirs := requireToolByName(t, tools, "mcp_fs_list_allowed_directories")
 	readFile := requireToolByName(t, tools, "mcp_fs_read_file")
 
-	out, err := listAllowedDirs.Execute(ctx, map[string]interface{}{})
-	if err != nil {
-		t.Fatalf("Execute(list_allowed_directories) error: %v", err)
+	outResult := listAllowedDirs.Execute(ctx, map[string]interface{}{})
+	if outResult.IsError {
+		t.Fatalf("Execute(list_allowed_directories) error: %s", outResult.ForLLM)
 	}
+	out := outResult.ForLLM
 	if !strings.Contains(out, rootCanonical) {
 		t.Fatalf("expected allowed directory %q in output: %s", rootCanonical, out)
 	}
 
-	readOut, err := readFile.Execute(ctx, map[string]interface{}{"path": testFile})
-	if err != nil {
-		t.Fatalf("Execute(read_file) error: %v", err)
+	readOutResult := readFile.Execute(ctx, map[string]interface{}{"path": testFile})
+	if readOutResult.IsError {
+		t.Fatalf("Execute(read_file) error: %s", readOutResult.ForLLM)
 	}
+	readOut := readOutResult.ForLLM
 	if !strings.Contains(readOut, "hello from filesystem mcp") {
 		t.Fatalf("expected file content in output, got: %s", readOut)
 	}
@@ -87,10 +89,11 @@ func TestMCPExternalPopularMemoryCommand(t *testing.T) {
 
 	readGraph := requireToolByName(t, tools, "mcp_memory_read_graph")
 
-	out, err := readGraph.Execute(ctx, map[string]interface{}{})
-	if err != nil {
-		t.Fatalf("Execute(read_graph) error: %v", err)
+	outResult := readGraph.Execute(ctx, map[string]interface{}{})
+	if outResult.IsError {
+		t.Fatalf("Execute(read_graph) error: %s", outResult.ForLLM)
 	}
+	out := outResult.ForLLM
 	if !strings.Contains(strings.ToLower(out), "entities") {
 		t.Fatalf("expected graph output to include entities: %s", out)
 	}
@@ -118,10 +121,11 @@ func TestMCPExternalPopularEverythingSSE(t *testing.T) {
 
 	echoTool := requireToolByName(t, tools, "mcp_every_echo")
 
-	out, err := echoTool.Execute(ctx, map[string]interface{}{"message": "hello from sse"})
-	if err != nil {
-		t.Fatalf("Execute(echo) error: %v", err)
+	outResult := echoTool.Execute(ctx, map[string]interface{}{"message": "hello from sse"})
+	if outResult.IsError {
+		t.Fatalf("Execute(echo) error: %s", outResult.ForLLM)
 	}
+	out := outResult.ForLLM
 	if !strings.Contains(out, "hello from sse") {
 		t.Fatalf("unexpected echo output: %s", out)
 	}
@@ -149,10 +153,11 @@ func TestMCPExternalPopularEverythingStreamableHTTP(t *testing.T) {
 
 	echoTool := requireToolByName(t, tools, "mcp_http_echo")
 
-	out, err := echoTool.Execute(ctx, map[string]interface{}{"message": "hello from streamable-http"})
-	if err != nil {
-		t.Fatalf("Execute(echo) error: %v", err)
+	outResult := echoTool.Execute(ctx, map[string]interface{}{"message": "hello from streamable-http"})
+	if outResult.IsError {
+		t.Fatalf("Execute(echo) error: %s", outResult.ForLLM)
 	}
+	out := outResult.ForLLM
 	if !strings.Contains(out, "hello from streamable-http") {
 		t.Fatalf("unexpected echo output: %s", out)
 	}
@@ -94,18 +94,20 @@ func TestLoadMCPTools_CommandTransport(t *testing.T) {
 		t.Fatalf("missing discovered tool mcp_helper_sum; got names=%v", toolNames(tools))
 	}
 
-	gotGreeting, err := greetTool.Execute(context.Background(), map[string]interface{}{"name": "Ada"})
-	if err != nil {
-		t.Fatalf("greetTool.Execute() error: %v", err)
+	gotGreetingResult := greetTool.Execute(context.Background(), map[string]interface{}{"name": "Ada"})
+	if gotGreetingResult.IsError {
+		t.Fatalf("greetTool.Execute() error: %s", gotGreetingResult.ForLLM)
 	}
+	gotGreeting := gotGreetingResult.ForLLM
 	if !strings.Contains(gotGreeting, "Hello Ada") {
 		t.Fatalf("greetTool.Execute() missing greeting: %s", gotGreeting)
 	}
 
-	gotSum, err := sumTool.Execute(context.Background(), map[string]interface{}{"a": 2, "b": 3})
-	if err != nil {
-		t.Fatalf("sumTool.Execute() error: %v", err)
+	gotSumResult := sumTool.Execute(context.Background(), map[string]interface{}{"a": 2, "b": 3})
+	if gotSumResult.IsError {
+		t.Fatalf("sumTool.Execute() error: %s", gotSumResult.ForLLM)
 	}
+	gotSum := gotSumResult.ForLLM
 	if !strings.Contains(gotSum, `"sum": 5`) {
 		t.Fatalf("sumTool.Execute() output missing sum result: %s", gotSum)
 	}
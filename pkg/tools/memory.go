@@ -0,0 +1,335 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// memoryMu serializes read-modify-write cycles on memory files across all
+// MemoryTool instances (the main agent and subagents each have one).
+var memoryMu sync.Mutex
+
+// memorySearchLimit caps the matching lines search returns.
+const memorySearchLimit = 50
+
+// MemoryTool edits the agent's memory files (memory/MEMORY.md and today's
+// daily note, the files ContextBuilder injects) by Markdown section, so the
+// model can persist facts without rewriting whole files. Writes are atomic.
+type MemoryTool struct {
+	memoryDir string
+	now       func() time.Time
+}
+
+func NewMemoryTool(workspace string) *MemoryTool {
+	return &MemoryTool{memoryDir: filepath.Join(workspace, "memory"), now: time.Now}
+}
+
+func (t *MemoryTool) Name() string {
+	return "memory"
+}
+
+func (t *MemoryTool) Description() string {
+	return "Edit long-term memory (MEMORY.md) or today's daily note by section. Operations: append (add text to the end of a section, or of the file), replace_section (replace a section's body), list (show section headings), search (find lines containing text). Prefer this over rewriting the memory file."
+}
+
+func (t *MemoryTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"operation": map[string]interface{}{
+				"type":        "string",
+				"enum":        []string{"append", "replace_section", "list", "search"},
+				"description": "What to do",
+			},
+			"section": map[string]interface{}{
+				"type":        "string",
+				"description": "Section heading text without the leading #s (append, replace_section). Missing sections are created.",
+			},
+			"content": map[string]interface{}{
+				"type":        "string",
+				"description": "Text to append or the new section body (append, replace_section)",
+			},
+			"query": map[string]interface{}{
+				"type":        "string",
+				"description": "Text to search for, case-insensitive (search)",
+			},
+			"file": map[string]interface{}{
+				"type":        "string",
+				"enum":        []string{"long_term", "today"},
+				"description": "long_term (MEMORY.md, default) or today's daily note",
+			},
+		},
+		"required": []string{"operation"},
+	}
+}
+
+func (t *MemoryTool) Execute(ctx context.Context, args map[string]interface{}) *ToolResult {
+	op, _ := args["operation"].(string)
+	section, _ := args["section"].(string)
+	content, _ := args["content"].(string)
+	section = strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(section), "#"))
+
+	path, header := t.resolveFile(args)
+	name := filepath.Base(path)
+
+	memoryMu.Lock()
+	defer memoryMu.Unlock()
+
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return ErrorResult(fmt.Sprintf("failed to read %s: %v", name, err))
+	}
+	lines := splitMemoryLines(string(data))
+
+	switch op {
+	case "list":
+		sections := parseMemorySections(lines)
+		if len(sections) == 0 {
+			return SilentResult(fmt.Sprintf("%s has no sections.", name))
+		}
+		out := []string{fmt.Sprintf("Sections in %s:", name)}
+		for _, s := range sections {
+			out = append(out, fmt.Sprintf("- %s %s (%d lines)", strings.Repeat("#", s.level), s.title, s.end-s.bodyStart))
+		}
+		return SilentResult(strings.Join(out, "\n"))
+
+	case "search":
+		query, _ := args["query"].(string)
+		if strings.TrimSpace(query) == "" {
+			return ErrorResult("query is required for search")
+		}
+		return SilentResult(searchMemory(name, lines, query))
+
+	case "append", "replace_section":
+		if strings.TrimSpace(content) == "" {
+			return ErrorResult("content is required for " + op)
+		}
+		if op == "replace_section" && section == "" {
+			return ErrorResult("section is required for replace_section")
+		}
+		if len(lines) == 0 && header != "" {
+			lines = []string{header, ""}
+		}
+		body := splitMemoryLines(strings.TrimRight(content, "\n"))
+		if op == "append" {
+			lines = appendToSection(lines, section, body)
+		} else {
+			lines = replaceSection(lines, section, body)
+		}
+		if err := writeFileAtomic(path, []byte(strings.Join(lines, "\n")+"\n")); err != nil {
+			return ErrorResult(fmt.Sprintf("failed to write %s: %v", name, err))
+		}
+		where := name
+		if section != "" {
+			where = fmt.Sprintf("section %q of %s", section, name)
+		}
+		if op == "append" {
+			return SilentResult(fmt.Sprintf("Appended to %s.", where))
+		}
+		return SilentResult(fmt.Sprintf("Replaced %s.", where))
+	}
+	return ErrorResult("operation must be one of append, replace_section, list, search")
+}
+
+// resolveFile returns the memory file to operate on and the header a new
+// file starts with.
+func (t *MemoryTool) resolveFile(args map[string]interface{}) (path, header string) {
+	if file, _ := args["file"].(string); file == "today" {
+		now := t.now()
+		day := now.Format("20060102")
+		return filepath.Join(t.memoryDir, day[:6], day+".md"), "# " + now.Format("2006-01-02")
+	}
+	return filepath.Join(t.memoryDir, "MEMORY.md"), ""
+}
+
+// memorySection is a Markdown heading and the lines it owns: the body runs
+// from bodyStart to end (exclusive), up to the next heading of the same or
+// a higher level.
+type memorySection struct {
+	title     string
+	level     int
+	start     int
+	bodyStart int
+	end       int
+}
+
+func splitMemoryLines(s string) []string {
+	s = strings.TrimRight(s, "\n")
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "\n")
+}
+
+// parseMemorySections finds headings outside fenced code blocks.
+func parseMemorySections(lines []string) []memorySection {
+	var sections []memorySection
+	inFence := false
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") {
+			inFence = !inFence
+			continue
+		}
+		if inFence {
+			continue
+		}
+		level := headingLevel(line)
+		if level == 0 {
+			continue
+		}
+		for j := range sections {
+			if sections[j].end == -1 && sections[j].level >= level {
+				sections[j].end = i
+			}
+		}
+		sections = append(sections, memorySection{
+			title:     strings.TrimSpace(line[level:]),
+			level:     level,
+			start:     i,
+			bodyStart: i + 1,
+			end:       -1,
+		})
+	}
+	for j := range sections {
+		if sections[j].end == -1 {
+			sections[j].end = len(lines)
+		}
+	}
+	return sections
+}
+
+func headingLevel(line string) int {
+	level := 0
+	for level < len(line) && level < 6 && line[level] == '#' {
+		level++
+	}
+	if level == 0 || level >= len(line) || line[level] != ' ' {
+		return 0
+	}
+	return level
+}
+
+func findMemorySection(lines []string, title string) (memorySection, bool) {
+	for _, s := range parseMemorySections(lines) {
+		if strings.EqualFold(s.title, title) {
+			return s, true
+		}
+	}
+	return memorySection{}, false
+}
+
+// appendToSection adds body at the end of the named section (before its
+// trailing blank lines), creating the section if needed. An empty title
+// appends to the end of the file.
+func appendToSection(lines []string, title string, body []string) []string {
+	if title == "" {
+		return append(withBlankLine(lines), body...)
+	}
+	s, ok := findMemorySection(lines, title)
+	if !ok {
+		return append(withBlankLine(lines), append([]string{"## " + title, ""}, body...)...)
+	}
+	at := s.end
+	for at > s.bodyStart && strings.TrimSpace(lines[at-1]) == "" {
+		at--
+	}
+	return spliceLines(lines, at, at, body)
+}
+
+// replaceSection swaps the named section's body for body, creating the
+// section if needed.
+func replaceSection(lines []string, title string, body []string) []string {
+	s, ok := findMemorySection(lines, title)
+	if !ok {
+		return append(withBlankLine(lines), append([]string{"## " + title, ""}, body...)...)
+	}
+	replacement := append([]string{""}, body...)
+	if s.end < len(lines) {
+		replacement = append(replacement, "")
+	}
+	return spliceLines(lines, s.bodyStart, s.end, replacement)
+}
+
+func spliceLines(lines []string, from, to int, insert []string) []string {
+	out := make([]string, 0, len(lines)-(to-from)+len(insert))
+	out = append(out, lines[:from]...)
+	out = append(out, insert...)
+	return append(out, lines[to:]...)
+}
+
+func withBlankLine(lines []string) []string {
+	if len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) != "" {
+		return append(lines, "")
+	}
+	return lines
+}
+
+func searchMemory(name string, lines []string, query string) string {
+	query = strings.ToLower(query)
+	sections := parseMemorySections(lines)
+	sectionAt := func(i int) string {
+		title := ""
+		for _, s := range sections {
+			if s.start < i && i < s.end {
+				title = s.title // Innermost section wins: later headings nest deeper
+			}
+		}
+		return title
+	}
+
+	var matches []string
+	for i, line := range lines {
+		if !strings.Contains(strings.ToLower(line), query) {
+			continue
+		}
+		if len(matches) == memorySearchLimit {
+			matches = append(matches, fmt.Sprintf("... more matches omitted (limit %d)", memorySearchLimit))
+			break
+		}
+		prefix := fmt.Sprintf("%d", i+1)
+		if title := sectionAt(i); title != "" {
+			prefix += " [" + title + "]"
+		}
+		matches = append(matches, prefix+": "+strings.TrimSpace(line))
+	}
+	if len(matches) == 0 {
+		return fmt.Sprintf("No matches for %q in %s.", query, name)
+	}
+	return fmt.Sprintf("Matches in %s:\n%s", name, strings.Join(matches, "\n"))
+}
+
+// writeFileAtomic writes data to a temp file next to path and renames it
+// into place, so readers never see a partial file.
+func writeFileAtomic(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+"-*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func readMemoryFile(t *testing.T, workspace string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(workspace, "memory", "MEMORY.md"))
+	if err != nil {
+		t.Fatalf("read MEMORY.md: %v", err)
+	}
+	return string(data)
+}
+
+func TestMemoryTool_ReplaceSection(t *testing.T) {
+	workspace := t.TempDir()
+	os.MkdirAll(filepath.Join(workspace, "memory"), 0755)
+	initial := "# Memory\n\n## User\n\nName: Bob\nLikes tea\n\n## Projects\n\n- picoclaw\n"
+	os.WriteFile(filepath.Join(workspace, "memory", "MEMORY.md"), []byte(initial), 0644)
+	tool := NewMemoryTool(workspace)
+
+	result := tool.Execute(context.Background(), map[string]interface{}{
+		"operation": "replace_section",
+		"section":   "## user",
+		"content":   "Name: Bob\nLikes coffee",
+	})
+	if result.IsError {
+		t.Fatalf("replace_section error: %s", result.ForLLM)
+	}
+	want := "# Memory\n\n## User\n\nName: Bob\nLikes coffee\n\n## Projects\n\n- picoclaw\n"
+	if got := readMemoryFile(t, workspace); got != want {
+		t.Fatalf("after replace_section:\n%q\nwant:\n%q", got, want)
+	}
+
+	// A missing section is created at the end.
+	tool.Execute(context.Background(), map[string]interface{}{
+		"operation": "replace_section",
+		"section":   "Preferences",
+		"content":   "Metric units",
+	})
+	if got := readMemoryFile(t, workspace); !strings.HasSuffix(got, "- picoclaw\n\n## Preferences\n\nMetric units\n") {
+		t.Fatalf("new section not appended:\n%s", got)
+	}
+
+	// The last section is replaced up to the end of the file.
+	tool.Execute(context.Background(), map[string]interface{}{
+		"operation": "replace_section",
+		"section":   "Preferences",
+		"content":   "Imperial units",
+	})
+	if got := readMemoryFile(t, workspace); !strings.HasSuffix(got, "## Preferences\n\nImperial units\n") || strings.Contains(got, "Metric") {
+		t.Fatalf("last section not replaced:\n%s", got)
+	}
+}
+
+func TestMemoryTool_AppendListSearch(t *testing.T) {
+	workspace := t.TempDir()
+	tool := NewMemoryTool(workspace)
+	run := func(args map[string]interface{}) string {
+		t.Helper()
+		result := tool.Execute(context.Background(), args)
+		if result.IsError {
+			t.Fatalf("%v error: %s", args["operation"], result.ForLLM)
+		}
+		return result.ForLLM
+	}
+
+	run(map[string]interface{}{"operation": "append", "section": "Facts", "content": "- Sky is blue"})
+	run(map[string]interface{}{"operation": "append", "section": "Todo", "content": "- Buy milk"})
+	run(map[string]interface{}{"operation": "append", "section": "Facts", "content": "- Grass is green"})
+
+	want := "## Facts\n\n- Sky is blue\n- Grass is green\n\n## Todo\n\n- Buy milk\n"
+	if got := readMemoryFile(t, workspace); got != want {
+		t.Fatalf("after appends:\n%q\nwant:\n%q", got, want)
+	}
+
+	if out := run(map[string]interface{}{"operation": "list"}); !strings.Contains(out, "- ## Facts (4 lines)") || !strings.Contains(out, "- ## Todo") {
+		t.Errorf("list = %q", out)
+	}
+	if out := run(map[string]interface{}{"operation": "search", "query": "GRASS"}); !strings.Contains(out, "[Facts]: - Grass is green") {
+		t.Errorf("search = %q", out)
+	}
+	if out := run(map[string]interface{}{"operation": "search", "query": "nothing"}); !strings.Contains(out, "No matches") {
+		t.Errorf("search without matches = %q", out)
+	}
+}
+
+func TestMemoryTool_TodayFile(t *testing.T) {
+	workspace := t.TempDir()
+	tool := NewMemoryTool(workspace)
+	tool.now = func() time.Time { return time.Date(2026, 3, 4, 10, 0, 0, 0, time.UTC) }
+
+	tool.Execute(context.Background(), map[string]interface{}{"operation": "append", "file": "today", "content": "Met Alice"})
+	data, err := os.ReadFile(filepath.Join(workspace, "memory", "202603", "20260304.md"))
+	if err != nil {
+		t.Fatalf("daily note not written: %v", err)
+	}
+	if string(data) != "# 2026-03-04\n\nMet Alice\n" {
+		t.Fatalf("daily note = %q", data)
+	}
+}
+
+func TestMemoryTool_ConcurrentAppends(t *testing.T) {
+	workspace := t.TempDir()
+	// Separate instances, like the main agent's and a subagent's registries.
+	tools := []*MemoryTool{NewMemoryTool(workspace), NewMemoryTool(workspace)}
+
+	const n = 20
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			tools[i%2].Execute(context.Background(), map[string]interface{}{
+				"operation": "append",
+				"section":   "Log",
+				"content":   fmt.Sprintf("- entry %d", i),
+			})
+		}(i)
+	}
+	wg.Wait()
+
+	got := readMemoryFile(t, workspace)
+	for i := 0; i < n; i++ {
+		if !strings.Contains(got, fmt.Sprintf("- entry %d\n", i)) {
+			t.Errorf("entry %d lost:\n%s", i, got)
+		}
+	}
+	if strings.Count(got, "## Log") != 1 {
+		t.Errorf("section duplicated:\n%s", got)
+	}
+}
@@ -3,6 +3,7 @@ package tools
 import (
 	"context"
 	"fmt"
+	"sync"
 )
 
 type SendCallback func(channel, chatID, content string) error
@@ -11,11 +12,12 @@ type MessageTool struct {
 	sendCallback   SendCallback
 	defaultChannel string
 	defaultChatID  string
-	sentInRound    bool // Tracks whether a message was sent in the current processing round
+	sentInRound    map[string]bool // channel:chatID -> message sent during that chat's current round
+	mu             sync.Mutex
 }
 
 func NewMessageTool() *MessageTool {
-	return &MessageTool{}
+	return &MessageTool{sentInRound: make(map[string]bool)}
 }
 
 func (t *MessageTool) Name() string {
@@ -48,14 +50,32 @@ func (t *MessageTool) Parameters() map[string]interface{} {
 }
 
 func (t *MessageTool) SetContext(channel, chatID string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
 	t.defaultChannel = channel
 	t.defaultChatID = chatID
-	t.sentInRound = false // Reset send tracking for new processing round
+	delete(t.sentInRound, roundKey(channel, chatID)) // Reset send tracking for this chat's new round
 }
 
-// HasSentInRound returns true if the message tool sent a message during the current round.
-func (t *MessageTool) HasSentInRound() bool {
-	return t.sentInRound
+// StartRound resets send tracking for the given chat at the start of its
+// turn, without touching the fallback context other chats might rely on.
+func (t *MessageTool) StartRound(channel, chatID string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.sentInRound, roundKey(channel, chatID))
+}
+
+// HasSentInRound returns true if the message tool sent a message to the
+// given chat during its current round. Rounds are tracked per chat so
+// concurrent conversations do not suppress each other's responses.
+func (t *MessageTool) HasSentInRound(channel, chatID string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.sentInRound[roundKey(channel, chatID)]
+}
+
+func roundKey(channel, chatID string) string {
+	return channel + ":" + chatID
 }
 
 func (t *MessageTool) SetSendCallback(callback SendCallback) {
@@ -71,11 +91,14 @@ func (t *MessageTool) Execute(ctx context.Context, args map[string]interface{})
 	channel, _ := args["channel"].(string)
 	chatID, _ := args["chat_id"].(string)
 
+	t.mu.Lock()
+	ctxChannel, ctxChatID := ToolContext(ctx, t.defaultChannel, t.defaultChatID)
+	t.mu.Unlock()
 	if channel == "" {
-		channel = t.defaultChannel
+		channel = ctxChannel
 	}
 	if chatID == "" {
-		chatID = t.defaultChatID
+		chatID = ctxChatID
 	}
 
 	if channel == "" || chatID == "" {
@@ -94,7 +117,9 @@ func (t *MessageTool) Execute(ctx context.Context, args map[string]interface{})
 		}
 	}
 
-	t.sentInRound = true
+	t.mu.Lock()
+	t.sentInRound[roundKey(channel, chatID)] = true
+	t.mu.Unlock()
 	// Silent: user already received the message directly
 	return &ToolResult{
 		ForLLM: fmt.Sprintf("Message sent to %s:%s", channel, chatID),
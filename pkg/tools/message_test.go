@@ -3,6 +3,7 @@ package tools
 import (
 	"context"
 	"errors"
+	"sync"
 	"testing"
 )
 
@@ -257,3 +258,119 @@ func TestMessageTool_Parameters(t *testing.T) {
 		t.Error("Expected chat_id type to be 'string'")
 	}
 }
+
+func TestMessageTool_HasSentInRound_PerSession(t *testing.T) {
+	tool := NewMessageTool()
+	tool.SetSendCallback(func(channel, chatID, content string) error { return nil })
+	ctx := context.Background()
+
+	// Session A's round starts and sends via the tool.
+	tool.SetContext("telegram", "A")
+	tool.Execute(ctx, map[string]interface{}{"content": "hi A"})
+
+	// Session B's round starts while A's is still finishing.
+	tool.SetContext("telegram", "B")
+
+	if !tool.HasSentInRound("telegram", "A") {
+		t.Error("Expected session A to keep its sent flag when B's round starts")
+	}
+	if tool.HasSentInRound("telegram", "B") {
+		t.Error("Expected session B not to be suppressed by A's message")
+	}
+
+	tool.Execute(ctx, map[string]interface{}{"content": "hi B"})
+	if !tool.HasSentInRound("telegram", "B") {
+		t.Error("Expected session B to be marked after sending")
+	}
+
+	// A new round for A resets only A.
+	tool.SetContext("telegram", "A")
+	if tool.HasSentInRound("telegram", "A") {
+		t.Error("Expected session A to reset on its next round")
+	}
+	if !tool.HasSentInRound("telegram", "B") {
+		t.Error("Expected session B to be unaffected by A's reset")
+	}
+}
+
+func TestMessageTool_ContextOverridesDefault(t *testing.T) {
+	tool := NewMessageTool()
+	tool.SetContext("default-channel", "default-chat-id")
+
+	var sentChannel, sentChatID string
+	tool.SetSendCallback(func(channel, chatID, content string) error {
+		sentChannel = channel
+		sentChatID = chatID
+		return nil
+	})
+
+	ctx := WithToolContext(context.Background(), "telegram", "42")
+	result := tool.Execute(ctx, map[string]interface{}{"content": "hi"})
+
+	if result.IsError {
+		t.Fatalf("Expected success, got error: %s", result.ForLLM)
+	}
+	if sentChannel != "telegram" || sentChatID != "42" {
+		t.Errorf("Expected telegram:42 from ctx, got %s:%s", sentChannel, sentChatID)
+	}
+	if !tool.HasSentInRound("telegram", "42") {
+		t.Error("Expected round to be tracked for the ctx chat")
+	}
+}
+
+// TestMessageTool_ConcurrentSessionsRouteToOwnChat runs two conversations
+// through one registry at once; run with -race to catch shared state.
+func TestMessageTool_ConcurrentSessionsRouteToOwnChat(t *testing.T) {
+	tool := NewMessageTool()
+	var mu sync.Mutex
+	misrouted := 0
+	sent := 0
+	tool.SetSendCallback(func(channel, chatID, content string) error {
+		mu.Lock()
+		defer mu.Unlock()
+		sent++
+		if content != channel+":"+chatID {
+			misrouted++
+		}
+		return nil
+	})
+
+	registry := NewToolRegistry()
+	registry.Register(tool)
+
+	sessions := []struct{ channel, chatID string }{
+		{"telegram", "A"},
+		{"discord", "B"},
+	}
+	const perSession = 100
+
+	var wg sync.WaitGroup
+	for _, s := range sessions {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range perSession {
+				tool.StartRound(s.channel, s.chatID)
+				args := map[string]interface{}{"content": s.channel + ":" + s.chatID}
+				result := registry.ExecuteWithContext(context.Background(), "message", args, s.channel, s.chatID, nil)
+				if result.IsError {
+					t.Errorf("%s:%s send failed: %s", s.channel, s.chatID, result.ForLLM)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if sent != len(sessions)*perSession {
+		t.Errorf("Expected %d sends, got %d", len(sessions)*perSession, sent)
+	}
+	if misrouted != 0 {
+		t.Errorf("Expected every message in its own chat, %d were misrouted", misrouted)
+	}
+	for _, s := range sessions {
+		if !tool.HasSentInRound(s.channel, s.chatID) {
+			t.Errorf("Expected %s:%s to be marked as sent", s.channel, s.chatID)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// ModelChain describes the models the agent can route to.
+// failover.Manager implements it.
+type ModelChain interface {
+	Enabled() bool
+	PrimaryModel() string
+	FallbackModels() []string
+	ActiveModel() string
+}
+
+// ModelsInfoTool reports the configured primary model, the fallback chain
+// and the model currently answering.
+type ModelsInfoTool struct {
+	chain ModelChain
+}
+
+func NewModelsInfoTool(chain ModelChain) *ModelsInfoTool {
+	return &ModelsInfoTool{chain: chain}
+}
+
+func (t *ModelsInfoTool) Name() string {
+	return "models_info"
+}
+
+// ParallelSafe reports true: models_info only reads.
+func (t *ModelsInfoTool) ParallelSafe() bool {
+	return true
+}
+
+func (t *ModelsInfoTool) Description() string {
+	return "Show which models you run on: the primary model, the fallback chain used when it is rate limited, and the model currently active."
+}
+
+func (t *ModelsInfoTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type":       "object",
+		"properties": map[string]interface{}{},
+	}
+}
+
+func (t *ModelsInfoTool) Execute(ctx context.Context, args map[string]interface{}) *ToolResult {
+	primary := t.chain.PrimaryModel()
+	active := t.chain.ActiveModel()
+	fallbacks := t.chain.FallbackModels()
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Primary model: %s\n", primary)
+	if len(fallbacks) == 0 {
+		sb.WriteString("Fallback chain: none\n")
+	} else {
+		sb.WriteString("Fallback chain:\n")
+		for i, model := range fallbacks {
+			fmt.Fprintf(&sb, "  %d. %s\n", i+1, model)
+		}
+	}
+	fmt.Fprintf(&sb, "Active model: %s", active)
+	if active != primary {
+		sb.WriteString(" (fallback)")
+	}
+	if !t.chain.Enabled() {
+		sb.WriteString("\nFailover: disabled, the fallback chain is not used")
+	}
+	return SilentResult(sb.String())
+}
@@ -0,0 +1,41 @@
+package tools
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/failover"
+	"github.com/sipeed/picoclaw/pkg/state"
+)
+
+func TestModelsInfoTool_ReflectsConfiguredChain(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Agents.Defaults.Model = "claude-sonnet-4-5"
+	cfg.Agents.Defaults.FallbackModels = []string{"gpt-5-mini", " claude-sonnet-4-5 ", "gemini-2.5-flash", "gpt-5-mini"}
+	cfg.Agents.Failover.Enabled = true
+	mgr := failover.NewManager(cfg, state.NewManager(t.TempDir()))
+
+	result := NewModelsInfoTool(mgr).Execute(context.Background(), nil)
+
+	want := "Primary model: claude-sonnet-4-5\n" +
+		"Fallback chain:\n" +
+		"  1. gpt-5-mini\n" +
+		"  2. gemini-2.5-flash\n" +
+		"Active model: claude-sonnet-4-5"
+	if result.IsError || result.ForLLM != want {
+		t.Fatalf("models_info =\n%s\nwant\n%s", result.ForLLM, want)
+	}
+}
+
+func TestModelsInfoTool_NoFallbacks(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Agents.Defaults.Model = "glm-4.7"
+	cfg.Agents.Failover.Enabled = false
+	mgr := failover.NewManager(cfg, state.NewManager(t.TempDir()))
+
+	want := "Primary model: glm-4.7\nFallback chain: none\nActive model: glm-4.7\nFailover: disabled, the fallback chain is not used"
+	if got := NewModelsInfoTool(mgr).Execute(context.Background(), nil).ForLLM; got != want {
+		t.Fatalf("models_info =\n%s\nwant\n%s", got, want)
+	}
+}
@@ -3,30 +3,79 @@ package tools
 import (
 	"context"
 	"fmt"
+	"regexp"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/sipeed/picoclaw/pkg/logger"
 	"github.com/sipeed/picoclaw/pkg/providers"
+	"github.com/sipeed/picoclaw/pkg/utils"
 )
 
+// dangerousMarker prefixes the provider description of tools marked with
+// MarkDangerous.
+const dangerousMarker = "[destructive] "
+
 type ToolRegistry struct {
-	tools map[string]Tool
-	mu    sync.RWMutex
+	tools     map[string]Tool
+	dangerous map[string]bool
+	denied    []string // Names (or "prefix*" patterns) Register ignores
+	mu        sync.RWMutex
 }
 
 func NewToolRegistry() *ToolRegistry {
 	return &ToolRegistry{
-		tools: make(map[string]Tool),
+		tools:     make(map[string]Tool),
+		dangerous: make(map[string]bool),
 	}
 }
 
 func (r *ToolRegistry) Register(tool Tool) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
+	if r.isDenied(tool.Name()) {
+		logger.DebugCF("tool", "Tool denied, not registering",
+			map[string]interface{}{
+				"tool": tool.Name(),
+			})
+		return
+	}
 	r.tools[tool.Name()] = tool
 }
 
+// Deny keeps the named tools out of the registry: later Register calls for
+// them are ignored. A trailing "*" matches a name prefix ("screen_*").
+// Tools already registered are removed.
+func (r *ToolRegistry) Deny(names ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, name := range names {
+		if name = strings.TrimSpace(name); name != "" {
+			r.denied = append(r.denied, name)
+		}
+	}
+	for name := range r.tools {
+		if r.isDenied(name) {
+			delete(r.tools, name)
+		}
+	}
+}
+
+// isDenied must be called with the lock held.
+func (r *ToolRegistry) isDenied(name string) bool {
+	for _, pattern := range r.denied {
+		if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+			if strings.HasPrefix(name, prefix) {
+				return true
+			}
+		} else if name == pattern {
+			return true
+		}
+	}
+	return false
+}
+
 func (r *ToolRegistry) Get(name string) (Tool, bool) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
@@ -34,6 +83,71 @@ func (r *ToolRegistry) Get(name string) (Tool, bool) {
 	return tool, ok
 }
 
+// MarkDangerous flags the named tools as destructive. Their descriptions in
+// ToProviderDefs carry a marker asking the model to confirm with the user
+// first. Names may be marked before the tool is registered.
+func (r *ToolRegistry) MarkDangerous(names ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, name := range names {
+		if name = strings.TrimSpace(name); name != "" {
+			r.dangerous[name] = true
+		}
+	}
+}
+
+// IsDangerous reports whether the named tool was marked with MarkDangerous.
+func (r *ToolRegistry) IsDangerous(name string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.dangerous[name]
+}
+
+// IsParallelSafe reports whether the named tool may run concurrently with
+// other parallel-safe calls. Unknown tools, and tools that receive
+// per-call context or callbacks, are never parallel-safe.
+func (r *ToolRegistry) IsParallelSafe(name string) bool {
+	tool, ok := r.Get(name)
+	if !ok {
+		return false
+	}
+	if _, ok := tool.(ContextualTool); ok {
+		return false
+	}
+	if _, ok := tool.(AsyncTool); ok {
+		return false
+	}
+	safe, ok := tool.(ParallelSafeTool)
+	return ok && safe.ParallelSafe()
+}
+
+// argumentErrorPattern spots tool errors that are likely caused by the
+// arguments rather than by the environment.
+var argumentErrorPattern = regexp.MustCompile(`(?i)\b(required|missing|must be|invalid|expected|empty)\b`)
+
+// withSchemaHint appends the tool's parameter summary to an argument error
+// for tools that opt in with SchemaHintTool.
+func withSchemaHint(tool Tool, result *ToolResult) *ToolResult {
+	if ht, ok := tool.(SchemaHintTool); !ok || !ht.SchemaHint() {
+		return result
+	}
+	if hint := formatSchemaHint(tool.Parameters()); hint != "" {
+		result.ForLLM += "\n" + hint
+	}
+	return result
+}
+
+// RedactedArgs returns args for logging, with secrets hidden by tools that
+// implement RedactingTool.
+func (r *ToolRegistry) RedactedArgs(name string, args map[string]interface{}) map[string]interface{} {
+	if tool, ok := r.Get(name); ok {
+		if rt, ok := tool.(RedactingTool); ok {
+			return rt.RedactArgs(args)
+		}
+	}
+	return args
+}
+
 func (r *ToolRegistry) Execute(ctx context.Context, name string, args map[string]interface{}) *ToolResult {
 	return r.ExecuteWithContext(ctx, name, args, "", "", nil)
 }
@@ -42,24 +156,43 @@ func (r *ToolRegistry) Execute(ctx context.Context, name string, args map[string
 // If the tool implements AsyncTool and a non-nil callback is provided,
 // the callback will be set on the tool before execution.
 func (r *ToolRegistry) ExecuteWithContext(ctx context.Context, name string, args map[string]interface{}, channel, chatID string, asyncCallback AsyncCallback) *ToolResult {
+	correlationID := CorrelationID(ctx)
 	logger.InfoCF("tool", "Tool execution started",
 		map[string]interface{}{
-			"tool": name,
-			"args": args,
+			"tool":           name,
+			"args":           r.RedactedArgs(name, args),
+			"correlation_id": correlationID,
 		})
 
 	tool, ok := r.Get(name)
 	if !ok {
 		logger.ErrorCF("tool", "Tool not found",
 			map[string]interface{}{
-				"tool": name,
+				"tool":           name,
+				"error_class":    utils.ErrorClassNotFound,
+				"correlation_id": correlationID,
 			})
 		return ErrorResult(fmt.Sprintf("tool %q not found", name)).WithError(fmt.Errorf("tool not found"))
 	}
 
-	// If tool implements ContextualTool, set context
-	if contextualTool, ok := tool.(ContextualTool); ok && channel != "" && chatID != "" {
-		contextualTool.SetContext(channel, chatID)
+	// Check the arguments against the declared schema here, so every tool
+	// reports missing or mistyped parameters the same way.
+	if err := validateArgs(tool.Parameters(), args); err != nil {
+		logger.WarnCF("tool", "Invalid tool arguments",
+			map[string]interface{}{
+				"tool":           name,
+				"error":          err.Error(),
+				"error_class":    utils.ErrorClassValidation,
+				"fingerprint":    utils.ErrorFingerprint(err.Error(), "tool", name, utils.ErrorClassValidation),
+				"correlation_id": correlationID,
+			})
+		return withSchemaHint(tool, ErrorResult(fmt.Sprintf("invalid arguments for tool %q: %v", name, err)).WithError(err))
+	}
+
+	// Pass channel/chatID with this call only; setting them on the shared
+	// tool instance would race with other conversations.
+	if channel != "" && chatID != "" {
+		ctx = WithToolContext(ctx, channel, chatID)
 	}
 
 	// If tool implements AsyncTool and callback is provided, set callback
@@ -74,14 +207,30 @@ func (r *ToolRegistry) ExecuteWithContext(ctx context.Context, name string, args
 	start := time.Now()
 	result := tool.Execute(ctx, args)
 	duration := time.Since(start)
+	if result.IsError && argumentErrorPattern.MatchString(result.ForLLM) {
+		result = withSchemaHint(tool, result)
+	}
 
 	// Log based on result type
 	if result.IsError {
+		// Some tools fail with only Err set; never log a failure without
+		// its cause.
+		errText := result.ForLLM
+		if errText == "" && result.Err != nil {
+			errText = result.Err.Error()
+		}
+		if errText == "" {
+			errText = "(no error message)"
+		}
+		errorClass := utils.ClassifyError(result.Err, errText)
 		logger.ErrorCF("tool", "Tool execution failed",
 			map[string]interface{}{
-				"tool":     name,
-				"duration": duration.Milliseconds(),
-				"error":    result.ForLLM,
+				"tool":           name,
+				"duration":       duration.Milliseconds(),
+				"error":          errText,
+				"error_class":    errorClass,
+				"fingerprint":    utils.ErrorFingerprint(errText, "tool", name, errorClass),
+				"correlation_id": correlationID,
 			})
 	} else if result.Async {
 		logger.InfoCF("tool", "Tool started (async)",
@@ -131,6 +280,9 @@ func (r *ToolRegistry) ToProviderDefs() []providers.ToolDefinition {
 		name, _ := fn["name"].(string)
 		desc, _ := fn["description"].(string)
 		params, _ := fn["parameters"].(map[string]interface{})
+		if r.dangerous[name] {
+			desc = dangerousMarker + "Confirm with the user before calling this. " + desc
+		}
 
 		definitions = append(definitions, providers.ToolDefinition{
 			Type: "function",
@@ -0,0 +1,139 @@
+package tools
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestToProviderDefs_MarksDangerousTools(t *testing.T) {
+	r := NewToolRegistry()
+	r.MarkDangerous(" write_file ", "") // before registration, with stray spaces
+	r.Register(NewWriteFileTool(t.TempDir(), true))
+	r.Register(NewReadFileTool(t.TempDir(), true))
+
+	if !r.IsDangerous("write_file") || r.IsDangerous("read_file") {
+		t.Fatalf("IsDangerous: write_file=%t read_file=%t", r.IsDangerous("write_file"), r.IsDangerous("read_file"))
+	}
+
+	descs := make(map[string]string)
+	for _, def := range r.ToProviderDefs() {
+		descs[def.Function.Name] = def.Function.Description
+	}
+	if !strings.HasPrefix(descs["write_file"], dangerousMarker) {
+		t.Errorf("write_file description = %q, want %q marker", descs["write_file"], dangerousMarker)
+	}
+	if !strings.HasSuffix(descs["write_file"], NewWriteFileTool("", true).Description()) {
+		t.Errorf("write_file description lost the original text: %q", descs["write_file"])
+	}
+	if strings.Contains(descs["read_file"], dangerousMarker) {
+		t.Errorf("read_file description = %q, should not be marked", descs["read_file"])
+	}
+}
+
+func TestToolRegistry_Deny(t *testing.T) {
+	r := NewToolRegistry()
+	r.Register(NewExecTool(t.TempDir(), true))
+	r.Deny("exec", "write_*")
+	r.Register(NewWriteFileTool(t.TempDir(), true))
+	r.Register(NewReadFileTool(t.TempDir(), true))
+
+	if _, ok := r.Get("exec"); ok {
+		t.Error("exec registered before Deny should be removed")
+	}
+	if _, ok := r.Get("write_file"); ok {
+		t.Error("write_file should be denied by the write_* pattern")
+	}
+	if _, ok := r.Get("read_file"); !ok {
+		t.Error("read_file should still register")
+	}
+}
+
+// schemaTool records whether Execute ran.
+type schemaTool struct {
+	ran bool
+}
+
+func (t *schemaTool) Name() string        { return "schema_tool" }
+func (t *schemaTool) Description() string { return "test tool" }
+func (t *schemaTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"path":    map[string]interface{}{"type": "string"},
+			"count":   map[string]interface{}{"type": "integer"},
+			"ratio":   map[string]interface{}{"type": "number"},
+			"force":   map[string]interface{}{"type": "boolean"},
+			"tags":    map[string]interface{}{"type": "array"},
+			"options": map[string]interface{}{"type": []interface{}{"object", "null"}},
+		},
+		"required": []interface{}{"path"},
+	}
+}
+func (t *schemaTool) Execute(ctx context.Context, args map[string]interface{}) *ToolResult {
+	t.ran = true
+	return SilentResult("ok")
+}
+
+func TestToolRegistry_ValidatesArguments(t *testing.T) {
+	tool := &schemaTool{}
+	r := NewToolRegistry()
+	r.Register(tool)
+
+	tests := []struct {
+		args    map[string]interface{}
+		wantErr string
+	}{
+		{map[string]interface{}{"path": "a", "count": float64(3), "ratio": 0.5, "force": true, "tags": []interface{}{"x"}, "options": nil}, ""},
+		{map[string]interface{}{"path": "a", "count": 3, "tags": []string{"x"}, "options": map[string]interface{}{}}, ""},
+		{map[string]interface{}{}, `missing required parameter "path"`},
+		{map[string]interface{}{"path": nil}, `missing required parameter "path"`},
+		{map[string]interface{}{"path": 7}, `parameter "path" must be string, got number`},
+		{map[string]interface{}{"path": "a", "count": 1.5}, `parameter "count" must be integer, got number`},
+		{map[string]interface{}{"path": "a", "force": "yes"}, `parameter "force" must be boolean, got string`},
+		{map[string]interface{}{"path": "a", "options": "x"}, `parameter "options" must be object or null, got string`},
+	}
+	for _, tt := range tests {
+		tool.ran = false
+		result := r.Execute(context.Background(), "schema_tool", tt.args)
+		if tt.wantErr == "" {
+			if result.IsError || !tool.ran {
+				t.Errorf("args %v: got error %q, want the tool to run", tt.args, result.ForLLM)
+			}
+			continue
+		}
+		if !result.IsError || tool.ran {
+			t.Errorf("args %v: tool ran, want it rejected", tt.args)
+			continue
+		}
+		want := `invalid arguments for tool "schema_tool": ` + tt.wantErr
+		if result.ForLLM != want {
+			t.Errorf("args %v: error = %q, want %q", tt.args, result.ForLLM, want)
+		}
+	}
+}
+
+func TestToolRegistry_SchemaHintOnArgumentErrors(t *testing.T) {
+	r := NewToolRegistry()
+	r.Register(NewSendFileTool(t.TempDir()))
+	r.Register(&schemaTool{})
+	hint := "\nExpected arguments: files (array of string, required), caption (string), channel (string), chat_id (string)"
+
+	// Rejected by schema validation
+	result := r.Execute(context.Background(), "send_file", map[string]interface{}{"files": "photo.png"})
+	if !result.IsError || !strings.HasSuffix(result.ForLLM, hint) {
+		t.Errorf("send_file with a string: got %q, want the schema hint", result.ForLLM)
+	}
+
+	// Rejected by the tool itself
+	result = r.Execute(context.Background(), "send_file", map[string]interface{}{"files": []interface{}{}})
+	if !result.IsError || !strings.HasPrefix(result.ForLLM, "files array is empty") || !strings.HasSuffix(result.ForLLM, hint) {
+		t.Errorf("send_file with no files: got %q, want the tool error and the schema hint", result.ForLLM)
+	}
+
+	// Tools that don't opt in keep the bare error
+	result = r.Execute(context.Background(), "schema_tool", map[string]interface{}{})
+	if strings.Contains(result.ForLLM, "Expected arguments") {
+		t.Errorf("schema_tool error = %q, want no hint", result.ForLLM)
+	}
+}
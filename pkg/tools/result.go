@@ -30,6 +30,67 @@ type ToolResult struct {
 	// Err is the underlying error (not JSON serialized).
 	// Used for internal error handling and logging.
 	Err error `json:"-"`
+
+	// Details carries optional structured outcome metadata (counts,
+	// warnings) for progress displays. It is never sent to the LLM.
+	Details *ResultDetails `json:"details,omitempty"`
+
+	// Type tells channels how Metadata can be rendered. Empty means text.
+	// ForUser must always hold a plain-text rendering for channels that
+	// cannot render the type.
+	Type ResultType `json:"type,omitempty"`
+
+	// Metadata carries optional structured data for richer rendering
+	// (see TableMetadata). It is never sent to the LLM.
+	Metadata map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// ResultType identifies the shape of a tool result for downstream rendering.
+type ResultType string
+
+const (
+	ResultTypeText  ResultType = "text"
+	ResultTypeTable ResultType = "table"
+	ResultTypeImage ResultType = "image"
+	ResultTypeError ResultType = "error"
+)
+
+// Metadata keys used by ResultTypeTable.
+const (
+	MetadataColumns = "columns" // []string
+	MetadataRows    = "rows"    // [][]string
+)
+
+// EffectiveType returns the effective type of the result: ResultTypeError
+// for failed results, ResultTypeText when no type was set.
+func (tr *ToolResult) EffectiveType() ResultType {
+	switch {
+	case tr.IsError:
+		return ResultTypeError
+	case tr.Type == "":
+		return ResultTypeText
+	default:
+		return tr.Type
+	}
+}
+
+// ResultDetails describes the outcome of a tool that operates on several
+// items, so partial successes (e.g. 3 of 5 files written) are not lost.
+type ResultDetails struct {
+	// Succeeded is the number of items processed successfully.
+	Succeeded int `json:"succeeded,omitempty"`
+
+	// Failed is the number of items that could not be processed.
+	Failed int `json:"failed,omitempty"`
+
+	// Warnings are non-fatal problems worth surfacing to the user.
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// Partial reports whether some items failed while the tool as a whole
+// still succeeded.
+func (d *ResultDetails) Partial() bool {
+	return d != nil && d.Failed > 0
 }
 
 // NewToolResult creates a basic ToolResult with content for the LLM.
@@ -141,3 +202,34 @@ func (tr *ToolResult) WithError(err error) *ToolResult {
 	tr.Err = err
 	return tr
 }
+
+// WithDetails attaches structured outcome metadata and returns the result
+// for chaining.
+//
+// Example:
+//
+//	result := SilentResult("Wrote 3 of 5 files").WithDetails(&ResultDetails{Succeeded: 3, Failed: 2})
+func (tr *ToolResult) WithDetails(details *ResultDetails) *ToolResult {
+	tr.Details = details
+	return tr
+}
+
+// WithMetadata sets the result type and structured metadata and returns the
+// result for chaining. ForUser should still carry a plain-text fallback.
+//
+// Example:
+//
+//	result := UserResult(text).WithMetadata(ResultTypeTable, TableMetadata(cols, rows))
+func (tr *ToolResult) WithMetadata(resultType ResultType, metadata map[string]interface{}) *ToolResult {
+	tr.Type = resultType
+	tr.Metadata = metadata
+	return tr
+}
+
+// TableMetadata builds the metadata for a ResultTypeTable result.
+func TableMetadata(columns []string, rows [][]string) map[string]interface{} {
+	return map[string]interface{}{
+		MetadataColumns: columns,
+		MetadataRows:    rows,
+	}
+}
@@ -227,3 +227,55 @@ func TestToolResultJSONStructure(t *testing.T) {
 		t.Errorf("Expected silent false, got %v", parsed["silent"])
 	}
 }
+
+func TestWithDetails(t *testing.T) {
+	result := SilentResult("Wrote 3 of 5 files").WithDetails(&ResultDetails{
+		Succeeded: 3,
+		Failed:    2,
+		Warnings:  []string{"b.txt: permission denied"},
+	})
+
+	if !result.Details.Partial() {
+		t.Error("Expected 3 succeeded / 2 failed to be partial")
+	}
+	if result.IsError {
+		t.Error("Partial success should not be an error")
+	}
+
+	plain := NewToolResult("ok")
+	if plain.Details.Partial() {
+		t.Error("Expected results without details not to be partial")
+	}
+}
+
+func TestWithMetadata(t *testing.T) {
+	columns := []string{"model", "tokens"}
+	rows := [][]string{{"gpt-4o", "1200"}}
+	result := UserResult("gpt-4o: 1200 tokens").WithMetadata(ResultTypeTable, TableMetadata(columns, rows))
+
+	if result.EffectiveType() != ResultTypeTable {
+		t.Errorf("Expected table type, got %q", result.EffectiveType())
+	}
+	if result.ForUser == "" {
+		t.Error("Expected plain-text fallback to be kept")
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		t.Fatalf("Failed to marshal: %v", err)
+	}
+	var parsed map[string]interface{}
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		t.Fatalf("Failed to unmarshal: %v", err)
+	}
+	if parsed["type"] != "table" || parsed["metadata"] == nil {
+		t.Errorf("Expected type and metadata in JSON, got %s", data)
+	}
+
+	if got := NewToolResult("ok").EffectiveType(); got != ResultTypeText {
+		t.Errorf("Expected untyped result to default to text, got %q", got)
+	}
+	if got := ErrorResult("failed").EffectiveType(); got != ResultTypeError {
+		t.Errorf("Expected error result to report error type, got %q", got)
+	}
+}
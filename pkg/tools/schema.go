@@ -0,0 +1,197 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// validateArgs checks args against a tool's Parameters() schema: required
+// properties must be present and non-null, and present properties must
+// match their declared JSON type. Nested schemas are left to the tool.
+// Integers arrive from JSON as float64, so any whole number is an integer.
+func validateArgs(schema map[string]interface{}, args map[string]interface{}) error {
+	for _, name := range schemaRequired(schema["required"]) {
+		if v, ok := args[name]; !ok || v == nil {
+			return fmt.Errorf("missing required parameter %q", name)
+		}
+	}
+
+	props, _ := schema["properties"].(map[string]interface{})
+	names := make([]string, 0, len(args))
+	for name := range args {
+		names = append(names, name)
+	}
+	sort.Strings(names) // Report the same error for the same call
+	for _, name := range names {
+		value := args[name]
+		prop, ok := props[name].(map[string]interface{})
+		if !ok || value == nil {
+			continue
+		}
+		types := schemaTypes(prop["type"])
+		if len(types) == 0 {
+			continue
+		}
+		matched := false
+		for _, typ := range types {
+			if matchesJSONType(typ, value) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return fmt.Errorf("parameter %q must be %s, got %s", name, strings.Join(types, " or "), jsonTypeName(value))
+		}
+	}
+	return nil
+}
+
+// schemaRequired reads "required" as built in Go ([]string) or decoded
+// from JSON ([]interface{}, e.g. MCP tools).
+func schemaRequired(v interface{}) []string {
+	switch r := v.(type) {
+	case []string:
+		return r
+	case []interface{}:
+		out := make([]string, 0, len(r))
+		for _, item := range r {
+			if s, ok := item.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+// schemaTypes reads "type" as a single type or a list of types.
+func schemaTypes(v interface{}) []string {
+	switch t := v.(type) {
+	case string:
+		return []string{t}
+	case []string:
+		return t
+	case []interface{}:
+		out := make([]string, 0, len(t))
+		for _, item := range t {
+			if s, ok := item.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+func matchesJSONType(typ string, value interface{}) bool {
+	switch typ {
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "number":
+		_, ok := numberValue(value)
+		return ok
+	case "integer":
+		f, ok := numberValue(value)
+		return ok && f == math.Trunc(f)
+	case "array":
+		k := reflect.TypeOf(value).Kind()
+		return k == reflect.Slice || k == reflect.Array
+	case "object":
+		return reflect.TypeOf(value).Kind() == reflect.Map
+	case "null":
+		return value == nil
+	}
+	return true // Unknown types are not checked
+}
+
+func numberValue(value interface{}) (float64, bool) {
+	switch n := value.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func jsonTypeName(value interface{}) string {
+	switch value.(type) {
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	}
+	if _, ok := numberValue(value); ok {
+		return "number"
+	}
+	switch reflect.TypeOf(value).Kind() {
+	case reflect.Slice, reflect.Array:
+		return "array"
+	case reflect.Map:
+		return "object"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+// formatSchemaHint summarizes a Parameters() schema on one line, required
+// parameters first: "Expected arguments: files (array of string, required),
+// caption (string)".
+func formatSchemaHint(schema map[string]interface{}) string {
+	props, _ := schema["properties"].(map[string]interface{})
+	if len(props) == 0 {
+		return ""
+	}
+	required := make(map[string]bool)
+	for _, name := range schemaRequired(schema["required"]) {
+		required[name] = true
+	}
+	names := make([]string, 0, len(props))
+	for name := range props {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if required[names[i]] != required[names[j]] {
+			return required[names[i]]
+		}
+		return names[i] < names[j]
+	})
+
+	parts := make([]string, 0, len(names))
+	for _, name := range names {
+		prop, _ := props[name].(map[string]interface{})
+		desc := strings.Join(schemaTypes(prop["type"]), " or ")
+		if desc == "" {
+			desc = "any"
+		}
+		if items, ok := prop["items"].(map[string]interface{}); ok {
+			if itemTypes := schemaTypes(items["type"]); len(itemTypes) > 0 {
+				desc += " of " + strings.Join(itemTypes, " or ")
+			}
+		}
+		if enum, ok := prop["enum"]; ok {
+			desc += fmt.Sprintf(", one of %v", enum)
+		}
+		if required[name] {
+			desc += ", required"
+		}
+		parts = append(parts, fmt.Sprintf("%s (%s)", name, desc))
+	}
+	return "Expected arguments: " + strings.Join(parts, ", ")
+}
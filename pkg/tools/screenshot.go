@@ -0,0 +1,106 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// ScreenshotCache remembers the most recent screenshot so a multi-step
+// automation can reuse it instead of capturing the screen again. Screen
+// capture tools call Record after a capture, and any tool that changes the
+// screen (tap, swipe, typing, ...) calls Invalidate.
+//
+// Nothing in this tree captures or drives the screen yet, so neither the
+// cache nor LastScreenshotTool is wired up: register the tool (with a
+// freshness setting in ToolsConfig) together with the first capture tool,
+// which is also where Record and Invalidate belong.
+type ScreenshotCache struct {
+	mu    sync.Mutex
+	path  string
+	taken time.Time
+	now   func() time.Time
+}
+
+func NewScreenshotCache() *ScreenshotCache {
+	return &ScreenshotCache{now: time.Now}
+}
+
+// Record stores path as the latest screenshot, taken now.
+func (c *ScreenshotCache) Record(path string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.path = path
+	c.taken = c.now()
+}
+
+// Invalidate forgets the cached screenshot because the screen changed.
+func (c *ScreenshotCache) Invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.path = ""
+	c.taken = time.Time{}
+}
+
+// Latest returns the cached screenshot and its age if it is no older than
+// maxAge. ok is false when nothing is cached or the entry is stale.
+func (c *ScreenshotCache) Latest(maxAge time.Duration) (path string, age time.Duration, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.path == "" {
+		return "", 0, false
+	}
+	age = c.now().Sub(c.taken)
+	if age > maxAge {
+		return "", 0, false
+	}
+	return c.path, age, true
+}
+
+// LastScreenshotTool returns the cached screenshot while it is fresh.
+type LastScreenshotTool struct {
+	cache     *ScreenshotCache
+	freshness time.Duration
+}
+
+// NewLastScreenshotTool creates a LastScreenshotTool that only reports
+// screenshots younger than freshness.
+func NewLastScreenshotTool(cache *ScreenshotCache, freshness time.Duration) *LastScreenshotTool {
+	return &LastScreenshotTool{cache: cache, freshness: freshness}
+}
+
+func (t *LastScreenshotTool) Name() string {
+	return "last_screenshot"
+}
+
+// ParallelSafe reports true: last_screenshot only reads.
+func (t *LastScreenshotTool) ParallelSafe() bool {
+	return true
+}
+
+func (t *LastScreenshotTool) Description() string {
+	return fmt.Sprintf("Get the path of the last screenshot if it was taken within the last %s and the screen has not been changed since. Check this before taking a new screenshot.", t.freshness)
+}
+
+func (t *LastScreenshotTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type":       "object",
+		"properties": map[string]interface{}{},
+	}
+}
+
+func (t *LastScreenshotTool) Execute(ctx context.Context, args map[string]interface{}) *ToolResult {
+	path, age, ok := t.cache.Latest(t.freshness)
+	if ok {
+		if _, err := os.Stat(path); err != nil {
+			t.cache.Invalidate()
+			ok = false
+		}
+	}
+	if !ok {
+		return SilentResult("No recent screenshot available; take a new one.")
+	}
+	return SilentResult(fmt.Sprintf("Last screenshot: %s (taken %s ago, screen unchanged since).", path, age.Round(time.Second)))
+}
@@ -0,0 +1,82 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestScreenshotCache(now *time.Time) *ScreenshotCache {
+	c := NewScreenshotCache()
+	c.now = func() time.Time { return *now }
+	return c
+}
+
+func TestScreenshotCache_Freshness(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	c := newTestScreenshotCache(&now)
+
+	if _, _, ok := c.Latest(time.Minute); ok {
+		t.Fatal("Expected empty cache to report nothing")
+	}
+
+	c.Record("/tmp/shot.png")
+	now = now.Add(30 * time.Second)
+	path, age, ok := c.Latest(time.Minute)
+	if !ok || path != "/tmp/shot.png" || age != 30*time.Second {
+		t.Errorf("Expected fresh /tmp/shot.png aged 30s, got %q %s %v", path, age, ok)
+	}
+
+	now = now.Add(31 * time.Second)
+	if _, _, ok := c.Latest(time.Minute); ok {
+		t.Error("Expected screenshot older than the window to be stale")
+	}
+}
+
+func TestScreenshotCache_Invalidate(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	c := newTestScreenshotCache(&now)
+
+	c.Record("/tmp/a.png")
+	c.Invalidate()
+	if _, _, ok := c.Latest(time.Hour); ok {
+		t.Error("Expected invalidated cache to report nothing")
+	}
+
+	c.Record("/tmp/b.png")
+	if path, _, ok := c.Latest(time.Hour); !ok || path != "/tmp/b.png" {
+		t.Errorf("Expected a new capture after invalidation to be cached, got %q %v", path, ok)
+	}
+}
+
+func TestLastScreenshotTool_Execute(t *testing.T) {
+	dir := t.TempDir()
+	shot := filepath.Join(dir, "shot.png")
+	if err := os.WriteFile(shot, []byte("png"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cache := NewScreenshotCache()
+	tool := NewLastScreenshotTool(cache, time.Minute)
+
+	result := tool.Execute(context.Background(), nil)
+	if !strings.Contains(result.ForLLM, "No recent screenshot") {
+		t.Errorf("Expected no screenshot before a capture, got %q", result.ForLLM)
+	}
+
+	cache.Record(shot)
+	result = tool.Execute(context.Background(), nil)
+	if result.IsError || !strings.Contains(result.ForLLM, shot) {
+		t.Errorf("Expected the cached path, got %q", result.ForLLM)
+	}
+
+	// A deleted file is not offered and drops from the cache.
+	os.Remove(shot)
+	result = tool.Execute(context.Background(), nil)
+	if !strings.Contains(result.ForLLM, "No recent screenshot") {
+		t.Errorf("Expected missing file to be ignored, got %q", result.ForLLM)
+	}
+}
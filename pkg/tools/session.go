@@ -0,0 +1,174 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sipeed/picoclaw/pkg/session"
+)
+
+const errSessionToolsAdminOnly = "only the owner or an allowlisted user can export or import sessions"
+
+// SessionExportTool writes a session (history, summary, timestamps) to a JSON
+// file in the workspace for backup or migration. Any session can be
+// exported, so only senders passing isAdmin may call it.
+type SessionExportTool struct {
+	workspace string
+	restrict  bool
+	sessions  *session.SessionManager
+	isAdmin   AdminCheck
+}
+
+func NewSessionExportTool(workspace string, restrict bool, sessions *session.SessionManager, isAdmin AdminCheck) *SessionExportTool {
+	return &SessionExportTool{
+		workspace: workspace,
+		restrict:  restrict,
+		sessions:  sessions,
+		isAdmin:   isAdmin,
+	}
+}
+
+func (t *SessionExportTool) Name() string {
+	return "session_export"
+}
+
+func (t *SessionExportTool) Description() string {
+	return "Export a session's history and summary to a JSON file for backup or migration (admin)"
+}
+
+func (t *SessionExportTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"key": map[string]interface{}{
+				"type":        "string",
+				"description": "Session key to export, e.g. telegram:123456",
+			},
+			"path": map[string]interface{}{
+				"type":        "string",
+				"description": "Destination file (default: exports/sessions/<key>.json in the workspace)",
+			},
+		},
+		"required": []string{"key"},
+	}
+}
+
+func (t *SessionExportTool) Execute(ctx context.Context, args map[string]interface{}) *ToolResult {
+	if !t.isAdmin.allows(ctx) {
+		return ErrorResult(errSessionToolsAdminOnly)
+	}
+	key, _ := args["key"].(string)
+	if key == "" {
+		return ErrorResult("key is required")
+	}
+
+	path, _ := args["path"].(string)
+	if path == "" {
+		path = filepath.Join("exports", "sessions", strings.ReplaceAll(key, ":", "_")+".json")
+	}
+	resolvedPath, err := validatePath(path, t.workspace, t.restrict)
+	if err != nil {
+		return ErrorResult(err.Error())
+	}
+
+	data, err := t.sessions.Export(key)
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("failed to export session: %v", err)).WithError(err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(resolvedPath), 0755); err != nil {
+		return ErrorResult(fmt.Sprintf("failed to create directory: %v", err))
+	}
+	if err := os.WriteFile(resolvedPath, data, 0600); err != nil {
+		return ErrorResult(fmt.Sprintf("failed to write export: %v", err))
+	}
+
+	return SilentResult(fmt.Sprintf("Session %s exported to %s (%d bytes)", key, resolvedPath, len(data)))
+}
+
+// SessionImportTool restores a session from a file produced by
+// session_export. Like SessionExportTool it is limited to senders passing
+// isAdmin.
+type SessionImportTool struct {
+	workspace string
+	restrict  bool
+	sessions  *session.SessionManager
+	isAdmin   AdminCheck
+}
+
+func NewSessionImportTool(workspace string, restrict bool, sessions *session.SessionManager, isAdmin AdminCheck) *SessionImportTool {
+	return &SessionImportTool{
+		workspace: workspace,
+		restrict:  restrict,
+		sessions:  sessions,
+		isAdmin:   isAdmin,
+	}
+}
+
+func (t *SessionImportTool) Name() string {
+	return "session_import"
+}
+
+func (t *SessionImportTool) Description() string {
+	return "Restore a session from a JSON file created by session_export (admin)"
+}
+
+func (t *SessionImportTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"path": map[string]interface{}{
+				"type":        "string",
+				"description": "Path to the exported session JSON file",
+			},
+			"key": map[string]interface{}{
+				"type":        "string",
+				"description": "Optional session key to import as (defaults to the key in the file)",
+			},
+			"overwrite": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Replace the session if it already exists",
+			},
+		},
+		"required": []string{"path"},
+	}
+}
+
+func (t *SessionImportTool) Execute(ctx context.Context, args map[string]interface{}) *ToolResult {
+	if !t.isAdmin.allows(ctx) {
+		return ErrorResult(errSessionToolsAdminOnly)
+	}
+	path, _ := args["path"].(string)
+	if path == "" {
+		return ErrorResult("path is required")
+	}
+	key, _ := args["key"].(string)
+	overwrite, _ := args["overwrite"].(bool)
+
+	resolvedPath, err := validatePath(path, t.workspace, t.restrict)
+	if err != nil {
+		return ErrorResult(err.Error())
+	}
+
+	data, err := os.ReadFile(resolvedPath)
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("failed to read export: %v", err))
+	}
+
+	info, err := t.sessions.Import(data, key, overwrite)
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("failed to import session: %v", err)).WithError(err)
+	}
+	if err := t.sessions.Save(info.Key); err != nil {
+		return ErrorResult(fmt.Sprintf("session imported but failed to persist: %v", err)).WithError(err)
+	}
+
+	summary := "no summary"
+	if info.HasSummary {
+		summary = "with summary"
+	}
+	return SilentResult(fmt.Sprintf("Session %s imported (%d messages, %s)", info.Key, info.MessageCount, summary))
+}
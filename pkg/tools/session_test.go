@@ -0,0 +1,101 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/session"
+)
+
+func TestSessionExportImportTools_RoundTrip(t *testing.T) {
+	workspace := t.TempDir()
+	src := session.NewSessionManager(filepath.Join(workspace, "sessions"))
+	src.AddMessage("telegram:42", "user", "remember the milk")
+	src.AddMessage("telegram:42", "assistant", "noted")
+	src.SetSummary("telegram:42", "shopping list")
+
+	exportRes := NewSessionExportTool(workspace, true, src, nil).Execute(context.Background(), map[string]interface{}{
+		"key": "telegram:42",
+	})
+	if exportRes.IsError {
+		t.Fatalf("session_export failed: %s", exportRes.ForLLM)
+	}
+	exported := filepath.Join(workspace, "exports", "sessions", "telegram_42.json")
+	if _, err := os.Stat(exported); err != nil {
+		t.Fatalf("expected export file: %v", err)
+	}
+
+	// Simulate moving to another machine with a fresh session store.
+	dstStorage := filepath.Join(t.TempDir(), "sessions")
+	dst := session.NewSessionManager(dstStorage)
+	importRes := NewSessionImportTool(workspace, true, dst, nil).Execute(context.Background(), map[string]interface{}{
+		"path": "exports/sessions/telegram_42.json",
+	})
+	if importRes.IsError {
+		t.Fatalf("session_import failed: %s", importRes.ForLLM)
+	}
+	if !strings.Contains(importRes.ForLLM, "2 messages, with summary") {
+		t.Fatalf("unexpected import result: %s", importRes.ForLLM)
+	}
+
+	// The imported session must be persisted, not just held in memory.
+	reloaded := session.NewSessionManager(dstStorage)
+	if got := reloaded.GetHistory("telegram:42"); len(got) != 2 || got[0].Content != "remember the milk" {
+		t.Fatalf("reloaded history = %+v", got)
+	}
+	if got := reloaded.GetSummary("telegram:42"); got != "shopping list" {
+		t.Fatalf("reloaded summary = %q", got)
+	}
+}
+
+func TestSessionImportTool_RejectsInvalidFile(t *testing.T) {
+	workspace := t.TempDir()
+	if err := os.WriteFile(filepath.Join(workspace, "bad.json"), []byte(`{"version": 1, "session": {}}`), 0644); err != nil {
+		t.Fatalf("write bad export: %v", err)
+	}
+
+	res := NewSessionImportTool(workspace, true, session.NewSessionManager(""), nil).Execute(context.Background(), map[string]interface{}{
+		"path": "bad.json",
+	})
+	if !res.IsError || !strings.Contains(res.ForLLM, "missing a key") {
+		t.Fatalf("expected validation error, got: %+v", res)
+	}
+}
+
+func TestSessionExportTool_UnknownSession(t *testing.T) {
+	res := NewSessionExportTool(t.TempDir(), true, session.NewSessionManager(""), nil).Execute(context.Background(), map[string]interface{}{
+		"key": "nope",
+	})
+	if !res.IsError {
+		t.Fatal("expected error exporting unknown session")
+	}
+}
+
+func TestSessionTools_RefuseNonAdminSender(t *testing.T) {
+	workspace := t.TempDir()
+	sessions := session.NewSessionManager("")
+	sessions.AddMessage("telegram:42", "user", "private")
+	isAdmin := AdminCheck(func(channel, senderID string) bool { return senderID == "owner" })
+	ctx := WithSenderID(WithToolContext(context.Background(), "telegram", "7"), "someone")
+
+	res := NewSessionExportTool(workspace, true, sessions, isAdmin).Execute(ctx, map[string]interface{}{"key": "telegram:42"})
+	if !res.IsError || !strings.Contains(res.ForLLM, "only the owner") {
+		t.Fatalf("non-admin export = %+v, want refusal", res)
+	}
+	if _, err := os.Stat(filepath.Join(workspace, "exports")); !os.IsNotExist(err) {
+		t.Fatalf("refused export still wrote files: %v", err)
+	}
+
+	res = NewSessionImportTool(workspace, true, sessions, isAdmin).Execute(ctx, map[string]interface{}{"path": "any.json", "key": "telegram:42", "overwrite": true})
+	if !res.IsError || !strings.Contains(res.ForLLM, "only the owner") {
+		t.Fatalf("non-admin import = %+v, want refusal", res)
+	}
+
+	ctx = WithSenderID(ctx, "owner")
+	if res := NewSessionExportTool(workspace, true, sessions, isAdmin).Execute(ctx, map[string]interface{}{"key": "telegram:42"}); res.IsError {
+		t.Fatalf("admin export failed: %s", res.ForLLM)
+	}
+}
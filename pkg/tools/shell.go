@@ -4,21 +4,62 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"regexp"
 	"runtime"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 )
 
+// execWaitDelay bounds how long a cancelled or finished command may keep
+// its output pipes open through leftover child processes.
+const execWaitDelay = 2 * time.Second
+
+// execStreamLimit caps the live output one command streams to a progress
+// sink; the result for the LLM is truncated separately.
+const execStreamLimit = 4000
+
+// execCwdFileEnv names the file the shell wrapper writes its final working
+// directory to, so a `cd` carries over to the session's next command.
+const execCwdFileEnv = "PICOCLAW_EXEC_CWD_FILE"
+
 type ExecTool struct {
 	workingDir          string
 	timeout             time.Duration
 	denyPatterns        []*regexp.Regexp
 	allowPatterns       []*regexp.Regexp
 	restrictToWorkspace bool
+
+	// allowedCommands and blockedCommands match the base name of every
+	// command a command line runs (shellCommandNames).
+	allowedCommands map[string]bool
+	blockedCommands map[string]bool
+
+	// defaultEnv is applied to every command; the env argument overrides
+	// it. secretEnv names variables whose values are kept out of logs.
+	defaultEnv map[string]string
+	secretEnv  map[string]bool
+
+	// sessionDirs holds each session's current directory, keyed by
+	// channel:chatID, as left by its last command. Sessions back in the
+	// default directory have no entry, and at most maxSessionDirs are kept.
+	dirMu       sync.Mutex
+	sessionDirs map[string]sessionDir
+	dirSeq      uint64 // orders sessionDirs by last use
+}
+
+// maxSessionDirs caps how many session directories ExecTool remembers; the
+// least recently used session falls back to the default directory.
+const maxSessionDirs = 256
+
+type sessionDir struct {
+	dir  string
+	used uint64
 }
 
 func NewExecTool(workingDir string, restrict bool) *ExecTool {
@@ -39,6 +80,7 @@ func NewExecTool(workingDir string, restrict bool) *ExecTool {
 		denyPatterns:        denyPatterns,
 		allowPatterns:       nil,
 		restrictToWorkspace: restrict,
+		sessionDirs:         make(map[string]sessionDir),
 	}
 }
 
@@ -47,7 +89,7 @@ func (t *ExecTool) Name() string {
 }
 
 func (t *ExecTool) Description() string {
-	return "Execute a shell command and return its output. Use with caution."
+	return "Execute a shell command and return its output. Use with caution. The working directory persists between calls in the same conversation, so `cd` carries over; the result ends with the directory the command finished in."
 }
 
 func (t *ExecTool) Parameters() map[string]interface{} {
@@ -58,9 +100,20 @@ func (t *ExecTool) Parameters() map[string]interface{} {
 				"type":        "string",
 				"description": "The shell command to execute",
 			},
+			"cwd": map[string]interface{}{
+				"type":        "string",
+				"description": "Optional working directory for the command, absolute or relative to the current directory",
+			},
 			"working_dir": map[string]interface{}{
 				"type":        "string",
-				"description": "Optional working directory for the command",
+				"description": "Deprecated alias for cwd",
+			},
+			"env": map[string]interface{}{
+				"type":        "object",
+				"description": "Optional environment variables for the command, e.g. {\"PATH\": \"$HOME/bin:$PATH\"}. $VAR references expand against the existing environment.",
+				"additionalProperties": map[string]interface{}{
+					"type": "string",
+				},
 			},
 		},
 		"required": []string{"command"},
@@ -73,47 +126,81 @@ func (t *ExecTool) Execute(ctx context.Context, args map[string]interface{}) *To
 		return ErrorResult("command is required")
 	}
 
-	cwd := t.workingDir
-	if wd, ok := args["working_dir"].(string); ok && wd != "" {
-		cwd = wd
-	}
+	channel, chatID := ToolContext(ctx, "", "")
+	session := channel + ":" + chatID
 
-	if cwd == "" {
-		wd, err := os.Getwd()
-		if err == nil {
-			cwd = wd
-		}
+	requested, _ := args["cwd"].(string)
+	if requested == "" {
+		requested, _ = args["working_dir"].(string)
+	}
+	cwd, err := t.resolveCwd(session, requested)
+	if err != nil {
+		return ErrorResult(err.Error())
 	}
 
-	if guardError := t.guardCommand(command, cwd); guardError != "" {
+	if guardError := t.guardCommand(command, t.guardRoot(cwd)); guardError != "" {
 		return ErrorResult(guardError)
 	}
 
+	env, err := t.commandEnv(args["env"])
+	if err != nil {
+		return ErrorResult(err.Error())
+	}
+
 	cmdCtx, cancel := context.WithTimeout(ctx, t.timeout)
 	defer cancel()
 
 	var cmd *exec.Cmd
+	var cwdFile string
 	if runtime.GOOS == "windows" {
 		cmd = exec.CommandContext(cmdCtx, "powershell", "-NoProfile", "-NonInteractive", "-Command", command)
 	} else {
-		cmd = exec.CommandContext(cmdCtx, "sh", "-c", command)
+		// Record where the command finished, keeping its exit status. A
+		// command that exits early leaves the session directory unchanged.
+		script := command
+		if f, err := os.CreateTemp("", "picoclaw-cwd-*"); err == nil {
+			cwdFile = f.Name()
+			f.Close()
+			defer os.Remove(cwdFile)
+			script = command + "\n__picoclaw_status=$?\npwd > \"$" + execCwdFileEnv + "\"\nexit $__picoclaw_status"
+		}
+		cmd = exec.CommandContext(cmdCtx, "sh", "-c", script)
+		if cwdFile != "" {
+			env = append(env, execCwdFileEnv+"="+cwdFile)
+		}
 	}
+	cmd.Env = env
 	if cwd != "" {
 		cmd.Dir = cwd
 	}
+	killProcessGroupOnCancel(cmd)
+	// Children that escaped the kill may still hold the output pipes open;
+	// stop waiting for them once the command itself is gone.
+	cmd.WaitDelay = execWaitDelay
 
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
+	var live []*progressWriter
+	if sink := Progress(ctx); sink != nil {
+		stream := newProgressStream(sink, execStreamLimit)
+		live = []*progressWriter{stream.writer(), stream.writer()}
+		cmd.Stdout = io.MultiWriter(&stdout, live[0])
+		cmd.Stderr = io.MultiWriter(&stderr, live[1])
+	}
 
-	err := cmd.Run()
+	err = cmd.Run()
+	for _, w := range live {
+		w.flush()
+	}
+	cwd, cwdNote := t.updateSessionDir(session, cwd, cwdFile)
 	output := stdout.String()
 	if stderr.Len() > 0 {
 		output += "\nSTDERR:\n" + stderr.String()
 	}
 
 	if err != nil {
-		if cmdCtx.Err() == context.DeadlineExceeded {
+		if cmdCtx.Err() == context.DeadlineExceeded && ctx.Err() == nil {
 			msg := fmt.Sprintf("Command timed out after %v", t.timeout)
 			return &ToolResult{
 				ForLLM:  msg,
@@ -121,6 +208,14 @@ func (t *ExecTool) Execute(ctx context.Context, args map[string]interface{}) *To
 				IsError: true,
 			}
 		}
+		if ctx.Err() != nil {
+			msg := "Command cancelled"
+			return &ToolResult{
+				ForLLM:  msg,
+				ForUser: msg,
+				IsError: true,
+			}
+		}
 		output += fmt.Sprintf("\nExit code: %v", err)
 	}
 
@@ -132,22 +227,235 @@ func (t *ExecTool) Execute(ctx context.Context, args map[string]interface{}) *To
 	if len(output) > maxLen {
 		output = output[:maxLen] + fmt.Sprintf("\n... (truncated, %d more chars)", len(output)-maxLen)
 	}
+	forLLM := output + cwdNote
+	if cwd != "" {
+		forLLM += "\n[cwd: " + cwd + "]"
+	}
 
 	if err != nil {
 		return &ToolResult{
-			ForLLM:  output,
+			ForLLM:  forLLM,
 			ForUser: output,
 			IsError: true,
 		}
 	}
 
 	return &ToolResult{
-		ForLLM:  output,
+		ForLLM:  forLLM,
 		ForUser: output,
 		IsError: false,
 	}
 }
 
+// commandEnv returns the process environment with the default environment
+// and then callEnv (the env argument) applied, so call-level values win.
+// $VAR references in values expand against the environment built so far.
+func (t *ExecTool) commandEnv(callEnv interface{}) ([]string, error) {
+	overrides, ok := callEnv.(map[string]interface{})
+	if callEnv != nil && !ok {
+		return nil, fmt.Errorf("env must be an object of variable names to values")
+	}
+
+	env := make(map[string]string)
+	for _, kv := range os.Environ() {
+		if k, v, ok := strings.Cut(kv, "="); ok {
+			env[k] = v
+		}
+	}
+	for _, k := range sortedKeys(t.defaultEnv) {
+		env[k] = os.Expand(t.defaultEnv[k], func(name string) string { return env[name] })
+	}
+	names := make([]string, 0, len(overrides))
+	for k := range overrides {
+		if k == "" || strings.ContainsAny(k, "=\x00") {
+			return nil, fmt.Errorf("invalid environment variable name %q", k)
+		}
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	for _, k := range names {
+		var v string
+		switch val := overrides[k].(type) {
+		case string:
+			v = val
+		case nil:
+			v = ""
+		default:
+			v = fmt.Sprint(val)
+		}
+		env[k] = os.Expand(v, func(name string) string { return env[name] })
+	}
+
+	out := make([]string, 0, len(env))
+	for _, k := range sortedKeys(env) {
+		out = append(out, k+"="+env[k])
+	}
+	return out, nil
+}
+
+// RedactArgs hides the values of secret variables in the env argument, for
+// logging.
+func (t *ExecTool) RedactArgs(args map[string]interface{}) map[string]interface{} {
+	env, ok := args["env"].(map[string]interface{})
+	if !ok || len(t.secretEnv) == 0 {
+		return args
+	}
+	redactedEnv := make(map[string]interface{}, len(env))
+	changed := false
+	for k, v := range env {
+		if t.secretEnv[k] {
+			v = "[redacted]"
+			changed = true
+		}
+		redactedEnv[k] = v
+	}
+	if !changed {
+		return args
+	}
+	redacted := make(map[string]interface{}, len(args))
+	for k, v := range args {
+		redacted[k] = v
+	}
+	redacted["env"] = redactedEnv
+	return redacted
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// resolveCwd returns the directory to run the session's next command in:
+// requested (relative to the session's current directory) if given, else
+// the current directory. With restrictToWorkspace it must stay inside the
+// workspace.
+func (t *ExecTool) resolveCwd(session, requested string) (string, error) {
+	t.dirMu.Lock()
+	cwd := t.sessionDirs[session].dir
+	t.dirMu.Unlock()
+	if cwd == "" {
+		cwd = t.workingDir
+	}
+	if cwd == "" {
+		if wd, err := os.Getwd(); err == nil {
+			cwd = wd
+		}
+	}
+
+	if requested == "" {
+		return cwd, nil
+	}
+	if !filepath.IsAbs(requested) {
+		requested = filepath.Join(cwd, requested)
+	}
+	requested = filepath.Clean(requested)
+	if !t.insideWorkspace(requested) {
+		return "", fmt.Errorf("working directory %s is outside the workspace", requested)
+	}
+	info, err := os.Stat(requested)
+	if err != nil {
+		return "", fmt.Errorf("working directory %s: %w", requested, err)
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("working directory %s is not a directory", requested)
+	}
+	return requested, nil
+}
+
+// updateSessionDir stores the directory the command finished in, read from
+// cwdFile, as the session's current directory and returns it. Leaving the
+// workspace under restrictToWorkspace resets the session to the workspace,
+// with a note for the LLM.
+func (t *ExecTool) updateSessionDir(session, cwd, cwdFile string) (string, string) {
+	if cwdFile != "" {
+		if data, err := os.ReadFile(cwdFile); err == nil {
+			if dir := strings.TrimSpace(string(data)); dir != "" {
+				cwd = dir
+			}
+		}
+	}
+
+	note := ""
+	if !t.insideWorkspace(cwd) {
+		note = fmt.Sprintf("\nNote: %s is outside the workspace; the working directory was reset.", cwd)
+		cwd = t.workingDir
+	}
+
+	t.dirMu.Lock()
+	defer t.dirMu.Unlock()
+	if cwd == t.workingDir {
+		delete(t.sessionDirs, session)
+		return cwd, note
+	}
+	if _, ok := t.sessionDirs[session]; !ok && len(t.sessionDirs) >= maxSessionDirs {
+		t.evictSessionDirLocked()
+	}
+	t.dirSeq++
+	t.sessionDirs[session] = sessionDir{dir: cwd, used: t.dirSeq}
+	return cwd, note
+}
+
+// evictSessionDirLocked forgets the least recently used session directory.
+// Caller must hold t.dirMu.
+func (t *ExecTool) evictSessionDirLocked() {
+	oldest := ""
+	var oldestUsed uint64
+	for session, sd := range t.sessionDirs {
+		if oldest == "" || sd.used < oldestUsed {
+			oldest, oldestUsed = session, sd.used
+		}
+	}
+	delete(t.sessionDirs, oldest)
+}
+
+// ResetSessionDir forgets the current directory of a conversation, e.g.
+// when its session is cleared, so its next command runs in the default
+// directory.
+func (t *ExecTool) ResetSessionDir(channel, chatID string) {
+	t.dirMu.Lock()
+	defer t.dirMu.Unlock()
+	delete(t.sessionDirs, channel+":"+chatID)
+}
+
+// insideWorkspace reports whether dir is allowed as a working directory.
+func (t *ExecTool) insideWorkspace(dir string) bool {
+	if !t.restrictToWorkspace || t.workingDir == "" {
+		return true
+	}
+	root, err := filepath.Abs(t.workingDir)
+	if err != nil {
+		return false
+	}
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return false
+	}
+	// Compare resolved paths so symlinks (e.g. /tmp on macOS) don't count
+	// as leaving the workspace.
+	if r, err := filepath.EvalSymlinks(root); err == nil {
+		root = r
+	}
+	if a, err := filepath.EvalSymlinks(abs); err == nil {
+		abs = a
+	}
+	rel, err := filepath.Rel(root, abs)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// guardRoot is the directory absolute paths in a command must stay under:
+// the workspace, so a command run from a subdirectory may still reach the
+// rest of it.
+func (t *ExecTool) guardRoot(cwd string) string {
+	if t.workingDir != "" {
+		return t.workingDir
+	}
+	return cwd
+}
+
 func (t *ExecTool) guardCommand(command, cwd string) string {
 	cmd := strings.TrimSpace(command)
 	lower := strings.ToLower(cmd)
@@ -158,6 +466,17 @@ func (t *ExecTool) guardCommand(command, cwd string) string {
 		}
 	}
 
+	if len(t.allowedCommands) > 0 || len(t.blockedCommands) > 0 {
+		for _, name := range shellCommandNames(cmd) {
+			if t.blockedCommands[name] {
+				return fmt.Sprintf("Command blocked by safety guard (%s is a blocked command)", name)
+			}
+			if len(t.allowedCommands) > 0 && !t.allowedCommands[name] {
+				return fmt.Sprintf("Command blocked by safety guard (%s is not an allowed command)", name)
+			}
+		}
+	}
+
 	if len(t.allowPatterns) > 0 {
 		allowed := false
 		for _, pattern := range t.allowPatterns {
@@ -212,6 +531,30 @@ func (t *ExecTool) SetRestrictToWorkspace(restrict bool) {
 	t.restrictToWorkspace = restrict
 }
 
+// SetEnv sets the environment applied to every command and the variable
+// names whose values are redacted from logged arguments.
+func (t *ExecTool) SetEnv(defaults map[string]string, secrets []string) {
+	t.defaultEnv = defaults
+	t.secretEnv = make(map[string]bool, len(secrets))
+	for _, name := range secrets {
+		t.secretEnv[name] = true
+	}
+}
+
+// SetCommandLists restricts the commands a command line may run, by base
+// name: blocked commands are refused, and a non-empty allowed list refuses
+// everything else.
+func (t *ExecTool) SetCommandLists(allowed, blocked []string) {
+	t.allowedCommands = make(map[string]bool, len(allowed))
+	for _, name := range allowed {
+		t.allowedCommands[name] = true
+	}
+	t.blockedCommands = make(map[string]bool, len(blocked))
+	for _, name := range blocked {
+		t.blockedCommands[name] = true
+	}
+}
+
 func (t *ExecTool) SetAllowPatterns(patterns []string) error {
 	t.allowPatterns = make([]*regexp.Regexp, 0, len(patterns))
 	for _, p := range patterns {
@@ -223,3 +566,82 @@ func (t *ExecTool) SetAllowPatterns(patterns []string) error {
 	}
 	return nil
 }
+
+// progressStream streams command output to a progress sink line by line,
+// stdout and stderr interleaved as lines complete, until limit bytes have
+// been sent.
+type progressStream struct {
+	mu     sync.Mutex
+	sink   func(output string)
+	left   int
+	capped bool
+}
+
+func newProgressStream(sink func(output string), limit int) *progressStream {
+	return &progressStream{sink: sink, left: limit}
+}
+
+// writer returns an io.Writer for one output stream; each keeps its own
+// partial line so stdout and stderr lines are not spliced together.
+func (ps *progressStream) writer() *progressWriter {
+	return &progressWriter{stream: ps}
+}
+
+func (ps *progressStream) send(line string) {
+	if ps.capped {
+		return
+	}
+	if len(line)+1 > ps.left {
+		ps.stop()
+		return
+	}
+	ps.left -= len(line) + 1
+	ps.sink(strings.TrimRight(line, "\r"))
+}
+
+func (ps *progressStream) stop() {
+	ps.capped = true
+	ps.sink("... (live output truncated)")
+}
+
+type progressWriter struct {
+	stream  *progressStream
+	partial []byte
+}
+
+func (w *progressWriter) Write(p []byte) (int, error) {
+	ps := w.stream
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	if ps.capped {
+		return len(p), nil
+	}
+	w.partial = append(w.partial, p...)
+	for !ps.capped {
+		i := bytes.IndexByte(w.partial, '\n')
+		if i < 0 {
+			break
+		}
+		line := string(w.partial[:i])
+		w.partial = w.partial[i+1:]
+		ps.send(line)
+	}
+	if !ps.capped && len(w.partial) > ps.left {
+		ps.stop()
+	}
+	if ps.capped {
+		w.partial = nil
+	}
+	return len(p), nil
+}
+
+// flush sends a trailing line without a newline.
+func (w *progressWriter) flush() {
+	ps := w.stream
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	if len(w.partial) > 0 {
+		ps.send(string(w.partial))
+		w.partial = nil
+	}
+}
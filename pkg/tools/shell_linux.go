@@ -0,0 +1,17 @@
+package tools
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// killProcessGroupOnCancel runs cmd in its own process group and makes
+// context cancellation (timeout or /stop) kill the whole group, so the
+// processes the shell started, and theirs, do not outlive the tool call.
+func killProcessGroupOnCancel(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		// The group ID is the shell's PID; a negative PID signals the group.
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+}
@@ -0,0 +1,74 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// processGone reports whether pid has exited (a zombie waiting to be
+// reaped counts as gone).
+func processGone(pid int) bool {
+	if err := syscall.Kill(pid, 0); err != nil {
+		return true
+	}
+	stat, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "stat"))
+	if err != nil {
+		return true
+	}
+	// The state follows the parenthesized command name.
+	fields := strings.Fields(string(stat[strings.LastIndexByte(string(stat), ')')+1:]))
+	return len(fields) > 0 && fields[0] == "Z"
+}
+
+// TestShellTool_CancelKillsChildProcesses verifies cancelling the context
+// (as /stop does) kills the processes the shell started, not only the shell.
+func TestShellTool_CancelKillsChildProcesses(t *testing.T) {
+	dir := t.TempDir()
+	pidFile := filepath.Join(dir, "sleep.pid")
+	tool := NewExecTool(dir, false)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan *ToolResult, 1)
+	go func() {
+		done <- tool.Execute(ctx, map[string]interface{}{
+			"command": "sleep 30 & echo $! > " + pidFile + "; wait",
+		})
+	}()
+
+	var pid int
+	deadline := time.Now().Add(5 * time.Second)
+	for pid == 0 {
+		if time.Now().After(deadline) {
+			cancel()
+			t.Fatal("command did not start")
+		}
+		if data, err := os.ReadFile(pidFile); err == nil {
+			pid, _ = strconv.Atoi(strings.TrimSpace(string(data)))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+
+	select {
+	case result := <-done:
+		if !result.IsError || !strings.Contains(result.ForLLM, "cancelled") {
+			t.Errorf("result = %+v, want a cancellation error", result)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Execute did not return after cancellation")
+	}
+
+	for !processGone(pid) {
+		if time.Now().After(deadline.Add(5 * time.Second)) {
+			syscall.Kill(pid, syscall.SIGKILL)
+			t.Fatalf("child process %d survived cancellation", pid)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
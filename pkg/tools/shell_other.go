@@ -0,0 +1,10 @@
+//go:build !linux
+
+package tools
+
+import "os/exec"
+
+// killProcessGroupOnCancel is a no-op on non-Linux platforms: cancellation
+// kills the shell only, and execWaitDelay keeps leftover children from
+// blocking the tool call.
+func killProcessGroupOnCancel(cmd *exec.Cmd) {}
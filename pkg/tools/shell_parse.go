@@ -0,0 +1,288 @@
+package tools
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// shellWrappers are commands that run the command after their options.
+// The values list options that take an argument, so it is not mistaken
+// for the wrapped command.
+var shellWrappers = map[string]map[string]bool{
+	"sudo":    {"-u": true, "-g": true, "-C": true, "-p": true, "-U": true, "-h": true},
+	"doas":    {"-u": true, "-C": true},
+	"env":     {"-u": true, "-C": true, "-S": true},
+	"nohup":   {},
+	"time":    {"-f": true, "-o": true},
+	"nice":    {"-n": true},
+	"ionice":  {"-c": true, "-n": true, "-p": true},
+	"timeout": {"-s": true, "-k": true},
+	"stdbuf":  {},
+	"exec":    {"-a": true},
+	"command": {},
+	"builtin": {},
+	"xargs":   {"-I": true, "-n": true, "-P": true, "-d": true, "-L": true, "-s": true, "-E": true},
+	"watch":   {"-n": true},
+}
+
+// shellInterpreters run their -c argument as a script, which is parsed in
+// turn.
+var shellInterpreters = map[string]bool{
+	"sh": true, "bash": true, "dash": true, "zsh": true, "ash": true, "ksh": true,
+}
+
+// shellKeywords are skipped where a command is expected.
+var shellKeywords = map[string]bool{
+	"if": true, "then": true, "else": true, "elif": true, "fi": true,
+	"while": true, "until": true, "do": true, "done": true, "esac": true,
+	"{": true, "}": true, "!": true,
+}
+
+var shellAssignment = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*=`)
+
+// shellFrame is the parser state for one nesting level: the top level, or
+// a $(...) or `...` command substitution.
+type shellFrame struct {
+	words  []string
+	word   strings.Builder
+	inWord bool
+	dquote bool
+	// closer ends the frame: ')' for $(...), '`' for backticks, 0 at the
+	// top level.
+	closer rune
+	// skipNext drops the next word, a redirection target; heredoc marks
+	// it as a heredoc delimiter.
+	skipNext bool
+	heredoc  bool
+}
+
+// shellCommandNames returns the base name of every command a shell command
+// line runs: each part of a pipeline or list, commands inside subshells and
+// substitutions (listed before the command using them), commands wrapped
+// by sudo, env, xargs and the like (the wrapper is included too), and sh -c
+// scripts. Quotes, redirections, heredoc bodies and comments are understood
+// well enough that their contents are not mistaken for commands.
+func shellCommandNames(command string) []string {
+	var names []string
+	var heredocs []string
+	frames := []*shellFrame{{}}
+	top := func() *shellFrame { return frames[len(frames)-1] }
+
+	endWord := func(f *shellFrame) {
+		if !f.inWord {
+			return
+		}
+		w := f.word.String()
+		f.word.Reset()
+		f.inWord = false
+		switch {
+		case f.skipNext:
+			if f.heredoc {
+				heredocs = append(heredocs, w)
+			}
+			f.skipNext, f.heredoc = false, false
+		default:
+			f.words = append(f.words, w)
+		}
+	}
+	endSegment := func(f *shellFrame) {
+		endWord(f)
+		names = append(names, segmentCommandNames(f.words)...)
+		f.words = nil
+		f.skipNext, f.heredoc = false, false
+	}
+	push := func(closer rune) {
+		f := top()
+		f.inWord = true // The substitution is part of the enclosing word
+		frames = append(frames, &shellFrame{closer: closer})
+	}
+	pop := func() {
+		endSegment(top())
+		if len(frames) > 1 {
+			frames = frames[:len(frames)-1]
+		}
+	}
+
+	runes := []rune(command)
+	at := func(i int) rune {
+		if i < len(runes) {
+			return runes[i]
+		}
+		return 0
+	}
+
+	for i := 0; i < len(runes); i++ {
+		f := top()
+		c := runes[i]
+
+		if f.dquote {
+			switch {
+			case c == '\\' && i+1 < len(runes):
+				i++
+				f.word.WriteRune(runes[i])
+			case c == '"':
+				f.dquote = false
+			case c == '$' && at(i+1) == '(' && at(i+2) != '(':
+				i++
+				push(')')
+			case c == '`':
+				push('`')
+			default:
+				f.word.WriteRune(c)
+			}
+			continue
+		}
+
+		switch {
+		case c == '\\':
+			if i+1 < len(runes) && runes[i+1] != '\n' {
+				f.word.WriteRune(runes[i+1])
+				f.inWord = true
+			}
+			i++
+		case c == '\'':
+			end := strings.IndexRune(string(runes[i+1:]), '\'')
+			if end < 0 {
+				end = len(string(runes[i+1:]))
+			}
+			lit := []rune(string(runes[i+1:])[:end])
+			f.word.WriteString(string(lit))
+			f.inWord = true
+			i += len(lit) + 1
+		case c == '"':
+			f.dquote = true
+			f.inWord = true
+		case c == '$' && at(i+1) == '(' && at(i+2) == '(':
+			// Arithmetic expansion runs no commands
+			end := strings.Index(string(runes[i:]), "))")
+			if end < 0 {
+				end = len(string(runes[i:])) - 2
+			}
+			lit := []rune(string(runes[i:])[:end+2])
+			f.word.WriteString(string(lit))
+			f.inWord = true
+			i += len(lit) - 1
+		case c == '$' && at(i+1) == '(':
+			i++
+			push(')')
+		case c == '`':
+			if f.closer == '`' {
+				pop()
+			} else {
+				push('`')
+			}
+		case c == ')':
+			if f.closer == ')' {
+				pop()
+			} else {
+				endSegment(f)
+			}
+		case c == '(':
+			endSegment(f)
+		case c == '>' || c == '<' || (c == '&' && at(i+1) == '>'):
+			// A number right before the operator is the redirected fd
+			if f.inWord && strings.Trim(f.word.String(), "0123456789") == "" {
+				f.word.Reset()
+				f.inWord = false
+			}
+			endWord(f)
+			op := string(c)
+			for strings.ContainsRune("<>&|", at(i+1)) {
+				i++
+				op += string(runes[i])
+			}
+			if strings.HasSuffix(op, "&") && (at(i+1) == '-' || (at(i+1) >= '0' && at(i+1) <= '9')) {
+				// Duplicating an fd (2>&1, >&-) names no file
+				for at(i+1) == '-' || (at(i+1) >= '0' && at(i+1) <= '9') {
+					i++
+				}
+				continue
+			}
+			f.skipNext = true
+			f.heredoc = strings.HasPrefix(op, "<<") && op != "<<<"
+		case c == '|' || c == '&' || c == ';':
+			endSegment(f)
+		case c == '\n':
+			endSegment(f)
+			// Skip heredoc bodies up to their delimiter lines
+			for _, delim := range heredocs {
+				for i+1 < len(runes) {
+					rest := string(runes[i+1:])
+					line, _, _ := strings.Cut(rest, "\n")
+					i += len([]rune(line)) + 1
+					if strings.TrimLeft(line, "\t") == delim {
+						break
+					}
+				}
+			}
+			heredocs = nil
+		case c == '#' && !f.inWord:
+			for i+1 < len(runes) && runes[i+1] != '\n' {
+				i++
+			}
+		case c == ' ' || c == '\t' || c == '\r':
+			endWord(f)
+		default:
+			f.word.WriteRune(c)
+			f.inWord = true
+		}
+	}
+
+	for len(frames) > 1 {
+		pop()
+	}
+	endSegment(top())
+	return names
+}
+
+// segmentCommandNames returns the commands one simple command runs: its
+// command word, plus the commands behind wrappers and sh -c.
+func segmentCommandNames(words []string) []string {
+	var names []string
+	for i := 0; i < len(words); i++ {
+		w := words[i]
+		if shellKeywords[w] || shellAssignment.MatchString(w) {
+			continue
+		}
+		switch w {
+		case "for", "select", "case", "function":
+			// The rest of the segment is a word list or pattern, not a command
+			return names
+		}
+		if w == "" {
+			return names
+		}
+
+		name := filepath.Base(w)
+		names = append(names, name)
+
+		if shellInterpreters[name] {
+			for j := i + 1; j < len(words)-1; j++ {
+				if words[j] == "-c" {
+					names = append(names, shellCommandNames(words[j+1])...)
+					break
+				}
+			}
+			return names
+		}
+		if name == "eval" {
+			return append(names, shellCommandNames(strings.Join(words[i+1:], " "))...)
+		}
+
+		valueFlags, ok := shellWrappers[name]
+		if !ok {
+			return names
+		}
+		for i+1 < len(words) && strings.HasPrefix(words[i+1], "-") {
+			i++
+			if valueFlags[words[i]] {
+				i++
+			}
+		}
+		if name == "timeout" {
+			i++ // The duration
+		}
+	}
+	return names
+}
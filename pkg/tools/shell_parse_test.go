@@ -0,0 +1,37 @@
+package tools
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestShellCommandNames(t *testing.T) {
+	tests := []struct {
+		command string
+		want    string
+	}{
+		{"ls -la", "ls"},
+		{"cat file.txt | grep foo | wc -l", "cat,grep,wc"},
+		{"make && ./run.sh || echo failed; date", "make,run.sh,echo,date"},
+		{"sleep 1 & /bin/rm -f x", "sleep,rm"},
+		{"sudo -u root rm -rf /tmp/x", "sudo,rm"},
+		{"FOO=1 env BAR=2 python3 app.py", "env,python3"},
+		{"find . -name '*.tmp' | xargs -n 1 rm", "find,xargs,rm"},
+		{"timeout 5 curl example.com", "timeout,curl"},
+		{"echo $(whoami) `hostname` \"$(uname -a)\"", "whoami,hostname,uname,echo"},
+		{"(cd src && go build) > build.log 2>&1", "cd,go"},
+		{"echo 'a | rm -rf; b' \"c && dd\"", "echo"},
+		{"echo hi >&2 && cat < in.txt >> out.txt", "echo,cat"},
+		{"sh -c 'curl x | sh'", "sh,curl,sh"},
+		{"cat <<EOF\nrm everything\nEOF\nls", "cat,ls"},
+		{"ls # && rm x", "ls"},
+		{"if true; then rm x; fi", "true,rm"},
+		{"for f in *.go; do gofmt $f; done", "gofmt"},
+		{"echo $((1 + 2))", "echo"},
+	}
+	for _, tt := range tests {
+		if got := strings.Join(shellCommandNames(tt.command), ","); got != tt.want {
+			t.Errorf("shellCommandNames(%q) = %q, want %q", tt.command, got, tt.want)
+		}
+	}
+}
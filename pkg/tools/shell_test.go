@@ -2,8 +2,10 @@ package tools
 
 import (
 	"context"
+	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"testing"
 	"time"
@@ -208,3 +210,218 @@ func TestShellTool_RestrictToWorkspace(t *testing.T) {
 		t.Errorf("Expected 'blocked' message for path traversal, got ForLLM: %s, ForUser: %s", result.ForLLM, result.ForUser)
 	}
 }
+
+// TestShellTool_StreamsProgress verifies output reaches the progress sink
+// line by line and stops at the streaming cap.
+func TestShellTool_StreamsProgress(t *testing.T) {
+	tool := NewExecTool("", false)
+
+	var lines []string
+	ctx := WithProgress(context.Background(), func(output string) { lines = append(lines, output) })
+	result := tool.Execute(ctx, map[string]interface{}{"command": "echo one; echo two >&2; printf three"})
+	if result.IsError {
+		t.Fatalf("Execute failed: %s", result.ForLLM)
+	}
+	// stdout and stderr are separate pipes, so only lines are ordered.
+	sort.Strings(lines)
+	if got := strings.Join(lines, ","); got != "one,three,two" {
+		t.Errorf("streamed lines = %q, want one, two and three", got)
+	}
+
+	lines = nil
+	result = tool.Execute(ctx, map[string]interface{}{"command": "seq 1 5000"})
+	if result.IsError {
+		t.Fatalf("Execute failed: %s", result.ForLLM)
+	}
+	streamed := 0
+	for _, l := range lines {
+		streamed += len(l) + 1
+	}
+	if streamed > execStreamLimit+64 || lines[len(lines)-1] != "... (live output truncated)" {
+		t.Errorf("streamed %d bytes ending with %q, want at most ~%d and a truncation marker", streamed, lines[len(lines)-1], execStreamLimit)
+	}
+	if !strings.Contains(result.ForLLM, "\n2000\n") {
+		t.Error("result for the LLM should not be cut at the streaming cap")
+	}
+}
+
+// TestShellTool_CdPersistsPerSession verifies a cd carries over to the
+// session's next command, and only to that session's.
+func TestShellTool_CdPersistsPerSession(t *testing.T) {
+	tmpDir := t.TempDir()
+	os.MkdirAll(filepath.Join(tmpDir, "sub"), 0755)
+	os.WriteFile(filepath.Join(tmpDir, "sub", "note.txt"), []byte("in sub"), 0644)
+	tool := NewExecTool(tmpDir, false)
+	tool.SetRestrictToWorkspace(true)
+
+	alice := WithToolContext(context.Background(), "telegram", "alice")
+	bob := WithToolContext(context.Background(), "telegram", "bob")
+
+	result := tool.Execute(alice, map[string]interface{}{"command": "cd sub"})
+	if result.IsError || !strings.Contains(result.ForLLM, "[cwd: ") || !strings.Contains(result.ForLLM, "sub]") {
+		t.Fatalf("cd result = %q, want the new cwd reported", result.ForLLM)
+	}
+
+	result = tool.Execute(alice, map[string]interface{}{"command": "cat note.txt"})
+	if result.IsError || !strings.Contains(result.ForLLM, "in sub") {
+		t.Errorf("relative command after cd = %q, want it to run in sub", result.ForLLM)
+	}
+
+	result = tool.Execute(bob, map[string]interface{}{"command": "cat note.txt"})
+	if !result.IsError {
+		t.Errorf("another session should still be in the workspace root, got %q", result.ForLLM)
+	}
+
+	result = tool.Execute(bob, map[string]interface{}{"command": "cat note.txt", "cwd": "sub"})
+	if result.IsError || !strings.Contains(result.ForLLM, "in sub") {
+		t.Errorf("relative cwd argument = %q, want it to run in sub", result.ForLLM)
+	}
+}
+
+// TestShellTool_CwdRestrictedToWorkspace verifies the cwd argument and cd
+// cannot leave the workspace when restrictToWorkspace is on.
+func TestShellTool_CwdRestrictedToWorkspace(t *testing.T) {
+	tmpDir := t.TempDir()
+	workspace := filepath.Join(tmpDir, "workspace")
+	os.MkdirAll(workspace, 0755)
+	tool := NewExecTool(workspace, false)
+	tool.SetRestrictToWorkspace(true)
+	ctx := context.Background()
+
+	for _, cwd := range []string{tmpDir, "..", "/"} {
+		result := tool.Execute(ctx, map[string]interface{}{"command": "ls", "cwd": cwd})
+		if !result.IsError || !strings.Contains(result.ForLLM, "outside the workspace") {
+			t.Errorf("cwd %q: got %q, want it rejected", cwd, result.ForLLM)
+		}
+	}
+
+	result := tool.Execute(ctx, map[string]interface{}{"command": "cd /"})
+	if !strings.Contains(result.ForLLM, "reset") {
+		t.Errorf("cd outside the workspace = %q, want a reset note", result.ForLLM)
+	}
+	result = tool.Execute(ctx, map[string]interface{}{"command": "pwd"})
+	if got := strings.TrimSpace(strings.Split(result.ForLLM, "\n")[0]); got != workspace {
+		t.Errorf("pwd after reset = %q, want %q", got, workspace)
+	}
+
+	unrestricted := NewExecTool(workspace, false)
+	result = unrestricted.Execute(ctx, map[string]interface{}{"command": "pwd", "cwd": tmpDir})
+	if result.IsError || !strings.Contains(result.ForLLM, "[cwd: "+tmpDir+"]") {
+		t.Errorf("unrestricted cwd = %q, want it honored", result.ForLLM)
+	}
+}
+
+// TestShellTool_EnvPrecedence verifies the env argument overrides the
+// default environment, which overrides the process environment.
+func TestShellTool_EnvPrecedence(t *testing.T) {
+	t.Setenv("PICOCLAW_TEST_BASE", "process")
+	t.Setenv("PICOCLAW_TEST_OVERRIDE", "process")
+	tool := NewExecTool(t.TempDir(), false)
+	tool.SetEnv(map[string]string{
+		"PICOCLAW_TEST_OVERRIDE": "default",
+		"PICOCLAW_TEST_DEFAULT":  "default",
+		"PICOCLAW_TEST_PATH":     "/opt/bin:$PICOCLAW_TEST_BASE",
+	}, nil)
+
+	result := tool.Execute(context.Background(), map[string]interface{}{
+		"command": `echo "$PICOCLAW_TEST_BASE $PICOCLAW_TEST_OVERRIDE $PICOCLAW_TEST_DEFAULT $PICOCLAW_TEST_PATH $PICOCLAW_TEST_CALL"`,
+		"env": map[string]interface{}{
+			"PICOCLAW_TEST_OVERRIDE": "call",
+			"PICOCLAW_TEST_CALL":     "$PICOCLAW_TEST_DEFAULT-call",
+		},
+	})
+	if result.IsError {
+		t.Fatalf("Execute failed: %s", result.ForLLM)
+	}
+	if want := "process call default /opt/bin:process default-call\n"; result.ForUser != want {
+		t.Errorf("output = %q, want %q", result.ForUser, want)
+	}
+
+	result = tool.Execute(context.Background(), map[string]interface{}{"command": "true", "env": "FOO=bar"})
+	if !result.IsError {
+		t.Error("expected a non-object env to be rejected")
+	}
+}
+
+// TestShellTool_RedactArgs verifies secret env values are hidden from the
+// logged arguments without changing the call's own arguments.
+func TestShellTool_RedactArgs(t *testing.T) {
+	tool := NewExecTool("", false)
+	tool.SetEnv(nil, []string{"API_TOKEN"})
+	args := map[string]interface{}{
+		"command": "curl example.com",
+		"env":     map[string]interface{}{"API_TOKEN": "s3cret", "LANG": "C"},
+	}
+
+	redacted := tool.RedactArgs(args)
+	env := redacted["env"].(map[string]interface{})
+	if env["API_TOKEN"] != "[redacted]" || env["LANG"] != "C" || redacted["command"] != "curl example.com" {
+		t.Errorf("redacted args = %v", redacted)
+	}
+	if args["env"].(map[string]interface{})["API_TOKEN"] != "s3cret" {
+		t.Error("RedactArgs modified the original arguments")
+	}
+}
+
+// TestShellTool_CommandLists verifies blocked and allowed commands are
+// matched in every part of piped and chained commands.
+func TestShellTool_CommandLists(t *testing.T) {
+	tool := NewExecTool(t.TempDir(), false)
+	tool.SetCommandLists(nil, []string{"rm", "dd"})
+	ctx := context.Background()
+
+	for _, command := range []string{"rm x", "echo hi && rm x", "ls | xargs rm", "sudo rm x", "echo $(/bin/dd count=1)"} {
+		result := tool.Execute(ctx, map[string]interface{}{"command": command})
+		if !result.IsError || !strings.Contains(result.ForLLM, "is a blocked command") {
+			t.Errorf("%q: got %q, want it blocked", command, result.ForLLM)
+		}
+	}
+	result := tool.Execute(ctx, map[string]interface{}{"command": "echo 'rm is fine to mention' | cat"})
+	if result.IsError {
+		t.Errorf("quoted mention of a blocked command was refused: %s", result.ForLLM)
+	}
+
+	tool.SetCommandLists([]string{"echo", "grep"}, nil)
+	result = tool.Execute(ctx, map[string]interface{}{"command": "echo hello | grep hell"})
+	if result.IsError {
+		t.Errorf("allowed pipeline was refused: %s", result.ForLLM)
+	}
+	result = tool.Execute(ctx, map[string]interface{}{"command": "echo hello; curl example.com"})
+	if !result.IsError || !strings.Contains(result.ForLLM, "curl is not an allowed command") {
+		t.Errorf("got %q, want curl refused by the allowlist", result.ForLLM)
+	}
+}
+
+// TestShellTool_SessionDirsBounded verifies session directories are
+// forgotten on reset, on returning to the workspace, and beyond the cap.
+func TestShellTool_SessionDirsBounded(t *testing.T) {
+	tmpDir := t.TempDir()
+	os.MkdirAll(filepath.Join(tmpDir, "sub"), 0755)
+	tool := NewExecTool(tmpDir, false)
+
+	alice := WithToolContext(context.Background(), "telegram", "alice")
+	tool.Execute(alice, map[string]interface{}{"command": "cd sub"})
+	if _, ok := tool.sessionDirs["telegram:alice"]; !ok {
+		t.Fatal("cd should be remembered for the session")
+	}
+	tool.ResetSessionDir("telegram", "alice")
+	if _, ok := tool.sessionDirs["telegram:alice"]; ok {
+		t.Fatal("ResetSessionDir should forget the session's directory")
+	}
+
+	tool.updateSessionDir("telegram:alice", tmpDir, "")
+	if len(tool.sessionDirs) != 0 {
+		t.Fatalf("a session in the default directory should have no entry, have %v", tool.sessionDirs)
+	}
+
+	sub := filepath.Join(tmpDir, "sub")
+	for i := 0; i <= maxSessionDirs; i++ {
+		tool.updateSessionDir(fmt.Sprintf("telegram:%d", i), sub, "")
+	}
+	if len(tool.sessionDirs) != maxSessionDirs {
+		t.Fatalf("sessionDirs has %d entries, want the cap %d", len(tool.sessionDirs), maxSessionDirs)
+	}
+	if _, ok := tool.sessionDirs["telegram:0"]; ok {
+		t.Error("the least recently used session should be evicted first")
+	}
+}
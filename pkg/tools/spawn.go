@@ -68,7 +68,8 @@ func (t *SpawnTool) Execute(ctx context.Context, args map[string]interface{}) *T
 	}
 
 	// Pass callback to manager for async completion notification
-	result, err := t.manager.Spawn(ctx, task, label, t.originChannel, t.originChatID, t.callback)
+	channel, chatID := ToolContext(ctx, t.originChannel, t.originChatID)
+	result, err := t.manager.Spawn(ctx, task, label, channel, chatID, t.callback)
 	if err != nil {
 		return ErrorResult(fmt.Sprintf("failed to spawn subagent: %v", err))
 	}
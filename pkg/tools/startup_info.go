@@ -0,0 +1,68 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// startupInfoMaxNames caps how many tool or skill names are listed.
+const startupInfoMaxNames = 50
+
+// StartupInfoTool reports the tools and skills the agent has loaded, so the
+// model can answer "what can you do" from facts instead of guessing.
+type StartupInfoTool struct {
+	toolNames  func() []string
+	skillNames func() []string
+}
+
+// NewStartupInfoTool creates a StartupInfoTool. Both functions are called
+// on every execution so the answer reflects tools registered later.
+func NewStartupInfoTool(toolNames, skillNames func() []string) *StartupInfoTool {
+	return &StartupInfoTool{toolNames: toolNames, skillNames: skillNames}
+}
+
+func (t *StartupInfoTool) Name() string {
+	return "startup_info"
+}
+
+// ParallelSafe reports true: startup_info only reads.
+func (t *StartupInfoTool) ParallelSafe() bool {
+	return true
+}
+
+func (t *StartupInfoTool) Description() string {
+	return "List the tools and skills currently available to you, with counts. Use this to answer questions like \"what can you do\" or \"how many skills do you have\" accurately."
+}
+
+func (t *StartupInfoTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type":       "object",
+		"properties": map[string]interface{}{},
+	}
+}
+
+func (t *StartupInfoTool) Execute(ctx context.Context, args map[string]interface{}) *ToolResult {
+	return SilentResult(formatNameList("Tools", t.toolNames()) + "\n" + formatNameList("Skills", t.skillNames()))
+}
+
+// formatNameList renders "Label (n): a, b, c", sorted and capped at
+// startupInfoMaxNames.
+func formatNameList(label string, names []string) string {
+	if len(names) == 0 {
+		return fmt.Sprintf("%s (0): none", label)
+	}
+	sorted := append([]string(nil), names...)
+	sort.Strings(sorted)
+
+	shown := sorted
+	if len(shown) > startupInfoMaxNames {
+		shown = shown[:startupInfoMaxNames]
+	}
+	line := fmt.Sprintf("%s (%d): %s", label, len(sorted), strings.Join(shown, ", "))
+	if extra := len(sorted) - len(shown); extra > 0 {
+		line += fmt.Sprintf(" (+%d more)", extra)
+	}
+	return line
+}
@@ -0,0 +1,30 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestStartupInfoTool_CapsNames(t *testing.T) {
+	var many []string
+	for i := 0; i < startupInfoMaxNames+5; i++ {
+		many = append(many, fmt.Sprintf("tool_%03d", i))
+	}
+	tool := NewStartupInfoTool(
+		func() []string { return many },
+		func() []string { return nil },
+	)
+
+	out := tool.Execute(context.Background(), nil).ForLLM
+	if !strings.Contains(out, fmt.Sprintf("Tools (%d): tool_000, tool_001", len(many))) {
+		t.Fatalf("unexpected tools line:\n%s", out)
+	}
+	if !strings.Contains(out, "(+5 more)") || strings.Contains(out, "tool_054") {
+		t.Fatalf("names should be capped at %d:\n%s", startupInfoMaxNames, out)
+	}
+	if !strings.Contains(out, "Skills (0): none") {
+		t.Fatalf("missing empty skills line:\n%s", out)
+	}
+}
@@ -277,6 +277,7 @@ func (t *SubagentTool) Execute(ctx context.Context, args map[string]interface{})
 	}
 
 	// Use RunToolLoop to execute with tools (same as async SpawnTool)
+	channel, chatID := ToolContext(ctx, t.originChannel, t.originChatID)
 	sm := t.manager
 	sm.mu.RLock()
 	tools := sm.tools
@@ -292,7 +293,7 @@ func (t *SubagentTool) Execute(ctx context.Context, args map[string]interface{})
 			"max_tokens":  4096,
 			"temperature": 0.7,
 		},
-	}, messages, t.originChannel, t.originChatID)
+	}, messages, channel, chatID)
 
 	if err != nil {
 		return ErrorResult(fmt.Sprintf("Subagent execution failed: %v", err)).WithError(err)
@@ -0,0 +1,221 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/cron"
+)
+
+// ListTasksTool shows the upcoming scheduled jobs (created with the cron
+// tool) of the calling conversation with their due times.
+type ListTasksTool struct {
+	cronService    *cron.CronService
+	defaultChannel string
+	defaultChatID  string
+	now            func() time.Time
+}
+
+// NewListTasksTool creates a new ListTasksTool
+func NewListTasksTool(cronService *cron.CronService) *ListTasksTool {
+	return &ListTasksTool{
+		cronService: cronService,
+		now:         time.Now,
+	}
+}
+
+func (t *ListTasksTool) Name() string {
+	return "list_tasks"
+}
+
+// ParallelSafe reports true: list_tasks only reads.
+func (t *ListTasksTool) ParallelSafe() bool {
+	return true
+}
+
+func (t *ListTasksTool) Description() string {
+	return "List this chat's upcoming scheduled tasks and reminders with their IDs and when they are next due. The list is shown to the user directly. Use the ID with cancel_task."
+}
+
+func (t *ListTasksTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type":       "object",
+		"properties": map[string]interface{}{},
+	}
+}
+
+// SetContext sets the conversation used when a call carries none.
+func (t *ListTasksTool) SetContext(channel, chatID string) {
+	t.defaultChannel = channel
+	t.defaultChatID = chatID
+}
+
+func (t *ListTasksTool) Execute(ctx context.Context, args map[string]interface{}) *ToolResult {
+	channel, chatID := ToolContext(ctx, t.defaultChannel, t.defaultChatID)
+	var jobs []cron.CronJob
+	for _, job := range t.cronService.UpcomingJobs() {
+		if jobInChat(job, channel, chatID) {
+			jobs = append(jobs, job)
+		}
+	}
+	now := t.now()
+	if len(jobs) == 0 {
+		return SilentResult(FormatTaskList(jobs, now))
+	}
+	return UserResult(FormatTaskList(jobs, now)).
+		WithMetadata(ResultTypeTable, TableMetadata(taskTableColumns, taskTableRows(jobs, now)))
+}
+
+// CancelTaskTool cancels a scheduled job of the calling conversation by ID.
+type CancelTaskTool struct {
+	cronService    *cron.CronService
+	defaultChannel string
+	defaultChatID  string
+}
+
+// NewCancelTaskTool creates a new CancelTaskTool
+func NewCancelTaskTool(cronService *cron.CronService) *CancelTaskTool {
+	return &CancelTaskTool{cronService: cronService}
+}
+
+func (t *CancelTaskTool) Name() string {
+	return "cancel_task"
+}
+
+func (t *CancelTaskTool) Description() string {
+	return "Cancel a scheduled task or reminder by ID (see list_tasks). Recurring tasks stop recurring; one-time tasks that already ran cannot be undone."
+}
+
+func (t *CancelTaskTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"task_id": map[string]interface{}{
+				"type":        "string",
+				"description": "ID of the task to cancel",
+			},
+		},
+		"required": []string{"task_id"},
+	}
+}
+
+// SetContext sets the conversation used when a call carries none.
+func (t *CancelTaskTool) SetContext(channel, chatID string) {
+	t.defaultChannel = channel
+	t.defaultChatID = chatID
+}
+
+func (t *CancelTaskTool) Execute(ctx context.Context, args map[string]interface{}) *ToolResult {
+	taskID, _ := args["task_id"].(string)
+	taskID = strings.TrimSpace(taskID)
+	if taskID == "" {
+		return ErrorResult("task_id is required")
+	}
+
+	// Tasks of other chats are reported as unknown rather than refused, so
+	// their IDs do not leak.
+	channel, chatID := ToolContext(ctx, t.defaultChannel, t.defaultChatID)
+	owned := false
+	for _, job := range t.cronService.ListJobs(true) {
+		if job.ID == taskID {
+			owned = jobInChat(job, channel, chatID)
+			break
+		}
+	}
+	var job *cron.CronJob
+	err := cron.ErrJobNotFound
+	if owned {
+		job, err = t.cronService.CancelJob(taskID)
+	}
+	switch {
+	case errors.Is(err, cron.ErrJobNotFound):
+		return ErrorResult(fmt.Sprintf("No scheduled task with id %s (one-time tasks are removed after they run)", taskID))
+	case errors.Is(err, cron.ErrJobAlreadyFired):
+		return SilentResult(fmt.Sprintf("Task '%s' (id: %s) already fired, so there was nothing left to cancel; removed it from the list", job.Name, job.ID))
+	case err != nil:
+		return ErrorResult(fmt.Sprintf("failed to cancel task: %v", err)).WithError(err)
+	}
+	return SilentResult(fmt.Sprintf("Cancelled task '%s' (id: %s)", job.Name, job.ID))
+}
+
+// jobInChat reports whether job delivers to the given conversation.
+func jobInChat(job cron.CronJob, channel, chatID string) bool {
+	return job.Payload.Channel == channel && job.Payload.To == chatID
+}
+
+// FormatTaskList renders upcoming jobs, soonest first, with due times
+// relative to now.
+func FormatTaskList(jobs []cron.CronJob, now time.Time) string {
+	if len(jobs) == 0 {
+		return "No upcoming scheduled tasks."
+	}
+
+	lines := []string{fmt.Sprintf("Upcoming tasks (%d):", len(jobs))}
+	for _, j := range jobs {
+		due := "not scheduled"
+		if j.State.NextRunAtMS != nil {
+			due = "due " + formatDue(*j.State.NextRunAtMS, now)
+		}
+		lines = append(lines, fmt.Sprintf("- %s (id: %s) · %s · %s", j.Name, j.ID, due, describeSchedule(j.Schedule)))
+	}
+	return strings.Join(lines, "\n")
+}
+
+var taskTableColumns = []string{"Task", "ID", "Due", "Schedule"}
+
+// taskTableRows renders upcoming jobs as rows for a ResultTypeTable result.
+func taskTableRows(jobs []cron.CronJob, now time.Time) [][]string {
+	rows := make([][]string, 0, len(jobs))
+	for _, j := range jobs {
+		due := "not scheduled"
+		if j.State.NextRunAtMS != nil {
+			due = formatDue(*j.State.NextRunAtMS, now)
+		}
+		rows = append(rows, []string{j.Name, j.ID, due, describeSchedule(j.Schedule)})
+	}
+	return rows
+}
+
+// formatDue renders a due time with its distance from now, e.g.
+// "2026-01-02 15:04 (in 1h)".
+func formatDue(nextRunAtMS int64, now time.Time) string {
+	next := time.UnixMilli(nextRunAtMS)
+	return fmt.Sprintf("%s (%s)", next.Local().Format("2006-01-02 15:04"), formatDueIn(next.Sub(now)))
+}
+
+// formatDueIn renders a duration until a job is due, e.g. "in 1h25m".
+func formatDueIn(d time.Duration) string {
+	if d < time.Minute {
+		return "in <1m"
+	}
+	return "in " + shortDuration(d.Round(time.Minute))
+}
+
+// shortDuration drops zero trailing units, e.g. "1h0m0s" -> "1h".
+func shortDuration(d time.Duration) string {
+	s := d.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = strings.TrimSuffix(s, "0s")
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return s
+}
+
+func describeSchedule(s cron.CronSchedule) string {
+	switch s.Kind {
+	case "every":
+		if s.EveryMS != nil {
+			return "every " + shortDuration(time.Duration(*s.EveryMS)*time.Millisecond)
+		}
+	case "cron":
+		return "cron " + s.Expr
+	case "at":
+		return "one-time"
+	}
+	return "unknown schedule"
+}
@@ -0,0 +1,147 @@
+package tools
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/cron"
+)
+
+func newTestCronService(t *testing.T) *cron.CronService {
+	t.Helper()
+	return cron.NewCronService(filepath.Join(t.TempDir(), "cron", "jobs.json"), nil)
+}
+
+func addAtJob(t *testing.T, cs *cron.CronService, name string, in time.Duration) *cron.CronJob {
+	t.Helper()
+	at := time.Now().Add(in).UnixMilli()
+	job, err := cs.AddJob(name, cron.CronSchedule{Kind: "at", AtMS: &at}, name, true, "telegram", "1")
+	if err != nil {
+		t.Fatalf("AddJob() error: %v", err)
+	}
+	return job
+}
+
+// telegramChat returns the tool context of the chat addAtJob schedules for.
+func telegramChat() context.Context {
+	return WithToolContext(context.Background(), "telegram", "1")
+}
+
+func TestListTasksTool_ShowsUpcomingSoonestFirst(t *testing.T) {
+	cs := newTestCronService(t)
+	every := int64(time.Hour / time.Millisecond)
+	if _, err := cs.AddJob("hourly check", cron.CronSchedule{Kind: "every", EveryMS: &every}, "check", false, "telegram", "1"); err != nil {
+		t.Fatalf("AddJob() error: %v", err)
+	}
+	soon := addAtJob(t, cs, "call mom", 10*time.Minute)
+	paused := addAtJob(t, cs, "paused", 5*time.Minute)
+	cs.EnableJob(paused.ID, false)
+
+	result := NewListTasksTool(cs).Execute(telegramChat(), nil)
+	out := result.ForLLM
+	if result.IsError || !strings.HasPrefix(out, "Upcoming tasks (2):") {
+		t.Fatalf("unexpected list output:\n%s", out)
+	}
+	if strings.Index(out, "call mom") > strings.Index(out, "hourly check") {
+		t.Fatalf("tasks should be ordered by due time:\n%s", out)
+	}
+	if !strings.Contains(out, "(id: "+soon.ID+")") || !strings.Contains(out, "(in 10m)") {
+		t.Fatalf("missing id or due time:\n%s", out)
+	}
+	if !strings.Contains(out, "every 1h") {
+		t.Fatalf("missing recurring schedule:\n%s", out)
+	}
+	if strings.Contains(out, "paused") {
+		t.Fatalf("disabled tasks should not be listed:\n%s", out)
+	}
+}
+
+func TestListTasksTool_Empty(t *testing.T) {
+	result := NewListTasksTool(newTestCronService(t)).Execute(telegramChat(), nil)
+	if result.ForLLM != "No upcoming scheduled tasks." {
+		t.Fatalf("ForLLM = %q", result.ForLLM)
+	}
+}
+
+func TestCancelTaskTool_RemovesJobImmediately(t *testing.T) {
+	cs := newTestCronService(t)
+	job := addAtJob(t, cs, "call mom", time.Hour)
+
+	result := NewCancelTaskTool(cs).Execute(telegramChat(), map[string]interface{}{"task_id": job.ID})
+	if result.IsError || !strings.Contains(result.ForLLM, "Cancelled task 'call mom'") {
+		t.Fatalf("unexpected cancel result: %+v", result)
+	}
+	if got := cs.UpcomingJobs(); len(got) != 0 {
+		t.Fatalf("job still scheduled after cancel: %+v", got)
+	}
+
+	result = NewCancelTaskTool(cs).Execute(telegramChat(), map[string]interface{}{"task_id": job.ID})
+	if !result.IsError {
+		t.Fatal("cancelling an unknown task should be an error")
+	}
+}
+
+func TestCancelTaskTool_AlreadyFired(t *testing.T) {
+	cs := newTestCronService(t)
+	job := addAtJob(t, cs, "call mom", time.Hour)
+
+	// Simulate the scheduler having run the one-time job (without
+	// DeleteAfterRun it stays behind, disabled).
+	ran := time.Now().UnixMilli()
+	job.Enabled = false
+	job.State.NextRunAtMS = nil
+	job.State.LastRunAtMS = &ran
+	if err := cs.UpdateJob(job); err != nil {
+		t.Fatalf("UpdateJob() error: %v", err)
+	}
+
+	result := NewCancelTaskTool(cs).Execute(telegramChat(), map[string]interface{}{"task_id": job.ID})
+	if result.IsError || !strings.Contains(result.ForLLM, "already fired") {
+		t.Fatalf("unexpected result for fired task: %+v", result)
+	}
+	if jobs := cs.ListJobs(true); len(jobs) != 0 {
+		t.Fatalf("fired job should be cleaned up, still have %+v", jobs)
+	}
+}
+
+func TestListTasksTool_ReturnsTable(t *testing.T) {
+	cs := newTestCronService(t)
+	job := addAtJob(t, cs, "call mom", 10*time.Minute)
+
+	result := NewListTasksTool(cs).Execute(telegramChat(), nil)
+	if result.Silent || result.ForUser == "" {
+		t.Fatalf("task list should be shown to the user: %+v", result)
+	}
+	if result.EffectiveType() != ResultTypeTable {
+		t.Fatalf("Type = %q, want table", result.EffectiveType())
+	}
+	rows, _ := result.Metadata[MetadataRows].([][]string)
+	if len(rows) != 1 || rows[0][0] != "call mom" || rows[0][1] != job.ID || rows[0][3] != "one-time" {
+		t.Fatalf("rows = %v", rows)
+	}
+	if !strings.HasSuffix(rows[0][2], "(in 10m)") {
+		t.Fatalf("due cell = %q", rows[0][2])
+	}
+}
+
+func TestTaskTools_ScopedToCallingChat(t *testing.T) {
+	cs := newTestCronService(t)
+	job := addAtJob(t, cs, "call mom", time.Hour)
+	otherChat := WithToolContext(context.Background(), "telegram", "2")
+
+	result := NewListTasksTool(cs).Execute(otherChat, nil)
+	if strings.Contains(result.ForLLM, "call mom") {
+		t.Fatalf("another chat's task was listed:\n%s", result.ForLLM)
+	}
+
+	result = NewCancelTaskTool(cs).Execute(otherChat, map[string]interface{}{"task_id": job.ID})
+	if !result.IsError || !strings.Contains(result.ForLLM, "No scheduled task") {
+		t.Fatalf("cancelling another chat's task should look like an unknown ID: %+v", result)
+	}
+	if got := cs.UpcomingJobs(); len(got) != 1 {
+		t.Fatalf("another chat's task was cancelled: %+v", got)
+	}
+}
@@ -0,0 +1,594 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/sipeed/picoclaw/pkg/utils"
+)
+
+// termuxUnavailable is returned by Termux:API tools outside Termux.
+const termuxUnavailable = "This tool needs picoclaw running in Termux on Android with the Termux:API app and package installed."
+
+// BatteryStatusTool reports the phone's battery via termux-battery-status.
+type BatteryStatusTool struct{}
+
+func NewBatteryStatusTool() *BatteryStatusTool {
+	return &BatteryStatusTool{}
+}
+
+func (t *BatteryStatusTool) Name() string {
+	return "battery_status"
+}
+
+// ParallelSafe reports true: battery_status only reads.
+func (t *BatteryStatusTool) ParallelSafe() bool {
+	return true
+}
+
+func (t *BatteryStatusTool) Description() string {
+	return "Get the phone's battery level, charging state, health and temperature. Android (Termux) only."
+}
+
+func (t *BatteryStatusTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type":       "object",
+		"properties": map[string]interface{}{},
+	}
+}
+
+func (t *BatteryStatusTool) Execute(ctx context.Context, args map[string]interface{}) *ToolResult {
+	if !utils.IsTermux() {
+		return ErrorResult(termuxUnavailable)
+	}
+	out, err := runTermuxCommand(ctx, "termux-battery-status")
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("failed to read battery status: %v", err)).WithError(err)
+	}
+	summary, err := formatBatteryStatus(out)
+	if err != nil {
+		return ErrorResult(err.Error()).WithError(err)
+	}
+	return SilentResult(summary)
+}
+
+// batteryStatus is the termux-battery-status JSON output.
+type batteryStatus struct {
+	Health      string  `json:"health"`
+	Percentage  int     `json:"percentage"`
+	Plugged     string  `json:"plugged"`
+	Status      string  `json:"status"`
+	Temperature float64 `json:"temperature"`
+}
+
+// formatBatteryStatus turns termux-battery-status output into a summary,
+// e.g. "Battery 85% · discharging · unplugged · health good · 29.5°C".
+func formatBatteryStatus(raw []byte) (string, error) {
+	var b batteryStatus
+	if err := json.Unmarshal(raw, &b); err != nil {
+		return "", fmt.Errorf("failed to parse battery status: %w", err)
+	}
+
+	parts := []string{fmt.Sprintf("Battery %d%%", b.Percentage)}
+	if b.Status != "" {
+		parts = append(parts, strings.ToLower(b.Status))
+	}
+	if b.Plugged != "" {
+		plugged := strings.ToLower(strings.TrimPrefix(b.Plugged, "PLUGGED_"))
+		if b.Plugged != "UNPLUGGED" {
+			plugged = "plugged in (" + plugged + ")"
+		}
+		parts = append(parts, plugged)
+	}
+	if b.Health != "" {
+		parts = append(parts, "health "+strings.ToLower(b.Health))
+	}
+	if b.Temperature != 0 {
+		parts = append(parts, fmt.Sprintf("%.1f°C", b.Temperature))
+	}
+	return strings.Join(parts, " · "), nil
+}
+
+// SensorTool reads a phone sensor once via termux-sensor.
+type SensorTool struct{}
+
+func NewSensorTool() *SensorTool {
+	return &SensorTool{}
+}
+
+func (t *SensorTool) Name() string {
+	return "device_sensors"
+}
+
+// ParallelSafe reports true: device_sensors only reads.
+func (t *SensorTool) ParallelSafe() bool {
+	return true
+}
+
+func (t *SensorTool) Description() string {
+	return "Read the phone's sensors (accelerometer, light, proximity, ...). Call without 'sensor' to list available sensors, then with a sensor name (or part of it) to take one reading. Android (Termux) only."
+}
+
+func (t *SensorTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"sensor": map[string]interface{}{
+				"type":        "string",
+				"description": "Sensor name or part of it, e.g. \"light\" or \"accelerometer\". Omit to list sensors.",
+			},
+		},
+	}
+}
+
+func (t *SensorTool) Execute(ctx context.Context, args map[string]interface{}) *ToolResult {
+	if !utils.IsTermux() {
+		return ErrorResult(termuxUnavailable)
+	}
+
+	sensor, _ := args["sensor"].(string)
+	sensor = strings.TrimSpace(sensor)
+	if sensor == "" {
+		out, err := runTermuxCommand(ctx, "termux-sensor", "-l")
+		if err != nil {
+			return ErrorResult(fmt.Sprintf("failed to list sensors: %v", err)).WithError(err)
+		}
+		summary, err := formatSensorList(out)
+		if err != nil {
+			return ErrorResult(err.Error()).WithError(err)
+		}
+		return SilentResult(summary)
+	}
+
+	out, err := runTermuxCommand(ctx, "termux-sensor", "-s", sensor, "-n", "1")
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("failed to read sensor %q: %v", sensor, err)).WithError(err)
+	}
+	summary, err := formatSensorReading(out)
+	if err != nil {
+		return ErrorResult(err.Error()).WithError(err)
+	}
+	return SilentResult(summary)
+}
+
+// formatSensorList renders `termux-sensor -l` output as one name per line.
+func formatSensorList(raw []byte) (string, error) {
+	var list struct {
+		Sensors []string `json:"sensors"`
+	}
+	if err := json.Unmarshal(raw, &list); err != nil {
+		return "", fmt.Errorf("failed to parse sensor list: %w", err)
+	}
+	if len(list.Sensors) == 0 {
+		return "No sensors reported.", nil
+	}
+	return fmt.Sprintf("Available sensors (%d):\n- %s", len(list.Sensors), strings.Join(list.Sensors, "\n- ")), nil
+}
+
+// formatSensorReading renders `termux-sensor -s <name> -n 1` output, which
+// maps each matched sensor to its values, e.g. "LIGHT: 312".
+func formatSensorReading(raw []byte) (string, error) {
+	var readings map[string]struct {
+		Values []float64 `json:"values"`
+	}
+	if err := json.Unmarshal(raw, &readings); err != nil {
+		return "", fmt.Errorf("failed to parse sensor reading: %w", err)
+	}
+	if len(readings) == 0 {
+		return "", fmt.Errorf("no matching sensor returned a reading; call without 'sensor' to list sensors")
+	}
+
+	names := make([]string, 0, len(readings))
+	for name := range readings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	lines := make([]string, 0, len(names))
+	for _, name := range names {
+		values := make([]string, 0, len(readings[name].Values))
+		for _, v := range readings[name].Values {
+			values = append(values, fmt.Sprintf("%g", v))
+		}
+		lines = append(lines, fmt.Sprintf("%s: %s", name, strings.Join(values, ", ")))
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// locationProviders are the providers termux-location accepts.
+var locationProviders = []string{"gps", "network", "passive"}
+
+const (
+	locationDefaultTimeout = 30 * time.Second
+	locationMaxTimeout     = 120 * time.Second
+)
+
+// locationPermissionHint tells the user how to fix a denied location request.
+const locationPermissionHint = "Location permission denied. On the phone, open Settings > Apps > Termux:API > Permissions, allow Location, and make sure location services are on."
+
+// LocationTool reports the phone's position via termux-location.
+type LocationTool struct{}
+
+func NewLocationTool() *LocationTool {
+	return &LocationTool{}
+}
+
+func (t *LocationTool) Name() string {
+	return "location"
+}
+
+// ParallelSafe reports true: location only reads.
+func (t *LocationTool) ParallelSafe() bool {
+	return true
+}
+
+func (t *LocationTool) Description() string {
+	return "Get the phone's current location (latitude, longitude, accuracy). Use 'gps' for precision outdoors, 'network' for a faster rough fix, or 'passive' for the last known position. Android (Termux) only."
+}
+
+func (t *LocationTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"provider": map[string]interface{}{
+				"type":        "string",
+				"enum":        locationProviders,
+				"description": "Location provider (default: gps)",
+			},
+			"timeout": map[string]interface{}{
+				"type":        "integer",
+				"description": "Optional: seconds to wait for a fix (default 30, max 120)",
+			},
+		},
+	}
+}
+
+func (t *LocationTool) Execute(ctx context.Context, args map[string]interface{}) *ToolResult {
+	if !utils.IsTermux() {
+		return ErrorResult(termuxUnavailable)
+	}
+
+	provider := "gps"
+	if p, _ := args["provider"].(string); p != "" {
+		provider = strings.ToLower(p)
+		if !slices.Contains(locationProviders, provider) {
+			return ErrorResult(fmt.Sprintf("invalid provider %q (use one of: %s)", p, strings.Join(locationProviders, ", ")))
+		}
+	}
+	timeout := locationDefaultTimeout
+	if secs, ok := args["timeout"].(float64); ok && secs > 0 {
+		timeout = min(time.Duration(secs)*time.Second, locationMaxTimeout)
+	}
+
+	request := "once"
+	if provider == "passive" {
+		request = "last"
+	}
+	out, err := runTermuxCommandTimeout(ctx, timeout, "termux-location", "-p", provider, "-r", request)
+	if err != nil {
+		if isPermissionError(err.Error()) {
+			return ErrorResult(locationPermissionHint).WithError(err)
+		}
+		return ErrorResult(fmt.Sprintf("failed to get location: %v", err)).WithError(err)
+	}
+	summary, err := formatLocation(out)
+	if err != nil {
+		return ErrorResult(err.Error()).WithError(err)
+	}
+	return SilentResult(summary)
+}
+
+// location is the termux-location JSON output.
+type location struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	Altitude  float64 `json:"altitude"`
+	Accuracy  float64 `json:"accuracy"`
+	Provider  string  `json:"provider"`
+	APIError  string  `json:"API_ERROR"`
+}
+
+// formatLocation turns termux-location output into a summary, e.g.
+// "Location: 52.520008, 13.404954 (±12 m, gps)".
+func formatLocation(raw []byte) (string, error) {
+	trimmed := strings.TrimSpace(string(raw))
+	if trimmed == "" {
+		return "", fmt.Errorf("no location fix; location services may be off or the provider timed out, so try again or use provider \"network\"")
+	}
+	if isPermissionError(trimmed) {
+		return "", fmt.Errorf("%s", locationPermissionHint)
+	}
+
+	var loc location
+	if err := json.Unmarshal([]byte(trimmed), &loc); err != nil {
+		return "", fmt.Errorf("failed to parse location: %w", err)
+	}
+	if loc.APIError != "" {
+		return "", fmt.Errorf("location unavailable: %s", loc.APIError)
+	}
+
+	summary := fmt.Sprintf("Location: %.6f, %.6f (±%.0f m", loc.Latitude, loc.Longitude, loc.Accuracy)
+	if loc.Provider != "" {
+		summary += ", " + loc.Provider
+	}
+	return summary + ")", nil
+}
+
+func isPermissionError(s string) bool {
+	return strings.Contains(strings.ToLower(s), "permission")
+}
+
+// ClipboardGetTool reads the phone clipboard via termux-clipboard-get.
+type ClipboardGetTool struct{}
+
+func NewClipboardGetTool() *ClipboardGetTool {
+	return &ClipboardGetTool{}
+}
+
+func (t *ClipboardGetTool) Name() string {
+	return "clipboard_get"
+}
+
+// ParallelSafe reports true: clipboard_get only reads.
+func (t *ClipboardGetTool) ParallelSafe() bool {
+	return true
+}
+
+func (t *ClipboardGetTool) Description() string {
+	return "Read the current text on the phone's clipboard. Android (Termux) only."
+}
+
+func (t *ClipboardGetTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type":       "object",
+		"properties": map[string]interface{}{},
+	}
+}
+
+func (t *ClipboardGetTool) Execute(ctx context.Context, args map[string]interface{}) *ToolResult {
+	if !utils.IsTermux() {
+		return ErrorResult(termuxUnavailable)
+	}
+	out, err := runTermuxCommand(ctx, "termux-clipboard-get")
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("failed to read clipboard: %v", err)).WithError(err)
+	}
+	if len(out) == 0 {
+		return SilentResult("The clipboard is empty.")
+	}
+	return SilentResult(string(out))
+}
+
+// ClipboardSetTool replaces the phone clipboard via termux-clipboard-set.
+type ClipboardSetTool struct{}
+
+func NewClipboardSetTool() *ClipboardSetTool {
+	return &ClipboardSetTool{}
+}
+
+func (t *ClipboardSetTool) Name() string {
+	return "clipboard_set"
+}
+
+func (t *ClipboardSetTool) Description() string {
+	return "Copy text to the phone's clipboard, replacing what is there. Android (Termux) only."
+}
+
+func (t *ClipboardSetTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"text": map[string]interface{}{
+				"type":        "string",
+				"description": "Text to put on the clipboard",
+			},
+		},
+		"required": []string{"text"},
+	}
+}
+
+func (t *ClipboardSetTool) Execute(ctx context.Context, args map[string]interface{}) *ToolResult {
+	if !utils.IsTermux() {
+		return ErrorResult(termuxUnavailable)
+	}
+	text, ok := args["text"].(string)
+	if !ok {
+		return ErrorResult("text is required")
+	}
+	if _, err := runTermuxCommand(ctx, "termux-clipboard-set", text); err != nil {
+		return ErrorResult(fmt.Sprintf("failed to set clipboard: %v", err)).WithError(err)
+	}
+	return SilentResult(fmt.Sprintf("Copied %d characters to the clipboard.", utf8.RuneCountInString(text)))
+}
+
+// notificationPriorities are the priorities termux-notification accepts.
+var notificationPriorities = []string{"min", "low", "default", "high", "max"}
+
+// NotifyTool posts an Android notification via termux-notification.
+type NotifyTool struct{}
+
+func NewNotifyTool() *NotifyTool {
+	return &NotifyTool{}
+}
+
+func (t *NotifyTool) Name() string {
+	return "notify"
+}
+
+func (t *NotifyTool) Description() string {
+	return "Post a system notification on the phone, e.g. when a long task finishes. Reuse an 'id' to update or replace an earlier notification. Android (Termux) only."
+}
+
+func (t *NotifyTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"title": map[string]interface{}{
+				"type":        "string",
+				"description": "Notification title",
+			},
+			"content": map[string]interface{}{
+				"type":        "string",
+				"description": "Notification text",
+			},
+			"id": map[string]interface{}{
+				"type":        "string",
+				"description": "Optional: notification ID; posting again with the same ID replaces it",
+			},
+			"priority": map[string]interface{}{
+				"type":        "string",
+				"enum":        notificationPriorities,
+				"description": "Optional: notification priority (default: default)",
+			},
+		},
+		"required": []string{"title", "content"},
+	}
+}
+
+func (t *NotifyTool) Execute(ctx context.Context, args map[string]interface{}) *ToolResult {
+	if !utils.IsTermux() {
+		return ErrorResult(termuxUnavailable)
+	}
+	cmdArgs, err := notificationArgs(args)
+	if err != nil {
+		return ErrorResult(err.Error()).WithError(err)
+	}
+	if _, err := runTermuxCommand(ctx, "termux-notification", cmdArgs...); err != nil {
+		return ErrorResult(fmt.Sprintf("failed to post notification: %v", err)).WithError(err)
+	}
+	if id, _ := args["id"].(string); strings.TrimSpace(id) != "" {
+		return SilentResult(fmt.Sprintf("Notification posted (id %s).", strings.TrimSpace(id)))
+	}
+	return SilentResult("Notification posted.")
+}
+
+// notificationArgs validates the tool arguments and builds the
+// termux-notification command line.
+func notificationArgs(args map[string]interface{}) ([]string, error) {
+	title, _ := args["title"].(string)
+	content, _ := args["content"].(string)
+	if strings.TrimSpace(title) == "" {
+		return nil, fmt.Errorf("title is required")
+	}
+	if strings.TrimSpace(content) == "" {
+		return nil, fmt.Errorf("content is required")
+	}
+
+	cmdArgs := []string{"--title", title, "--content", content}
+	if id, _ := args["id"].(string); strings.TrimSpace(id) != "" {
+		cmdArgs = append(cmdArgs, "--id", strings.TrimSpace(id))
+	}
+	if priority, _ := args["priority"].(string); priority != "" {
+		priority = strings.ToLower(priority)
+		if !slices.Contains(notificationPriorities, priority) {
+			return nil, fmt.Errorf("invalid priority %q (use one of: %s)", priority, strings.Join(notificationPriorities, ", "))
+		}
+		cmdArgs = append(cmdArgs, "--priority", priority)
+	}
+	return cmdArgs, nil
+}
+
+// cameraTimeout bounds termux-camera-photo, which waits for autofocus and
+// exposure before saving.
+const cameraTimeout = 30 * time.Second
+
+// cameraPermissionHint tells the user how to fix a denied camera request.
+const cameraPermissionHint = "Camera permission denied. On the phone, open Settings > Apps > Termux:API > Permissions and allow Camera."
+
+// CameraPhotoTool takes a photo with the phone camera via
+// termux-camera-photo and saves it in the workspace.
+type CameraPhotoTool struct {
+	workspace string
+	restrict  bool
+	now       func() time.Time
+}
+
+func NewCameraPhotoTool(workspace string, restrict bool) *CameraPhotoTool {
+	return &CameraPhotoTool{workspace: workspace, restrict: restrict, now: time.Now}
+}
+
+func (t *CameraPhotoTool) Name() string {
+	return "camera_photo"
+}
+
+func (t *CameraPhotoTool) Description() string {
+	return "Take a photo with the phone camera and save it as a JPEG in the workspace. Share it with send_file. Camera 0 is usually the back camera and 1 the front camera. Android (Termux) only."
+}
+
+func (t *CameraPhotoTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"camera_id": map[string]interface{}{
+				"type":        "integer",
+				"description": "Optional: camera to use (default 0, usually the back camera; 1 is usually the front camera)",
+			},
+			"path": map[string]interface{}{
+				"type":        "string",
+				"description": "Optional: workspace path for the .jpg (default camera/photo_<timestamp>.jpg)",
+			},
+		},
+	}
+}
+
+func (t *CameraPhotoTool) Execute(ctx context.Context, args map[string]interface{}) *ToolResult {
+	if !utils.IsTermux() {
+		return ErrorResult(termuxUnavailable)
+	}
+	outPath, cmdArgs, err := t.cameraPhotoArgs(args)
+	if err != nil {
+		return ErrorResult(err.Error()).WithError(err)
+	}
+	if err := os.MkdirAll(filepath.Dir(outPath), 0755); err != nil {
+		return ErrorResult(fmt.Sprintf("failed to create photo directory: %v", err)).WithError(err)
+	}
+
+	if _, err := runTermuxCommandTimeout(ctx, cameraTimeout, "termux-camera-photo", cmdArgs...); err != nil {
+		if isPermissionError(err.Error()) {
+			return ErrorResult(cameraPermissionHint).WithError(err)
+		}
+		return ErrorResult(fmt.Sprintf("failed to take photo: %v", err)).WithError(err)
+	}
+	// termux-camera-photo exits cleanly even when the camera could not be
+	// opened, so check that a photo was actually written.
+	info, err := os.Stat(outPath)
+	if err != nil || info.Size() == 0 {
+		os.Remove(outPath)
+		return ErrorResult("no photo was saved; the camera may be in use, the camera_id may not exist, or Termux:API lacks the Camera permission")
+	}
+	return SilentResult(fmt.Sprintf("Photo saved to %s (%d bytes). Use send_file to share it.", outPath, info.Size()))
+}
+
+// cameraPhotoArgs validates the tool arguments and returns the output path
+// and the termux-camera-photo command line.
+func (t *CameraPhotoTool) cameraPhotoArgs(args map[string]interface{}) (string, []string, error) {
+	cameraID := 0
+	if v, ok := args["camera_id"]; ok {
+		id, ok := v.(float64)
+		if !ok || id < 0 || id != float64(int(id)) {
+			return "", nil, fmt.Errorf("camera_id must be a non-negative integer")
+		}
+		cameraID = int(id)
+	}
+
+	path, _ := args["path"].(string)
+	if strings.TrimSpace(path) == "" {
+		path = filepath.Join("camera", fmt.Sprintf("photo_%s.jpg", t.now().Format("20060102_150405")))
+	}
+	if ext := strings.ToLower(filepath.Ext(path)); ext != ".jpg" && ext != ".jpeg" {
+		return "", nil, fmt.Errorf("path must end in .jpg")
+	}
+	outPath, err := validatePath(path, t.workspace, t.restrict)
+	if err != nil {
+		return "", nil, err
+	}
+	return outPath, []string{"-c", fmt.Sprintf("%d", cameraID), outPath}, nil
+}
@@ -0,0 +1,34 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// termuxCommandTimeout bounds Termux:API calls, which hang if the
+// Termux:API app is missing.
+const termuxCommandTimeout = 15 * time.Second
+
+// runTermuxCommand runs a Termux:API command and returns its stdout.
+func runTermuxCommand(ctx context.Context, name string, args ...string) ([]byte, error) {
+	return runTermuxCommandTimeout(ctx, termuxCommandTimeout, name, args...)
+}
+
+// runTermuxCommandTimeout is runTermuxCommand with a custom timeout, for
+// commands such as termux-location that can legitimately take longer.
+func runTermuxCommandTimeout(ctx context.Context, timeout time.Duration, name string, args ...string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	out, err := exec.CommandContext(ctx, name, args...).Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok && len(exitErr.Stderr) > 0 {
+			return nil, fmt.Errorf("%s: %s", name, strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return out, nil
+}
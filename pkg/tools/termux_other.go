@@ -0,0 +1,19 @@
+//go:build !linux
+
+package tools
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// runTermuxCommand is a stub for non-Linux platforms.
+func runTermuxCommand(ctx context.Context, name string, args ...string) ([]byte, error) {
+	return nil, fmt.Errorf("%s is only available on Android (Termux)", name)
+}
+
+// runTermuxCommandTimeout is a stub for non-Linux platforms.
+func runTermuxCommandTimeout(ctx context.Context, timeout time.Duration, name string, args ...string) ([]byte, error) {
+	return runTermuxCommand(ctx, name, args...)
+}
@@ -0,0 +1,146 @@
+package tools
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFormatBatteryStatus(t *testing.T) {
+	raw := []byte(`{"health":"GOOD","percentage":14,"plugged":"UNPLUGGED","status":"DISCHARGING","temperature":29.5,"current":-350000}`)
+	got, err := formatBatteryStatus(raw)
+	if err != nil {
+		t.Fatalf("formatBatteryStatus() error: %v", err)
+	}
+	if want := "Battery 14% · discharging · unplugged · health good · 29.5°C"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	charging, _ := formatBatteryStatus([]byte(`{"percentage":80,"plugged":"PLUGGED_AC","status":"CHARGING"}`))
+	if want := "Battery 80% · charging · plugged in (ac)"; charging != want {
+		t.Errorf("got %q, want %q", charging, want)
+	}
+
+	if _, err := formatBatteryStatus([]byte("termux-api not installed")); err == nil {
+		t.Error("expected error for non-JSON output")
+	}
+}
+
+func TestFormatSensorOutput(t *testing.T) {
+	list, err := formatSensorList([]byte(`{"sensors":["BMI160 Accelerometer","TMD2725 Light"]}`))
+	if err != nil {
+		t.Fatalf("formatSensorList() error: %v", err)
+	}
+	if want := "Available sensors (2):\n- BMI160 Accelerometer\n- TMD2725 Light"; list != want {
+		t.Errorf("got %q, want %q", list, want)
+	}
+
+	reading, err := formatSensorReading([]byte(`{"BMI160 Accelerometer":{"values":[0.12,9.81,-0.03]}}`))
+	if err != nil {
+		t.Fatalf("formatSensorReading() error: %v", err)
+	}
+	if want := "BMI160 Accelerometer: 0.12, 9.81, -0.03"; reading != want {
+		t.Errorf("got %q, want %q", reading, want)
+	}
+
+	if _, err := formatSensorReading([]byte(`{}`)); err == nil {
+		t.Error("expected error when no sensor matched")
+	}
+}
+
+func TestTermuxTools_RequireTermux(t *testing.T) {
+	t.Setenv("TERMUX_VERSION", "")
+	t.Setenv("PREFIX", "/usr")
+
+	for _, tool := range []Tool{NewBatteryStatusTool(), NewSensorTool(), NewNotifyTool(), NewClipboardGetTool(), NewClipboardSetTool(), NewLocationTool(), NewCameraPhotoTool(t.TempDir(), true)} {
+		result := tool.Execute(context.Background(), map[string]interface{}{})
+		if !result.IsError || !strings.Contains(result.ForLLM, "Termux") {
+			t.Errorf("%s outside Termux = %+v, want Termux error", tool.Name(), result)
+		}
+	}
+}
+
+func TestNotificationArgs(t *testing.T) {
+	got, err := notificationArgs(map[string]interface{}{
+		"title":    "Backup",
+		"content":  "Finished in 3m",
+		"id":       " backup ",
+		"priority": "HIGH",
+	})
+	if err != nil {
+		t.Fatalf("notificationArgs() error: %v", err)
+	}
+	want := []string{"--title", "Backup", "--content", "Finished in 3m", "--id", "backup", "--priority", "high"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	minimal, _ := notificationArgs(map[string]interface{}{"title": "Hi", "content": "there"})
+	if len(minimal) != 4 {
+		t.Errorf("expected only title and content flags, got %q", minimal)
+	}
+
+	if _, err := notificationArgs(map[string]interface{}{"title": "Hi"}); err == nil {
+		t.Error("expected error for missing content")
+	}
+	if _, err := notificationArgs(map[string]interface{}{"title": "Hi", "content": "x", "priority": "urgent"}); err == nil {
+		t.Error("expected error for unknown priority")
+	}
+}
+
+func TestFormatLocation(t *testing.T) {
+	got, err := formatLocation([]byte(`{"latitude":52.520008,"longitude":13.404954,"altitude":34.2,"accuracy":12.4,"provider":"gps"}`))
+	if err != nil {
+		t.Fatalf("formatLocation() error: %v", err)
+	}
+	if want := "Location: 52.520008, 13.404954 (±12 m, gps)"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	if _, err := formatLocation([]byte("Permission denied: android.permission.ACCESS_FINE_LOCATION")); err == nil || !strings.Contains(err.Error(), "Settings > Apps > Termux:API") {
+		t.Errorf("expected actionable permission error, got %v", err)
+	}
+	if _, err := formatLocation([]byte(`{"API_ERROR":"Location services disabled"}`)); err == nil || !strings.Contains(err.Error(), "disabled") {
+		t.Errorf("expected API error to be surfaced, got %v", err)
+	}
+	if _, err := formatLocation([]byte("\n")); err == nil {
+		t.Error("expected error for empty output")
+	}
+}
+
+func TestCameraPhotoArgs(t *testing.T) {
+	workspace := t.TempDir()
+	tool := NewCameraPhotoTool(workspace, true)
+	tool.now = func() time.Time { return time.Date(2026, 3, 1, 9, 30, 0, 0, time.UTC) }
+
+	path, cmdArgs, err := tool.cameraPhotoArgs(map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("cameraPhotoArgs() error: %v", err)
+	}
+	wantPath := filepath.Join(workspace, "camera", "photo_20260301_093000.jpg")
+	if path != wantPath || strings.Join(cmdArgs, " ") != "-c 0 "+wantPath {
+		t.Errorf("defaults = %q %q, want %q with camera 0", path, cmdArgs, wantPath)
+	}
+
+	path, cmdArgs, err = tool.cameraPhotoArgs(map[string]interface{}{"camera_id": float64(1), "path": "selfie.JPG"})
+	if err != nil {
+		t.Fatalf("cameraPhotoArgs() error: %v", err)
+	}
+	if path != filepath.Join(workspace, "selfie.JPG") || cmdArgs[1] != "1" {
+		t.Errorf("got %q %q, want front camera into selfie.JPG", path, cmdArgs)
+	}
+
+	for _, bad := range []map[string]interface{}{
+		{"camera_id": float64(-1)},
+		{"camera_id": 1.5},
+		{"camera_id": "front"},
+		{"path": "photo.png"},
+		{"path": "../outside.jpg"},
+	} {
+		if _, _, err := tool.cameraPhotoArgs(bad); err == nil {
+			t.Errorf("cameraPhotoArgs(%v) should fail", bad)
+		}
+	}
+}
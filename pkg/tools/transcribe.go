@@ -0,0 +1,82 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/attachments"
+	"github.com/sipeed/picoclaw/pkg/utils"
+	"github.com/sipeed/picoclaw/pkg/voice"
+)
+
+// transcribeAttachmentTimeout bounds one on-demand transcription; saved
+// attachments can be longer than inbound voice notes.
+const transcribeAttachmentTimeout = 2 * time.Minute
+
+// TranscribeAttachmentTool transcribes a saved audio attachment on demand,
+// e.g. one that was not transcribed on arrival or needs another language.
+type TranscribeAttachmentTool struct {
+	store       *attachments.Store
+	transcriber voice.Transcriber
+}
+
+func NewTranscribeAttachmentTool(store *attachments.Store, transcriber voice.Transcriber) *TranscribeAttachmentTool {
+	return &TranscribeAttachmentTool{store: store, transcriber: transcriber}
+}
+
+func (t *TranscribeAttachmentTool) Name() string {
+	return "transcribe_attachment"
+}
+
+func (t *TranscribeAttachmentTool) Description() string {
+	return "Transcribe a saved audio attachment to text by its attachment ID (from an attachment_saved marker). Set language when the speech is known to be in a specific language; otherwise it is detected."
+}
+
+func (t *TranscribeAttachmentTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"attachment_id": map[string]interface{}{
+				"type":        "string",
+				"description": "Attachment ID from an attachment_saved marker",
+			},
+			"language": map[string]interface{}{
+				"type":        "string",
+				"description": "Optional: spoken language as an ISO 639-1 code (e.g. \"de\") or \"auto\" to detect it (default)",
+			},
+		},
+		"required": []string{"attachment_id"},
+	}
+}
+
+func (t *TranscribeAttachmentTool) Execute(ctx context.Context, args map[string]interface{}) *ToolResult {
+	id, _ := args["attachment_id"].(string)
+	if strings.TrimSpace(id) == "" {
+		return ErrorResult("attachment_id is required")
+	}
+	if t.transcriber == nil || !t.transcriber.IsAvailable() {
+		return ErrorResult("transcription is not configured (set providers.groq.api_key)")
+	}
+
+	rec, ok := t.store.GetByID(strings.TrimSpace(id))
+	if !ok {
+		return ErrorResult(fmt.Sprintf("attachment not found: %s", id))
+	}
+	if !utils.IsAudioFile(rec.Name, rec.MIMEType) {
+		return ErrorResult(fmt.Sprintf("attachment %s (%s) is not an audio file", rec.ID, rec.Name))
+	}
+
+	language, _ := args["language"].(string)
+	ctx, cancel := context.WithTimeout(ctx, transcribeAttachmentTimeout)
+	defer cancel()
+	result, err := t.transcriber.TranscribeLanguage(ctx, rec.StoredPath, strings.TrimSpace(language))
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("transcription failed: %v", err)).WithError(err)
+	}
+	if strings.TrimSpace(result.Text) == "" {
+		return NewToolResult(fmt.Sprintf("No speech found in %s.", rec.Name))
+	}
+	return NewToolResult(voice.FormatTranscription("audio", result))
+}
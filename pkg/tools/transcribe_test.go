@@ -0,0 +1,96 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/attachments"
+	"github.com/sipeed/picoclaw/pkg/voice"
+)
+
+type fakeTranscriber struct {
+	available bool
+	err       error
+	language  string // Last language passed to TranscribeLanguage
+}
+
+func (f *fakeTranscriber) IsAvailable() bool { return f.available }
+
+func (f *fakeTranscriber) Transcribe(ctx context.Context, path string) (*voice.TranscriptionResponse, error) {
+	return f.TranscribeLanguage(ctx, path, "")
+}
+
+func (f *fakeTranscriber) TranscribeLanguage(ctx context.Context, path, language string) (*voice.TranscriptionResponse, error) {
+	f.language = language
+	if f.err != nil {
+		return nil, f.err
+	}
+	lang := language
+	if lang == "" {
+		lang = "en"
+	}
+	return &voice.TranscriptionResponse{Text: "call me back", Language: lang}, nil
+}
+
+func saveTestAttachment(t *testing.T, store *attachments.Store, dir, name, mime string) attachments.Record {
+	t.Helper()
+	src := filepath.Join(dir, name)
+	if err := os.WriteFile(src, []byte("data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	rec, err := store.SaveFromLocalFile("telegram", "1", "u1", "m-"+name, name, mime, "audio", src)
+	if err != nil {
+		t.Fatalf("save attachment: %v", err)
+	}
+	return rec
+}
+
+func TestTranscribeAttachmentTool(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	workspace := t.TempDir()
+	store := attachments.NewStore(workspace)
+	memo := saveTestAttachment(t, store, workspace, "memo.m4a", "audio/mp4")
+	notes := saveTestAttachment(t, store, workspace, "notes.pdf", "application/pdf")
+
+	tr := &fakeTranscriber{available: true}
+	tool := NewTranscribeAttachmentTool(store, tr)
+
+	res := tool.Execute(context.Background(), map[string]interface{}{"attachment_id": memo.ID, "language": "de"})
+	if res.IsError || res.ForLLM != "[audio transcription lang=de: call me back]" {
+		t.Fatalf("result = %+v", res)
+	}
+	if tr.language != "de" {
+		t.Errorf("language passed = %q, want de", tr.language)
+	}
+
+	res = tool.Execute(context.Background(), map[string]interface{}{"attachment_id": memo.ID})
+	if res.IsError || tr.language != "" {
+		t.Errorf("without language: result = %+v, language = %q; want auto-detect", res, tr.language)
+	}
+
+	for name, args := range map[string]map[string]interface{}{
+		"missing id":   {},
+		"unknown id":   {"attachment_id": "nope"},
+		"not audio":    {"attachment_id": notes.ID},
+		"backend fail": {"attachment_id": memo.ID},
+	} {
+		t.Run(name, func(t *testing.T) {
+			tool := tool
+			if name == "backend fail" {
+				tool = NewTranscribeAttachmentTool(store, &fakeTranscriber{available: true, err: errors.New("quota")})
+			}
+			if res := tool.Execute(context.Background(), args); !res.IsError {
+				t.Errorf("expected error, got %q", res.ForLLM)
+			}
+		})
+	}
+
+	unconfigured := NewTranscribeAttachmentTool(store, &fakeTranscriber{})
+	if res := unconfigured.Execute(context.Background(), map[string]interface{}{"attachment_id": memo.ID}); !res.IsError || !strings.Contains(res.ForLLM, "not configured") {
+		t.Errorf("unavailable transcriber = %+v, want not configured error", res)
+	}
+}
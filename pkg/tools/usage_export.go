@@ -0,0 +1,113 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/usage"
+)
+
+// UsageExportTool writes token usage records to a CSV or JSON file in the
+// workspace so they can be sent with send_file or opened in a spreadsheet.
+type UsageExportTool struct {
+	workspace string
+	store     *usage.Store
+	now       func() time.Time
+}
+
+func NewUsageExportTool(workspace string, store *usage.Store) *UsageExportTool {
+	return &UsageExportTool{
+		workspace: workspace,
+		store:     store,
+		now:       time.Now,
+	}
+}
+
+func (t *UsageExportTool) Name() string {
+	return "usage_export"
+}
+
+func (t *UsageExportTool) Description() string {
+	return "Export token usage records to a CSV or JSON file in the workspace. Returns the file path, which can be sent with send_file."
+}
+
+func (t *UsageExportTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"format": map[string]interface{}{
+				"type":        "string",
+				"enum":        []string{"csv", "json"},
+				"description": "Export format (default: csv)",
+			},
+			"day_key": map[string]interface{}{
+				"type":        "string",
+				"description": "Optional: only include records for this day (YYYY-MM-DD)",
+			},
+			"session_key": map[string]interface{}{
+				"type":        "string",
+				"description": "Optional: only include records for this session",
+			},
+			"since_days": map[string]interface{}{
+				"type":        "integer",
+				"description": "Optional: only include records from the last N days",
+			},
+		},
+	}
+}
+
+func (t *UsageExportTool) Execute(ctx context.Context, args map[string]interface{}) *ToolResult {
+	format, _ := args["format"].(string)
+	format = strings.ToLower(strings.TrimSpace(format))
+	if format == "" {
+		format = "csv"
+	}
+	if format != "csv" && format != "json" {
+		return ErrorResult(fmt.Sprintf("unsupported format %q (use csv or json)", format))
+	}
+
+	filter := usage.Filter{}
+	filter.DayKey, _ = args["day_key"].(string)
+	filter.SessionKey, _ = args["session_key"].(string)
+
+	now := t.now()
+	if v, ok := args["since_days"].(float64); ok {
+		if v < 0 {
+			return ErrorResult("since_days must not be negative")
+		}
+		if v > 0 {
+			filter.Since = now.Add(-time.Duration(v * float64(24*time.Hour)))
+		}
+	}
+
+	records := t.store.Query(filter)
+
+	relPath := filepath.Join("exports", "usage", fmt.Sprintf("usage-%s.%s", now.Format("20060102-150405"), format))
+	path := filepath.Join(t.workspace, relPath)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return ErrorResult(fmt.Sprintf("failed to create export directory: %v", err))
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("failed to create export file: %v", err))
+	}
+	if format == "csv" {
+		err = usage.WriteCSV(f, records)
+	} else {
+		err = usage.WriteJSON(f, records)
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(path)
+		return ErrorResult(fmt.Sprintf("failed to write usage export: %v", err)).WithError(err)
+	}
+
+	return SilentResult(fmt.Sprintf("Usage export written: %s (%d records, %s)", path, len(records), format))
+}
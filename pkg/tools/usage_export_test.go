@@ -0,0 +1,112 @@
+package tools
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/usage"
+)
+
+func newUsageExportTestTool(t *testing.T) (*UsageExportTool, string) {
+	t.Helper()
+	workspace := t.TempDir()
+	store := usage.NewStore("", time.UTC)
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+
+	store.Add(usage.Record{Timestamp: now.Add(-72 * time.Hour), DayKey: "2026-03-07", SessionKey: "telegram:1", Provider: "openai", Model: "gpt", PromptTokens: 100, CompletionTokens: 50, UsageKnown: true, Reason: "stop"})
+	store.Add(usage.Record{Timestamp: now.Add(-2 * time.Hour), DayKey: "2026-03-10", SessionKey: "telegram:1", Provider: "openai", Model: "gpt", PromptTokens: 10, CompletionTokens: 5, UsageKnown: true, Reason: "stop"})
+	store.Add(usage.Record{Timestamp: now.Add(-1 * time.Hour), DayKey: "2026-03-10", SessionKey: "discord:2", Provider: "anthropic", Model: "claude", Reason: "tool_calls"})
+
+	tool := NewUsageExportTool(workspace, store)
+	tool.now = func() time.Time { return now }
+	return tool, workspace
+}
+
+// exportPath pulls the written file path out of the tool result.
+func exportPath(t *testing.T, res *ToolResult) string {
+	t.Helper()
+	if res.IsError {
+		t.Fatalf("usage_export failed: %s", res.ForLLM)
+	}
+	path := strings.TrimPrefix(res.ForLLM, "Usage export written: ")
+	path = path[:strings.Index(path, " (")]
+	return path
+}
+
+func readCSV(t *testing.T, path string) [][]string {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("open export: %v", err)
+	}
+	defer f.Close()
+	rows, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		t.Fatalf("parse csv: %v", err)
+	}
+	return rows
+}
+
+func TestUsageExportTool_CSVHeader(t *testing.T) {
+	tool, workspace := newUsageExportTestTool(t)
+
+	path := exportPath(t, tool.Execute(context.Background(), map[string]interface{}{"format": "csv"}))
+	if !strings.HasPrefix(path, filepath.Join(workspace, "exports", "usage")) {
+		t.Fatalf("export path %q should be inside the workspace", path)
+	}
+
+	rows := readCSV(t, path)
+	want := []string{"timestamp", "day_key", "session_key", "provider", "model", "prompt_tokens", "completion_tokens", "total_tokens", "usage_known", "reason", "cost_usd"}
+	if !reflect.DeepEqual(rows[0], want) {
+		t.Fatalf("header = %v, want %v", rows[0], want)
+	}
+	if len(rows) != 4 {
+		t.Fatalf("rows = %d, want header + 3 records", len(rows))
+	}
+	if rows[1][7] != "150" || rows[1][8] != "true" {
+		t.Fatalf("first record = %v, want total_tokens=150 usage_known=true", rows[1])
+	}
+}
+
+func TestUsageExportTool_AppliesFilters(t *testing.T) {
+	tool, _ := newUsageExportTestTool(t)
+
+	rows := readCSV(t, exportPath(t, tool.Execute(context.Background(), map[string]interface{}{
+		"session_key": "telegram:1",
+		"since_days":  float64(1),
+	})))
+	if len(rows) != 2 || rows[1][1] != "2026-03-10" || rows[1][2] != "telegram:1" {
+		t.Fatalf("session+since filter rows = %v", rows)
+	}
+
+	path := exportPath(t, tool.Execute(context.Background(), map[string]interface{}{
+		"format":  "json",
+		"day_key": "2026-03-10",
+	}))
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read json export: %v", err)
+	}
+	var records []usage.Record
+	if err := json.Unmarshal(data, &records); err != nil {
+		t.Fatalf("parse json export: %v", err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("day filter records = %d, want 2", len(records))
+	}
+}
+
+func TestUsageExportTool_RejectsUnknownFormat(t *testing.T) {
+	tool, _ := newUsageExportTestTool(t)
+
+	if res := tool.Execute(context.Background(), map[string]interface{}{"format": "xlsx"}); !res.IsError {
+		t.Fatal("expected error for unsupported format")
+	}
+}
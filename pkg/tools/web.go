@@ -218,6 +218,11 @@ func (t *WebSearchTool) Name() string {
 	return "web_search"
 }
 
+// ParallelSafe reports true: web_search only reads.
+func (t *WebSearchTool) ParallelSafe() bool {
+	return true
+}
+
 func (t *WebSearchTool) Description() string {
 	return "Search the web for current information. Returns titles, URLs, and snippets from search results."
 }
@@ -282,6 +287,11 @@ func (t *WebFetchTool) Name() string {
 	return "web_fetch"
 }
 
+// ParallelSafe reports true: web_fetch only reads.
+func (t *WebFetchTool) ParallelSafe() bool {
+	return true
+}
+
 func (t *WebFetchTool) Description() string {
 	return "Fetch a URL and extract readable content (HTML to text). Use this to get weather info, news, articles, or any web content."
 }
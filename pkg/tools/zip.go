@@ -0,0 +1,263 @@
+package tools
+
+import (
+	"archive/zip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// defaultZipMaxBytes caps the total uncompressed size of files in one archive.
+const defaultZipMaxBytes int64 = 50 * 1024 * 1024
+
+// ZipFilesTool bundles workspace files into a single zip archive so they can
+// be sent with send_file in one go.
+type ZipFilesTool struct {
+	workspace string
+	maxBytes  int64
+}
+
+func NewZipFilesTool(workspace string) *ZipFilesTool {
+	return &ZipFilesTool{
+		workspace: workspace,
+		maxBytes:  defaultZipMaxBytes,
+	}
+}
+
+func (t *ZipFilesTool) Name() string {
+	return "zip_files"
+}
+
+func (t *ZipFilesTool) Description() string {
+	return fmt.Sprintf("Archive a list of workspace files into a single zip file in the workspace (max %d MB total), e.g. before sending them with send_file", t.maxBytes/(1024*1024))
+}
+
+func (t *ZipFilesTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"files": map[string]interface{}{
+				"type":        "array",
+				"items":       map[string]interface{}{"type": "string"},
+				"description": "Workspace file paths to include",
+			},
+			"output": map[string]interface{}{
+				"type":        "string",
+				"description": "Optional archive path in the workspace (default: exports/archive-<timestamp>.zip)",
+			},
+			"overwrite": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Overwrite the archive if it already exists",
+			},
+		},
+		"required": []string{"files"},
+	}
+}
+
+func (t *ZipFilesTool) Execute(ctx context.Context, args map[string]interface{}) *ToolResult {
+	filesRaw, ok := args["files"].([]interface{})
+	if !ok || len(filesRaw) == 0 {
+		return ErrorResult("files must be a non-empty array of file paths")
+	}
+	overwrite, _ := args["overwrite"].(bool)
+
+	absWorkspace, err := filepath.Abs(t.workspace)
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("failed to resolve workspace path: %v", err))
+	}
+	realWorkspace, err := filepath.EvalSymlinks(absWorkspace)
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("failed to resolve workspace path: %v", err))
+	}
+
+	// Archives only ever contain workspace files, regardless of the
+	// restrict_to_workspace setting.
+	var sources []string
+	seen := make(map[string]bool)
+	var total int64
+	for _, raw := range filesRaw {
+		p, ok := raw.(string)
+		if !ok || p == "" {
+			return ErrorResult("files must contain only non-empty strings")
+		}
+		resolved, err := t.resolveInWorkspace(p, absWorkspace)
+		if err != nil {
+			return ErrorResult(err.Error())
+		}
+		if seen[resolved] {
+			continue
+		}
+		info, err := os.Stat(resolved)
+		if err != nil {
+			return ErrorResult(fmt.Sprintf("file not found: %s", p))
+		}
+		// Don't follow symlinks out of the workspace.
+		if real, err := filepath.EvalSymlinks(resolved); err != nil || !isWithinDir(real, realWorkspace) {
+			return ErrorResult(fmt.Sprintf("access denied: %s resolves outside the workspace", p))
+		}
+		if info.IsDir() {
+			return ErrorResult(fmt.Sprintf("path is a directory, not a file: %s", p))
+		}
+		total += info.Size()
+		if total > t.maxBytes {
+			return ErrorResult(fmt.Sprintf("files exceed the %d byte archive limit", t.maxBytes))
+		}
+		seen[resolved] = true
+		sources = append(sources, resolved)
+	}
+
+	output, _ := args["output"].(string)
+	if output == "" {
+		output = filepath.Join("exports", fmt.Sprintf("archive-%s.zip", time.Now().Format("20060102-150405")))
+	}
+	if !strings.HasSuffix(strings.ToLower(output), ".zip") {
+		output += ".zip"
+	}
+	outPath, err := t.resolveInWorkspace(output, absWorkspace)
+	if err != nil {
+		return ErrorResult(err.Error())
+	}
+	if seen[outPath] {
+		return ErrorResult("output archive cannot also be an input file")
+	}
+	if _, err := os.Stat(outPath); err == nil && !overwrite {
+		return ErrorResult("output already exists; set overwrite=true to replace it")
+	}
+	// Don't write through a symlinked directory out of the workspace; check
+	// before creating missing directories so none are made outside it.
+	if !dirWithinWorkspace(filepath.Dir(outPath), realWorkspace) {
+		return ErrorResult(fmt.Sprintf("access denied: %s resolves outside the workspace", output))
+	}
+	if err := os.MkdirAll(filepath.Dir(outPath), 0755); err != nil {
+		return ErrorResult(fmt.Sprintf("failed to create output directory: %v", err))
+	}
+	if !dirWithinWorkspace(filepath.Dir(outPath), realWorkspace) {
+		return ErrorResult(fmt.Sprintf("access denied: %s resolves outside the workspace", output))
+	}
+
+	if err := writeZip(ctx, outPath, absWorkspace, sources); err != nil {
+		return ErrorResult(fmt.Sprintf("failed to create archive: %v", err)).WithError(err)
+	}
+
+	info, err := os.Stat(outPath)
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("failed to stat archive: %v", err))
+	}
+	return SilentResult(fmt.Sprintf("Archive created: %s (%d files, %d bytes)", outPath, len(sources), info.Size()))
+}
+
+// resolveInWorkspace resolves p against the workspace and rejects anything
+// that lands outside it.
+func (t *ZipFilesTool) resolveInWorkspace(p, absWorkspace string) (string, error) {
+	resolved, err := validatePath(p, absWorkspace, true)
+	if err != nil {
+		return "", err
+	}
+	if !isWithinDir(resolved, absWorkspace) {
+		return "", fmt.Errorf("access denied: path is outside the workspace")
+	}
+	return resolved, nil
+}
+
+// isWithinDir reports whether path is strictly inside dir.
+func isWithinDir(path, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil || rel == "." {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// dirWithinWorkspace reports whether dir, with symlinks resolved, is the
+// workspace or inside it. A missing dir is judged by its nearest existing
+// ancestor.
+func dirWithinWorkspace(dir, realWorkspace string) bool {
+	for {
+		real, err := filepath.EvalSymlinks(dir)
+		if err == nil {
+			return real == realWorkspace || isWithinDir(real, realWorkspace)
+		}
+		parent := filepath.Dir(dir)
+		if !os.IsNotExist(err) || parent == dir {
+			return false
+		}
+		dir = parent
+	}
+}
+
+// writeZip writes sources into a zip at outPath via a temp file, using
+// workspace-relative entry names.
+func writeZip(ctx context.Context, outPath, absWorkspace string, sources []string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(outPath), ".zip-*.tmp")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	cleanup := true
+	defer func() {
+		if cleanup {
+			_ = os.Remove(tmpPath)
+		}
+	}()
+
+	zw := zip.NewWriter(tmp)
+	for _, src := range sources {
+		if err := ctx.Err(); err != nil {
+			_ = zw.Close()
+			_ = tmp.Close()
+			return err
+		}
+		rel, _ := filepath.Rel(absWorkspace, src)
+		if err := addZipEntry(zw, src, filepath.ToSlash(rel)); err != nil {
+			_ = zw.Close()
+			_ = tmp.Close()
+			return err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(0644); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, outPath); err != nil {
+		return err
+	}
+	cleanup = false
+	return nil
+}
+
+func addZipEntry(zw *zip.Writer, src, name string) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	header, err := zip.FileInfoHeader(info)
+	if err != nil {
+		return err
+	}
+	header.Name = name
+	header.Method = zip.Deflate
+
+	w, err := zw.CreateHeader(header)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, f)
+	return err
+}
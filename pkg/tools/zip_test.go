@@ -0,0 +1,121 @@
+package tools
+
+import (
+	"archive/zip"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestZipFilesTool_CreatesArchive(t *testing.T) {
+	workspace := t.TempDir()
+	os.MkdirAll(filepath.Join(workspace, "out"), 0755)
+	os.WriteFile(filepath.Join(workspace, "out", "a.txt"), []byte("alpha"), 0644)
+	os.WriteFile(filepath.Join(workspace, "b.csv"), []byte("x,y\n1,2\n"), 0644)
+
+	tool := NewZipFilesTool(workspace)
+	result := tool.Execute(context.Background(), map[string]interface{}{
+		"files":  []interface{}{"out/a.txt", filepath.Join(workspace, "b.csv"), "out/a.txt"},
+		"output": "bundle",
+	})
+	if result.IsError {
+		t.Fatalf("zip_files failed: %s", result.ForLLM)
+	}
+
+	archive := filepath.Join(workspace, "bundle.zip")
+	zr, err := zip.OpenReader(archive)
+	if err != nil {
+		t.Fatalf("open archive: %v", err)
+	}
+	defer zr.Close()
+
+	got := map[string]string{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("open entry %s: %v", f.Name, err)
+		}
+		data, _ := io.ReadAll(rc)
+		rc.Close()
+		got[f.Name] = string(data)
+	}
+	if len(got) != 2 || got["out/a.txt"] != "alpha" || got["b.csv"] != "x,y\n1,2\n" {
+		t.Fatalf("archive entries = %v", got)
+	}
+
+	// A second run without overwrite must not clobber the archive.
+	again := tool.Execute(context.Background(), map[string]interface{}{
+		"files":  []interface{}{"b.csv"},
+		"output": "bundle.zip",
+	})
+	if !again.IsError || !strings.Contains(again.ForLLM, "overwrite") {
+		t.Fatalf("expected overwrite error, got: %s", again.ForLLM)
+	}
+}
+
+func TestZipFilesTool_RejectsPathTraversal(t *testing.T) {
+	root := t.TempDir()
+	workspace := filepath.Join(root, "ws")
+	os.MkdirAll(workspace, 0755)
+	os.WriteFile(filepath.Join(root, "secret.txt"), []byte("secret"), 0644)
+	os.WriteFile(filepath.Join(workspace, "ok.txt"), []byte("ok"), 0644)
+
+	tool := NewZipFilesTool(workspace)
+	cases := []map[string]interface{}{
+		{"files": []interface{}{"../secret.txt"}},
+		{"files": []interface{}{filepath.Join(root, "secret.txt")}},
+		{"files": []interface{}{"ok.txt"}, "output": "../escape.zip"},
+	}
+	for _, args := range cases {
+		if result := tool.Execute(context.Background(), args); !result.IsError {
+			t.Errorf("expected rejection for %v, got: %s", args, result.ForLLM)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(root, "escape.zip")); err == nil {
+		t.Fatal("archive must not be written outside the workspace")
+	}
+
+	if err := os.Symlink(filepath.Join(root, "secret.txt"), filepath.Join(workspace, "link.txt")); err == nil {
+		if result := tool.Execute(context.Background(), map[string]interface{}{"files": []interface{}{"link.txt"}}); !result.IsError {
+			t.Errorf("expected symlink escaping the workspace to be rejected, got: %s", result.ForLLM)
+		}
+	}
+}
+
+func TestZipFilesTool_SizeCap(t *testing.T) {
+	workspace := t.TempDir()
+	os.WriteFile(filepath.Join(workspace, "big.bin"), make([]byte, 2048), 0644)
+
+	tool := NewZipFilesTool(workspace)
+	tool.maxBytes = 1024
+	result := tool.Execute(context.Background(), map[string]interface{}{"files": []interface{}{"big.bin"}})
+	if !result.IsError || !strings.Contains(result.ForLLM, "limit") {
+		t.Fatalf("expected size cap error, got: %s", result.ForLLM)
+	}
+}
+
+func TestZipFilesTool_RejectsSymlinkedOutputDir(t *testing.T) {
+	workspace := t.TempDir()
+	outside := t.TempDir()
+	os.WriteFile(filepath.Join(workspace, "a.txt"), []byte("alpha"), 0644)
+	if err := os.Symlink(outside, filepath.Join(workspace, "exports")); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+
+	tool := NewZipFilesTool(workspace)
+	for _, output := range []string{"exports/bundle.zip", "exports/new/bundle.zip"} {
+		result := tool.Execute(context.Background(), map[string]interface{}{
+			"files":  []interface{}{"a.txt"},
+			"output": output,
+		})
+		if !result.IsError || !strings.Contains(result.ForLLM, "outside the workspace") {
+			t.Errorf("output %s through a symlink = %q, want access denied", output, result.ForLLM)
+		}
+	}
+	if entries, _ := os.ReadDir(outside); len(entries) != 0 {
+		t.Errorf("files were created outside the workspace: %v", entries)
+	}
+}
@@ -0,0 +1,67 @@
+package usage
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+	"time"
+)
+
+// CSVHeader lists the columns written by WriteCSV, in order.
+var CSVHeader = []string{
+	"timestamp",
+	"day_key",
+	"session_key",
+	"provider",
+	"model",
+	"prompt_tokens",
+	"completion_tokens",
+	"total_tokens",
+	"usage_known",
+	"reason",
+	"cost_usd",
+}
+
+// WriteCSV writes records as CSV with a CSVHeader row. cost_usd is empty
+// when no price was configured for the record's provider.
+func WriteCSV(w io.Writer, records []Record) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(CSVHeader); err != nil {
+		return err
+	}
+	for _, r := range records {
+		row := []string{
+			r.Timestamp.UTC().Format(time.RFC3339),
+			r.DayKey,
+			r.SessionKey,
+			r.Provider,
+			r.Model,
+			strconv.Itoa(r.PromptTokens),
+			strconv.Itoa(r.CompletionTokens),
+			strconv.Itoa(r.TotalTokens),
+			strconv.FormatBool(r.UsageKnown),
+			r.Reason,
+			"",
+		}
+		if r.CostKnown {
+			row[len(row)-1] = strconv.FormatFloat(r.CostUSD, 'f', 6, 64)
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteJSON writes records as an indented JSON array, the same shape as the
+// store's usage.json.
+func WriteJSON(w io.Writer, records []Record) error {
+	if records == nil {
+		records = []Record{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(records)
+}
@@ -41,6 +41,15 @@ func GroupedInt(n int) string {
 	return b.String()
 }
 
+// FormatUSD formats a dollar amount, keeping sub-cent precision for small
+// values so cheap models don't all show as $0.00.
+func FormatUSD(v float64) string {
+	if v != 0 && v < 1 {
+		return fmt.Sprintf("$%.4f", v)
+	}
+	return fmt.Sprintf("$%.2f", v)
+}
+
 func formatScaled(value float64, suffix string) string {
 	s := fmt.Sprintf("%.1f", value)
 	s = strings.TrimSuffix(s, ".0")
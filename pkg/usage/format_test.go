@@ -42,3 +42,21 @@ func TestGroupedInt(t *testing.T) {
 		}
 	}
 }
+
+func TestFormatUSD(t *testing.T) {
+	tests := []struct {
+		in   float64
+		want string
+	}{
+		{0, "$0.00"},
+		{0.00123, "$0.0012"},
+		{1.5, "$1.50"},
+		{42.426, "$42.43"},
+	}
+
+	for _, tc := range tests {
+		if got := FormatUSD(tc.in); got != tc.want {
+			t.Fatalf("FormatUSD(%v)=%q want %q", tc.in, got, tc.want)
+		}
+	}
+}
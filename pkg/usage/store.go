@@ -1,6 +1,7 @@
 package usage
 
 import (
+	"bufio"
 	"encoding/json"
 	"os"
 	"path/filepath"
@@ -20,12 +21,15 @@ type Record struct {
 	TotalTokens      int       `json:"total_tokens"`
 	UsageKnown       bool      `json:"usage_known"`
 	Reason           string    `json:"reason"`
+	CostUSD          float64   `json:"cost_usd,omitempty"`
+	CostKnown        bool      `json:"cost_known"`
 }
 
 type Filter struct {
 	SessionKey string
 	DayKey     string
 	Provider   string
+	Since      time.Time // zero = no lower bound
 	Limit      int
 }
 
@@ -36,47 +40,179 @@ type Aggregate struct {
 	PromptTokens     int
 	CompletionTokens int
 	TotalTokens      int
+	CostUSD          float64
+	UnknownCostCalls int // calls with no usage or no configured price
+}
+
+// Price is the cost of a model in USD per million tokens.
+type Price struct {
+	InputPerMTok  float64
+	OutputPerMTok float64
+}
+
+// Configured reports whether any price has been set.
+func (p Price) Configured() bool {
+	return p.InputPerMTok > 0 || p.OutputPerMTok > 0
+}
+
+// Cost returns the USD cost of the given token counts.
+func (p Price) Cost(promptTokens, completionTokens int) float64 {
+	return (float64(promptTokens)*p.InputPerMTok + float64(completionTokens)*p.OutputPerMTok) / 1_000_000
 }
 
 type Store struct {
-	mu      sync.RWMutex
-	records []Record
-	path    string
+	mu            sync.RWMutex
+	records       []Record
+	dir           string
+	prices        map[string]Price // keyed by lowercase provider name
+	loc           *time.Location   // timezone for day-key bucketing
+	retentionDays int              // 0 = keep forever
+	lastDayKey    string           // day of the newest shard written, to prune on rollover
+
+	// With a flush interval, records are queryable immediately but written
+	// in batches; pending holds records not yet on disk.
+	flushInterval time.Duration
+	pending       []Record
+	stopFlush     chan struct{}
 }
 
-func NewStore(workspace string) *Store {
+// maxPendingRecords forces a flush before the interval elapses so a burst
+// of calls can't hold an unbounded number of records in memory only.
+const maxPendingRecords = 200
+
+// NewStore creates a store persisted under dir. Records are appended to one
+// JSON-lines file per day so each Add writes a single line instead of
+// rewriting the whole history. Day keys are bucketed in loc; nil means the
+// system local zone.
+func NewStore(dir string, loc *time.Location) *Store {
+	if loc == nil {
+		loc = time.Local
+	}
 	s := &Store{
 		records: make([]Record, 0, 256),
+		loc:     loc,
 	}
-	if workspace == "" {
+	if dir == "" {
 		return s
 	}
-	_ = os.MkdirAll(workspace, 0755)
-	s.path = filepath.Join(workspace, "usage.json")
+	_ = os.MkdirAll(dir, 0755)
+	s.dir = dir
 	s.load()
 	return s
 }
 
+// SetRetentionDays drops records (and their day files) older than the given
+// number of days, now and whenever a new day starts. 0 keeps everything.
+func (s *Store) SetRetentionDays(days int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.retentionDays = days
+	s.pruneLocked(time.Now())
+}
+
+// SetFlushInterval batches writes: records are persisted every d (or once
+// maxPendingRecords accumulate) instead of on every Add, so at most d worth
+// of records is lost on a crash. 0 writes each record synchronously. Call
+// Close on shutdown to flush.
+func (s *Store) SetFlushInterval(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.stopFlush != nil {
+		close(s.stopFlush)
+		s.stopFlush = nil
+	}
+	s.flushInterval = d
+	if d <= 0 {
+		s.flushLocked()
+		return
+	}
+	if s.dir != "" {
+		s.stopFlush = make(chan struct{})
+		go s.flushLoop(d, s.stopFlush)
+	}
+}
+
+func (s *Store) flushLoop(interval time.Duration, stop chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			s.Flush()
+		}
+	}
+}
+
+// Flush writes any buffered records to disk.
+func (s *Store) Flush() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.flushLocked()
+}
+
+// Close stops background flushing and writes buffered records. Records
+// added afterwards are written synchronously.
+func (s *Store) Close() {
+	s.SetFlushInterval(0)
+}
+
+// DayKey returns the YYYY-MM-DD bucket for t in the store's timezone.
+func (s *Store) DayKey(t time.Time) string {
+	return t.In(s.loc).Format("2006-01-02")
+}
+
 func (s *Store) TodayKey() string {
-	return time.Now().UTC().Format("2006-01-02")
+	return s.DayKey(time.Now())
+}
+
+// SetPrices sets the per-provider price table used to compute Record.CostUSD.
+// Keys are provider names as recorded in Record.Provider (case-insensitive).
+func (s *Store) SetPrices(prices map[string]Price) {
+	table := make(map[string]Price, len(prices))
+	for name, p := range prices {
+		if p.Configured() {
+			table[strings.ToLower(strings.TrimSpace(name))] = p
+		}
+	}
+	s.mu.Lock()
+	s.prices = table
+	s.mu.Unlock()
 }
 
 func (s *Store) Add(r Record) {
+	if r.Timestamp.IsZero() {
+		r.Timestamp = time.Now().UTC()
+	}
 	if r.DayKey == "" {
-		r.DayKey = s.TodayKey()
+		r.DayKey = s.DayKey(r.Timestamp)
 	}
 	if r.TotalTokens == 0 {
 		r.TotalTokens = r.PromptTokens + r.CompletionTokens
 	}
-	if r.Timestamp.IsZero() {
-		r.Timestamp = time.Now().UTC()
-	}
 
 	s.mu.Lock()
+	defer s.mu.Unlock()
+	if price, ok := s.prices[strings.ToLower(r.Provider)]; ok && r.UsageKnown {
+		r.CostUSD = price.Cost(r.PromptTokens, r.CompletionTokens)
+		r.CostKnown = true
+	}
 	s.records = append(s.records, r)
-	s.mu.Unlock()
 
-	s.save()
+	if r.DayKey > s.lastDayKey {
+		s.lastDayKey = r.DayKey
+		s.pruneLocked(r.Timestamp)
+	}
+	if s.flushInterval <= 0 {
+		s.appendLocked(r)
+		return
+	}
+	s.pending = append(s.pending, r)
+	if len(s.pending) >= maxPendingRecords {
+		s.flushLocked()
+	}
 }
 
 func (s *Store) LastBySession(sessionKey string) (Record, bool) {
@@ -106,6 +242,9 @@ func (s *Store) Query(f Filter) []Record {
 		if f.Provider != "" && strings.ToLower(r.Provider) != strings.ToLower(f.Provider) {
 			continue
 		}
+		if !f.Since.IsZero() && r.Timestamp.Before(f.Since) {
+			continue
+		}
 		out = append(out, r)
 	}
 	if f.Limit > 0 && len(out) > f.Limit {
@@ -114,18 +253,27 @@ func (s *Store) Query(f Filter) []Record {
 	return out
 }
 
+func (agg *Aggregate) add(r Record) {
+	agg.Calls++
+	if r.UsageKnown {
+		agg.KnownCalls++
+		agg.PromptTokens += r.PromptTokens
+		agg.CompletionTokens += r.CompletionTokens
+		agg.TotalTokens += r.TotalTokens
+	} else {
+		agg.UnknownCalls++
+	}
+	if r.CostKnown {
+		agg.CostUSD += r.CostUSD
+	} else {
+		agg.UnknownCostCalls++
+	}
+}
+
 func AggregateRecords(records []Record) Aggregate {
 	var agg Aggregate
 	for _, r := range records {
-		agg.Calls++
-		if r.UsageKnown {
-			agg.KnownCalls++
-			agg.PromptTokens += r.PromptTokens
-			agg.CompletionTokens += r.CompletionTokens
-			agg.TotalTokens += r.TotalTokens
-		} else {
-			agg.UnknownCalls++
-		}
+		agg.add(r)
 	}
 	return agg
 }
@@ -138,25 +286,74 @@ func ProviderBreakdown(records []Record) map[string]Aggregate {
 			p = "unknown"
 		}
 		agg := out[p]
-		agg.Calls++
-		if r.UsageKnown {
-			agg.KnownCalls++
-			agg.PromptTokens += r.PromptTokens
-			agg.CompletionTokens += r.CompletionTokens
-			agg.TotalTokens += r.TotalTokens
-		} else {
-			agg.UnknownCalls++
-		}
+		agg.add(r)
 		out[p] = agg
 	}
 	return out
 }
 
+// legacyFile is the single-file format used before day sharding.
+const legacyFile = "usage.json"
+
+func shardFile(dayKey string) string {
+	return "usage-" + dayKey + ".jsonl"
+}
+
+// shardDay returns the day key of a shard file name, or "" if name is not
+// a shard.
+func shardDay(name string) string {
+	if !strings.HasPrefix(name, "usage-") || !strings.HasSuffix(name, ".jsonl") {
+		return ""
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(name, "usage-"), ".jsonl")
+}
+
+// load reads all day shards in date order, migrating a legacy usage.json
+// into shards first.
 func (s *Store) load() {
-	if s.path == "" {
+	s.migrateLegacy()
+
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
 		return
 	}
-	data, err := os.ReadFile(s.path)
+	// ReadDir sorts by name, and shard names sort by day.
+	for _, e := range entries {
+		day := shardDay(e.Name())
+		if day == "" {
+			continue
+		}
+		s.records = append(s.records, readShard(filepath.Join(s.dir, e.Name()))...)
+		if day > s.lastDayKey {
+			s.lastDayKey = day
+		}
+	}
+}
+
+// readShard parses one JSON record per line. Unparseable lines (e.g. a
+// write cut short by a crash) are skipped.
+func readShard(path string) []Record {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	var records []Record
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var r Record
+		if err := json.Unmarshal(scanner.Bytes(), &r); err == nil {
+			records = append(records, r)
+		}
+	}
+	return records
+}
+
+func (s *Store) migrateLegacy() {
+	legacyPath := filepath.Join(s.dir, legacyFile)
+	data, err := os.ReadFile(legacyPath)
 	if err != nil {
 		return
 	}
@@ -164,21 +361,95 @@ func (s *Store) load() {
 	if err := json.Unmarshal(data, &records); err != nil {
 		return
 	}
-	s.records = records
+	for _, r := range records {
+		if r.DayKey == "" {
+			r.DayKey = s.DayKey(r.Timestamp)
+		}
+		if !s.appendLocked(r) {
+			return // keep the legacy file so nothing is lost
+		}
+	}
+	_ = os.Rename(legacyPath, legacyPath+".migrated")
 }
 
-func (s *Store) save() {
-	if s.path == "" {
+// appendLocked writes r as one line to its day's shard. Caller must hold
+// s.mu (or be the constructor).
+func (s *Store) appendLocked(r Record) bool {
+	return s.writeShard(r.DayKey, []Record{r})
+}
+
+// flushLocked writes pending records with one write per day shard. Records
+// whose write fails stay pending for the next flush. Caller must hold s.mu.
+func (s *Store) flushLocked() {
+	if len(s.pending) == 0 {
 		return
 	}
-	s.mu.RLock()
-	snapshot := make([]Record, len(s.records))
-	copy(snapshot, s.records)
-	s.mu.RUnlock()
+	byDay := make(map[string][]Record)
+	var days []string
+	for _, r := range s.pending {
+		if _, ok := byDay[r.DayKey]; !ok {
+			days = append(days, r.DayKey)
+		}
+		byDay[r.DayKey] = append(byDay[r.DayKey], r)
+	}
+
+	var failed []Record
+	for _, day := range days {
+		if !s.writeShard(day, byDay[day]) {
+			failed = append(failed, byDay[day]...)
+		}
+	}
+	s.pending = failed
+}
 
-	data, err := json.MarshalIndent(snapshot, "", "  ")
+// writeShard appends records to the day's shard file in a single write.
+func (s *Store) writeShard(dayKey string, records []Record) bool {
+	if s.dir == "" {
+		return true
+	}
+	var buf []byte
+	for _, r := range records {
+		line, err := json.Marshal(r)
+		if err != nil {
+			return false
+		}
+		buf = append(append(buf, line...), '\n')
+	}
+	f, err := os.OpenFile(filepath.Join(s.dir, shardFile(dayKey)), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
 	if err != nil {
+		return false
+	}
+	defer f.Close()
+	_, err = f.Write(buf)
+	return err == nil
+}
+
+// pruneLocked drops records and shard files older than the retention
+// window. Caller must hold s.mu.
+func (s *Store) pruneLocked(now time.Time) {
+	if s.retentionDays <= 0 {
 		return
 	}
-	_ = os.WriteFile(s.path, data, 0644)
+	cutoff := s.DayKey(now.AddDate(0, 0, -s.retentionDays))
+
+	kept := s.records[:0]
+	for _, r := range s.records {
+		if r.DayKey >= cutoff {
+			kept = append(kept, r)
+		}
+	}
+	s.records = kept
+
+	if s.dir == "" {
+		return
+	}
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return
+	}
+	for _, e := range entries {
+		if day := shardDay(e.Name()); day != "" && day < cutoff {
+			_ = os.Remove(filepath.Join(s.dir, e.Name()))
+		}
+	}
 }
@@ -0,0 +1,257 @@
+package usage
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func approxEqual(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestAdd_ComputesCostFromPriceTable(t *testing.T) {
+	s := NewStore("", nil)
+	s.SetPrices(map[string]Price{
+		"OpenAI":    {InputPerMTok: 2.5, OutputPerMTok: 10},
+		"anthropic": {},
+	})
+
+	s.Add(Record{Provider: "openai", PromptTokens: 1_000_000, CompletionTokens: 500_000, UsageKnown: true})
+	s.Add(Record{Provider: "anthropic", PromptTokens: 1000, CompletionTokens: 1000, UsageKnown: true})
+	s.Add(Record{Provider: "openai", UsageKnown: false})
+
+	records := s.Query(Filter{})
+	if !records[0].CostKnown || !approxEqual(records[0].CostUSD, 7.5) {
+		t.Fatalf("openai cost = %v (known=%t), want 7.5", records[0].CostUSD, records[0].CostKnown)
+	}
+	if records[1].CostKnown {
+		t.Fatal("zero price should be treated as unconfigured")
+	}
+	if records[2].CostKnown {
+		t.Fatal("records without known usage should not be priced")
+	}
+}
+
+func TestAggregateRecords_SumsCostAndCountsUnpriced(t *testing.T) {
+	records := []Record{
+		{Provider: "openai", PromptTokens: 100, CompletionTokens: 50, TotalTokens: 150, UsageKnown: true, CostUSD: 0.25, CostKnown: true},
+		{Provider: "openai", PromptTokens: 200, CompletionTokens: 100, TotalTokens: 300, UsageKnown: true, CostUSD: 0.5, CostKnown: true},
+		{Provider: "deepseek", PromptTokens: 10, CompletionTokens: 10, TotalTokens: 20, UsageKnown: true},
+		{Provider: "openai"},
+	}
+
+	agg := AggregateRecords(records)
+	if !approxEqual(agg.CostUSD, 0.75) {
+		t.Fatalf("CostUSD = %v, want 0.75", agg.CostUSD)
+	}
+	if agg.UnknownCostCalls != 2 || agg.UnknownCalls != 1 {
+		t.Fatalf("UnknownCostCalls = %d, UnknownCalls = %d; want 2, 1", agg.UnknownCostCalls, agg.UnknownCalls)
+	}
+	if agg.TotalTokens != 470 {
+		t.Fatalf("TotalTokens = %d, want 470", agg.TotalTokens)
+	}
+
+	byProvider := ProviderBreakdown(records)
+	if got := byProvider["openai"]; !approxEqual(got.CostUSD, 0.75) || got.UnknownCostCalls != 1 {
+		t.Fatalf("openai breakdown = %+v", got)
+	}
+	if got := byProvider["deepseek"]; got.CostUSD != 0 || got.UnknownCostCalls != 1 {
+		t.Fatalf("deepseek breakdown = %+v", got)
+	}
+}
+
+func TestDayKey_UsesStoreLocation(t *testing.T) {
+	ist, err := time.LoadLocation("Asia/Kolkata")
+	if err != nil {
+		t.Skipf("tzdata unavailable: %v", err)
+	}
+	// 20:00 UTC on Mar 10 is already Mar 11 in IST.
+	ts := time.Date(2026, 3, 10, 20, 0, 0, 0, time.UTC)
+
+	if got := NewStore("", time.UTC).DayKey(ts); got != "2026-03-10" {
+		t.Fatalf("UTC DayKey = %s, want 2026-03-10", got)
+	}
+
+	s := NewStore("", ist)
+	if got := s.DayKey(ts); got != "2026-03-11" {
+		t.Fatalf("IST DayKey = %s, want 2026-03-11", got)
+	}
+	s.Add(Record{Timestamp: ts, Provider: "openai"})
+	if got := s.Query(Filter{DayKey: "2026-03-11"}); len(got) != 1 {
+		t.Fatalf("record should be bucketed by store timezone, got %d matches", len(got))
+	}
+}
+
+func TestNewStore_NilLocationUsesLocal(t *testing.T) {
+	ts := time.Date(2026, 3, 10, 23, 30, 0, 0, time.UTC)
+	if got, want := NewStore("", nil).DayKey(ts), ts.In(time.Local).Format("2006-01-02"); got != want {
+		t.Fatalf("DayKey = %s, want %s", got, want)
+	}
+}
+
+func TestStore_PersistsAcrossReloadInDayShards(t *testing.T) {
+	dir := t.TempDir()
+	s := NewStore(dir, time.UTC)
+	s.Add(Record{Timestamp: time.Date(2026, 3, 10, 9, 0, 0, 0, time.UTC), SessionKey: "a", Provider: "openai", PromptTokens: 10, UsageKnown: true})
+	s.Add(Record{Timestamp: time.Date(2026, 3, 11, 9, 0, 0, 0, time.UTC), SessionKey: "a", Provider: "openai", PromptTokens: 20, UsageKnown: true})
+
+	for _, day := range []string{"2026-03-10", "2026-03-11"} {
+		if _, err := os.Stat(filepath.Join(dir, shardFile(day))); err != nil {
+			t.Fatalf("missing shard for %s: %v", day, err)
+		}
+	}
+
+	reloaded := NewStore(dir, time.UTC)
+	if got := reloaded.Query(Filter{}); len(got) != 2 || got[1].PromptTokens != 20 {
+		t.Fatalf("reloaded records = %+v", got)
+	}
+	if last, ok := reloaded.LastBySession("a"); !ok || last.DayKey != "2026-03-11" {
+		t.Fatalf("LastBySession = %+v, want the newest record", last)
+	}
+}
+
+func TestStore_AppendDoesNotRewriteHistory(t *testing.T) {
+	dir := t.TempDir()
+	s := NewStore(dir, time.UTC)
+	old := time.Date(2026, 3, 10, 9, 0, 0, 0, time.UTC)
+	for i := 0; i < 5000; i++ {
+		s.Add(Record{Timestamp: old, Provider: "openai"})
+	}
+	oldShard := filepath.Join(dir, shardFile("2026-03-10"))
+	before, _ := os.Stat(oldShard)
+
+	today := time.Date(2026, 3, 11, 9, 0, 0, 0, time.UTC)
+	s.Add(Record{Timestamp: today, Provider: "openai"})
+	s.Add(Record{Timestamp: today, Provider: "openai"})
+
+	after, _ := os.Stat(oldShard)
+	if after.Size() != before.Size() || !after.ModTime().Equal(before.ModTime()) {
+		t.Fatal("appending to a new day must not touch earlier shards")
+	}
+	line, _ := json.Marshal(Record{Timestamp: today, DayKey: "2026-03-11", Provider: "openai"})
+	newShard, _ := os.Stat(filepath.Join(dir, shardFile("2026-03-11")))
+	if newShard.Size() != int64(2*(len(line)+1)) {
+		t.Fatalf("new shard is %d bytes, want exactly two appended lines (%d)", newShard.Size(), 2*(len(line)+1))
+	}
+}
+
+func TestStore_MigratesLegacyFile(t *testing.T) {
+	dir := t.TempDir()
+	legacy := []Record{
+		{Timestamp: time.Date(2026, 3, 10, 9, 0, 0, 0, time.UTC), DayKey: "2026-03-10", Provider: "openai", TotalTokens: 5},
+		{Timestamp: time.Date(2026, 3, 11, 9, 0, 0, 0, time.UTC), DayKey: "2026-03-11", Provider: "openai", TotalTokens: 7},
+	}
+	data, _ := json.Marshal(legacy)
+	os.WriteFile(filepath.Join(dir, legacyFile), data, 0644)
+
+	s := NewStore(dir, time.UTC)
+	if got := s.Query(Filter{DayKey: "2026-03-11"}); len(got) != 1 || got[0].TotalTokens != 7 {
+		t.Fatalf("migrated records = %+v", got)
+	}
+	if _, err := os.Stat(filepath.Join(dir, legacyFile)); !os.IsNotExist(err) {
+		t.Fatal("legacy file should be moved aside after migration")
+	}
+	if got := NewStore(dir, time.UTC).Query(Filter{}); len(got) != 2 {
+		t.Fatalf("records after second load = %d, want 2 (no double migration)", len(got))
+	}
+}
+
+func TestStore_RetentionPrunesOldDays(t *testing.T) {
+	dir := t.TempDir()
+	s := NewStore(dir, time.UTC)
+	now := time.Now().UTC()
+	s.Add(Record{Timestamp: now.AddDate(0, 0, -40), Provider: "openai"})
+	s.Add(Record{Timestamp: now.AddDate(0, 0, -1), Provider: "openai"})
+
+	s.SetRetentionDays(30)
+	if got := s.Query(Filter{}); len(got) != 1 {
+		t.Fatalf("records after pruning = %d, want 1", len(got))
+	}
+	if _, err := os.Stat(filepath.Join(dir, shardFile(s.DayKey(now.AddDate(0, 0, -40))))); !os.IsNotExist(err) {
+		t.Fatal("expired shard file should be removed")
+	}
+}
+
+func BenchmarkStoreAdd(b *testing.B) {
+	for _, existing := range []int{0, 10_000, 100_000} {
+		b.Run(fmt.Sprintf("existing=%d", existing), func(b *testing.B) {
+			s := NewStore(b.TempDir(), time.UTC)
+			s.records = make([]Record, existing)
+			ts := time.Date(2026, 3, 10, 9, 0, 0, 0, time.UTC)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				s.Add(Record{Timestamp: ts, Provider: "openai", PromptTokens: 100, UsageKnown: true})
+			}
+		})
+	}
+}
+
+func TestStore_BufferedRecordsQueryableBeforeFlush(t *testing.T) {
+	dir := t.TempDir()
+	s := NewStore(dir, time.UTC)
+	s.SetFlushInterval(time.Hour) // only explicit flushes in this test
+	defer s.Close()
+
+	ts := time.Date(2026, 3, 10, 9, 0, 0, 0, time.UTC)
+	s.Add(Record{Timestamp: ts, SessionKey: "a", Provider: "openai", TotalTokens: 42})
+
+	if got := s.Query(Filter{SessionKey: "a"}); len(got) != 1 || got[0].TotalTokens != 42 {
+		t.Fatalf("buffered record not queryable: %+v", got)
+	}
+	if got := NewStore(dir, time.UTC).Query(Filter{}); len(got) != 0 {
+		t.Fatalf("record persisted before flush: %+v", got)
+	}
+
+	s.Flush()
+	if got := NewStore(dir, time.UTC).Query(Filter{}); len(got) != 1 || got[0].TotalTokens != 42 {
+		t.Fatalf("record not persisted after flush: %+v", got)
+	}
+}
+
+func TestStore_FlushesOnIntervalAndClose(t *testing.T) {
+	dir := t.TempDir()
+	s := NewStore(dir, time.UTC)
+	s.SetFlushInterval(20 * time.Millisecond)
+
+	ts := time.Date(2026, 3, 10, 9, 0, 0, 0, time.UTC)
+	s.Add(Record{Timestamp: ts, Provider: "openai"})
+	deadline := time.Now().Add(2 * time.Second)
+	for len(NewStore(dir, time.UTC).Query(Filter{})) != 1 {
+		if time.Now().After(deadline) {
+			t.Fatal("record was not flushed by the background interval")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	s.SetFlushInterval(time.Hour)
+	s.Add(Record{Timestamp: ts, Provider: "openai"})
+	s.Close()
+	if got := NewStore(dir, time.UTC).Query(Filter{}); len(got) != 2 {
+		t.Fatalf("Close should flush pending records, got %d persisted", len(got))
+	}
+
+	s.Add(Record{Timestamp: ts, Provider: "openai"})
+	if got := NewStore(dir, time.UTC).Query(Filter{}); len(got) != 3 {
+		t.Fatalf("records after Close should be written synchronously, got %d persisted", len(got))
+	}
+}
+
+func TestStore_FlushesWhenPendingCapReached(t *testing.T) {
+	dir := t.TempDir()
+	s := NewStore(dir, time.UTC)
+	s.SetFlushInterval(time.Hour)
+	defer s.Close()
+
+	ts := time.Date(2026, 3, 10, 9, 0, 0, 0, time.UTC)
+	for i := 0; i < maxPendingRecords; i++ {
+		s.Add(Record{Timestamp: ts, Provider: "openai"})
+	}
+	if got := NewStore(dir, time.UTC).Query(Filter{}); len(got) != maxPendingRecords {
+		t.Fatalf("persisted %d records, want a forced flush at %d", len(got), maxPendingRecords)
+	}
+}
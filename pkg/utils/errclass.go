@@ -0,0 +1,102 @@
+package utils
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// Error classes logged as error_class, so log monitors can group failures
+// without parsing messages.
+const (
+	ErrorClassTimeout           = "timeout"
+	ErrorClassCancelled         = "cancelled"
+	ErrorClassNotFound          = "not_found"
+	ErrorClassPermission        = "permission"
+	ErrorClassValidation        = "validation"
+	ErrorClassNetwork           = "network"
+	ErrorClassProviderRateLimit = "provider_rate_limit"
+	ErrorClassProviderAuth      = "provider_auth"
+	ErrorClassProviderError     = "provider_error"
+	ErrorClassContentFilter     = "content_filter"
+	ErrorClassUnknown           = "unknown"
+)
+
+// errorClassRules map message fragments to classes, checked in order after
+// the typed checks. Fragments are lowercase.
+var errorClassRules = []struct {
+	class     string
+	fragments []string
+}{
+	{ErrorClassContentFilter, []string{"content filtered", "content_filter", "content_policy"}},
+	{ErrorClassProviderRateLimit, []string{"rate limit", "status 429", "too many requests", "quota"}},
+	{ErrorClassProviderAuth, []string{"status 401", "unauthorized", "invalid api key", "invalid_api_key", "authentication"}},
+	{ErrorClassCancelled, []string{"context canceled", "cancelled", "canceled"}},
+	{ErrorClassTimeout, []string{"timed out", "timeout", "deadline exceeded"}},
+	{ErrorClassValidation, []string{"invalid argument", "is required", "must be", "invalid", "missing required", "expected"}},
+	{ErrorClassPermission, []string{"permission denied", "access denied", "not permitted", "forbidden", "status 403", "blocked", "outside the workspace"}},
+	{ErrorClassNotFound, []string{"not found", "no such file", "does not exist", "status 404"}},
+	{ErrorClassNetwork, []string{"connection refused", "connection reset", "no such host", "network is unreachable", "broken pipe", "tls handshake"}},
+	{ErrorClassProviderError, []string{"api error", "status 500", "status 502", "status 503", "status 504", "overloaded"}},
+}
+
+// ClassifyError returns a stable error class for a failure, from err when
+// given and otherwise from message (e.g. a tool's error text).
+func ClassifyError(err error, message string) string {
+	if err != nil {
+		switch {
+		case errors.Is(err, context.DeadlineExceeded):
+			return ErrorClassTimeout
+		case errors.Is(err, context.Canceled):
+			return ErrorClassCancelled
+		case errors.Is(err, os.ErrNotExist):
+			return ErrorClassNotFound
+		case errors.Is(err, os.ErrPermission):
+			return ErrorClassPermission
+		}
+		var timeout interface{ Timeout() bool }
+		if errors.As(err, &timeout) && timeout.Timeout() {
+			return ErrorClassTimeout
+		}
+		if message == "" {
+			message = err.Error()
+		}
+	}
+
+	lower := strings.ToLower(message)
+	for _, rule := range errorClassRules {
+		for _, fragment := range rule.fragments {
+			if strings.Contains(lower, fragment) {
+				return rule.class
+			}
+		}
+	}
+	return ErrorClassUnknown
+}
+
+var (
+	fingerprintPaths   = regexp.MustCompile(`(?:[a-zA-Z]:)?[\\/][^\s"':,)]+`)
+	fingerprintQuoted  = regexp.MustCompile(`"[^"]*"|'[^']*'`)
+	fingerprintNumbers = regexp.MustCompile(`0x[0-9a-f]+|[0-9a-f]{8,}|\d+`)
+	fingerprintSpaces  = regexp.MustCompile(`\s+`)
+)
+
+// ErrorFingerprint returns a short stable hash identifying a kind of
+// failure: parts such as the component and tool are joined with the
+// message stripped of paths, quoted values and numbers, so repeats of the
+// same problem share a fingerprint.
+func ErrorFingerprint(message string, parts ...string) string {
+	normalized := strings.ToLower(message)
+	normalized = fingerprintPaths.ReplaceAllString(normalized, "<path>")
+	normalized = fingerprintQuoted.ReplaceAllString(normalized, "<str>")
+	normalized = fingerprintNumbers.ReplaceAllString(normalized, "<n>")
+	normalized = strings.TrimSpace(fingerprintSpaces.ReplaceAllString(normalized, " "))
+	normalized = Truncate(normalized, 200)
+
+	sum := sha256.Sum256([]byte(strings.Join(append(parts, normalized), "|")))
+	return hex.EncodeToString(sum[:6])
+}
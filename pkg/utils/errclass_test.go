@@ -0,0 +1,53 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+)
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		err     error
+		message string
+		want    string
+	}{
+		{fmt.Errorf("fetch: %w", context.DeadlineExceeded), "", ErrorClassTimeout},
+		{context.Canceled, "", ErrorClassCancelled},
+		{fmt.Errorf("read: %w", os.ErrNotExist), "", ErrorClassNotFound},
+		{&os.PathError{Op: "open", Path: "/x", Err: os.ErrPermission}, "", ErrorClassPermission},
+		{errors.New("rate limited (status 429): slow down"), "", ErrorClassProviderRateLimit},
+		{errors.New("API error (status 401): invalid api key"), "", ErrorClassProviderAuth},
+		{errors.New("API error (status 503): overloaded"), "", ErrorClassProviderError},
+		{errors.New("dial tcp: connection refused"), "", ErrorClassNetwork},
+		{nil, "Command timed out after 1m0s", ErrorClassTimeout},
+		{nil, "Command blocked by safety guard (rm is a blocked command)", ErrorClassPermission},
+		{nil, `invalid arguments for tool "exec": missing required parameter "command"`, ErrorClassValidation},
+		{nil, "file not found: notes.txt", ErrorClassNotFound},
+		{nil, "something odd happened", ErrorClassUnknown},
+	}
+	for _, tt := range tests {
+		if got := ClassifyError(tt.err, tt.message); got != tt.want {
+			t.Errorf("ClassifyError(%v, %q) = %q, want %q", tt.err, tt.message, got, tt.want)
+		}
+	}
+}
+
+func TestErrorFingerprint(t *testing.T) {
+	a := ErrorFingerprint(`failed to read file: open /home/a/notes.txt: no such file (attempt 3)`, "tool", "read_file")
+	b := ErrorFingerprint(`failed to read file: open /tmp/other.md: no such file (attempt 12)`, "tool", "read_file")
+	if a != b {
+		t.Errorf("fingerprints differ for the same failure with different paths and numbers: %s vs %s", a, b)
+	}
+	if c := ErrorFingerprint(`failed to read file: open /tmp/other.md: no such file (attempt 12)`, "tool", "write_file"); c == a {
+		t.Error("fingerprint should depend on the tool")
+	}
+	if d := ErrorFingerprint("permission denied", "tool", "read_file"); d == a {
+		t.Error("fingerprint should depend on the message")
+	}
+	if len(a) != 12 {
+		t.Errorf("fingerprint %q should be 12 hex chars", a)
+	}
+}
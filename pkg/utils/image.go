@@ -0,0 +1,123 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/gif" // Registers GIF for image.DecodeConfig
+	"image/jpeg"
+	_ "image/png" // Registers PNG for image.Decode
+	"sync"
+)
+
+// Defaults for downscaling inbound images before they are sent to vision
+// models. 1568px is the longest side common vision APIs accept unscaled.
+const (
+	DefaultMaxImageDimension = 1568
+	DefaultImageJPEGQuality  = 85
+)
+
+var (
+	imageScaleMu      sync.RWMutex
+	maxImageDimension = DefaultMaxImageDimension
+	imageJPEGQuality  = DefaultImageJPEGQuality
+)
+
+// SetImageDownscale sets the longest side inbound images are scaled down to
+// and the JPEG quality they are re-encoded at. maxDimension < 0 disables
+// downscaling; 0 values keep the defaults.
+func SetImageDownscale(maxDimension, jpegQuality int) {
+	if maxDimension == 0 {
+		maxDimension = DefaultMaxImageDimension
+	}
+	if jpegQuality <= 0 || jpegQuality > 100 {
+		jpegQuality = DefaultImageJPEGQuality
+	}
+	imageScaleMu.Lock()
+	maxImageDimension, imageJPEGQuality = maxDimension, jpegQuality
+	imageScaleMu.Unlock()
+}
+
+// downscaleImage shrinks a JPEG or PNG whose longest side exceeds the
+// configured maximum, keeping the aspect ratio, and re-encodes it as JPEG.
+// Other images, and images already small enough, are returned unchanged
+// with scaled false. from and to are the original and final sizes.
+func downscaleImage(data []byte, mimeType string) (out []byte, outMime string, from, to image.Point, scaled bool, err error) {
+	imageScaleMu.RLock()
+	maxDim, quality := maxImageDimension, imageJPEGQuality
+	imageScaleMu.RUnlock()
+
+	// GIFs may be animated and WebP can't be decoded with the standard
+	// library; both are sent as they are.
+	if maxDim <= 0 || (mimeType != "image/jpeg" && mimeType != "image/png") {
+		return data, mimeType, from, to, false, nil
+	}
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return data, mimeType, from, to, false, fmt.Errorf("reading image size: %w", err)
+	}
+	from = image.Pt(cfg.Width, cfg.Height)
+	if max(cfg.Width, cfg.Height) <= maxDim {
+		return data, mimeType, from, from, false, nil
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return data, mimeType, from, from, false, fmt.Errorf("decoding image: %w", err)
+	}
+	to = fitWithin(from, maxDim)
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, scaleBilinear(src, to.X, to.Y), &jpeg.Options{Quality: quality}); err != nil {
+		return data, mimeType, from, from, false, fmt.Errorf("encoding JPEG: %w", err)
+	}
+	return buf.Bytes(), "image/jpeg", from, to, true, nil
+}
+
+// fitWithin scales size so its longest side is maxDim, keeping the aspect
+// ratio.
+func fitWithin(size image.Point, maxDim int) image.Point {
+	if size.X >= size.Y {
+		return image.Pt(maxDim, max(1, size.Y*maxDim/size.X))
+	}
+	return image.Pt(max(1, size.X*maxDim/size.Y), maxDim)
+}
+
+// scaleBilinear resizes src to w×h with bilinear interpolation.
+// Transparent areas are flattened onto white, since JPEG has no alpha.
+func scaleBilinear(src image.Image, w, h int) *image.RGBA {
+	b := src.Bounds()
+	flat := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(flat, flat.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(flat, flat.Bounds(), src, b.Min, draw.Over)
+
+	sw, sh := b.Dx(), b.Dy()
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	xRatio := float64(sw) / float64(w)
+	yRatio := float64(sh) / float64(h)
+	for y := 0; y < h; y++ {
+		sy := max((float64(y)+0.5)*yRatio-0.5, 0)
+		y0 := int(sy)
+		y1 := min(y0+1, sh-1)
+		fy := sy - float64(y0)
+		for x := 0; x < w; x++ {
+			sx := max((float64(x)+0.5)*xRatio-0.5, 0)
+			x0 := int(sx)
+			x1 := min(x0+1, sw-1)
+			fx := sx - float64(x0)
+
+			p00 := flat.PixOffset(x0, y0)
+			p10 := flat.PixOffset(x1, y0)
+			p01 := flat.PixOffset(x0, y1)
+			p11 := flat.PixOffset(x1, y1)
+			d := dst.PixOffset(x, y)
+			for c := 0; c < 4; c++ {
+				top := float64(flat.Pix[p00+c])*(1-fx) + float64(flat.Pix[p10+c])*fx
+				bottom := float64(flat.Pix[p01+c])*(1-fx) + float64(flat.Pix[p11+c])*fx
+				dst.Pix[d+c] = uint8(top*(1-fy) + bottom*fy + 0.5)
+			}
+		}
+	}
+	return dst
+}
@@ -2,11 +2,13 @@ package utils
 
 import (
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
 	"time"
@@ -25,7 +27,7 @@ type MediaImage struct {
 func IsImageFile(path string) bool {
 	ext := strings.ToLower(filepath.Ext(path))
 	switch ext {
-	case ".jpg", ".jpeg", ".png", ".gif", ".webp":
+	case ".jpg", ".jpeg", ".png", ".gif", ".webp", ".heic", ".heif", ".bmp":
 		return true
 	}
 	return false
@@ -43,20 +45,50 @@ func DetectImageMimeType(path string) string {
 		return "image/gif"
 	case ".webp":
 		return "image/webp"
+	case ".heic":
+		return "image/heic"
+	case ".heif":
+		return "image/heif"
+	case ".bmp":
+		return "image/bmp"
 	}
 	return ""
 }
 
 // LoadAndEncodeImage reads an image file and returns its MIME type and base64-encoded data.
+// HEIC and BMP images, which vision providers reject, are transcoded to JPEG,
+// and images larger than the configured dimension are scaled down.
 func LoadAndEncodeImage(path string) (mimeType, base64Data string, err error) {
 	mimeType = DetectImageMimeType(path)
 	if mimeType == "" {
 		return "", "", fmt.Errorf("unsupported image type: %s", filepath.Ext(path))
 	}
+	if needsJPEGTranscode(mimeType) {
+		jpegPath, err := TranscodeToJPEG(path)
+		if err != nil {
+			return "", "", fmt.Errorf("transcoding %s to JPEG: %w", mimeType, err)
+		}
+		defer os.Remove(jpegPath)
+		path, mimeType = jpegPath, "image/jpeg"
+	}
 	data, err := os.ReadFile(path)
 	if err != nil {
 		return "", "", fmt.Errorf("reading image %s: %w", path, err)
 	}
+	if scaledData, scaledMime, from, to, scaled, err := downscaleImage(data, mimeType); err != nil {
+		logger.DebugCF("media", "Sending image at original size",
+			map[string]interface{}{"path": path, "error": err.Error()})
+	} else if scaled {
+		logger.InfoCF("media", "Downscaled image for LLM",
+			map[string]interface{}{
+				"path":          path,
+				"original_size": fmt.Sprintf("%dx%d", from.X, from.Y),
+				"final_size":    fmt.Sprintf("%dx%d", to.X, to.Y),
+				"bytes_before":  len(data),
+				"bytes_after":   len(scaledData),
+			})
+		data, mimeType = scaledData, scaledMime
+	}
 	base64Data = base64.StdEncoding.EncodeToString(data)
 	return mimeType, base64Data, nil
 }
@@ -70,7 +102,11 @@ func ProcessMediaImages(paths []string) []MediaImage {
 		}
 		mimeType, b64, err := LoadAndEncodeImage(p)
 		if err != nil {
-			logger.WarnCF("media", "Failed to encode image",
+			msg := "Failed to encode image"
+			if needsJPEGTranscode(DetectImageMimeType(p)) {
+				msg = "Skipping image the model cannot read: transcoding to JPEG failed"
+			}
+			logger.WarnCF("media", msg,
 				map[string]interface{}{"path": p, "error": err.Error()})
 			continue
 		}
@@ -160,9 +196,200 @@ func GetMediaCacheDir() string {
 		return dir
 	}
 
+	return LegacyMediaCacheDir()
+}
+
+// LegacyMediaCacheDir returns the temp directory media was downloaded to
+// before a workspace cache directory was configured.
+func LegacyMediaCacheDir() string {
 	return filepath.Join(os.TempDir(), "picoclaw_media")
 }
 
+// IsPathWithin reports whether path is dir itself or lies inside it.
+// Both paths should be cleaned and absolute.
+func IsPathWithin(path, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return false
+	}
+	return rel == "." || (rel != ".." && !strings.HasPrefix(rel, ".."+string(os.PathSeparator)))
+}
+
+// MediaCleanupResult summarizes a CleanMediaCache run.
+type MediaCleanupResult struct {
+	Removed    int   // Files deleted
+	BytesFreed int64 // Total size of deleted files
+	Skipped    int   // Files kept because they were still in use
+	Failed     int   // Files that could not be deleted
+}
+
+// CleanMediaCache deletes cached media files under dirs, leaving the
+// directories in place. Files for which keep returns true are skipped.
+// Symlinks are removed without following them, and nothing outside dirs is
+// ever touched. Missing directories are ignored.
+func CleanMediaCache(dirs []string, keep func(path string, info os.FileInfo) bool) (MediaCleanupResult, error) {
+	var res MediaCleanupResult
+	for _, dir := range dirs {
+		dir = filepath.Clean(dir)
+		err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+			if err != nil {
+				if errors.Is(err, os.ErrNotExist) {
+					return nil
+				}
+				return err
+			}
+			if d.IsDir() {
+				return nil
+			}
+			path = filepath.Clean(path)
+			if path == dir || !IsPathWithin(path, dir) {
+				return nil
+			}
+
+			info, err := d.Info()
+			if err != nil {
+				if errors.Is(err, os.ErrNotExist) {
+					return nil
+				}
+				res.Failed++
+				return nil
+			}
+			if keep != nil && keep(path, info) {
+				res.Skipped++
+				return nil
+			}
+
+			if err := os.Remove(path); err != nil {
+				if !errors.Is(err, os.ErrNotExist) {
+					logger.DebugCF("media", "Failed to remove cached media",
+						map[string]interface{}{"path": path, "error": err.Error()})
+					res.Failed++
+				}
+				return nil
+			}
+			res.Removed++
+			if info.Mode().IsRegular() {
+				res.BytesFreed += info.Size()
+			}
+			return nil
+		})
+		if err != nil {
+			return res, fmt.Errorf("failed to clean %s: %w", dir, err)
+		}
+	}
+	return res, nil
+}
+
+var (
+	mediaCacheLimitMu sync.RWMutex
+	mediaCacheLimit   int64
+	mediaCacheKeep    func(path string, info os.FileInfo) bool
+)
+
+// SetMediaCacheLimit caps the total size of the media cache directory at
+// maxBytes; 0 disables the cap. Files for which keep returns true are never
+// evicted.
+func SetMediaCacheLimit(maxBytes int64, keep func(path string, info os.FileInfo) bool) {
+	mediaCacheLimitMu.Lock()
+	defer mediaCacheLimitMu.Unlock()
+	mediaCacheLimit = maxBytes
+	mediaCacheKeep = keep
+}
+
+// EnforceMediaCacheLimit deletes the oldest files in the media cache until
+// it fits under the limit set with SetMediaCacheLimit. It catches files
+// the per-turn cleanup never saw, such as downloads orphaned by a crash.
+func EnforceMediaCacheLimit() (MediaCleanupResult, error) {
+	return enforceMediaCacheLimit("")
+}
+
+// enforceMediaCacheLimit is EnforceMediaCacheLimit, additionally keeping
+// the file at protect.
+func enforceMediaCacheLimit(protect string) (MediaCleanupResult, error) {
+	mediaCacheLimitMu.RLock()
+	limit, keep := mediaCacheLimit, mediaCacheKeep
+	mediaCacheLimitMu.RUnlock()
+	if limit <= 0 {
+		return MediaCleanupResult{}, nil
+	}
+
+	dir := GetMediaCacheDir()
+	res, err := evictMediaCache(dir, limit, func(path string, info os.FileInfo) bool {
+		return path == protect || (keep != nil && keep(path, info))
+	})
+	if res.Removed > 0 {
+		logger.InfoCF("media", "Evicted cached media to stay under the size limit",
+			map[string]interface{}{
+				"dir":         dir,
+				"removed":     res.Removed,
+				"bytes_freed": res.BytesFreed,
+				"limit_bytes": limit,
+			})
+	}
+	return res, err
+}
+
+// evictMediaCache deletes regular files under dir, least recently modified
+// first, until their total size is at most maxBytes. Files for which keep
+// returns true count towards the total but are not deleted.
+func evictMediaCache(dir string, maxBytes int64, keep func(path string, info os.FileInfo) bool) (MediaCleanupResult, error) {
+	type cachedFile struct {
+		path string
+		info os.FileInfo
+	}
+	var (
+		res   MediaCleanupResult
+		files []cachedFile
+		total int64
+	)
+	dir = filepath.Clean(dir)
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		files = append(files, cachedFile{filepath.Clean(path), info})
+		total += info.Size()
+		return nil
+	})
+	if err != nil {
+		return res, fmt.Errorf("failed to scan %s: %w", dir, err)
+	}
+
+	slices.SortFunc(files, func(a, b cachedFile) int {
+		return a.info.ModTime().Compare(b.info.ModTime())
+	})
+	for _, f := range files {
+		if total <= maxBytes {
+			break
+		}
+		if keep != nil && keep(f.path, f.info) {
+			res.Skipped++
+			continue
+		}
+		if err := os.Remove(f.path); err != nil {
+			if !errors.Is(err, os.ErrNotExist) {
+				res.Failed++
+				continue
+			}
+		} else {
+			res.Removed++
+			res.BytesFreed += f.info.Size()
+		}
+		total -= f.info.Size()
+	}
+	return res, nil
+}
+
 // DownloadFile downloads a file from URL to a local temp directory.
 // Returns the local file path or empty string on error.
 func DownloadFile(url, filename string, opts DownloadOptions) string {
@@ -242,6 +469,12 @@ func DownloadFile(url, filename string, opts DownloadOptions) string {
 		"path": localPath,
 	})
 
+	if _, err := enforceMediaCacheLimit(localPath); err != nil {
+		logger.WarnCF(opts.LoggerPrefix, "Failed to enforce media cache limit", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+
 	return localPath
 }
 
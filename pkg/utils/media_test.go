@@ -0,0 +1,221 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/base64"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIsPathWithin(t *testing.T) {
+	dir := filepath.Join(string(os.PathSeparator), "ws", "tmp", "media")
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{dir, true},
+		{filepath.Join(dir, "a.jpg"), true},
+		{filepath.Join(dir, "sub", "b.ogg"), true},
+		{filepath.Join(dir, "..", "other.txt"), false},
+		{filepath.Join(dir, "..", "media-evil", "c.jpg"), false},
+		{filepath.Join(dir, "..", "..", "..", "etc", "passwd"), false},
+	}
+	for _, tt := range tests {
+		if got := IsPathWithin(filepath.Clean(tt.path), dir); got != tt.want {
+			t.Errorf("IsPathWithin(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func writeSized(t *testing.T, path string, size int) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(strings.Repeat("x", size)), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCleanMediaCache_CountsBytesAndKeepsInUseFiles(t *testing.T) {
+	root := t.TempDir()
+	cache := filepath.Join(root, "media")
+	legacy := filepath.Join(root, "legacy")
+	writeSized(t, filepath.Join(cache, "a.jpg"), 1000)
+	writeSized(t, filepath.Join(cache, "sub", "b.ogg"), 24)
+	writeSized(t, filepath.Join(legacy, "c.png"), 2048)
+	writeSized(t, filepath.Join(cache, "busy.jpg"), 500)
+
+	res, err := CleanMediaCache([]string{cache, legacy, filepath.Join(root, "missing")}, func(path string, info os.FileInfo) bool {
+		return filepath.Base(path) == "busy.jpg"
+	})
+	if err != nil {
+		t.Fatalf("CleanMediaCache() error: %v", err)
+	}
+
+	want := MediaCleanupResult{Removed: 3, BytesFreed: 1000 + 24 + 2048, Skipped: 1}
+	if res != want {
+		t.Fatalf("result = %+v, want %+v", res, want)
+	}
+	if _, err := os.Stat(filepath.Join(cache, "busy.jpg")); err != nil {
+		t.Fatalf("in-use file should be kept: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(cache, "sub")); err != nil {
+		t.Fatalf("cache directories should be left in place: %v", err)
+	}
+}
+
+func TestCleanMediaCache_DoesNotFollowSymlinks(t *testing.T) {
+	root := t.TempDir()
+	cache := filepath.Join(root, "media")
+	outsideDir := filepath.Join(root, "outside")
+	outsideFile := filepath.Join(outsideDir, "keep.txt")
+	writeSized(t, outsideFile, 4096)
+	if err := os.MkdirAll(cache, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outsideFile, filepath.Join(cache, "link.txt")); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+	if err := os.Symlink(outsideDir, filepath.Join(cache, "linkdir")); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := CleanMediaCache([]string{cache}, nil)
+	if err != nil {
+		t.Fatalf("CleanMediaCache() error: %v", err)
+	}
+	if res.Removed != 2 || res.BytesFreed != 0 {
+		t.Fatalf("result = %+v, want 2 links removed and no bytes counted", res)
+	}
+	if _, err := os.Stat(outsideFile); err != nil {
+		t.Fatalf("symlink target outside the cache was touched: %v", err)
+	}
+}
+
+func TestDetectImageMimeType_HEICAndBMP(t *testing.T) {
+	for path, want := range map[string]string{
+		"IMG_0001.HEIC": "image/heic",
+		"photo.heif":    "image/heif",
+		"scan.bmp":      "image/bmp",
+		"notes.txt":     "",
+	} {
+		if got := DetectImageMimeType(path); got != want {
+			t.Errorf("DetectImageMimeType(%q) = %q, want %q", path, got, want)
+		}
+		if got := IsImageFile(path); got != (want != "") {
+			t.Errorf("IsImageFile(%q) = %v", path, got)
+		}
+	}
+}
+
+func TestProcessMediaImages_TranscodesHEIC(t *testing.T) {
+	dir := t.TempDir()
+	heic := filepath.Join(dir, "IMG_0001.heic")
+	writeSized(t, heic, 10)
+	bmp := filepath.Join(dir, "scan.bmp")
+	writeSized(t, bmp, 10)
+
+	var transcoded []string
+	SetImageTranscoder(func(src, dst string) error {
+		if filepath.Ext(src) == ".bmp" {
+			return ErrNoImageConverter
+		}
+		transcoded = append(transcoded, dst)
+		return os.WriteFile(dst, []byte("jpeg"), 0o644)
+	})
+	t.Cleanup(func() { SetImageTranscoder(nil) })
+
+	images := ProcessMediaImages([]string{heic, bmp})
+	if len(images) != 1 || images[0].MimeType != "image/jpeg" || images[0].Base64Data != "anBlZw==" {
+		t.Fatalf("images = %+v, want the HEIC as JPEG and the BMP skipped", images)
+	}
+	if _, err := os.Stat(transcoded[0]); !os.IsNotExist(err) {
+		t.Errorf("transcoded copy %s should be removed after encoding", transcoded[0])
+	}
+}
+
+func TestLoadAndEncodeImage_Downscales(t *testing.T) {
+	dir := t.TempDir()
+	writePNG := func(name string, w, h int) string {
+		img := image.NewRGBA(image.Rect(0, 0, w, h))
+		for i := range img.Pix {
+			img.Pix[i] = 200
+		}
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, img); err != nil {
+			t.Fatal(err)
+		}
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	SetImageDownscale(1000, 80)
+	t.Cleanup(func() { SetImageDownscale(0, 0) })
+
+	mime, data, err := LoadAndEncodeImage(writePNG("wide.png", 3000, 1200))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mime != "image/jpeg" {
+		t.Fatalf("mime = %q, want image/jpeg", mime)
+	}
+	raw, _ := base64.StdEncoding.DecodeString(data)
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(raw))
+	if err != nil || format != "jpeg" || cfg.Width != 1000 || cfg.Height != 400 {
+		t.Fatalf("scaled image = %s %dx%d (%v), want jpeg 1000x400", format, cfg.Width, cfg.Height, err)
+	}
+
+	mime, _, err = LoadAndEncodeImage(writePNG("small.png", 800, 600))
+	if err != nil || mime != "image/png" {
+		t.Fatalf("small image: mime = %q, err = %v; want it sent unchanged", mime, err)
+	}
+}
+
+func TestEnforceMediaCacheLimit_EvictsOldestFirst(t *testing.T) {
+	cache := t.TempDir()
+	if err := SetMediaCacheDir(cache); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		mediaCacheDirMu.Lock()
+		mediaCacheDir = ""
+		mediaCacheDirMu.Unlock()
+		SetMediaCacheLimit(0, nil)
+	})
+
+	base := time.Now().Add(-time.Hour)
+	for i, name := range []string{"old.jpg", "busy.jpg", "mid.ogg", "new.pdf"} {
+		path := filepath.Join(cache, name)
+		writeSized(t, path, 400)
+		mtime := base.Add(time.Duration(i) * time.Minute)
+		if err := os.Chtimes(path, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	SetMediaCacheLimit(1000, func(path string, _ os.FileInfo) bool {
+		return filepath.Base(path) == "busy.jpg"
+	})
+	res, err := EnforceMediaCacheLimit()
+	if err != nil {
+		t.Fatalf("EnforceMediaCacheLimit() error: %v", err)
+	}
+	if res.Removed != 2 || res.BytesFreed != 800 || res.Skipped != 1 {
+		t.Fatalf("result = %+v, want old.jpg and mid.ogg evicted", res)
+	}
+	for name, want := range map[string]bool{"old.jpg": false, "busy.jpg": true, "mid.ogg": false, "new.pdf": true} {
+		_, err := os.Stat(filepath.Join(cache, name))
+		if got := err == nil; got != want {
+			t.Errorf("%s present = %v, want %v", name, got, want)
+		}
+	}
+}
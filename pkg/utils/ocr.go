@@ -0,0 +1,48 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// OCRFunc extracts the text shown in an image file.
+type OCRFunc func(path string) (string, error)
+
+// NewCommandOCR returns an OCRFunc that runs an external OCR program, e.g.
+// "tesseract {path} stdout", and returns what it prints. "{path}" in args
+// is replaced by the image path; without it the path is appended.
+func NewCommandOCR(command string, args []string, timeout time.Duration) OCRFunc {
+	return func(path string) (string, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+
+		cmdArgs := make([]string, 0, len(args)+1)
+		hasPath := false
+		for _, arg := range args {
+			if strings.Contains(arg, "{path}") {
+				hasPath = true
+				arg = strings.ReplaceAll(arg, "{path}", path)
+			}
+			cmdArgs = append(cmdArgs, arg)
+		}
+		if !hasPath {
+			cmdArgs = append(cmdArgs, path)
+		}
+
+		var stderr bytes.Buffer
+		cmd := exec.CommandContext(ctx, command, cmdArgs...)
+		cmd.Stderr = &stderr
+		out, err := cmd.Output()
+		if err != nil {
+			if msg := strings.TrimSpace(stderr.String()); msg != "" {
+				return "", fmt.Errorf("%s: %w: %s", command, err, Truncate(msg, 200))
+			}
+			return "", fmt.Errorf("%s: %w", command, err)
+		}
+		return strings.TrimSpace(string(out)), nil
+	}
+}
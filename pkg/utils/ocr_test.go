@@ -0,0 +1,34 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNewCommandOCR(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{name: "path placeholder", args: []string{"text of", "{path}"}, want: "text of /tmp/receipt.png"},
+		{name: "path appended", args: []string{"text of"}, want: "text of /tmp/receipt.png"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewCommandOCR("echo", tt.args, 5*time.Second)("/tmp/receipt.png")
+			if err != nil {
+				t.Fatalf("OCR error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("OCR = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewCommandOCR_ReportsFailure(t *testing.T) {
+	if _, err := NewCommandOCR("false", nil, 5*time.Second)("/tmp/receipt.png"); err == nil {
+		t.Fatal("expected an error from a failing OCR command")
+	}
+}
@@ -0,0 +1,13 @@
+package utils
+
+import (
+	"os"
+	"strings"
+)
+
+// IsTermux reports whether picoclaw is running inside Termux on Android.
+// Termux:API commands (termux-battery-status, termux-sms-list, ...) are
+// only usable there.
+func IsTermux() bool {
+	return os.Getenv("TERMUX_VERSION") != "" || strings.Contains(os.Getenv("PREFIX"), "com.termux")
+}
@@ -0,0 +1,102 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ImageTranscoder converts the image at src into a JPEG file at dst.
+type ImageTranscoder func(src, dst string) error
+
+var (
+	imageTranscoderMu sync.RWMutex
+	imageTranscoder   ImageTranscoder = commandTranscoder
+)
+
+// SetImageTranscoder replaces how HEIC and BMP images are converted to
+// JPEG. nil restores the default, which runs an installed converter.
+func SetImageTranscoder(t ImageTranscoder) {
+	if t == nil {
+		t = commandTranscoder
+	}
+	imageTranscoderMu.Lock()
+	imageTranscoder = t
+	imageTranscoderMu.Unlock()
+}
+
+// needsJPEGTranscode reports whether vision providers reject mimeType, so
+// the image has to be sent as JPEG.
+func needsJPEGTranscode(mimeType string) bool {
+	switch mimeType {
+	case "image/heic", "image/heif", "image/bmp":
+		return true
+	}
+	return false
+}
+
+// ErrNoImageConverter is returned when no program to convert HEIC or BMP
+// images is installed.
+var ErrNoImageConverter = errors.New("no image converter found (install ImageMagick, libheif or ffmpeg)")
+
+// TranscodeToJPEG writes a JPEG copy of the image at path to a temporary
+// file and returns its path. The caller removes the file.
+func TranscodeToJPEG(path string) (string, error) {
+	tmp, err := os.CreateTemp("", "picoclaw_transcode_*.jpg")
+	if err != nil {
+		return "", fmt.Errorf("creating transcode target: %w", err)
+	}
+	dst := tmp.Name()
+	tmp.Close()
+
+	imageTranscoderMu.RLock()
+	transcode := imageTranscoder
+	imageTranscoderMu.RUnlock()
+
+	if err := transcode(path, dst); err != nil {
+		os.Remove(dst)
+		return "", err
+	}
+	return dst, nil
+}
+
+// commandTranscoder converts with the first installed of heif-convert (for
+// HEIC), ImageMagick and ffmpeg.
+func commandTranscoder(src, dst string) error {
+	ext := strings.ToLower(filepath.Ext(src))
+	candidates := [][]string{
+		{"magick", src, dst},
+		{"convert", src, dst},
+		{"ffmpeg", "-y", "-loglevel", "error", "-i", src, dst},
+	}
+	if ext == ".heic" || ext == ".heif" {
+		candidates = append([][]string{{"heif-convert", src, dst}}, candidates...)
+	}
+
+	for _, args := range candidates {
+		if _, err := exec.LookPath(args[0]); err != nil {
+			continue
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		var stderr bytes.Buffer
+		cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+		cmd.Stderr = &stderr
+		err := cmd.Run()
+		cancel()
+		if err != nil {
+			if msg := strings.TrimSpace(stderr.String()); msg != "" {
+				return fmt.Errorf("%s: %w: %s", args[0], err, Truncate(msg, 200))
+			}
+			return fmt.Errorf("%s: %w", args[0], err)
+		}
+		return nil
+	}
+	return ErrNoImageConverter
+}
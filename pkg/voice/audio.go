@@ -0,0 +1,47 @@
+package voice
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/utils"
+)
+
+// TranscriptionTimeout bounds transcription of one inbound audio file.
+const TranscriptionTimeout = 30 * time.Second
+
+// Transcriber converts audio files to text. GroqTranscriber is the
+// implementation; channels and tools take the interface so the backend can
+// be swapped.
+type Transcriber interface {
+	IsAvailable() bool
+	// Transcribe auto-detects the spoken language.
+	Transcribe(ctx context.Context, audioFilePath string) (*TranscriptionResponse, error)
+	// TranscribeLanguage transcribes speech in the given language (ISO
+	// 639-1 code or name); "" auto-detects like Transcribe.
+	TranscribeLanguage(ctx context.Context, audioFilePath, language string) (*TranscriptionResponse, error)
+}
+
+// TranscribeAudio transcribes a saved inbound attachment for any channel.
+// It returns the content marker to add to the message, such as
+// "[audio transcription: ...]" or a failure note, or "" when the file is
+// not audio (by name or content type) or no transcriber is available.
+func TranscribeAudio(ctx context.Context, t Transcriber, kind, path, name, contentType string) string {
+	if t == nil || !t.IsAvailable() || !utils.IsAudioFile(name, contentType) {
+		return ""
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, TranscriptionTimeout)
+	defer cancel()
+	result, err := t.Transcribe(ctx, path)
+	if err != nil {
+		logger.ErrorCF("voice", "Audio transcription failed", map[string]interface{}{
+			"name":  name,
+			"error": err.Error(),
+		})
+		return fmt.Sprintf("[%s: %s (transcription failed)]", kind, name)
+	}
+	return FormatTranscription(kind, result)
+}
@@ -0,0 +1,54 @@
+package voice
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+type stubTranscriber struct {
+	available bool
+	err       error
+	calls     int
+}
+
+func (s *stubTranscriber) IsAvailable() bool { return s.available }
+
+func (s *stubTranscriber) Transcribe(ctx context.Context, path string) (*TranscriptionResponse, error) {
+	s.calls++
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &TranscriptionResponse{Text: "see you at noon"}, nil
+}
+
+func (s *stubTranscriber) TranscribeLanguage(ctx context.Context, path, language string) (*TranscriptionResponse, error) {
+	return s.Transcribe(ctx, path)
+}
+
+func TestTranscribeAudio(t *testing.T) {
+	ctx := context.Background()
+
+	ok := &stubTranscriber{available: true}
+	if got := TranscribeAudio(ctx, ok, "audio", "/tmp/a.mp3", "a.mp3", ""); got != "[audio transcription: see you at noon]" {
+		t.Errorf("audio by extension = %q", got)
+	}
+	if got := TranscribeAudio(ctx, ok, "audio", "/tmp/a.bin", "recording", "audio/ogg"); got == "" {
+		t.Error("audio by content type should be transcribed")
+	}
+	if got := TranscribeAudio(ctx, ok, "audio", "/tmp/a.pdf", "a.pdf", "application/pdf"); got != "" || ok.calls != 2 {
+		t.Errorf("non-audio = %q after %d calls, want skipped", got, ok.calls)
+	}
+
+	if got := TranscribeAudio(ctx, nil, "audio", "/tmp/a.mp3", "a.mp3", ""); got != "" {
+		t.Errorf("nil transcriber = %q, want skipped", got)
+	}
+	if got := TranscribeAudio(ctx, &stubTranscriber{}, "audio", "/tmp/a.mp3", "a.mp3", ""); got != "" {
+		t.Errorf("unavailable transcriber = %q, want skipped", got)
+	}
+
+	failing := &stubTranscriber{available: true, err: errors.New("boom")}
+	if got := TranscribeAudio(ctx, failing, "voice", "/tmp/a.mp3", "a.mp3", ""); got != "[voice: a.mp3 (transcription failed)]" {
+		t.Errorf("failure marker = %q", got)
+	}
+}
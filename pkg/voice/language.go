@@ -0,0 +1,95 @@
+package voice
+
+import (
+	"fmt"
+	"strings"
+)
+
+// LanguageRoute holds the transcription settings used for one language.
+type LanguageRoute struct {
+	Model  string // Whisper model; "" keeps the default
+	Prompt string // Vocabulary or style hint passed to the model
+}
+
+// SetLanguageRoutes enables language detection and routing. Keys are ISO
+// 639-1 codes ("de") or language names as reported by Whisper ("german").
+// Languages without a route keep the detection pass. A nil map turns
+// detection off.
+func (t *GroqTranscriber) SetLanguageRoutes(routes map[string]LanguageRoute) {
+	if routes == nil {
+		t.routes = nil
+		return
+	}
+	normalized := make(map[string]LanguageRoute, len(routes))
+	for lang, route := range routes {
+		if code := languageCode(lang); code != "" {
+			normalized[code] = route
+		}
+	}
+	t.routes = normalized
+}
+
+// RouteLanguage maps a detected language (code or name) to its ISO 639-1
+// code and reports the route configured for it, if any.
+func RouteLanguage(detected string, routes map[string]LanguageRoute) (code string, route LanguageRoute, ok bool) {
+	code = languageCode(detected)
+	if code == "" {
+		return "", LanguageRoute{}, false
+	}
+	route, ok = routes[code]
+	return code, route, ok
+}
+
+// FormatTranscription renders a transcript as a content marker such as
+// "[voice transcription: ...]", noting the language when it was detected.
+func FormatTranscription(kind string, result *TranscriptionResponse) string {
+	if result.Language != "" {
+		return fmt.Sprintf("[%s transcription lang=%s: %s]", kind, result.Language, result.Text)
+	}
+	return fmt.Sprintf("[%s transcription: %s]", kind, result.Text)
+}
+
+// whisperLanguageCodes maps the language names Whisper reports in
+// verbose_json to ISO 639-1 codes.
+var whisperLanguageCodes = map[string]string{
+	"arabic":     "ar",
+	"chinese":    "zh",
+	"czech":      "cs",
+	"danish":     "da",
+	"dutch":      "nl",
+	"english":    "en",
+	"finnish":    "fi",
+	"french":     "fr",
+	"german":     "de",
+	"greek":      "el",
+	"hebrew":     "he",
+	"hindi":      "hi",
+	"hungarian":  "hu",
+	"indonesian": "id",
+	"italian":    "it",
+	"japanese":   "ja",
+	"korean":     "ko",
+	"malay":      "ms",
+	"norwegian":  "no",
+	"persian":    "fa",
+	"polish":     "pl",
+	"portuguese": "pt",
+	"romanian":   "ro",
+	"russian":    "ru",
+	"spanish":    "es",
+	"swedish":    "sv",
+	"thai":       "th",
+	"turkish":    "tr",
+	"ukrainian":  "uk",
+	"vietnamese": "vi",
+}
+
+// languageCode normalizes a language code or Whisper language name to a
+// lowercase ISO 639-1 code. Unknown names are returned lowercased.
+func languageCode(lang string) string {
+	lang = strings.ToLower(strings.TrimSpace(lang))
+	if code, ok := whisperLanguageCodes[lang]; ok {
+		return code
+	}
+	return lang
+}
@@ -0,0 +1,98 @@
+package voice
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestRouteLanguage(t *testing.T) {
+	routes := map[string]LanguageRoute{"de": {Model: "whisper-de"}}
+
+	tests := []struct {
+		detected string
+		wantCode string
+		wantOK   bool
+	}{
+		{detected: "German", wantCode: "de", wantOK: true},
+		{detected: "de", wantCode: "de", wantOK: true},
+		{detected: "english", wantCode: "en", wantOK: false},
+		{detected: "klingon", wantCode: "klingon", wantOK: false},
+		{detected: "", wantCode: "", wantOK: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.detected, func(t *testing.T) {
+			code, route, ok := RouteLanguage(tt.detected, routes)
+			if code != tt.wantCode || ok != tt.wantOK {
+				t.Fatalf("RouteLanguage(%q) = %q, %t; want %q, %t", tt.detected, code, ok, tt.wantCode, tt.wantOK)
+			}
+			if ok && route.Model != "whisper-de" {
+				t.Errorf("route = %+v, want the de route", route)
+			}
+		})
+	}
+}
+
+func TestTranscribe_RoutesByDetectedLanguage(t *testing.T) {
+	type request struct{ model, format, language, prompt string }
+	var (
+		mu       sync.Mutex
+		requests []request
+	)
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			t.Errorf("parse form: %v", err)
+		}
+		req := request{r.FormValue("model"), r.FormValue("response_format"), r.FormValue("language"), r.FormValue("prompt")}
+		mu.Lock()
+		requests = append(requests, req)
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		if req.format == "verbose_json" {
+			w.Write([]byte(`{"text":"hallo welt","language":"German"}`))
+			return
+		}
+		w.Write([]byte(`{"text":"Hallo Welt!"}`))
+	}))
+	defer api.Close()
+
+	audio := filepath.Join(t.TempDir(), "voice.ogg")
+	if err := os.WriteFile(audio, []byte("OggS"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tr := NewGroqTranscriber("key")
+	tr.apiBase = api.URL
+	tr.SetLanguageRoutes(map[string]LanguageRoute{"german": {Model: "whisper-de", Prompt: "Umlaute"}})
+
+	result, err := tr.Transcribe(context.Background(), audio)
+	if err != nil {
+		t.Fatalf("Transcribe() error: %v", err)
+	}
+	if result.Text != "Hallo Welt!" || result.Language != "de" {
+		t.Fatalf("result = %+v, want routed transcript in de", result)
+	}
+	if got := FormatTranscription("voice", result); got != "[voice transcription lang=de: Hallo Welt!]" {
+		t.Errorf("marker = %q", got)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := []request{
+		{model: defaultTranscriptionModel, format: "verbose_json"},
+		{model: "whisper-de", format: "json", language: "de", prompt: "Umlaute"},
+	}
+	if len(requests) != len(want) {
+		t.Fatalf("requests = %+v, want %+v", requests, want)
+	}
+	for i := range want {
+		if requests[i] != want[i] {
+			t.Errorf("request %d = %+v, want %+v", i, requests[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package voice
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+const (
+	defaultSpeechAPIBase = "https://api.openai.com/v1"
+	defaultSpeechModel   = "gpt-4o-mini-tts"
+	defaultSpeechVoice   = "alloy"
+	// maxSpeechBytes bounds the audio accepted from the speech API.
+	maxSpeechBytes = 25 * 1024 * 1024
+)
+
+// Synthesizer converts text to speech audio files, the counterpart of
+// Transcriber for replies.
+type Synthesizer interface {
+	IsAvailable() bool
+	// Synthesize writes speech for text to a new file in dir and returns
+	// its path. Ogg/Opus output is named "*.voice.ogg" so channels send it
+	// as a voice note rather than an audio file.
+	Synthesize(ctx context.Context, text, dir string) (string, error)
+}
+
+// SpeechSynthesizer calls an OpenAI-compatible /audio/speech endpoint.
+type SpeechSynthesizer struct {
+	apiKey     string
+	apiBase    string
+	model      string
+	voice      string
+	format     string // "opus" or "mp3"
+	httpClient *http.Client
+}
+
+// NewSpeechSynthesizer creates a synthesizer. Empty apiBase, model and
+// voiceName use the OpenAI defaults; format is "opus" (default) or "mp3".
+func NewSpeechSynthesizer(apiBase, apiKey, model, voiceName, format string) *SpeechSynthesizer {
+	logger.DebugCF("voice", "Creating speech synthesizer", map[string]interface{}{"has_api_key": apiKey != ""})
+
+	if apiBase == "" {
+		apiBase = defaultSpeechAPIBase
+	}
+	if model == "" {
+		model = defaultSpeechModel
+	}
+	if voiceName == "" {
+		voiceName = defaultSpeechVoice
+	}
+	if format != "mp3" {
+		format = "opus"
+	}
+	return &SpeechSynthesizer{
+		apiKey:  apiKey,
+		apiBase: strings.TrimRight(apiBase, "/"),
+		model:   model,
+		voice:   voiceName,
+		format:  format,
+		httpClient: &http.Client{
+			Timeout: 60 * time.Second,
+		},
+	}
+}
+
+func (s *SpeechSynthesizer) IsAvailable() bool {
+	return s.apiKey != ""
+}
+
+func (s *SpeechSynthesizer) Synthesize(ctx context.Context, text, dir string) (string, error) {
+	payload, err := json.Marshal(map[string]string{
+		"model":           s.model,
+		"input":           text,
+		"voice":           s.voice,
+		"response_format": s.format,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", s.apiBase+"/audio/speech", bytes.NewReader(payload))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+s.apiKey)
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return "", fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(body))
+	}
+	audio, err := io.ReadAll(io.LimitReader(resp.Body, maxSpeechBytes+1))
+	if err != nil {
+		return "", fmt.Errorf("failed to read response: %w", err)
+	}
+	if len(audio) > maxSpeechBytes {
+		return "", fmt.Errorf("speech audio exceeds %d bytes", maxSpeechBytes)
+	}
+
+	ext := ".voice.ogg"
+	if s.format == "mp3" {
+		ext = ".mp3"
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create output directory: %w", err)
+	}
+	path := filepath.Join(dir, fmt.Sprintf("reply_%d%s", time.Now().UnixNano(), ext))
+	if err := os.WriteFile(path, audio, 0644); err != nil {
+		return "", fmt.Errorf("failed to write audio: %w", err)
+	}
+
+	logger.InfoCF("voice", "Speech synthesized", map[string]interface{}{
+		"text_length": len(text),
+		"audio_bytes": len(audio),
+		"path":        path,
+	})
+	return path, nil
+}
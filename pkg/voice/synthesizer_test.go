@@ -0,0 +1,60 @@
+package voice
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestSpeechSynthesizer_Synthesize(t *testing.T) {
+	var got map[string]string
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/audio/speech" || r.Header.Get("Authorization") != "Bearer key" {
+			t.Errorf("unexpected request %s with auth %q", r.URL.Path, r.Header.Get("Authorization"))
+		}
+		json.NewDecoder(r.Body).Decode(&got)
+		w.Write([]byte("OggS-audio"))
+	}))
+	defer api.Close()
+
+	dir := t.TempDir()
+	s := NewSpeechSynthesizer(api.URL+"/v1/", "key", "", "nova", "")
+	path, err := s.Synthesize(context.Background(), "hello there", dir)
+	if err != nil {
+		t.Fatalf("Synthesize() error: %v", err)
+	}
+	if !strings.HasSuffix(path, ".voice.ogg") || !strings.HasPrefix(path, dir) {
+		t.Errorf("path = %q, want a .voice.ogg file in %s", path, dir)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "OggS-audio" {
+		t.Errorf("audio = %q", data)
+	}
+	if got["input"] != "hello there" || got["voice"] != "nova" || got["model"] != defaultSpeechModel || got["response_format"] != "opus" {
+		t.Errorf("request body = %v", got)
+	}
+
+	mp3, err := NewSpeechSynthesizer(api.URL+"/v1", "key", "", "", "mp3").Synthesize(context.Background(), "hi", dir)
+	if err != nil || !strings.HasSuffix(mp3, ".mp3") {
+		t.Errorf("mp3 Synthesize() = %q, %v", mp3, err)
+	}
+}
+
+func TestSpeechSynthesizer_APIError(t *testing.T) {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "quota exceeded", http.StatusTooManyRequests)
+	}))
+	defer api.Close()
+
+	dir := t.TempDir()
+	_, err := NewSpeechSynthesizer(api.URL, "key", "", "", "").Synthesize(context.Background(), "hi", dir)
+	if err == nil || !strings.Contains(err.Error(), "429") {
+		t.Fatalf("Synthesize() error = %v, want API error", err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("failed synthesis left %d file(s)", len(entries))
+	}
+}
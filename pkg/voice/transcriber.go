@@ -16,10 +16,21 @@ import (
 	"github.com/sipeed/picoclaw/pkg/utils"
 )
 
+const defaultTranscriptionModel = "whisper-large-v3"
+
 type GroqTranscriber struct {
 	apiKey     string
 	apiBase    string
 	httpClient *http.Client
+	routes     map[string]LanguageRoute // ISO 639-1 code -> settings; nil = no detection
+}
+
+// transcribeOptions are the per-request settings of one transcription pass.
+type transcribeOptions struct {
+	model    string
+	language string // ISO 639-1 hint; "" lets the model detect it
+	prompt   string
+	verbose  bool // Request verbose_json to learn the detected language
 }
 
 type TranscriptionResponse struct {
@@ -41,8 +52,68 @@ func NewGroqTranscriber(apiKey string) *GroqTranscriber {
 	}
 }
 
+// Transcribe converts the audio file to text. With language routes set (see
+// SetLanguageRoutes), it first detects the spoken language and, when a route matches, transcribes
+// again with that route's settings; Language then holds the ISO 639-1 code.
 func (t *GroqTranscriber) Transcribe(ctx context.Context, audioFilePath string) (*TranscriptionResponse, error) {
-	logger.InfoCF("voice", "Starting transcription", map[string]interface{}{"audio_file": audioFilePath})
+	if t.routes == nil {
+		return t.transcribe(ctx, audioFilePath, transcribeOptions{model: defaultTranscriptionModel})
+	}
+
+	detected, err := t.transcribe(ctx, audioFilePath, transcribeOptions{model: defaultTranscriptionModel, verbose: true})
+	if err != nil {
+		return nil, err
+	}
+	code, route, ok := RouteLanguage(detected.Language, t.routes)
+	detected.Language = code
+	if !ok {
+		return detected, nil
+	}
+
+	model := route.Model
+	if model == "" {
+		model = defaultTranscriptionModel
+	}
+	logger.InfoCF("voice", "Routing transcription by detected language", map[string]interface{}{
+		"language": code,
+		"model":    model,
+	})
+	routed, err := t.transcribe(ctx, audioFilePath, transcribeOptions{model: model, language: code, prompt: route.Prompt})
+	if err != nil {
+		// The detection pass already produced a usable transcript.
+		logger.WarnCF("voice", "Routed transcription failed, keeping detection pass", map[string]interface{}{
+			"language": code,
+			"error":    err.Error(),
+		})
+		return detected, nil
+	}
+	routed.Language = code
+	return routed, nil
+}
+
+// TranscribeLanguage transcribes speech known to be in language, using the
+// route configured for it if any. An empty language auto-detects.
+func (t *GroqTranscriber) TranscribeLanguage(ctx context.Context, audioFilePath, language string) (*TranscriptionResponse, error) {
+	code := languageCode(language)
+	if code == "" || code == "auto" {
+		return t.Transcribe(ctx, audioFilePath)
+	}
+
+	route := t.routes[code]
+	model := route.Model
+	if model == "" {
+		model = defaultTranscriptionModel
+	}
+	result, err := t.transcribe(ctx, audioFilePath, transcribeOptions{model: model, language: code, prompt: route.Prompt})
+	if err != nil {
+		return nil, err
+	}
+	result.Language = code
+	return result, nil
+}
+
+func (t *GroqTranscriber) transcribe(ctx context.Context, audioFilePath string, opts transcribeOptions) (*TranscriptionResponse, error) {
+	logger.InfoCF("voice", "Starting transcription", map[string]interface{}{"audio_file": audioFilePath, "model": opts.model})
 
 	audioFile, err := os.Open(audioFilePath)
 	if err != nil {
@@ -79,16 +150,31 @@ func (t *GroqTranscriber) Transcribe(ctx context.Context, audioFilePath string)
 
 	logger.DebugCF("voice", "File copied to request", map[string]interface{}{"bytes_copied": copied})
 
-	if err := writer.WriteField("model", "whisper-large-v3"); err != nil {
+	if err := writer.WriteField("model", opts.model); err != nil {
 		logger.ErrorCF("voice", "Failed to write model field", map[string]interface{}{"error": err})
 		return nil, fmt.Errorf("failed to write model field: %w", err)
 	}
 
-	if err := writer.WriteField("response_format", "json"); err != nil {
+	// Only verbose_json reports the detected language.
+	responseFormat := "json"
+	if opts.verbose {
+		responseFormat = "verbose_json"
+	}
+	if err := writer.WriteField("response_format", responseFormat); err != nil {
 		logger.ErrorCF("voice", "Failed to write response_format field", map[string]interface{}{"error": err})
 		return nil, fmt.Errorf("failed to write response_format field: %w", err)
 	}
 
+	for _, f := range []struct{ name, value string }{{"language", opts.language}, {"prompt", opts.prompt}} {
+		if f.value == "" {
+			continue
+		}
+		if err := writer.WriteField(f.name, f.value); err != nil {
+			logger.ErrorCF("voice", "Failed to write "+f.name+" field", map[string]interface{}{"error": err})
+			return nil, fmt.Errorf("failed to write %s field: %w", f.name, err)
+		}
+	}
+
 	if err := writer.Close(); err != nil {
 		logger.ErrorCF("voice", "Failed to close multipart writer", map[string]interface{}{"error": err})
 		return nil, fmt.Errorf("failed to close multipart writer: %w", err)